@@ -0,0 +1,293 @@
+package annotations
+
+import (
+	"math"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+// lineCap is the shape of the ends of a stroke, with the values of the
+// PDF operator J.
+type lineCap int
+
+const (
+	capButt lineCap = iota
+	capRound
+	capSquare
+)
+
+// vec is a point or a direction in device pixels.
+type vec struct{ x, y float64 }
+
+func (v vec) add(w vec) vec           { return vec{v.x + w.x, v.y + w.y} }
+func (v vec) sub(w vec) vec           { return vec{v.x - w.x, v.y - w.y} }
+func (v vec) mul(k float64) vec       { return vec{v.x * k, v.y * k} }
+func (v vec) dot(w vec) float64       { return v.x*w.x + v.y*w.y }
+func (v vec) normal() vec             { return vec{-v.y, v.x} }
+func pointVec(p rmencoding.Point) vec { return vec{float64(p.X), float64(p.Y)} }
+
+func (v vec) unit() (vec, bool) {
+	length := math.Hypot(v.x, v.y)
+	if length < 1e-6 {
+		return vec{}, false
+	}
+	return v.mul(1 / length), true
+}
+
+// A brushStroke is a part of a line drawn with the same opacity. The
+// brushes with a constant width are drawn along their points with a
+// width, the other ones are filled outlines following the width at
+// each point.
+type brushStroke struct {
+	// points and width are the ones of the constant strokes, width
+	// being in device pixels
+	points []rmencoding.Point
+	width  float64
+	// outline is the polygon of the strokes whose width varies
+	outline []vec
+	opacity float64
+	cap     lineCap
+}
+
+// opacitySteps is the number of opacities of the strokes, so that the
+// consecutive segments of a line are merged in long strokes.
+const opacitySteps = 20
+
+// brushStyle returns the width and the opacity of a brush at a point,
+// tilt being the tilt of the pen between 0 (upright) and 1, and angle
+// the direction of the line at the point.
+type brushStyle func(i int, p rmencoding.Point, tilt, angle float64) (width, opacity float64)
+
+// brushStrokes returns the strokes drawing a line the way the device
+// does, base being the width of the constant brushes in device pixels:
+//   - the ballpoint gets wider and darker with the pressure, and
+//     lighter with the speed,
+//   - the pencils are grainy, their opacity follows the pressure and
+//     the tilted pencil draws wider and lighter strokes,
+//   - the paintbrush gets wider with the pressure,
+//   - the marker has a square tip, thinner when tilted,
+//   - the calligraphy pen has a chisel tip, wide across the strokes
+//     going up to the right and thin along them.
+//
+// The fineliner and the unknown brushes have a constant width. The
+// highlighter and the erasers are drawn by the renderers.
+func brushStrokes(line rmencoding.Line, base float64) []brushStroke {
+	if len(line.Points) == 0 {
+		return nil
+	}
+
+	var style brushStyle
+	end := capRound
+	switch line.BrushType {
+	case rmencoding.BallPoint, rmencoding.BallPointV5:
+		style = func(_ int, p rmencoding.Point, _, _ float64) (float64, float64) {
+			pressure, speed := float64(p.Pressure), float64(p.Speed)
+			return pointWidth(p, base) * (0.7 + 0.6*pressure) * clamp(1-speed/200, 0.6, 1),
+				clamp(0.5+1.2*pressure-speed/350, 0.3, 1)
+		}
+	case rmencoding.TiltPencil, rmencoding.TiltPencilV5:
+		style = func(i int, p rmencoding.Point, tilt, _ float64) (float64, float64) {
+			pressure, speed := float64(p.Pressure), float64(p.Speed)
+			return pointWidth(p, base) * (0.8 + 0.4*pressure) * (1 + 1.5*tilt),
+				clamp(0.3+0.6*pressure-speed/500-0.3*tilt, 0.1, 0.8) * grain(i)
+		}
+	case rmencoding.SharpPencil, rmencoding.SharpPencilV5:
+		style = func(i int, p rmencoding.Point, _, _ float64) (float64, float64) {
+			return base * 0.7, clamp(0.4+0.5*float64(p.Pressure), 0.1, 0.8) * grain(i)
+		}
+	case rmencoding.Brush, rmencoding.BrushV5:
+		style = func(_ int, p rmencoding.Point, _, _ float64) (float64, float64) {
+			pressure := float64(p.Pressure)
+			return pointWidth(p, base) * (0.6 + 0.8*pressure),
+				clamp(1.5*math.Pow(pressure, 1.5)-float64(p.Speed)/150, 0.4, 1)
+		}
+	case rmencoding.Marker, rmencoding.MarkerV5:
+		end = capSquare
+		style = func(_ int, p rmencoding.Point, tilt, _ float64) (float64, float64) {
+			return pointWidth(p, base) * (1 - 0.4*tilt), 1
+		}
+	case rmencoding.Calligraphy:
+		end = capButt
+		style = func(_ int, p rmencoding.Point, _, angle float64) (float64, float64) {
+			return pointWidth(p, base) * (0.25 + 0.75*math.Abs(math.Sin(angle-math.Pi/4))), 1
+		}
+	default:
+		return []brushStroke{{points: line.Points, width: base, opacity: 1, cap: end}}
+	}
+	return variableStrokes(line.Points, style, end)
+}
+
+// variableStrokes returns the outlines of a line whose width varies,
+// one for each run of segments of the same opacity. The outlines of
+// consecutive runs share their sides, they don't overlap.
+func variableStrokes(points []rmencoding.Point, style brushStyle, end lineCap) []brushStroke {
+	n := len(points)
+	tangents := lineTangents(points)
+	halves := make([]float64, n)
+	opacities := make([]float64, n)
+	for i, p := range points {
+		// the y axis goes down
+		angle := math.Atan2(-tangents[i].y, tangents[i].x)
+		width, opacity := style(i, p, pointTilt(p), angle)
+		halves[i] = math.Max(width, 0.25) / 2
+		opacities[i] = math.Round(opacity*opacitySteps) / opacitySteps
+	}
+
+	if n == 1 {
+		// a dot
+		dot := end
+		if dot == capButt {
+			dot = capRound
+		}
+		center, normal := pointVec(points[0]), tangents[0].normal()
+		outline := []vec{center.add(normal.mul(halves[0]))}
+		outline = append(outline, capOutline(center, tangents[0], halves[0], dot)...)
+		outline = append(outline, center.sub(normal.mul(halves[0])))
+		outline = append(outline, capOutline(center, tangents[0].mul(-1), halves[0], dot)...)
+		return []brushStroke{{outline: outline, opacity: opacities[0], cap: end}}
+	}
+
+	// the sides, miters limited to twice the width
+	left := make([]vec, n)
+	right := make([]vec, n)
+	for i := range points {
+		normal := tangents[i].normal()
+		offset := halves[i]
+		if i > 0 && i < n-1 {
+			if segment, ok := pointVec(points[i+1]).sub(pointVec(points[i])).unit(); ok {
+				offset /= math.Max(normal.dot(segment.normal()), 0.5)
+			}
+		}
+		center := pointVec(points[i])
+		left[i] = center.add(normal.mul(offset))
+		right[i] = center.sub(normal.mul(offset))
+	}
+
+	var strokes []brushStroke
+	start := 0
+	for i := 1; i < n; i++ {
+		// a segment has the opacity of its last point
+		if i < n-1 && opacities[i+1] == opacities[i] {
+			continue
+		}
+		var outline []vec
+		outline = append(outline, left[start:i+1]...)
+		if i == n-1 {
+			outline = append(outline, capOutline(pointVec(points[i]), tangents[i], halves[i], end)...)
+		}
+		for j := i; j >= start; j-- {
+			outline = append(outline, right[j])
+		}
+		if start == 0 {
+			outline = append(outline, capOutline(pointVec(points[0]), tangents[0].mul(-1), halves[0], end)...)
+		}
+		strokes = append(strokes, brushStroke{outline: outline, opacity: opacities[i], cap: end})
+		start = i
+	}
+	return strokes
+}
+
+// lineTangents returns the direction of a line at each of its points.
+func lineTangents(points []rmencoding.Point) []vec {
+	tangents := make([]vec, len(points))
+	previous := vec{1, 0}
+	for i := range points {
+		before, after := i, i
+		if i > 0 {
+			before = i - 1
+		}
+		if i < len(points)-1 {
+			after = i + 1
+		}
+		if t, ok := pointVec(points[after]).sub(pointVec(points[before])).unit(); ok {
+			previous = t
+		}
+		tangents[i] = previous
+	}
+	return tangents
+}
+
+// capOutline returns the points of the end of a stroke going in the
+// direction tangent, from its left side to its right side.
+func capOutline(center, tangent vec, half float64, end lineCap) []vec {
+	normal := tangent.normal()
+	switch end {
+	case capRound:
+		const steps = 8
+		outline := make([]vec, 0, steps-1)
+		for k := 1; k < steps; k++ {
+			theta := float64(k) * math.Pi / steps
+			dir := normal.mul(math.Cos(theta)).add(tangent.mul(math.Sin(theta)))
+			outline = append(outline, center.add(dir.mul(half)))
+		}
+		return outline
+	case capSquare:
+		tip := center.add(tangent.mul(half))
+		return []vec{tip.add(normal.mul(half)), tip.sub(normal.mul(half))}
+	}
+	return nil
+}
+
+// pointWidth returns the width recorded by the device for a point, or
+// base when there is none.
+func pointWidth(p rmencoding.Point, base float64) float64 {
+	if p.Width > 0 {
+		return float64(p.Width)
+	}
+	return base
+}
+
+// pointTilt returns the tilt of the pen at a point, recorded in
+// radians, between 0 when the pen is upright and 1 when it is at 90°.
+func pointTilt(p rmencoding.Point) float64 {
+	return clamp(float64(p.Direction)/(math.Pi/2), 0, 1)
+}
+
+// grain is a factor of the opacity between 0.8 and 1 which varies from
+// one segment to the next, as the texture of the pencils.
+func grain(i int) float64 {
+	v := math.Sin(float64(i)*12.9898) * 43758.5453
+	return 0.8 + 0.2*(v-math.Floor(v))
+}
+
+func clamp(v, low, high float64) float64 {
+	return math.Max(low, math.Min(high, v))
+}
+
+// curve is a cubic Bézier curve from the end of the previous one, c1
+// and c2 being its control points.
+type curve struct{ c1, c2, end vec }
+
+// smoothPath returns the Catmull-Rom spline through points, from the
+// first one, as Bézier curves. The ends of an open path are repeated to
+// give the tangents of its first and last curves, a closed path goes
+// back to its first point.
+func smoothPath(points []vec, closed bool) []curve {
+	n := len(points)
+	at := func(i int) vec {
+		if closed {
+			return points[(i+n)%n]
+		}
+		if i < 0 {
+			return points[0]
+		}
+		if i >= n {
+			return points[n-1]
+		}
+		return points[i]
+	}
+	segments := n - 1
+	if closed {
+		segments = n
+	}
+	curves := make([]curve, 0, segments)
+	for i := 0; i < segments; i++ {
+		p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+		curves = append(curves, curve{
+			c1:  p1.add(p2.sub(p0).mul(1.0 / 6)),
+			c2:  p2.sub(p3.sub(p1).mul(1.0 / 6)),
+			end: p2,
+		})
+	}
+	return curves
+}
@@ -0,0 +1,156 @@
+package annotations
+
+import (
+	"math"
+	"testing"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/pdf"
+)
+
+func brushLine(brush rmencoding.BrushType, points ...rmencoding.Point) rmencoding.Line {
+	return rmencoding.Line{BrushType: brush, BrushSize: rmencoding.Medium, Points: points}
+}
+
+func TestVariableStrokes(t *testing.T) {
+	points := []rmencoding.Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 20, Y: 0}, {X: 30, Y: 0}}
+	opacities := []float64{1, 0.5, 0.5, 1}
+	strokes := variableStrokes(points, func(i int, _ rmencoding.Point, _, _ float64) (float64, float64) {
+		return float64(2 * (i + 1)), opacities[i]
+	}, capButt)
+
+	want := [][]vec{
+		{{0, 1}, {10, 2}, {20, 3}, {20, -3}, {10, -2}, {0, -1}},
+		{{20, 3}, {30, 4}, {30, -4}, {20, -3}},
+	}
+	if len(strokes) != len(want) {
+		t.Fatalf("expected %d strokes, got %d", len(want), len(strokes))
+	}
+	for i, s := range strokes {
+		if len(s.outline) != len(want[i]) {
+			t.Fatalf("stroke %d: unexpected outline %v", i, s.outline)
+		}
+		for j, v := range s.outline {
+			if math.Abs(v.x-want[i][j].x) > 1e-9 || math.Abs(v.y-want[i][j].y) > 1e-9 {
+				t.Errorf("stroke %d: expected %v, got %v", i, want[i], s.outline)
+				break
+			}
+		}
+	}
+	if strokes[0].opacity != 0.5 || strokes[1].opacity != 1 {
+		t.Errorf("unexpected opacities %v, %v", strokes[0].opacity, strokes[1].opacity)
+	}
+
+	dot := variableStrokes(points[:1], func(int, rmencoding.Point, float64, float64) (float64, float64) { return 4, 1 }, capRound)
+	if len(dot) != 1 || len(dot[0].outline) < 8 {
+		t.Errorf("unexpected dot %+v", dot)
+	}
+}
+
+// strokeHeight returns the height of the outlines of strokes.
+func strokeHeight(strokes []brushStroke) float64 {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, s := range strokes {
+		for _, v := range s.outline {
+			low, high = math.Min(low, v.y), math.Max(high, v.y)
+		}
+	}
+	return high - low
+}
+
+func horizontalLine(brush rmencoding.BrushType, p rmencoding.Point) rmencoding.Line {
+	var points []rmencoding.Point
+	for i := 0; i < 5; i++ {
+		p.X = float32(i * 10)
+		points = append(points, p)
+	}
+	return brushLine(brush, points...)
+}
+
+func TestBrushStrokesStyles(t *testing.T) {
+	light := rmencoding.Point{Width: 4, Pressure: 0.1}
+	hard := rmencoding.Point{Width: 4, Pressure: 0.9}
+
+	fineliner := brushStrokes(brushLine(rmencoding.FinelinerV5, light, hard), 2)
+	if len(fineliner) != 1 || fineliner[0].width != 2 || fineliner[0].opacity != 1 || fineliner[0].outline != nil {
+		t.Errorf("unexpected fineliner %+v", fineliner)
+	}
+
+	for _, brush := range []rmencoding.BrushType{rmencoding.BallPointV5, rmencoding.BrushV5} {
+		soft := brushStrokes(horizontalLine(brush, light), 2)
+		pressed := brushStrokes(horizontalLine(brush, hard), 2)
+		if strokeHeight(soft) >= strokeHeight(pressed) {
+			t.Errorf("%s should get wider with the pressure", brush)
+		}
+		if soft[0].opacity >= pressed[0].opacity {
+			t.Errorf("%s should get darker with the pressure", brush)
+		}
+	}
+
+	upright := brushStrokes(horizontalLine(rmencoding.TiltPencilV5, light), 2)
+	tilted := light
+	tilted.Direction = math.Pi / 2
+	if strokeHeight(brushStrokes(horizontalLine(rmencoding.TiltPencilV5, tilted), 2)) <= strokeHeight(upright) {
+		t.Error("the tilted pencil should draw wider strokes")
+	}
+	for _, s := range upright {
+		if s.opacity > 0.8 {
+			t.Errorf("the pencil should be lighter: %+v", s)
+		}
+	}
+
+	if marker := brushStrokes(brushLine(rmencoding.MarkerV5, light, hard), 2); marker[0].cap != capSquare {
+		t.Errorf("the marker should have a square tip: %+v", marker)
+	}
+
+	// a chisel tip going up to the right, the y axis going down
+	across := brushStrokes(brushLine(rmencoding.Calligraphy, rmencoding.Point{X: 0, Y: 10, Width: 4}, rmencoding.Point{X: 10, Y: 20, Width: 4}), 2)
+	along := brushStrokes(brushLine(rmencoding.Calligraphy, rmencoding.Point{X: 0, Y: 10, Width: 4}, rmencoding.Point{X: 10, Y: 0, Width: 4}), 2)
+	width := func(s brushStroke) float64 {
+		first, last := s.outline[0], s.outline[len(s.outline)-1]
+		return math.Hypot(first.x-last.x, first.y-last.y)
+	}
+	if width(across[0]) <= width(along[0]) {
+		t.Errorf("the calligraphy pen should be thin along its tip: %v, %v", width(across[0]), width(along[0]))
+	}
+}
+
+func TestOpacityStates(t *testing.T) {
+	states := pageResources["ExtGState"].(pdf.Dict)
+	for _, opacity := range []float64{0.1, 0.45, 0.8} {
+		name := opacityState(opacity)
+		if _, ok := states[pdf.Name(name)]; !ok {
+			t.Errorf("missing graphics state %s", name)
+		}
+	}
+	if opacityState(1) != "" {
+		t.Error("an opaque stroke needs no graphics state")
+	}
+}
+
+func TestSmoothPath(t *testing.T) {
+	points := []vec{{0, 0}, {10, 10}, {20, 0}}
+	curves := smoothPath(points, false)
+	want := []curve{
+		{c1: vec{10.0 / 6, 10.0 / 6}, c2: vec{10 - 20.0/6, 10}, end: vec{10, 10}},
+		{c1: vec{10 + 20.0/6, 10}, c2: vec{20 - 10.0/6, 10.0 / 6}, end: vec{20, 0}},
+	}
+	if len(curves) != len(want) {
+		t.Fatalf("expected %d curves, got %v", len(want), curves)
+	}
+	for i, c := range curves {
+		for j, v := range []vec{c.c1, c.c2, c.end} {
+			w := []vec{want[i].c1, want[i].c2, want[i].end}[j]
+			if math.Abs(v.x-w.x) > 1e-9 || math.Abs(v.y-w.y) > 1e-9 {
+				t.Errorf("curve %d: expected %v, got %v", i, want[i], c)
+				break
+			}
+		}
+	}
+
+	// a closed path goes back to its first point
+	closed := smoothPath(points, true)
+	if len(closed) != 3 || closed[2].end != points[0] {
+		t.Errorf("unexpected closed path %v", closed)
+	}
+}
@@ -0,0 +1,104 @@
+package annotations
+
+import (
+	"image/color"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+// PenColors replaces the colors of the pens in the exports, by the
+// name of the color of the brush (black, grey, white, blue, red...).
+// highlighter replaces the yellow of the highlighters. The default
+// color is kept for the pens which aren't set.
+type PenColors map[string]color.RGBA
+
+var defaultPenColors = PenColors{
+	"black":        {0, 0, 0, 0xff},
+	"grey":         {0x80, 0x80, 0x80, 0xff},
+	"white":        {0xff, 0xff, 0xff, 0xff},
+	"yellow":       {0xfb, 0xf7, 0x19, 0xff},
+	"green":        {0x00, 0xc8, 0x3c, 0xff},
+	"pink":         {0xff, 0x14, 0x93, 0xff},
+	"blue":         {0x00, 0x62, 0xcc, 0xff},
+	"red":          {0xd9, 0x07, 0x07, 0xff},
+	"grey_overlap": {0x7d, 0x7d, 0x7d, 0xff},
+	"highlighter":  {0xff, 0xff, 0, 0xff},
+	"light_green":  {0xa1, 0xd8, 0x7d, 0xff},
+	"cyan":         {0x8b, 0xd0, 0xe5, 0xff},
+	"magenta":      {0xb7, 0x82, 0xcd, 0xff},
+	"light_yellow": {0xf7, 0xe8, 0x51, 0xff},
+}
+
+// line returns the color of a stroke of a pen, the unknown colors are
+// drawn in black.
+func (c PenColors) line(line rmencoding.Line) color.RGBA {
+	name := line.BrushColor.String()
+	if _, ok := defaultPenColors[name]; !ok {
+		name = "black"
+	}
+	if rgba, ok := c[name]; ok {
+		return rgba
+	}
+	return defaultPenColors[name]
+}
+
+// defaultHighlightColors are the colors of the highlighters, drawn
+// half transparent over the page.
+var defaultHighlightColors = PenColors{
+	"yellow": {0xff, 0xed, 0x00, 0xff},
+	"green":  {0x7c, 0xe0, 0x5a, 0xff},
+	"pink":   {0xff, 0x6e, 0xc7, 0xff},
+	"grey":   {0xb4, 0xb4, 0xb4, 0xff},
+	"blue":   {0x6e, 0xb4, 0xff, 0xff},
+	"red":    {0xff, 0x64, 0x64, 0xff},
+}
+
+// highlightNames are the colors of the highlighters by the color of
+// their lines. The older firmwares draw them in black, grey or white,
+// they are yellow like the unknown colors.
+var highlightNames = map[rmencoding.BrushColor]string{
+	rmencoding.Green:       "green",
+	rmencoding.LightGreen:  "green",
+	rmencoding.Pink:        "pink",
+	rmencoding.Magenta:     "pink",
+	rmencoding.GreyOverlap: "grey",
+	rmencoding.Blue:        "blue",
+	rmencoding.Cyan:        "blue",
+	rmencoding.Red:         "red",
+}
+
+// HighlightColorName returns the name of the color of a highlighter
+// line: yellow, green, pink, grey, blue or red.
+func HighlightColorName(c rmencoding.BrushColor) string {
+	if name, ok := highlightNames[c]; ok {
+		return name
+	}
+	return "yellow"
+}
+
+// lineColor returns the color of a stroke. The strokes of the Paper Pro
+// have their own. The highlighters take theirs from highlights, then
+// from the highlighter of the pens for the yellow ones, the other
+// brushes from pens.
+func lineColor(line rmencoding.Line, pens, highlights PenColors) color.RGBA {
+	if line.ARGB != 0 {
+		// the inks of the Paper Pro
+		return color.RGBA{uint8(line.ARGB >> 16), uint8(line.ARGB >> 8), uint8(line.ARGB), 0xff}
+	}
+	if line.BrushType != rmencoding.Highlighter && line.BrushType != rmencoding.HighlighterV5 {
+		return pens.line(line)
+	}
+	name := HighlightColorName(line.BrushColor)
+	if rgba, ok := highlights[name]; ok {
+		return rgba
+	}
+	if rgba, ok := pens["highlighter"]; ok && name == "yellow" {
+		return rgba
+	}
+	return defaultHighlightColors[name]
+}
+
+// rgb returns the components of a color between 0 and 1.
+func rgb(c color.RGBA) (r, g, b float64) {
+	return float64(c.R) / 0xff, float64(c.G) / 0xff, float64(c.B) / 0xff
+}
@@ -0,0 +1,80 @@
+package annotations
+
+import (
+	"image/color"
+	"testing"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+func TestPenColors(t *testing.T) {
+	colors := PenColors{"blue": {0, 0, 0x80, 0xff}}
+	for _, tc := range []struct {
+		brush rmencoding.BrushType
+		color rmencoding.BrushColor
+		want  color.RGBA
+	}{
+		{rmencoding.BallPointV5, rmencoding.Black, defaultPenColors["black"]},
+		{rmencoding.BallPointV5, rmencoding.Red, defaultPenColors["red"]},
+		{rmencoding.FinelinerV5, rmencoding.Blue, color.RGBA{0, 0, 0x80, 0xff}},
+		{rmencoding.FinelinerV5, rmencoding.BrushColor(99), defaultPenColors["black"]},
+	} {
+		line := rmencoding.Line{BrushType: tc.brush, BrushColor: tc.color}
+		if got := colors.line(line); got != tc.want {
+			t.Errorf("%s %s: expected %v, got %v", tc.brush, tc.color, tc.want, got)
+		}
+	}
+
+	// every color of the highlighters has a default
+	for c := rmencoding.Black; c <= rmencoding.LightYellow; c++ {
+		if _, ok := defaultHighlightColors[HighlightColorName(c)]; !ok {
+			t.Errorf("no default highlight for %s", c)
+		}
+	}
+
+	// every color of the brushes has a default
+	for c := rmencoding.Black; c <= rmencoding.LightYellow; c++ {
+		if _, ok := defaultPenColors[c.String()]; !ok {
+			t.Errorf("no default for %s", c)
+		}
+		if byName, ok := rmencoding.BrushColorByName(c.String()); !ok || byName != c {
+			t.Errorf("%s: unexpected color by name %v", c, byName)
+		}
+	}
+}
+
+func TestHighlightColors(t *testing.T) {
+	green := color.RGBA{0, 0xff, 0, 0xff}
+	orange := color.RGBA{0xff, 0xa5, 0, 0xff}
+	for _, tc := range []struct {
+		brush      rmencoding.BrushType
+		color      rmencoding.BrushColor
+		pens       PenColors
+		highlights PenColors
+		want       color.RGBA
+	}{
+		{rmencoding.HighlighterV5, rmencoding.Black, nil, nil, defaultHighlightColors["yellow"]},
+		{rmencoding.Highlighter, rmencoding.Grey, nil, nil, defaultHighlightColors["yellow"]},
+		{rmencoding.HighlighterV5, rmencoding.Highlight, nil, nil, defaultHighlightColors["yellow"]},
+		{rmencoding.HighlighterV5, rmencoding.Green, nil, nil, defaultHighlightColors["green"]},
+		{rmencoding.HighlighterV5, rmencoding.Pink, nil, nil, defaultHighlightColors["pink"]},
+		{rmencoding.HighlighterV5, rmencoding.GreyOverlap, nil, nil, defaultHighlightColors["grey"]},
+		{rmencoding.HighlighterV5, rmencoding.Green, nil, PenColors{"green": green}, green},
+		{rmencoding.HighlighterV5, rmencoding.Yellow, PenColors{"highlighter": orange}, nil, orange},
+		{rmencoding.HighlighterV5, rmencoding.Yellow, PenColors{"highlighter": orange}, PenColors{"yellow": green}, green},
+		{rmencoding.HighlighterV5, rmencoding.Pink, PenColors{"highlighter": orange}, nil, defaultHighlightColors["pink"]},
+		{rmencoding.FinelinerV5, rmencoding.Green, nil, PenColors{"green": orange}, defaultPenColors["green"]},
+	} {
+		line := rmencoding.Line{BrushType: tc.brush, BrushColor: tc.color}
+		if got := lineColor(line, tc.pens, tc.highlights); got != tc.want {
+			t.Errorf("%s %s: expected %v, got %v", tc.brush, tc.color, tc.want, got)
+		}
+	}
+}
+
+func TestPaperProColors(t *testing.T) {
+	line := rmencoding.Line{BrushType: rmencoding.FinelinerV5, BrushColor: rmencoding.Highlight, ARGB: 0xff2a7fff}
+	if got, want := lineColor(line, PenColors{"black": {}}, nil), (color.RGBA{0x2a, 0x7f, 0xff, 0xff}); got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,84 @@
+package annotations
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joagonca/rmapi/pdf"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// encryptedArchive copies an archive with its pdf encrypted.
+func encryptedArchive(t *testing.T, name, password string) string {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	encrypted := filepath.Join(t.TempDir(), filepath.Base(name))
+	out, err := os.Create(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(out)
+	for _, file := range zr.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasSuffix(file.Name, ".pdf") {
+			var b bytes.Buffer
+			if err := api.Encrypt(bytes.NewReader(content), &b, model.NewAESConfiguration(password, password, 256)); err != nil {
+				t.Fatal(err)
+			}
+			content = b.Bytes()
+		}
+		w, err := zw.Create(file.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+	return encrypted
+}
+
+func TestNativeEncrypted(t *testing.T) {
+	name := encryptedArchive(t, "testfiles/a4.zip", "secret")
+	out := filepath.Join(t.TempDir(), "a4.pdf")
+
+	err := CreatePdfGenerator(name, out, PdfGeneratorOptions{Renderer: "native"}).Generate()
+	if err == nil || !strings.Contains(err.Error(), "password") {
+		t.Errorf("expected an error about the password, got %v", err)
+	}
+
+	if err := CreatePdfGenerator(name, out, PdfGeneratorOptions{Renderer: "native", Password: "secret"}).Generate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := pdf.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages, err := f.Pages(); err != nil || len(pages) != 1 {
+		t.Errorf("expected the page of the pdf, got %d (%v)", len(pages), err)
+	}
+}
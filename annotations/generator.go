@@ -0,0 +1,179 @@
+package annotations
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	DeviceWidth  = 1404
+	DeviceHeight = 1872
+)
+
+// rmPageSize is the default page size for blank templates (in PDF points: 1/72 inch)
+var rmPageSize = struct{ Width, Height float64 }{445, 594}
+
+// PdfGenerator exports the annotations of a document as a PDF, with
+// the renderer set in its options.
+type PdfGenerator struct {
+	zipName        string
+	outputFilePath string
+	options        PdfGeneratorOptions
+	files          []string
+}
+
+type PdfGeneratorOptions struct {
+	AddPageNumbers bool
+	// PageNumbers is the look of the numbers of AddPageNumbers
+	PageNumbers PageNumberStyle
+	// AllPages keeps the pages without annotations of notebooks and of
+	// annotations-only exports. The pages of a PDF are always kept.
+	AllPages        bool
+	AnnotationsOnly bool //export the annotations without the background/pdf
+	// Colors replaces the colors of the pens
+	Colors PenColors
+	// HighlightColors replaces the colors of the highlighters, by the
+	// name returned by HighlightColorName
+	HighlightColors PenColors
+	// Widths replaces the widths of the strokes of the brushes, the
+	// highlighters keep theirs
+	Widths PenWidths
+	// SplitEvery writes the export in files of this number of pages
+	// when set, see SplitName
+	SplitEvery int
+	// Renderer is the name of the renderer, DefaultRenderer when empty
+	Renderer string
+	// Pages are the pages exported, numbered from 1, all of them when
+	// empty
+	Pages PageRanges
+	// NoTemplates leaves the pages of the notebooks blank, without the
+	// lines of their template
+	NoTemplates bool
+	// Smooth draws the strokes as curves through their points instead
+	// of straight segments between them
+	Smooth bool
+	// RasterDPI draws the strokes of each page as a transparent image
+	// of this resolution, instead of paths, for the viewers slow with
+	// many paths
+	RasterDPI int
+	// RasterCompression is the level of compression of the images of
+	// RasterDPI, from 1 (fastest) to 9 (smallest), the default one when
+	// 0
+	RasterCompression int
+	// Flatten draws the strokes of each page as an image of RasterDPI,
+	// or of flattenDPI, whose colors are compressed with JPEG, for the
+	// smallest files. JPEGQuality is their quality, from 1 to 100, 75
+	// when 0.
+	Flatten     bool
+	JPEGQuality int
+	// Layers selects the layers of the pages exported
+	Layers LayerFilter
+	// SplitLayers writes an export for each layer, named by LayerName
+	SplitLayers bool
+	// Password opens the original PDF when it is encrypted, the empty
+	// one when not set
+	Password string
+	// Jobs is the number of pages read and drawn at the same time by the
+	// native renderer, one when 0
+	Jobs int
+	// Eink draws the strokes like the screen of the device, in its 16
+	// greys with the grain of the pencils, as images of RasterDPI or
+	// of the resolution of the device
+	Eink bool
+	// OCR is the name of the recognizer of the handwriting, see
+	// Recognizers, whose words are written as invisible text under the
+	// strokes by the native renderer. OCRLanguage is the language of
+	// the handwriting, the default one of the recognizer when empty.
+	OCR         string
+	OCRLanguage string
+	// Watermark is stamped on the pages, under the annotations, by the
+	// renderers with the capability
+	Watermark Watermark
+	// Progress, when set, is called after each page of the document is
+	// read, page going from 1 to total, the number of pages selected.
+	// It is called by the goroutine of Generate, once per export of
+	// SplitLayers.
+	Progress func(page, total int)
+}
+
+// rasterDPI returns the resolution of the images of the strokes, 0
+// when they are drawn as paths.
+func (o PdfGeneratorOptions) rasterDPI() int {
+	if o.Eink && o.RasterDPI <= 0 {
+		return deviceDPI
+	}
+	if o.Flatten && o.RasterDPI <= 0 {
+		return flattenDPI
+	}
+	return o.RasterDPI
+}
+
+// progress calls Progress, when set.
+func (o PdfGeneratorOptions) progress(page, total int) {
+	if o.Progress != nil {
+		o.Progress(page, total)
+	}
+}
+
+func CreatePdfGenerator(zipName, outputFilePath string, options PdfGeneratorOptions) *PdfGenerator {
+	return &PdfGenerator{zipName: zipName, outputFilePath: outputFilePath, options: options}
+}
+
+// Generate writes the PDF with the renderer of the options.
+func (p *PdfGenerator) Generate() error {
+	name := p.options.Renderer
+	if name == "" {
+		name = DefaultRenderer()
+	}
+	renderer, err := LookupRenderer(name)
+	if err != nil {
+		return err
+	}
+	if p.options.AddPageNumbers {
+		if err := p.options.PageNumbers.Check(); err != nil {
+			return err
+		}
+	}
+	if p.options.SplitLayers {
+		return p.generateLayers(renderer)
+	}
+	files, err := renderer.New().Render(p.zipName, p.outputFilePath, p.options)
+	if err != nil {
+		return err
+	}
+	p.files = files
+	return nil
+}
+
+// generateLayers writes an export for each layer selected, with the
+// strokes of this layer only.
+func (p *PdfGenerator) generateLayers(renderer RendererInfo) error {
+	layers, err := DocumentLayers(p.zipName)
+	if err != nil {
+		return err
+	}
+	p.files = nil
+	for i, layer := range layers {
+		if !p.options.Layers.Keeps(i+1, layer) {
+			continue
+		}
+		options := p.options
+		options.SplitLayers = false
+		options.Layers.Include = []string{strconv.Itoa(i + 1)}
+		files, err := renderer.New().Render(p.zipName, LayerName(p.outputFilePath, i+1), options)
+		if err != nil {
+			return fmt.Errorf("layer %d: %w", i+1, err)
+		}
+		p.files = append(p.files, files...)
+	}
+	if len(p.files) == 0 {
+		return errors.New("the document has no layers to export")
+	}
+	return nil
+}
+
+// OutputFiles returns the files written by Generate.
+func (p *PdfGenerator) OutputFiles() []string {
+	return p.files
+}
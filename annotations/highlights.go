@@ -0,0 +1,132 @@
+package annotations
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joagonca/rmapi/archive"
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// ExtractHighlights returns the passages highlighted in the pdf or the
+// epub of an archive, in page order, the document being named after
+// the archive and dated by its modification. They can be written with
+// archive.WriteHighlights, e.g. as markdown grouped by page.
+func ExtractHighlights(zipPath string) ([]archive.HighlightRecord, error) {
+	file, err := os.Open(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	zip := archive.NewZip()
+	if err := zip.ReadLazy(file, fi.Size()); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(zipPath), filepath.Ext(zipPath))
+	return zip.HighlightRecords(name, fi.ModTime().UTC().Truncate(time.Second)), nil
+}
+
+// addHighlightAnnotations adds the highlights of the text of a page of
+// the original pdf as highlight annotations, which the viewers list
+// with the text they cover. device converts the pixels of the device into
+// the user space of the page, see deviceMatrix.
+func addHighlightAnnotations(f *pdf.File, page *pdf.PageObject, highlights []archive.Highlight, device pdf.Matrix, colors PenColors) error {
+	for _, h := range highlights {
+		if len(h.Rects) == 0 {
+			continue
+		}
+
+		var quads pdf.Array
+		bounds := pdf.Rect{LLX: math.Inf(1), LLY: math.Inf(1), URX: math.Inf(-1), URY: math.Inf(-1)}
+		for _, r := range h.Rects {
+			// upper left, upper right, lower left and lower right
+			for _, corner := range [][2]float64{
+				{r.X, r.Y}, {r.X + r.Width, r.Y}, {r.X, r.Y + r.Height}, {r.X + r.Width, r.Y + r.Height},
+			} {
+				x, y := device.Apply(corner[0], corner[1])
+				quads = append(quads, round(x), round(y))
+				bounds.LLX, bounds.URX = math.Min(bounds.LLX, x), math.Max(bounds.URX, x)
+				bounds.LLY, bounds.URY = math.Min(bounds.LLY, y), math.Max(bounds.URY, y)
+			}
+		}
+		bounds = pdf.Rect{LLX: round(bounds.LLX), LLY: round(bounds.LLY), URX: round(bounds.URX), URY: round(bounds.URY)}
+
+		c := highlightColor(rmencoding.BrushColor(h.Color), colors)
+		r, g, b := rgb(c)
+		annot := pdf.Dict{
+			"Type":       pdf.Name("Annot"),
+			"Subtype":    pdf.Name("Highlight"),
+			"Rect":       bounds.Array(),
+			"QuadPoints": quads,
+			"C":          pdf.Array{r, g, b},
+			"Contents":   pdf.TextString(h.Text),
+			// printed
+			"F":  int64(4),
+			"AP": pdf.Dict{"N": f.Add(highlightAppearance(bounds, quads, r, g, b))},
+		}
+		if err := f.AddAnnotation(page, annot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transformHighlights returns the highlights moved by the transform of
+// a document, see strokes.TransformPage.
+func transformHighlights(highlights []archive.Highlight, m [6]float64) []archive.Highlight {
+	moved := make([]archive.Highlight, len(highlights))
+	for i, h := range highlights {
+		moved[i] = h
+		moved[i].Rects = make([]archive.HighlightRect, len(h.Rects))
+		for j, r := range h.Rects {
+			matrix := pdf.Matrix(m)
+			x1, y1 := matrix.Apply(r.X, r.Y)
+			x2, y2 := matrix.Apply(r.X+r.Width, r.Y+r.Height)
+			moved[i].Rects[j] = archive.HighlightRect{
+				X: math.Min(x1, x2), Y: math.Min(y1, y2), Width: math.Abs(x2 - x1), Height: math.Abs(y2 - y1),
+			}
+		}
+	}
+	return moved
+}
+
+// highlightAppearance returns the drawing of a highlight, for the
+// viewers which don't draw the annotations themselves.
+func highlightAppearance(bounds pdf.Rect, quads pdf.Array, r, g, b float64) *pdf.Stream {
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "/RmapiHighlight gs %.3f %.3f %.3f rg\n", r, g, b)
+	for i := 0; i+8 <= len(quads); i += 8 {
+		// around the quad: upper left, upper right, lower right, lower left
+		fmt.Fprintf(&content, "%v %v m %v %v l %v %v l %v %v l h f\n",
+			quads[i], quads[i+1], quads[i+2], quads[i+3], quads[i+6], quads[i+7], quads[i+4], quads[i+5])
+	}
+	return pdf.NewStream(pdf.Dict{
+		"Type":      pdf.Name("XObject"),
+		"Subtype":   pdf.Name("Form"),
+		"BBox":      bounds.Array(),
+		"Resources": pdf.Dict{"ExtGState": pdf.Dict{"RmapiHighlight": pageResources["ExtGState"].(pdf.Dict)["RmapiHighlight"]}},
+	}, content.Bytes())
+}
+
+// highlightColor returns the color of a highlight of the text, which
+// has the colors of the highlighters.
+func highlightColor(c rmencoding.BrushColor, colors PenColors) color.RGBA {
+	return lineColor(rmencoding.Line{BrushType: rmencoding.HighlighterV5, BrushColor: c}, nil, colors)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
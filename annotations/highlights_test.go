@@ -0,0 +1,177 @@
+package annotations
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joagonca/rmapi/archive"
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/pdf"
+)
+
+func TestHighlightAnnotations(t *testing.T) {
+	f := pdf.NewFile()
+	page := f.NewPage(rmPageSize.Width, rmPageSize.Height)
+	highlights := []archive.Highlight{
+		{Text: "café au lait", Color: int(rmencoding.Green), Rects: []archive.HighlightRect{
+			{X: 100, Y: 200, Width: 400, Height: 30},
+			{X: 100, Y: 240, Width: 200, Height: 30},
+		}},
+		// the highlights without rects have no location
+		{Text: "nowhere"},
+	}
+	device, _ := deviceMatrix(f, page, nil, false)
+	if err := addHighlightAnnotations(f, page, highlights, device, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetPages([]*pdf.PageObject{page}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := f.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	f, err := pdf.Open(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	annots, _ := f.Get(pages[0].Dict, "Annots").(pdf.Array)
+	if len(annots) != 1 {
+		t.Fatalf("expected 1 annotation, got %v", annots)
+	}
+	annot, _ := f.Get(pdf.Dict{"A": annots[0]}, "A").(pdf.Dict)
+	if annot["Subtype"] != pdf.Name("Highlight") {
+		t.Errorf("unexpected annotation %v", annot)
+	}
+	if got := annot["Contents"].(pdf.String); !bytes.Equal(got, pdf.TextString("café au lait")) {
+		t.Errorf("unexpected text %q", got)
+	}
+
+	quads, _ := annot["QuadPoints"].(pdf.Array)
+	if len(quads) != 16 {
+		t.Fatalf("expected 2 quads, got %v", quads)
+	}
+	// the upper left corner of the first line, from the top of the page
+	scale := rmPageSize.Height / float64(rmencoding.Height)
+	x, _ := pdf.Number(quads[0])
+	y, _ := pdf.Number(quads[1])
+	if x != round(100*scale) || y != round(rmPageSize.Height-200*scale) {
+		t.Errorf("unexpected corner %v %v", x, y)
+	}
+
+	c, _ := annot["C"].(pdf.Array)
+	g, _ := pdf.Number(c[1])
+	if want := float64(defaultHighlightColors["green"].G) / 0xff; len(c) != 3 || g != want {
+		t.Errorf("expected the green of the highlighter, got %v", c)
+	}
+	if ap, _ := f.Get(annot, "AP").(pdf.Dict); ap["N"] == nil {
+		t.Error("the highlight has no appearance")
+	}
+}
+
+func TestExtractHighlights(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "Paper.zip")
+	out, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(out)
+	for file, content := range map[string]string{
+		"doc.content":  `{"fileType":"pdf","pageCount":2,"pages":["a1e7c8f0-0000-4000-8000-000000000001","a1e7c8f0-0000-4000-8000-000000000002"]}`,
+		"doc.pagedata": "Blank\nBlank\n",
+		"doc.highlights/a1e7c8f0-0000-4000-8000-000000000002.json": `{"highlights":[[{"text":"a passage","color":3,"start":10,"length":9,` +
+			`"rects":[{"x":100,"y":200,"width":300,"height":30}]}]]}`,
+	} {
+		w, err := zw.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	records, err := ExtractHighlights(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Document != "Paper" || records[0].Page != 2 || records[0].Text != "a passage" {
+		t.Fatalf("unexpected highlights %+v", records)
+	}
+
+	var md bytes.Buffer
+	if err := archive.WriteHighlights(&md, "markdown", records); err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Paper\n\n## Page 2\n\n> a passage\n"; md.String() != want {
+		t.Errorf("unexpected markdown %q", md.String())
+	}
+}
+
+func TestNativeHighlightsWithoutStrokes(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "Paper.zip")
+	out, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the pdf is missing, the highlighted page has no strokes
+	zw := zip.NewWriter(out)
+	for file, content := range map[string]string{
+		"doc.content":  `{"fileType":"pdf","pageCount":2,"pages":["a1e7c8f0-0000-4000-8000-000000000001","a1e7c8f0-0000-4000-8000-000000000002"]}`,
+		"doc.pagedata": "Blank\nBlank\n",
+		"doc.highlights/a1e7c8f0-0000-4000-8000-000000000002.json": `{"highlights":[[{"text":"a passage","color":4,"start":10,"length":9,` +
+			`"rects":[{"x":100,"y":200,"width":300,"height":30}]}]]}`,
+	} {
+		w, err := zw.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	output := filepath.Join(dir, "Paper.pdf")
+	if err := CreatePdfGenerator(name, output, PdfGeneratorOptions{Renderer: "native"}).Generate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := pdf.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("expected the highlighted page, got %d pages", len(pages))
+	}
+	annots, _ := f.Get(pages[0].Dict, "Annots").(pdf.Array)
+	if len(annots) != 1 {
+		t.Fatalf("expected the highlight, got %v", annots)
+	}
+	annot, err := f.Resolve(annots[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dict, _ := annot.(pdf.Dict); dict["Subtype"] != pdf.Name("Highlight") {
+		t.Errorf("unexpected annotation %v", annot)
+	}
+}
@@ -0,0 +1,129 @@
+package annotations
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joagonca/rmapi/archive"
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+// A LayerFilter selects the layers of the pages which are exported.
+// The layers are named by their name, as on the device, or by their
+// number from 1.
+type LayerFilter struct {
+	// Include keeps only these layers, all of them when empty
+	Include []string
+	// Exclude leaves out these layers
+	Exclude []string
+	// Visible leaves out the layers hidden on the device
+	Visible bool
+}
+
+// ParseLayers parses a list of layers separated by commas, such as
+// "1,Sketch".
+func ParseLayers(s string) []string {
+	var layers []string
+	for _, layer := range strings.Split(s, ",") {
+		if layer = strings.TrimSpace(layer); layer != "" {
+			layers = append(layers, layer)
+		}
+	}
+	return layers
+}
+
+// Keeps returns whether a layer, numbered from 1, is exported.
+func (f LayerFilter) Keeps(number int, layer rmencoding.Layer) bool {
+	if f.Visible && layer.Hidden {
+		return false
+	}
+	if len(f.Include) > 0 && !matchLayer(f.Include, number, layer) {
+		return false
+	}
+	return !matchLayer(f.Exclude, number, layer)
+}
+
+func matchLayer(list []string, number int, layer rmencoding.Layer) bool {
+	for _, s := range list {
+		if n, err := strconv.Atoi(s); err == nil {
+			if n == number {
+				return true
+			}
+		} else if strings.EqualFold(s, layer.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply removes the strokes of the layers which aren't exported from a
+// drawing, the other layers keep their numbers. It returns nil when
+// nothing is left to draw.
+func (f LayerFilter) Apply(data *rmencoding.Rm) *rmencoding.Rm {
+	if data == nil {
+		return nil
+	}
+	removed := false
+	left := data.Text != nil
+	for i := range data.Layers {
+		layer := &data.Layers[i]
+		if !f.Keeps(i+1, *layer) {
+			removed = removed || len(layer.Lines) > 0
+			layer.Lines = nil
+		}
+		left = left || len(layer.Lines) > 0
+	}
+	if removed && !left {
+		return nil
+	}
+	return data
+}
+
+// LayerName returns the path of the export of a layer, numbered from
+// 1, of a document exported with SplitLayers, e.g. name-layer2.pdf.
+func LayerName(outputFilePath string, layer int) string {
+	return fmt.Sprintf("%s-layer%d.pdf", strings.TrimSuffix(outputFilePath, ".pdf"), layer)
+}
+
+// DocumentLayers returns the layers of the pages of a document, without
+// their strokes: the name of each layer and whether it is hidden are
+// the ones of the first page naming it.
+func DocumentLayers(zipName string) ([]rmencoding.Layer, error) {
+	file, err := os.Open(zipName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	zip := archive.NewZip()
+	if err := zip.ReadLazy(file, fi.Size()); err != nil {
+		return nil, err
+	}
+
+	var layers []rmencoding.Layer
+	for index := range zip.Pages {
+		data, err := zip.PageData(index)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		for i, layer := range data.Layers {
+			found := rmencoding.Layer{Name: layer.Name, Hidden: layer.Hidden}
+			if i == len(layers) {
+				layers = append(layers, found)
+			} else if layers[i].Name == "" && layer.Name != "" {
+				layers[i] = found
+			}
+		}
+	}
+	return layers, nil
+}
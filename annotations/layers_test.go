@@ -0,0 +1,84 @@
+package annotations
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+func TestLayerFilter(t *testing.T) {
+	layers := []rmencoding.Layer{{Name: "Layer 1"}, {Name: "Sketch", Hidden: true}, {Name: "Notes"}}
+	for _, tt := range []struct {
+		filter LayerFilter
+		want   []bool
+	}{
+		{LayerFilter{}, []bool{true, true, true}},
+		{LayerFilter{Include: ParseLayers("1, sketch")}, []bool{true, true, false}},
+		{LayerFilter{Exclude: []string{"3"}}, []bool{true, true, false}},
+		{LayerFilter{Visible: true}, []bool{true, false, true}},
+	} {
+		for i, layer := range layers {
+			if got := tt.filter.Keeps(i+1, layer); got != tt.want[i] {
+				t.Errorf("%+v: layer %d: expected %v", tt.filter, i+1, tt.want[i])
+			}
+		}
+	}
+
+	line := rmencoding.Line{BrushType: rmencoding.FinelinerV5, Points: []rmencoding.Point{{X: 1, Y: 1}}}
+	data := &rmencoding.Rm{Layers: []rmencoding.Layer{{Lines: []rmencoding.Line{line}}, {}}}
+	if got := (LayerFilter{Include: []string{"2"}}).Apply(data); got != nil {
+		t.Errorf("nothing should be left to draw, got %+v", got)
+	}
+	data = &rmencoding.Rm{Layers: []rmencoding.Layer{{Lines: []rmencoding.Line{line}}, {Lines: []rmencoding.Line{line}}}}
+	if got := (LayerFilter{Include: []string{"2"}}).Apply(data); got == nil || len(got.Layers) != 2 || len(got.Layers[0].Lines) != 0 || len(got.Layers[1].Lines) != 1 {
+		t.Errorf("unexpected layers %+v", got)
+	}
+}
+
+func TestSplitLayers(t *testing.T) {
+	line := rmencoding.Line{BrushType: rmencoding.FinelinerV5, BrushSize: rmencoding.Medium, Points: []rmencoding.Point{{X: 100, Y: 100, Width: 2}, {X: 200, Y: 200, Width: 2}}}
+	page := rmencoding.New()
+	page.Layers = []rmencoding.Layer{{Lines: []rmencoding.Line{line}}, {Lines: []rmencoding.Line{line}}}
+	drawing, err := page.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "notes.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for file, content := range map[string]string{
+		"notes.content":  `{"fileType":"notebook","pageCount":1,"pages":["a1e7c8f0-0000-4000-8000-000000000001"]}`,
+		"notes.pagedata": "Blank\n",
+		"notes/a1e7c8f0-0000-4000-8000-000000000001.rm": string(drawing),
+	} {
+		w, err := zw.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	out := filepath.Join(t.TempDir(), "notes.pdf")
+	generator := CreatePdfGenerator(name, out, PdfGeneratorOptions{Renderer: "native", SplitLayers: true, Layers: LayerFilter{Exclude: []string{"1"}}})
+	if err := generator.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	files := generator.OutputFiles()
+	if len(files) != 1 || files[0] != LayerName(out, 2) {
+		t.Fatalf("expected the export of the second layer, got %v", files)
+	}
+	if _, err := os.Stat(files[0]); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,188 @@
+package annotations
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// A Recognizer finds the words written by hand in the image of the
+// strokes of a page.
+type Recognizer interface {
+	// Recognize returns the words of img, their boxes being in the
+	// pixels of img.
+	Recognize(img image.Image) ([]Word, error)
+}
+
+// A Word is a word recognized in an image, and its box.
+type Word struct {
+	Text string
+	Box  image.Rectangle
+}
+
+// RecognizerInfo describes a recognizer of the registry. New creates it
+// for a language, the default one of the recognizer when empty.
+type RecognizerInfo struct {
+	Name        string
+	Description string
+	New         func(language string) Recognizer
+}
+
+var recognizers = make(map[string]RecognizerInfo)
+
+func init() {
+	RegisterRecognizer(RecognizerInfo{
+		Name:        "tesseract",
+		Description: "runs the tesseract command, with the language given as -l",
+		New:         func(language string) Recognizer { return tesseract{language: language} },
+	})
+}
+
+// RegisterRecognizer adds a recognizer to the registry, such as the
+// handwriting recognition of a service.
+func RegisterRecognizer(info RecognizerInfo) {
+	if _, ok := recognizers[info.Name]; ok {
+		panic("annotations: recognizer registered twice: " + info.Name)
+	}
+	recognizers[info.Name] = info
+}
+
+// Recognizers returns the recognizers of the registry, by name.
+func Recognizers() []RecognizerInfo {
+	list := make([]RecognizerInfo, 0, len(recognizers))
+	for _, info := range recognizers {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// LookupRecognizer returns the recognizer named name.
+func LookupRecognizer(name string) (RecognizerInfo, error) {
+	info, ok := recognizers[name]
+	if !ok {
+		var names []string
+		for _, r := range Recognizers() {
+			names = append(names, r.Name)
+		}
+		return RecognizerInfo{}, fmt.Errorf("unknown recognizer %s, available: %s", name, strings.Join(names, ", "))
+	}
+	return info, nil
+}
+
+// RecognizeWords returns the words written by hand on a page, their
+// boxes being in device pixels.
+func RecognizeWords(r Recognizer, data *rmencoding.Rm) ([]Word, error) {
+	// the strokes in the colors of the device, in device pixels
+	words, err := r.Recognize(renderPage(data, 1, nil, nil, nil, false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to recognize the handwriting: %w", err)
+	}
+	return words, nil
+}
+
+// recognizeText returns the content writing the words recognized in
+// the strokes of a page as invisible text, in device pixels, so that
+// the viewers can search and select them.
+func recognizeText(r Recognizer, data *rmencoding.Rm) ([]byte, error) {
+	words, err := RecognizeWords(r, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	var b bytes.Buffer
+	// invisible
+	b.WriteString("BT 3 Tr\n")
+	font := fontResources[pdf.Helvetica]
+	for _, w := range words {
+		size := float64(w.Box.Dy())
+		width := pdf.TextWidth(w.Text, pdf.Helvetica, size)
+		if size <= 0 || width <= 0 {
+			continue
+		}
+		// stretched over the box, its baseline above the descenders
+		fmt.Fprintf(&b, "/%s %.2f Tf %.3f 0 0 -1 %d %.2f Tm %s Tj\n", font, size,
+			float64(w.Box.Dx())/width, w.Box.Min.X, float64(w.Box.Min.Y)+0.8*size, pdf.EncodeText(w.Text))
+	}
+	b.WriteString("ET\n")
+	return b.Bytes(), nil
+}
+
+// tesseract recognizes the words with the tesseract command, which
+// must be in the path.
+type tesseract struct {
+	language string
+}
+
+func (t tesseract) Recognize(img image.Image) ([]Word, error) {
+	tmp, err := os.CreateTemp("", "rmapi-ocr-*.png")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	if err := writePng(tmp.Name(), img); err != nil {
+		return nil, err
+	}
+
+	args := []string{tmp.Name(), "stdout"}
+	if t.language != "" {
+		args = append(args, "-l", t.language)
+	}
+	args = append(args, "tsv")
+	var stderr bytes.Buffer
+	cmd := exec.Command("tesseract", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("tesseract: %w %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseTesseract(out), nil
+}
+
+// parseTesseract reads the words of the tsv output of tesseract:
+// level, page, block, paragraph, line and word numbers, left, top,
+// width, height, confidence and text.
+func parseTesseract(out []byte) []Word {
+	var words []Word
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 12 || fields[0] != "5" {
+			continue
+		}
+		text := strings.TrimSpace(fields[11])
+		if text == "" {
+			continue
+		}
+		var box [4]int
+		valid := true
+		for i := range box {
+			v, err := strconv.Atoi(fields[6+i])
+			if err != nil {
+				valid = false
+				break
+			}
+			box[i] = v
+		}
+		if !valid {
+			continue
+		}
+		words = append(words, Word{Text: text, Box: image.Rect(box[0], box[1], box[0]+box[2], box[1]+box[3])})
+	}
+	return words
+}
@@ -0,0 +1,66 @@
+package annotations
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// fakeRecognizer finds a word over the strokes of every page.
+type fakeRecognizer struct{}
+
+func (fakeRecognizer) Recognize(img image.Image) ([]Word, error) {
+	return []Word{{Text: "handwriting", Box: image.Rect(100, 100, 400, 140)}}, nil
+}
+
+func init() {
+	RegisterRecognizer(RecognizerInfo{Name: "fake", New: func(string) Recognizer { return fakeRecognizer{} }})
+}
+
+func TestParseTesseract(t *testing.T) {
+	out := "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+		"1\t1\t0\t0\t0\t0\t0\t0\t1404\t1872\t-1\t\n" +
+		"5\t1\t1\t1\t1\t1\t120\t80\t200\t40\t91.5\tMeeting\n" +
+		"5\t1\t1\t1\t1\t2\t340\t82\t90\t38\t88\tnotes\n" +
+		"5\t1\t1\t1\t1\t3\t440\t82\t10\t38\t10\t \n"
+	words := parseTesseract([]byte(out))
+	if len(words) != 2 || words[0].Text != "Meeting" || words[1].Box != image.Rect(340, 82, 430, 120) {
+		t.Errorf("unexpected words %v", words)
+	}
+}
+
+func TestNativeOCR(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "strange.pdf")
+	generator := CreatePdfGenerator("testfiles/strange.zip", out, PdfGeneratorOptions{OCR: "fake", Renderer: "native"})
+	if err := generator.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := pdf.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := f.PageText(pages[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "handwriting") {
+		t.Errorf("the handwriting should be searchable, got %q", text)
+	}
+
+	generator = CreatePdfGenerator("testfiles/strange.zip", out, PdfGeneratorOptions{OCR: "unknown", Renderer: "native"})
+	if err := generator.Generate(); err == nil || !strings.Contains(err.Error(), "tesseract") {
+		t.Errorf("expected an unknown recognizer, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package annotations
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// PageNumberPositions are the positions of the page numbers, see
+// PageNumberStyle.
+var PageNumberPositions = []string{"bottom-right", "bottom-center", "bottom-left", "top-right", "top-center", "top-left"}
+
+// PageNumberStyle is the look of the numbers of AddPageNumbers. The
+// zero value draws the number alone, in 8pt at the bottom right.
+type PageNumberStyle struct {
+	// Position is one of PageNumberPositions, bottom-right when empty
+	Position string
+	// Size is the size of the font, 8 when 0
+	Size float64
+	// Format is the text of the numbers, with %d for the number and a
+	// second %d for the number of pages, as "Page %d of %d", "%d" when
+	// empty
+	Format string
+	// Offset is added to the numbers and to the number of pages, -1
+	// numbering the second page 1
+	Offset int
+	// SkipFirst leaves the first page without number
+	SkipFirst bool
+}
+
+// Check returns an error when the position or the format is invalid.
+func (s PageNumberStyle) Check() error {
+	if s.Position != "" {
+		valid := false
+		for _, p := range PageNumberPositions {
+			valid = valid || p == s.Position
+		}
+		if !valid {
+			return fmt.Errorf("unknown position %s of the page numbers, available: %s", s.Position, strings.Join(PageNumberPositions, ", "))
+		}
+	}
+	if s.Size < 0 {
+		return fmt.Errorf("invalid size %v of the page numbers", s.Size)
+	}
+	verbs := 0
+	for i := 0; i < len(s.Format); i++ {
+		if s.Format[i] != '%' {
+			continue
+		}
+		i++
+		switch {
+		case i < len(s.Format) && s.Format[i] == 'd':
+			verbs++
+		case i < len(s.Format) && s.Format[i] == '%':
+		default:
+			return fmt.Errorf("invalid format %q of the page numbers, expected %%d for the number and the number of pages", s.Format)
+		}
+	}
+	if verbs > 2 {
+		return fmt.Errorf("invalid format %q of the page numbers, with more than two %%d", s.Format)
+	}
+	return nil
+}
+
+// hasTotal returns whether the numbers show the number of pages, which
+// is then counted before the pages are drawn.
+func (s PageNumberStyle) hasTotal() bool {
+	return strings.Count(strings.ReplaceAll(s.Format, "%%", ""), "%d") > 1
+}
+
+// text returns the text of the number of a page from 1, false when the
+// page has none.
+func (s PageNumberStyle) text(number, total int) (string, bool) {
+	if s.SkipFirst && number == 1 {
+		return "", false
+	}
+	if s.Format == "" {
+		return fmt.Sprint(number + s.Offset), true
+	}
+	args := []interface{}{number + s.Offset, total + s.Offset}
+	if !s.hasTotal() {
+		args = args[:1]
+	}
+	return fmt.Sprintf(s.Format, args...), true
+}
+
+// size returns the size of the font.
+func (s PageNumberStyle) size() float64 {
+	if s.Size > 0 {
+		return s.Size
+	}
+	return 8
+}
+
+// place returns the start of the baseline of the text of a number on a
+// page of the given size, from its bottom left corner.
+func (s PageNumberStyle) place(text string, width, height float64) (x, y float64) {
+	const margin = 10
+	size := s.size()
+	textWidth := pdf.TextWidth(text, pdf.Helvetica, size)
+	position := s.Position
+	if position == "" {
+		position = "bottom-right"
+	}
+	vertical, horizontal, _ := strings.Cut(position, "-")
+
+	y = margin
+	if vertical == "top" {
+		y = height - margin - size
+	}
+	switch horizontal {
+	case "left":
+		x = 2 * margin
+	case "center":
+		x = (width - textWidth) / 2
+	default:
+		x = width - 1.5*margin - textWidth
+	}
+	return x, y
+}
+
+// drawPageNumber returns the content drawing the number of a page in
+// the user space of the page, display being its matrix, see
+// pdf.File.DisplayMatrix.
+func drawPageNumber(style PageNumberStyle, display pdf.Matrix, width, height float64, number, total int) []byte {
+	text, ok := style.text(number, total)
+	if !ok {
+		return nil
+	}
+	x, y := style.place(text, width, height)
+	return []byte(fmt.Sprintf("q %s cm BT /%s %.2f Tf 0 g %.2f %.2f Td %s Tj ET Q\n",
+		display, fontResources[pdf.Helvetica], style.size(), x, y, pdf.EncodeText(text)))
+}
@@ -0,0 +1,100 @@
+package annotations
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joagonca/rmapi/pdf"
+)
+
+func TestPageNumberStyle(t *testing.T) {
+	for _, style := range []PageNumberStyle{
+		{},
+		{Position: "top-center", Size: 12, Format: "Page %d of %d", Offset: -1, SkipFirst: true},
+		{Format: "%d %%"},
+	} {
+		if err := style.Check(); err != nil {
+			t.Errorf("%+v: %v", style, err)
+		}
+	}
+	for _, style := range []PageNumberStyle{
+		{Position: "middle"},
+		{Size: -1},
+		{Format: "Page %s"},
+		{Format: "%d/%d/%d"},
+		{Format: "Page %"},
+	} {
+		if err := style.Check(); err == nil {
+			t.Errorf("%+v: expected an error", style)
+		}
+	}
+
+	for _, tc := range []struct {
+		style  PageNumberStyle
+		number int
+		want   string
+	}{
+		{PageNumberStyle{}, 3, "3"},
+		{PageNumberStyle{Format: "- %d -"}, 3, "- 3 -"},
+		{PageNumberStyle{Format: "Page %d of %d"}, 3, "Page 3 of 10"},
+		{PageNumberStyle{Format: "%d%% of %d", Offset: -1}, 3, "2% of 9"},
+		{PageNumberStyle{SkipFirst: true, Offset: -1}, 2, "1"},
+		{PageNumberStyle{SkipFirst: true}, 1, ""},
+	} {
+		if got, _ := tc.style.text(tc.number, 10); got != tc.want {
+			t.Errorf("%+v: expected %q, got %q", tc.style, tc.want, got)
+		}
+	}
+
+	width, height := 500.0, 800.0
+	right, bottom := PageNumberStyle{}.place("12", width, height)
+	if right < width/2 || right+pdf.TextWidth("12", pdf.Helvetica, 8) > width || bottom > height/2 {
+		t.Errorf("expected the number at the bottom right, got %v,%v", right, bottom)
+	}
+	center, top := PageNumberStyle{Position: "top-center"}.place("12", width, height)
+	if center >= right || top < height/2 || top > height {
+		t.Errorf("expected the number at the top center, got %v,%v", center, top)
+	}
+}
+
+func TestNativePageNumbers(t *testing.T) {
+	// the pages without annotations are counted out of the total
+	out := filepath.Join(t.TempDir(), "strange.pdf")
+	options := PdfGeneratorOptions{
+		AddPageNumbers:  true,
+		AnnotationsOnly: true,
+		PageNumbers:     PageNumberStyle{Format: "Page %d of %d", Offset: -1, SkipFirst: true},
+		Renderer:        "native",
+	}
+	if err := CreatePdfGenerator("testfiles/strange.zip", out, options).Generate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := pdf.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("expected the 2 annotated pages, got %d", len(pages))
+	}
+	if content := pageContent(t, f, pages[0]); strings.Contains(content, "(Page") {
+		t.Errorf("the first page should have no number: %q", content)
+	}
+	if content := pageContent(t, f, pages[1]); !strings.Contains(content, "(Page 1 of 1) Tj") {
+		t.Errorf("expected the number of the second page in %q", content)
+	}
+
+	options.PageNumbers.Position = "middle"
+	if err := CreatePdfGenerator("testfiles/strange.zip", out, options).Generate(); err == nil {
+		t.Error("expected an error for the position")
+	}
+}
@@ -0,0 +1,64 @@
+package annotations
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A PageRange is a range of pages, numbered from 1, Last included.
+type PageRange struct {
+	First, Last int
+}
+
+// PageRanges select the exported pages, all of them when empty.
+type PageRanges []PageRange
+
+// ParsePageRanges parses a list of pages and ranges of pages separated
+// by commas, such as "3-10,15". A range without end, such as "20-", goes
+// to the last page.
+func ParsePageRanges(s string) (PageRanges, error) {
+	var ranges PageRanges
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+		r := PageRange{}
+		var err error
+		if r.First, err = strconv.Atoi(strings.TrimSpace(first)); err != nil || r.First < 1 {
+			return nil, fmt.Errorf("invalid page %q", part)
+		}
+		switch {
+		case !isRange:
+			r.Last = r.First
+		case strings.TrimSpace(last) == "":
+			r.Last = math.MaxInt
+		default:
+			if r.Last, err = strconv.Atoi(strings.TrimSpace(last)); err != nil || r.Last < r.First {
+				return nil, fmt.Errorf("invalid range of pages %q", part)
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no pages in %q", s)
+	}
+	return ranges, nil
+}
+
+// Contains returns whether a page, numbered from 1, is selected.
+func (ranges PageRanges) Contains(page int) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if page >= r.First && page <= r.Last {
+			return true
+		}
+	}
+	return false
+}
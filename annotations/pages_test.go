@@ -0,0 +1,24 @@
+package annotations
+
+import "testing"
+
+func TestParsePageRanges(t *testing.T) {
+	ranges, err := ParsePageRanges("3-10, 15,20-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for page, want := range map[int]bool{1: false, 3: true, 10: true, 11: false, 15: true, 19: false, 400: true} {
+		if ranges.Contains(page) != want {
+			t.Errorf("page %d: expected %v", page, want)
+		}
+	}
+	if !PageRanges(nil).Contains(7) {
+		t.Error("all the pages should be selected without ranges")
+	}
+
+	for _, s := range []string{"", "0", "a-3", "5-2", "3-x", ","} {
+		if _, err := ParsePageRanges(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
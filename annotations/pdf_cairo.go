@@ -1,20 +1,25 @@
+//go:build cairo
 // +build cairo
 
 package annotations
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"unsafe"
 
 	"github.com/joagonca/rmapi/archive"
 	rmencoding "github.com/joagonca/rmapi/encoding/rm"
 	"github.com/joagonca/rmapi/log"
+	"github.com/joagonca/rmapi/pdf"
 	"github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
 	"github.com/ungerik/go-cairo"
 )
 
@@ -26,30 +31,37 @@ import (
 */
 import "C"
 
-const (
-	DeviceWidth  = 1404
-	DeviceHeight = 1872
-)
+var logger = log.For(log.Annotations)
 
-// rmPageSize is the default page size for blank templates (in PDF points: 1/72 inch)
-var rmPageSize = struct{ Width, Height float64 }{445, 594}
+func init() {
+	RegisterRenderer(RendererInfo{
+		Name:         "cairo",
+		Description:  "draws the annotations with cairo and merges them with the original PDF with pdfcpu",
+		Capabilities: Capabilities{Color: true, Textures: true, Layers: true},
+		New:          func() Renderer { return &cairoRenderer{} },
+	})
+	defaultRenderer = "cairo"
+}
 
-type PdfGenerator struct {
+type cairoRenderer struct {
 	zipName        string
 	outputFilePath string
 	options        PdfGeneratorOptions
-	backgroundPDF  []byte
-	template       bool
+	// background is the original PDF, decrypted, in a temporary file
+	background *os.File
+	// backgroundSizes are the sizes of the pages of background, as
+	// they are shown
+	backgroundSizes [][2]float64
+	template        bool
+	files           []string
 }
 
-type PdfGeneratorOptions struct {
-	AddPageNumbers  bool
-	AllPages        bool
-	AnnotationsOnly bool //export the annotations without the background/pdf
-}
-
-func CreatePdfGenerator(zipName, outputFilePath string, options PdfGeneratorOptions) *PdfGenerator {
-	return &PdfGenerator{zipName: zipName, outputFilePath: outputFilePath, options: options}
+func (p *cairoRenderer) Render(zipName, outputFilePath string, options PdfGeneratorOptions) ([]string, error) {
+	p.zipName, p.outputFilePath, p.options = zipName, outputFilePath, options
+	if err := p.generateAll(); err != nil {
+		return nil, err
+	}
+	return p.files, nil
 }
 
 func normalized(p1 rmencoding.Point, scale float64) (float64, float64) {
@@ -62,7 +74,54 @@ func setPDFPageSize(surface *cairo.Surface, width, height float64) {
 	C.cairo_pdf_surface_set_size((*C.cairo_surface_t)(unsafe.Pointer(surfacePtr)), C.double(width), C.double(height))
 }
 
-func (p *PdfGenerator) Generate() error {
+func (p *cairoRenderer) generateAll() error {
+	if err := p.generate(); err != nil {
+		return err
+	}
+	p.files = []string{p.outputFilePath}
+	if p.options.SplitEvery > 0 {
+		return p.split()
+	}
+	return nil
+}
+
+// split cuts the output into files of SplitEvery pages, named by SplitName.
+func (p *cairoRenderer) split() error {
+	defer os.Remove(p.outputFilePath)
+
+	count, err := api.PageCountFile(p.outputFilePath)
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "rmapi-split")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := api.SplitFile(p.outputFilePath, dir, p.options.SplitEvery, model.NewDefaultConfiguration()); err != nil {
+		return fmt.Errorf("failed to split PDF: %w", err)
+	}
+
+	// pdfcpu names the parts name_from-thru.pdf
+	base := strings.TrimSuffix(filepath.Base(p.outputFilePath), ".pdf")
+	p.files = nil
+	for from := 1; from <= count; from += p.options.SplitEvery {
+		thru := min(from+p.options.SplitEvery-1, count)
+		name := fmt.Sprintf("%s_%d-%d.pdf", base, from, thru)
+		if from == thru {
+			name = fmt.Sprintf("%s_%d.pdf", base, from)
+		}
+		dst := SplitName(p.outputFilePath, len(p.files)+1)
+		if err := copyFile(filepath.Join(dir, name), dst); err != nil {
+			return err
+		}
+		p.files = append(p.files, dst)
+	}
+	return nil
+}
+
+func (p *cairoRenderer) generate() error {
 	file, err := os.Open(p.zipName)
 	if err != nil {
 		return err
@@ -76,7 +135,8 @@ func (p *PdfGenerator) Generate() error {
 		return err
 	}
 
-	err = zip.Read(file, fi.Size())
+	// the drawings and the original PDF are read when needed
+	err = zip.ReadLazy(file, fi.Size())
 	if err != nil {
 		return err
 	}
@@ -85,95 +145,128 @@ func (p *PdfGenerator) Generate() error {
 		return errors.New("only pdf and notebooks supported")
 	}
 
-	if err = p.initBackgroundPages(zip.Payload); err != nil {
+	payload, err := extractPayload(zip)
+	if err != nil {
 		return err
 	}
+	if payload != nil {
+		defer os.Remove(payload.Name())
+		defer payload.Close()
+	}
+	if err = p.initBackgroundPages(payload); err != nil {
+		return err
+	}
+	if p.background != nil && p.background != payload {
+		defer os.Remove(p.background.Name())
+		defer p.background.Close()
+	}
 
 	if len(zip.Pages) == 0 {
 		return errors.New("the document has no pages")
 	}
 
 	// If we have a background PDF and not annotations-only mode, we need a two-step process
-	if p.backgroundPDF != nil && !p.options.AnnotationsOnly {
+	if p.background != nil && !p.options.AnnotationsOnly {
 		return p.generateWithBackground(zip)
 	}
 
 	// Otherwise, simple case: just annotations or blank pages
-	return p.generateAnnotationsOnly(zip)
+	_, err = p.writeAnnotations(zip, p.outputFilePath, false)
+	return err
 }
 
-func (p *PdfGenerator) generateAnnotationsOnly(zip *archive.Zip) error {
+// writeAnnotations writes the pages of the annotations to path and
+// returns the pages of the document which have strokes. With
+// everyPage, each page of the document has its page in the same order,
+// so that they can be stamped on the original PDF, see
+// generateWithBackground.
+func (p *cairoRenderer) writeAnnotations(zip *archive.Zip, path string, everyPage bool) ([]bool, error) {
 	// Create a temporary file for PDF output (Cairo requires a file path)
 	tmpFile, err := os.CreateTemp("", "rmapi-annotations-*.pdf")
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
 	}
 	tmpPath := tmpFile.Name()
 	tmpFile.Close()
 	defer os.Remove(tmpPath)
 
-	// Determine first page dimensions
-	var firstWidth, firstHeight float64
-	if p.template {
-		firstWidth, firstHeight = rmPageSize.Width, rmPageSize.Height
-	} else {
-		// TODO: Get dimensions from background PDF
-		firstWidth, firstHeight = rmPageSize.Width, rmPageSize.Height
+	// Create PDF surface, each page is sized before being drawn
+	pdfSurface := cairo.NewPDFSurface(tmpPath, rmPageSize.Width, rmPageSize.Height, cairo.PDF_VERSION_1_5)
+	defer pdfSurface.Finish()
+
+	total := 0
+	for index := range zip.Pages {
+		if everyPage || p.options.Pages.Contains(index+1) {
+			total++
+		}
 	}
 
-	// Create PDF surface
-	pdfSurface := cairo.NewPDFSurface(tmpPath, firstWidth, firstHeight, cairo.PDF_VERSION_1_5)
-	defer pdfSurface.Finish()
+	numbers := 0
+	if p.options.AddPageNumbers && p.options.PageNumbers.hasTotal() {
+		if numbers, err = p.countPages(zip, everyPage); err != nil {
+			return nil, err
+		}
+	}
 
-	pageCount := 0
-	for _, pageAnnotations := range zip.Pages {
+	drawn := make([]bool, len(zip.Pages))
+	pageCount, read := 0, 0
+	for index, pageAnnotations := range zip.Pages {
+		if !everyPage && !p.options.Pages.Contains(index+1) {
+			continue
+		}
+		// one drawing in memory at a time
+		data, err := zip.PageDrawing(index)
+		if err != nil {
+			return nil, err
+		}
+		read++
+		p.options.progress(read, total)
+		pageAnnotations.Data = p.options.Layers.Apply(data)
 		hasContent := pageAnnotations.Data != nil
+		if m, ok := zip.Content.Transform.Matrix(); ok {
+			pageAnnotations.Highlights = transformHighlights(pageAnnotations.Highlights, m)
+		}
+		hasHighlights := len(pageAnnotations.Highlights) > 0
+		drawn[index] = hasContent || hasHighlights
 
 		// Skip pages without content unless AllPages is set
-		if !p.options.AllPages && !hasContent {
+		if !everyPage && !p.options.AllPages && !hasContent && !hasHighlights {
 			continue
 		}
 
 		pageCount++
 
-		// Set page size (for pages after the first)
-		if pageCount > 1 {
-			var pageWidth, pageHeight float64
-			if p.template {
-				pageWidth, pageHeight = rmPageSize.Width, rmPageSize.Height
-			} else {
-				// TODO: Get dimensions from background PDF page
-				pageWidth, pageHeight = rmPageSize.Width, rmPageSize.Height
-			}
-			setPDFPageSize(pdfSurface, pageWidth, pageHeight)
-		}
+		pageWidth, pageHeight := p.pageSize(pageAnnotations)
+		setPDFPageSize(pdfSurface, pageWidth, pageHeight)
 
 		// Calculate scale
-		pageWidth := firstWidth
-		pageHeight := firstHeight
 		ratio := pageHeight / pageWidth
 
+		screenWidth, screenHeight := pageAnnotations.Data.ScreenSize()
 		var scale float64
-		if ratio < 1.33 {
-			scale = pageWidth / DeviceWidth
+		if ratio < float64(screenHeight)/float64(screenWidth) {
+			scale = pageWidth / float64(screenWidth)
 		} else {
-			scale = pageHeight / DeviceHeight
+			scale = pageHeight / float64(screenHeight)
 		}
 
+		// the highlights of the text, under the strokes
+		p.drawHighlights(pdfSurface, pageAnnotations.Highlights, scale, pageHeight)
+
 		// Draw annotations if present
 		if hasContent {
 			if err := p.drawAnnotations(pdfSurface, pageAnnotations.Data, scale, pageHeight); err != nil {
-				return err
+				return nil, err
 			}
 		}
 
 		// Add page numbers if requested
 		if p.options.AddPageNumbers {
-			p.drawPageNumber(pdfSurface, pageCount, pageWidth, pageHeight)
+			p.drawPageNumber(pdfSurface, pageCount, numbers, pageWidth, pageHeight)
 		}
 
 		// Show page (prepare for next page)
-		if pageCount < len(zip.Pages) || p.options.AllPages {
+		if pageCount < len(zip.Pages) || p.options.AllPages || everyPage {
 			pdfSurface.ShowPage()
 		}
 	}
@@ -181,10 +274,13 @@ func (p *PdfGenerator) generateAnnotationsOnly(zip *archive.Zip) error {
 	pdfSurface.Finish()
 
 	// Copy temp file to final destination
-	return copyFile(tmpPath, p.outputFilePath)
+	return drawn, copyFile(tmpPath, path)
 }
 
-func (p *PdfGenerator) generateWithBackground(zip *archive.Zip) error {
+// generateWithBackground stamps the pages of the annotations on the
+// pages of the original PDF, which keeps its outlines, links and
+// metadata. The pages not selected are trimmed afterwards.
+func (p *cairoRenderer) generateWithBackground(zip *archive.Zip) error {
 	// Step 1: Create annotations-only PDF with transparent background
 	tmpAnnotations, err := os.CreateTemp("", "rmapi-annotations-*.pdf")
 	if err != nil {
@@ -194,56 +290,126 @@ func (p *PdfGenerator) generateWithBackground(zip *archive.Zip) error {
 	tmpAnnotations.Close()
 	defer os.Remove(tmpAnnotationsPath)
 
-	// Generate annotations PDF
-	if err := p.generateAnnotationsOnly(zip); err != nil {
+	drawn, err := p.writeAnnotations(zip, tmpAnnotationsPath, true)
+	if err != nil {
 		return err
 	}
-
-	// Step 2: Write background PDF to temp file
-	tmpBackground, err := os.CreateTemp("", "rmapi-background-*.pdf")
+	annotationsPDF, err := os.Open(tmpAnnotationsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read annotations PDF: %w", err)
+	}
+	defer annotationsPDF.Close()
+	info, err := annotationsPDF.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to create temp background file: %w", err)
+		return fmt.Errorf("failed to read annotations PDF: %w", err)
+	}
+
+	// The pages of the document in order, as pages of the original PDF.
+	// They differ from the pages of the PDF when pages were moved or
+	// deleted on the device, and the pages inserted on the device have
+	// no page of the PDF to be stamped on.
+	var selected []string
+	inOrder := len(zip.Pages) == len(p.backgroundSizes)
+	inserted := 0
+	for index, page := range zip.Pages {
+		if page.DocPage != index {
+			inOrder = false
+		}
+		if !p.options.Pages.Contains(index + 1) {
+			continue
+		}
+		if page.DocPage < 0 {
+			inserted++
+			continue
+		}
+		selected = append(selected, strconv.Itoa(page.DocPage+1))
+	}
+	if inserted > 0 {
+		logger.Warning.Printf("%d pages inserted on the device are left out, the native renderer exports them", inserted)
 	}
-	tmpBackgroundPath := tmpBackground.Name()
-	if _, err := tmpBackground.Write(p.backgroundPDF); err != nil {
-		tmpBackground.Close()
-		os.Remove(tmpBackgroundPath)
-		return fmt.Errorf("failed to write background PDF: %w", err)
+
+	// Step 2: A stamp for each annotated page, on top of the page of
+	// the original PDF it annotates, aligned on its top left corner.
+	// The stamps read the file of the annotations when they are added,
+	// each from its own section reader.
+	stamps := map[int][]*model.Watermark{}
+	for index, page := range zip.Pages {
+		if !drawn[index] || page.DocPage < 0 || !p.options.Pages.Contains(index+1) {
+			continue
+		}
+		wm, err := api.PDFWatermarkForReadSeeker(io.NewSectionReader(annotationsPDF, 0, info.Size()), index+1,
+			"pos:tl, off:0 0, scale:1 abs, rot:0", true, false, types.POINTS)
+		if err != nil {
+			return fmt.Errorf("failed to stamp the annotations: %w", err)
+		}
+		stamps[page.DocPage+1] = append(stamps[page.DocPage+1], wm)
 	}
-	tmpBackground.Close()
-	defer os.Remove(tmpBackgroundPath)
 
-	// Step 3: Merge background and annotations using pdfcpu
 	outFile, err := os.Create(p.outputFilePath)
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
 	defer outFile.Close()
 
-	// Open both PDFs as ReadSeekers
-	bgFile, err := os.Open(tmpBackgroundPath)
-	if err != nil {
-		return fmt.Errorf("failed to open background PDF: %w", err)
+	// the stamped PDF is written to the output, or to a temporary file
+	// when the pages are selected or reordered afterwards
+	stamped := outFile
+	if len(p.options.Pages) > 0 || !inOrder {
+		if stamped, err = os.CreateTemp("", "rmapi-stamped-*.pdf"); err != nil {
+			return fmt.Errorf("failed to create temp file: %w", err)
+		}
+		defer os.Remove(stamped.Name())
+		defer stamped.Close()
 	}
-	defer bgFile.Close()
 
-	annFile, err := os.Open(tmpAnnotationsPath)
+	background, err := p.backgroundReader()
 	if err != nil {
-		return fmt.Errorf("failed to open annotations PDF: %w", err)
+		return err
 	}
-	defer annFile.Close()
-
-	// Merge: background first, then overlay annotations
 	conf := model.NewDefaultConfiguration()
-	rsc := []io.ReadSeeker{bgFile, annFile}
-	if err := api.MergeRaw(rsc, outFile, false, conf); err != nil {
-		return fmt.Errorf("failed to merge PDFs: %w", err)
+	if len(stamps) == 0 {
+		if _, err := io.Copy(stamped, background); err != nil {
+			return err
+		}
+	} else {
+		// the stamps are in the layer of the annotations, which the
+		// viewers can hide
+		conf.Cmd = model.ADDWATERMARKS
+		ctx, err := api.ReadValidateAndOptimize(background, conf)
+		if err != nil {
+			return fmt.Errorf("failed to read the PDF: %w", err)
+		}
+		if err := addStamps(ctx, stamps); err != nil {
+			return fmt.Errorf("failed to stamp the annotations: %w", err)
+		}
+		if err := api.Write(ctx, stamped, conf); err != nil {
+			return err
+		}
+	}
+	if stamped == outFile {
+		return nil
 	}
 
+	// Step 3: Keep the selected pages, in the order of the document
+	if _, err := stamped.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := api.Collect(stamped, outFile, selected, conf); err != nil {
+		return fmt.Errorf("failed to select the pages: %w", err)
+	}
 	return nil
 }
 
-func (p *PdfGenerator) drawAnnotations(surface *cairo.Surface, rmData *rmencoding.Rm, scale, pageHeight float64) error {
+// backgroundReader returns a reader of the whole original PDF.
+func (p *cairoRenderer) backgroundReader() (io.ReadSeeker, error) {
+	info, err := p.background.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(p.background, 0, info.Size()), nil
+}
+
+func (p *cairoRenderer) drawAnnotations(surface *cairo.Surface, rmData *rmencoding.Rm, scale, pageHeight float64) error {
 	surface.Save()
 	defer surface.Restore()
 
@@ -256,7 +422,7 @@ func (p *PdfGenerator) drawAnnotations(surface *cairo.Surface, rmData *rmencodin
 				continue
 			}
 
-			if line.BrushType == rmencoding.HighlighterV5 {
+			if line.BrushType == rmencoding.Highlighter || line.BrushType == rmencoding.HighlighterV5 {
 				// Draw highlighter as semi-transparent rectangle
 				p.drawHighlighter(surface, line, scale, pageHeight)
 			} else {
@@ -269,7 +435,7 @@ func (p *PdfGenerator) drawAnnotations(surface *cairo.Surface, rmData *rmencodin
 	return nil
 }
 
-func (p *PdfGenerator) drawHighlighter(surface *cairo.Surface, line rmencoding.Line, scale, pageHeight float64) {
+func (p *cairoRenderer) drawHighlighter(surface *cairo.Surface, line rmencoding.Line, scale, pageHeight float64) {
 	if len(line.Points) < 2 {
 		return
 	}
@@ -285,8 +451,9 @@ func (p *PdfGenerator) drawHighlighter(surface *cairo.Surface, line rmencoding.L
 	// Convert Y coordinate (Cairo origin is top-left, PDF is bottom-left)
 	y := pageHeight - y1
 
-	// Yellow color with 50% opacity
-	surface.SetSourceRGBA(1.0, 1.0, 0.0, 0.5)
+	// 50% opacity
+	r, g, b := rgb(lineColor(line, p.options.Colors, p.options.HighlightColors))
+	surface.SetSourceRGBA(r, g, b, 0.5)
 	surface.SetLineWidth(width)
 	surface.SetLineCap(cairo.LINE_CAP_BUTT)
 
@@ -295,94 +462,241 @@ func (p *PdfGenerator) drawHighlighter(surface *cairo.Surface, line rmencoding.L
 	surface.Stroke()
 }
 
-func (p *PdfGenerator) drawStroke(surface *cairo.Surface, line rmencoding.Line, scale, pageHeight float64) {
-	if len(line.Points) < 1 {
-		return
-	}
+// drawHighlights draws the highlights of the text of a page, stored
+// apart from the strokes, as half transparent rectangles.
+func (p *cairoRenderer) drawHighlights(surface *cairo.Surface, highlights []archive.Highlight, scale, pageHeight float64) {
+	surface.Save()
+	defer surface.Restore()
 
-	// Set stroke color
-	var r, g, b float64
-	switch line.BrushColor {
-	case rmencoding.Black:
-		r, g, b = 0.0, 0.0, 0.0
-	case rmencoding.White:
-		r, g, b = 1.0, 1.0, 1.0
-	case rmencoding.Grey:
-		r, g, b = 0.5, 0.5, 0.5
-	default:
-		r, g, b = 0.0, 0.0, 0.0
+	for _, h := range highlights {
+		r, g, b := rgb(highlightColor(rmencoding.BrushColor(h.Color), p.options.HighlightColors))
+		surface.SetSourceRGBA(r, g, b, 0.5)
+		for _, rect := range h.Rects {
+			// Convert Y coordinate, like the strokes
+			surface.Rectangle(rect.X*scale, pageHeight-(rect.Y+rect.Height)*scale, rect.Width*scale, rect.Height*scale)
+		}
+		surface.Fill()
 	}
-	surface.SetSourceRGB(r, g, b)
+}
 
-	// Set stroke width
-	// Formula from original: line.BrushSize*6.0 - 10.8
-	strokeWidth := float64(line.BrushSize)*6.0 - 10.8
-	if strokeWidth < 0.5 {
-		strokeWidth = 0.5
+func (p *cairoRenderer) drawStroke(surface *cairo.Surface, line rmencoding.Line, scale, pageHeight float64) {
+	if len(line.Points) < 1 {
+		return
 	}
-	surface.SetLineWidth(strokeWidth)
 
-	// Set line cap
-	surface.SetLineCap(cairo.LINE_CAP_ROUND)
+	r, g, b := rgb(lineColor(line, p.options.Colors, p.options.HighlightColors))
 	surface.SetLineJoin(cairo.LINE_JOIN_ROUND)
 
-	// Draw path
-	for i, point := range line.Points {
-		x, y := normalized(point, scale)
-		// Convert Y coordinate
-		y = pageHeight - y
+	// the brushes have their own width, opacity and tip, see
+	// brushStrokes
+	for _, stroke := range brushStrokes(line, p.options.Widths.line(line)/scale) {
+		surface.SetSourceRGBA(r, g, b, stroke.opacity)
 
-		if i == 0 {
-			surface.MoveTo(x, y)
-		} else {
-			surface.LineTo(x, y)
+		if stroke.outline != nil {
+			// a variable width
+			if p.options.Smooth {
+				p.curvePath(surface, stroke.outline, true, scale, pageHeight)
+			} else {
+				for i, v := range stroke.outline {
+					x, y := v.x*scale, pageHeight-v.y*scale
+					if i == 0 {
+						surface.MoveTo(x, y)
+					} else {
+						surface.LineTo(x, y)
+					}
+				}
+			}
+			surface.ClosePath()
+			surface.Fill()
+			continue
 		}
+
+		surface.SetLineWidth(stroke.width * scale)
+		surface.SetLineCap(cairoLineCaps[stroke.cap])
+
+		if p.options.Smooth && len(stroke.points) > 2 {
+			points := make([]vec, len(stroke.points))
+			for i, point := range stroke.points {
+				points[i] = pointVec(point)
+			}
+			p.curvePath(surface, points, false, scale, pageHeight)
+			surface.Stroke()
+			continue
+		}
+
+		for i, point := range stroke.points {
+			x, y := normalized(point, scale)
+			// Convert Y coordinate
+			y = pageHeight - y
+
+			if i == 0 {
+				surface.MoveTo(x, y)
+			} else {
+				surface.LineTo(x, y)
+			}
+		}
+		if len(stroke.points) == 1 {
+			// a dot
+			x, y := normalized(stroke.points[0], scale)
+			surface.LineTo(x, pageHeight-y)
+		}
+
+		surface.Stroke()
 	}
+}
 
-	surface.Stroke()
+// curvePath adds the curves through points, in device pixels, to the
+// path of the surface, see smoothPath.
+func (p *cairoRenderer) curvePath(surface *cairo.Surface, points []vec, closed bool, scale, pageHeight float64) {
+	surface.MoveTo(points[0].x*scale, pageHeight-points[0].y*scale)
+	for _, c := range smoothPath(points, closed) {
+		surface.CurveTo(
+			c.c1.x*scale, pageHeight-c.c1.y*scale,
+			c.c2.x*scale, pageHeight-c.c2.y*scale,
+			c.end.x*scale, pageHeight-c.end.y*scale)
+	}
 }
 
-func (p *PdfGenerator) drawPageNumber(surface *cairo.Surface, pageNum int, pageWidth, pageHeight float64) {
+// pageSize returns the size of the page of the annotations of a page:
+// the one of its page of the original PDF, or the one of the pages of
+// the notebooks.
+func (p *cairoRenderer) pageSize(page archive.Page) (float64, float64) {
+	if !p.template && page.DocPage >= 0 && page.DocPage < len(p.backgroundSizes) {
+		size := p.backgroundSizes[page.DocPage]
+		return size[0], size[1]
+	}
+	return rmPageSize.Width, rmPageSize.Height
+}
+
+var cairoLineCaps = map[lineCap]cairo.LineCap{
+	capButt:   cairo.LINE_CAP_BUTT,
+	capRound:  cairo.LINE_CAP_ROUND,
+	capSquare: cairo.LINE_CAP_SQUARE,
+}
+
+// drawPageNumber draws the number of a page out of total, see
+// PageNumberStyle.
+func (p *cairoRenderer) drawPageNumber(surface *cairo.Surface, pageNum, total int, pageWidth, pageHeight float64) {
+	style := p.options.PageNumbers
+	text, ok := style.text(pageNum, total)
+	if !ok {
+		return
+	}
 	surface.Save()
 	defer surface.Restore()
 
 	surface.SelectFontFace("sans-serif", cairo.FONT_SLANT_NORMAL, cairo.FONT_WEIGHT_NORMAL)
-	surface.SetFontSize(8.0)
+	surface.SetFontSize(style.size())
 	surface.SetSourceRGB(0, 0, 0)
 
-	text := fmt.Sprintf("%d", pageNum)
-	surface.MoveTo(pageWidth-20, pageHeight-10)
+	// the y axis of cairo goes down
+	x, y := style.place(text, pageWidth, pageHeight)
+	surface.MoveTo(x, pageHeight-y)
 	surface.ShowText(text)
 }
 
-func (p *PdfGenerator) initBackgroundPages(pdfArr []byte) error {
-	if len(pdfArr) > 0 {
-		// Check if PDF is encrypted and decrypt if necessary
-		rs := bytes.NewReader(pdfArr)
-		ctx, err := api.ReadContext(rs, model.NewDefaultConfiguration())
+// countPages returns the number of pages written by writeAnnotations,
+// reading the drawings of the pages only kept with strokes or
+// highlights.
+func (p *cairoRenderer) countPages(zip *archive.Zip, everyPage bool) (int, error) {
+	count := 0
+	for index := range zip.Pages {
+		if everyPage || p.options.AllPages {
+			if everyPage || p.options.Pages.Contains(index+1) {
+				count++
+			}
+			continue
+		}
+		if !p.options.Pages.Contains(index + 1) {
+			continue
+		}
+		data, err := zip.PageDrawing(index)
 		if err != nil {
-			return fmt.Errorf("failed to read PDF: %w", err)
+			return 0, err
 		}
-
-		// Check if encrypted by checking if Encrypt field exists
-		if ctx.XRefTable.Encrypt != nil {
-			log.Info.Println("PDF is encrypted - pdfcpu will handle decryption")
-			// pdfcpu's ReadContext already handles decryption with empty password
+		if p.options.Layers.Apply(data) != nil || len(zip.Pages[index].Highlights) > 0 {
+			count++
 		}
+	}
+	return count, nil
+}
 
-		p.backgroundPDF = pdfArr
-		p.template = false
+// initBackgroundPages reads the sizes of the pages of the original
+// PDF, in the file payload, nil for the notebooks. The encrypted PDFs
+// are decrypted into another temporary file.
+func (p *cairoRenderer) initBackgroundPages(payload *os.File) error {
+	if payload == nil {
+		p.template = true
 		return nil
 	}
+	info, err := payload.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		p.template = true
+		return nil
+	}
+
+	// Check if PDF is encrypted and decrypt if necessary
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = p.options.Password
+	conf.OwnerPW = p.options.Password
+	ctx, err := api.ReadContext(io.NewSectionReader(payload, 0, info.Size()), conf)
+	if err != nil {
+		if p.options.Password == "" {
+			return fmt.Errorf("failed to read PDF: %w", err)
+		}
+		return fmt.Errorf("failed to read PDF, wrong password? %w", err)
+	}
+
+	// the stamps are added to the decrypted PDF
+	background := payload
+	if ctx.XRefTable.Encrypt != nil {
+		logger.Info.Println("PDF is encrypted, decrypting it")
+		if background, err = decryptPayload(payload, p.options.Password); err != nil {
+			return err
+		}
+		if info, err = background.Stat(); err != nil {
+			background.Close()
+			os.Remove(background.Name())
+			return err
+		}
+	}
+	p.background = background
 
-	p.template = true
+	f, err := pdf.OpenReader(background, info.Size())
+	if err != nil {
+		return fmt.Errorf("failed to read PDF: %w", err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		return fmt.Errorf("failed to read PDF: %w", err)
+	}
+	p.backgroundSizes = make([][2]float64, len(pages))
+	for i, page := range pages {
+		width, height := f.DisplaySize(page)
+		p.backgroundSizes[i] = [2]float64{width, height}
+	}
+	p.template = false
 	return nil
 }
 
+// copyFile copies src to dst without reading it in memory.
 func copyFile(src, dst string) error {
-	data, err := os.ReadFile(src)
+	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(dst, data, 0644)
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
@@ -0,0 +1,881 @@
+package annotations
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/joagonca/rmapi/archive"
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/pdf"
+)
+
+func init() {
+	RegisterRenderer(RendererInfo{
+		Name:         "native",
+		Description:  "written in Go, draws the strokes over the untouched pages of the original PDF",
+		Capabilities: Capabilities{Color: true, Textures: true, Layers: true, Streaming: true, Portable: true, OCR: true, Watermark: true},
+		New:          func() Renderer { return &nativeRenderer{} },
+	})
+}
+
+// nativeRenderer exports the annotations of a document as a PDF. This
+// implementation is written in Go only; the strokes are drawn over the
+// pages of the original PDF, which are kept as they are.
+type nativeRenderer struct {
+	zipName        string
+	outputFilePath string
+	options        PdfGeneratorOptions
+	files          []string
+	// landscape is set for the documents shown in landscape
+	landscape bool
+	// epub is set for the books, drawn on their pages as paginated by
+	// the device when the archive has them
+	epub bool
+	// recognizer finds the handwriting of the pages, with options.OCR
+	recognizer Recognizer
+	// total is the number of pages exported, counted when the page
+	// numbers show it
+	total int
+	// watermark is stamped on the pages, with options.Watermark
+	watermark *watermark
+}
+
+func (p *nativeRenderer) Render(zipName, outputFilePath string, options PdfGeneratorOptions) ([]string, error) {
+	p.zipName, p.outputFilePath, p.options = zipName, outputFilePath, options
+	if err := p.generate(); err != nil {
+		return nil, err
+	}
+	return p.files, nil
+}
+
+// chunkPages is the number of pages drawn before their content is
+// written to the output and released from memory.
+const chunkPages = 50
+
+// generate writes the PDF. The pages are read from the archive and
+// drawn one at a time, and written to the output by chunks, so that
+// the memory used doesn't depend on the size of the document.
+func (p *nativeRenderer) generate() error {
+	if p.options.OCR != "" {
+		info, err := LookupRecognizer(p.options.OCR)
+		if err != nil {
+			return err
+		}
+		p.recognizer = info.New(p.options.OCRLanguage)
+	}
+	watermark, err := newWatermark(p.options.Watermark, time.Now())
+	if err != nil {
+		return err
+	}
+	p.watermark = watermark
+
+	file, err := os.Open(p.zipName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	zip := archive.NewZip()
+	if err := zip.ReadLazy(file, fi.Size()); err != nil {
+		return err
+	}
+
+	if len(zip.Pages) == 0 {
+		return errors.New("the document has no pages")
+	}
+	p.landscape = zip.Content.Orientation == "landscape"
+	p.epub = zip.Content.FileType == "epub"
+
+	var background *pdf.File
+	var backgroundPages []*pdf.PageObject
+	if zip.Content.FileType == "pdf" || p.epub {
+		payload, err := extractPayload(zip)
+		if err != nil {
+			return err
+		}
+		if payload != nil {
+			defer os.Remove(payload.Name())
+			defer payload.Close()
+
+			info, err := payload.Stat()
+			if err != nil {
+				return err
+			}
+			if info.Size() > 0 {
+				background, err = pdf.OpenReader(payload, info.Size())
+				if errors.Is(err, pdf.ErrEncrypted) {
+					if payload, err = decryptPayload(payload, p.options.Password); err != nil {
+						return err
+					}
+					defer os.Remove(payload.Name())
+					defer payload.Close()
+					if info, err = payload.Stat(); err != nil {
+						return err
+					}
+					background, err = pdf.OpenReader(payload, info.Size())
+				}
+				if err != nil {
+					return fmt.Errorf("failed to read PDF: %w", err)
+				}
+				if backgroundPages, err = background.Pages(); err != nil {
+					return fmt.Errorf("failed to read PDF: %w", err)
+				}
+			}
+		}
+	}
+
+	if p.options.SplitEvery > 0 {
+		return p.writeParts(zip, background, backgroundPages)
+	}
+
+	out := background
+	if out == nil || p.options.AnnotationsOnly {
+		out = pdf.NewFile()
+	}
+
+	output, err := os.Create(p.outputFilePath)
+	if err != nil {
+		return err
+	}
+	if err := p.write(out, output, zip, background, backgroundPages); err != nil {
+		output.Close()
+		os.Remove(p.outputFilePath)
+		return err
+	}
+	p.files = []string{p.outputFilePath}
+	return output.Close()
+}
+
+// A preparedPage is an exported page, read and drawn by eachPage.
+type preparedPage struct {
+	// data is the drawing, nil when there is none
+	data *rmencoding.Rm
+	// bg is the page of the original pdf, nil for the pages of the
+	// notebooks
+	bg *pdf.PageObject
+	// page is the page of the archive, with its template and the
+	// highlights of its text
+	page archive.Page
+	// strokes is the content drawing the strokes, see drawStrokes, nil
+	// when they are drawn later
+	strokes []byte
+	// text is the content of the handwriting recognized, see
+	// recognizeText
+	text []byte
+	keep bool
+	err  error
+}
+
+// eachPage calls fn with the exported pages in order. With
+// options.Jobs, several pages are read and their strokes drawn at the
+// same time, ahead of fn.
+func (p *nativeRenderer) eachPage(zip *archive.Zip, background *pdf.File, backgroundPages []*pdf.PageObject, fn func(pg *preparedPage) error) error {
+	var indexes []int
+	for index := range zip.Pages {
+		if p.options.Pages.Contains(index + 1) {
+			indexes = append(indexes, index)
+		}
+	}
+	if p.options.AddPageNumbers && p.options.PageNumbers.hasTotal() {
+		var err error
+		if p.total, err = p.countPages(zip, backgroundPages, indexes); err != nil {
+			return err
+		}
+	}
+	// the sizes of the pages, which the workers can't read from the
+	// file of the original pdf
+	sizes := make([][2]float64, len(backgroundPages))
+	if p.options.Jobs > 1 {
+		for i, bg := range backgroundPages {
+			sizes[i][0], sizes[i][1] = background.DisplaySize(bg)
+		}
+	}
+
+	prepare := func(index int) *preparedPage {
+		pg := &preparedPage{page: zip.Pages[index]}
+		docPage := pg.page.DocPage
+		if docPage >= 0 && docPage < len(backgroundPages) {
+			pg.bg = backgroundPages[docPage]
+		}
+
+		pg.data, pg.err = zip.PageDrawing(index)
+		if pg.err != nil {
+			return pg
+		}
+		pg.data = p.options.Layers.Apply(pg.data)
+		if m, ok := zip.Content.Transform.Matrix(); ok {
+			pg.page.Highlights = transformHighlights(pg.page.Highlights, m)
+		}
+
+		pg.keep = p.keeps(pg.bg, pg.page, pg.data)
+		if pg.keep && pg.data != nil && p.recognizer != nil {
+			if pg.text, pg.err = recognizeText(p.recognizer, pg.data); pg.err != nil {
+				return pg
+			}
+		}
+		if pg.keep && pg.data != nil && p.options.rasterDPI() <= 0 && p.options.Jobs > 1 {
+			width, height := notebookPageSize(pg.data, p.landscape)
+			if pg.bg != nil {
+				width, height = sizes[docPage][0], sizes[docPage][1]
+			}
+			pg.strokes = drawStrokes(pg.data, deviceScale(width, height, pg.data, p.landscape), p.options)
+		}
+		return pg
+	}
+
+	read := 0
+	return inOrder(len(indexes), p.options.Jobs, func(i int) *preparedPage {
+		return prepare(indexes[i])
+	}, func(pg *preparedPage) error {
+		if pg.err != nil {
+			return pg.err
+		}
+		if pg.keep {
+			if err := fn(pg); err != nil {
+				return err
+			}
+		}
+		read++
+		p.options.progress(read, len(indexes))
+		return nil
+	})
+}
+
+// keeps returns whether a page is exported: the pages of the original
+// pdf, unless AnnotationsOnly, and the other ones with strokes or
+// highlights, unless AllPages.
+func (p *nativeRenderer) keeps(bg *pdf.PageObject, page archive.Page, data *rmencoding.Rm) bool {
+	if bg != nil && !p.options.AnnotationsOnly {
+		return true
+	}
+	return p.options.AllPages || data != nil || len(page.Highlights) > 0
+}
+
+// countPages returns the number of pages exported of the indexes. The
+// drawings of the pages only kept with strokes are read, one at a time.
+func (p *nativeRenderer) countPages(zip *archive.Zip, backgroundPages []*pdf.PageObject, indexes []int) (int, error) {
+	count := 0
+	for _, index := range indexes {
+		var bg *pdf.PageObject
+		if docPage := zip.Pages[index].DocPage; docPage >= 0 && docPage < len(backgroundPages) {
+			bg = backgroundPages[docPage]
+		}
+		var data *rmencoding.Rm
+		if !p.keeps(bg, zip.Pages[index], nil) {
+			var err error
+			if data, err = zip.PageDrawing(index); err != nil {
+				return 0, err
+			}
+			data = p.options.Layers.Apply(data)
+		}
+		if p.keeps(bg, zip.Pages[index], data) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// inOrder calls fn with the pages prepared for the indexes from 0 to
+// n-1, in order. With more than one job, about this number of pages are
+// prepared at the same time, ahead of fn.
+func inOrder(n, jobs int, prepare func(i int) *preparedPage, fn func(pg *preparedPage) error) error {
+	if jobs <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(prepare(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	queue := make(chan chan *preparedPage, jobs)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(queue)
+		for i := 0; i < n; i++ {
+			result := make(chan *preparedPage, 1)
+			select {
+			case queue <- result:
+			case <-done:
+				return
+			}
+			go func(i int) {
+				result <- prepare(i)
+			}(i)
+		}
+	}()
+	for result := range queue {
+		if err := fn(<-result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newPage returns a blank page of out for a page of the original pdf,
+// or for a page of a notebook when bg is nil. The pages of the
+// notebooks taller than the screen are extended, the ones of the
+// notebooks in landscape are turned.
+func newPage(out *pdf.File, background *pdf.File, bg *pdf.PageObject, data *rmencoding.Rm, landscape bool) *pdf.PageObject {
+	if bg != nil {
+		return out.NewPage(background.DisplaySize(bg))
+	}
+	return out.NewPage(notebookPageSize(data, landscape))
+}
+
+// notebookPageSize returns the size of the pages of the notebooks, see
+// newPage.
+func notebookPageSize(data *rmencoding.Rm, landscape bool) (width, height float64) {
+	width, height = rmPageSize.Width, rmPageSize.Height
+	if landscape {
+		width, height = height, width
+	} else if _, screenHeight := data.ScreenSize(); data != nil && data.PageHeight > screenHeight {
+		height *= float64(data.PageHeight) / float64(screenHeight)
+	}
+	return width, height
+}
+
+// write draws the pages of the archive and writes the result to w.
+func (p *nativeRenderer) write(out *pdf.File, w io.Writer, zip *archive.Zip, background *pdf.File, backgroundPages []*pdf.PageObject) error {
+	layer, err := out.AddLayer(annotationsLayer)
+	if err != nil {
+		return err
+	}
+	resources := layerResources(layer)
+	writer := out.NewWriter(w)
+
+	var pages []*pdf.PageObject
+	err = p.eachPage(zip, background, backgroundPages, func(pg *preparedPage) error {
+		target := pg.bg
+		if pg.bg == nil || p.options.AnnotationsOnly {
+			target = newPage(out, background, pg.bg, pg.data, p.landscape)
+		}
+		if err := p.addHighlights(out, target, pg.data, pg.page.Highlights); err != nil {
+			return err
+		}
+
+		content, err := p.drawPage(out, target, pg, len(pages)+1)
+		if err != nil {
+			return err
+		}
+		if err := out.AppendContent(target, content, resources); err != nil {
+			return err
+		}
+		pages = append(pages, target)
+
+		if len(pages)%chunkPages == 0 {
+			return writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(pages) == 0 {
+		return errors.New("the document has no annotations, use the option to export all pages")
+	}
+	if err := out.SetPages(pages); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// drawPage returns the content drawn on a page: the template of the
+// pages of the notebooks, unless disabled, or the page of the book
+// annotated on the pages of the epubs without their pages, then the
+// watermark and the annotations.
+func (p *nativeRenderer) drawPage(out *pdf.File, target *pdf.PageObject, pg *preparedPage, number int) ([]byte, error) {
+	var content []byte
+	if p.epub && (pg.bg == nil || p.options.AnnotationsOnly) {
+		if pg.page.DocPage >= 0 {
+			content = drawReference(out, target, fmt.Sprintf("Page %d", pg.page.DocPage+1))
+		}
+	} else if pg.bg == nil && !p.options.NoTemplates {
+		content = drawTemplate(out, target, pg.data, p.landscape, pg.page.Pagedata)
+	}
+	if p.watermark != nil {
+		stamp, err := p.watermark.draw(out, target)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, stamp...)
+	}
+	annotations, err := drawPage(out, target, pg.data, p.landscape, number, p.total, p.options, pg.text, pg.strokes)
+	return append(content, annotations...), err
+}
+
+// addHighlights adds the highlights of the text of a page as
+// annotations, over the drawing of the page. Without the original pdf,
+// they mark where the text was.
+func (p *nativeRenderer) addHighlights(out *pdf.File, page *pdf.PageObject, data *rmencoding.Rm, highlights []archive.Highlight) error {
+	if len(highlights) == 0 {
+		return nil
+	}
+	device, _ := deviceMatrix(out, page, data, p.landscape)
+	return addHighlightAnnotations(out, page, highlights, device, p.options.HighlightColors)
+}
+
+// A part is one of the files of a split export.
+type part struct {
+	name      string
+	output    *os.File
+	out       *pdf.File
+	writer    *pdf.Writer
+	importer  *pdf.Importer
+	resources pdf.Dict
+	pages     []*pdf.PageObject
+}
+
+func (p *nativeRenderer) newPart(background *pdf.File) (*part, error) {
+	name := SplitName(p.outputFilePath, len(p.files)+1)
+	output, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	p.files = append(p.files, name)
+
+	out := pdf.NewFile()
+	layer, err := out.AddLayer(annotationsLayer)
+	if err != nil {
+		output.Close()
+		return nil, err
+	}
+	pt := &part{name: name, output: output, out: out, writer: out.NewWriter(output), resources: layerResources(layer)}
+	if background != nil {
+		pt.importer = out.NewImporter(background)
+	}
+	return pt, nil
+}
+
+func (pt *part) close() error {
+	if err := pt.out.SetPages(pt.pages); err != nil {
+		pt.output.Close()
+		return err
+	}
+	if err := pt.writer.Close(); err != nil {
+		pt.output.Close()
+		return err
+	}
+	return pt.output.Close()
+}
+
+// writeParts writes the pages in files of SplitEvery pages, named by
+// SplitName. Every file only has the objects used by its pages.
+func (p *nativeRenderer) writeParts(zip *archive.Zip, background *pdf.File, backgroundPages []*pdf.PageObject) error {
+	var current *part
+	count := 0
+	err := p.eachPage(zip, background, backgroundPages, func(pg *preparedPage) error {
+		if current == nil {
+			var err error
+			if current, err = p.newPart(background); err != nil {
+				return err
+			}
+		}
+
+		var target *pdf.PageObject
+		if pg.bg != nil && !p.options.AnnotationsOnly {
+			var err error
+			if target, err = current.importer.Import(pg.bg); err != nil {
+				return err
+			}
+		} else {
+			target = newPage(current.out, background, pg.bg, pg.data, p.landscape)
+		}
+		if err := p.addHighlights(current.out, target, pg.data, pg.page.Highlights); err != nil {
+			return err
+		}
+
+		count++
+		content, err := p.drawPage(current.out, target, pg, count)
+		if err != nil {
+			return err
+		}
+		if err := current.out.AppendContent(target, content, current.resources); err != nil {
+			return err
+		}
+		current.pages = append(current.pages, target)
+
+		if len(current.pages) == p.options.SplitEvery {
+			err := current.close()
+			current = nil
+			return err
+		}
+		if len(current.pages)%chunkPages == 0 {
+			if background != nil {
+				background.Release()
+			}
+			return current.writer.Flush()
+		}
+		return nil
+	})
+	if err == nil && current != nil {
+		err = current.close()
+		current = nil
+	}
+	if err == nil && count == 0 {
+		err = errors.New("the document has no annotations, use the option to export all pages")
+	}
+	if err != nil {
+		if current != nil {
+			current.output.Close()
+		}
+		for _, name := range p.files {
+			os.Remove(name)
+		}
+		p.files = nil
+		return err
+	}
+	return nil
+}
+
+// decryptPayload writes the decrypted copy of an encrypted pdf to a
+// temporary file. The whole file is decrypted in memory.
+func decryptPayload(payload *os.File, password string) (*os.File, error) {
+	if _, err := payload.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	decrypted, err := os.CreateTemp("", "rmapi-decrypted-*.pdf")
+	if err != nil {
+		return nil, err
+	}
+	if err := pdf.Decrypt(payload, decrypted, password); err != nil {
+		decrypted.Close()
+		os.Remove(decrypted.Name())
+		return nil, err
+	}
+	return decrypted, nil
+}
+
+// extractPayload copies the pdf of an archive into a temporary file,
+// to read it without loading it in memory. It returns nil when the
+// archive has no pdf.
+func extractPayload(zip *archive.Zip) (*os.File, error) {
+	r, err := zip.OpenPDF()
+	if err != nil || r == nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	tmp, err := os.CreateTemp("", "rmapi-payload-*.pdf")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// annotationsLayer is the name of the layer of the strokes, which the
+// viewers can hide to show the original pages.
+const annotationsLayer = "reMarkable annotations"
+
+// layerResources returns the resources of the drawings of drawPage,
+// the strokes being in the layer.
+func layerResources(layer pdf.Ref) pdf.Dict {
+	resources := pdf.Dict{}
+	for name, v := range pageResources {
+		resources[name] = v
+	}
+	resources["Properties"] = pdf.Dict{"RmapiAnnotations": layer}
+	return resources
+}
+
+// pageResources are the resources used by the drawings of drawPage.
+var pageResources = pdf.Dict{
+	"ExtGState": opacityStates(pdf.Dict{
+		"RmapiHighlight": pdf.Dict{
+			"Type": pdf.Name("ExtGState"),
+			"CA":   0.5,
+			"ca":   0.5,
+			"BM":   pdf.Name("Multiply"),
+		},
+	}),
+	"Font": pdf.Dict{
+		"RmapiFont": pdf.Dict{
+			"Type":     pdf.Name("Font"),
+			"Subtype":  pdf.Name("Type1"),
+			"BaseFont": pdf.Name("Helvetica"),
+			"Encoding": pdf.Name("WinAnsiEncoding"),
+		},
+		"RmapiFontBold": pdf.Dict{
+			"Type":     pdf.Name("Font"),
+			"Subtype":  pdf.Name("Type1"),
+			"BaseFont": pdf.Name("Helvetica-Bold"),
+			"Encoding": pdf.Name("WinAnsiEncoding"),
+		},
+	},
+}
+
+// fontResources are the names of the fonts in pageResources.
+var fontResources = map[pdf.Font]string{
+	pdf.Helvetica:     "RmapiFont",
+	pdf.HelveticaBold: "RmapiFontBold",
+}
+
+// opacityStates adds the graphics states of the opacities of the
+// brushes, named by opacityState.
+func opacityStates(states pdf.Dict) pdf.Dict {
+	for n := 0; n < opacitySteps; n++ {
+		alpha := float64(n) / opacitySteps
+		states[pdf.Name(opacityState(alpha))] = pdf.Dict{
+			"Type": pdf.Name("ExtGState"),
+			"CA":   alpha,
+			"ca":   alpha,
+		}
+	}
+	return states
+}
+
+// deviceMatrix returns the matrix converting the device pixels of a
+// drawing, from the top left corner of the page as shown, into the user
+// space of the page, and the size of a pixel. The pages fit the screen
+// of the device which wrote them, see Rm.ScreenSize. The documents in
+// landscape are shown on the device turned counterclockwise, their
+// pages fit the turned screen.
+func deviceMatrix(f *pdf.File, page *pdf.PageObject, data *rmencoding.Rm, landscape bool) (pdf.Matrix, float64) {
+	width, height := f.DisplaySize(page)
+	scale := deviceScale(width, height, data, landscape)
+	turn := pdf.Identity
+	if landscape {
+		// the left of the screen is the bottom of the page
+		screenWidth, _ := data.ScreenSize()
+		turn = pdf.Matrix{0, -1, 1, 0, 0, float64(screenWidth)}
+	}
+	return turn.Multiply(pdf.Matrix{scale, 0, 0, -scale, 0, height}).Multiply(f.DisplayMatrix(page)), scale
+}
+
+// deviceScale returns the size of a device pixel on a page of the given
+// size as shown, see deviceMatrix.
+func deviceScale(width, height float64, data *rmencoding.Rm, landscape bool) float64 {
+	screenWidth, screenHeight := data.ScreenSize()
+	sw, sh := float64(screenWidth), float64(screenHeight)
+	if landscape {
+		sw, sh = sh, sw
+	}
+
+	scale := width / sw
+	if height/width > sh/sw {
+		scale = height / sh
+	}
+	if !landscape && data != nil && data.PageHeight > screenHeight {
+		// the page scrolls, the strokes keep the width of the screen
+		scale = width / sw
+	}
+	return scale
+}
+
+// drawPage returns the content stream drawing the typed text and the
+// strokes of a page, and its number out of total. The strokes are an image when
+// options.RasterDPI or Eink is set, see drawRaster, and the ones drawn already
+// by drawStrokes otherwise, when not nil. text, the handwriting
+// recognized, is written under the strokes.
+func drawPage(f *pdf.File, page *pdf.PageObject, data *rmencoding.Rm, landscape bool, number, total int, options PdfGeneratorOptions, text, strokes []byte) ([]byte, error) {
+	var b bytes.Buffer
+
+	device, scale := deviceMatrix(f, page, data, landscape)
+
+	if data != nil {
+		// in the layer of the annotations, see layerResources
+		fmt.Fprintf(&b, "/OC /RmapiAnnotations BDC q %s cm 1 J 1 j\n", device)
+		if data.Text != nil {
+			drawText(&b, data.Text)
+		}
+		b.Write(text)
+		if options.rasterDPI() > 0 {
+			raster, err := drawRaster(f, page, data, options)
+			if err != nil {
+				return nil, err
+			}
+			b.Write(raster)
+		} else {
+			if strokes == nil {
+				strokes = drawStrokes(data, scale, options)
+			}
+			b.Write(strokes)
+		}
+		b.WriteString("Q EMC\n")
+	}
+
+	if options.AddPageNumbers {
+		width, height := f.DisplaySize(page)
+		b.Write(drawPageNumber(options.PageNumbers, f.DisplayMatrix(page), width, height, number, total))
+	}
+	return b.Bytes(), nil
+}
+
+// drawStrokes returns the content drawing the strokes of a page in
+// device pixels, scale being the size of a pixel.
+func drawStrokes(data *rmencoding.Rm, scale float64, options PdfGeneratorOptions) []byte {
+	var b bytes.Buffer
+	for _, layer := range data.Layers {
+		for _, line := range layer.Lines {
+			drawLine(&b, line, scale, lineColor(line, options.Colors, options.HighlightColors), options.Widths, options.Smooth)
+		}
+	}
+	return b.Bytes()
+}
+
+// textStyle is the look of a style of paragraph, in device pixels.
+type textStyle struct {
+	font pdf.Font
+	// size of the font and height of the lines
+	size, line float64
+	indent     float64
+	// prefix marks the paragraph, the lines after the first one are
+	// aligned on its text
+	prefix string
+}
+
+// textStyles are the styles of the paragraphs, close to the ones of the
+// device.
+var textStyles = map[rmencoding.ParagraphStyle]textStyle{
+	rmencoding.StyleBasic:           {font: pdf.Helvetica, size: 34, line: 71},
+	rmencoding.StylePlain:           {font: pdf.Helvetica, size: 34, line: 71},
+	rmencoding.StyleHeading:         {font: pdf.HelveticaBold, size: 50, line: 100},
+	rmencoding.StyleBold:            {font: pdf.HelveticaBold, size: 34, line: 71},
+	rmencoding.StyleBullet:          {font: pdf.Helvetica, size: 34, line: 71, prefix: "• "},
+	rmencoding.StyleBullet2:         {font: pdf.Helvetica, size: 34, line: 71, indent: 50, prefix: "– "},
+	rmencoding.StyleCheckbox:        {font: pdf.Helvetica, size: 34, line: 71, prefix: "[ ] "},
+	rmencoding.StyleCheckboxChecked: {font: pdf.Helvetica, size: 34, line: 71, prefix: "[x] "},
+}
+
+// drawText draws the typed text in device pixels, wrapped to the width
+// of its box. The y axis of the device goes down, the text is flipped to
+// be upright.
+func drawText(b *bytes.Buffer, text *rmencoding.Text) {
+	b.WriteString("0 g\n")
+	y := float64(text.Y)
+	for _, p := range text.Paragraphs {
+		style, ok := textStyles[p.Style]
+		if !ok {
+			style = textStyles[rmencoding.StylePlain]
+		}
+		font := fontResources[style.font]
+		x := float64(text.X) + style.indent
+		if style.prefix != "" {
+			fmt.Fprintf(b, "BT /%s %v Tf 1 0 0 -1 %.2f %.2f Tm %s Tj ET\n", font, style.size, x, y+style.size, pdf.EncodeText(style.prefix))
+			x += pdf.TextWidth(style.prefix, style.font, style.size)
+		}
+		width := float64(text.X+text.Width) - x
+		for _, line := range pdf.WrapText(p.Text, style.font, style.size, width) {
+			if line != "" {
+				fmt.Fprintf(b, "BT /%s %v Tf 1 0 0 -1 %.2f %.2f Tm %s Tj ET\n", font, style.size, x, y+style.size, pdf.EncodeText(line))
+			}
+			y += style.line
+		}
+	}
+}
+
+// drawReference writes the reference of the page annotated at the top
+// left of a page.
+func drawReference(f *pdf.File, page *pdf.PageObject, reference string) []byte {
+	_, height := f.DisplaySize(page)
+	return []byte(fmt.Sprintf("q %s cm BT /RmapiFont 8 Tf 0.5 g 20 %.2f Td %s Tj ET Q\n",
+		f.DisplayMatrix(page), height-20, pdf.EncodeText(reference)))
+}
+
+// drawLine draws a stroke in device pixels, as curves through its
+// points when smooth.
+func drawLine(b *bytes.Buffer, line rmencoding.Line, scale float64, c color.RGBA, widths PenWidths, smooth bool) {
+	if len(line.Points) < 1 {
+		return
+	}
+
+	r, g, bl := rgb(c)
+	switch line.BrushType {
+	case rmencoding.Eraser, rmencoding.EraseArea:
+		return
+	case rmencoding.Highlighter, rmencoding.HighlighterV5:
+		// semi-transparent
+		fmt.Fprintf(b, "q /RmapiHighlight gs %.3f %.3f %.3f RG 30 w 0 J\n", r, g, bl)
+		writePath(b, line.Points, smooth)
+		b.WriteString("S Q\n")
+		return
+	}
+
+	for _, stroke := range brushStrokes(line, widths.line(line)/scale) {
+		b.WriteString("q")
+		if alpha := opacityState(stroke.opacity); alpha != "" {
+			fmt.Fprintf(b, " /%s gs", alpha)
+		}
+		if stroke.outline != nil {
+			// a variable width
+			fmt.Fprintf(b, " %.3f %.3f %.3f rg\n", r, g, bl)
+			if smooth {
+				writeCurves(b, stroke.outline, true)
+			} else {
+				for i, v := range stroke.outline {
+					op := "l"
+					if i == 0 {
+						op = "m"
+					}
+					fmt.Fprintf(b, "%.2f %.2f %s\n", v.x, v.y, op)
+				}
+			}
+			b.WriteString("h f Q\n")
+			continue
+		}
+		fmt.Fprintf(b, " %.3f %.3f %.3f RG %.3f w %d J\n", r, g, bl, stroke.width, stroke.cap)
+		writePath(b, stroke.points, smooth)
+		b.WriteString("S Q\n")
+	}
+}
+
+// writePath writes the path of the points of a stroke, as curves
+// through them when smooth.
+func writePath(b *bytes.Buffer, points []rmencoding.Point, smooth bool) {
+	if smooth && len(points) > 2 {
+		vecs := make([]vec, len(points))
+		for i, point := range points {
+			vecs[i] = pointVec(point)
+		}
+		writeCurves(b, vecs, false)
+		return
+	}
+	for i, point := range points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(b, "%.2f %.2f %s\n", point.X, point.Y, op)
+	}
+	if len(points) == 1 {
+		// a dot
+		fmt.Fprintf(b, "%.2f %.2f l\n", points[0].X, points[0].Y)
+	}
+}
+
+// writeCurves writes the path of the curves through points, see
+// smoothPath.
+func writeCurves(b *bytes.Buffer, points []vec, closed bool) {
+	fmt.Fprintf(b, "%.2f %.2f m\n", points[0].x, points[0].y)
+	for _, c := range smoothPath(points, closed) {
+		fmt.Fprintf(b, "%.2f %.2f %.2f %.2f %.2f %.2f c\n", c.c1.x, c.c1.y, c.c2.x, c.c2.y, c.end.x, c.end.y)
+	}
+}
+
+// opacityState returns the name of the graphics state of the resources
+// setting an opacity, none for an opaque stroke.
+func opacityState(opacity float64) string {
+	n := int(math.Round(opacity * opacitySteps))
+	if n >= opacitySteps {
+		return ""
+	}
+	return fmt.Sprintf("RmapiAlpha%d", n)
+}
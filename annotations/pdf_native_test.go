@@ -0,0 +1,451 @@
+package annotations
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joagonca/rmapi/archive"
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/pdf"
+)
+
+func TestNativeKeepsBackgroundPages(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "a4.pdf")
+	generator := CreatePdfGenerator("testfiles/a4.zip", out, PdfGeneratorOptions{AddPageNumbers: true, Renderer: "native"})
+	if err := generator.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := archive.ReadRawFiles("testfiles/a4.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var original []byte
+	for name, data := range files {
+		if filepath.Ext(name) == ".pdf" {
+			original = data
+		}
+	}
+	f, err := pdf.Open(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, err = pdf.Open(data); err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != len(want) {
+		t.Fatalf("expected %d pages, got %d", len(want), len(pages))
+	}
+	for i, p := range pages {
+		if _, ok, err := f.ContentBounds(p); err != nil || !ok {
+			t.Errorf("page %d: no content (%v)", i+1, err)
+		}
+	}
+
+	// the strokes are in a layer
+	root, _ := f.Get(f.Trailer(), "Root").(pdf.Dict)
+	properties, _ := f.Get(root, "OCProperties").(pdf.Dict)
+	if groups, _ := f.Get(properties, "OCGs").(pdf.Array); len(groups) == 0 {
+		t.Error("no layer of the annotations")
+	}
+}
+
+func TestNativeAnnotationsOnly(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "rm.pdf")
+	generator := CreatePdfGenerator("testfiles/a4.zip", out, PdfGeneratorOptions{AnnotationsOnly: true, Renderer: "native"})
+	if err := generator.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := pdf.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range pages {
+		if _, ok, _ := f.ContentBounds(p); !ok {
+			t.Errorf("page %d has no annotations", i+1)
+		}
+	}
+}
+
+func TestNativeSplit(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "strange.pdf")
+	generator := CreatePdfGenerator("testfiles/strange.zip", out, PdfGeneratorOptions{SplitEvery: 1, Renderer: "native"})
+	if err := generator.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := generator.OutputFiles()
+	if len(files) < 2 {
+		t.Fatalf("expected several parts, got %v", files)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("the whole export should not be written")
+	}
+	for i, name := range files {
+		if name != SplitName(out, i+1) {
+			t.Errorf("unexpected name %s", name)
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the original pdf has 1 MB
+		if len(data) > 1000000 {
+			t.Errorf("%s: the part should only have its objects, got %d bytes", name, len(data))
+		}
+		f, err := pdf.Open(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages, err := f.Pages()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pages) != 1 {
+			t.Fatalf("%s: expected 1 page, got %d", name, len(pages))
+		}
+		if _, ok, err := f.ContentBounds(pages[0]); err != nil || !ok {
+			t.Errorf("%s: no content (%v)", name, err)
+		}
+	}
+}
+
+func TestNativeJobs(t *testing.T) {
+	dir := t.TempDir()
+	var exports [][]byte
+	for _, jobs := range []int{0, 4} {
+		out := filepath.Join(dir, fmt.Sprintf("strange-%d.pdf", jobs))
+		generator := CreatePdfGenerator("testfiles/strange.zip", out, PdfGeneratorOptions{AllPages: true, Jobs: jobs, Renderer: "native"})
+		if err := generator.Generate(); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exports = append(exports, data)
+	}
+	if !bytes.Equal(exports[0], exports[1]) {
+		t.Error("the pages drawn at the same time should give the same export")
+	}
+}
+
+func TestNativeProgress(t *testing.T) {
+	for _, jobs := range []int{0, 4} {
+		var calls [][2]int
+		out := filepath.Join(t.TempDir(), "strange.pdf")
+		generator := CreatePdfGenerator("testfiles/strange.zip", out, PdfGeneratorOptions{
+			Pages:    PageRanges{{2, 3}},
+			Jobs:     jobs,
+			Renderer: "native",
+			Progress: func(page, total int) { calls = append(calls, [2]int{page, total}) },
+		})
+		if err := generator.Generate(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(calls) != "[[1 2] [2 2]]" {
+			t.Errorf("jobs %d: unexpected progress %v", jobs, calls)
+		}
+	}
+}
+
+func TestNativeTallPage(t *testing.T) {
+	out := pdf.NewFile()
+	data := &rmencoding.Rm{Version: rmencoding.V6, PageHeight: 2 * rmencoding.Height}
+	page := newPage(out, nil, nil, data, false)
+	width, height := out.DisplaySize(page)
+	if width != rmPageSize.Width || height != 2*rmPageSize.Height {
+		t.Errorf("unexpected size %vx%v", width, height)
+	}
+
+	drawn, err := drawPage(out, page, data, false, 1, 1, PdfGeneratorOptions{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(drawn)
+	// the scale of the width of the screen, not of the height
+	if want := fmt.Sprintf("/OC /RmapiAnnotations BDC q %.2f 0 0 -%.2f ", width/float64(rmencoding.Width), width/float64(rmencoding.Width)); !strings.HasPrefix(content, want) {
+		t.Errorf("expected %q, got %q", want, content)
+	}
+}
+
+func TestNativeText(t *testing.T) {
+	out := pdf.NewFile()
+	data := &rmencoding.Rm{Version: rmencoding.V6, Text: &rmencoding.Text{
+		X: 100, Y: 200, Width: 400,
+		Paragraphs: []rmencoding.Paragraph{
+			{Style: rmencoding.StyleHeading, Text: "Title"},
+			{Style: rmencoding.StyleBullet, Text: "a long item (wrapped) on two lines"},
+		},
+	}}
+	page := newPage(out, nil, nil, data, false)
+
+	drawn, err := drawPage(out, page, data, false, 1, 1, PdfGeneratorOptions{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(drawn)
+	for _, want := range []string{
+		"BT /RmapiFontBold 50 Tf 1 0 0 -1 100.00 250.00 Tm (Title) Tj ET",
+		"BT /RmapiFont 34 Tf 1 0 0 -1 100.00 334.00 Tm (\\225 ) Tj ET",
+		"128.36 334.00 Tm (a long item \\(wrapped\\)) Tj ET",
+		"128.36 405.00 Tm (on two lines) Tj ET",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in %q", want, content)
+		}
+	}
+	if strings.Index(content, "Tj") < strings.Index(content, "BDC") {
+		t.Error("the text should be in the layer of the annotations")
+	}
+}
+
+func TestNativePages(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "rm.pdf")
+	generator := CreatePdfGenerator("testfiles/strange.zip", out, PdfGeneratorOptions{Pages: PageRanges{{2, 3}}, Renderer: "native"})
+	if err := generator.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := pdf.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Errorf("expected the 2 selected pages, got %d", len(pages))
+	}
+}
+
+func TestNativeLandscape(t *testing.T) {
+	out := pdf.NewFile()
+	page := newPage(out, nil, nil, nil, true)
+	if width, height := out.DisplaySize(page); width != rmPageSize.Height || height != rmPageSize.Width {
+		t.Fatalf("the page should be turned, got %vx%v", width, height)
+	}
+
+	device, _ := deviceMatrix(out, page, nil, true)
+	// the top of the screen is on the left of the page
+	for _, c := range []struct{ x, y, wantX, wantY float64 }{
+		{0, 0, 0, 0},
+		{1404, 0, 0, rmPageSize.Width},
+		{0, 1872, rmPageSize.Height, 0},
+	} {
+		x, y := device.Apply(c.x, c.y)
+		if math.Abs(x-c.wantX) > 1 || math.Abs(y-c.wantY) > 1 {
+			t.Errorf("%v,%v: expected %v,%v, got %v,%v", c.x, c.y, c.wantX, c.wantY, x, y)
+		}
+	}
+}
+
+func TestNativePaperPro(t *testing.T) {
+	out := pdf.NewFile()
+	data := &rmencoding.Rm{Version: rmencoding.V6, PageWidth: rmencoding.PaperProWidth, PageHeight: rmencoding.PaperProHeight}
+	page := newPage(out, nil, nil, data, false)
+	if width, height := out.DisplaySize(page); width != rmPageSize.Width || height != rmPageSize.Height {
+		t.Fatalf("the page should not be extended, got %vx%v", width, height)
+	}
+
+	// the bottom right corner of the wider screen
+	device, _ := deviceMatrix(out, page, data, false)
+	if x, y := device.Apply(float64(rmencoding.PaperProWidth), float64(rmencoding.PaperProHeight)); math.Abs(x-rmPageSize.Width) > 1 || math.Abs(y) > 1 {
+		t.Errorf("unexpected corner %v,%v", x, y)
+	}
+}
+
+func TestNativeEpub(t *testing.T) {
+	page := rmencoding.New()
+	page.Layers = []rmencoding.Layer{{Lines: []rmencoding.Line{
+		{BrushType: rmencoding.FinelinerV5, BrushSize: rmencoding.Medium, Points: []rmencoding.Point{{X: 100, Y: 100, Width: 2}, {X: 200, Y: 200, Width: 2}}},
+	}}}
+	drawing, err := page.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "book.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for file, content := range map[string]string{
+		"book.content":  `{"fileType":"epub","pageCount":2,"pages":["a1e7c8f0-0000-4000-8000-000000000001","a1e7c8f0-0000-4000-8000-000000000002"],"redirectionPageMap":[6,7]}`,
+		"book.pagedata": "Blank\nBlank\n",
+		"book.epub":     "not read",
+		"book/a1e7c8f0-0000-4000-8000-000000000002.rm": string(drawing),
+	} {
+		w, err := zw.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	out := filepath.Join(t.TempDir(), "book.pdf")
+	if err := CreatePdfGenerator(name, out, PdfGeneratorOptions{Renderer: "native"}).Generate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := pdf.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := result.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("expected the annotated page, got %d pages", len(pages))
+	}
+	if content := pageContent(t, result, pages[0]); !strings.Contains(content, "(Page 8) Tj") {
+		t.Errorf("the page of the book is missing in %q", content)
+	}
+}
+
+// pageContent returns the decoded content streams of a page.
+func pageContent(t *testing.T, f *pdf.File, page *pdf.PageObject) string {
+	streams, ok := f.Get(page.Dict, "Contents").(pdf.Array)
+	if !ok {
+		streams = pdf.Array{page.Dict["Contents"]}
+	}
+	var content strings.Builder
+	for _, s := range streams {
+		obj, err := f.Resolve(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream, ok := obj.(*pdf.Stream)
+		if !ok {
+			t.Fatalf("unexpected content %v", obj)
+		}
+		data, err := stream.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content.Write(data)
+	}
+	return content.String()
+}
+
+func TestNativeRaster(t *testing.T) {
+	out := pdf.NewFile()
+	data := &rmencoding.Rm{Version: rmencoding.V6, Layers: []rmencoding.Layer{{Lines: []rmencoding.Line{
+		{BrushType: rmencoding.Fineliner, BrushColor: rmencoding.Black, BrushSize: rmencoding.Medium,
+			Points: []rmencoding.Point{{X: 100, Y: 100}, {X: 300, Y: 100}}},
+	}}}}
+	page := newPage(out, nil, nil, data, false)
+
+	drawn, err := drawPage(out, page, data, false, 1, 1, PdfGeneratorOptions{RasterDPI: 113, RasterCompression: 9}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(drawn)
+	if !strings.Contains(content, "/RmapiStrokes Do") || strings.Contains(content, " l\n") {
+		t.Errorf("expected the image of the strokes, got %q", content)
+	}
+
+	resources, _ := out.Get(page.Dict, "Resources").(pdf.Dict)
+	xobjects, _ := out.Get(resources, "XObject").(pdf.Dict)
+	img, ok := out.Get(xobjects, "RmapiStrokes").(*pdf.Stream)
+	if !ok {
+		t.Fatalf("missing image in %v", resources)
+	}
+	if img.Dict["Width"] != int64(702) || img.Dict["Height"] != int64(936) {
+		t.Errorf("unexpected size %vx%v", img.Dict["Width"], img.Dict["Height"])
+	}
+	mask, ok := out.Get(img.Dict, "SMask").(*pdf.Stream)
+	if !ok {
+		t.Fatal("the image has no transparency")
+	}
+	alphas, err := mask.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the line, at half the resolution of the device
+	if alphas[50*702+100] != 0xff || alphas[250*702+100] != 0 {
+		t.Error("unexpected transparency of the strokes")
+	}
+}
+
+func TestNativeFlatten(t *testing.T) {
+	out := pdf.NewFile()
+	data := &rmencoding.Rm{Version: rmencoding.V6, Layers: []rmencoding.Layer{{Lines: []rmencoding.Line{
+		{BrushType: rmencoding.Fineliner, BrushColor: rmencoding.Blue, BrushSize: rmencoding.Medium,
+			Points: []rmencoding.Point{{X: 100, Y: 100}, {X: 300, Y: 100}}},
+	}}}}
+	page := newPage(out, nil, nil, data, false)
+
+	drawn, err := drawPage(out, page, data, false, 1, 1, PdfGeneratorOptions{Flatten: true, JPEGQuality: 50}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(drawn), "/RmapiStrokes Do") {
+		t.Errorf("expected the image of the strokes, got %q", drawn)
+	}
+
+	resources, _ := out.Get(page.Dict, "Resources").(pdf.Dict)
+	xobjects, _ := out.Get(resources, "XObject").(pdf.Dict)
+	img, ok := out.Get(xobjects, "RmapiStrokes").(*pdf.Stream)
+	if !ok {
+		t.Fatalf("missing image in %v", resources)
+	}
+	// at flattenDPI, the width of the screen of the device being 1404
+	if want := int64(math.Round(1404 * float64(flattenDPI) / deviceDPI)); img.Dict["Width"] != want {
+		t.Errorf("expected a width of %d, got %v", want, img.Dict["Width"])
+	}
+	if img.Dict["Filter"] != pdf.Name("DCTDecode") {
+		t.Errorf("expected a jpeg, got %v", img.Dict["Filter"])
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Bounds().Dx() != int(img.Dict["Width"].(int64)) {
+		t.Errorf("unexpected size of the jpeg %v", decoded.Bounds())
+	}
+	if _, ok := out.Get(img.Dict, "SMask").(*pdf.Stream); !ok {
+		t.Error("the image has no transparency")
+	}
+}
@@ -0,0 +1,319 @@
+package annotations
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/joagonca/rmapi/archive"
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+// deviceDPI is the resolution of the screen of the device.
+const deviceDPI = 226
+
+// PngGenerator exports the strokes of the pages of a document as png
+// images, on a white background.
+type PngGenerator struct {
+	zipName        string
+	outputFilePath string
+	options        PngGeneratorOptions
+}
+
+type PngGeneratorOptions struct {
+	// AllPages keeps the pages without annotations
+	AllPages bool
+	// DPI is the resolution of the images, the one of the device when 0
+	DPI int
+	// Colors replaces the colors of the pens, HighlightColors the ones
+	// of the highlighters
+	Colors          PenColors
+	HighlightColors PenColors
+	// Widths replaces the widths of the strokes of the brushes
+	Widths PenWidths
+	// Pages are the pages exported, all of them when empty
+	Pages PageRanges
+	// Layers selects the layers of the pages exported
+	Layers LayerFilter
+	// Eink draws the strokes like the screen of the device, see
+	// PdfGeneratorOptions
+	Eink bool
+}
+
+func CreatePngGenerator(zipName, outputFilePath string, options PngGeneratorOptions) *PngGenerator {
+	return &PngGenerator{zipName: zipName, outputFilePath: outputFilePath, options: options}
+}
+
+// Generate writes an image for every page and returns their paths.
+// A single page is written to the output path, several pages are
+// numbered, as name-1.png, name-2.png...
+func (p *PngGenerator) Generate() ([]string, error) {
+	file, err := os.Open(p.zipName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	zip := archive.NewZip()
+	if err := zip.ReadLazy(file, fi.Size()); err != nil {
+		return nil, err
+	}
+
+	dpi := p.options.DPI
+	if dpi <= 0 {
+		dpi = deviceDPI
+	}
+	scale := float64(dpi) / deviceDPI
+
+	var files []string
+	for index := range zip.Pages {
+		if !p.options.Pages.Contains(index + 1) {
+			continue
+		}
+		data, err := zip.PageDrawing(index)
+		if err != nil {
+			return files, err
+		}
+		data = p.options.Layers.Apply(data)
+		if data == nil && !p.options.AllPages {
+			continue
+		}
+
+		name := PngPageName(p.outputFilePath, len(files)+1)
+		if err := writePng(name, renderPage(data, scale, p.options.Colors, p.options.HighlightColors, p.options.Widths, p.options.Eink)); err != nil {
+			return files, err
+		}
+		files = append(files, name)
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("the document has no annotations, use the option to export all pages")
+	}
+	if len(files) == 1 {
+		if err := os.Rename(files[0], p.outputFilePath); err != nil {
+			return files, err
+		}
+		files[0] = p.outputFilePath
+	}
+	return files, nil
+}
+
+// PngPageName returns the path of the image of a page of a document
+// exported to outputFilePath, numbered from 1.
+func PngPageName(outputFilePath string, page int) string {
+	return fmt.Sprintf("%s-%d.png", strings.TrimSuffix(outputFilePath, ".png"), page)
+}
+
+func writePng(name string, img image.Image) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	// the thumbnails of the notebooks uploaded
+	archive.StrokesRenderer = func(page *rmencoding.Rm, dpi int) image.Image {
+		return RenderPage(page, dpi, nil, nil, nil, false)
+	}
+}
+
+// RenderPage draws the strokes of a page on a white background, at
+// the given resolution, the one of the device when 0, like the screen
+// of the device with eink.
+func RenderPage(data *rmencoding.Rm, dpi int, colors, highlightColors PenColors, widths PenWidths, eink bool) *image.RGBA {
+	if dpi <= 0 {
+		dpi = deviceDPI
+	}
+	return renderPage(data, float64(dpi)/deviceDPI, colors, highlightColors, widths, eink)
+}
+
+// renderPage draws the strokes of a page, scale being the size of a
+// device pixel in the image. The pages taller than the screen keep
+// their height.
+func renderPage(data *rmencoding.Rm, scale float64, colors, highlightColors PenColors, widths PenWidths, eink bool) *image.RGBA {
+	screenWidth, pageHeight := data.ScreenSize()
+	if data != nil && data.PageHeight > pageHeight {
+		pageHeight = data.PageHeight
+	}
+	width := int(math.Round(float64(screenWidth) * scale))
+	height := int(math.Round(float64(pageHeight) * scale))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	renderStrokes(img, data, scale, colors, highlightColors, widths, eink)
+	return img
+}
+
+// renderStrokes draws the strokes of a page over img, see renderPage.
+// With eink, they are drawn in the greys of the screen of the device,
+// see einkGreys.
+func renderStrokes(img *image.RGBA, data *rmencoding.Rm, scale float64, colors, highlightColors PenColors, widths PenWidths, eink bool) {
+	if data == nil {
+		return
+	}
+	for _, layer := range data.Layers {
+		for _, line := range layer.Lines {
+			renderLine(img, line, scale, colors, highlightColors, widths, eink)
+		}
+	}
+	if eink {
+		einkGreys(img)
+	}
+}
+
+// renderLine draws a stroke with round caps and joins. The highlighter
+// is drawn half transparent. With eink, the pencils have a grain.
+func renderLine(img *image.RGBA, line rmencoding.Line, scale float64, colors, highlightColors PenColors, widths PenWidths, eink bool) {
+	if len(line.Points) < 1 {
+		return
+	}
+
+	alpha := uint8(0xff)
+	width := widths.line(line)
+	switch line.BrushType {
+	case rmencoding.Eraser, rmencoding.EraseArea:
+		return
+	case rmencoding.Highlighter, rmencoding.HighlighterV5:
+		alpha = 0x80
+		width = 30
+	}
+	radius := math.Max(width*scale/2, 0.5)
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, point := range line.Points {
+		minX, maxX = math.Min(minX, float64(point.X)), math.Max(maxX, float64(point.X))
+		minY, maxY = math.Min(minY, float64(point.Y)), math.Max(maxY, float64(point.Y))
+	}
+	bounds := image.Rect(
+		int(math.Floor(minX*scale-radius-1)), int(math.Floor(minY*scale-radius-1)),
+		int(math.Ceil(maxX*scale+radius))+2, int(math.Ceil(maxY*scale+radius))+2,
+	).Intersect(img.Bounds())
+	if bounds.Empty() {
+		return
+	}
+	// the coverage of the whole stroke, so that the overlapping
+	// segments of the highlighter aren't darker
+	mask := image.NewAlpha(bounds)
+	prev := line.Points[0]
+	for _, point := range line.Points {
+		coverSegment(mask,
+			float64(prev.X)*scale, float64(prev.Y)*scale,
+			float64(point.X)*scale, float64(point.Y)*scale,
+			radius)
+		prev = point
+	}
+	if eink && isPencil(line.BrushType) {
+		pencilGrain(mask)
+	}
+
+	c := lineColor(line, colors, highlightColors)
+	c.A = alpha
+	c.R, c.G, c.B = premultiply(c.R, alpha), premultiply(c.G, alpha), premultiply(c.B, alpha)
+	draw.DrawMask(img, bounds, image.NewUniform(c), image.Point{}, mask, bounds.Min, draw.Over)
+}
+
+// einkLevels is the number of greys of the screen of the device.
+const einkLevels = 16
+
+// einkGreys changes the colors of img into the greys of the screen of
+// the device, over a white page. The pixels which turn white and were
+// transparent, such as the edges of the strokes, stay transparent.
+func einkGreys(img *image.RGBA) {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if c.A == 0 {
+				continue
+			}
+			// premultiplied, over white
+			white := float64(0xff - c.A)
+			r, g, b := float64(c.R)+white, float64(c.G)+white, float64(c.B)+white
+			level := math.Round((0.299*r + 0.587*g + 0.114*b) / 0xff * (einkLevels - 1))
+			if level >= einkLevels-1 && c.A < 0xff {
+				img.SetRGBA(x, y, color.RGBA{})
+				continue
+			}
+			v := uint8(level * 0xff / (einkLevels - 1))
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 0xff})
+		}
+	}
+}
+
+// isPencil tells whether a brush is a pencil, drawn with a grain on
+// the screen.
+func isPencil(brush rmencoding.BrushType) bool {
+	switch brush {
+	case rmencoding.TiltPencil, rmencoding.TiltPencilV5, rmencoding.SharpPencil, rmencoding.SharpPencilV5:
+		return true
+	}
+	return false
+}
+
+// pencilGrain lightens the coverage of the pixels of mask by a factor
+// between 0.3 and 1 varying from one pixel to the next, the grain of
+// the pencils on the screen.
+func pencilGrain(mask *image.Alpha) {
+	bounds := mask.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			a := mask.AlphaAt(x, y).A
+			if a == 0 {
+				continue
+			}
+			v := math.Sin(float64(x)*12.9898+float64(y)*78.233) * 43758.5453
+			factor := 0.3 + 0.7*(v-math.Floor(v))
+			mask.SetAlpha(x, y, color.Alpha{A: uint8(float64(a) * factor)})
+		}
+	}
+}
+
+func premultiply(v, alpha uint8) uint8 {
+	return uint8(uint32(v) * uint32(alpha) / 0xff)
+}
+
+// coverSegment marks the pixels closer than radius to the segment
+// from (x1, y1) to (x2, y2), and the next ones partly, so that the
+// edges are smooth.
+func coverSegment(mask *image.Alpha, x1, y1, x2, y2, radius float64) {
+	bounds := image.Rect(
+		int(math.Floor(math.Min(x1, x2)-radius-1)), int(math.Floor(math.Min(y1, y2)-radius-1)),
+		int(math.Ceil(math.Max(x1, x2)+radius))+2, int(math.Ceil(math.Max(y1, y2)+radius))+2,
+	).Intersect(mask.Bounds())
+
+	dx, dy := x2-x1, y2-y1
+	length2 := dx*dx + dy*dy
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			t := 0.0
+			if length2 > 0 {
+				t = math.Max(0, math.Min(1, ((px-x1)*dx+(py-y1)*dy)/length2))
+			}
+			ex, ey := px-(x1+t*dx), py-(y1+t*dy)
+			// solid up to radius, fading over a pixel
+			coverage := math.Min(1, radius+1-math.Hypot(ex, ey))
+			if a := uint8(math.Round(coverage * 0xff)); coverage > 0 && a > mask.AlphaAt(x, y).A {
+				mask.SetAlpha(x, y, color.Alpha{A: a})
+			}
+		}
+	}
+}
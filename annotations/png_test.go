@@ -0,0 +1,100 @@
+package annotations
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+func TestRenderPage(t *testing.T) {
+	page := &rmencoding.Rm{Layers: []rmencoding.Layer{{Lines: []rmencoding.Line{
+		{BrushType: rmencoding.Fineliner, BrushColor: rmencoding.Black, BrushSize: rmencoding.Medium,
+			Points: []rmencoding.Point{{X: 100, Y: 100}, {X: 300, Y: 100}}},
+		{BrushType: rmencoding.Fineliner, BrushColor: rmencoding.Grey, BrushSize: rmencoding.Medium,
+			Points: []rmencoding.Point{{X: 100, Y: 500}, {X: 300, Y: 500}}},
+	}}}}
+	colors := PenColors{"grey": {0xff, 0, 0, 0xff}}
+
+	img := renderPage(page, 0.5, colors, nil, nil, false)
+	if b := img.Bounds(); b.Dx() != 702 || b.Dy() != 936 {
+		t.Fatalf("unexpected size %v", b)
+	}
+	for _, tt := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{100, 50, color.RGBA{0, 0, 0, 0xff}},
+		{100, 250, color.RGBA{0xff, 0, 0, 0xff}},
+		{100, 150, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{200, 50, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+	} {
+		if got := img.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("%d,%d: expected %v, got %v", tt.x, tt.y, tt.want, got)
+		}
+	}
+}
+
+func TestPngGenerator(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "rm.png")
+	generator := CreatePngGenerator("testfiles/rm.zip", out, PngGeneratorOptions{AllPages: true, DPI: 113})
+	files, err := generator.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no image written")
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			t.Error(err)
+		}
+	}
+	if len(files) > 1 && files[1] != PngPageName(out, 2) {
+		t.Errorf("unexpected name %s", files[1])
+	}
+}
+
+func TestRenderTallPage(t *testing.T) {
+	page := &rmencoding.Rm{PageHeight: 2 * rmencoding.Height}
+	img := RenderPage(page, 113, nil, nil, nil, false)
+	if b := img.Bounds(); b.Dx() != 702 || b.Dy() != 1872 {
+		t.Errorf("unexpected size %v", b)
+	}
+}
+
+func TestRenderEink(t *testing.T) {
+	page := &rmencoding.Rm{Layers: []rmencoding.Layer{{Lines: []rmencoding.Line{
+		{BrushType: rmencoding.Fineliner, BrushColor: rmencoding.Red, BrushSize: rmencoding.Large,
+			Points: []rmencoding.Point{{X: 100, Y: 100}, {X: 300, Y: 100}}},
+		{BrushType: rmencoding.SharpPencil, BrushColor: rmencoding.Black, BrushSize: rmencoding.Large,
+			Points: []rmencoding.Point{{X: 100, Y: 500}, {X: 900, Y: 500}}},
+	}}}}
+
+	img := renderPage(page, 1, nil, nil, nil, true)
+	levels := make(map[uint8]bool)
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			c := img.RGBAAt(x, y)
+			if c.R != c.G || c.G != c.B || c.A != 0xff || c.R%17 != 0 {
+				t.Fatalf("%d,%d: %v is not a grey of the screen", x, y, c)
+			}
+			if y == 500 && x > 150 && x < 850 {
+				levels[c.R] = true
+			}
+		}
+	}
+	if c := img.RGBAAt(200, 100); c.R == 0 || c.R == 0xff {
+		t.Errorf("the red line should be grey, got %v", c)
+	}
+	if len(levels) < 2 {
+		t.Errorf("the pencil should have a grain, got %v", levels)
+	}
+
+	// the strokes of the pdf are images of the screen
+	if dpi := (PdfGeneratorOptions{Eink: true}).rasterDPI(); dpi != deviceDPI {
+		t.Errorf("unexpected resolution %d", dpi)
+	}
+}
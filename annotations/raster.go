@@ -0,0 +1,148 @@
+package annotations
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// rasterStrokes is the name of the image of the strokes of a page in
+// its resources, see drawRaster.
+const rasterStrokes = "RmapiStrokes"
+
+// flattenDPI is the resolution of the images of Flatten, unless set.
+const flattenDPI = 150
+
+// drawRaster draws the strokes of a page, in device pixels, as a
+// transparent image of options.RasterDPI, or the resolution of the
+// device in the e-ink mode, added to the resources of the page. The
+// viewers draw one image faster than thousands of paths.
+func drawRaster(f *pdf.File, page *pdf.PageObject, data *rmencoding.Rm, options PdfGeneratorOptions) ([]byte, error) {
+	screenWidth, pageHeight := data.ScreenSize()
+	if data.PageHeight > pageHeight {
+		pageHeight = data.PageHeight
+	}
+	scale := float64(options.rasterDPI()) / deviceDPI
+	width := int(math.Round(float64(screenWidth) * scale))
+	height := int(math.Round(float64(pageHeight) * scale))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	renderStrokes(img, data, scale, options.Colors, options.HighlightColors, options.Widths, options.Eink)
+
+	stream := rasterImage(f, img, options.RasterCompression)
+	if options.Flatten {
+		var err error
+		if stream, err = jpegImage(f, img, options.RasterCompression, options.JPEGQuality); err != nil {
+			return nil, err
+		}
+	}
+	ref := f.Add(stream)
+	if err := f.AddResources(page, pdf.Dict{"XObject": pdf.Dict{rasterStrokes: ref}}); err != nil {
+		return nil, err
+	}
+	// the image space goes up from the bottom of the image
+	return []byte(fmt.Sprintf("q %d 0 0 %d 0 %d cm /%s Do Q\n", screenWidth, -pageHeight, pageHeight, rasterStrokes)), nil
+}
+
+// rasterImage returns the image object of img, its transparency being
+// a soft mask. level is the level of the compression of compress/zlib,
+// the default one when 0.
+func rasterImage(f *pdf.File, img *image.RGBA, level int) *pdf.Stream {
+	if level == 0 {
+		level = -1
+	}
+	bounds := img.Bounds()
+	colors := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if c.A == 0 {
+				colors = append(colors, 0, 0, 0)
+			} else {
+				// the colors of img are premultiplied
+				colors = append(colors, unpremultiply(c.R, c.A), unpremultiply(c.G, c.A), unpremultiply(c.B, c.A))
+			}
+		}
+	}
+
+	mask := imageMask(f, img, level)
+	return pdf.NewStreamLevel(pdf.Dict{
+		"Type":             pdf.Name("XObject"),
+		"Subtype":          pdf.Name("Image"),
+		"Width":            int64(bounds.Dx()),
+		"Height":           int64(bounds.Dy()),
+		"ColorSpace":       pdf.Name("DeviceRGB"),
+		"BitsPerComponent": int64(8),
+		"SMask":            mask,
+		"Interpolate":      true,
+	}, colors, level)
+}
+
+// jpegImage returns the image object of img like rasterImage, its
+// colors compressed with JPEG of the given quality, 75 when 0. The
+// transparent pixels are white, the mask hiding them.
+func jpegImage(f *pdf.File, img *image.RGBA, level, quality int) (*pdf.Stream, error) {
+	if level == 0 {
+		level = -1
+	}
+	if quality <= 0 {
+		quality = jpeg.DefaultQuality
+	}
+	bounds := img.Bounds()
+	opaque := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if c.A == 0 {
+				c.R, c.G, c.B = 0xff, 0xff, 0xff
+			} else {
+				c.R, c.G, c.B = unpremultiply(c.R, c.A), unpremultiply(c.G, c.A), unpremultiply(c.B, c.A)
+			}
+			c.A = 0xff
+			opaque.SetRGBA(x, y, c)
+		}
+	}
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, opaque, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+
+	return &pdf.Stream{Dict: pdf.Dict{
+		"Type":             pdf.Name("XObject"),
+		"Subtype":          pdf.Name("Image"),
+		"Width":            int64(bounds.Dx()),
+		"Height":           int64(bounds.Dy()),
+		"ColorSpace":       pdf.Name("DeviceRGB"),
+		"BitsPerComponent": int64(8),
+		"Filter":           pdf.Name("DCTDecode"),
+		"SMask":            imageMask(f, img, level),
+		"Interpolate":      true,
+	}, Data: b.Bytes()}, nil
+}
+
+// imageMask adds the soft mask of the transparency of img to f.
+func imageMask(f *pdf.File, img *image.RGBA, level int) pdf.Ref {
+	bounds := img.Bounds()
+	alphas := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			alphas = append(alphas, img.RGBAAt(x, y).A)
+		}
+	}
+	return f.Add(pdf.NewStreamLevel(pdf.Dict{
+		"Type":             pdf.Name("XObject"),
+		"Subtype":          pdf.Name("Image"),
+		"Width":            int64(bounds.Dx()),
+		"Height":           int64(bounds.Dy()),
+		"ColorSpace":       pdf.Name("DeviceGray"),
+		"BitsPerComponent": int64(8),
+	}, alphas, level))
+}
+
+func unpremultiply(v, alpha uint8) uint8 {
+	return uint8(min(0xff, uint32(v)*0xff/uint32(alpha)))
+}
@@ -0,0 +1,120 @@
+package annotations
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const rendererEnvVar = "RMAPI_RENDERER"
+
+// A Renderer draws the annotations of a document into PDFs.
+type Renderer interface {
+	// Render writes the export of the archive zipName to
+	// outputFilePath, or to the parts named by SplitName, and returns
+	// the written files.
+	Render(zipName, outputFilePath string, options PdfGeneratorOptions) ([]string, error)
+}
+
+// Capabilities are the features supported by a renderer.
+type Capabilities struct {
+	// Color draws the colors of the pens, instead of shades of grey
+	Color bool
+	// Textures draws the textures of the pencils and of the brushes
+	Textures bool
+	// Layers draws the annotations in a layer the viewers can hide
+	Layers bool
+	// Streaming keeps the memory used independent of the size of the
+	// document
+	Streaming bool
+	// Portable needs no C library
+	Portable bool
+	// OCR writes the handwriting recognized as searchable text, see
+	// Recognizer
+	OCR bool
+	// Watermark stamps the watermark of the options on the pages
+	Watermark bool
+}
+
+func (c Capabilities) String() string {
+	var names []string
+	for _, capability := range []struct {
+		name string
+		ok   bool
+	}{
+		{"color", c.Color},
+		{"textures", c.Textures},
+		{"layers", c.Layers},
+		{"streaming", c.Streaming},
+		{"portable", c.Portable},
+		{"ocr", c.OCR},
+		{"watermark", c.Watermark},
+	} {
+		if capability.ok {
+			names = append(names, capability.name)
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ", ")
+}
+
+// RendererInfo describes a renderer of the registry.
+type RendererInfo struct {
+	Name         string
+	Description  string
+	Capabilities Capabilities
+	New          func() Renderer
+}
+
+var (
+	renderers = make(map[string]RendererInfo)
+	// defaultRenderer is used when RMAPI_RENDERER isn't set
+	defaultRenderer = "native"
+)
+
+// RegisterRenderer adds a renderer to the registry. The renderers
+// register themselves when they are built in.
+func RegisterRenderer(info RendererInfo) {
+	if _, ok := renderers[info.Name]; ok {
+		panic("annotations: renderer registered twice: " + info.Name)
+	}
+	renderers[info.Name] = info
+}
+
+// Renderers returns the renderers built in, by name.
+func Renderers() []RendererInfo {
+	list := make([]RendererInfo, 0, len(renderers))
+	for _, info := range renderers {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// LookupRenderer returns the renderer named name.
+func LookupRenderer(name string) (RendererInfo, error) {
+	info, ok := renderers[name]
+	if !ok {
+		var names []string
+		for _, r := range Renderers() {
+			names = append(names, r.Name)
+		}
+		return RendererInfo{}, fmt.Errorf("unknown renderer %s, available: %s", name, strings.Join(names, ", "))
+	}
+	return info, nil
+}
+
+// DefaultRenderer returns the name of the renderer set in
+// RMAPI_RENDERER, or else of the one preferred by the build: cairo
+// when it is built in, native otherwise.
+func DefaultRenderer() string {
+	if name := os.Getenv(rendererEnvVar); name != "" {
+		return name
+	}
+	return defaultRenderer
+}
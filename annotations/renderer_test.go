@@ -0,0 +1,40 @@
+package annotations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRendererRegistry(t *testing.T) {
+	native, err := LookupRenderer("native")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !native.Capabilities.Portable || native.Capabilities.String() != "color, textures, layers, streaming, portable, ocr, watermark" {
+		t.Errorf("unexpected capabilities %s", native.Capabilities)
+	}
+	if _, err := LookupRenderer("skia"); err == nil {
+		t.Error("expected an error for an unknown renderer")
+	}
+
+	found := false
+	for _, info := range Renderers() {
+		found = found || info.Name == DefaultRenderer()
+	}
+	if !found {
+		t.Errorf("the default renderer %s is not registered", DefaultRenderer())
+	}
+
+	t.Setenv(rendererEnvVar, "skia")
+	out := filepath.Join(t.TempDir(), "a4.pdf")
+	if err := CreatePdfGenerator("testfiles/a4.zip", out, PdfGeneratorOptions{}).Generate(); err == nil {
+		t.Error("expected an error for the renderer of the environment")
+	}
+	if err := CreatePdfGenerator("testfiles/a4.zip", out, PdfGeneratorOptions{Renderer: "native"}).Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Error(err)
+	}
+}
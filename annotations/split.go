@@ -0,0 +1,12 @@
+package annotations
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitName returns the path of a part of an export split into
+// several files, numbered from 1, e.g. name-1.pdf.
+func SplitName(outputFilePath string, part int) string {
+	return fmt.Sprintf("%s-%d.pdf", strings.TrimSuffix(outputFilePath, ".pdf"), part)
+}
@@ -0,0 +1,64 @@
+package annotations
+
+import (
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// addStamps stamps the pages of a pdf read by pdfcpu, in the layer of
+// the annotations.
+func addStamps(ctx *model.Context, stamps map[int][]*model.Watermark) error {
+	if err := addStampLayer(ctx); err != nil {
+		return err
+	}
+	return pdfcpu.AddWatermarksSliceMap(ctx, stamps)
+}
+
+// addStampLayer adds the layer of the annotations to a pdf read by
+// pdfcpu, before the layers of the document. pdfcpu puts the stamps in
+// the first layer.
+func addStampLayer(ctx *model.Context) error {
+	root, err := ctx.Catalog()
+	if err != nil {
+		return err
+	}
+	layer, err := ctx.IndRefForNewObject(types.Dict{
+		"Type": types.Name("OCG"),
+		"Name": types.StringLiteral(annotationsLayer),
+	})
+	if err != nil {
+		return err
+	}
+
+	// the layers of the document are kept
+	properties, err := ctx.DereferenceDict(root["OCProperties"])
+	if err != nil {
+		return err
+	}
+	if properties == nil {
+		properties = types.Dict{}
+	}
+	groups, err := ctx.DereferenceArray(properties["OCGs"])
+	if err != nil {
+		return err
+	}
+	properties["OCGs"] = append(types.Array{*layer}, groups...)
+	config, err := ctx.DereferenceDict(properties["D"])
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		config = types.Dict{}
+	}
+	for _, key := range []string{"Order", "ON"} {
+		list, err := ctx.DereferenceArray(config[key])
+		if err != nil {
+			return err
+		}
+		config[key] = append(list, *layer)
+	}
+	properties["D"] = config
+	root["OCProperties"] = properties
+	return nil
+}
@@ -0,0 +1,95 @@
+package annotations
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/joagonca/rmapi/pdf"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+func testPDF(t *testing.T, pages int) []byte {
+	doc := pdf.NewDocument()
+	for i := 0; i < pages; i++ {
+		doc.AddPage(pdf.A4Width, pdf.A4Height).Line(100, 100, 200, 200, 1)
+	}
+	var b bytes.Buffer
+	if err := doc.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestStampLayer(t *testing.T) {
+	// the document has a layer of its own
+	f, err := pdf.Open(testPDF(t, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.AddLayer("Notes"); err != nil {
+		t.Fatal(err)
+	}
+	var background bytes.Buffer
+	if err := f.Write(&background); err != nil {
+		t.Fatal(err)
+	}
+
+	wm, err := api.PDFWatermarkForReadSeeker(bytes.NewReader(testPDF(t, 1)), 1,
+		"pos:tl, off:0 0, scale:1 abs, rot:0", true, false, types.POINTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.ADDWATERMARKS
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(background.Bytes()), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addStamps(ctx, map[int][]*model.Watermark{2: {wm}}); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := api.Write(ctx, &out, conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if f, err = pdf.Open(out.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	root, _ := f.Get(f.Trailer(), "Root").(pdf.Dict)
+	properties, _ := f.Get(root, "OCProperties").(pdf.Dict)
+	groups, _ := f.Get(properties, "OCGs").(pdf.Array)
+	if len(groups) != 2 {
+		t.Fatalf("expected the 2 layers, got %v", groups)
+	}
+	layer := groups[0]
+	group, _ := f.Resolve(layer)
+	if name, _ := f.Get(group.(pdf.Dict), "Name").(pdf.String); string(name) != annotationsLayer {
+		t.Errorf("the first layer should be the annotations, got %v", group)
+	}
+	config, _ := f.Get(properties, "D").(pdf.Dict)
+	if order, _ := f.Get(config, "Order").(pdf.Array); len(order) != 2 {
+		t.Errorf("the layers should be listed, got %v", order)
+	}
+
+	// the stamp of the second page is in the layer
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources, _ := f.Resolve(pages[1].Attr("Resources"))
+	xobjects, _ := f.Get(resources.(pdf.Dict), "XObject").(pdf.Dict)
+	found := false
+	for name := range xobjects {
+		if form, _ := f.Resolve(xobjects[name]); form != nil {
+			if s, ok := form.(*pdf.Stream); ok && s.Dict["OC"] == layer {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Error("the stamp is not in the layer of the annotations")
+	}
+}
@@ -0,0 +1,115 @@
+package annotations
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+// WriteSVG draws the strokes of a page as an svg image of the size of
+// the screen of the device, or of the page when it is taller. The
+// strokes are paths drawn like in the PDFs, see brushStrokes, and the
+// layers are Inkscape layers. The highlighter is drawn half
+// transparent.
+func WriteSVG(w io.Writer, data *rmencoding.Rm, colors, highlightColors PenColors, widths PenWidths) error {
+	width, height := data.ScreenSize()
+	if data != nil && data.PageHeight > height {
+		height = data.PageHeight
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="white"/>`+"\n")
+
+	if data != nil {
+		for i, layer := range data.Layers {
+			name := layer.Name
+			if name == "" {
+				name = fmt.Sprintf("Layer %d", i+1)
+			}
+			bw.WriteString(`<g inkscape:groupmode="layer" inkscape:label="`)
+			xml.EscapeText(bw, []byte(name))
+			bw.WriteString("\">\n")
+			for _, line := range layer.Lines {
+				writeSVGLine(bw, line, colors, highlightColors, widths)
+			}
+			bw.WriteString("</g>\n")
+		}
+	}
+
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+func writeSVGLine(w *bufio.Writer, line rmencoding.Line, colors, highlightColors PenColors, widths PenWidths) {
+	if len(line.Points) < 1 {
+		return
+	}
+
+	c := svgColor(lineColor(line, colors, highlightColors))
+	switch line.BrushType {
+	case rmencoding.Eraser, rmencoding.EraseArea:
+		return
+	case rmencoding.Highlighter, rmencoding.HighlighterV5:
+		fmt.Fprintf(w, `<path fill="none" stroke="%s" stroke-width="30" stroke-opacity="0.5" stroke-linejoin="round" style="mix-blend-mode:multiply" d="`, c)
+		writeSVGPoints(w, line.Points)
+		w.WriteString("\"/>\n")
+		return
+	}
+
+	for _, stroke := range brushStrokes(line, widths.line(line)) {
+		opacity := ""
+		if stroke.opacity < 1 {
+			opacity = fmt.Sprintf(` opacity="%.2f"`, stroke.opacity)
+		}
+
+		if stroke.outline != nil {
+			// a variable width
+			fmt.Fprintf(w, `<path fill="%s"%s d="`, c, opacity)
+			for i, v := range stroke.outline {
+				op := "L"
+				if i == 0 {
+					op = "M"
+				}
+				fmt.Fprintf(w, "%s%.2f %.2f", op, v.x, v.y)
+			}
+			w.WriteString("Z\"/>\n")
+			continue
+		}
+
+		fmt.Fprintf(w, `<path fill="none" stroke="%s" stroke-width="%.2f" stroke-linecap="%s" stroke-linejoin="round"%s d="`,
+			c, stroke.width, svgLineCaps[stroke.cap], opacity)
+		writeSVGPoints(w, stroke.points)
+		w.WriteString("\"/>\n")
+	}
+}
+
+// writeSVGPoints writes the path data of the points of a stroke.
+func writeSVGPoints(w *bufio.Writer, points []rmencoding.Point) {
+	for i, point := range points {
+		op := "L"
+		if i == 0 {
+			op = "M"
+		}
+		fmt.Fprintf(w, "%s%.2f %.2f", op, point.X, point.Y)
+	}
+	if len(points) == 1 {
+		// a dot
+		fmt.Fprintf(w, "L%.2f %.2f", points[0].X, points[0].Y)
+	}
+}
+
+var svgLineCaps = map[lineCap]string{
+	capButt:   "butt",
+	capRound:  "round",
+	capSquare: "square",
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,68 @@
+package annotations
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+func TestWriteSVG(t *testing.T) {
+	points := []rmencoding.Point{{X: 100, Y: 100, Width: 4, Pressure: 0.5}, {X: 300, Y: 120, Width: 6, Pressure: 0.5}}
+	page := &rmencoding.Rm{PageHeight: 2600, Layers: []rmencoding.Layer{
+		{Name: "Notes & sketches", Lines: []rmencoding.Line{
+			{BrushType: rmencoding.FinelinerV5, BrushColor: rmencoding.Blue, BrushSize: rmencoding.Medium, Points: points},
+			{BrushType: rmencoding.Calligraphy, BrushColor: rmencoding.Black, BrushSize: rmencoding.Medium, Points: points},
+		}},
+		{Lines: []rmencoding.Line{
+			{BrushType: rmencoding.HighlighterV5, BrushColor: rmencoding.Green, Points: points},
+			{BrushType: rmencoding.EraseArea, Points: points},
+		}},
+	}}
+
+	var b bytes.Buffer
+	if err := WriteSVG(&b, page, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	svg := b.String()
+
+	// well formed
+	d := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid svg: %v\n%s", err, svg)
+		}
+	}
+
+	for _, want := range []string{
+		`height="2600"`,
+		`inkscape:label="Notes &amp; sketches"`,
+		`inkscape:label="Layer 2"`,
+		`<path fill="none" stroke="` + svgColor(defaultPenColors["blue"]) + `" stroke-width="1.20" stroke-linecap="round"`,
+		`<path fill="` + svgColor(defaultPenColors["black"]) + `" d="M`,
+		`stroke="` + svgColor(defaultHighlightColors["green"]) + `" stroke-width="30" stroke-opacity="0.5"`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("expected %s in\n%s", want, svg)
+		}
+	}
+	if n := strings.Count(svg, "<path"); n != 3 {
+		t.Errorf("expected 3 paths, got %d", n)
+	}
+
+	// the highlighters remapped like in the pdf
+	orange := color.RGBA{0xff, 0x80, 0, 0xff}
+	b.Reset()
+	if err := WriteSVG(&b, page, nil, PenColors{"green": orange}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := `stroke="` + svgColor(orange) + `" stroke-width="30"`; !strings.Contains(b.String(), want) {
+		t.Errorf("expected %s in\n%s", want, b.String())
+	}
+}
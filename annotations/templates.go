@@ -0,0 +1,139 @@
+package annotations
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// A pageTemplate draws the background of the pages of a notebook in
+// device pixels, on a page of the given size.
+type pageTemplate func(b *bytes.Buffer, width, height, spacing float64)
+
+// pageTemplates are the templates drawn, by the kind in their name, such
+// as "P Lines small", with their spacing by size. The other templates,
+// such as the blank one, aren't drawn.
+var pageTemplates = map[string]struct {
+	draw    pageTemplate
+	spacing [3]float64
+}{
+	"lines":     {drawLines, [3]float64{52, 70, 90}},
+	"grid":      {drawGrid, [3]float64{52, 78, 104}},
+	"dots":      {drawDots, [3]float64{52, 78, 104}},
+	"checklist": {drawChecklist, [3]float64{70, 70, 90}},
+	"day":       {drawDay, [3]float64{78, 78, 104}},
+}
+
+// templateMargin is the space at the top of the pages with lines.
+const templateMargin = 156
+
+// lookupTemplate returns the drawing of a template named in the
+// pagedata of a notebook and its spacing, nil when it isn't drawn.
+func lookupTemplate(name string) (pageTemplate, float64) {
+	fields := strings.Fields(strings.ToLower(name))
+	// the orientation comes first
+	if len(fields) > 0 && (fields[0] == "p" || fields[0] == "ls") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return nil, 0
+	}
+	template, ok := pageTemplates[fields[0]]
+	if !ok {
+		return nil, 0
+	}
+	size := 1
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "small", "s":
+			size = 0
+		case "large", "l":
+			size = 2
+		}
+	}
+	return template.draw, template.spacing[size]
+}
+
+// drawTemplate returns the content stream drawing the template of a
+// page of a notebook under its strokes, nil when it isn't drawn. The
+// templates of the notebooks in landscape are drawn on the turned
+// screen.
+func drawTemplate(f *pdf.File, page *pdf.PageObject, data *rmencoding.Rm, landscape bool, name string) []byte {
+	draw, spacing := lookupTemplate(name)
+	if draw == nil {
+		return nil
+	}
+	width, height := data.ScreenSize()
+	if data != nil && data.PageHeight > height {
+		height = data.PageHeight
+	}
+
+	var b bytes.Buffer
+	device, _ := deviceMatrix(f, page, data, landscape)
+	if landscape {
+		// from the turned screen to the pixels of the device
+		device = pdf.Matrix{0, 1, -1, 0, float64(width), 0}.Multiply(device)
+		width, height = height, width
+	}
+	fmt.Fprintf(&b, "q %s cm 0.75 G 2 w\n", device)
+	draw(&b, float64(width), float64(height), spacing)
+	b.WriteString("Q\n")
+	return b.Bytes()
+}
+
+func templateLine(b *bytes.Buffer, x1, y1, x2, y2 float64) {
+	fmt.Fprintf(b, "%.2f %.2f m %.2f %.2f l\n", x1, y1, x2, y2)
+}
+
+func drawLines(b *bytes.Buffer, width, height, spacing float64) {
+	for y := float64(templateMargin); y < height; y += spacing {
+		templateLine(b, 0, y, width, y)
+	}
+	b.WriteString("S\n")
+}
+
+func drawGrid(b *bytes.Buffer, width, height, spacing float64) {
+	for y := spacing; y < height; y += spacing {
+		templateLine(b, 0, y, width, y)
+	}
+	for x := spacing; x < width; x += spacing {
+		templateLine(b, x, 0, x, height)
+	}
+	b.WriteString("S\n")
+}
+
+// drawDots draws the dots as segments of no length with round caps.
+func drawDots(b *bytes.Buffer, width, height, spacing float64) {
+	b.WriteString("q 1 J 6 w\n")
+	for y := spacing; y < height; y += spacing {
+		for x := spacing; x < width; x += spacing {
+			templateLine(b, x, y, x, y)
+		}
+	}
+	b.WriteString("S Q\n")
+}
+
+// drawChecklist draws lines with a box at their left.
+func drawChecklist(b *bytes.Buffer, width, height, spacing float64) {
+	const box = 32
+	drawLines(b, width, height, spacing)
+	for y := float64(templateMargin) + spacing; y < height; y += spacing {
+		fmt.Fprintf(b, "%d %.2f %d %d re\n", 80, y-(spacing+box)/2, box, box)
+	}
+	b.WriteString("S\n")
+}
+
+// drawDay draws a daily planner: a title, and the lines of the hours
+// right of a margin.
+func drawDay(b *bytes.Buffer, width, height, spacing float64) {
+	const margin = 180
+	templateLine(b, 0, templateMargin, width, templateMargin)
+	for y := float64(templateMargin) + spacing; y < height; y += spacing {
+		templateLine(b, margin, y, width, y)
+	}
+	templateLine(b, margin, templateMargin, margin, height)
+	b.WriteString("S\n")
+}
@@ -0,0 +1,44 @@
+package annotations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joagonca/rmapi/pdf"
+)
+
+func TestLookupTemplate(t *testing.T) {
+	for name, spacing := range map[string]float64{
+		"P Lines small":  52,
+		"LS Grid large":  104,
+		"P Dots S":       52,
+		"P Checklist":    70,
+		"P Day":          78,
+		"Blank":          0,
+		"P Storyboard 2": 0,
+		"":               0,
+	} {
+		draw, s := lookupTemplate(name)
+		if (draw == nil) != (spacing == 0) || s != spacing {
+			t.Errorf("%q: unexpected spacing %v", name, s)
+		}
+	}
+}
+
+func TestDrawTemplate(t *testing.T) {
+	out := pdf.NewFile()
+	page := newPage(out, nil, nil, nil, false)
+
+	if content := drawTemplate(out, page, nil, false, "Blank"); content != nil {
+		t.Errorf("the blank template should not be drawn, got %q", content)
+	}
+
+	content := string(drawTemplate(out, page, nil, false, "P Lines small"))
+	if !strings.HasPrefix(content, "q 0.32 0 0 -0.32 0 594 cm 0.75 G") || !strings.HasSuffix(content, "S\nQ\n") {
+		t.Errorf("unexpected content %q", content)
+	}
+	// from the margin to the bottom of the screen
+	if n := strings.Count(content, " l\n"); n != 33 {
+		t.Errorf("expected 33 lines, got %d", n)
+	}
+}
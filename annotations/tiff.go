@@ -0,0 +1,242 @@
+package annotations
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"os"
+
+	"github.com/hhrutter/lzw"
+	"github.com/joagonca/rmapi/archive"
+)
+
+// TiffGenerator exports the strokes of the pages of a document as a
+// multi-page TIFF, on a white background, for the document management
+// systems which archive TIFF.
+type TiffGenerator struct {
+	zipName        string
+	outputFilePath string
+	options        TiffGeneratorOptions
+}
+
+type TiffGeneratorOptions struct {
+	// AllPages keeps the pages without annotations
+	AllPages bool
+	// DPI is the resolution of the pages, the one of the device when 0
+	DPI int
+	// Colors replaces the colors of the pens, HighlightColors the ones
+	// of the highlighters
+	Colors          PenColors
+	HighlightColors PenColors
+	// Widths replaces the widths of the strokes of the brushes
+	Widths PenWidths
+	// Pages are the pages exported, all of them when empty
+	Pages PageRanges
+	// Layers selects the layers of the pages exported
+	Layers LayerFilter
+	// Eink draws the strokes like the screen of the device, see
+	// PdfGeneratorOptions
+	Eink bool
+}
+
+func CreateTiffGenerator(zipName, outputFilePath string, options TiffGeneratorOptions) *TiffGenerator {
+	return &TiffGenerator{zipName: zipName, outputFilePath: outputFilePath, options: options}
+}
+
+// Generate writes the pages in the output file, compressed with LZW.
+// They are rendered and written one at a time.
+func (p *TiffGenerator) Generate() error {
+	file, err := os.Open(p.zipName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	zip := archive.NewZip()
+	if err := zip.ReadLazy(file, fi.Size()); err != nil {
+		return err
+	}
+
+	dpi := p.options.DPI
+	if dpi <= 0 {
+		dpi = deviceDPI
+	}
+	scale := float64(dpi) / deviceDPI
+
+	out, err := os.Create(p.outputFilePath)
+	if err != nil {
+		return err
+	}
+	w, err := newTiffWriter(out)
+	if err == nil {
+		err = p.writePages(w, zip, dpi, scale)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(p.outputFilePath)
+	}
+	return err
+}
+
+func (p *TiffGenerator) writePages(w *tiffWriter, zip *archive.Zip, dpi int, scale float64) error {
+	for index := range zip.Pages {
+		if !p.options.Pages.Contains(index + 1) {
+			continue
+		}
+		data, err := zip.PageDrawing(index)
+		if err != nil {
+			return err
+		}
+		data = p.options.Layers.Apply(data)
+		if data == nil && !p.options.AllPages {
+			continue
+		}
+		if err := w.addPage(renderPage(data, scale, p.options.Colors, p.options.HighlightColors, p.options.Widths, p.options.Eink), dpi); err != nil {
+			return err
+		}
+	}
+	if w.pages == 0 {
+		return errors.New("the document has no annotations, use the option to export all pages")
+	}
+	return nil
+}
+
+// TIFF tags and field types, see the TIFF 6.0 specification
+const (
+	tiffNewSubfileType = 254
+	tiffImageWidth     = 256
+	tiffImageLength    = 257
+	tiffBitsPerSample  = 258
+	tiffCompression    = 259
+	tiffPhotometric    = 262
+	tiffStripOffsets   = 273
+	tiffSamplesPerPix  = 277
+	tiffRowsPerStrip   = 278
+	tiffStripByteCount = 279
+	tiffXResolution    = 282
+	tiffYResolution    = 283
+	tiffPlanarConfig   = 284
+	tiffResolutionUnit = 296
+	tiffPageNumber     = 297
+
+	tiffShort    = 3
+	tiffLong     = 4
+	tiffRational = 5
+)
+
+type tiffEntry struct {
+	tag, typ uint16
+	count    uint32
+	// value is the value, or its offset when it takes more than 4
+	// bytes
+	value uint32
+}
+
+// A tiffWriter appends the pages of a multi-page TIFF to a file, as RGB
+// images in a single strip compressed with LZW. The offset of each page
+// is written in the previous one once it is known.
+type tiffWriter struct {
+	f *os.File
+	// offset is the end of the written data, and next the position of
+	// the offset of the next page
+	offset, next int64
+	pages        int
+}
+
+func newTiffWriter(f *os.File) (*tiffWriter, error) {
+	// little endian, the offset of the first page is set by addPage
+	if _, err := f.Write([]byte{'I', 'I', 42, 0, 0, 0, 0, 0}); err != nil {
+		return nil, err
+	}
+	return &tiffWriter{f: f, offset: 8, next: 4}, nil
+}
+
+func (t *tiffWriter) addPage(img *image.RGBA, dpi int) error {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+
+	var strip bytes.Buffer
+	lw := lzw.NewWriter(&strip, true)
+	row := make([]byte, 3*width)
+	for y := 0; y < height; y++ {
+		pix := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			copy(row[3*x:3*x+3], pix[4*x:4*x+3])
+		}
+		if _, err := lw.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := lw.Close(); err != nil {
+		return err
+	}
+	if strip.Len()%2 == 1 {
+		// the following values start on a word
+		strip.WriteByte(0)
+	}
+
+	stripOffset := t.offset
+	// bits per sample and resolution, after the strip
+	extra := stripOffset + int64(strip.Len())
+	var b bytes.Buffer
+	b.Write(strip.Bytes())
+	for i := 0; i < 3; i++ {
+		binary.Write(&b, binary.LittleEndian, uint16(8))
+	}
+	b.Write([]byte{0, 0})
+	for i := 0; i < 2; i++ {
+		binary.Write(&b, binary.LittleEndian, [2]uint32{uint32(dpi), 1})
+	}
+
+	entries := []tiffEntry{
+		{tiffNewSubfileType, tiffLong, 1, 2},
+		{tiffImageWidth, tiffLong, 1, uint32(width)},
+		{tiffImageLength, tiffLong, 1, uint32(height)},
+		{tiffBitsPerSample, tiffShort, 3, uint32(extra)},
+		// LZW
+		{tiffCompression, tiffShort, 1, 5},
+		// RGB
+		{tiffPhotometric, tiffShort, 1, 2},
+		{tiffStripOffsets, tiffLong, 1, uint32(stripOffset)},
+		{tiffSamplesPerPix, tiffShort, 1, 3},
+		{tiffRowsPerStrip, tiffLong, 1, uint32(height)},
+		{tiffStripByteCount, tiffLong, 1, uint32(strip.Len())},
+		{tiffXResolution, tiffRational, 1, uint32(extra + 8)},
+		{tiffYResolution, tiffRational, 1, uint32(extra + 16)},
+		{tiffPlanarConfig, tiffShort, 1, 1},
+		// inches
+		{tiffResolutionUnit, tiffShort, 1, 2},
+		// the number of the page, the total being unknown
+		{tiffPageNumber, tiffShort, 2, uint32(t.pages)},
+	}
+	ifd := t.offset + int64(b.Len())
+	binary.Write(&b, binary.LittleEndian, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&b, binary.LittleEndian, e)
+	}
+	// no next page until there is one
+	binary.Write(&b, binary.LittleEndian, uint32(0))
+	if ifd > 0xffffffff {
+		return errors.New("the TIFF is larger than 4 GB")
+	}
+
+	if _, err := t.f.Write(b.Bytes()); err != nil {
+		return err
+	}
+	var offset [4]byte
+	binary.LittleEndian.PutUint32(offset[:], uint32(ifd))
+	if _, err := t.f.WriteAt(offset[:], t.next); err != nil {
+		return err
+	}
+	t.offset += int64(b.Len())
+	t.next = t.offset - 4
+	t.pages++
+	return nil
+}
@@ -0,0 +1,54 @@
+package annotations
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hhrutter/tiff"
+)
+
+func TestTiff(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "strange.tiff")
+	generator := CreateTiffGenerator("testfiles/strange.zip", out, TiffGeneratorOptions{DPI: 50})
+	if err := generator.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the two annotated pages, chained
+	pages := 0
+	for ifd := binary.LittleEndian.Uint32(data[4:]); ifd != 0; pages++ {
+		count := int(binary.LittleEndian.Uint16(data[ifd:]))
+		ifd = binary.LittleEndian.Uint32(data[int(ifd)+2+12*count:])
+	}
+	if pages != 2 {
+		t.Errorf("expected 2 pages, got %d", pages)
+	}
+
+	img, err := tiff.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RenderPage(nil, 50, nil, nil, nil, false).Bounds()
+	if img.Bounds() != want {
+		t.Errorf("expected a page of %v, got %v", want, img.Bounds())
+	}
+	drawn := false
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y && !drawn; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+				drawn = true
+				break
+			}
+		}
+	}
+	if !drawn {
+		t.Error("the strokes are not drawn")
+	}
+}
@@ -0,0 +1,119 @@
+package annotations
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// A Watermark is stamped on every page exported by the native
+// renderer, under the annotations.
+type Watermark struct {
+	// Text is written in grey across the page, {date} being replaced
+	// by the date of the export
+	Text string
+	// Image is the path of a png or jpeg image drawn in the middle of
+	// the page, a third of its width
+	Image string
+	// Opacity is the opacity of the watermark, 0.3 when 0
+	Opacity float64
+}
+
+// watermarkImage is the name of the image of the watermark in the
+// resources of the pages.
+const watermarkImage = "RmapiWatermark"
+
+// watermark is a Watermark ready to be drawn.
+type watermark struct {
+	text    string
+	img     *image.RGBA
+	opacity float64
+	// refs are the images added to the files, one per file
+	refs map[*pdf.File]pdf.Ref
+}
+
+// newWatermark reads the image of w, now being the date of the export.
+// It returns nil when w is empty.
+func newWatermark(w Watermark, now time.Time) (*watermark, error) {
+	if w.Text == "" && w.Image == "" {
+		return nil, nil
+	}
+	if w.Opacity < 0 || w.Opacity > 1 {
+		return nil, fmt.Errorf("invalid opacity %v of the watermark, expected between 0 and 1", w.Opacity)
+	}
+	wm := &watermark{
+		text:    strings.ReplaceAll(w.Text, "{date}", now.Format("2006-01-02")),
+		opacity: w.Opacity,
+		refs:    make(map[*pdf.File]pdf.Ref),
+	}
+	if wm.opacity == 0 {
+		wm.opacity = 0.3
+	}
+	if w.Image != "" {
+		f, err := os.Open(w.Image)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		img, _, err := image.Decode(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the image of the watermark: %w", err)
+		}
+		if img.Bounds().Empty() {
+			return nil, errors.New("the image of the watermark is empty")
+		}
+		wm.img = image.NewRGBA(img.Bounds())
+		draw.Draw(wm.img, wm.img.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+	return wm, nil
+}
+
+// draw returns the content stamping the watermark on a page of f, in
+// the middle of the page as shown. The image is added to f the first
+// time.
+func (w *watermark) draw(f *pdf.File, page *pdf.PageObject) ([]byte, error) {
+	width, height := f.DisplaySize(page)
+	var b strings.Builder
+	fmt.Fprintf(&b, "q %s cm", f.DisplayMatrix(page))
+	if alpha := opacityState(w.opacity); alpha != "" {
+		fmt.Fprintf(&b, " /%s gs", alpha)
+	}
+	b.WriteString("\n")
+
+	if w.img != nil {
+		ref, ok := w.refs[f]
+		if !ok {
+			ref = f.Add(rasterImage(f, w.img, 0))
+			w.refs[f] = ref
+		}
+		if err := f.AddResources(page, pdf.Dict{"XObject": pdf.Dict{watermarkImage: ref}}); err != nil {
+			return nil, err
+		}
+		bounds := w.img.Bounds()
+		iw := width / 3
+		ih := iw * float64(bounds.Dy()) / float64(bounds.Dx())
+		fmt.Fprintf(&b, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", iw, ih, (width-iw)/2, (height-ih)/2, watermarkImage)
+	}
+
+	if w.text != "" {
+		// along the diagonal, over most of it
+		angle := math.Atan2(height, width)
+		size := 0.7 * math.Hypot(width, height) / pdf.TextWidth(w.text, pdf.HelveticaBold, 1)
+		size = math.Min(size, height/4)
+		cos, sin := math.Cos(angle), math.Sin(angle)
+		fmt.Fprintf(&b, "BT 0.5 g /%s %.2f Tf %.4f %.4f %.4f %.4f %.2f %.2f Tm %.2f %.2f Td %s Tj ET\n",
+			fontResources[pdf.HelveticaBold], size, cos, sin, -sin, cos, width/2, height/2,
+			-pdf.TextWidth(w.text, pdf.HelveticaBold, size)/2, -0.35*size, pdf.EncodeText(w.text))
+	}
+	b.WriteString("Q\n")
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,71 @@
+package annotations
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joagonca/rmapi/pdf"
+)
+
+func TestWatermarkText(t *testing.T) {
+	w, err := newWatermark(Watermark{Text: "Exported {date}"}, time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.text != "Exported 2024-03-09" || w.opacity != 0.3 {
+		t.Errorf("unexpected watermark %+v", w)
+	}
+	if w, err := newWatermark(Watermark{}, time.Now()); w != nil || err != nil {
+		t.Errorf("expected no watermark, got %v %v", w, err)
+	}
+	if _, err := newWatermark(Watermark{Text: "a", Opacity: 2}, time.Now()); err == nil {
+		t.Error("expected an error for the opacity")
+	}
+}
+
+func TestNativeWatermark(t *testing.T) {
+	dir := t.TempDir()
+	logo := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	logo.Set(1, 1, color.RGBA{0xff, 0, 0, 0xff})
+	logoName := filepath.Join(dir, "logo.png")
+	if err := writePng(logoName, logo); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "a4.pdf")
+	options := PdfGeneratorOptions{
+		Renderer:  "native",
+		Watermark: Watermark{Text: "CONFIDENTIAL", Image: logoName, Opacity: 0.5},
+	}
+	if err := CreatePdfGenerator("testfiles/a4.zip", out, options).Generate(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := pdf.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := pageContent(t, f, pages[0])
+	text, img, annotations := strings.Index(content, "(CONFIDENTIAL) Tj"), strings.Index(content, "/RmapiWatermark Do"), strings.Index(content, "BDC")
+	if text < 0 || img < 0 {
+		t.Fatalf("the watermark is missing in %q", content)
+	}
+	if annotations >= 0 && (text > annotations || img > annotations) {
+		t.Error("the watermark should be under the annotations")
+	}
+	if !strings.Contains(content, "/"+opacityState(0.5)+" gs") {
+		t.Error("the watermark should be half transparent")
+	}
+}
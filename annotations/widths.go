@@ -0,0 +1,37 @@
+package annotations
+
+import (
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+// PenWidths replaces the widths of the strokes in the exports, by the
+// name of the brush (ballpoint, fineliner, sharp pencil...). default
+// replaces the formula of the brushes which aren't set.
+type PenWidths map[string]PenWidth
+
+// A PenWidth maps the size of a brush to the width of its strokes in
+// device pixels: the size times Scale, plus Offset. A Scale of 0 draws
+// all the sizes with the same width.
+type PenWidth struct {
+	Scale, Offset float64
+}
+
+// defaultPenWidth is the formula of the original exports.
+var defaultPenWidth = PenWidth{Scale: 6.0, Offset: -10.8}
+
+// line returns the width of a stroke in device pixels, at least half a
+// pixel.
+func (w PenWidths) line(line rmencoding.Line) float64 {
+	pen, ok := w[line.BrushType.String()]
+	if !ok {
+		pen, ok = w["default"]
+	}
+	if !ok {
+		pen = defaultPenWidth
+	}
+	width := float64(line.BrushSize)*pen.Scale + pen.Offset
+	if width < 0.5 {
+		width = 0.5
+	}
+	return width
+}
@@ -0,0 +1,35 @@
+package annotations
+
+import (
+	"math"
+	"testing"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+func TestPenWidths(t *testing.T) {
+	widths := PenWidths{
+		"fineliner": {Scale: 2, Offset: -1},
+		"pencil":    {Offset: 3},
+	}
+	for _, tc := range []struct {
+		widths PenWidths
+		brush  rmencoding.BrushType
+		size   rmencoding.BrushSize
+		want   float64
+	}{
+		{nil, rmencoding.BallPointV5, rmencoding.Large, 2.125*6 - 10.8},
+		{nil, rmencoding.FinelinerV5, rmencoding.Small, 0.5},
+		{widths, rmencoding.FinelinerV5, rmencoding.Medium, 3},
+		{widths, rmencoding.Fineliner, rmencoding.Medium, 3},
+		{widths, rmencoding.TiltPencilV5, rmencoding.Large, 3},
+		{widths, rmencoding.BallPointV5, rmencoding.Large, 2.125*6 - 10.8},
+		{PenWidths{"default": {Scale: 1}}, rmencoding.MarkerV5, rmencoding.Medium, 2},
+		{PenWidths{"default": {Scale: 1}, "marker": {Offset: 5}}, rmencoding.MarkerV5, rmencoding.Medium, 5},
+	} {
+		line := rmencoding.Line{BrushType: tc.brush, BrushSize: tc.size}
+		if got := tc.widths.line(line); math.Abs(got-tc.want) > 1e-6 {
+			t.Errorf("%v %s %v: expected %v, got %v", tc.widths, tc.brush, tc.size, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/joagonca/rmapi/api/sync10"
+	"github.com/joagonca/rmapi/api/sync15"
+	"github.com/joagonca/rmapi/config"
+	"github.com/joagonca/rmapi/log"
+)
+
+const authRetries = 3
+
+// OpenAccount logs in a configured account and reads its documents.
+// The requests are sent to the current hosts, see config.SetHosts. The
+// account must have been registered before, by running rmapi with its
+// config file.
+func OpenAccount(account *config.Account) (ApiCtx, *UserInfo, error) {
+	if config.LoadTokens(account.Config).DeviceToken == "" {
+		return nil, nil, fmt.Errorf("account %s isn't registered, run rmapi once with RMAPI_CONFIG=%s", account.Name, account.Config)
+	}
+
+	var err error
+	for i := 0; i < authRetries; i++ {
+		httpCtx := AuthConfigHttpCtx(account.Config, i > 0, true)
+
+		var userInfo *UserInfo
+		userInfo, err = ParseToken(httpCtx.Tokens().UserToken)
+		if err != nil {
+			log.Trace.Println(err)
+			continue
+		}
+
+		var ctx ApiCtx
+		switch userInfo.SyncVersion {
+		case Version10:
+			ctx, err = sync10.CreateCtx(httpCtx)
+		case Version15:
+			ctx, err = sync15.CreateAccountCtx(httpCtx, account.Name)
+		default:
+			return nil, nil, fmt.Errorf("unsupported sync version %s", userInfo.SyncVersion)
+		}
+		if err == nil {
+			return ctx, userInfo, nil
+		}
+		log.Trace.Println(err)
+	}
+	return nil, nil, fmt.Errorf("failed to open account %s: %v", account.Name, err)
+}
@@ -21,12 +21,25 @@ type ApiCtx interface {
 	CreateDir(parentId, name string, notify bool) (*model.Document, error)
 	UploadDocument(parentId string, sourceDocPath string, notify bool) (*model.Document, error)
 	MoveEntry(src, dstDir *model.Node, name string) (*model.Node, error)
+	SetPinned(node *model.Node, pinned bool) (*model.Document, error)
 	DeleteEntry(node *model.Node) error
 	SyncComplete() error
 	Nuke() error
 	Refresh() error
 }
 
+// An Inspector describes the files of the documents without
+// downloading them. Only the sync 1.5 api implements it.
+type Inspector interface {
+	DocumentFiles() ([]*model.DocumentFiles, error)
+}
+
+// An Updater replaces the files of an existing document, keeping its ID.
+// Only the sync 1.5 api implements it.
+type Updater interface {
+	UpdateDocument(docId, sourceZip string, notify bool) (*model.Document, error)
+}
+
 type UserToken struct {
 	Auth0 struct {
 		UserID string
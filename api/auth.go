@@ -22,6 +22,11 @@ func AuthHttpCtx(reAuth, nonInteractive bool) *transport.HttpClientCtx {
 	if err != nil {
 		log.Error.Fatal("failed to get config path")
 	}
+	return AuthConfigHttpCtx(configPath, reAuth, nonInteractive)
+}
+
+// AuthConfigHttpCtx is AuthHttpCtx with the tokens saved in configPath.
+func AuthConfigHttpCtx(configPath string, reAuth, nonInteractive bool) *transport.HttpClientCtx {
 	authTokens := config.LoadTokens(configPath)
 	httpClientCtx := transport.CreateHttpClientCtx(authTokens)
 
@@ -38,7 +43,7 @@ func AuthHttpCtx(reAuth, nonInteractive bool) *transport.HttpClientCtx {
 		log.Trace.Println("device token", deviceToken)
 
 		authTokens.DeviceToken = deviceToken
-		httpClientCtx.Tokens.DeviceToken = deviceToken
+		httpClientCtx.SetDeviceToken(deviceToken)
 
 		config.SaveTokens(configPath, authTokens)
 	}
@@ -56,7 +61,7 @@ func AuthHttpCtx(reAuth, nonInteractive bool) *transport.HttpClientCtx {
 		log.Trace.Println("user token:", userToken)
 
 		authTokens.UserToken = userToken
-		httpClientCtx.Tokens.UserToken = userToken
+		httpClientCtx.SetUserToken(userToken)
 
 		config.SaveTokens(configPath, authTokens)
 	}
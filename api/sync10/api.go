@@ -202,6 +202,25 @@ func (ctx *ApiCtx) MoveEntry(src, dstDir *model.Node, name string) (*model.Node,
 	return &model.Node{&doc, src.Children, dstDir}, nil
 }
 
+// SetPinned pins (stars) or unpins an entry.
+func (ctx *ApiCtx) SetPinned(node *model.Node, pinned bool) (*model.Document, error) {
+	metaDoc := node.Document.ToMetaDocument()
+	metaDoc.Version = metaDoc.Version + 1
+	metaDoc.Bookmarked = pinned
+
+	err := ctx.Http.Put(transport.UserBearer, config.UpdateStatus, util.InSlice(metaDoc), nil)
+
+	if err != nil {
+		log.Error.Println("failed to pin entry", err)
+		return nil, err
+	}
+
+	doc := metaDoc.ToDocument()
+	doc.Version = metaDoc.Version
+	ctx.Filetree().UpdateDocument(&doc)
+	return &doc, nil
+}
+
 // UploadDocument uploads a local document given by sourceDocPath under the parentId directory
 func (ctx *ApiCtx) UploadDocument(parentId string, sourceDocPath string, notify bool) (*model.Document, error) {
 	name, ext := util.DocPathToName(sourceDocPath)
@@ -218,7 +237,7 @@ func (ctx *ApiCtx) UploadDocument(parentId string, sourceDocPath string, notify
 	var err error
 
 	//restore document
-	if ext == "zip" {
+	if ext == util.ZIP || ext == util.RMDOC {
 		id, err = archive.GetIdFromZip(sourceDocPath)
 		if err != nil {
 			return nil, err
@@ -238,7 +257,7 @@ func (ctx *ApiCtx) UploadDocument(parentId string, sourceDocPath string, notify
 		return nil, errors.New("upload request returned success := false")
 	}
 
-	zipPath, err := archive.CreateZipDocument(uploadRsp.ID, sourceDocPath)
+	zipPath, err := archive.CreateZipDocument(uploadRsp.ID, sourceDocPath, archive.DocumentOptions{})
 
 	if err != nil {
 		log.Error.Println("failed to create zip doc", err)
@@ -261,6 +280,10 @@ func (ctx *ApiCtx) UploadDocument(parentId string, sourceDocPath string, notify
 	}
 
 	metaDoc := model.CreateUploadDocumentMeta(uploadRsp.ID, model.DocumentType, parentId, name)
+	// the archives made with --pinned, or restored, keep their star
+	if meta, err := archive.GetMetadataFromZip(zipPath); err == nil {
+		metaDoc.Bookmarked = meta.Pinned
+	}
 
 	err = ctx.Http.Put(transport.UserBearer, config.UpdateStatus, util.InSlice(metaDoc), nil)
 
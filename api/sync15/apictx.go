@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,12 +20,17 @@ import (
 	"github.com/joagonca/rmapi/util"
 )
 
-// An ApiCtx allows you interact with the remote reMarkable API
+// An ApiCtx allows you interact with the remote reMarkable API.
+// It is safe for concurrent use: the changes of the tree are applied
+// one at a time, while the files are transferred in parallel.
 type ApiCtx struct {
 	Http        *transport.HttpClientCtx
-	ft          *filetree.FileTreeCtx
 	blobStorage *BlobStorage
-	hashTree    *HashTree
+
+	// mu guards the trees
+	mu       sync.Mutex
+	ft       *filetree.FileTreeCtx
+	hashTree *HashTree
 }
 
 // max number of concurrent requests
@@ -38,8 +44,14 @@ func init() {
 }
 
 func CreateCtx(http *transport.HttpClientCtx) (*ApiCtx, error) {
+	return CreateAccountCtx(http, "")
+}
+
+// CreateAccountCtx creates the context of a configured account, whose
+// tree is cached apart from the one of the shell.
+func CreateAccountCtx(http *transport.HttpClientCtx, account string) (*ApiCtx, error) {
 	apiStorage := NewBlobStorage(http)
-	cacheTree, err := loadTree()
+	cacheTree, err := loadTree(account)
 	if err != nil {
 		fmt.Print(err)
 		return nil, err
@@ -50,14 +62,18 @@ func CreateCtx(http *transport.HttpClientCtx) (*ApiCtx, error) {
 	}
 	saveTree(cacheTree)
 	tree := DocumentsFileTree(cacheTree)
-	return &ApiCtx{http, tree, apiStorage, cacheTree}, nil
+	return &ApiCtx{Http: http, blobStorage: apiStorage, ft: tree, hashTree: cacheTree}, nil
 }
 
 func (ctx *ApiCtx) Filetree() *filetree.FileTreeCtx {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
 	return ctx.ft
 }
 
 func (ctx *ApiCtx) Refresh() error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
 	err := ctx.hashTree.Mirror(ctx.blobStorage, concurrent)
 	if err != nil {
 		return err
@@ -68,6 +84,8 @@ func (ctx *ApiCtx) Refresh() error {
 
 // Nuke removes all documents from the account
 func (ctx *ApiCtx) Nuke() (err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
 	err = Sync(ctx.blobStorage, ctx.hashTree, func(t *HashTree) error {
 		ctx.hashTree.Docs = nil
 		ctx.hashTree.Rehash()
@@ -77,12 +95,12 @@ func (ctx *ApiCtx) Nuke() (err error) {
 	if err != nil {
 		return
 	}
-	return ctx.SyncComplete()
+	return ctx.syncComplete()
 }
 
 // FetchDocument downloads a document given its ID and saves it locally into dstPath
 func (ctx *ApiCtx) FetchDocument(docId, dstPath string) error {
-	doc, err := ctx.hashTree.FindDoc(docId)
+	files, err := ctx.docFiles(docId)
 	if err != nil {
 		return err
 	}
@@ -97,7 +115,7 @@ func (ctx *ApiCtx) FetchDocument(docId, dstPath string) error {
 
 	w := zip.NewWriter(tmp)
 	defer w.Close()
-	for _, f := range doc.Files {
+	for _, f := range files {
 		log.Trace.Println("fetching document: ", f.DocumentID)
 		blobReader, err := ctx.blobStorage.GetReader(f.Hash)
 		if err != nil {
@@ -148,7 +166,7 @@ func (ctx *ApiCtx) CreateDir(parentId, name string, notify bool) (*model.Documen
 	}
 	files.AddMap(objectName, filePath)
 
-	objectName, filePath, err = archive.CreateContent(id, "", tmpDir, nil)
+	objectName, filePath, err = archive.CreateContent(id, "", tmpDir, nil, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -193,7 +211,7 @@ func (ctx *ApiCtx) CreateDir(parentId, name string, notify bool) (*model.Documen
 		return nil, err
 	}
 
-	err = Sync(ctx.blobStorage, ctx.hashTree, func(t *HashTree) error {
+	err = ctx.sync(func(t *HashTree) error {
 		return t.Add(doc)
 	})
 
@@ -263,11 +281,14 @@ func Sync(b *BlobStorage, tree *HashTree, operation func(t *HashTree) error) err
 
 // DeleteEntry removes an entry: either an empty directory or a file
 func (ctx *ApiCtx) DeleteEntry(node *model.Node) error {
-	if node.IsDirectory() && len(node.Children) > 0 {
+	unlock := ctx.Filetree().ReadLock()
+	empty := len(node.Children) == 0
+	unlock()
+	if node.IsDirectory() && !empty {
 		return errors.New("directory is not empty")
 	}
 
-	err := Sync(ctx.blobStorage, ctx.hashTree, func(t *HashTree) error {
+	err := ctx.sync(func(t *HashTree) error {
 		return t.Remove(node.Document.ID)
 	})
 	if err != nil {
@@ -285,16 +306,39 @@ func (ctx *ApiCtx) MoveEntry(src, dstDir *model.Node, name string) (*model.Node,
 	if dstDir.IsFile() {
 		return nil, errors.New("destination directory is a file")
 	}
-	var err error
+	document, err := ctx.updateMetadata(src.Document.ID, func(m *archive.MetadataFile) {
+		m.DocName = name
+		m.Parent = dstDir.Id()
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	err = Sync(ctx.blobStorage, ctx.hashTree, func(t *HashTree) error {
-		doc, err := t.FindDoc(src.Document.ID)
+	return &model.Node{Document: document, Children: src.Children, Parent: dstDir}, nil
+}
+
+// SetPinned pins (stars) or unpins an entry.
+func (ctx *ApiCtx) SetPinned(node *model.Node, pinned bool) (*model.Document, error) {
+	document, err := ctx.updateMetadata(node.Id(), func(m *archive.MetadataFile) {
+		m.Pinned = pinned
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx.Filetree().UpdateDocument(document)
+	return document, nil
+}
+
+// updateMetadata changes the metadata of a document with change and
+// uploads it.
+func (ctx *ApiCtx) updateMetadata(docId string, change func(m *archive.MetadataFile)) (*model.Document, error) {
+	err := ctx.sync(func(t *HashTree) error {
+		doc, err := t.FindDoc(docId)
 		if err != nil {
 			return err
 		}
 		doc.Metadata.Version += 1
-		doc.Metadata.DocName = name
-		doc.Metadata.Parent = dstDir.Id()
+		change(&doc.Metadata)
 		doc.Metadata.MetadataModified = true
 
 		hashStr, reader, err := doc.MetadataHashAndReader()
@@ -335,12 +379,13 @@ func (ctx *ApiCtx) MoveEntry(src, dstDir *model.Node, name string) (*model.Node,
 		return nil, err
 	}
 
-	d, err := ctx.hashTree.FindDoc(src.Document.ID)
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	d, err := ctx.hashTree.FindDoc(docId)
 	if err != nil {
 		return nil, err
 	}
-
-	return &model.Node{Document: d.ToDocument(), Children: src.Children, Parent: dstDir}, nil
+	return d.ToDocument(), nil
 }
 
 // UploadDocument uploads a local document given by sourceDocPath under the parentId directory
@@ -371,11 +416,100 @@ func (ctx *ApiCtx) UploadDocument(parentId string, sourceDocPath string, notify
 	}
 
 	doc := NewBlobDoc(name, id, model.DocumentType, parentId)
+	if err = ctx.uploadFiles(doc, docFiles); err != nil {
+		return nil, err
+	}
+
+	err = ctx.sync(func(t *HashTree) error {
+		return t.Add(doc)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if notify {
+		err = ctx.SyncComplete()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return doc.ToDocument(), nil
+}
+
+// UpdateDocument replaces the files of an existing document with the
+// content of an archive, keeping the document ID, name and parent.
+// The archive must belong to the document, e.g. be a modified copy of
+// the archive returned by FetchDocument.
+func (ctx *ApiCtx) UpdateDocument(docId, sourceZip string, notify bool) (*model.Document, error) {
+	ctx.mu.Lock()
+	current, err := ctx.hashTree.FindDoc(docId)
+	var name, parentId string
+	if err == nil {
+		name = current.Metadata.DocName
+		parentId = current.Metadata.Parent
+	}
+	ctx.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "rmupload")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	id, docFiles, metadataPath, err := archive.Unpack(sourceZip, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	if id != docId {
+		return nil, fmt.Errorf("the archive belongs to document %s, not %s", id, docId)
+	}
+	if metadataPath == "" {
+		objectName, filePath, err := archive.CreateMetadata(id, name, parentId, model.DocumentType, tmpDir)
+		if err != nil {
+			return nil, err
+		}
+		docFiles.AddMap(objectName, filePath)
+	} else if err = archive.FixMetadata(parentId, name, metadataPath); err != nil {
+		return nil, err
+	}
+
+	doc := NewBlobDoc(name, id, model.DocumentType, parentId)
+	if err = ctx.uploadFiles(doc, docFiles); err != nil {
+		return nil, err
+	}
+
+	err = ctx.sync(func(t *HashTree) error {
+		if err := t.Remove(id); err != nil {
+			return err
+		}
+		return t.Add(doc)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if notify {
+		if err = ctx.SyncComplete(); err != nil {
+			return nil, err
+		}
+	}
+
+	document := doc.ToDocument()
+	ctx.Filetree().UpdateDocument(document)
+	return document, nil
+}
+
+// uploadFiles uploads the files of a document and its index.
+func (ctx *ApiCtx) uploadFiles(doc *BlobDoc, docFiles *archive.DocumentFiles) error {
 	for _, f := range docFiles.Files {
 		log.Info.Printf("File %s, path: %s", f.Name, f.Path)
 		hash, size, err := FileHashAndSize(f.Path)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		hashStr := hex.EncodeToString(hash)
 		fileEntry := &Entry{
@@ -386,12 +520,13 @@ func (ctx *ApiCtx) UploadDocument(parentId string, sourceDocPath string, notify
 		}
 		reader, err := os.Open(f.Path)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		err = ctx.blobStorage.UploadBlob(hashStr, reader)
+		reader.Close()
 
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		doc.AddFile(fileEntry)
@@ -400,29 +535,10 @@ func (ctx *ApiCtx) UploadDocument(parentId string, sourceDocPath string, notify
 	log.Info.Println("Uploading new doc index...", doc.Hash)
 	indexReader, err := doc.IndexReader()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer indexReader.Close()
-	err = ctx.blobStorage.UploadBlob(doc.Hash, indexReader)
-	if err != nil {
-		return nil, err
-	}
-
-	err = Sync(ctx.blobStorage, ctx.hashTree, func(t *HashTree) error {
-		return t.Add(doc)
-	})
-
-	if err != nil {
-		return nil, err
-	}
-	if notify {
-		err = ctx.SyncComplete()
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return doc.ToDocument(), nil
+	return ctx.blobStorage.UploadBlob(doc.Hash, indexReader)
 }
 
 // DocumentsFileTree reads your remote documents and builds a file tree
@@ -452,8 +568,37 @@ func DocumentsFileTree(tree *HashTree) *filetree.FileTreeCtx {
 	return &fileTree
 }
 
+// sync applies changes to the tree and syncs with the remote storage,
+// waiting for the other changes to be done.
+func (ctx *ApiCtx) sync(operation func(t *HashTree) error) error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return Sync(ctx.blobStorage, ctx.hashTree, operation)
+}
+
+// docFiles returns the files of a document.
+func (ctx *ApiCtx) docFiles(docId string) ([]Entry, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	doc, err := ctx.hashTree.FindDoc(docId)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]Entry, len(doc.Files))
+	for i, f := range doc.Files {
+		files[i] = *f
+	}
+	return files, nil
+}
+
 // SyncComplete notfies that somethings has changed (triggers tablet sync)
 func (ctx *ApiCtx) SyncComplete() error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.syncComplete()
+}
+
+func (ctx *ApiCtx) syncComplete() error {
 	err := ctx.blobStorage.SyncComplete(ctx.hashTree.Generation)
 
 	//sync can be called once per generation, ignore the error if nothing was changed
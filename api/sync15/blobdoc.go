@@ -225,5 +225,7 @@ func (d *BlobDoc) ToDocument() *model.Document {
 		Type:           d.Metadata.CollectionType,
 		CurrentPage:    d.Metadata.LastOpenedPage,
 		ModifiedClient: lastModified,
+		Bookmarked:     d.Metadata.Pinned,
+		Tags:           d.Metadata.TagNames(),
 	}
 }
@@ -27,7 +27,9 @@ func HashEntries(entries []*Entry) (string, error) {
 	return hashStr, nil
 }
 
-func getCachedTreePath() (string, error) {
+// getCachedTreePath returns the path of the cache of the tree of an
+// account, the one of the shell when account is empty.
+func getCachedTreePath(account string) (string, error) {
 	cachedir, err := os.UserCacheDir()
 	if err != nil {
 		return "", err
@@ -38,17 +40,20 @@ func getCachedTreePath() (string, error) {
 		return "", err
 	}
 	cacheFile := path.Join(rmapiFolder, ".tree")
+	if account != "" {
+		cacheFile += "-" + account
+	}
 	return cacheFile, nil
 }
 
 const cacheVersion = 3
 
-func loadTree() (*HashTree, error) {
-	cacheFile, err := getCachedTreePath()
+func loadTree(account string) (*HashTree, error) {
+	cacheFile, err := getCachedTreePath(account)
 	if err != nil {
 		return nil, err
 	}
-	tree := &HashTree{}
+	tree := &HashTree{cacheFile: cacheFile}
 	if _, err := os.Stat(cacheFile); err == nil {
 		b, err := os.ReadFile(cacheFile)
 		if err != nil {
@@ -61,7 +66,7 @@ func loadTree() (*HashTree, error) {
 		}
 		if tree.CacheVersion != cacheVersion {
 			log.Info.Println("wrong cache file version, resync")
-			return &HashTree{}, nil
+			return &HashTree{cacheFile: cacheFile}, nil
 		}
 	}
 	log.Info.Println("cache loaded: ", cacheFile)
@@ -70,11 +75,14 @@ func loadTree() (*HashTree, error) {
 }
 
 func saveTree(tree *HashTree) error {
-	cacheFile, err := getCachedTreePath()
-	log.Info.Println("Writing cache: ", cacheFile)
-	if err != nil {
-		return err
+	cacheFile := tree.cacheFile
+	if cacheFile == "" {
+		var err error
+		if cacheFile, err = getCachedTreePath(""); err != nil {
+			return err
+		}
 	}
+	log.Info.Println("Writing cache: ", cacheFile)
 	tree.CacheVersion = cacheVersion
 	b, err := json.MarshalIndent(tree, "", "")
 	if err != nil {
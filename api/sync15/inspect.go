@@ -0,0 +1,115 @@
+package sync15
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joagonca/rmapi/archive"
+	"github.com/joagonca/rmapi/log"
+	"github.com/joagonca/rmapi/model"
+	"golang.org/x/sync/errgroup"
+)
+
+// DocumentFiles lists the files of every document with their size.
+// The .content files are downloaded to know the type and the number
+// of pages of the documents.
+func (ctx *ApiCtx) DocumentFiles() ([]*model.DocumentFiles, error) {
+	// the documents are read while the tree cannot change
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	result := make([]*model.DocumentFiles, 0, len(ctx.hashTree.Docs))
+	var mu sync.Mutex
+
+	wg, gctx := errgroup.WithContext(context.TODO())
+	wg.SetLimit(concurrent)
+
+	for _, d := range ctx.hashTree.Docs {
+		if d.Metadata.Deleted || d.Metadata.CollectionType != model.DocumentType {
+			continue
+		}
+
+		doc := d
+		wg.Go(func() error {
+			if gctx.Err() != nil {
+				return gctx.Err()
+			}
+			files, err := documentFiles(doc, ctx.blobStorage)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			result = append(result, files)
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func documentFiles(doc *BlobDoc, r RemoteStorage) (*model.DocumentFiles, error) {
+	files := &model.DocumentFiles{
+		ID:         doc.DocumentID,
+		Files:      make(map[string]int64),
+		LastOpened: parseTimestamp(doc.Metadata.LastOpened),
+	}
+
+	for _, f := range doc.Files {
+		files.Files[f.DocumentID] = f.Size
+		if !strings.HasSuffix(f.DocumentID, ".content") {
+			continue
+		}
+
+		content, err := readContent(f, r)
+		if err != nil {
+			return nil, err
+		}
+		files.FileType = content.FileType
+		files.Pages = content.PageCount
+		if len(content.Pages) > files.Pages {
+			files.Pages = len(content.Pages)
+		}
+	}
+
+	if files.FileType == "" {
+		files.FileType = "notebook"
+	}
+	return files, nil
+}
+
+func readContent(e *Entry, r RemoteStorage) (*archive.Content, error) {
+	reader, err := r.GetReader(e.Hash)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &archive.Content{}
+	if err := json.Unmarshal(data, content); err != nil {
+		log.Trace.Printf("cannot read content %s %v", e.DocumentID, err)
+	}
+	return content, nil
+}
+
+// parseTimestamp converts a timestamp in milliseconds, zero when empty.
+func parseTimestamp(ms string) time.Time {
+	t, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil || t == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(t)
+}
@@ -15,6 +15,8 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+var treeLogger = log.For(log.Filetree)
+
 const SchemaVersion = "3"
 const DocType = "80000000"
 const FileType = "0"
@@ -118,6 +120,8 @@ type HashTree struct {
 	Generation   int64
 	Docs         []*BlobDoc
 	CacheVersion int
+	// cacheFile is the file the tree is saved to
+	cacheFile string
 }
 
 func (t *HashTree) FindDoc(id string) (*BlobDoc, error) {
@@ -139,7 +143,7 @@ func (t *HashTree) Remove(id string) error {
 		}
 	}
 	if docIndex > -1 {
-		log.Trace.Printf("Removing %s", id)
+		treeLogger.Trace.Printf("Removing %s", id)
 		length := len(t.Docs) - 1
 		t.Docs[docIndex] = t.Docs[length]
 		t.Docs = t.Docs[:length]
@@ -159,7 +163,7 @@ func (t *HashTree) Rehash() error {
 	if err != nil {
 		return err
 	}
-	log.Info.Println("New root hash: ", hash)
+	treeLogger.Info.Println("New root hash: ", hash)
 	t.Hash = hash
 	return nil
 }
@@ -171,7 +175,7 @@ func (t *HashTree) Mirror(r RemoteStorage, maxconcurrent int) error {
 		return err
 	}
 	if rootHash == "" && gen == 0 {
-		log.Info.Println("Empty cloud")
+		treeLogger.Info.Println("Empty cloud")
 		t.Docs = nil
 		t.Generation = 0
 		return nil
@@ -180,7 +184,7 @@ func (t *HashTree) Mirror(r RemoteStorage, maxconcurrent int) error {
 	if rootHash == t.Hash {
 		return nil
 	}
-	log.Info.Printf("remote root hash different")
+	treeLogger.Info.Printf("remote root hash different")
 
 	rootIndexReader, err := r.GetReader(rootHash)
 	if err != nil {
@@ -210,7 +214,7 @@ func (t *HashTree) Mirror(r RemoteStorage, maxconcurrent int) error {
 			current[doc.DocumentID] = doc
 
 			if entry.Hash != doc.Hash {
-				log.Info.Println("doc updated: ", doc.DocumentID)
+				treeLogger.Info.Println("doc updated: ", doc.DocumentID)
 				e := entry
 				d := doc
 				wg.Go(func() error {
@@ -229,7 +233,7 @@ func (t *HashTree) Mirror(r RemoteStorage, maxconcurrent int) error {
 	for k, newEntry := range new {
 		if _, ok := current[k]; !ok {
 			doc := &BlobDoc{}
-			log.Trace.Println("doc new: ", k)
+			treeLogger.Trace.Println("doc new: ", k)
 			head = append(head, doc)
 			e := newEntry
 			wg.Go(func() error {
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -35,10 +34,11 @@ func (d *DocumentFiles) AddMap(name, filepath string) {
 	d.Files = append(d.Files, fs)
 }
 
-// Prepare prepares a file for uploading (creates needed temp files or unpacks a zip)
+// Prepare prepares a file for uploading (creates needed temp files or
+// unpacks a zip or an .rmdoc, which share their layout)
 func Prepare(name, parentId, sourceDocPath, ext, tmpDir string) (files *DocumentFiles, id string, err error) {
 	files = &DocumentFiles{}
-	if ext == util.ZIP {
+	if ext == util.ZIP || ext == util.RMDOC {
 		var metadataPath string
 		id, files, metadataPath, err = Unpack(sourceDocPath, tmpDir)
 		if err != nil {
@@ -62,6 +62,10 @@ func Prepare(name, parentId, sourceDocPath, ext, tmpDir string) (files *Document
 			}
 		}
 	} else {
+		sourceDocPath, ext, err = convertFile(sourceDocPath, ext, tmpDir)
+		if err != nil {
+			return
+		}
 		id = uuid.New().String()
 		objectName := id + "." + ext
 		doctype := ext
@@ -73,6 +77,14 @@ func Prepare(name, parentId, sourceDocPath, ext, tmpDir string) (files *Document
 			pageIds = []string{pageId}
 		}
 		files.AddMap(objectName, sourceDocPath)
+		// thumbnail generation is opt-in via RMAPI_THUMBNAILS
+		if ext == util.RM && os.Getenv("RMAPI_THUMBNAILS") != "" {
+			if thumbnailPath, err1 := createNotebookThumbnail(sourceDocPath, tmpDir); err1 != nil {
+				log.Error.Println("cannot generate thumbnail", err1)
+			} else {
+				files.AddMap(fmt.Sprintf("%s.thumbnails/%s.jpg", id, pageIds[0]), thumbnailPath)
+			}
+		}
 		objectName, filePath, err1 := CreateMetadata(id, name, parentId, model.DocumentType, tmpDir)
 		if err1 != nil {
 			err = err1
@@ -80,7 +92,11 @@ func Prepare(name, parentId, sourceDocPath, ext, tmpDir string) (files *Document
 		}
 		files.AddMap(objectName, filePath)
 
-		objectName, filePath, err = CreateContent(id, doctype, tmpDir, pageIds)
+		var size int64
+		if info, err1 := os.Stat(sourceDocPath); err1 == nil {
+			size = info.Size()
+		}
+		objectName, filePath, err = CreateContent(id, doctype, tmpDir, pageIds, size)
 		if err != nil {
 			return
 		}
@@ -89,7 +105,30 @@ func Prepare(name, parentId, sourceDocPath, ext, tmpDir string) (files *Document
 	return files, id, err
 }
 
-// FixMetadata fixes the metadata with the new parent and filename
+// createNotebookThumbnail writes the thumbnail of a .rm page in tmpDir.
+func createNotebookThumbnail(srcPath, tmpDir string) (string, error) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", err
+	}
+	thumbnail, err := makeNotebookThumbnail(data)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(tmpDir, "thumbnail-*.jpg")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(thumbnail); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// FixMetadata fixes the metadata with the new parent and filename. The
+// modification time of the metadata is kept, the one of the archives
+// made from a document elsewhere, and set to now when missing.
 func FixMetadata(parentId, name, path string) error {
 	meta := MetadataFile{}
 	metaData, err := ioutil.ReadFile(path)
@@ -102,7 +141,9 @@ func FixMetadata(parentId, name, path string) error {
 	}
 	meta.Parent = parentId
 	meta.DocName = name
-	meta.LastModified = UnixTimestamp()
+	if meta.LastModified == "" {
+		meta.LastModified = UnixTimestamp()
+	}
 
 	metaData, err = json.Marshal(meta)
 	if err != nil {
@@ -159,17 +200,10 @@ func Unpack(src, dest string) (id string, files *DocumentFiles, metadataPath str
 			return
 		}
 
-		rc, err1 := f.Open()
-		if err != nil {
-			err = err1
-			return
-		}
-
-		_, err = io.Copy(outFile, rc)
+		err = copyZipFile(outFile, f)
 
 		// Close the file without defer to close before next iteration of loop
 		outFile.Close()
-		rc.Close()
 
 		if err != nil {
 			return
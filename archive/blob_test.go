@@ -0,0 +1,52 @@
+package archive
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFixMetadataKeepsTags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.metadata")
+	original := `{"visibleName":"old","type":"DocumentType","parent":"","pinned":true,"lastModified":"1622536200000",` +
+		`"tags":[{"name":"work","timestamp":1700000000000},{"name":"todo","timestamp":1700000001000}]}`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := FixMetadata("parent", "new", path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta MetadataFile
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.DocName != "new" || meta.Parent != "parent" || !meta.Pinned || meta.LastModified != "1622536200000" {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+	if got, want := meta.TagNames(), []string{"work", "todo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected tags %v, got %v", want, got)
+	}
+	if meta.Tags[1].Timestamp != 1700000001000 {
+		t.Errorf("the timestamp of the tags is lost")
+	}
+}
+
+func TestContentPageTags(t *testing.T) {
+	data := []byte(`{"fileType":"pdf","tags":[{"name":"book","timestamp":1}],` +
+		`"pageTags":[{"name":"important","pageId":"p1","timestamp":2}]}`)
+	var content Content
+	if err := json.Unmarshal(data, &content); err != nil {
+		t.Fatal(err)
+	}
+	if len(content.Tags) != 1 || len(content.PageTags) != 1 || content.PageTags[0].PageID != "p1" {
+		t.Errorf("unexpected tags %+v %+v", content.Tags, content.PageTags)
+	}
+}
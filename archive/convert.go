@@ -0,0 +1,173 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/joagonca/rmapi/generate"
+	"github.com/joagonca/rmapi/pdf"
+	"github.com/joagonca/rmapi/strokes"
+	"github.com/joagonca/rmapi/util"
+)
+
+// convertDocument converts a source document the tablet cannot open
+// into one of the natively supported formats. It returns the converted
+// content and its extension, or the input untouched. name is the title
+// of the documents typeset from text.
+func convertDocument(doc []byte, ext, name string) ([]byte, string, error) {
+	switch ext {
+	case util.SVG:
+		page, err := strokes.FromSVG(bytes.NewReader(doc), strokes.DefaultSvgOptions())
+		if err != nil {
+			return nil, "", err
+		}
+		data, err := page.MarshalBinary()
+		if err != nil {
+			return nil, "", err
+		}
+		return data, util.RM, nil
+	case util.PNG, util.JPG, util.JPEG:
+		data, err := imageToPDF(doc)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, util.PDF, nil
+	case util.MD, util.MARKDOWN, util.TXT:
+		data, err := ConvertText(doc, ext, name, generate.MarkdownOptions{})
+		if err != nil {
+			return nil, "", err
+		}
+		return data, util.PDF, nil
+	}
+	return doc, ext, nil
+}
+
+// ConvertText typesets markdown or plain text, by the extension ext,
+// as a pdf titled name.
+func ConvertText(doc []byte, ext, name string, opts generate.MarkdownOptions) ([]byte, error) {
+	var typeset *pdf.Document
+	if ext == util.TXT {
+		typeset = generate.TextPDF(string(doc), name, opts)
+	} else {
+		typeset = generate.MarkdownPDF(string(doc), name, opts)
+	}
+	var b bytes.Buffer
+	if err := typeset.Write(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// needsConversion tells whether the extension is converted before upload
+func needsConversion(ext string) bool {
+	switch ext {
+	case util.SVG, util.PNG, util.JPG, util.JPEG, util.MD, util.MARKDOWN, util.TXT:
+		return true
+	}
+	return false
+}
+
+// imageToPDF converts a png or jpeg image into a pdf of a single page,
+// with the proportions of the screen of the device, in portrait or in
+// landscape like the image. The image is fitted in the page and
+// centered. The jpeg images are kept as they are, the other ones are
+// drawn on white.
+func imageToPDF(doc []byte) ([]byte, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the image: %v", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, errors.New("empty image")
+	}
+
+	dict := pdf.Dict{
+		"Type":             pdf.Name("XObject"),
+		"Subtype":          pdf.Name("Image"),
+		"Width":            int64(config.Width),
+		"Height":           int64(config.Height),
+		"ColorSpace":       pdf.Name("DeviceRGB"),
+		"BitsPerComponent": int64(8),
+	}
+	var stream *pdf.Stream
+	switch {
+	case format == "jpeg" && config.ColorModel == color.YCbCrModel:
+		dict["Filter"] = pdf.Name("DCTDecode")
+		stream = &pdf.Stream{Dict: dict, Data: doc}
+	case format == "jpeg" && config.ColorModel == color.GrayModel:
+		dict["Filter"] = pdf.Name("DCTDecode")
+		dict["ColorSpace"] = pdf.Name("DeviceGray")
+		stream = &pdf.Stream{Dict: dict, Data: doc}
+	default:
+		img, _, err := image.Decode(bytes.NewReader(doc))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the image: %v", err)
+		}
+		bounds := img.Bounds()
+		opaque := image.NewRGBA(bounds)
+		draw.Draw(opaque, bounds, image.White, image.Point{}, draw.Src)
+		draw.Draw(opaque, bounds, img, bounds.Min, draw.Over)
+		colors := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+		for i := 0; i < len(opaque.Pix); i += 4 {
+			colors = append(colors, opaque.Pix[i:i+3]...)
+		}
+		stream = pdf.NewStream(dict, colors)
+	}
+
+	width, height := float64(pdf.RemarkableWidth), float64(pdf.RemarkableHeight)
+	if config.Width > config.Height {
+		width, height = height, width
+	}
+	scale := math.Min(width/float64(config.Width), height/float64(config.Height))
+	w, h := float64(config.Width)*scale, float64(config.Height)*scale
+	placement := pdf.Matrix{w, 0, 0, h, (width - w) / 2, (height - h) / 2}
+
+	f := pdf.NewFile()
+	page := f.NewPage(width, height)
+	content := fmt.Sprintf("q %s cm /Im0 Do Q", placement)
+	if err := f.AppendContent(page, []byte(content), pdf.Dict{"XObject": pdf.Dict{"Im0": f.Add(stream)}}); err != nil {
+		return nil, err
+	}
+	if err := f.SetPages([]*pdf.PageObject{page}); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := f.Write(&b); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// convertFile converts the source file into tmpDir if needed and
+// returns the path and extension to upload.
+func convertFile(srcPath, ext, tmpDir string) (string, string, error) {
+	if !needsConversion(ext) {
+		return srcPath, ext, nil
+	}
+
+	doc, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	name, _ := util.DocPathToName(srcPath)
+	converted, newExt, err := convertDocument(doc, ext, name)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to convert %s: %v", srcPath, err)
+	}
+
+	dst := filepath.Join(tmpDir, name+"."+newExt)
+	if err := os.WriteFile(dst, converted, 0600); err != nil {
+		return "", "", err
+	}
+	return dst, newExt, nil
+}
@@ -0,0 +1,106 @@
+package archive
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/joagonca/rmapi/pdf"
+	"github.com/joagonca/rmapi/util"
+)
+
+func TestConvertImages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{0xff, 0, 0, 0xff}), image.Point{}, draw.Src)
+	var pngData, jpegData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&jpegData, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		ext    string
+		data   []byte
+		filter pdf.Object
+	}{
+		{util.PNG, pngData.Bytes(), pdf.Name("FlateDecode")},
+		{util.JPG, jpegData.Bytes(), pdf.Name("DCTDecode")},
+	} {
+		converted, ext, err := convertDocument(tc.data, tc.ext, "photo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ext != util.PDF {
+			t.Errorf("%s: expected a pdf, got %s", tc.ext, ext)
+		}
+		f, err := pdf.Open(converted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages, err := f.Pages()
+		if err != nil || len(pages) != 1 {
+			t.Fatalf("%s: expected a page, got %d (%v)", tc.ext, len(pages), err)
+		}
+		// a landscape page, the image fitted in its width
+		if w, h := f.DisplaySize(pages[0]); w != pdf.RemarkableHeight || h != pdf.RemarkableWidth {
+			t.Errorf("%s: expected a landscape page, got %vx%v", tc.ext, w, h)
+		}
+		resources, _ := f.Resolve(pages[0].Attr("Resources"))
+		xobjects, _ := f.Get(resources.(pdf.Dict), "XObject").(pdf.Dict)
+		obj, _ := f.Resolve(xobjects["Im0"])
+		if stream, ok := obj.(*pdf.Stream); !ok || f.Get(stream.Dict, "Filter") != tc.filter {
+			t.Errorf("%s: expected an image with the filter %v, got %v", tc.ext, tc.filter, obj)
+		}
+
+		preview, err := f.RenderPage(pages[0], 60, 45)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := preview.RGBAAt(30, 22); c.R < 0xe0 || c.G > 0x20 {
+			t.Errorf("%s: expected the image in the middle of the page, got %v", tc.ext, c)
+		}
+		if c := preview.RGBAAt(30, 2); c != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+			t.Errorf("%s: expected the page blank above the image, got %v", tc.ext, c)
+		}
+	}
+
+	if _, _, err := convertDocument([]byte("not an image"), util.PNG, ""); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestConvertText(t *testing.T) {
+	for _, ext := range []string{util.MD, util.TXT} {
+		converted, newExt, err := convertDocument([]byte("# Notes\n\nSome text"), ext, "Notes")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newExt != util.PDF {
+			t.Errorf("%s: expected a pdf, got %s", ext, newExt)
+		}
+		f, err := pdf.Open(converted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages, err := f.Pages()
+		if err != nil || len(pages) != 1 {
+			t.Fatalf("%s: expected a page, got %d (%v)", ext, len(pages), err)
+		}
+		text, err := f.PageText(pages[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the markdown is typeset, the text kept as it is
+		want := map[string]string{util.MD: "Notes", util.TXT: "# Notes"}[ext]
+		if !strings.HasPrefix(strings.TrimSpace(text), want) {
+			t.Errorf("%s: expected the text to start with %q, got %q", ext, want, text)
+		}
+	}
+}
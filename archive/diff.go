@@ -0,0 +1,242 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+// A DocumentDiff lists the changes between two archives of a document,
+// such as two backups.
+type DocumentDiff struct {
+	Added    []PageChange `json:"added"`
+	Removed  []PageChange `json:"removed"`
+	Modified []PageChange `json:"modified"`
+	// DocumentChanged is set when the pdf or the epub differ
+	DocumentChanged bool          `json:"documentChanged,omitempty"`
+	Fields          []FieldChange `json:"fields"`
+}
+
+// Empty tells whether the archives hold the same document.
+func (d *DocumentDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 && !d.DocumentChanged && len(d.Fields) == 0
+}
+
+// A PageChange is a page added, removed or modified. Page is its number
+// in the newer archive, or in the older one for a removed page, from 1.
+type PageChange struct {
+	ID   string `json:"id"`
+	Page int    `json:"page"`
+	// Changes are what was modified: "strokes", "template" or "moved"
+	Changes []string `json:"changes,omitempty"`
+}
+
+// A FieldChange is a field of the .metadata or the .content which
+// differs, such as "metadata.visibleName", with its values as json.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// diffPage is a page of an archive: its number, the hash of its
+// strokes, empty when it has none, and its template.
+type diffPage struct {
+	number   int
+	strokes  string
+	template string
+}
+
+// diffDoc is an archive read to be compared.
+type diffDoc struct {
+	files   map[string][]byte
+	id      string
+	content map[string]interface{}
+	pages   map[string]diffPage
+	order   []string
+}
+
+func readDiffDoc(path string) (*diffDoc, error) {
+	files, err := ReadRawFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &diffDoc{files: files, pages: make(map[string]diffPage)}
+	for name := range files {
+		if !strings.Contains(name, "/") && strings.HasSuffix(name, ".content") {
+			d.id = strings.TrimSuffix(name, ".content")
+		}
+	}
+	if d.id == "" {
+		return nil, fmt.Errorf("%s has no .content", path)
+	}
+	if err := json.Unmarshal(files[d.id+".content"], &d.content); err != nil {
+		return nil, fmt.Errorf("cannot read the content of %s: %v", path, err)
+	}
+
+	_, hasPdf := files[d.id+".pdf"]
+	_, hasEpub := files[d.id+".epub"]
+	pagedata := strings.Split(strings.TrimRight(string(files[d.id+".pagedata"]), "\n"), "\n")
+	refs := documentPages(d.content, pagedata, hasPdf || hasEpub)
+	templates := make(map[string]string)
+	if len(refs) == 0 {
+		// the older firmwares name the pages after their index
+		for i := 0; i < pageCount(d.content, nil); i++ {
+			d.order = append(d.order, fmt.Sprint(i))
+			if i < len(pagedata) {
+				templates[fmt.Sprint(i)] = pagedata[i]
+			}
+		}
+	}
+	for _, ref := range refs {
+		d.order = append(d.order, ref.id)
+		templates[ref.id] = ref.template
+	}
+	for i, id := range d.order {
+		d.pages[id] = diffPage{number: i + 1, template: templates[id]}
+	}
+	for name, data := range files {
+		if id, kind, ok := pageFile(name, d.id); ok && kind == ".rm" {
+			if p, ok := d.pages[id]; ok {
+				// the pages are compared by hash, a corrupted one
+				// would only show as modified
+				var strokes rm.Rm
+				if _, err := strokes.UnmarshalMode(data, rm.Strict); err != nil {
+					return nil, fmt.Errorf("cannot read the strokes of page %d of %s: %v", p.number, path, err)
+				}
+				p.strokes = fmt.Sprintf("%x", sha256.Sum256(data))
+				d.pages[id] = p
+			}
+		}
+	}
+	return d, nil
+}
+
+// Diff compares two archives of a document, a being the older one: the
+// pages added, removed or modified, by their id and the hash of their
+// strokes, and the fields of the .metadata and the .content which
+// changed.
+func Diff(a, b string) (*DocumentDiff, error) {
+	older, err := readDiffDoc(a)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := readDiffDoc(b)
+	if err != nil {
+		return nil, err
+	}
+	if older.id != newer.id {
+		return nil, errors.New("the archives hold different documents")
+	}
+
+	diff := &DocumentDiff{Added: []PageChange{}, Removed: []PageChange{}, Modified: []PageChange{}, Fields: []FieldChange{}}
+	for _, id := range older.order {
+		if _, ok := newer.pages[id]; !ok {
+			diff.Removed = append(diff.Removed, PageChange{ID: id, Page: older.pages[id].number})
+		}
+	}
+	// the pages kept in both archives which aren't in the longest run
+	// keeping their order were moved
+	var kept []string
+	for _, id := range newer.order {
+		if _, ok := older.pages[id]; ok {
+			kept = append(kept, id)
+		}
+	}
+	inOrder := longestInOrder(kept, func(id string) int { return older.pages[id].number })
+	for _, id := range newer.order {
+		p := newer.pages[id]
+		old, ok := older.pages[id]
+		if !ok {
+			diff.Added = append(diff.Added, PageChange{ID: id, Page: p.number})
+			continue
+		}
+		var changes []string
+		if old.strokes != p.strokes {
+			changes = append(changes, "strokes")
+		}
+		if old.template != p.template {
+			changes = append(changes, "template")
+		}
+		if !inOrder[id] {
+			changes = append(changes, "moved")
+		}
+		if len(changes) > 0 {
+			diff.Modified = append(diff.Modified, PageChange{ID: id, Page: p.number, Changes: changes})
+		}
+	}
+
+	for _, ext := range []string{".pdf", ".epub"} {
+		if !bytes.Equal(older.files[older.id+ext], newer.files[newer.id+ext]) {
+			diff.DocumentChanged = true
+		}
+	}
+
+	// the pages are compared above
+	pageFields := map[string]bool{"pages": true, "cPages": true, "redirectionPageMap": true, "pageCount": true}
+	diff.Fields = append(diff.Fields, diffFields("content", older.content, newer.content, pageFields)...)
+	var oldMeta, newMeta map[string]interface{}
+	json.Unmarshal(older.files[older.id+".metadata"], &oldMeta)
+	json.Unmarshal(newer.files[newer.id+".metadata"], &newMeta)
+	diff.Fields = append(diff.Fields, diffFields("metadata", oldMeta, newMeta, nil)...)
+	return diff, nil
+}
+
+// longestInOrder returns the longest subsequence of the pages whose
+// numbers increase.
+func longestInOrder(ids []string, number func(string) int) map[string]bool {
+	length := make([]int, len(ids))
+	prev := make([]int, len(ids))
+	best := -1
+	for i := range ids {
+		length[i], prev[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if number(ids[j]) < number(ids[i]) && length[j]+1 > length[i] {
+				length[i], prev[i] = length[j]+1, j
+			}
+		}
+		if best < 0 || length[i] > length[best] {
+			best = i
+		}
+	}
+	inOrder := make(map[string]bool)
+	for i := best; i >= 0; i = prev[i] {
+		inOrder[ids[i]] = true
+	}
+	return inOrder
+}
+
+// diffFields compares the fields of two json objects, but the skipped
+// ones, in the order of their names.
+func diffFields(prefix string, a, b map[string]interface{}, skipped map[string]bool) []FieldChange {
+	names := make(map[string]bool)
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	var sorted []string
+	for name := range names {
+		if !skipped[name] {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	var changes []FieldChange
+	for _, name := range sorted {
+		was, _ := json.Marshal(a[name])
+		now, _ := json.Marshal(b[name])
+		if !bytes.Equal(was, now) {
+			changes = append(changes, FieldChange{Field: prefix + "." + name, Old: string(was), New: string(now)})
+		}
+	}
+	return changes
+}
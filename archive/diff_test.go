@@ -0,0 +1,92 @@
+package archive
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+// testStrokes encodes a page with one stroke of n points.
+func testStrokes(t *testing.T, n int) []byte {
+	page := rm.New()
+	line := rm.Line{BrushType: rm.FinelinerV5, BrushSize: rm.Medium}
+	for i := 0; i < n; i++ {
+		line.Points = append(line.Points, rm.Point{X: float32(i), Y: 100, Width: 2})
+	}
+	page.Layers = []rm.Layer{{Lines: []rm.Line{line}}}
+	data, err := page.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, "older.zip")
+	err := WriteRawFiles(older, map[string][]byte{
+		"doc.content":  []byte(`{"fileType":"notebook","orientation":"portrait","pages":["p1","p2","p3","p4"]}`),
+		"doc.metadata": []byte(`{"visibleName":"Notes","pinned":false}`),
+		"doc/p1.rm":    testStrokes(t, 1),
+		"doc/p2.rm":    testStrokes(t, 2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer := filepath.Join(dir, "newer.zip")
+	err = WriteRawFiles(newer, map[string][]byte{
+		"doc.content":  []byte(`{"fileType":"notebook","orientation":"landscape","pages":["p3","p1","p2","p5"]}`),
+		"doc.metadata": []byte(`{"visibleName":"Meeting notes","pinned":false}`),
+		"doc/p1.rm":    testStrokes(t, 1),
+		"doc/p2.rm":    testStrokes(t, 5),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := Diff(older, newer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []PageChange{{ID: "p5", Page: 4}}; !reflect.DeepEqual(diff.Added, want) {
+		t.Errorf("expected the added pages %v, got %v", want, diff.Added)
+	}
+	if want := []PageChange{{ID: "p4", Page: 4}}; !reflect.DeepEqual(diff.Removed, want) {
+		t.Errorf("expected the removed pages %v, got %v", want, diff.Removed)
+	}
+	want := []PageChange{
+		{ID: "p3", Page: 1, Changes: []string{"moved"}},
+		{ID: "p2", Page: 3, Changes: []string{"strokes"}},
+	}
+	if !reflect.DeepEqual(diff.Modified, want) {
+		t.Errorf("expected the modified pages %v, got %v", want, diff.Modified)
+	}
+	fields := []FieldChange{
+		{Field: "content.orientation", Old: `"portrait"`, New: `"landscape"`},
+		{Field: "metadata.visibleName", Old: `"Notes"`, New: `"Meeting notes"`},
+	}
+	if !reflect.DeepEqual(diff.Fields, fields) {
+		t.Errorf("expected the fields %v, got %v", fields, diff.Fields)
+	}
+
+	if diff, err = Diff(older, older); err != nil || !diff.Empty() {
+		t.Errorf("expected no changes, got %+v (%v)", diff, err)
+	}
+	if _, err := Diff(older, "test.zip"); err == nil {
+		t.Error("expected an error for another document")
+	}
+
+	corrupted := filepath.Join(dir, "corrupted.zip")
+	err = WriteRawFiles(corrupted, map[string][]byte{
+		"doc.content": []byte(`{"fileType":"notebook","pages":["p1"]}`),
+		"doc/p1.rm":   testStrokes(t, 3)[:60],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Diff(older, corrupted); err == nil {
+		t.Error("expected an error for a corrupted page")
+	}
+}
@@ -0,0 +1,168 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// pageRef is a page of a document: its ID, the page of the pdf shown
+// as background (-1 for none) and its template.
+type pageRef struct {
+	id       string
+	redir    int
+	template string
+}
+
+// documentPages lists the pages of a document in order, reading the
+// "cPages" structure of the newer firmwares or the older "pages" list.
+func documentPages(content map[string]interface{}, pagedata []string, hasPayload bool) []pageRef {
+	var pages []pageRef
+
+	if cPages, ok := content["cPages"].(map[string]interface{}); ok {
+		list, _ := cPages["pages"].([]interface{})
+		type indexed struct {
+			idx string
+			ref pageRef
+		}
+		var entries []indexed
+		for _, p := range list {
+			entry, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if deleted, ok := entry["deleted"].(map[string]interface{}); ok && deleted["value"] != float64(0) {
+				continue
+			}
+			id, _ := entry["id"].(string)
+			ref := pageRef{id: id, redir: -1}
+			if redir, ok := entry["redir"].(map[string]interface{}); ok {
+				if v, ok := redir["value"].(float64); ok {
+					ref.redir = int(v)
+				}
+			}
+			if template, ok := entry["template"].(map[string]interface{}); ok {
+				ref.template, _ = template["value"].(string)
+			}
+			idx := ""
+			if i, ok := entry["idx"].(map[string]interface{}); ok {
+				idx, _ = i["value"].(string)
+			}
+			entries = append(entries, indexed{idx, ref})
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].idx < entries[j].idx })
+		for _, e := range entries {
+			pages = append(pages, e.ref)
+		}
+		return pages
+	}
+
+	ids, _ := content["pages"].([]interface{})
+	redirections, _ := content["redirectionPageMap"].([]interface{})
+	for i, p := range ids {
+		id, _ := p.(string)
+		ref := pageRef{id: id, redir: -1}
+		if i < len(redirections) {
+			if v, ok := redirections[i].(float64); ok {
+				ref.redir = int(v)
+			}
+		} else if hasPayload {
+			ref.redir = i
+		}
+		if i < len(pagedata) {
+			ref.template = pagedata[i]
+		}
+		pages = append(pages, ref)
+	}
+	return pages
+}
+
+// ExtractPage creates a new document containing a single page of a
+// document, given the raw files of its archive. The page number starts
+// at 1. The strokes are copied and, when the page shows a page of a
+// pdf, the pdf is kept as background with only this page displayed.
+// It returns the files of the new document and its ID.
+func ExtractPage(files map[string][]byte, docID string, page int) (map[string][]byte, string, error) {
+	data, ok := files[docID+".content"]
+	if !ok {
+		return nil, "", fmt.Errorf("%s.content not found in the archive", docID)
+	}
+	content := make(map[string]interface{})
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, "", fmt.Errorf("cannot read the content of the document: %v", err)
+	}
+
+	fileType, _ := content["fileType"].(string)
+	var payloads []string
+	for _, ext := range []string{"pdf", "epub"} {
+		if _, ok := files[docID+"."+ext]; ok {
+			payloads = append(payloads, ext)
+		}
+	}
+
+	pagedata := strings.Split(strings.TrimRight(string(files[docID+".pagedata"]), "\n"), "\n")
+	pages := documentPages(content, pagedata, len(payloads) > 0)
+	if page < 1 || page > len(pages) {
+		return nil, "", fmt.Errorf("page %d doesn't exist, the document has %d pages", page, len(pages))
+	}
+	ref := pages[page-1]
+	background := ref.redir >= 0 && len(payloads) > 0
+
+	newID := uuid.New().String()
+	newPageID := uuid.New().String()
+	out := make(map[string][]byte)
+
+	content["pages"] = []string{newPageID}
+	content["pageCount"] = 1
+	content["lastOpenedPage"] = 0
+	content["pageTags"] = []interface{}{}
+	if background {
+		content["redirectionPageMap"] = []int{ref.redir}
+		for _, ext := range payloads {
+			out[newID+"."+ext] = files[docID+"."+ext]
+		}
+	} else {
+		delete(content, "redirectionPageMap")
+		if fileType != "" {
+			content["fileType"] = "notebook"
+		}
+	}
+
+	if cPages, ok := content["cPages"].(map[string]interface{}); ok {
+		delete(content, "pages")
+		entry := map[string]interface{}{
+			"id":  newPageID,
+			"idx": map[string]interface{}{"timestamp": "1:2", "value": "ba"},
+		}
+		if ref.template != "" {
+			entry["template"] = map[string]interface{}{"timestamp": "1:1", "value": ref.template}
+		}
+		if background {
+			entry["redir"] = map[string]interface{}{"timestamp": "1:1", "value": ref.redir}
+		}
+		cPages["pages"] = []interface{}{entry}
+		cPages["lastOpened"] = map[string]interface{}{"timestamp": "1:1", "value": newPageID}
+	}
+
+	data, err := json.MarshalIndent(content, "", "    ")
+	if err != nil {
+		return nil, "", err
+	}
+	out[newID+".content"] = data
+
+	if ref.template != "" {
+		out[newID+".pagedata"] = []byte(ref.template + "\n")
+	}
+
+	// the page has no file when nothing was drawn on it
+	for _, suffix := range []string{".rm", "-metadata.json"} {
+		if f, ok := files[docID+"/"+ref.id+suffix]; ok {
+			out[newID+"/"+newPageID+suffix] = f
+		}
+	}
+
+	return out, newID, nil
+}
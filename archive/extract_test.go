@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExtractPageFromPdf(t *testing.T) {
+	files := map[string][]byte{
+		"doc.content":  []byte(`{"fileType":"pdf","pageCount":3,"pages":["p1","p2","p3"],"redirectionPageMap":[0,-1,1]}`),
+		"doc.pdf":      []byte("%PDF"),
+		"doc.pagedata": []byte("Blank\nP Grid small\nBlank\n"),
+		"doc/p3.rm":    []byte("strokes"),
+		"doc/p1.rm":    []byte("other"),
+		"doc.metadata": []byte("{}"),
+	}
+
+	out, id, err := ExtractPage(files, "doc", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out[id+".pdf"]) != "%PDF" {
+		t.Error("the pdf should be kept as background")
+	}
+	if _, ok := out[id+".metadata"]; ok {
+		t.Error("the metadata should not be copied")
+	}
+
+	var content map[string]interface{}
+	json.Unmarshal(out[id+".content"], &content)
+	pages := content["pages"].([]interface{})
+	if len(pages) != 1 || content["pageCount"].(float64) != 1 {
+		t.Fatalf("wrong pages %v", content)
+	}
+	if redir := content["redirectionPageMap"].([]interface{}); len(redir) != 1 || redir[0].(float64) != 1 {
+		t.Errorf("wrong redirection %v", redir)
+	}
+	if string(out[id+"/"+pages[0].(string)+".rm"]) != "strokes" {
+		t.Error("strokes not copied")
+	}
+
+	// an inserted blank page becomes a notebook
+	out, id, err = ExtractPage(files, "doc", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out[id+".pdf"]; ok {
+		t.Error("the pdf should not be copied for a blank page")
+	}
+	if !strings.Contains(string(out[id+".content"]), `"fileType": "notebook"`) {
+		t.Errorf("wrong content %s", out[id+".content"])
+	}
+	if string(out[id+".pagedata"]) != "P Grid small\n" {
+		t.Errorf("wrong template %q", out[id+".pagedata"])
+	}
+
+	if _, _, err := ExtractPage(files, "doc", 4); err == nil {
+		t.Error("expected an error for a missing page")
+	}
+}
+
+func TestExtractPageCPages(t *testing.T) {
+	files := map[string][]byte{
+		"doc.content": []byte(`{"formatVersion":2,"cPages":{"pages":[
+			{"id":"p2","idx":{"value":"bb"}},
+			{"id":"gone","idx":{"value":"ba"},"deleted":{"value":1}},
+			{"id":"p1","idx":{"value":"ba"},"template":{"value":"P Lines small"}}]}}`),
+		"doc/p2.rm": []byte("second"),
+	}
+
+	out, id, err := ExtractPage(files, "doc", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var content map[string]interface{}
+	json.Unmarshal(out[id+".content"], &content)
+	entry := content["cPages"].(map[string]interface{})["pages"].([]interface{})[0].(map[string]interface{})
+	if string(out[id+"/"+entry["id"].(string)+".rm"]) != "second" {
+		t.Error("wrong page extracted")
+	}
+	if _, ok := content["pages"]; ok {
+		t.Error("the old page list should not be added")
+	}
+}
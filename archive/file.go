@@ -1,6 +1,9 @@
 package archive
 
 import (
+	"archive/zip"
+	"io"
+
 	"github.com/joagonca/rmapi/encoding/rm"
 )
 
@@ -14,6 +17,16 @@ type Zip struct {
 	Payload []byte
 	UUID    string
 	pageMap map[string]int
+	// Mode is how the drawings of the pages are parsed: Lenient by
+	// default, the corrupted pages being skipped with a warning, or
+	// Strict to fail on them.
+	Mode rm.ParseMode
+
+	// the archive and the .rm files of the pages, kept by ReadLazy
+	zr        *zip.Reader
+	dataFiles map[int]*zip.File
+	// closer is the file opened by OpenZip
+	closer io.Closer
 }
 
 // NewZip creates a File with sane defaults.
@@ -60,6 +73,7 @@ func NewZip() *Zip {
 
 	return &Zip{
 		Content: content,
+		Mode:    rm.Lenient,
 	}
 }
 
@@ -75,6 +89,11 @@ type Page struct {
 	Pagedata string
 	// page number of the underlying document
 	DocPage int
+	// Highlights are the passages of text highlighted on the page
+	Highlights []Highlight
+	// highlightGroups are the sizes of the groups of Highlights in the
+	// file read, kept to write them back the same way
+	highlightGroups []int
 }
 
 // Metadata represents the structure of a .metadata json file associated to a page.
@@ -91,25 +110,87 @@ type Layer struct {
 type Content struct {
 	DummyDocument bool          `json:"dummyDocument"`
 	ExtraMetadata ExtraMetadata `json:"extraMetadata"`
+	// FormatVersion is 2 for the documents of the firmwares 3.x, which
+	// list their pages in CPages, and 1 or 0 for the older ones, which
+	// list them in Pages.
+	FormatVersion int `json:"formatVersion,omitempty"`
 
-	// FileType is "pdf", "epub" or empty for a simple note
+	// FileType is "pdf", "epub", or "notebook" or empty for a simple
+	// note. The Quick sheets are notebooks, with DummyDocument set on
+	// some firmwares.
 	FileType       string `json:"fileType"`
 	FontName       string `json:"fontName"`
 	LastOpenedPage int    `json:"lastOpenedPage"`
 	LineHeight     int    `json:"lineHeight"`
 	Margins        int    `json:"margins"`
+	// CoverPageNumber is the page shown as the cover in the library,
+	// 0 for the first page and -1 for the last visited one.
+	CoverPageNumber int `json:"coverPageNumber"`
 	// Orientation can take "portrait" or "landscape".
 	Orientation string `json:"orientation"`
 	PageCount   int    `json:"pageCount"`
 	// Pages is a list of page IDs
-	Pages          []string `json:"pages"`
-	Tags           []string `json:"pageTags"`
-	RedirectionMap []int    `json:"redirectionPageMap"`
-	TextScale      int      `json:"textScale"`
+	Pages []string `json:"pages,omitempty"`
+	// CPages lists the pages on the firmwares 3.x
+	CPages *CPages `json:"cPages,omitempty"`
+	// SizeInBytes is the size of the pdf or the epub, as a string
+	SizeInBytes string `json:"sizeInBytes,omitempty"`
+	// Tags are the tags of the document, PageTags the tags of its pages
+	Tags           []Tag `json:"tags,omitempty"`
+	PageTags       []Tag `json:"pageTags,omitempty"`
+	RedirectionMap []int `json:"redirectionPageMap,omitempty"`
+	// TextAlignment is the alignment of the text of the epubs, such as
+	// "justify" or "left"
+	TextAlignment string `json:"textAlignment,omitempty"`
+	TextScale     int    `json:"textScale"`
+	// ZoomMode is how the pages fit the screen, such as "bestFit",
+	// "fitToWidth" or "fitToHeight"
+	ZoomMode string `json:"zoomMode,omitempty"`
 
 	Transform Transform `json:"transform"`
 }
 
+// CPages lists the pages of a document on the firmwares 3.x. Its values
+// are stamped with the time they were set, such as "1:2", to merge the
+// changes of several devices.
+type CPages struct {
+	LastOpened StampedString `json:"lastOpened"`
+	// Original is the number of pages of the pdf, -1 for a notebook
+	Original StampedInt `json:"original"`
+	Pages    []CPage    `json:"pages"`
+	UUIDs    []CPageID  `json:"uuids,omitempty"`
+}
+
+// A CPage is a page of a document on the firmwares 3.x.
+type CPage struct {
+	ID string `json:"id"`
+	// Idx orders the pages by the sorting of its value
+	Idx StampedString `json:"idx"`
+	// Redir is the page of the pdf shown, from 0
+	Redir    *StampedInt    `json:"redir,omitempty"`
+	Template *StampedString `json:"template,omitempty"`
+	// Deleted is set to a value other than 0 for the removed pages
+	Deleted *StampedInt `json:"deleted,omitempty"`
+}
+
+// A CPageID is a device which created pages of a document.
+type CPageID struct {
+	First  string `json:"first"`
+	Second int    `json:"second"`
+}
+
+// A StampedString is a string of CPages with the time it was set.
+type StampedString struct {
+	Timestamp string `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// A StampedInt is a number of CPages with the time it was set.
+type StampedInt struct {
+	Timestamp string `json:"timestamp"`
+	Value     int    `json:"value"`
+}
+
 // ExtraMetadata is a struct contained into a Content struct.
 type ExtraMetadata struct {
 	LastBrushColor           string `json:"LastBrushColor"`
@@ -128,6 +209,15 @@ type ExtraMetadata struct {
 	LastFinelinerv2Size      string `json:"LastFinelinerv2Size"`
 }
 
+// A Tag is a tag of a document or of one of its pages.
+type Tag struct {
+	Name string `json:"name"`
+	// PageID is the page of a page tag
+	PageID string `json:"pageId,omitempty"`
+	// Timestamp is the time the tag was added in milliseconds
+	Timestamp int64 `json:"timestamp"`
+}
+
 // Transform is a struct contained into a Content struct.
 type Transform struct {
 	M11 float32 `json:"m11"`
@@ -141,6 +231,18 @@ type Transform struct {
 	M33 float32 `json:"m33"`
 }
 
+// Matrix returns the transform of the drawings of the pages as a matrix
+// a b c d e f of device pixels, like the ones of PDF, and whether it
+// moves them. The transform is stored like the ones of Qt, its third
+// column is ignored. The documents without one have a zero transform.
+func (t Transform) Matrix() ([6]float64, bool) {
+	m := [6]float64{float64(t.M11), float64(t.M12), float64(t.M21), float64(t.M22), float64(t.M31), float64(t.M32)}
+	if m == ([6]float64{}) || m == ([6]float64{1, 0, 0, 1, 0, 0}) {
+		return m, false
+	}
+	return m, true
+}
+
 // MetadataFile content
 type MetadataFile struct {
 	DocName        string `json:"visibleName"`
@@ -156,4 +258,17 @@ type MetadataFile struct {
 	Modified         bool   `json:"modified"`
 	Deleted          bool   `json:"deleted"`
 	MetadataModified bool   `json:"metadatamodified"`
+	Tags             []Tag  `json:"tags,omitempty"`
+}
+
+// TagNames returns the names of the tags of the entry.
+func (m MetadataFile) TagNames() []string {
+	if len(m.Tags) == 0 {
+		return nil
+	}
+	names := make([]string, len(m.Tags))
+	for i, t := range m.Tags {
+		names[i] = t.Name
+	}
+	return names
 }
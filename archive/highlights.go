@@ -0,0 +1,291 @@
+package archive
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joagonca/rmapi/log"
+)
+
+// A Highlight is a passage of text highlighted in a pdf or epub.
+type Highlight struct {
+	Text   string `json:"text"`
+	Color  int    `json:"color"`
+	Start  int    `json:"start"`
+	Length int    `json:"length"`
+	// Rects cover the text on the page, in the pixels of the device
+	// like the strokes
+	Rects []HighlightRect `json:"rects"`
+}
+
+// A HighlightRect is a part of a highlight, usually a line of text.
+type HighlightRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// highlightsFile is the structure of the json files in the .highlights
+// folder of an archive, one per page.
+type highlightsFile struct {
+	Highlights [][]Highlight `json:"highlights"`
+}
+
+// highlightColors names the colors of the highlighter.
+var highlightColors = map[int]string{
+	1:  "grey",
+	2:  "white",
+	3:  "yellow",
+	4:  "green",
+	5:  "pink",
+	6:  "blue",
+	7:  "red",
+	8:  "grey",
+	9:  "yellow",
+	10: "green",
+	11: "cyan",
+	12: "magenta",
+	13: "yellow",
+}
+
+// ColorName returns the name of the color of the highlight. The files
+// written before colors were introduced have none, their highlights
+// are yellow.
+func (h Highlight) ColorName() string {
+	if h.Color == 0 {
+		return "yellow"
+	}
+	if name, ok := highlightColors[h.Color]; ok {
+		return name
+	}
+	return strconv.Itoa(h.Color)
+}
+
+// Box returns the rectangle bounding the rects of the highlight, nil
+// when it has none.
+func (h Highlight) Box() *HighlightRect {
+	if len(h.Rects) == 0 {
+		return nil
+	}
+	minX, minY := h.Rects[0].X, h.Rects[0].Y
+	maxX, maxY := minX+h.Rects[0].Width, minY+h.Rects[0].Height
+	for _, r := range h.Rects[1:] {
+		minX, minY = math.Min(minX, r.X), math.Min(minY, r.Y)
+		maxX, maxY = math.Max(maxX, r.X+r.Width), math.Max(maxY, r.Y+r.Height)
+	}
+	return &HighlightRect{X: minX, Y: minY, Width: maxX - minX, Height: maxY - minY}
+}
+
+// readHighlights extracts the highlights of the pages from an archive.
+func (z *Zip) readHighlights(zr *zip.Reader) error {
+	for _, file := range zr.File {
+		if !strings.HasSuffix(path.Dir(file.Name), ".highlights") {
+			continue
+		}
+		name, ext := splitExt(file.FileInfo().Name())
+		if ext != ".json" {
+			continue
+		}
+
+		idx, err := z.pageIndex(name)
+		if err != nil || idx >= len(z.Pages) {
+			log.Warning.Printf("highlights of unknown page %s", name)
+			continue
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return err
+		}
+		var content highlightsFile
+		err = json.NewDecoder(r).Decode(&content)
+		r.Close()
+		if err != nil {
+			log.Warning.Printf("page %d: invalid highlights, %v", idx, err)
+			continue
+		}
+
+		for _, group := range content.Highlights {
+			z.Pages[idx].Highlights = append(z.Pages[idx].Highlights, group...)
+			z.Pages[idx].highlightGroups = append(z.Pages[idx].highlightGroups, len(group))
+		}
+	}
+	return nil
+}
+
+// writeHighlights writes the highlights of the pages to the .highlights
+// folder of the archive, in the groups they were read in. The files are
+// named after the ids of the pages, as on the device, or their index
+// when the content doesn't list them.
+func (z *Zip) writeHighlights(zw *zip.Writer) error {
+	ids := make(map[int]string)
+	for id, idx := range z.pageMap {
+		ids[idx] = id
+	}
+	for idx, page := range z.Pages {
+		if len(page.Highlights) == 0 {
+			continue
+		}
+
+		groups := page.highlightGroups
+		total := 0
+		for _, n := range groups {
+			total += n
+		}
+		if total != len(page.Highlights) {
+			groups = []int{len(page.Highlights)}
+		}
+		content := highlightsFile{}
+		rest := page.Highlights
+		for _, n := range groups {
+			content.Highlights = append(content.Highlights, rest[:n])
+			rest = rest[n:]
+		}
+
+		name, ok := ids[idx]
+		if !ok {
+			name = strconv.Itoa(idx)
+		}
+		w, err := addToZip(zw, path.Join(z.UUID+".highlights", name+".json"))
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(w).Encode(&content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A HighlightRecord is a highlight with its location, as exported.
+type HighlightRecord struct {
+	Document  string    `json:"document"`
+	Page      int       `json:"page"`
+	Text      string    `json:"text"`
+	Color     string    `json:"color"`
+	Timestamp time.Time `json:"timestamp"`
+	// Box bounds the Rects of the highlight, in the pixels of the
+	// device, nil when the archive has none
+	Box   *HighlightRect  `json:"box,omitempty"`
+	Rects []HighlightRect `json:"rects,omitempty"`
+}
+
+// HighlightRecords lists the highlights of the archive in page order,
+// with the page numbers of the original document starting at 1. The
+// highlights don't have a date of their own, modified is used.
+func (z *Zip) HighlightRecords(document string, modified time.Time) []HighlightRecord {
+	records := []HighlightRecord{}
+	for i, page := range z.Pages {
+		number := i + 1
+		if page.DocPage >= 0 {
+			number = page.DocPage + 1
+		}
+		for _, h := range page.Highlights {
+			records = append(records, HighlightRecord{
+				Document:  document,
+				Page:      number,
+				Text:      h.Text,
+				Color:     h.ColorName(),
+				Timestamp: modified,
+				Box:       h.Box(),
+				Rects:     h.Rects,
+			})
+		}
+	}
+	return records
+}
+
+// WriteHighlightsCSV writes the records as csv with a header line.
+func WriteHighlightsCSV(w io.Writer, records []HighlightRecord) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"document", "page", "text", "color", "timestamp"})
+	for _, r := range records {
+		cw.Write([]string{r.Document, strconv.Itoa(r.Page), r.Text, r.Color, formatTimestamp(r.Timestamp)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteHighlightsJSON writes the records as an indented json array.
+func WriteHighlightsJSON(w io.Writer, records []HighlightRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// WriteHighlightsMarkdown writes the records as markdown, under a
+// heading for every document and page, the texts being quoted.
+func WriteHighlightsMarkdown(w io.Writer, records []HighlightRecord) error {
+	bw := bufio.NewWriter(w)
+	document, page := "", 0
+	for i, r := range records {
+		if i == 0 || r.Document != document {
+			if i > 0 {
+				bw.WriteString("\n")
+			}
+			fmt.Fprintf(bw, "# %s\n", r.Document)
+			document, page = r.Document, 0
+		}
+		if r.Page != page {
+			fmt.Fprintf(bw, "\n## Page %d\n", r.Page)
+			page = r.Page
+		}
+		bw.WriteString("\n> " + strings.Join(strings.Split(strings.TrimSpace(r.Text), "\n"), "\n> ") + "\n")
+	}
+	return bw.Flush()
+}
+
+// WriteHighlightsText writes the texts of the records as plain text,
+// under a line with the page for every page, the documents being
+// separated by their name.
+func WriteHighlightsText(w io.Writer, records []HighlightRecord) error {
+	bw := bufio.NewWriter(w)
+	document, page := "", 0
+	for i, r := range records {
+		if i == 0 || r.Document != document {
+			if i > 0 {
+				bw.WriteString("\n")
+			}
+			fmt.Fprintf(bw, "%s\n", r.Document)
+			document, page = r.Document, 0
+		}
+		if r.Page != page {
+			fmt.Fprintf(bw, "\nPage %d\n", r.Page)
+			page = r.Page
+		}
+		bw.WriteString("\n" + strings.TrimSpace(r.Text) + "\n")
+	}
+	return bw.Flush()
+}
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// WriteHighlights writes the records in the given format.
+func WriteHighlights(w io.Writer, format string, records []HighlightRecord) error {
+	switch format {
+	case "csv":
+		return WriteHighlightsCSV(w, records)
+	case "json":
+		return WriteHighlightsJSON(w, records)
+	case "markdown":
+		return WriteHighlightsMarkdown(w, records)
+	case "text":
+		return WriteHighlightsText(w, records)
+	}
+	return fmt.Errorf("unknown format %s, expected csv, json, markdown or text", format)
+}
@@ -0,0 +1,138 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func highlightedArchive(t *testing.T) []byte {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	files := map[string]string{
+		"doc.content":  `{"fileType":"pdf","pageCount":2,"pages":["a1e7c8f0-0000-4000-8000-000000000001","a1e7c8f0-0000-4000-8000-000000000002"]}`,
+		"doc.pagedata": "Blank\nBlank\n",
+		"doc.highlights/a1e7c8f0-0000-4000-8000-000000000002.json": `{"highlights":[[{"text":"first, passage","color":4,"start":10,"length":14,"rects":[{"x":100,"y":200,"width":300,"height":20},{"x":100,"y":225,"width":150,"height":20}]},{"text":"second","start":40,"length":6}]]}`,
+	}
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestHighlightRecords(t *testing.T) {
+	data := highlightedArchive(t)
+	z := NewZip()
+	if err := z.Read(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := z.HighlightRecords("/Books/doc", modified)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 highlights, got %d", len(records))
+	}
+	if r := records[0]; r.Page != 2 || r.Text != "first, passage" || r.Color != "green" || r.Document != "/Books/doc" {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if b := records[0].Box; b == nil || *b != (HighlightRect{X: 100, Y: 200, Width: 300, Height: 45}) || len(records[0].Rects) != 2 {
+		t.Errorf("unexpected box %v of %v", b, records[0].Rects)
+	}
+	if records[1].Box != nil {
+		t.Errorf("the highlight without rects should have no box, got %v", records[1].Box)
+	}
+	if records[1].Color != "yellow" {
+		t.Errorf("highlights without color should be yellow, got %s", records[1].Color)
+	}
+
+	var csv bytes.Buffer
+	if err := WriteHighlights(&csv, "csv", records); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if len(lines) != 3 || lines[1] != `/Books/doc,2,"first, passage",green,2024-03-01T12:00:00Z` {
+		t.Errorf("unexpected csv %q", csv.String())
+	}
+
+	var js bytes.Buffer
+	if err := WriteHighlights(&js, "json", records); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []HighlightRecord
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || !decoded[0].Timestamp.Equal(modified) || decoded[0].Box == nil || decoded[0].Box.Height != 45 {
+		t.Errorf("unexpected json %s", js.String())
+	}
+
+	var md bytes.Buffer
+	if err := WriteHighlights(&md, "markdown", records); err != nil {
+		t.Fatal(err)
+	}
+	if want := "# /Books/doc\n\n## Page 2\n\n> first, passage\n\n> second\n"; md.String() != want {
+		t.Errorf("unexpected markdown %q", md.String())
+	}
+
+	var text bytes.Buffer
+	if err := WriteHighlights(&text, "text", records); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/Books/doc\n\nPage 2\n\nfirst, passage\n\nsecond\n"; text.String() != want {
+		t.Errorf("unexpected text %q", text.String())
+	}
+
+	if err := WriteHighlights(&js, "xml", records); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestHighlightsRoundTrip(t *testing.T) {
+	data := highlightedArchive(t)
+	z := NewZip()
+	if err := z.Read(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := z.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		if strings.Contains(f.Name, ".highlights/") {
+			names = append(names, f.Name)
+		}
+	}
+	want := z.UUID + ".highlights/a1e7c8f0-0000-4000-8000-000000000002.json"
+	if len(names) != 1 || names[0] != want {
+		t.Errorf("expected the highlights in %s, got %v", want, names)
+	}
+
+	read := NewZip()
+	if err := read.Read(bytes.NewReader(b.Bytes()), int64(b.Len())); err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Pages) != 2 || len(read.Pages[0].Highlights) != 0 {
+		t.Fatalf("unexpected pages %+v", read.Pages)
+	}
+	got, _ := json.Marshal(read.Pages[1].Highlights)
+	expected, _ := json.Marshal(z.Pages[1].Highlights)
+	if !bytes.Equal(got, expected) || len(read.Pages[1].highlightGroups) != 1 {
+		t.Errorf("expected the highlights %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,44 @@
+package archive
+
+import (
+	"archive/zip"
+	"os"
+	"time"
+)
+
+// ReadRawFiles reads all the files of an archive, as downloaded from
+// the cloud, without interpreting them.
+func ReadRawFiles(path string) (map[string][]byte, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	files := make(map[string][]byte)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		data, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		files[f.Name] = data
+	}
+	return files, nil
+}
+
+// WriteRawFiles writes files into a new archive which can be uploaded.
+func WriteRawFiles(path string, files map[string][]byte) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := writeFiles(out, files, time.Time{}); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -5,8 +5,10 @@ import (
 	"bufio"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"path"
 	"path/filepath"
 	"strconv"
@@ -15,48 +17,120 @@ import (
 	"github.com/google/uuid"
 	"github.com/joagonca/rmapi/encoding/rm"
 	"github.com/joagonca/rmapi/log"
+	"github.com/joagonca/rmapi/strokes"
 	"github.com/joagonca/rmapi/util"
 )
 
 // Read fills a Zip parsing a Remarkable archive file.
 func (z *Zip) Read(r io.ReaderAt, size int64) error {
-	zr, err := zip.NewReader(r, size)
+	zr, hasPages, err := z.readStructure(r, size)
 	if err != nil {
 		return err
 	}
 
-	// reading content first because it contains the number of pages
-	if err := z.readContent(zr); err != nil {
-		return err
-	}
-
 	if err := z.readPayload(zr); err != nil {
 		return err
 	}
 
-	//uploading and then downloading a file results in 0 pages
-	if z.Content.PageCount <= 0 {
-		log.Warning.Printf("PageCount is 0")
+	if !hasPages {
 		return nil
 	}
 
-	if err := z.readMetadata(zr); err != nil {
+	if err := z.readData(zr); err != nil {
 		return err
 	}
 
-	if err := z.readPagedata(zr); err != nil {
+	if err := z.readThumbnails(zr); err != nil {
 		return err
 	}
 
-	if err := z.readData(zr); err != nil {
+	return nil
+}
+
+// ReadLazy fills a Zip like Read, except for the payload, the drawings
+// and the thumbnails, so that large documents don't have to be loaded
+// in memory. The payload and the drawings are read from r on demand
+// with OpenPayload and PageData, r must stay readable until then.
+func (z *Zip) ReadLazy(r io.ReaderAt, size int64) error {
+	zr, hasPages, err := z.readStructure(r, size)
+	if err != nil {
 		return err
 	}
+	z.zr = zr
 
-	if err := z.readThumbnails(zr); err != nil {
-		return err
+	if !hasPages {
+		return nil
 	}
 
-	return nil
+	z.dataFiles, err = z.pageDataFiles(zr)
+	return err
+}
+
+// OpenZip reads an archive file like ReadLazy: the pages are listed
+// and their drawings, their thumbnails and the payload are read on
+// demand. The file stays open until Close is called.
+func OpenZip(path string) (*Zip, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	z := NewZip()
+	if err := z.ReadLazy(file, fi.Size()); err != nil {
+		file.Close()
+		return nil, err
+	}
+	z.closer = file
+	return z, nil
+}
+
+// Close closes the file of an archive opened with OpenZip.
+func (z *Zip) Close() error {
+	if z.closer == nil {
+		return nil
+	}
+	err := z.closer.Close()
+	z.closer = nil
+	return err
+}
+
+// readStructure reads the content and the metadata of the pages. It
+// tells whether the pages can be read.
+func (z *Zip) readStructure(r io.ReaderAt, size int64) (*zip.Reader, bool, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// reading content first because it contains the number of pages
+	if err := z.readContent(zr); err != nil {
+		return nil, false, err
+	}
+
+	// uploading and then downloading a file results in 0 pages, the
+	// Quick sheets list their pages with a pageCount of 0
+	if len(z.Pages) == 0 {
+		log.Warning.Printf("PageCount is 0")
+		return zr, false, nil
+	}
+
+	if err := z.readMetadata(zr); err != nil {
+		return nil, false, err
+	}
+
+	if err := z.readPagedata(zr); err != nil {
+		return nil, false, err
+	}
+
+	if err := z.readHighlights(zr); err != nil {
+		return nil, false, err
+	}
+
+	return zr, true, nil
 }
 
 // readContent reads the .content file contained in an archive and the UUID
@@ -91,11 +165,32 @@ func (z *Zip) readContent(zr *zip.Reader) error {
 
 	redirectedCount := len(z.Content.RedirectionMap)
 	pagesCount := len(z.Content.Pages)
+	if pagesCount == 0 && redirectedCount == 0 {
+		// the newer firmwares list the pages and their redirection
+		// to the pages of the PDF in a "cPages" structure
+		var raw map[string]interface{}
+		if err := json.Unmarshal(bytes, &raw); err == nil {
+			if refs := documentPages(raw, nil, false); len(refs) > 0 {
+				z.pageMap = make(map[string]int)
+				z.Pages = make([]Page, len(refs))
+				background := z.Content.FileType == "pdf" || z.Content.FileType == "epub"
+				for index, ref := range refs {
+					z.pageMap[ref.id] = index
+					z.Pages[index].DocPage = ref.redir
+					if !background {
+						z.Pages[index].DocPage = index
+					}
+					z.Pages[index].Pagedata = ref.template
+				}
+				return nil
+			}
+		}
+	}
 	if redirectedCount > 0 {
 		z.pageMap = make(map[string]int)
 		z.Pages = make([]Page, redirectedCount)
 		for index, docPage := range z.Content.RedirectionMap {
-			if index > pagesCount {
+			if index >= pagesCount {
 				log.Warning.Print("redirection > pages")
 				break
 			}
@@ -114,18 +209,26 @@ func (z *Zip) readContent(zr *zip.Reader) error {
 	} else {
 		// instantiate the slice of pages
 		z.Pages = make([]Page, z.Content.PageCount)
+		for index := range z.Pages {
+			z.Pages[index].DocPage = index
+		}
 	}
 	return nil
 }
 
 // readPagedata reads the .pagedata file contained in an archive
-// and iterate to gather which template was used for each page.
+// and iterate to gather which template was used for each page. The
+// documents without one, such as the Quick sheets of the newer
+// firmwares, are drawn without template.
 func (z *Zip) readPagedata(zr *zip.Reader) error {
 	files, err := zipExtFinder(zr, ".pagedata")
 	if err != nil {
 		return err
 	}
 
+	if len(files) == 0 {
+		return nil
+	}
 	if len(files) != 1 {
 		return errors.New("archive does not contain a unique pagedata file")
 	}
@@ -138,8 +241,10 @@ func (z *Zip) readPagedata(zr *zip.Reader) error {
 
 	// iterate pagedata file lines
 	sc := bufio.NewScanner(file)
+	// the lines of the pages deleted from the Quick sheets may be
+	// left over
 	var i int = 0
-	for sc.Scan() {
+	for sc.Scan() && i < len(z.Pages) {
 		line := sc.Text()
 		z.Pages[i].Pagedata = line
 		i++
@@ -165,57 +270,159 @@ func (z *Zip) readPayload(zr *zip.Reader) error {
 		return nil
 	}
 
-	file, err := files[0].Open()
-	if err != nil {
-		return err
+	z.Payload, err = readZipFile(files[0])
+	return err
+}
+
+// OpenPayload opens the payload of an archive read by ReadLazy. It
+// returns nil when there is none.
+func (z *Zip) OpenPayload() (io.ReadCloser, error) {
+	return z.openFile("." + z.Content.FileType)
+}
+
+// OpenPDF opens the pdf of an archive read by ReadLazy: the payload of
+// the pdf documents, or the book as paginated by the device for the
+// epubs which have one. It returns nil when there is none.
+func (z *Zip) OpenPDF() (io.ReadCloser, error) {
+	return z.openFile(".pdf")
+}
+
+func (z *Zip) openFile(ext string) (io.ReadCloser, error) {
+	if z.zr == nil {
+		return nil, errors.New("archive not read lazily")
 	}
-	defer file.Close()
+	files, err := zipExtFinder(z.zr, ext)
+	if err != nil || len(files) != 1 {
+		return nil, err
+	}
+	return files[0].Open()
+}
+
+// PageData reads the drawing of a page of an archive read by ReadLazy.
+// It returns nil when the page has no drawing or cannot be read.
+func (z *Zip) PageData(idx int) (*rm.Rm, error) {
+	file, ok := z.dataFiles[idx]
+	if !ok {
+		return nil, nil
+	}
+	return readPageData(file, idx, z.Mode)
+}
+
+// PageDrawing reads the drawing of a page like PageData, as the device
+// shows it, see ShowDrawing.
+func (z *Zip) PageDrawing(idx int) (*rm.Rm, error) {
+	data, err := z.PageData(idx)
+	if data != nil {
+		z.ShowDrawing(data)
+	}
+	return data, err
+}
 
-	z.Payload, err = ioutil.ReadAll(file)
+// ShowDrawing changes the drawing of a page of the archive into the one
+// shown by the device: the strokes erased with the area eraser are
+// removed and the transform of the document is applied.
+func (z *Zip) ShowDrawing(data *rm.Rm) {
+	strokes.EraseAreas(data)
+	if m, ok := z.Content.Transform.Matrix(); ok {
+		strokes.TransformPage(data, m)
+	}
+}
+
+// readData extracts existing .rm files from an archive.
+func (z *Zip) readData(zr *zip.Reader) error {
+	files, err := z.pageDataFiles(zr)
 	if err != nil {
 		return err
 	}
 
+	for idx, file := range files {
+		page, err := readPageData(file, idx, z.Mode)
+		if err != nil {
+			return err
+		}
+		z.Pages[idx].Data = page
+	}
+
 	return nil
 }
 
-// readData extracts existing .rm files from an archive.
-func (z *Zip) readData(zr *zip.Reader) error {
+// pageDataFiles finds the .rm files of the pages.
+func (z *Zip) pageDataFiles(zr *zip.Reader) (map[int]*zip.File, error) {
 	files, err := zipExtFinder(zr, ".rm")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	result := make(map[int]*zip.File)
 	for _, file := range files {
 		name, _ := splitExt(file.FileInfo().Name())
 
 		idx, err := z.pageIndex(name)
+		if errors.Is(err, errPageNotListed) {
+			continue
+		}
 		if err != nil {
-			return err
+			return nil, err
 		}
+		result[idx] = file
+	}
 
-		if len(z.Pages) <= idx {
-			return errors.New("page not found")
-		}
+	return result, nil
+}
 
-		r, err := file.Open()
-		if err != nil {
-			return err
-		}
+// readPageData parses a .rm file. In lenient mode, corrupted pages are
+// skipped with a warning, they should not prevent reading the rest of
+// the document.
+func readPageData(file *zip.File, idx int, mode rm.ParseMode) (*rm.Rm, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
 
-		bytes, err := ioutil.ReadAll(r)
-		if err != nil {
-			return err
-		}
+	bytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
 
-		z.Pages[idx].Data = rm.New()
-		err = z.Pages[idx].Data.UnmarshalBinary(bytes)
-		if err != nil {
-			return err
-		}
+	page := rm.New()
+	warnings, err := page.UnmarshalMode(bytes, mode)
+	if err != nil && mode == rm.Strict {
+		return nil, fmt.Errorf("page %d: %v", idx, err)
 	}
+	for _, w := range warnings {
+		log.Warning.Printf("page %d: %s", idx, w)
+	}
+	if err != nil {
+		log.Warning.Printf("page %d: skipped, %v", idx, err)
+		return nil, nil
+	}
+	return page, nil
+}
 
-	return nil
+// PageThumbnail reads the thumbnail of a page of an archive read by
+// ReadLazy. It returns nil when the page has none.
+func (z *Zip) PageThumbnail(idx int) ([]byte, error) {
+	if idx < 0 || idx >= len(z.Pages) {
+		return nil, fmt.Errorf("page %d doesn't exist", idx)
+	}
+	if z.Pages[idx].Thumbnail != nil {
+		return z.Pages[idx].Thumbnail, nil
+	}
+	if z.zr == nil {
+		return nil, errors.New("archive not read lazily")
+	}
+	files, err := zipExtFinder(z.zr, ".jpg")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name, _ := splitExt(file.FileInfo().Name())
+		if i, err := z.pageIndex(name); err == nil && i == idx {
+			return readZipFile(file)
+		}
+	}
+	return nil, nil
 }
 
 // readThumbnails extracts existing thumbnails from an archive.
@@ -228,15 +435,15 @@ func (z *Zip) readThumbnails(zr *zip.Reader) error {
 	for _, file := range files {
 		name, _ := splitExt(file.FileInfo().Name())
 
-		idx, err := strconv.Atoi(name)
+		// named by number, or by page id on the newer firmwares
+		idx, err := z.pageIndex(name)
+		if errors.Is(err, errPageNotListed) {
+			continue
+		}
 		if err != nil {
 			return errors.New("error in .jpg filename")
 		}
 
-		if len(z.Pages) <= idx {
-			return errors.New("page not found")
-		}
-
 		r, err := file.Open()
 		if err != nil {
 			return err
@@ -251,9 +458,20 @@ func (z *Zip) readThumbnails(zr *zip.Reader) error {
 	return nil
 }
 
+// errPageNotListed is the error of the files of the pages which the
+// content doesn't list, such as the pages deleted from the Quick
+// sheets, whose files are left over. They are ignored.
+var errPageNotListed = errors.New("page not listed in the content")
+
+// pageIndex returns the index of the page of a file named after it,
+// by number or by page id.
 func (z *Zip) pageIndex(namePart string) (idx int, err error) {
 	idx, err = strconv.Atoi(namePart)
 	if err == nil {
+		if idx < 0 || idx >= len(z.Pages) {
+			log.Warning.Println("Page not found: ", namePart)
+			return -1, errPageNotListed
+		}
 		return idx, nil
 	}
 	_, err = uuid.Parse(namePart)
@@ -268,6 +486,7 @@ func (z *Zip) pageIndex(namePart string) (idx int, err error) {
 	idx, ok = z.pageMap[namePart]
 	if !ok {
 		log.Warning.Println("Page not found in map: ", namePart)
+		return -1, errPageNotListed
 	}
 
 	return
@@ -286,14 +505,13 @@ func (z *Zip) readMetadata(zr *zip.Reader) error {
 		// name is 0-metadata.json or uuid-metadata
 		namePart := strings.TrimSuffix(name, "-metadata")
 		idx, err := z.pageIndex(namePart)
+		if errors.Is(err, errPageNotListed) {
+			continue
+		}
 		if err != nil {
 			return err
 		}
 
-		if len(z.Pages) <= idx {
-			return errors.New("page not found")
-		}
-
 		r, err := file.Open()
 		if err != nil {
 			return err
@@ -1,8 +1,15 @@
 package archive
 
 import (
+	"archive/zip"
+	"bytes"
+	"io"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/joagonca/rmapi/encoding/rm"
 )
 
 func TestRead(t *testing.T) {
@@ -26,3 +33,249 @@ func TestRead(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestReadLazy(t *testing.T) {
+	file, err := os.Open("test.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eager := NewZip()
+	if err := eager.Read(file, fi.Size()); err != nil {
+		t.Fatal(err)
+	}
+	lazy := NewZip()
+	if err := lazy.ReadLazy(file, fi.Size()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lazy.Pages) != len(eager.Pages) {
+		t.Fatalf("expected %d pages, got %d", len(eager.Pages), len(lazy.Pages))
+	}
+	for i, page := range eager.Pages {
+		data, err := lazy.PageData(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (data == nil) != (page.Data == nil) {
+			t.Errorf("page %d: drawing read lazily differs", i)
+		}
+		if lazy.Pages[i].Data != nil {
+			t.Errorf("page %d: drawing should not be loaded", i)
+		}
+	}
+}
+
+func TestOpenZip(t *testing.T) {
+	file, err := os.Open("test.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eager := NewZip()
+	if err := eager.Read(file, fi.Size()); err != nil {
+		t.Fatal(err)
+	}
+
+	lazy, err := OpenZip("test.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lazy.Close()
+	if lazy.UUID != eager.UUID || len(lazy.Pages) != len(eager.Pages) {
+		t.Fatalf("expected the document %s of %d pages, got %s of %d", eager.UUID, len(eager.Pages), lazy.UUID, len(lazy.Pages))
+	}
+	if lazy.Pages[0].Thumbnail != nil {
+		t.Error("the thumbnail should not be loaded")
+	}
+	thumbnail, err := lazy.PageThumbnail(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thumbnail) == 0 || !bytes.Equal(thumbnail, eager.Pages[0].Thumbnail) {
+		t.Errorf("expected the thumbnail of %d bytes, got %d", len(eager.Pages[0].Thumbnail), len(thumbnail))
+	}
+	if _, err := lazy.PageThumbnail(len(lazy.Pages)); err == nil {
+		t.Error("expected an error for a page which doesn't exist")
+	}
+	if err := lazy.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadQuickSheets(t *testing.T) {
+	src, err := zip.OpenReader("test.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	var drawing []byte
+	for _, f := range src.File {
+		if strings.HasSuffix(f.Name, ".rm") {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			drawing, err = io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// two pages listed with a pageCount of 0, the files of a deleted
+	// page left over, and no pagedata
+	const (
+		first   = "5a3d2b0e-8c1f-4f0e-9d55-0c1b3e7a9f01"
+		second  = "5a3d2b0e-8c1f-4f0e-9d55-0c1b3e7a9f02"
+		deleted = "5a3d2b0e-8c1f-4f0e-9d55-0c1b3e7a9f03"
+	)
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for name, data := range map[string][]byte{
+		"sheets.content":                        []byte(`{"dummyDocument":true,"fileType":"notebook","pageCount":0,"pages":["` + first + `","` + second + `"]}`),
+		"sheets/" + second + ".rm":              drawing,
+		"sheets/" + deleted + ".rm":             drawing,
+		"sheets/" + deleted + "-metadata.json":  []byte(`{"layers":[{"name":"Layer 1"}]}`),
+		"sheets.thumbnails/" + deleted + ".jpg": []byte("jpg"),
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	eager := NewZip()
+	if err := eager.Read(bytes.NewReader(b.Bytes()), int64(b.Len())); err != nil {
+		t.Fatal(err)
+	}
+	if len(eager.Pages) != 2 || eager.Pages[0].Data != nil || eager.Pages[1].Data == nil {
+		t.Errorf("expected the drawing on the second of 2 pages, got %+v", eager.Pages)
+	}
+
+	lazy := NewZip()
+	if err := lazy.ReadLazy(bytes.NewReader(b.Bytes()), int64(b.Len())); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := lazy.PageData(1); err != nil || data == nil {
+		t.Errorf("expected the drawing of the second page, got %v", err)
+	}
+}
+
+func TestReadRedirections(t *testing.T) {
+	read := func(content string) *Zip {
+		t.Helper()
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("doc.content")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		z := NewZip()
+		if err := z.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+			t.Fatal(err)
+		}
+		return z
+	}
+	const (
+		a = "6b1e7c2d-9f30-4a8e-b1c4-2d5e8f0a1b01"
+		b = "6b1e7c2d-9f30-4a8e-b1c4-2d5e8f0a1b02"
+		c = "6b1e7c2d-9f30-4a8e-b1c4-2d5e8f0a1b03"
+		d = "6b1e7c2d-9f30-4a8e-b1c4-2d5e8f0a1b04"
+	)
+	docPages := func(z *Zip) []int {
+		var pages []int
+		for _, page := range z.Pages {
+			pages = append(pages, page.DocPage)
+		}
+		return pages
+	}
+
+	// a page inserted before the second page of the pdf, whose first
+	// page was moved to the end
+	z := read(`{"fileType":"pdf","pages":["` + a + `","` + b + `","` + c + `"],"redirectionPageMap":[1,-1,0]}`)
+	if got := docPages(z); !reflect.DeepEqual(got, []int{1, -1, 0}) {
+		t.Errorf("expected the pages of the pdf [1 -1 0], got %v", got)
+	}
+	if index, err := z.pageIndex(c); err != nil || index != 2 {
+		t.Errorf("expected the page c at 2, got %d (%v)", index, err)
+	}
+
+	z = read(`{"fileType":"pdf","cPages":{"pages":[
+		{"id":"` + c + `","idx":{"value":"bc"},"redir":{"value":0}},
+		{"id":"` + a + `","idx":{"value":"ba"},"redir":{"value":1},"template":{"value":"Blank"}},
+		{"id":"` + d + `","idx":{"value":"bd"},"redir":{"value":2},"deleted":{"value":1}},
+		{"id":"` + b + `","idx":{"value":"bb"}}
+	]}}`)
+	if got := docPages(z); !reflect.DeepEqual(got, []int{1, -1, 0}) {
+		t.Errorf("expected the pages of the pdf [1 -1 0], got %v", got)
+	}
+	if index, err := z.pageIndex(b); err != nil || index != 1 {
+		t.Errorf("expected the page b at 1, got %d (%v)", index, err)
+	}
+	if z.Pages[0].Pagedata != "Blank" {
+		t.Errorf("expected the template of the first page, got %q", z.Pages[0].Pagedata)
+	}
+}
+
+func TestReadParseMode(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string][]byte{
+		"doc.content": []byte(`{"fileType":"notebook","pages":["0","1"]}`),
+		"doc/0.rm":    testStrokes(t, 2),
+		"doc/1.rm":    testStrokes(t, 3)[:60],
+	}
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(buf.Bytes())
+
+	z := NewZip()
+	if err := z.Read(r, r.Size()); err != nil {
+		t.Fatalf("the corrupted page should be skipped in lenient mode: %v", err)
+	}
+	if z.Pages[0].Data == nil {
+		t.Error("expected the drawing of the first page")
+	}
+
+	z = NewZip()
+	z.Mode = rm.Strict
+	if err := z.Read(r, r.Size()); err == nil {
+		t.Error("expected an error for the corrupted page in strict mode")
+	}
+	z = NewZip()
+	z.Mode = rm.Strict
+	if err := z.ReadLazy(r, r.Size()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := z.PageData(1); err == nil {
+		t.Error("expected an error reading the corrupted page in strict mode")
+	}
+}
@@ -0,0 +1,316 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// pageEntry is a page of a document with its entry in the "cPages"
+// structure of the newer firmwares, nil on the older ones.
+type pageEntry struct {
+	pageRef
+	entry map[string]interface{}
+}
+
+// repackDoc is a document read from the raw files of its archive,
+// whose pages can be changed.
+type repackDoc struct {
+	files   map[string][]byte
+	id      string
+	content map[string]interface{}
+	pages   []pageEntry
+	// payload is the extension of the pdf or the epub, empty for a
+	// notebook
+	payload string
+}
+
+func readRepackDoc(files map[string][]byte, docID string) (*repackDoc, error) {
+	data, ok := files[docID+".content"]
+	if !ok {
+		return nil, fmt.Errorf("%s.content not found in the archive", docID)
+	}
+	d := &repackDoc{files: files, id: docID, content: make(map[string]interface{})}
+	if err := json.Unmarshal(data, &d.content); err != nil {
+		return nil, fmt.Errorf("cannot read the content of the document: %v", err)
+	}
+	for _, ext := range []string{"pdf", "epub"} {
+		if _, ok := files[docID+"."+ext]; ok {
+			d.payload = ext
+		}
+	}
+
+	entries := make(map[string]map[string]interface{})
+	if cPages, ok := d.content["cPages"].(map[string]interface{}); ok {
+		list, _ := cPages["pages"].([]interface{})
+		for _, p := range list {
+			if entry, ok := p.(map[string]interface{}); ok {
+				id, _ := entry["id"].(string)
+				entries[id] = entry
+			}
+		}
+	}
+	pagedata := strings.Split(strings.TrimRight(string(files[docID+".pagedata"]), "\n"), "\n")
+	for _, ref := range documentPages(d.content, pagedata, d.payload != "") {
+		d.pages = append(d.pages, pageEntry{ref, entries[ref.id]})
+	}
+	if len(d.pages) == 0 {
+		return nil, errors.New("the document lists no pages, open it on the tablet to update it")
+	}
+	for _, p := range d.pages {
+		if p.id == "" {
+			return nil, errors.New("the document has pages without id, open it on the tablet to update it")
+		}
+	}
+	return d, nil
+}
+
+// pageIndexes returns n strings sorting in order, the indexes of the
+// pages of "cPages".
+func pageIndexes(n int) []string {
+	width := 1
+	for size := 26; size < n; size *= 26 {
+		width++
+	}
+	indexes := make([]string, n)
+	for i := range indexes {
+		b := make([]byte, width)
+		for j, v := width-1, i; j >= 0; j, v = j-1, v/26 {
+			b[j] = byte('a' + v%26)
+		}
+		indexes[i] = "b" + string(b)
+	}
+	return indexes
+}
+
+// setPages writes the pages into the content and the pagedata of the
+// document, in the format the content uses, and removes the files of the
+// pages left out and the thumbnails named after the position of the
+// pages.
+func (d *repackDoc) setPages(pages []pageEntry) error {
+	kept := make(map[string]bool)
+	ids := make([]interface{}, len(pages))
+	for i, p := range pages {
+		kept[p.id] = true
+		ids[i] = p.id
+	}
+
+	if cPages, ok := d.content["cPages"].(map[string]interface{}); ok {
+		indexes := pageIndexes(len(pages))
+		list := make([]interface{}, len(pages))
+		for i, p := range pages {
+			entry := make(map[string]interface{})
+			for k, v := range p.entry {
+				entry[k] = v
+			}
+			entry["id"] = p.id
+			entry["idx"] = map[string]interface{}{"timestamp": "1:2", "value": indexes[i]}
+			if _, ok := entry["template"]; !ok && p.template != "" {
+				entry["template"] = map[string]interface{}{"timestamp": "1:1", "value": p.template}
+			}
+			list[i] = entry
+		}
+		cPages["pages"] = list
+		if last, ok := cPages["lastOpened"].(map[string]interface{}); ok {
+			if id, _ := last["value"].(string); !kept[id] {
+				last["value"] = pages[0].id
+			}
+		}
+	}
+	if _, ok := d.content["pages"]; ok || d.content["cPages"] == nil {
+		d.content["pages"] = ids
+		if d.payload != "" {
+			redirections := make([]int, len(pages))
+			for i, p := range pages {
+				redirections[i] = p.redir
+			}
+			d.content["redirectionPageMap"] = redirections
+		}
+	}
+	d.content["pageCount"] = len(pages)
+	if last, _ := d.content["lastOpenedPage"].(float64); int(last) >= len(pages) {
+		d.content["lastOpenedPage"] = 0
+	}
+	if tags, ok := d.content["pageTags"].([]interface{}); ok {
+		var keptTags []interface{}
+		for _, t := range tags {
+			if tag, ok := t.(map[string]interface{}); ok && kept[fmt.Sprint(tag["pageId"])] {
+				keptTags = append(keptTags, tag)
+			}
+		}
+		d.content["pageTags"] = append([]interface{}{}, keptTags...)
+	}
+
+	data, err := json.MarshalIndent(d.content, "", "    ")
+	if err != nil {
+		return err
+	}
+	d.files[d.id+".content"] = data
+
+	// the pagedata has one template per page
+	if _, ok := d.files[d.id+".pagedata"]; ok {
+		var b strings.Builder
+		for _, p := range pages {
+			template := p.template
+			if template == "" {
+				template = defaultPagadata
+			}
+			b.WriteString(template + "\n")
+		}
+		d.files[d.id+".pagedata"] = []byte(b.String())
+	}
+
+	for name := range d.files {
+		page, _, ok := pageFile(name, d.id)
+		if !ok {
+			page, _, ok = pageFile(name, d.id+".thumbnails")
+		}
+		if ok && !kept[page] {
+			delete(d.files, name)
+		}
+	}
+	d.pages = pages
+	return nil
+}
+
+// DeletePages removes pages of a document, given the raw files of its
+// archive, with their strokes and thumbnails. The page numbers start
+// at 1 and count the pages as shown on the tablet. The pages of a pdf
+// or an epub are hidden, the file itself is kept.
+func DeletePages(files map[string][]byte, docID string, pages []int) error {
+	d, err := readRepackDoc(files, docID)
+	if err != nil {
+		return err
+	}
+	removed := make(map[int]bool)
+	for _, page := range pages {
+		if page < 1 || page > len(d.pages) {
+			return fmt.Errorf("page %d doesn't exist, the document has %d pages", page, len(d.pages))
+		}
+		removed[page-1] = true
+	}
+	if len(removed) == len(d.pages) {
+		return errors.New("the document can't be left without pages")
+	}
+	var kept []pageEntry
+	for i, p := range d.pages {
+		if !removed[i] {
+			kept = append(kept, p)
+		}
+	}
+	return d.setPages(kept)
+}
+
+// ReorderPages moves pages of a document, given the raw files of its
+// archive. The pages listed in order come first, followed by the other
+// pages in their order. The page numbers start at 1 and count the pages
+// as shown on the tablet.
+func ReorderPages(files map[string][]byte, docID string, order []int) error {
+	d, err := readRepackDoc(files, docID)
+	if err != nil {
+		return err
+	}
+	moved := make(map[int]bool)
+	var pages []pageEntry
+	for _, page := range order {
+		if page < 1 || page > len(d.pages) {
+			return fmt.Errorf("page %d doesn't exist, the document has %d pages", page, len(d.pages))
+		}
+		if moved[page-1] {
+			return fmt.Errorf("page %d is listed twice", page)
+		}
+		moved[page-1] = true
+		pages = append(pages, d.pages[page-1])
+	}
+	for i, p := range d.pages {
+		if !moved[i] {
+			pages = append(pages, p)
+		}
+	}
+	return d.setPages(pages)
+}
+
+// MergeNotebooks creates a new document with the pages of a notebook
+// followed by the pages of another one, given the raw files of their
+// archives. The settings of the first notebook are kept. It returns the
+// files of the new document and its ID.
+func MergeNotebooks(first map[string][]byte, firstID string, second map[string][]byte, secondID string) (map[string][]byte, string, error) {
+	a, err := readRepackDoc(first, firstID)
+	if err != nil {
+		return nil, "", err
+	}
+	b, err := readRepackDoc(second, secondID)
+	if err != nil {
+		return nil, "", err
+	}
+	if a.payload != "" || b.payload != "" {
+		return nil, "", errors.New("only notebooks can be merged, not pdf or epub documents")
+	}
+
+	newID := uuid.New().String()
+	out := make(map[string][]byte)
+	copyPages := func(d *repackDoc, ids map[string]string) {
+		for name, data := range d.files {
+			for _, dir := range []string{d.id, d.id + ".thumbnails"} {
+				if page, kind, ok := pageFile(name, dir); ok && ids[page] != "" {
+					out[path.Join(newID+strings.TrimPrefix(dir, d.id), ids[page]+kind)] = data
+				}
+			}
+		}
+	}
+
+	pages := append([]pageEntry{}, a.pages...)
+	firstIDs := make(map[string]string)
+	for _, p := range a.pages {
+		firstIDs[p.id] = p.id
+	}
+	// the pages of a notebook merged with itself get new ids
+	secondIDs := make(map[string]string)
+	for _, p := range b.pages {
+		id := p.id
+		if firstIDs[id] != "" {
+			id = uuid.New().String()
+		}
+		secondIDs[p.id] = id
+		p.pageRef.id = id
+		pages = append(pages, p)
+	}
+	copyPages(a, firstIDs)
+	copyPages(b, secondIDs)
+
+	if tags, ok := b.content["pageTags"].([]interface{}); ok {
+		firstTags, _ := a.content["pageTags"].([]interface{})
+		for _, t := range tags {
+			if tag, ok := t.(map[string]interface{}); ok {
+				copied := make(map[string]interface{})
+				for k, v := range tag {
+					copied[k] = v
+				}
+				copied["pageId"] = secondIDs[fmt.Sprint(tag["pageId"])]
+				firstTags = append(firstTags, copied)
+			}
+		}
+		a.content["pageTags"] = firstTags
+	}
+	if _, ok := a.content["cPages"]; !ok {
+		if _, ok := b.content["cPages"]; ok {
+			return nil, "", errors.New("the first notebook was made by an older firmware, open it on the tablet to update it")
+		}
+	}
+
+	// the content and the pagedata of the first notebook, under the new id
+	merged := &repackDoc{files: out, id: newID, content: a.content}
+	if data, ok := a.files[a.id+".pagedata"]; ok {
+		out[newID+".pagedata"] = data
+	} else if _, ok := b.files[b.id+".pagedata"]; ok {
+		out[newID+".pagedata"] = nil
+	}
+	if err := merged.setPages(pages); err != nil {
+		return nil, "", err
+	}
+	return out, newID, nil
+}
@@ -0,0 +1,160 @@
+package archive
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testPageIDs returns the ids of the pages of a document in order.
+func testPageIDs(t *testing.T, files map[string][]byte, docID string) []string {
+	d, err := readRepackDoc(files, docID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, p := range d.pages {
+		ids = append(ids, p.id)
+	}
+	return ids
+}
+
+func TestDeletePages(t *testing.T) {
+	files := map[string][]byte{
+		"doc.content":           []byte(`{"fileType":"notebook","pageCount":3,"pages":["p1","p2","p3"],"lastOpenedPage":2}`),
+		"doc.pagedata":          []byte("Blank\nP Lines small\nP Grid medium\n"),
+		"doc/p2.rm":             []byte("strokes"),
+		"doc/p2-metadata.json":  []byte("{}"),
+		"doc/p3.rm":             []byte("strokes"),
+		"doc.thumbnails/p2.jpg": []byte("jpg"),
+	}
+	if err := DeletePages(files, "doc", []int{2}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := testPageIDs(t, files, "doc"); !reflect.DeepEqual(ids, []string{"p1", "p3"}) {
+		t.Errorf("expected the pages p1 and p3, got %v", ids)
+	}
+	if got := string(files["doc.pagedata"]); got != "Blank\nP Grid medium\n" {
+		t.Errorf("expected the templates of the pages kept, got %q", got)
+	}
+	for _, name := range []string{"doc/p2.rm", "doc/p2-metadata.json", "doc.thumbnails/p2.jpg"} {
+		if _, ok := files[name]; ok {
+			t.Errorf("expected %s to be removed", name)
+		}
+	}
+	if _, ok := files["doc/p3.rm"]; !ok {
+		t.Error("expected the strokes of p3 kept")
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(files["doc.content"], &content); err != nil {
+		t.Fatal(err)
+	}
+	if content["pageCount"] != float64(2) || content["lastOpenedPage"] != float64(0) {
+		t.Errorf("expected the page count and the last opened page updated, got %v", content)
+	}
+
+	if err := DeletePages(files, "doc", []int{1, 2}); err == nil {
+		t.Error("expected an error when removing every page")
+	}
+	if err := DeletePages(files, "doc", []int{3}); err == nil {
+		t.Error("expected an error for a missing page")
+	}
+}
+
+func TestReorderPages(t *testing.T) {
+	files := map[string][]byte{
+		"doc.content": []byte(`{"fileType":"notebook","cPages":{"lastOpened":{"timestamp":"1:1","value":"p1"},"pages":[` +
+			`{"id":"p1","idx":{"timestamp":"1:2","value":"ba"},"template":{"timestamp":"1:1","value":"Blank"}},` +
+			`{"id":"p2","idx":{"timestamp":"1:2","value":"bb"}},` +
+			`{"id":"p3","idx":{"timestamp":"1:2","value":"bc"},"scrollTime":{"timestamp":"1:1","value":"5"}}]}}`),
+		"doc.thumbnails/0.jpg": []byte("jpg"),
+	}
+	if err := ReorderPages(files, "doc", []int{3, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := testPageIDs(t, files, "doc"); !reflect.DeepEqual(ids, []string{"p3", "p1", "p2"}) {
+		t.Errorf("expected the pages p3, p1 and p2, got %v", ids)
+	}
+	if !strings.Contains(string(files["doc.content"]), `"scrollTime"`) {
+		t.Error("expected the other fields of the pages kept")
+	}
+	if _, ok := files["doc.thumbnails/0.jpg"]; ok {
+		t.Error("expected the thumbnails named after their position removed")
+	}
+
+	if err := ReorderPages(files, "doc", []int{2, 2}); err == nil {
+		t.Error("expected an error for a page listed twice")
+	}
+}
+
+func TestReorderPdfPages(t *testing.T) {
+	files := map[string][]byte{
+		"doc.content": []byte(`{"fileType":"pdf","pages":["p1","p2"]}`),
+		"doc.pdf":     []byte("%PDF"),
+	}
+	if err := ReorderPages(files, "doc", []int{2}); err != nil {
+		t.Fatal(err)
+	}
+	var content struct {
+		Redirections []int `json:"redirectionPageMap"`
+	}
+	if err := json.Unmarshal(files["doc.content"], &content); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(content.Redirections, []int{1, 0}) {
+		t.Errorf("expected the pages of the pdf swapped, got %v", content.Redirections)
+	}
+}
+
+func TestMergeNotebooks(t *testing.T) {
+	first := map[string][]byte{
+		"a.content":  []byte(`{"fileType":"notebook","pages":["p1","p2"]}`),
+		"a.pagedata": []byte("Blank\nP Lines small\n"),
+		"a/p1.rm":    []byte("a1"),
+		"a.metadata": []byte(`{"visibleName":"A"}`),
+	}
+	second := map[string][]byte{
+		"b.content":           []byte(`{"fileType":"notebook","pages":["p3"],"pageTags":[{"name":"todo","pageId":"p3","timestamp":1}]}`),
+		"b.pagedata":          []byte("P Grid medium\n"),
+		"b/p3.rm":             []byte("b3"),
+		"b.thumbnails/p3.jpg": []byte("jpg"),
+		"b/p3-metadata.json":  []byte("{}"),
+	}
+	out, id, err := MergeNotebooks(first, "a", second, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := testPageIDs(t, out, id); !reflect.DeepEqual(ids, []string{"p1", "p2", "p3"}) {
+		t.Errorf("expected the pages of both notebooks, got %v", ids)
+	}
+	if got := string(out[id+".pagedata"]); got != "Blank\nP Lines small\nP Grid medium\n" {
+		t.Errorf("expected the templates of both notebooks, got %q", got)
+	}
+	for name, want := range map[string]string{
+		id + "/p1.rm":             "a1",
+		id + "/p3.rm":             "b3",
+		id + ".thumbnails/p3.jpg": "jpg",
+	} {
+		if string(out[name]) != want {
+			t.Errorf("expected %s to be copied, got %q", name, out[name])
+		}
+	}
+	if !strings.Contains(string(out[id+".content"]), `"todo"`) {
+		t.Error("expected the tags of the pages of the second notebook")
+	}
+
+	// merged with itself, the pages of the copy get new ids
+	out, id, err = MergeNotebooks(first, "a", first, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := testPageIDs(t, out, id); len(ids) != 4 || ids[2] == "p1" || out[id+"/"+ids[2]+".rm"] == nil {
+		t.Errorf("expected the copied pages under new ids, got %v", ids)
+	}
+
+	pdfDoc := map[string][]byte{"c.content": []byte(`{"fileType":"pdf","pages":["p1"]}`), "c.pdf": []byte("%PDF")}
+	if _, _, err := MergeNotebooks(first, "a", pdfDoc, "c"); err == nil {
+		t.Error("expected an error for a pdf")
+	}
+}
@@ -0,0 +1,122 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joagonca/rmapi/model"
+)
+
+// WriteRmdoc writes the archive src as an .rmdoc file, the format of
+// the documents exported over USB, which the device and the desktop
+// apps import. The files of the document are copied unchanged. Its
+// .metadata, which the downloaded archives may lack, is written with
+// name, modified and no parent, so that the document lands at the root
+// of the tablet importing it.
+func WriteRmdoc(src, dst, name string, modified time.Time) error {
+	r, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	id, metadata, meta, err := documentMetadata(&r.Reader)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		meta.DocName = name
+	}
+	if meta.DocName == "" {
+		meta.DocName = id
+	}
+	meta.Parent = ""
+	meta.Deleted = false
+	if !modified.IsZero() {
+		meta.LastModified = strconv.FormatInt(modified.UnixMilli(), 10)
+	} else if meta.LastModified == "" {
+		meta.LastModified = UnixTimestamp()
+	}
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || f == metadata {
+			continue
+		}
+		// the compressed data is copied as it is, under the name the
+		// device uses, without the empty parts of some downloads, such
+		// as id//0.rm
+		header := f.FileHeader
+		header.Name = path.Clean(f.Name)
+		raw, err := f.OpenRaw()
+		if err != nil {
+			return err
+		}
+		w, err := zw.CreateRaw(&header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, raw); err != nil {
+			return err
+		}
+	}
+	w, err := addToZip(zw, id+".metadata")
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(metaData); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// documentMetadata returns the id of the document of an archive, its
+// .metadata file and what it holds, the metadata of a document without
+// name when the archive has none.
+func documentMetadata(r *zip.Reader) (id string, metadata *zip.File, meta MetadataFile, err error) {
+	for _, f := range r.File {
+		switch path.Ext(f.Name) {
+		case ".content":
+			id = strings.TrimSuffix(f.Name, ".content")
+		case ".metadata":
+			metadata = f
+		}
+	}
+	if id == "" {
+		return "", nil, meta, errors.New("the archive has no .content")
+	}
+
+	meta = MetadataFile{CollectionType: model.DocumentType}
+	if metadata != nil {
+		rc, err := metadata.Open()
+		if err != nil {
+			return "", nil, meta, err
+		}
+		err = json.NewDecoder(rc).Decode(&meta)
+		rc.Close()
+		if err != nil {
+			return "", nil, meta, err
+		}
+	}
+	return id, metadata, meta, nil
+}
@@ -0,0 +1,102 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteRmdoc(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "doc.rmdoc")
+	modified := time.UnixMilli(1700000000000)
+	if err := WriteRmdoc("test.zip", out, "Notes", modified); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	const id = "384327f5-133e-49c8-82ff-30aa19f3cfa4"
+	names := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		names[f.Name] = f
+	}
+	for _, name := range []string{id + ".content", id + "/0.rm", id + "/0-metadata.json", id + ".metadata"} {
+		if names[name] == nil {
+			t.Errorf("missing %s in %v", name, zr.File)
+		}
+	}
+
+	rc, err := names[id+".metadata"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta MetadataFile
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.DocName != "Notes" || meta.Parent != "" || meta.CollectionType != "DocumentType" || meta.LastModified != "1700000000000" {
+		t.Errorf("unexpected metadata %s", data)
+	}
+
+	// the drawings are still read
+	file, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := NewZip()
+	if err := z.Read(file, info.Size()); err != nil {
+		t.Fatal(err)
+	}
+	if len(z.Pages) != 1 || z.Pages[0].Data == nil {
+		t.Errorf("unexpected pages %v", z.Pages)
+	}
+}
+
+func TestPrepareRmdoc(t *testing.T) {
+	dir := t.TempDir()
+	rmdoc := filepath.Join(dir, "Notes.rmdoc")
+	if err := WriteRmdoc("test.zip", rmdoc, "Notes", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, id, err := Prepare("Copy", "parent", rmdoc, "rmdoc", filepath.Join(dir, "upload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "384327f5-133e-49c8-82ff-30aa19f3cfa4" {
+		t.Errorf("expected the id of the document, got %q", id)
+	}
+	var meta MetadataFile
+	for _, f := range files.Files {
+		if f.Name != id+".metadata" {
+			continue
+		}
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if meta.DocName != "Copy" || meta.Parent != "parent" {
+		t.Errorf("expected the metadata of the upload, got %+v", meta)
+	}
+}
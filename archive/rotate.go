@@ -0,0 +1,125 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/strokes"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// RotatePages rotates pages of a pdf document by a multiple of 90
+// degrees clockwise, given the raw files of its archive. The page
+// numbers start at 1 and count the pages as shown on the tablet. The
+// strokes drawn over the rotated pages are turned with them and the
+// thumbnails are removed so that the tablet draws them again.
+func RotatePages(files map[string][]byte, docID string, pages []int, angle int) error {
+	if angle%90 != 0 {
+		return fmt.Errorf("invalid angle %d, only multiples of 90 are supported", angle)
+	}
+
+	data, ok := files[docID+".content"]
+	if !ok {
+		return fmt.Errorf("%s.content not found in the archive", docID)
+	}
+	content := make(map[string]interface{})
+	if err := json.Unmarshal(data, &content); err != nil {
+		return fmt.Errorf("cannot read the content of the document: %v", err)
+	}
+	background, ok := files[docID+".pdf"]
+	if !ok {
+		return errors.New("only the pages of pdf documents can be rotated")
+	}
+
+	pagedata := strings.Split(strings.TrimRight(string(files[docID+".pagedata"]), "\n"), "\n")
+	refs := documentPages(content, pagedata, true)
+
+	// pages of the pdf to rotate
+	selected := make(map[int]bool)
+	for _, page := range pages {
+		if page < 1 || page > len(refs) {
+			return fmt.Errorf("page %d doesn't exist, the document has %d pages", page, len(refs))
+		}
+		if refs[page-1].redir < 0 {
+			return fmt.Errorf("page %d has no pdf background", page)
+		}
+		selected[refs[page-1].redir] = true
+	}
+
+	// the pages are rotated by pdfcpu, which keeps the encryption of
+	// the pdfs only restricting their printing or their editing
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.ROTATE
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(background), conf)
+	if err != nil {
+		return fmt.Errorf("cannot read the pdf: %v", err)
+	}
+	boundaries, err := ctx.PageBoundaries(nil)
+	if err != nil {
+		return err
+	}
+
+	type size struct{ width, height float64 }
+	sizes := make(map[int]size)
+	rotated := make(types.IntSet)
+	for redir := range selected {
+		if redir >= len(boundaries) {
+			return fmt.Errorf("the pdf has no page %d", redir+1)
+		}
+		box := boundaries[redir].CropBox()
+		w, h := box.Width(), box.Height()
+		if boundaries[redir].Rot%180 != 0 {
+			w, h = h, w
+		}
+		sizes[redir] = size{w, h}
+		rotated[redir+1] = true
+	}
+	if err := pdfcpu.RotatePages(ctx, rotated, (angle%360+360)%360); err != nil {
+		return err
+	}
+
+	// every page showing a rotated pdf page is updated, the files are
+	// only replaced once all of them could be read
+	updated := make(map[string][]byte)
+	var removed []string
+	for _, ref := range refs {
+		s, ok := sizes[ref.redir]
+		if !ok {
+			continue
+		}
+		name := docID + "/" + ref.id + ".rm"
+		if data, ok := files[name]; ok {
+			page := rm.New()
+			if err := page.UnmarshalBinary(data); err != nil {
+				return fmt.Errorf("cannot read the strokes of page %s: %v", ref.id, err)
+			}
+			strokes.RotatePage(page, angle, s.width, s.height)
+			if updated[name], err = page.MarshalBinary(); err != nil {
+				return err
+			}
+		}
+		for _, ext := range []string{".png", ".jpg"} {
+			removed = append(removed, docID+".thumbnails/"+ref.id+ext)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := api.Write(ctx, &b, conf); err != nil {
+		return err
+	}
+	files[docID+".pdf"] = b.Bytes()
+	for name, data := range updated {
+		files[name] = data
+	}
+	for _, name := range removed {
+		delete(files, name)
+	}
+	return nil
+}
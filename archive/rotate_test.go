@@ -0,0 +1,127 @@
+package archive
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/pdf"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+func TestRotatePages(t *testing.T) {
+	doc := pdf.NewDocument()
+	doc.AddPage(pdf.RemarkableWidth, pdf.RemarkableHeight)
+	doc.AddPage(pdf.RemarkableWidth, pdf.RemarkableHeight)
+	var b bytes.Buffer
+	if err := doc.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	page := rm.New()
+	page.Layers = []rm.Layer{{Lines: []rm.Line{{
+		BrushType: rm.FinelinerV5,
+		BrushSize: rm.Medium,
+		Points:    []rm.Point{{X: 100, Y: 200, Width: 2}},
+	}}}}
+	strokes, err := page.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{
+		"doc.content":           []byte(`{"fileType":"pdf","pageCount":2,"pages":["p1","p2"],"redirectionPageMap":[0,1]}`),
+		"doc.pdf":               b.Bytes(),
+		"doc/p1.rm":             strokes,
+		"doc.thumbnails/p1.png": []byte("png"),
+		"doc.thumbnails/p2.png": []byte("png"),
+	}
+
+	if err := RotatePages(files, "doc", []int{1}, 90); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := pdf.Open(files["doc.pdf"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := f.PageRotation(pages[0]); r != 90 {
+		t.Errorf("expected the first page rotated by 90, got %d", r)
+	}
+	if r := f.PageRotation(pages[1]); r != 0 {
+		t.Errorf("the second page should not be rotated, got %d", r)
+	}
+
+	if _, ok := files["doc.thumbnails/p1.png"]; ok {
+		t.Error("the thumbnail of the rotated page should be removed")
+	}
+	if _, ok := files["doc.thumbnails/p2.png"]; !ok {
+		t.Error("the thumbnail of the other page should be kept")
+	}
+
+	rotated := rm.New()
+	if err := rotated.UnmarshalBinary(files["doc/p1.rm"]); err != nil {
+		t.Fatal(err)
+	}
+	// the landscape page now fits the width of the screen
+	scale := float64(pdf.RemarkableHeight) / float64(rm.Width)
+	before := float64(pdf.RemarkableHeight) / float64(rm.Height)
+	wantX := (pdf.RemarkableHeight - 200*before) / scale
+	wantY := 100 * before / scale
+	p := rotated.Layers[0].Lines[0].Points[0]
+	if math.Abs(float64(p.X)-wantX) > 0.01 || math.Abs(float64(p.Y)-wantY) > 0.01 {
+		t.Errorf("expected point at (%.2f, %.2f), got (%.2f, %.2f)", wantX, wantY, p.X, p.Y)
+	}
+
+	if err := RotatePages(files, "doc", []int{3}, 90); err == nil {
+		t.Error("expected an error for a missing page")
+	}
+	if err := RotatePages(files, "doc", []int{1}, 45); err == nil {
+		t.Error("expected an error for an invalid angle")
+	}
+}
+
+func TestRotateEncryptedPages(t *testing.T) {
+	doc := pdf.NewDocument()
+	doc.AddPage(pdf.RemarkableWidth, pdf.RemarkableHeight)
+	var b bytes.Buffer
+	if err := doc.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	// only the printing and the editing are restricted
+	conf := model.NewAESConfiguration("", "owner", 256)
+	conf.Permissions = model.PermissionsNone
+	var encrypted bytes.Buffer
+	if err := api.Encrypt(bytes.NewReader(b.Bytes()), &encrypted, conf); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{
+		"doc.content": []byte(`{"fileType":"pdf","pageCount":1,"pages":["p1"],"redirectionPageMap":[0]}`),
+		"doc.pdf":     encrypted.Bytes(),
+	}
+	if err := RotatePages(files, "doc", []int{1}, -90); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(files["doc.pdf"], []byte("/Encrypt")) {
+		t.Error("the encryption of the pdf should be kept")
+	}
+
+	f, err := pdf.Open(files["doc.pdf"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := f.PageRotation(pages[0]); r != 270 {
+		t.Errorf("expected the page rotated by 270, got %d", r)
+	}
+}
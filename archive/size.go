@@ -0,0 +1,62 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"math"
+)
+
+// maxReadSize is the size of the largest file of an archive which can
+// be read in memory, limited by the size of the slices on the 32-bit
+// platforms, such as the tablet. The archives larger than 4 GB use the
+// zip64 format, which archive/zip reads and writes, their pdfs and
+// epubs are only copied to disk.
+var maxReadSize uint64 = math.MaxInt32
+
+func init() {
+	if math.MaxInt > math.MaxInt32 {
+		maxReadSize = math.MaxInt64
+	}
+}
+
+// checkSize returns an error for the files of an archive too large to
+// be read in memory.
+func checkSize(f *zip.File) error {
+	if f.UncompressedSize64 > maxReadSize {
+		return fmt.Errorf("%s is too large to be read: %d bytes, at most %d", f.Name, f.UncompressedSize64, maxReadSize)
+	}
+	return nil
+}
+
+// readZipFile reads a file of an archive, checking its size first.
+func readZipFile(f *zip.File) ([]byte, error) {
+	if err := checkSize(f); err != nil {
+		return nil, err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// copyZipFile copies a file of an archive to w, checking that it has
+// the size the archive gives, so that truncated archives aren't
+// uploaded.
+func copyZipFile(w io.Writer, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	n, err := io.Copy(w, rc)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %v", f.Name, err)
+	}
+	if uint64(n) != f.UncompressedSize64 {
+		return fmt.Errorf("%s is truncated: %d bytes of %d", f.Name, n, f.UncompressedSize64)
+	}
+	return nil
+}
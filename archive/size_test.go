@@ -0,0 +1,43 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestZip64RoundTrip(t *testing.T) {
+	// more files than the classic format can count are written with
+	// the zip64 records
+	files := make(map[string][]byte)
+	for i := 0; i < 70000; i++ {
+		files[fmt.Sprintf("doc/%05d.rm", i)] = []byte{byte(i)}
+	}
+	path := filepath.Join(t.TempDir(), "large.zip")
+	if err := WriteRawFiles(path, files); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadRawFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(files) || string(read["doc/69999.rm"]) != string(files["doc/69999.rm"]) {
+		t.Errorf("expected %d files, got %d", len(files), len(read))
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	defer func(size uint64) { maxReadSize = size }(maxReadSize)
+	maxReadSize = 4
+
+	f := &zip.File{FileHeader: zip.FileHeader{Name: "doc.pdf", UncompressedSize64: 5}}
+	if err := checkSize(f); err == nil || !strings.Contains(err.Error(), "doc.pdf is too large") {
+		t.Errorf("expected an error for a large file, got %v", err)
+	}
+	f.UncompressedSize64 = 4
+	if err := checkSize(f); err != nil {
+		t.Error(err)
+	}
+}
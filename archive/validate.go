@@ -0,0 +1,312 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/model"
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// A Problem is an inconsistency found in the files of an archive.
+type Problem struct {
+	// File is the file of the archive concerned, empty for the archive
+	// as a whole
+	File    string `json:"file,omitempty"`
+	Message string `json:"message"`
+	// Warning is set for the problems the device copes with, such as
+	// a missing thumbnail, the other ones may keep the document from
+	// opening
+	Warning bool `json:"warning,omitempty"`
+}
+
+func (p Problem) String() string {
+	level := "error"
+	if p.Warning {
+		level = "warning"
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%s: %s", level, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", level, p.File, p.Message)
+}
+
+// Validate checks that an archive, a zip or an .rmdoc, holds a
+// consistent document: a .content file which can be read, the pages it
+// lists with their strokes, templates and thumbnails, and the pdf or the
+// epub shown. It returns the problems found, none for a sound document,
+// and an error when the archive can't be read.
+func Validate(path string) ([]Problem, error) {
+	files, err := ReadRawFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	return validateFiles(files), nil
+}
+
+// validator collects the problems of the files of an archive.
+type validator struct {
+	files    map[string][]byte
+	problems []Problem
+}
+
+func (v *validator) error(file, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{File: file, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warning(file, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{File: file, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+func validateFiles(files map[string][]byte) []Problem {
+	v := &validator{files: files}
+
+	var contents []string
+	for name := range files {
+		if !strings.Contains(name, "/") && strings.HasSuffix(name, ".content") {
+			contents = append(contents, name)
+		}
+	}
+	sort.Strings(contents)
+	switch len(contents) {
+	case 0:
+		v.error("", "no .content file")
+		return v.problems
+	case 1:
+	default:
+		v.error("", "several .content files: %s", strings.Join(contents, ", "))
+		return v.problems
+	}
+	docID := strings.TrimSuffix(contents[0], ".content")
+
+	content := make(map[string]interface{})
+	if err := json.Unmarshal(files[contents[0]], &content); err != nil {
+		v.error(contents[0], "cannot be read: %v", err)
+		return v.problems
+	}
+	fileType := v.checkContent(contents[0], content)
+	v.checkMetadata(docID)
+
+	payload := ""
+	for _, ext := range []string{"pdf", "epub"} {
+		if _, ok := files[docID+"."+ext]; ok {
+			payload = ext
+		}
+	}
+	if _, ok := files[docID+"."+fileType]; !ok && (fileType == "pdf" || fileType == "epub") {
+		v.error("", "the %s of the document is missing", fileType)
+	}
+
+	pagedata := strings.Split(strings.TrimRight(string(files[docID+".pagedata"]), "\n"), "\n")
+	if len(pagedata) == 1 && pagedata[0] == "" {
+		pagedata = nil
+	}
+	pages := documentPages(content, pagedata, payload != "")
+	if _, ok := content["cPages"]; !ok && pagedata != nil && len(pagedata) != pageCount(content, pages) {
+		v.warning(docID+".pagedata", "%d templates for %d pages", len(pagedata), pageCount(content, pages))
+	}
+	if payload == "pdf" {
+		v.checkRedirections(docID+".pdf", pages)
+	}
+	v.checkPages(docID, content, pages)
+	v.checkThumbnails(docID, content, pages)
+
+	// the files which don't belong to the document
+	for name := range files {
+		if !strings.HasPrefix(name, docID+"/") && !strings.HasPrefix(name, docID+".") {
+			v.warning(name, "doesn't belong to the document %s", docID)
+		}
+	}
+
+	sort.SliceStable(v.problems, func(i, j int) bool { return v.problems[i].File < v.problems[j].File })
+	return v.problems
+}
+
+// checkContent checks the fields of the .content file and returns the
+// type of the document.
+func (v *validator) checkContent(name string, content map[string]interface{}) string {
+	fileType, ok := content["fileType"].(string)
+	if _, found := content["fileType"]; found && !ok {
+		v.error(name, "fileType isn't a string")
+	}
+	switch fileType {
+	case "", "notebook", "pdf", "epub":
+	default:
+		v.error(name, "unknown fileType %q", fileType)
+	}
+
+	if pages, found := content["pages"]; found && pages != nil {
+		list, ok := pages.([]interface{})
+		if !ok {
+			v.error(name, "pages isn't a list")
+		}
+		for _, p := range list {
+			if _, ok := p.(string); !ok {
+				v.error(name, "the page %v isn't an id", p)
+			}
+		}
+		if count, ok := content["pageCount"].(float64); ok && count != 0 && len(list) > 0 && int(count) != len(list) {
+			v.warning(name, "pageCount is %d for %d pages", int(count), len(list))
+		}
+	}
+	if cPages, found := content["cPages"]; found {
+		c, ok := cPages.(map[string]interface{})
+		list, _ := c["pages"].([]interface{})
+		if !ok || (c["pages"] != nil && list == nil) {
+			v.error(name, "cPages isn't a list of pages")
+		}
+		for _, p := range list {
+			entry, _ := p.(map[string]interface{})
+			if id, _ := entry["id"].(string); id == "" {
+				v.error(name, "a page of cPages has no id")
+			}
+		}
+	}
+	if redirections, found := content["redirectionPageMap"]; found && redirections != nil {
+		if _, ok := redirections.([]interface{}); !ok {
+			v.error(name, "redirectionPageMap isn't a list")
+		}
+	}
+	return fileType
+}
+
+// checkMetadata checks the .metadata file, which the zips of the
+// documents may leave out.
+func (v *validator) checkMetadata(docID string) {
+	name := docID + ".metadata"
+	data, ok := v.files[name]
+	if !ok {
+		return
+	}
+	var meta MetadataFile
+	if err := json.Unmarshal(data, &meta); err != nil {
+		v.error(name, "cannot be read: %v", err)
+		return
+	}
+	if meta.CollectionType != "" && meta.CollectionType != model.DocumentType {
+		v.error(name, "the type %q isn't a document", meta.CollectionType)
+	}
+	if meta.DocName == "" {
+		v.warning(name, "the document has no name")
+	}
+}
+
+// checkRedirections checks that the pages show pages of the pdf.
+func (v *validator) checkRedirections(name string, pages []pageRef) {
+	f, err := pdf.Open(v.files[name])
+	if err != nil {
+		v.error(name, "cannot be read: %v", err)
+		return
+	}
+	pdfPages, err := f.Pages()
+	if err != nil {
+		v.error(name, "cannot be read: %v", err)
+		return
+	}
+	for i, p := range pages {
+		if p.redir >= len(pdfPages) {
+			v.error("", "page %d shows the page %d of a pdf of %d pages", i+1, p.redir+1, len(pdfPages))
+		}
+	}
+}
+
+// pageCount returns the number of pages of a document, given by
+// pageCount alone on the older firmwares, which don't list them.
+func pageCount(content map[string]interface{}, pages []pageRef) int {
+	if n, ok := content["pageCount"].(float64); ok && len(pages) == 0 {
+		return int(n)
+	}
+	return len(pages)
+}
+
+// pageNames returns the page numbers of a document by the names of
+// their files: their id, or their index on the older firmwares.
+func pageNames(content map[string]interface{}, pages []pageRef) map[string]int {
+	names := make(map[string]int)
+	for i := 0; i < pageCount(content, pages); i++ {
+		names[fmt.Sprint(i)] = i + 1
+	}
+	for i, p := range pages {
+		if p.id != "" {
+			names[p.id] = i + 1
+		}
+	}
+	return names
+}
+
+// pageFile splits the name of a file of the directory of the pages, or
+// of the thumbnails, into the name of the page and the kind of file,
+// such as ".rm". It returns false for the other files.
+func pageFile(name, dir string) (string, string, bool) {
+	if !strings.HasPrefix(name, dir+"/") {
+		return "", "", false
+	}
+	file := strings.TrimLeft(strings.TrimPrefix(name, dir), "/")
+	if strings.Contains(file, "/") {
+		return "", "", false
+	}
+	if page := strings.TrimSuffix(file, "-metadata.json"); page != file {
+		return page, "-metadata.json", true
+	}
+	ext := path.Ext(file)
+	return strings.TrimSuffix(file, ext), ext, true
+}
+
+// checkPages checks the ids of the pages and their strokes: every .rm
+// file is read and belongs to a page. The pages without strokes have no
+// .rm file.
+func (v *validator) checkPages(docID string, content map[string]interface{}, pages []pageRef) {
+	seen := make(map[string]bool)
+	for _, p := range pages {
+		if p.id == "" {
+			continue
+		}
+		if seen[p.id] {
+			v.error(docID+".content", "the page %s is listed twice", p.id)
+		}
+		seen[p.id] = true
+	}
+
+	names := pageNames(content, pages)
+	for name, data := range v.files {
+		page, kind, ok := pageFile(name, docID)
+		if !ok {
+			continue
+		}
+		number, found := names[page]
+		if !found {
+			v.warning(name, "belongs to no page of the document")
+			continue
+		}
+		if kind == ".rm" {
+			var strokes rm.Rm
+			if _, err := strokes.UnmarshalMode(data, rm.Strict); err != nil {
+				v.error(name, "the strokes of page %d cannot be read: %v", number, err)
+			}
+		}
+	}
+}
+
+// checkThumbnails checks that the thumbnails are images of pages of the
+// document.
+func (v *validator) checkThumbnails(docID string, content map[string]interface{}, pages []pageRef) {
+	names := pageNames(content, pages)
+	for name, data := range v.files {
+		page, _, ok := pageFile(name, docID+".thumbnails")
+		if !ok {
+			continue
+		}
+		if _, found := names[page]; !found {
+			v.warning(name, "belongs to no page of the document")
+		}
+		if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+			v.warning(name, "isn't an image: %v", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package archive
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	problems, err := Validate("test.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("expected a sound document, got %v", problems)
+	}
+
+	zipPath, err := CreateZipDocument("1234", "zipdoc_test.pdf", DocumentOptions{Tags: []string{"work"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(zipPath)
+	if problems, err = Validate(zipPath); err != nil || len(problems) != 0 {
+		t.Errorf("expected a sound document, got %v (%v)", problems, err)
+	}
+}
+
+func TestValidateProblems(t *testing.T) {
+	const (
+		doc   = "doc"
+		page1 = "2f3c1a3e-5c4e-4a57-9b1b-0d5b8f0c8e11"
+		page2 = "7a1d2e4b-8f2c-4a6e-a1c3-3b9e5d7f1a22"
+		other = "c9b8a7d6-e5f4-4a3b-8c2d-1e0f9a8b7c33"
+	)
+	files := map[string][]byte{
+		doc + ".content": []byte(`{"fileType":"pdf","pageCount":3,"pages":["` +
+			page1 + `","` + page2 + `","` + page1 + `"]}`),
+		doc + ".pagedata":                     []byte("Blank\n"),
+		doc + "/" + page2 + ".rm":             []byte("not strokes"),
+		doc + "/" + other + ".rm":             []byte("not strokes"),
+		doc + ".thumbnails/" + page1 + ".jpg": []byte("not an image"),
+		"notes.txt":                           []byte("left over"),
+	}
+
+	want := []Problem{
+		{"", "the pdf of the document is missing", false},
+		{"doc.content", "the page " + page1 + " is listed twice", false},
+		{"doc.pagedata", "1 templates for 3 pages", true},
+		{"doc.thumbnails/" + page1 + ".jpg", "isn't an image", true},
+		{"doc/" + page2 + ".rm", "the strokes of page 2 cannot be read", false},
+		{"doc/" + other + ".rm", "belongs to no page of the document", true},
+		{"notes.txt", "doesn't belong to the document doc", true},
+	}
+	problems := validateFiles(files)
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for i, p := range problems {
+		if p.File != want[i].File || !strings.HasPrefix(p.Message, want[i].Message) || p.Warning != want[i].Warning {
+			t.Errorf("expected %v, got %v", want[i], p)
+		}
+	}
+
+	if problems := validateFiles(map[string][]byte{"a.pdf": nil}); len(problems) != 1 || problems[0].Message != "no .content file" {
+		t.Errorf("expected a missing content, got %v", problems)
+	}
+}
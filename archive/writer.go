@@ -3,10 +3,13 @@ package archive
 import (
 	"archive/zip"
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -47,11 +50,79 @@ func (z *Zip) Write(w io.Writer) error {
 		return err
 	}
 
+	if err := z.writeHighlights(archive); err != nil {
+		return err
+	}
+
 	archive.Close()
 
 	return nil
 }
 
+// deterministicTime is the modification time of the files of the
+// deterministic archives, the earliest date of the zip format.
+var deterministicTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteDeterministic writes an archive file like Write, the same bytes
+// for the same Zip: the files are sorted by name with a fixed
+// modification time, so that backups can be deduplicated by their
+// hash. The UUID must be set, a random one would change the archive.
+func (z *Zip) WriteDeterministic(w io.Writer) error {
+	if z.UUID == "" {
+		return errors.New("the UUID of a deterministic archive must be set")
+	}
+	var b bytes.Buffer
+	if err := z.Write(&b); err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		return err
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		files[f.Name] = data
+	}
+	return writeFiles(w, files, deterministicTime)
+}
+
+// writeFiles writes files into an archive sorted by name. The files get
+// the modification time given, or the current time when it is zero.
+func writeFiles(w io.Writer, files map[string][]byte, modified time.Time) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		var fw io.Writer
+		var err error
+		if modified.IsZero() {
+			fw, err = addToZip(zw, name)
+		} else {
+			fw, err = zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: modified})
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
 // writeContent writes the .content file to the archive.
 func (z *Zip) writeContent(zw *zip.Writer) error {
 	bytes, err := json.MarshalIndent(&z.Content, "", "    ")
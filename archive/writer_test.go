@@ -1,8 +1,12 @@
 package archive
 
 import (
+	"archive/zip"
+	"bytes"
 	"os"
+	"sort"
 	"testing"
+	"time"
 )
 
 func TestWrite(t *testing.T) {
@@ -25,3 +29,43 @@ func TestWrite(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestWriteDeterministic(t *testing.T) {
+	z := NewZip()
+	z.UUID = "1234"
+	z.Content.FileType = "pdf"
+	z.Content.PageCount = 2
+	z.Pages = []Page{{Pagedata: "Blank", Thumbnail: []byte("jpg")}, {Pagedata: "Blank"}}
+	z.Payload = []byte("pdf")
+
+	var first, second bytes.Buffer
+	if err := z.WriteDeterministic(&first); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := z.WriteDeterministic(&second); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("expected the same archive twice")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(first.Bytes()), int64(first.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if !f.Modified.Equal(deterministicTime) {
+			t.Errorf("%s: unexpected modification time %v", f.Name, f.Modified)
+		}
+	}
+	if !sort.StringsAreSorted(names) || len(names) != 4 {
+		t.Errorf("expected the 4 files sorted, got %v", names)
+	}
+
+	if err := NewZip().WriteDeterministic(&first); err == nil {
+		t.Error("expected an error without UUID")
+	}
+}
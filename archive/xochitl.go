@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractToDevice unpacks an archive downloaded from the cloud into dir
+// with the layout of the documents of the tablet, as found in
+// /home/root/.local/share/remarkable/xochitl: id.content, id.metadata,
+// id.pagedata, the pdf or the epub, and the id/ directory of the pages.
+// The files of the document already in dir are replaced. The .metadata,
+// which the downloaded archives may lack, is written with the id as
+// name, and the document is put at the root when its folder isn't in
+// dir. The tablet shows the document once xochitl is restarted.
+func ExtractToDevice(zipPath, dir string) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	id, metadata, meta, err := documentMetadata(&r.Reader)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return fmt.Errorf("invalid document id %q", id)
+	}
+	if meta.DocName == "" {
+		meta.DocName = id
+	}
+	if meta.Parent != "" && meta.Parent != "trash" {
+		if _, err := os.Stat(filepath.Join(dir, meta.Parent+".metadata")); err != nil {
+			meta.Parent = ""
+		}
+	}
+	meta.Deleted = false
+	if meta.LastModified == "" {
+		meta.LastModified = UnixTimestamp()
+	}
+
+	// the names without the empty parts of some downloads, such as
+	// id//0.rm, checked before any file is written
+	names := make(map[*zip.File]string)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || f == metadata {
+			continue
+		}
+		name := path.Clean(f.Name)
+		if !strings.HasPrefix(name, id+"/") && !strings.HasPrefix(name, id+".") {
+			return fmt.Errorf("%s doesn't belong to the document %s", f.Name, id)
+		}
+		names[f] = name
+	}
+	for _, f := range r.File {
+		if name, ok := names[f]; ok {
+			if err := extractFile(f, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(meta, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, id+".metadata"), data, 0644)
+}
+
+// extractFile writes a file of an archive to dst, creating its
+// directory.
+func extractFile(f *zip.File, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := copyZipFile(out, f); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,79 @@
+package archive
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractToDevice(t *testing.T) {
+	const id = "384327f5-133e-49c8-82ff-30aa19f3cfa4"
+	dir := t.TempDir()
+	if err := ExtractToDevice("test.zip", dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{id + ".content", id + ".pagedata", id + "/0.rm", id + "/0-metadata.json", id + ".thumbnails/0.jpg"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+	if meta := readTestMetadata(t, filepath.Join(dir, id+".metadata")); meta.DocName != id || meta.CollectionType != "DocumentType" {
+		t.Errorf("expected the metadata of the document, got %+v", meta)
+	}
+}
+
+func TestExtractToDeviceParent(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "doc.zip")
+	err := WriteRawFiles(src, map[string][]byte{
+		"doc.content":  []byte(`{"fileType":"pdf"}`),
+		"doc.pdf":      []byte("%PDF"),
+		"doc.metadata": []byte(`{"visibleName":"Paper","type":"DocumentType","parent":"folder"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// at the root when the folder isn't on the tablet
+	dir := t.TempDir()
+	if err := ExtractToDevice(src, dir); err != nil {
+		t.Fatal(err)
+	}
+	if meta := readTestMetadata(t, filepath.Join(dir, "doc.metadata")); meta.DocName != "Paper" || meta.Parent != "" {
+		t.Errorf("expected the document at the root, got %+v", meta)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "folder.metadata"), []byte(`{"type":"CollectionType"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractToDevice(src, dir); err != nil {
+		t.Fatal(err)
+	}
+	if meta := readTestMetadata(t, filepath.Join(dir, "doc.metadata")); meta.Parent != "folder" {
+		t.Errorf("expected the document in its folder, got %+v", meta)
+	}
+
+	foreign := filepath.Join(t.TempDir(), "foreign.zip")
+	err = WriteRawFiles(foreign, map[string][]byte{
+		"doc.content":   []byte(`{}`),
+		"../escape.txt": []byte("outside"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractToDevice(foreign, t.TempDir()); err == nil {
+		t.Error("expected an error for a file outside the document")
+	}
+}
+
+func readTestMetadata(t *testing.T, path string) MetadataFile {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta MetadataFile
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+	return meta
+}
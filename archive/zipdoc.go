@@ -4,23 +4,114 @@ import (
 	"archive/zip"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
 	"image/jpeg"
 	"image/png"
 	"io/ioutil"
+	"math"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	uuid "github.com/google/uuid"
+	"github.com/joagonca/rmapi/encoding/rm"
 	"github.com/joagonca/rmapi/log"
+	"github.com/joagonca/rmapi/model"
+	"github.com/joagonca/rmapi/pdf"
 	"github.com/joagonca/rmapi/util"
 	"github.com/nfnt/resize"
 )
 
-func makeThumbnail(pdf []byte) ([]byte, error) {
+// makeThumbnail renders the first page of a pdf as a thumbnail, with
+// pdftoppm when it is installed, better at drawing the text, and with
+// the renderer of the pdf package otherwise.
+func makeThumbnail(data []byte) ([]byte, error) {
+	var img image.Image
+	var err error
+	if _, lookErr := exec.LookPath("pdftoppm"); lookErr == nil {
+		img, err = renderPdftoppm(data)
+	} else {
+		img, err = renderFirstPage(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encodeThumbnail(img)
+}
+
+// StrokesRenderer draws the strokes of a page on a white background at
+// a resolution, for the thumbnails of the notebooks. It is set by the
+// annotations package, which draws them like its exports.
+var StrokesRenderer func(page *rm.Rm, dpi int) image.Image
+
+// thumbnailDPI is the resolution of the strokes of the thumbnails, a
+// little above the width of the thumbnails.
+const thumbnailDPI = 50
+
+// makeNotebookThumbnail renders the strokes of a .rm page as a
+// thumbnail.
+func makeNotebookThumbnail(data []byte) ([]byte, error) {
+	if StrokesRenderer == nil {
+		return nil, errors.New("no renderer of the strokes")
+	}
+	page := rm.New()
+	if err := page.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("failed to read the page: %w", err)
+	}
+	return encodeThumbnail(StrokesRenderer(page, thumbnailDPI))
+}
+
+// makeImageThumbnail resizes a jpeg or png image to a thumbnail.
+func makeImageThumbnail(data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the thumbnail: %w", err)
+	}
+	return encodeThumbnail(img)
+}
+
+// encodeThumbnail resizes an image of a page to a thumbnail.
+func encodeThumbnail(img image.Image) ([]byte, error) {
+	// Resize to reMarkable thumbnail dimensions (280x374 pixels)
+	thumbnail := resize.Resize(280, 374, img, resize.Lanczos3)
+
+	// Encode as JPEG
+	out := &bytes.Buffer{}
+	if err := jpeg.Encode(out, thumbnail, nil); err != nil {
+		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// renderFirstPage renders the first page of a pdf, its longest side of
+// 800 pixels like with pdftoppm.
+func renderFirstPage(data []byte) (image.Image, error) {
+	f, err := pdf.Open(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("PDF without pages")
+	}
+	w, h := f.DisplaySize(pages[0])
+	scale := 800 / math.Max(w, h)
+	return f.RenderPage(pages[0], max(1, int(w*scale+0.5)), max(1, int(h*scale+0.5)))
+}
+
+// renderPdftoppm renders the first page of a pdf with pdftoppm.
+func renderPdftoppm(data []byte) (image.Image, error) {
 	// 1. Write PDF to temporary file (pdftoppm requires a file path)
 	tmpPdf, err := os.CreateTemp("", "rmapi-pdf-*.pdf")
 	if err != nil {
@@ -29,7 +120,7 @@ func makeThumbnail(pdf []byte) ([]byte, error) {
 	tmpPdfPath := tmpPdf.Name()
 	defer os.Remove(tmpPdfPath)
 
-	if _, err := tmpPdf.Write(pdf); err != nil {
+	if _, err := tmpPdf.Write(data); err != nil {
 		tmpPdf.Close()
 		return nil, fmt.Errorf("failed to write temp PDF: %w", err)
 	}
@@ -68,53 +159,237 @@ func makeThumbnail(pdf []byte) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode PNG: %w", err)
 	}
-
-	// 4. Resize to reMarkable thumbnail dimensions (280x374 pixels)
-	thumbnail := resize.Resize(280, 374, img, resize.Lanczos3)
-
-	// 5. Encode as JPEG
-	out := &bytes.Buffer{}
-	if err := jpeg.Encode(out, thumbnail, nil); err != nil {
-		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
-	}
-
-	return out.Bytes(), nil
+	return img, nil
 }
 
 // GetIdFromZip tries to get the Document UUID from an archive
 func GetIdFromZip(srcPath string) (id string, err error) {
-	file, err := os.Open(srcPath)
+	zip, err := OpenZip(srcPath)
 	if err != nil {
 		return
 	}
-	defer file.Close()
-	fi, err := file.Stat()
+	defer zip.Close()
+	id = zip.UUID
+	return
+}
+
+// GetMetadataFromZip returns the metadata of the document of an
+// archive, such as its pinned state, the metadata of a document without
+// name when the archive has none.
+func GetMetadataFromZip(srcPath string) (MetadataFile, error) {
+	r, err := zip.OpenReader(srcPath)
 	if err != nil {
-		return
+		return MetadataFile{}, err
 	}
-	zip := Zip{}
-	err = zip.Read(file, fi.Size())
-	if err != nil {
-		return
+	defer r.Close()
+	_, _, meta, err := documentMetadata(&r.Reader)
+	return meta, err
+}
+
+// CoverPage is the page a document shows as its cover in the library.
+type CoverPage int
+
+const (
+	CoverFirstPage   CoverPage = 0
+	CoverLastVisited CoverPage = -1
+)
+
+// ParseCoverPage reads a cover page option: "first" or "last".
+func ParseCoverPage(s string) (CoverPage, error) {
+	switch s {
+	case "first":
+		return CoverFirstPage, nil
+	case "last":
+		return CoverLastVisited, nil
 	}
-	id = zip.UUID
-	return
+	return 0, fmt.Errorf("unknown cover page %q, expected first or last", s)
 }
 
-func CreateZipDocument(id, srcPath string) (zipPath string, err error) {
-	_, ext := util.DocPathToName(srcPath)
-	fileType := ext
+// DocumentOptions are the settings of a document created from a local
+// file.
+type DocumentOptions struct {
+	// Cover is the page shown as the cover in the library
+	Cover CoverPage
+	// Tags are the names of the tags of the document
+	Tags []string
+	// Template is the template of the pages of a notebook, such as
+	// "LS Grid medium", the device's default when empty
+	Template string
+	// LastModified is the modification time of the document, now when
+	// zero, set to keep the time of a document moved from elsewhere
+	LastModified time.Time
+	// Version is the version of the metadata of the document
+	Version int
+	// Pinned marks the document as a favorite
+	Pinned bool
+	// Thumbnail is a jpeg or png image, such as a cover, shown as the
+	// thumbnail of the first page instead of rendering the page
+	Thumbnail []byte
+	// Content are the settings of the .content of the document
+	Content ContentSettings
+}
+
+// ContentSettings are the settings of the .content of a new document,
+// the ones of rmapi for the zero values.
+type ContentSettings struct {
+	// Pen is the tool selected when opening the document, Finelinerv2
+	// by default
+	Pen string
+	// Margins are the margins of the epubs, 180 by default
+	Margins int
+	// TextScale is the size of the text of the epubs, 1 by default
+	TextScale int
+	// FontName is the font of the epubs, the device's default when
+	// empty
+	FontName string
+	// LineHeight is the spacing of the lines of the epubs in percents,
+	// the device's default when 0
+	LineHeight int
+	// TextAlignment is "justify", by default, or "left"
+	TextAlignment string
+}
+
+// hasMetadata tells whether the options are kept in the metadata.
+func (o DocumentOptions) hasMetadata() bool {
+	return len(o.Tags) > 0 || !o.LastModified.IsZero() || o.Version != 0 || o.Pinned
+}
+
+// newTags returns the tags of the given names, added now.
+func newTags(names []string) []Tag {
+	if len(names) == 0 {
+		return nil
+	}
+	now := time.Now().UnixMilli()
+	tags := make([]Tag, len(names))
+	for i, name := range names {
+		tags[i] = Tag{Name: name, Timestamp: now}
+	}
+	return tags
+}
+
+// CreateZipDocument creates the zip of a document from a local file,
+// with its options. The zip and .rmdoc files are returned as they are,
+// and a directory of .rm files makes a notebook of these pages.
+func CreateZipDocument(id, srcPath string, opts DocumentOptions) (zipPath string, err error) {
+	name, ext := util.DocPathToName(srcPath)
 
-	if ext == util.ZIP {
+	if ext == util.ZIP || ext == util.RMDOC {
 		zipPath = srcPath
 		return
 	}
 
+	if info, err := os.Stat(srcPath); err == nil && info.IsDir() {
+		pagePaths, err := NotebookPages(srcPath)
+		if err != nil {
+			return "", err
+		}
+		return CreateNotebook(id, filepath.Base(srcPath), pagePaths, opts)
+	}
+
 	doc, err := ioutil.ReadFile(srcPath)
 	if err != nil {
 		log.Error.Println("failed to open source document file to read", err)
 		return
 	}
+
+	doc, ext, err = convertDocument(doc, ext, name)
+	if err != nil {
+		log.Error.Println("failed to convert source document", err)
+		return
+	}
+	if ext == util.RM {
+		return createZip(id, name, ext, nil, [][]byte{doc}, opts)
+	}
+	if opts.Template != "" {
+		return "", errors.New("templates can only be set on notebooks")
+	}
+	return createZip(id, name, ext, doc, nil, opts)
+}
+
+// NotebookPages lists the .rm files of a directory, the pages of a
+// notebook, in the order of their names, the numbers in the names
+// being compared as numbers: 2.rm comes before 10.rm.
+func NotebookPages(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), "."+util.RM) {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s has no .rm page", dir)
+	}
+	sort.SliceStable(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(dir, name)
+	}
+	return paths, nil
+}
+
+// naturalLess compares two names, the runs of digits as numbers.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+// CreateNotebook creates the zip of a notebook named name with the .rm
+// files given, one page each, in order.
+func CreateNotebook(id, name string, pagePaths []string, opts DocumentOptions) (string, error) {
+	if len(pagePaths) == 0 {
+		return "", errors.New("a notebook needs at least a page")
+	}
+	pages := make([][]byte, len(pagePaths))
+	for i, p := range pagePaths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return "", err
+		}
+		pages[i] = data
+	}
+	return createZip(id, name, util.RM, nil, pages, opts)
+}
+
+// createZip writes the zip of a document: doc is its pdf or epub, of
+// the type ext, or the .rm files of the pages of a notebook when ext is
+// util.RM.
+func createZip(id, name, ext string, doc []byte, notebook [][]byte, opts DocumentOptions) (zipPath string, err error) {
+	fileType := ext
+	var cover []byte
+	if opts.Thumbnail != nil {
+		if cover, err = makeImageThumbnail(opts.Thumbnail); err != nil {
+			return
+		}
+	}
 	// Create document (pdf or epub) file
 	tmp, err := ioutil.TempFile("", "rmapizip")
 	if err != nil {
@@ -130,50 +405,62 @@ func CreateZipDocument(id, srcPath string) (zipPath string, err error) {
 	w := zip.NewWriter(tmp)
 	defer w.Close()
 
-	var documentPath string
-
+	thumbnails := os.Getenv("RMAPI_THUMBNAILS") != ""
 	pages := make([]string, 0)
 	if ext == util.RM {
-		pageUUID := uuid.New()
-		pageID := pageUUID.String()
-		documentPath = fmt.Sprintf("%s/%s.rm", id, pageID)
 		fileType = "notebook"
-		pages = append(pages, pageID)
+		for i, page := range notebook {
+			pageID := uuid.New().String()
+			pages = append(pages, pageID)
+			f, err := w.Create(fmt.Sprintf("%s/%s.rm", id, pageID))
+			if err != nil {
+				log.Error.Println("failed to create doc entry in zip file", err)
+				return "", err
+			}
+			f.Write(page)
+
+			//thumbnail generation is opt-in via RMAPI_THUMBNAILS environment variable
+			if i == 0 && cover != nil {
+				if err := writeThumbnail(w, fmt.Sprintf("%s.thumbnails/%s.jpg", id, pageID), cover); err != nil {
+					return "", err
+				}
+			} else if thumbnails {
+				if err := addThumbnail(w, fmt.Sprintf("%s.thumbnails/%s.jpg", id, pageID), page, makeNotebookThumbnail); err != nil {
+					return "", err
+				}
+			}
+		}
 	} else {
-		documentPath = fmt.Sprintf("%s.%s", id, ext)
+		f, err := w.Create(fmt.Sprintf("%s.%s", id, ext))
+		if err != nil {
+			log.Error.Println("failed to create doc entry in zip file", err)
+			return "", err
+		}
+		f.Write(doc)
 		pages = append(pages, "")
-	}
 
-	f, err := w.Create(documentPath)
-	if err != nil {
-		log.Error.Println("failed to create doc entry in zip file", err)
-		return
-	}
-	f.Write(doc)
-
-	//try to create a thumbnail
-	//thumbnail generation is opt-in via RMAPI_THUMBNAILS environment variable
-	if ext == util.PDF && os.Getenv("RMAPI_THUMBNAILS") != "" {
-		thumbnail, err := makeThumbnail(doc)
-		if err != nil {
-			log.Error.Println("cannot generate thumbnail", err)
-		} else {
-			f, err := w.Create(fmt.Sprintf("%s.thumbnails/0.jpg", id))
-			if err != nil {
-				log.Error.Println("failed to create doc entry in zip file", err)
+		//try to create a thumbnail
+		//thumbnail generation is opt-in via RMAPI_THUMBNAILS environment variable
+		if cover != nil {
+			if err := writeThumbnail(w, fmt.Sprintf("%s.thumbnails/0.jpg", id), cover); err != nil {
+				return "", err
+			}
+		} else if ext == util.PDF && thumbnails {
+			if err := addThumbnail(w, fmt.Sprintf("%s.thumbnails/0.jpg", id), doc, makeThumbnail); err != nil {
 				return "", err
 			}
-			f.Write(thumbnail)
 		}
 	}
 
-	// Create pagedata file
-	f, err = w.Create(fmt.Sprintf("%s.pagedata", id))
+	// Create pagedata file, with the template of each page
+	f, err := w.Create(fmt.Sprintf("%s.pagedata", id))
 	if err != nil {
 		log.Error.Println("failed to create content entry in zip file", err)
 		return
 	}
-	f.Write(make([]byte, 0))
+	if opts.Template != "" {
+		f.Write([]byte(strings.Repeat(opts.Template+"\n", len(pages))))
+	}
 
 	// Create content content
 	f, err = w.Create(fmt.Sprintf("%s.content", id))
@@ -182,17 +469,54 @@ func CreateZipDocument(id, srcPath string) (zipPath string, err error) {
 		return
 	}
 
-	c, err := createZipContent(fileType, pages)
+	c, err := createZipContent(fileType, pages, int64(len(doc)), opts)
 	if err != nil {
 		return
 	}
 
 	f.Write([]byte(c))
+
+	// the tags and the times are kept in the metadata, which names the
+	// document
+	if opts.hasMetadata() {
+		meta, err := json.Marshal(newMetadata(name, "", model.DocumentType, opts))
+		if err != nil {
+			return "", err
+		}
+		f, err := w.Create(fmt.Sprintf("%s.metadata", id))
+		if err != nil {
+			log.Error.Println("failed to create metadata entry in zip file", err)
+			return "", err
+		}
+		f.Write(meta)
+	}
 	zipPath = tmp.Name()
 
 	return
 }
 
+// addThumbnail adds the thumbnail made from data to the zip, logging
+// the thumbnails which can't be made.
+func addThumbnail(w *zip.Writer, name string, data []byte, makeThumb func([]byte) ([]byte, error)) error {
+	thumbnail, err := makeThumb(data)
+	if err != nil {
+		log.Error.Println("cannot generate thumbnail", err)
+		return nil
+	}
+	return writeThumbnail(w, name, thumbnail)
+}
+
+// writeThumbnail adds a thumbnail to the zip.
+func writeThumbnail(w *zip.Writer, name string, thumbnail []byte) error {
+	f, err := w.Create(name)
+	if err != nil {
+		log.Error.Println("failed to create doc entry in zip file", err)
+		return err
+	}
+	_, err = f.Write(thumbnail)
+	return err
+}
+
 func CreateZipDirectory(id string) (string, error) {
 	tmp, err := ioutil.TempFile("", "rmapizip")
 
@@ -217,7 +541,12 @@ func CreateZipDirectory(id string) (string, error) {
 	return tmp.Name(), nil
 }
 
-func createZipContent(ext string, pageIDs []string) (string, error) {
+// createZipContent returns the .content of a new document of the given
+// type with its pages, size being the size of its pdf or epub. The
+// notebooks are written as on the firmwares 3.x, with their pages in
+// cPages, the pdfs and the epubs with the older list of pages, which the
+// device completes when opening them.
+func createZipContent(ext string, pageIDs []string, size int64, opts DocumentOptions) (string, error) {
 	c := Content{
 		DummyDocument: false,
 		ExtraMetadata: ExtraMetadata{
@@ -225,12 +554,16 @@ func createZipContent(ext string, pageIDs []string) (string, error) {
 			LastTool:            "Finelinerv2",
 			LastFinelinerv2Size: "1",
 		},
-		FileType:       ext,
-		PageCount:      0,
-		LastOpenedPage: 0,
-		LineHeight:     -1,
-		Margins:        180,
-		TextScale:      1,
+		FileType:        ext,
+		PageCount:       0,
+		LastOpenedPage:  0,
+		CoverPageNumber: int(opts.Cover),
+		LineHeight:      -1,
+		Margins:         180,
+		Orientation:     "portrait",
+		TextAlignment:   "justify",
+		TextScale:       1,
+		ZoomMode:        "bestFit",
 		Transform: Transform{
 			M11: 1,
 			M12: 0,
@@ -242,7 +575,53 @@ func createZipContent(ext string, pageIDs []string) (string, error) {
 			M32: 0,
 			M33: 1,
 		},
-		Pages: pageIDs,
+		Tags: newTags(opts.Tags),
+	}
+	s := opts.Content
+	if s.Pen != "" {
+		c.ExtraMetadata.LastPen = s.Pen
+		c.ExtraMetadata.LastTool = s.Pen
+	}
+	if s.Margins > 0 {
+		c.Margins = s.Margins
+	}
+	if s.TextScale > 0 {
+		c.TextScale = s.TextScale
+	}
+	c.FontName = s.FontName
+	if s.LineHeight > 0 {
+		c.LineHeight = s.LineHeight
+	}
+	if s.TextAlignment != "" {
+		c.TextAlignment = s.TextAlignment
+	}
+	if ext == "notebook" {
+		template := opts.Template
+		if template == "" {
+			template = defaultPagadata
+		}
+		c.FormatVersion = 2
+		c.PageCount = len(pageIDs)
+		c.CPages = &CPages{
+			Original: StampedInt{Timestamp: "0:0", Value: -1},
+			Pages:    make([]CPage, len(pageIDs)),
+		}
+		for i, idx := range pageIndexes(len(pageIDs)) {
+			c.CPages.Pages[i] = CPage{
+				ID:       pageIDs[i],
+				Idx:      StampedString{Timestamp: "1:2", Value: idx},
+				Template: &StampedString{Timestamp: "1:1", Value: template},
+			}
+		}
+		if len(pageIDs) > 0 {
+			c.CPages.LastOpened = StampedString{Timestamp: "1:1", Value: pageIDs[0]}
+		}
+	} else {
+		c.FormatVersion = 1
+		c.Pages = pageIDs
+		if size > 0 {
+			c.SizeInBytes = strconv.FormatInt(size, 10)
+		}
 	}
 
 	cstring, err := json.Marshal(c)
@@ -255,13 +634,16 @@ func createZipContent(ext string, pageIDs []string) (string, error) {
 	return string(cstring), nil
 }
 
-func CreateContent(id, ext, fpath string, pageIds []string) (fileName, filePath string, err error) {
+// CreateContent writes the .content of a new document in fpath, the one
+// of a folder when ext is empty. size is the size of the pdf or the
+// epub of the document.
+func CreateContent(id, ext, fpath string, pageIds []string, size int64) (fileName, filePath string, err error) {
 	fileName = id + ".content"
 	filePath = path.Join(fpath, fileName)
 	content := "{}"
 
 	if ext != "" {
-		content, err = createZipContent(ext, pageIds)
+		content, err = createZipContent(ext, pageIds, size, DocumentOptions{})
 		if err != nil {
 			return
 		}
@@ -272,22 +654,44 @@ func CreateContent(id, ext, fpath string, pageIds []string) (fileName, filePath
 }
 
 func UnixTimestamp() string {
-	t := time.Now().UnixNano() / 1000000
-	tf := strconv.FormatInt(t, 10)
-	return tf
+	return unixTimestamp(time.Now())
 }
 
-func CreateMetadata(id, name, parent, colType, fpath string) (fileName string, filePath string, err error) {
-	fileName = id + ".metadata"
-	filePath = path.Join(fpath, fileName)
-	meta := MetadataFile{
+// unixTimestamp returns the time in milliseconds, as in the metadata.
+func unixTimestamp(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/1000000, 10)
+}
+
+// newMetadata returns the metadata of a new entry with the tags, the
+// modification time, the version and the pinned state of the options.
+func newMetadata(name, parent, colType string, opts DocumentOptions) MetadataFile {
+	modified := opts.LastModified
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+	return MetadataFile{
 		DocName:        name,
-		Version:        0,
+		Version:        opts.Version,
 		CollectionType: colType,
 		Parent:         parent,
 		Synced:         true,
-		LastModified:   UnixTimestamp(),
+		LastModified:   unixTimestamp(modified),
+		Pinned:         opts.Pinned,
+		Tags:           newTags(opts.Tags),
 	}
+}
+
+func CreateMetadata(id, name, parent, colType, fpath string) (fileName string, filePath string, err error) {
+	return CreateMetadataWith(id, name, parent, colType, fpath, DocumentOptions{})
+}
+
+// CreateMetadataWith creates the metadata like CreateMetadata, with the
+// tags, the modification time, the version and the pinned state of the
+// options.
+func CreateMetadataWith(id, name, parent, colType, fpath string, opts DocumentOptions) (fileName string, filePath string, err error) {
+	fileName = id + ".metadata"
+	filePath = path.Join(fpath, fileName)
+	meta := newMetadata(name, parent, colType, opts)
 
 	c, err := json.Marshal(meta)
 	if err != nil {
@@ -1,15 +1,420 @@
 package archive
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/joagonca/rmapi/encoding/rm"
 )
 
 func TestZipFile(t *testing.T) {
-	d, err := CreateZipDocument("1234", "zipdoc_test.pdf")
+	d, err := CreateZipDocument("1234", "zipdoc_test.pdf", DocumentOptions{})
 	fmt.Println(d)
 	if err != nil {
 		t.Error(err)
 	}
 
 }
+
+func TestRenderFirstPage(t *testing.T) {
+	data, err := os.ReadFile("zipdoc_test.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := renderFirstPage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); max(b.Dx(), b.Dy()) != 800 {
+		t.Errorf("expected a longest side of 800 pixels, got %v", b)
+	}
+}
+
+// testPage returns the first .rm page of test.zip.
+func testPage(t *testing.T) []byte {
+	src, err := zip.OpenReader("test.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	var page []byte
+	for _, f := range src.File {
+		if strings.HasSuffix(f.Name, ".rm") {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			page, err = io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	return page
+}
+
+func TestNotebookThumbnail(t *testing.T) {
+	page := testPage(t)
+
+	defer func(renderer func(*rm.Rm, int) image.Image) { StrokesRenderer = renderer }(StrokesRenderer)
+	var rendered bool
+	StrokesRenderer = func(data *rm.Rm, dpi int) image.Image {
+		rendered = len(data.Layers) > 0
+		return image.NewRGBA(image.Rect(0, 0, 1404*dpi/226, 1872*dpi/226))
+	}
+	thumbnail, err := makeNotebookThumbnail(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rendered {
+		t.Error("expected the strokes of the page to be rendered")
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 280 || b.Dy() != 374 {
+		t.Errorf("expected a thumbnail of 280x374, got %v", b)
+	}
+}
+
+func TestZipThumbnail(t *testing.T) {
+	var cover bytes.Buffer
+	if err := png.Encode(&cover, image.NewRGBA(image.Rect(0, 0, 600, 800))); err != nil {
+		t.Fatal(err)
+	}
+	zipPath, err := CreateZipDocument("1234", "zipdoc_test.pdf", DocumentOptions{Thumbnail: cover.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(zipPath)
+
+	files, err := ReadRawFiles(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(files["1234.thumbnails/0.jpg"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 280 || b.Dy() != 374 {
+		t.Errorf("expected a thumbnail of 280x374, got %v", b)
+	}
+
+	if _, err := CreateZipDocument("1234", "zipdoc_test.pdf", DocumentOptions{Thumbnail: []byte("gif")}); err == nil {
+		t.Error("expected an error for an invalid thumbnail")
+	}
+}
+
+func TestZipCover(t *testing.T) {
+	for _, tc := range []struct {
+		option string
+		want   int
+	}{
+		{"first", 0},
+		{"last", -1},
+	} {
+		cover, err := ParseCoverPage(tc.option)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zipPath, err := CreateZipDocument("1234", "zipdoc_test.pdf", DocumentOptions{Cover: cover})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(zipPath)
+
+		f, err := os.Open(zipPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		z := NewZip()
+		if err := z.Read(f, fi.Size()); err != nil {
+			t.Fatal(err)
+		}
+		if z.Content.CoverPageNumber != tc.want {
+			t.Errorf("%s: expected the cover page %d, got %d", tc.option, tc.want, z.Content.CoverPageNumber)
+		}
+	}
+
+	if _, err := ParseCoverPage("middle"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestZipTags(t *testing.T) {
+	zipPath, err := CreateZipDocument("1234", "zipdoc_test.pdf", DocumentOptions{Tags: []string{"work", "2024"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(zipPath)
+
+	files, id, err := Prepare("report", "parent", zipPath, "zip", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta MetadataFile
+	var content Content
+	for _, f := range files.Files {
+		var v interface{}
+		switch f.Name {
+		case id + ".metadata":
+			v = &meta
+		case id + ".content":
+			v = &content
+		default:
+			continue
+		}
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"work", "2024"}
+	if got := meta.TagNames(); !reflect.DeepEqual(got, want) || meta.Parent != "parent" {
+		t.Errorf("expected the tags %v in the metadata, got %+v", want, meta)
+	}
+	if len(content.Tags) != 2 || content.Tags[1].Name != "2024" || content.Tags[1].Timestamp == 0 {
+		t.Errorf("expected the tags %v in the content, got %+v", want, content.Tags)
+	}
+}
+
+func TestZipMetadata(t *testing.T) {
+	modified := time.Date(2021, 6, 1, 8, 30, 0, 0, time.UTC)
+	zipPath, err := CreateZipDocument("1234", "zipdoc_test.pdf", DocumentOptions{LastModified: modified, Version: 7, Pinned: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(zipPath)
+
+	files, id, err := Prepare("report", "parent", zipPath, "zip", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta MetadataFile
+	for _, f := range files.Files {
+		if f.Name != id+".metadata" {
+			continue
+		}
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if meta.LastModified != "1622536200000" || meta.Version != 7 || !meta.Pinned || meta.DocName != "report" {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+}
+
+func TestPinnedMetadata(t *testing.T) {
+	zipPath, err := CreateZipDocument("1234", "zipdoc_test.pdf", DocumentOptions{Pinned: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(zipPath)
+	meta, err := GetMetadataFromZip(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Pinned {
+		t.Errorf("expected a pinned document, got %+v", meta)
+	}
+
+	dir := t.TempDir()
+	_, path, err := CreateMetadataWith("5678", "report", "parent", "DocumentType", dir, DocumentOptions{Pinned: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"pinned":true`) {
+		t.Errorf("expected a pinned document, got %s", data)
+	}
+}
+
+func TestZipTemplate(t *testing.T) {
+	page := filepath.Join(t.TempDir(), "Sketch.rm")
+	if err := os.WriteFile(page, testPage(t), 0600); err != nil {
+		t.Fatal(err)
+	}
+	zipPath, err := CreateZipDocument("1234", page, DocumentOptions{Template: "LS Grid medium"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(zipPath)
+
+	f, err := os.Open(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := NewZip()
+	if err := z.Read(f, fi.Size()); err != nil {
+		t.Fatal(err)
+	}
+	if len(z.Pages) != 1 || z.Pages[0].Pagedata != "LS Grid medium" {
+		t.Errorf("expected a page on the template, got %+v", z.Pages)
+	}
+
+	if _, err := CreateZipDocument("1234", "zipdoc_test.pdf", DocumentOptions{Template: "P Lines small"}); err == nil {
+		t.Error("expected an error for the template of a pdf")
+	}
+}
+
+func TestZipNotebookDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Sketches")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	page := testPage(t)
+	for _, name := range []string{"10.rm", "2.rm", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), page, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages, err := NotebookPages(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "2.rm"), filepath.Join(dir, "10.rm")}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("expected the pages %v, got %v", want, pages)
+	}
+
+	zipPath, err := CreateZipDocument("1234", dir, DocumentOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(zipPath)
+	f, err := os.Open(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := NewZip()
+	if err := z.Read(f, fi.Size()); err != nil {
+		t.Fatal(err)
+	}
+	if len(z.Pages) != 2 || z.Content.FileType != "notebook" {
+		t.Errorf("expected a notebook of 2 pages, got %d pages of %q", len(z.Pages), z.Content.FileType)
+	}
+
+	if _, err := NotebookPages(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without pages")
+	}
+}
+
+func TestZipContent(t *testing.T) {
+	data, err := createZipContent("notebook", []string{"p1", "p2"}, 0, DocumentOptions{Template: "P Grid medium"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c Content
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.FormatVersion != 2 || c.CPages == nil || len(c.CPages.Pages) != 2 || c.Pages != nil {
+		t.Fatalf("expected the pages of the notebook in cPages, got %s", data)
+	}
+	if p := c.CPages.Pages[1]; p.ID != "p2" || p.Idx.Value <= c.CPages.Pages[0].Idx.Value || p.Template.Value != "P Grid medium" {
+		t.Errorf("unexpected second page %+v", p)
+	}
+	if c.Orientation != "portrait" || c.ZoomMode != "bestFit" || c.TextAlignment != "justify" {
+		t.Errorf("expected the settings of the device, got %s", data)
+	}
+
+	if data, err = createZipContent("pdf", []string{""}, 1234, DocumentOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	c = Content{}
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.FormatVersion != 1 || c.CPages != nil || c.SizeInBytes != "1234" {
+		t.Errorf("expected a pdf of 1234 bytes with its list of pages, got %s", data)
+	}
+}
+
+func TestZipContentSettings(t *testing.T) {
+	settings := ContentSettings{Pen: "Ballpoint", Margins: 50, TextScale: 2, FontName: "Noto Serif", LineHeight: 150, TextAlignment: "left"}
+	data, err := createZipContent("epub", []string{""}, 0, DocumentOptions{Content: settings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c Content
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.ExtraMetadata.LastPen != "Ballpoint" || c.ExtraMetadata.LastTool != "Ballpoint" || c.Margins != 50 || c.TextScale != 2 ||
+		c.FontName != "Noto Serif" || c.LineHeight != 150 || c.TextAlignment != "left" {
+		t.Errorf("expected the settings %+v, got %s", settings, data)
+	}
+
+	if data, err = createZipContent("epub", []string{""}, 0, DocumentOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	c = Content{}
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.ExtraMetadata.LastPen != "Finelinerv2" || c.Margins != 180 || c.TextScale != 1 || c.LineHeight != -1 {
+		t.Errorf("expected the default settings, got %s", data)
+	}
+}
+
+func TestContentRoundTrip(t *testing.T) {
+	data := []byte(`{"formatVersion":2,"fileType":"pdf","orientation":"landscape","zoomMode":"fitToWidth",` +
+		`"textAlignment":"left","sizeInBytes":"2048","cPages":{"lastOpened":{"timestamp":"1:1","value":"p1"},` +
+		`"original":{"timestamp":"1:1","value":3},"pages":[{"id":"p1","idx":{"timestamp":"1:2","value":"ba"},` +
+		`"redir":{"timestamp":"1:2","value":2}}],"uuids":[{"first":"device","second":1}]}}`)
+	var c Content
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again Content
+	if err := json.Unmarshal(out, &again); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, again) || again.CPages.Pages[0].Redir.Value != 2 || again.ZoomMode != "fitToWidth" {
+		t.Errorf("the content isn't kept: %s", out)
+	}
+}
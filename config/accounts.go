@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// An Account is a cloud account other than the one of the shell, used
+// to copy documents between accounts.
+type Account struct {
+	Name string `yaml:"name"`
+	// Config is the file of the tokens of the account, created by the
+	// first login, as the one of RMAPI_CONFIG
+	Config string `yaml:"config"`
+	// Host is the server of the account, such as a rmfakecloud, the
+	// hosts of the environment when empty
+	Host string `yaml:"host"`
+}
+
+// Accounts are the configured accounts.
+type Accounts []*Account
+
+// check checks the accounts, the relative paths of the token files
+// being relative to dir.
+func (accounts Accounts) check(dir string) error {
+	names := make(map[string]bool)
+	for i, a := range accounts {
+		if a.Name == "" {
+			return fmt.Errorf("invalid account %d: missing name", i+1)
+		}
+		if names[a.Name] {
+			return fmt.Errorf("duplicate account %s", a.Name)
+		}
+		names[a.Name] = true
+		if a.Config == "" {
+			return fmt.Errorf("invalid account %s: missing config", a.Name)
+		}
+		if !filepath.IsAbs(a.Config) {
+			a.Config = filepath.Join(dir, a.Config)
+		}
+		a.Host = strings.TrimSuffix(a.Host, "/")
+	}
+	return nil
+}
+
+// Find returns the account named name.
+func (accounts Accounts) Find(name string) (*Account, error) {
+	for _, a := range accounts {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("no account named %s", name)
+}
+
+// Hosts returns the hosts of the requests of the account.
+func (a *Account) Hosts() Hosts {
+	if a.Host == "" {
+		return DefaultHosts()
+	}
+	return SingleHost(a.Host)
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+const testAccounts = `
+accounts:
+  - name: work
+    config: work.conf
+  - name: personal
+    config: /etc/rmapi/personal.conf
+    host: https://cloud.example.com/
+`
+
+func TestParseAccounts(t *testing.T) {
+	dir := filepath.FromSlash("/home/user/.config/rmapi")
+	settings, err := ParseSettings([]byte(testAccounts), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts, err := settings.Accounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %d", len(accounts))
+	}
+
+	work, err := accounts.Find("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if work.Config != filepath.Join(dir, "work.conf") {
+		t.Errorf("unexpected config %s", work.Config)
+	}
+	if work.Hosts() != DefaultHosts() {
+		t.Errorf("unexpected hosts %+v", work.Hosts())
+	}
+
+	personal, err := accounts.Find("personal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Hosts{Auth: "https://cloud.example.com", Doc: "https://cloud.example.com", Sync: "https://cloud.example.com"}
+	if personal.Hosts() != want {
+		t.Errorf("unexpected hosts %+v", personal.Hosts())
+	}
+
+	if _, err := accounts.Find("other"); err == nil {
+		t.Error("expected an error for an unknown account")
+	}
+
+	for _, invalid := range []string{
+		"accounts:\n  - config: a.conf\n",
+		"accounts:\n  - name: a\n",
+		"accounts:\n  - name: a\n    config: a.conf\n  - name: a\n    config: b.conf\n",
+		"accounts:\n  - name: a\n    token: a.conf\n",
+	} {
+		settings, err := ParseSettings([]byte(invalid), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := settings.Accounts(); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestSetHosts(t *testing.T) {
+	current := CurrentHosts()
+	defer SetHosts(current)
+
+	SetHosts(SingleHost("http://localhost:3000"))
+	if UploadBlob != "http://localhost:3000/sync/v2/signed-urls/uploads" {
+		t.Errorf("unexpected url %s", UploadBlob)
+	}
+	if NewUserDevice != "http://localhost:3000/token/json/2/user/new" {
+		t.Errorf("unexpected url %s", NewUserDevice)
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Job is a command of the shell run by the daemon on a schedule.
+type Job struct {
+	Name string `yaml:"name"`
+	// Schedule is a cron expression, checked by the daemon
+	Schedule string `yaml:"schedule"`
+	// Command is a command line of the shell, such as
+	// mget -o backup /Papers
+	Command string `yaml:"command"`
+}
+
+// Daemon is the configuration of the daemon.
+type Daemon struct {
+	// Listen is the address of the status and metrics endpoints, none
+	// when empty
+	Listen string `yaml:"listen"`
+	Jobs   []*Job `yaml:"jobs"`
+}
+
+// check checks the configuration of the daemon, naming the unnamed jobs
+// by their number.
+func (d *Daemon) check() error {
+	if len(d.Jobs) == 0 {
+		return fmt.Errorf("invalid daemon settings: no jobs")
+	}
+	names := make(map[string]bool)
+	for i, job := range d.Jobs {
+		if job.Name == "" {
+			job.Name = strconv.Itoa(i + 1)
+		}
+		if names[job.Name] {
+			return fmt.Errorf("duplicate job %s", job.Name)
+		}
+		names[job.Name] = true
+		if job.Schedule == "" {
+			return fmt.Errorf("invalid job %s: missing schedule", job.Name)
+		}
+		if strings.TrimSpace(job.Command) == "" {
+			return fmt.Errorf("invalid job %s: missing command", job.Name)
+		}
+	}
+	return nil
+}
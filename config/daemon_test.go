@@ -0,0 +1,51 @@
+package config
+
+import "testing"
+
+func TestDaemonSettings(t *testing.T) {
+	settings, err := ParseSettings([]byte(`
+daemon:
+  listen: 127.0.0.1:9100
+  jobs:
+    - name: papers
+      schedule: "0 * * * *"
+      command: mget -o backup /Papers
+    - schedule: "@daily"
+      command: device backup tablet
+`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := settings.Daemon()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Listen != "127.0.0.1:9100" || len(d.Jobs) != 2 {
+		t.Fatalf("unexpected config %+v", d)
+	}
+	if d.Jobs[1].Name != "2" {
+		t.Errorf("expected a default name, got %q", d.Jobs[1].Name)
+	}
+
+	if settings, err = ParseSettings([]byte("documents:\n  pen: Ballpoint\n"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := settings.Daemon(); d != nil || err != nil {
+		t.Errorf("expected no daemon, got %+v %v", d, err)
+	}
+
+	for _, invalid := range []string{
+		"daemon:\n  listen: 127.0.0.1:9100\n",
+		"daemon:\n  jobs:\n    - command: ls\n",
+		"daemon:\n  jobs:\n    - schedule: '@daily'\n",
+		"daemon:\n  jobs:\n    - {name: a, schedule: '@daily', command: ls}\n    - {name: a, schedule: '@daily', command: ls}\n",
+	} {
+		settings, err := ParseSettings([]byte(invalid), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := settings.Daemon(); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package config
+
+import "fmt"
+
+// Documents are the settings of the new documents uploaded, the ones of
+// rmapi when unset.
+type Documents struct {
+	// Pen is the tool selected when opening the document, such as
+	// Finelinerv2 or Ballpoint
+	Pen string `yaml:"pen"`
+	// Margins are the margins of the epubs, in the pixels of the device
+	Margins int `yaml:"margins"`
+	// TextScale is the size of the text of the epubs
+	TextScale int `yaml:"textScale"`
+	// FontName is the font of the epubs, such as Maison Neue
+	FontName string `yaml:"fontName"`
+	// LineHeight is the spacing of the lines of the epubs in percents,
+	// such as 150
+	LineHeight int `yaml:"lineHeight"`
+	// TextAlignment is justify or left
+	TextAlignment string `yaml:"textAlignment"`
+}
+
+// check checks the settings of the documents.
+func (d *Documents) check() error {
+	if d.Margins < 0 || d.TextScale < 0 || d.LineHeight < 0 {
+		return fmt.Errorf("invalid documents settings: the margins, the text scale and the line height must be positive")
+	}
+	switch d.TextAlignment {
+	case "", "justify", "left":
+	default:
+		return fmt.Errorf("invalid documents settings: unknown text alignment %s, justify or left", d.TextAlignment)
+	}
+	return nil
+}
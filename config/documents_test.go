@@ -0,0 +1,39 @@
+package config
+
+import "testing"
+
+func TestDocumentsSettings(t *testing.T) {
+	settings, err := ParseSettings([]byte(`
+documents:
+  pen: Ballpoint
+  margins: 50
+  textScale: 2
+  lineHeight: 150
+  textAlignment: left
+`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := settings.Documents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Documents{Pen: "Ballpoint", Margins: 50, TextScale: 2, LineHeight: 150, TextAlignment: "left"}
+	if *d != want {
+		t.Errorf("expected %+v, got %+v", want, *d)
+	}
+
+	for _, invalid := range []string{
+		"documents:\n  margins: -1\n",
+		"documents:\n  textAlignment: center\n",
+		"documents:\n  fontSize: 12\n",
+	} {
+		settings, err := ParseSettings([]byte(invalid), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := settings.Documents(); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"fmt"
+	"image/color"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+// FormatPDF is the default export format of the profiles.
+const FormatPDF = "pdf"
+
+// An ExportProfile sets how the documents of some folders, or with
+// some tags, are exported.
+type ExportProfile struct {
+	Name string `yaml:"name"`
+	// Folders are the remote folders of the documents, their
+	// subfolders included
+	Folders []string `yaml:"folders"`
+	Tags    []string `yaml:"tags"`
+	// Format is a format of the export package, such as pdf (with the
+	// annotations), png or zip (the archive)
+	Format          string `yaml:"format"`
+	PageNumbers     bool   `yaml:"page_numbers"`
+	AllPages        bool   `yaml:"all_pages"`
+	AnnotationsOnly bool   `yaml:"annotations_only"`
+	// PageNumberPosition, PageNumberSize, PageNumberFormat,
+	// PageNumberOffset and SkipFirstPageNumber set the look of the page
+	// numbers, checked by the exports
+	PageNumberPosition  string  `yaml:"page_number_position"`
+	PageNumberSize      float64 `yaml:"page_number_size"`
+	PageNumberFormat    string  `yaml:"page_number_format"`
+	PageNumberOffset    int     `yaml:"page_number_offset"`
+	SkipFirstPageNumber bool    `yaml:"skip_first_page_number"`
+	// NoTemplates leaves the pages of the notebooks blank
+	NoTemplates bool `yaml:"no_templates"`
+	// Smooth draws the strokes as curves through their points
+	Smooth bool `yaml:"smooth"`
+	// RasterDPI draws the strokes of the pdf exports as images of this
+	// resolution, and RasterCompression sets their compression, from 1
+	// to 9
+	RasterDPI         int `yaml:"raster_dpi"`
+	RasterCompression int `yaml:"raster_compression"`
+	// Flatten draws the strokes as jpeg images, of JPEGQuality from 1
+	// to 100
+	Flatten     bool `yaml:"flatten"`
+	JPEGQuality int  `yaml:"jpeg_quality"`
+	// Eink draws the strokes like the screen of the device, in greys
+	Eink bool `yaml:"eink"`
+	// OCR is the recognizer of the handwriting written as searchable
+	// text, and OCRLanguage its language
+	OCR         string `yaml:"ocr"`
+	OCRLanguage string `yaml:"ocr_language"`
+	// Watermark is a text stamped on the pages, {date} being the date
+	// of the export, WatermarkImage a png or jpeg image, and
+	// WatermarkOpacity their opacity
+	Watermark        string  `yaml:"watermark"`
+	WatermarkImage   string  `yaml:"watermark_image"`
+	WatermarkOpacity float64 `yaml:"watermark_opacity"`
+	// Layers and ExcludeLayers select the layers exported, by name or
+	// by number from 1, VisibleLayers leaves out the hidden ones and
+	// SplitLayers writes a pdf for each layer
+	Layers        []string `yaml:"layers"`
+	ExcludeLayers []string `yaml:"exclude_layers"`
+	VisibleLayers bool     `yaml:"visible_layers"`
+	SplitLayers   bool     `yaml:"split_layers"`
+	// Colors maps the colors of the pens (black, grey, white, blue,
+	// red... or highlighter) to the ones of the export, as #rrggbb,
+	// HighlightColors the ones of the highlighters (yellow, green,
+	// pink, grey, blue or red)
+	Colors          map[string]string `yaml:"colors"`
+	HighlightColors map[string]string `yaml:"highlight_colors"`
+	// Widths maps the brushes (ballpoint, fineliner, sharp pencil...
+	// or default) to the widths of their strokes
+	Widths map[string]PenWidth `yaml:"widths"`
+	// DPI is the resolution of the images
+	DPI int `yaml:"dpi"`
+	// SplitEvery writes the pdf exports in files of this number of pages
+	SplitEvery int `yaml:"split_every"`
+	// Renderer is the renderer of the pdf exports, the default one
+	// when empty
+	Renderer string `yaml:"renderer"`
+}
+
+// A PenWidth is the width of the strokes of a brush in device pixels:
+// its size (1.875, 2 or 2.125) times Scale, plus Offset.
+type PenWidth struct {
+	Scale  float64 `yaml:"scale"`
+	Offset float64 `yaml:"offset"`
+}
+
+// Profiles are the export profiles, the first one matching a document
+// is used.
+type Profiles []*ExportProfile
+
+// check checks the profiles, naming the unnamed ones by their number.
+func (profiles Profiles) check() error {
+	for i, p := range profiles {
+		if p.Name == "" {
+			p.Name = strconv.Itoa(i + 1)
+		}
+		if err := p.check(); err != nil {
+			return fmt.Errorf("invalid profile %s: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *ExportProfile) check() error {
+	// the formats are the ones of the export registry, checked by the
+	// commands using them
+	if p.Format == "" {
+		p.Format = FormatPDF
+	}
+	if p.DPI < 0 {
+		return fmt.Errorf("invalid dpi %d", p.DPI)
+	}
+	if p.SplitEvery < 0 {
+		return fmt.Errorf("invalid split_every %d", p.SplitEvery)
+	}
+	if p.RasterDPI < 0 {
+		return fmt.Errorf("invalid raster_dpi %d", p.RasterDPI)
+	}
+	if p.RasterCompression < 0 || p.RasterCompression > 9 {
+		return fmt.Errorf("invalid raster_compression %d", p.RasterCompression)
+	}
+	if p.JPEGQuality < 0 || p.JPEGQuality > 100 {
+		return fmt.Errorf("invalid jpeg_quality %d", p.JPEGQuality)
+	}
+	if p.WatermarkOpacity < 0 || p.WatermarkOpacity > 1 {
+		return fmt.Errorf("invalid watermark_opacity %v", p.WatermarkOpacity)
+	}
+	for i, folder := range p.Folders {
+		p.Folders[i] = cleanFolder(folder)
+	}
+	for brush, w := range p.Widths {
+		if _, ok := rmencoding.BrushTypeByName(brush); !ok && brush != "default" {
+			return fmt.Errorf("unknown brush %s in widths", brush)
+		}
+		if w.Scale < 0 {
+			return fmt.Errorf("invalid scale %v of the width of %s", w.Scale, brush)
+		}
+	}
+	if _, err := p.ColorMap(); err != nil {
+		return err
+	}
+	_, err := p.HighlightColorMap()
+	return err
+}
+
+func cleanFolder(folder string) string {
+	return "/" + strings.Trim(filepath.ToSlash(folder), "/")
+}
+
+// ColorMap returns the colors of the pens, by name.
+func (p *ExportProfile) ColorMap() (map[string]color.RGBA, error) {
+	return PenColorMap(p.Colors)
+}
+
+// HighlightColorMap returns the colors of the highlighters, by name.
+func (p *ExportProfile) HighlightColorMap() (map[string]color.RGBA, error) {
+	return HighlightColorMap(p.HighlightColors)
+}
+
+// highlightColors are the names of the colors of the highlighters.
+var highlightColors = []string{"yellow", "green", "pink", "grey", "blue", "red"}
+
+// PenColorMap reads the colors of the pens, by name.
+func PenColorMap(colors map[string]string) (map[string]color.RGBA, error) {
+	rgba := make(map[string]color.RGBA, len(colors))
+	for pen, value := range colors {
+		if _, ok := rmencoding.BrushColorByName(pen); !ok {
+			return nil, fmt.Errorf("unknown pen color %s", pen)
+		}
+		c, err := ParseColor(value)
+		if err != nil {
+			return nil, err
+		}
+		rgba[pen] = c
+	}
+	return rgba, nil
+}
+
+// HighlightColorMap reads the colors of the highlighters, by name.
+func HighlightColorMap(colors map[string]string) (map[string]color.RGBA, error) {
+	rgba := make(map[string]color.RGBA, len(colors))
+	for name, value := range colors {
+		if !slices.Contains(highlightColors, name) {
+			return nil, fmt.Errorf("unknown highlighter color %s, expected one of %s", name, strings.Join(highlightColors, ", "))
+		}
+		c, err := ParseColor(value)
+		if err != nil {
+			return nil, err
+		}
+		rgba[name] = c
+	}
+	return rgba, nil
+}
+
+// ParseColorList reads colors written as name=#rrggbb, separated by
+// commas, such as blue=#1f4e79,highlighter=#ff8000.
+func ParseColorList(list string) (map[string]string, error) {
+	colors := make(map[string]string)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid color %q, expected name=#rrggbb", item)
+		}
+		colors[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return colors, nil
+}
+
+// ParseColor reads a color written #rrggbb.
+func ParseColor(value string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(value, "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb", value)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// Match returns the first profile of a document in the remote folder
+// (as /a/b) or with one of the tags, nil when there is none.
+func (profiles Profiles) Match(folder string, tags []string) *ExportProfile {
+	folder = cleanFolder(folder)
+	for _, p := range profiles {
+		for _, f := range p.Folders {
+			if f == "/" || folder == f || strings.HasPrefix(folder, f+"/") {
+				return p
+			}
+		}
+		for _, tag := range p.Tags {
+			for _, t := range tags {
+				if strings.EqualFold(tag, t) {
+					return p
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Find returns the profile named name.
+func (profiles Profiles) Find(name string) (*ExportProfile, error) {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no profile named %s", name)
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"image/color"
+	"testing"
+)
+
+const testProfiles = `
+profiles:
+  - name: sketches
+    folders: [Sketches/]
+    format: png
+    dpi: 150
+  - name: papers
+    folders: [/Papers]
+    tags: [paper]
+    page_numbers: true
+    layers: [1, Sketch]
+    colors:
+      black: "#000080"
+      blue: "#0000ff"
+    highlight_colors:
+      yellow: "#ff8000"
+    widths:
+      fineliner: {scale: 2, offset: -2.5}
+`
+
+func TestParseProfiles(t *testing.T) {
+	settings, err := ParseSettings([]byte(testProfiles), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := settings.Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("expected 2 profiles, got %d", len(profiles))
+	}
+	if p := profiles[1]; p.Format != FormatPDF || !p.PageNumbers || p.DPI != 0 {
+		t.Errorf("unexpected profile %+v", p)
+	}
+	if layers := profiles[1].Layers; len(layers) != 2 || layers[0] != "1" || layers[1] != "Sketch" {
+		t.Errorf("unexpected layers %v", layers)
+	}
+	colors, err := profiles[1].ColorMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if colors["black"] != (color.RGBA{0, 0, 0x80, 0xff}) || colors["blue"] != (color.RGBA{0, 0, 0xff, 0xff}) {
+		t.Errorf("unexpected colors %v", colors)
+	}
+	highlights, err := profiles[1].HighlightColorMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if highlights["yellow"] != (color.RGBA{0xff, 0x80, 0, 0xff}) {
+		t.Errorf("unexpected highlight colors %v", highlights)
+	}
+	if w := profiles[1].Widths["fineliner"]; w != (PenWidth{Scale: 2, Offset: -2.5}) {
+		t.Errorf("unexpected width %+v", w)
+	}
+
+	for folder, want := range map[string]string{
+		"/Sketches":      "sketches",
+		"/Sketches/2024": "sketches",
+		"/Papers/":       "papers",
+		"/SketchesOld":   "",
+		"/Inbox":         "",
+	} {
+		got := ""
+		if p := profiles.Match(folder, nil); p != nil {
+			got = p.Name
+		}
+		if got != want {
+			t.Errorf("%s: expected %q, got %q", folder, want, got)
+		}
+	}
+	if p := profiles.Match("/Inbox", []string{"Paper"}); p == nil || p.Name != "papers" {
+		t.Errorf("expected the profile of the tag, got %v", p)
+	}
+
+	if _, err := profiles.Find("sketches"); err != nil {
+		t.Error(err)
+	}
+	if _, err := profiles.Find("missing"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestParseProfilesErrors(t *testing.T) {
+	for _, content := range []string{
+		"profiles:\n  - colors: {purple: \"#ff00ff\"}\n",
+		"profiles:\n  - colors: {black: \"blue\"}\n",
+		"profiles:\n  - highlight_colors: {black: \"#ff00ff\"}\n",
+		"profiles:\n  - unknown: true\n",
+		"profiles:\n  - widths: {crayon: {scale: 1}}\n",
+		"profiles:\n  - widths: {marker: {scale: -1}}\n",
+	} {
+		settings, err := ParseSettings([]byte(content), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := settings.Profiles(); err == nil {
+			t.Errorf("%q: expected an error", content)
+		}
+	}
+}
+
+func TestParseColorList(t *testing.T) {
+	colors, err := ParseColorList("blue=#1f4e79, highlighter = #ff8000,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(colors) != 2 || colors["blue"] != "#1f4e79" || colors["highlighter"] != "#ff8000" {
+		t.Errorf("unexpected colors %v", colors)
+	}
+	if _, err := ParseColorList("blue"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	settingsFile       = "settings.yaml"
+	settingsFileEnvVar = "RMAPI_SETTINGS"
+)
+
+// Settings are the settings of rmapi other than the tokens, a section
+// for each feature. The sections are read and checked when the feature
+// using them runs, a mistake in one of them doesn't stop the others.
+type Settings struct {
+	// dir is the directory of the file of the settings
+	dir      string
+	sections map[string]interface{}
+}
+
+// SettingsPath returns the path of the settings, set with
+// RMAPI_SETTINGS or else settings.yaml in the config directory.
+func SettingsPath() (string, error) {
+	if path, ok := os.LookupEnv(settingsFileEnvVar); ok {
+		return path, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, appName, settingsFile), nil
+}
+
+// LoadSettings reads the settings of a file, none are set when the file
+// doesn't exist.
+func LoadSettings(path string) (*Settings, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Settings{dir: filepath.Dir(path)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseSettings(content, filepath.Dir(path))
+}
+
+// ParseSettings reads the sections of a yaml document, dir being the
+// directory of the file.
+func ParseSettings(content []byte, dir string) (*Settings, error) {
+	s := &Settings{dir: dir}
+	if err := yaml.Unmarshal(content, &s.sections); err != nil {
+		return nil, fmt.Errorf("invalid settings: %v", err)
+	}
+	return s, nil
+}
+
+// section reads the section name into v, which is left as it is when
+// the section is missing.
+func (s *Settings) section(name string, v interface{}) error {
+	value, ok := s.sections[name]
+	if !ok || value == nil {
+		return nil
+	}
+	content, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := yaml.UnmarshalStrict(content, v); err != nil {
+		return fmt.Errorf("invalid %s settings: %v", name, err)
+	}
+	return nil
+}
+
+// Documents returns the settings of the new documents, of the documents
+// section.
+func (s *Settings) Documents() (*Documents, error) {
+	var d Documents
+	if err := s.section("documents", &d); err != nil {
+		return nil, err
+	}
+	if err := d.check(); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Profiles returns the export profiles, of the profiles section.
+func (s *Settings) Profiles() (Profiles, error) {
+	var profiles Profiles
+	if err := s.section("profiles", &profiles); err != nil {
+		return nil, err
+	}
+	if err := profiles.check(); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// Accounts returns the accounts of xfer, of the accounts section. The
+// relative paths of their token files are relative to the settings.
+func (s *Settings) Accounts() (Accounts, error) {
+	var accounts Accounts
+	if err := s.section("accounts", &accounts); err != nil {
+		return nil, err
+	}
+	if err := accounts.check(s.dir); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// Daemon returns the configuration of the daemon, of the daemon
+// section, nil when it is missing.
+func (s *Settings) Daemon() (*Daemon, error) {
+	var d *Daemon
+	if err := s.section("daemon", &d); err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, nil
+	}
+	if err := d.check(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.yaml")
+	settings, err := LoadSettings(path)
+	if err != nil {
+		t.Fatalf("a missing file should give no settings, got %v", err)
+	}
+	if d, err := settings.Documents(); err != nil || *d != (Documents{}) {
+		t.Errorf("expected the default documents settings, got %+v %v", d, err)
+	}
+
+	// the mistakes of the other sections don't matter
+	content := `
+documents:
+  pen: Ballpoint
+other:
+  unknown: [1, 2]
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if settings, err = LoadSettings(path); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := settings.Documents(); err != nil || d.Pen != "Ballpoint" {
+		t.Errorf("unexpected documents settings %+v %v", d, err)
+	}
+
+	if _, err := ParseSettings([]byte("documents: [\n"), ""); err == nil {
+		t.Error("expected an error for an invalid file")
+	}
+}
@@ -13,37 +13,69 @@ var UploadBlob string
 var DownloadBlob string
 var SyncComplete string
 
+// Hosts are the servers of the cloud of an account.
+type Hosts struct {
+	Auth string
+	Doc  string
+	Sync string
+}
+
+var currentHosts Hosts
+
 func init() {
-	docHost := "https://document-storage-production-dot-remarkable-production.appspot.com"
-	authHost := "https://webapp-prod.cloud.remarkable.engineering"
-	syncHost := "https://internal.cloud.remarkable.com"
+	SetHosts(DefaultHosts())
+}
+
+// DefaultHosts returns the hosts set with RMAPI_AUTH, RMAPI_DOC and
+// RMAPI_HOST, or else the ones of the reMarkable cloud.
+func DefaultHosts() Hosts {
+	hosts := Hosts{
+		Doc:  "https://document-storage-production-dot-remarkable-production.appspot.com",
+		Auth: "https://webapp-prod.cloud.remarkable.engineering",
+		Sync: "https://internal.cloud.remarkable.com",
+	}
 
 	host := os.Getenv("RMAPI_DOC")
 	if host != "" {
-		docHost = host
+		hosts.Doc = host
 	}
 
 	host = os.Getenv("RMAPI_AUTH")
 
 	if host != "" {
-		authHost = host
+		hosts.Auth = host
 	}
 	host = os.Getenv("RMAPI_HOST")
 
 	if host != "" {
-		authHost = host
-		docHost = host
-		syncHost = host
+		return SingleHost(host)
 	}
+	return hosts
+}
+
+// SingleHost returns the hosts of a cloud served by a single host, such
+// as rmfakecloud.
+func SingleHost(host string) Hosts {
+	return Hosts{Auth: host, Doc: host, Sync: host}
+}
+
+// CurrentHosts returns the hosts of the requests.
+func CurrentHosts() Hosts {
+	return currentHosts
+}
+
+// SetHosts changes the hosts of the requests sent from now on.
+func SetHosts(hosts Hosts) {
+	currentHosts = hosts
 
-	NewTokenDevice = authHost + "/token/json/2/device/new"
-	NewUserDevice = authHost + "/token/json/2/user/new"
-	ListDocs = docHost + "/document-storage/json/2/docs"
-	UpdateStatus = docHost + "/document-storage/json/2/upload/update-status"
-	UploadRequest = docHost + "/document-storage/json/2/upload/request"
-	DeleteEntry = docHost + "/document-storage/json/2/delete"
+	NewTokenDevice = hosts.Auth + "/token/json/2/device/new"
+	NewUserDevice = hosts.Auth + "/token/json/2/user/new"
+	ListDocs = hosts.Doc + "/document-storage/json/2/docs"
+	UpdateStatus = hosts.Doc + "/document-storage/json/2/upload/update-status"
+	UploadRequest = hosts.Doc + "/document-storage/json/2/upload/request"
+	DeleteEntry = hosts.Doc + "/document-storage/json/2/delete"
 
-	UploadBlob = syncHost + "/sync/v2/signed-urls/uploads"
-	DownloadBlob = syncHost + "/sync/v2/signed-urls/downloads"
-	SyncComplete = syncHost + "/sync/v2/sync-complete"
+	UploadBlob = hosts.Sync + "/sync/v2/signed-urls/uploads"
+	DownloadBlob = hosts.Sync + "/sync/v2/signed-urls/downloads"
+	SyncComplete = hosts.Sync + "/sync/v2/sync-complete"
 }
@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// a wednesday
+	from := time.Date(2025, 1, 15, 10, 30, 20, 0, time.UTC)
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * mon-fri", time.Date(2025, 1, 16, 2, 30, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2025, 1, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 mar *", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"5,10 22 29 2 *", time.Date(2028, 2, 29, 22, 5, 0, 0, time.UTC)},
+		// either the day of month or the day of week
+		{"0 0 20 * fri", time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		s, err := ParseSchedule(tc.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tc.expr, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.expr, tc.want, got)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"@often",
+	} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("expected an error for %q", expr)
+		}
+	}
+}
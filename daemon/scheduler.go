@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joagonca/rmapi/config"
+)
+
+// A Runner runs the command of a job.
+type Runner func(job *config.Job) error
+
+// JobStatus is the state of a job, as reported by the status endpoint.
+type JobStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Command  string    `json:"command"`
+	Running  bool      `json:"running"`
+	Runs     int       `json:"runs"`
+	Failures int       `json:"failures"`
+	LastRun  time.Time `json:"last_run,omitempty"`
+	// LastDuration is in seconds
+	LastDuration float64   `json:"last_duration"`
+	LastError    string    `json:"last_error,omitempty"`
+	NextRun      time.Time `json:"next_run"`
+}
+
+type job struct {
+	config   *config.Job
+	schedule *Schedule
+	status   JobStatus
+}
+
+// A Scheduler runs the jobs one at a time, when they are due. A job
+// due while another one runs starts right after it, the runs missed
+// meanwhile are skipped.
+type Scheduler struct {
+	run Runner
+	now func() time.Time
+
+	mu      sync.Mutex
+	jobs    []*job
+	started time.Time
+}
+
+// NewScheduler checks the schedules of the jobs.
+func NewScheduler(jobs []*config.Job, run Runner) (*Scheduler, error) {
+	s := &Scheduler{run: run, now: time.Now}
+	s.started = s.now()
+	for _, j := range jobs {
+		schedule, err := ParseSchedule(j.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		s.jobs = append(s.jobs, &job{
+			config:   j,
+			schedule: schedule,
+			status: JobStatus{
+				Name:     j.Name,
+				Schedule: j.Schedule,
+				Command:  j.Command,
+				NextRun:  schedule.Next(s.started),
+			},
+		})
+	}
+	return s, nil
+}
+
+// Status returns the state of the jobs, in the order of the config.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := make([]JobStatus, len(s.jobs))
+	for i, j := range s.jobs {
+		status[i] = j.status
+	}
+	return status
+}
+
+// Run runs the jobs until ctx is done. The job running then finishes
+// first.
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		next, ok := s.nextRun()
+		if !ok {
+			// no schedule matches anymore
+			<-ctx.Done()
+			return nil
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		s.runDue(ctx)
+	}
+}
+
+// nextRun returns the time of the next job.
+func (s *Scheduler) nextRun() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next time.Time
+	for _, j := range s.jobs {
+		t := j.status.NextRun
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// runDue runs the jobs which are due, the earliest first.
+func (s *Scheduler) runDue(ctx context.Context) {
+	s.mu.Lock()
+	var due []*job
+	now := s.now()
+	for _, j := range s.jobs {
+		if !j.status.NextRun.IsZero() && !j.status.NextRun.After(now) {
+			due = append(due, j)
+		}
+	}
+	sort.SliceStable(due, func(a, b int) bool {
+		return due[a].status.NextRun.Before(due[b].status.NextRun)
+	})
+	s.mu.Unlock()
+
+	for _, j := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		s.runJob(j)
+	}
+}
+
+func (s *Scheduler) runJob(j *job) {
+	s.mu.Lock()
+	start := s.now()
+	j.status.Running = true
+	s.mu.Unlock()
+
+	err := s.run(j.config)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	end := s.now()
+	j.status.Running = false
+	j.status.Runs++
+	j.status.LastRun = start
+	j.status.LastDuration = end.Sub(start).Seconds()
+	j.status.LastError = ""
+	if err != nil {
+		j.status.Failures++
+		j.status.LastError = err.Error()
+	}
+	j.status.NextRun = j.schedule.Next(end)
+}
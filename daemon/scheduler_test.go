@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joagonca/rmapi/config"
+)
+
+func TestRunDue(t *testing.T) {
+	jobs := []*config.Job{
+		{Name: "hourly", Schedule: "@hourly", Command: "mget /"},
+		{Name: "broken", Schedule: "*/10 * * * *", Command: "geta doc"},
+		{Name: "daily", Schedule: "@daily", Command: "device backup out"},
+	}
+	var ran []string
+	s, err := NewScheduler(jobs, func(job *config.Job) error {
+		ran = append(ran, job.Name)
+		if job.Name == "broken" {
+			return errors.New("failure")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	for _, j := range s.jobs {
+		j.status.NextRun = j.schedule.Next(now.Add(-time.Minute))
+	}
+
+	s.runDue(context.Background())
+	if strings.Join(ran, ",") != "hourly,broken" {
+		t.Errorf("unexpected runs %v", ran)
+	}
+
+	status := s.Status()
+	if status[0].Runs != 1 || status[0].LastError != "" || !status[0].NextRun.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected status %+v", status[0])
+	}
+	if status[1].Failures != 1 || status[1].LastError != "failure" || !status[1].NextRun.Equal(now.Add(10*time.Minute)) {
+		t.Errorf("unexpected status %+v", status[1])
+	}
+	if status[2].Runs != 0 {
+		t.Errorf("unexpected status %+v", status[2])
+	}
+	if next, _ := s.nextRun(); !next.Equal(now.Add(10 * time.Minute)) {
+		t.Errorf("unexpected next run %v", next)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, line := range []string{
+		`rmapi_job_runs_total{job="hourly"} 1`,
+		`rmapi_job_failures_total{job="broken"} 1`,
+		`rmapi_job_last_success{job="broken"} 0`,
+		`rmapi_job_last_success{job="daily"} 0`,
+	} {
+		if !strings.Contains(rec.Body.String(), line+"\n") {
+			t.Errorf("missing %s in the metrics:\n%s", line, rec.Body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+	if !strings.Contains(rec.Body.String(), `"last_error": "failure"`) {
+		t.Errorf("unexpected status:\n%s", rec.Body)
+	}
+}
+
+func TestNewSchedulerInvalid(t *testing.T) {
+	jobs := []*config.Job{{Name: "bad", Schedule: "every day", Command: "ls"}}
+	if _, err := NewScheduler(jobs, nil); err == nil {
+		t.Error("expected an error for an invalid schedule")
+	}
+}
@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type statusResponse struct {
+	Started time.Time   `json:"started"`
+	Jobs    []JobStatus `json:"jobs"`
+}
+
+// Handler serves the state of the jobs: /status as json, and /metrics
+// in the text format of Prometheus.
+func (s *Scheduler) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.serveStatus)
+	mux.HandleFunc("/metrics", s.serveMetrics)
+	return mux
+}
+
+func (s *Scheduler) serveStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(statusResponse{Started: s.started, Jobs: s.Status()})
+}
+
+// metrics are the metrics of the jobs, with their help
+var metrics = []struct {
+	name, kind, help string
+	value            func(JobStatus) float64
+}{
+	{"rmapi_job_runs_total", "counter", "Number of runs of the job.",
+		func(j JobStatus) float64 { return float64(j.Runs) }},
+	{"rmapi_job_failures_total", "counter", "Number of failed runs of the job.",
+		func(j JobStatus) float64 { return float64(j.Failures) }},
+	{"rmapi_job_running", "gauge", "Whether the job is running.",
+		func(j JobStatus) float64 { return boolMetric(j.Running) }},
+	{"rmapi_job_last_success", "gauge", "Whether the last run of the job succeeded.",
+		func(j JobStatus) float64 { return boolMetric(j.Runs > 0 && j.LastError == "") }},
+	{"rmapi_job_last_run_timestamp_seconds", "gauge", "Start of the last run of the job.",
+		func(j JobStatus) float64 { return unixMetric(j.LastRun) }},
+	{"rmapi_job_last_duration_seconds", "gauge", "Duration of the last run of the job.",
+		func(j JobStatus) float64 { return j.LastDuration }},
+	{"rmapi_job_next_run_timestamp_seconds", "gauge", "Next run of the job.",
+		func(j JobStatus) float64 { return unixMetric(j.NextRun) }},
+}
+
+func (s *Scheduler) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	jobs := s.Status()
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, j := range jobs {
+			fmt.Fprintf(w, "%s{job=%s} %s\n", m.name, quoteLabel(j.Name),
+				strconv.FormatFloat(m.value(j), 'g', -1, 64))
+		}
+	}
+}
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func unixMetric(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,258 @@
+package device
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joagonca/rmapi/log"
+)
+
+// filesPerTransfer limits the number of files requested at once
+// to keep the remote command line short.
+const filesPerTransfer = 200
+
+// markerFile marks the directories created by Backup. Files missing
+// from the tablet are only deleted from such directories.
+const markerFile = ".rmapi-backup"
+
+// A RemoteFile is a regular file of the tablet.
+type RemoteFile struct {
+	// Path is relative to the listed directory
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// BackupResult summarizes a backup.
+type BackupResult struct {
+	Copied    int
+	Unchanged int
+	Removed   int
+	Bytes     int64
+}
+
+// ListFiles lists the regular files found under a remote directory.
+func (d *Device) ListFiles(dir string) ([]RemoteFile, error) {
+	out, err := d.Run(fmt.Sprintf("cd %s && find . -type f -exec stat -c '%%s %%Y %%n' {} +", Quote(dir)))
+	if err != nil {
+		return nil, err
+	}
+	return parseFileList(out)
+}
+
+// parseFileList parses lines of "size mtime path".
+func parseFileList(out []byte) ([]RemoteFile, error) {
+	var files []RemoteFile
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected file entry: %s", line)
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected file size: %s", line)
+		}
+		mtime, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected file time: %s", line)
+		}
+		files = append(files, RemoteFile{
+			Path:    strings.TrimPrefix(fields[2], "./"),
+			Size:    size,
+			ModTime: time.Unix(mtime, 0),
+		})
+	}
+	return files, sc.Err()
+}
+
+// changedFiles returns the remote files missing locally or whose
+// size or modification time differ.
+func changedFiles(remote []RemoteFile, localDir string) []RemoteFile {
+	var changed []RemoteFile
+	for _, f := range remote {
+		stat, err := os.Stat(filepath.Join(localDir, filepath.FromSlash(f.Path)))
+		if err == nil && stat.Size() == f.Size && stat.ModTime().Unix() == f.ModTime.Unix() {
+			continue
+		}
+		changed = append(changed, f)
+	}
+	return changed
+}
+
+// Backup mirrors the xochitl data directory into localDir. Only new
+// and modified files are transferred. With prune, local files that no
+// longer exist on the tablet are removed, so the directory is a
+// snapshot that can be copied back to the tablet as is. Pruning is
+// refused unless the directory was created by Backup.
+func (d *Device) Backup(localDir string, prune bool) (BackupResult, error) {
+	result := BackupResult{}
+
+	marked, err := prepareBackupDir(localDir)
+	if err != nil {
+		return result, err
+	}
+	if prune && !marked {
+		return result, fmt.Errorf("%s was not created by a backup (no %s file), refusing to delete files", localDir, markerFile)
+	}
+
+	remote, err := d.ListFiles(XochitlDir)
+	if err != nil {
+		return result, err
+	}
+
+	changed := changedFiles(remote, localDir)
+	result.Unchanged = len(remote) - len(changed)
+
+	for start := 0; start < len(changed); start += filesPerTransfer {
+		end := start + filesPerTransfer
+		if end > len(changed) {
+			end = len(changed)
+		}
+		batch := changed[start:end]
+
+		n, err := d.fetchFiles(XochitlDir, batch, localDir)
+		if err != nil {
+			return result, err
+		}
+		result.Copied += len(batch)
+		result.Bytes += n
+	}
+
+	if !prune {
+		return result, nil
+	}
+	removed, err := removeStale(remote, localDir)
+	result.Removed = removed
+	return result, err
+}
+
+// prepareBackupDir creates the backup directory, marking it when it
+// is new or empty. It returns whether the directory is marked.
+func prepareBackupDir(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if len(entries) > 0 {
+		_, err := os.Stat(filepath.Join(dir, markerFile))
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, markerFile), nil, 0600); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// fetchFiles streams the files as a tar archive and extracts them.
+func (d *Device) fetchFiles(dir string, files []RemoteFile, localDir string) (int64, error) {
+	quoted := make([]string, len(files))
+	for i, f := range files {
+		quoted[i] = Quote(f.Path)
+	}
+	command := fmt.Sprintf("cd %s && tar cf - %s", Quote(dir), strings.Join(quoted, " "))
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(d.Stream(command, pw))
+	}()
+	defer pr.Close()
+
+	return extractTar(pr, localDir)
+}
+
+// extractTar extracts regular files keeping their modification time.
+func extractTar(r io.Reader, dest string) (int64, error) {
+	var total int64
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		fpath := filepath.Join(dest, filepath.FromSlash(h.Name))
+		// Check for ZipSlip like when unpacking archives
+		if !strings.HasPrefix(fpath, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return total, fmt.Errorf("%s: illegal file path", fpath)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(fpath), 0700); err != nil {
+			return total, err
+		}
+
+		out, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return total, err
+		}
+		n, err := io.Copy(out, tr)
+		out.Close()
+		total += n
+		if err != nil {
+			return total, err
+		}
+
+		if err := os.Chtimes(fpath, h.ModTime, h.ModTime); err != nil {
+			return total, err
+		}
+		log.Trace.Println("backed up", h.Name)
+	}
+}
+
+// removeStale deletes local files which are not on the tablet anymore.
+// An empty listing is most likely a failure on the tablet side, so
+// nothing is deleted then.
+func removeStale(remote []RemoteFile, localDir string) (int, error) {
+	if len(remote) == 0 {
+		return 0, errors.New("no files listed on the tablet, refusing to delete the backup")
+	}
+
+	existing := make(map[string]struct{}, len(remote)+1)
+	existing[filepath.Join(localDir, markerFile)] = struct{}{}
+	for _, f := range remote {
+		existing[filepath.Join(localDir, filepath.FromSlash(f.Path))] = struct{}{}
+	}
+
+	removed := 0
+	err := filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if _, ok := existing[path]; !ok {
+			log.Info.Println("removing", path)
+			removed++
+			return os.Remove(path)
+		}
+		return nil
+	})
+	return removed, err
+}
@@ -0,0 +1,98 @@
+package device
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseFileList(t *testing.T) {
+	out := []byte("12 1700000000 ./abc.metadata\n30 1700000001 ./abc/page 1.rm\n")
+	files, err := parseFileList(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	if files[1].Path != "abc/page 1.rm" || files[1].Size != 30 {
+		t.Errorf("wrong entry %+v", files[1])
+	}
+}
+
+func TestBackupDelta(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Unix(1700000000, 0)
+
+	// build the tar stream the tablet would send
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	content := []byte("content")
+	tw.WriteHeader(&tar.Header{Name: "doc/0.rm", Mode: 0600, Size: int64(len(content)), ModTime: mtime, Typeflag: tar.TypeReg})
+	tw.Write(content)
+	tw.Close()
+
+	if _, err := extractTar(&b, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := filepath.Join(dir, "old.metadata")
+	os.WriteFile(stale, []byte("{}"), 0600)
+	os.WriteFile(filepath.Join(dir, markerFile), nil, 0600)
+
+	remote := []RemoteFile{
+		{Path: "doc/0.rm", Size: int64(len(content)), ModTime: mtime},
+		{Path: "doc/1.rm", Size: 3, ModTime: mtime},
+	}
+
+	changed := changedFiles(remote, dir)
+	if len(changed) != 1 || changed[0].Path != "doc/1.rm" {
+		t.Errorf("only the missing file should be transferred, got %+v", changed)
+	}
+
+	if _, err := removeStale(nil, dir); err == nil {
+		t.Error("an empty listing should not remove anything")
+	}
+
+	removed, err := removeStale(remote, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 removed file, got %d", removed)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale file should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, markerFile)); err != nil {
+		t.Error("the marker file should be kept")
+	}
+}
+
+func TestPrepareBackupDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backup")
+
+	marked, err := prepareBackupDir(dir)
+	if err != nil || !marked {
+		t.Fatalf("a new directory should be marked, got %v %v", marked, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, markerFile)); err != nil {
+		t.Error("missing marker file")
+	}
+	os.WriteFile(filepath.Join(dir, "doc.metadata"), []byte("{}"), 0600)
+	if marked, err := prepareBackupDir(dir); err != nil || !marked {
+		t.Errorf("an existing backup should stay marked, got %v %v", marked, err)
+	}
+
+	other := t.TempDir()
+	os.WriteFile(filepath.Join(other, "notes.txt"), []byte("mine"), 0600)
+	if marked, err := prepareBackupDir(other); err != nil || marked {
+		t.Errorf("a directory with other files should not be marked, got %v %v", marked, err)
+	}
+	if _, err := os.Stat(filepath.Join(other, markerFile)); !os.IsNotExist(err) {
+		t.Error("the marker should not be added to a directory with other files")
+	}
+}
@@ -0,0 +1,156 @@
+// Package device manages a reMarkable tablet directly over SSH,
+// for the tasks the cloud API does not cover (templates, screens,
+// backups...).
+//
+// The system ssh and scp commands are used, so the usual ssh
+// configuration (keys, agent, ~/.ssh/config) applies. The tablet
+// is reached through the USB network by default.
+package device
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// defaultHost is the address of the tablet over USB
+	defaultHost = "10.11.99.1"
+	defaultUser = "root"
+
+	hostEnvVar     = "RMAPI_DEVICE_HOST"
+	userEnvVar     = "RMAPI_DEVICE_USER"
+	identityEnvVar = "RMAPI_DEVICE_IDENTITY"
+)
+
+// Well known paths on the tablet.
+const (
+	XochitlDir   = "/home/root/.local/share/remarkable/xochitl"
+	TemplatesDir = "/usr/share/remarkable/templates"
+	ScreensDir   = "/usr/share/remarkable"
+)
+
+// A Device is a tablet reachable over SSH.
+type Device struct {
+	Host string
+	User string
+	// Identity is an optional private key file
+	Identity string
+}
+
+// FromEnv creates a Device configured with the RMAPI_DEVICE_HOST,
+// RMAPI_DEVICE_USER and RMAPI_DEVICE_IDENTITY environment variables.
+func FromEnv() *Device {
+	d := &Device{
+		Host:     defaultHost,
+		User:     defaultUser,
+		Identity: os.Getenv(identityEnvVar),
+	}
+	if host, ok := os.LookupEnv(hostEnvVar); ok && host != "" {
+		d.Host = host
+	}
+	if user, ok := os.LookupEnv(userEnvVar); ok && user != "" {
+		d.User = user
+	}
+	return d
+}
+
+func (d *Device) target() string {
+	return d.User + "@" + d.Host
+}
+
+func (d *Device) sshArgs() []string {
+	args := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}
+	if d.Identity != "" {
+		args = append(args, "-i", d.Identity)
+	}
+	return args
+}
+
+// Run executes a command on the tablet and returns its output.
+func (d *Device) Run(command string) ([]byte, error) {
+	return d.RunWithInput(command, nil)
+}
+
+// RunWithInput executes a command on the tablet feeding it stdin.
+func (d *Device) RunWithInput(command string, stdin io.Reader) ([]byte, error) {
+	args := append(d.sshArgs(), d.target(), command)
+	cmd := exec.Command("ssh", args...)
+	cmd.Stdin = stdin
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ssh %s failed: %w\nStderr: %s", command, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// Stream executes a command on the tablet writing its output to w.
+func (d *Device) Stream(command string, w io.Writer) error {
+	args := append(d.sshArgs(), d.target(), command)
+	cmd := exec.Command("ssh", args...)
+
+	var stderr bytes.Buffer
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ssh %s failed: %w\nStderr: %s", command, err, stderr.String())
+	}
+	return nil
+}
+
+// Upload copies local files or directories to a remote directory.
+func (d *Device) Upload(remoteDir string, localPaths ...string) error {
+	args := append(d.sshArgs(), "-r")
+	args = append(args, localPaths...)
+	args = append(args, d.target()+":"+Quote(remoteDir))
+	return scp(args)
+}
+
+// Download copies a remote file or directory to a local path.
+func (d *Device) Download(remotePath, localPath string) error {
+	args := append(d.sshArgs(), "-r", d.target()+":"+Quote(remotePath), localPath)
+	return scp(args)
+}
+
+// WriteFile replaces the content of a remote file.
+func (d *Device) WriteFile(remotePath string, content []byte) error {
+	_, err := d.RunWithInput("cat > "+Quote(remotePath), bytes.NewReader(content))
+	return err
+}
+
+// ReadFile returns the content of a remote file.
+func (d *Device) ReadFile(remotePath string) ([]byte, error) {
+	return d.Run("cat " + Quote(remotePath))
+}
+
+// RestartUI restarts xochitl, the reading and writing application,
+// so that it picks up changed files.
+func (d *Device) RestartUI() error {
+	_, err := d.Run("systemctl restart xochitl")
+	return err
+}
+
+func scp(args []string) error {
+	cmd := exec.Command("scp", args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("scp failed: %w\nStderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
+// Quote quotes a string for the remote shell.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
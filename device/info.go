@@ -0,0 +1,88 @@
+package device
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Info describes the state of a tablet.
+type Info struct {
+	Model    string `json:"model"`
+	Firmware string `json:"firmware"`
+	Serial   string `json:"serial"`
+	// Battery is the charge in percent, -1 when unknown
+	Battery int `json:"battery"`
+	// FreeStorage and TotalStorage are in bytes
+	FreeStorage  int64 `json:"free_storage"`
+	TotalStorage int64 `json:"total_storage"`
+	Documents    int   `json:"documents"`
+}
+
+// infoScript prints key=value lines, missing values are left empty.
+var infoScript = strings.Join([]string{
+	"echo model=$(cat /sys/devices/soc0/machine 2>/dev/null)",
+	"echo firmware=$(grep -s REMARKABLE_RELEASE_VERSION /usr/share/remarkable/update.conf /etc/remarkable.conf | head -n1 | cut -d= -f2)",
+	"echo serial=$(cat /sys/devices/soc0/serial_number 2>/dev/null)",
+	"echo battery=$(cat /sys/class/power_supply/*/capacity 2>/dev/null | head -n1)",
+	"echo storage=$(df -k /home | tail -n1 | awk '{print $2, $4}')",
+	fmt.Sprintf("echo documents=$(ls %s | grep -c '\\.metadata$')", Quote(XochitlDir)),
+}, "; ")
+
+// Info collects the firmware version, serial number, battery level,
+// storage and number of documents of the tablet.
+func (d *Device) Info() (*Info, error) {
+	out, err := d.Run(infoScript)
+	if err != nil {
+		return nil, err
+	}
+	return parseInfo(out)
+}
+
+func parseInfo(out []byte) (*Info, error) {
+	info := &Info{Battery: -1}
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		key, value, found := strings.Cut(sc.Text(), "=")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		var err error
+		switch key {
+		case "model":
+			info.Model = value
+		case "firmware":
+			info.Firmware = value
+		case "serial":
+			info.Serial = value
+		case "battery":
+			info.Battery, err = strconv.Atoi(value)
+		case "storage":
+			fields := strings.Fields(value)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("unexpected storage value: %s", value)
+			}
+			if info.TotalStorage, err = strconv.ParseInt(fields[0], 10, 64); err == nil {
+				info.FreeStorage, err = strconv.ParseInt(fields[1], 10, 64)
+			}
+			// df reports kilobytes
+			info.TotalStorage *= 1024
+			info.FreeStorage *= 1024
+		case "documents":
+			info.Documents, err = strconv.Atoi(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unexpected %s value: %s", key, value)
+		}
+	}
+
+	return info, sc.Err()
+}
@@ -0,0 +1,47 @@
+package device
+
+import "testing"
+
+func TestParseInfo(t *testing.T) {
+	out := []byte(`model=reMarkable 2.0
+firmware=3.5.2.1807
+serial=RM110-000-00000
+battery=87
+storage=6946816 4128768
+documents=42
+`)
+
+	info, err := parseInfo(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Info{
+		Model:        "reMarkable 2.0",
+		Firmware:     "3.5.2.1807",
+		Serial:       "RM110-000-00000",
+		Battery:      87,
+		TotalStorage: 6946816 * 1024,
+		FreeStorage:  4128768 * 1024,
+		Documents:    42,
+	}
+	if *info != expected {
+		t.Errorf("got %+v, expected %+v", *info, expected)
+	}
+}
+
+func TestParseInfoMissingValues(t *testing.T) {
+	info, err := parseInfo([]byte("model=\nbattery=\ndocuments=0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Battery != -1 || info.Model != "" {
+		t.Errorf("missing values should be left unknown, got %+v", *info)
+	}
+}
+
+func TestParseInfoInvalid(t *testing.T) {
+	if _, err := parseInfo([]byte("battery=full\n")); err == nil {
+		t.Error("expected an error for an invalid battery level")
+	}
+}
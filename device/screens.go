@@ -0,0 +1,118 @@
+package device
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/png"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/util"
+)
+
+// Screens maps the screen names to the image files shown by the tablet.
+var Screens = map[string]string{
+	"suspended":    "suspended.png",
+	"poweroff":     "poweroff.png",
+	"starting":     "starting.png",
+	"rebooting":    "rebooting.png",
+	"batteryempty": "batteryempty.png",
+	"overheating":  "overheating.png",
+}
+
+// screenAliases are alternative names accepted for the screens.
+var screenAliases = map[string]string{
+	"suspend":  "suspended",
+	"sleep":    "suspended",
+	"splash":   "starting",
+	"shutdown": "poweroff",
+	"reboot":   "rebooting",
+	"battery":  "batteryempty",
+}
+
+// backupSuffix is appended to the original screens when they are replaced
+const backupSuffix = ".orig"
+
+// ScreenNames returns the sorted list of screen names.
+func ScreenNames() []string {
+	names := make([]string, 0, len(Screens))
+	for name := range Screens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ScreenFile resolves a screen name, or the name of a local image
+// such as suspend.png, into the file name used on the tablet.
+func ScreenFile(name string) (string, error) {
+	base, _ := util.DocPathToName(name)
+	base = strings.ToLower(base)
+	if alias, ok := screenAliases[base]; ok {
+		base = alias
+	}
+	if file, ok := Screens[base]; ok {
+		return file, nil
+	}
+	return "", fmt.Errorf("unknown screen %s, valid screens: %s", name, strings.Join(ScreenNames(), ", "))
+}
+
+// ValidateScreen checks that the image is a PNG of the size of the display.
+func ValidateScreen(content []byte) error {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("cannot read image: %v", err)
+	}
+	if format != "png" {
+		return fmt.Errorf("screens must be png images, got %s", format)
+	}
+	if cfg.Width != rm.Width || cfg.Height != rm.Height {
+		return fmt.Errorf("screens must be %dx%d pixels, got %dx%d", rm.Width, rm.Height, cfg.Width, cfg.Height)
+	}
+	return nil
+}
+
+// SetScreen replaces a screen with a local PNG image. The original
+// image is kept next to it the first time it is replaced.
+func (d *Device) SetScreen(screen, localPath string) error {
+	file, err := ScreenFile(screen)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	if err := ValidateScreen(content); err != nil {
+		return err
+	}
+
+	remote := path.Join(ScreensDir, file)
+	backup := Quote(remote + backupSuffix)
+	if _, err := d.Run(fmt.Sprintf("[ -e %s ] || cp %s %s", backup, Quote(remote), backup)); err != nil {
+		return err
+	}
+
+	return d.WriteFile(remote, content)
+}
+
+// RestoreScreen puts back the original image of a screen.
+func (d *Device) RestoreScreen(screen string) error {
+	file, err := ScreenFile(screen)
+	if err != nil {
+		return err
+	}
+
+	remote := path.Join(ScreensDir, file)
+	backup := Quote(remote + backupSuffix)
+	_, err = d.Run(fmt.Sprintf("[ -e %s ] && cp %s %s", backup, backup, Quote(remote)))
+	if err != nil {
+		return fmt.Errorf("no backup found for %s: %v", screen, err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package device
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestScreenFile(t *testing.T) {
+	for name, expected := range map[string]string{
+		"suspend.png":    "suspended.png",
+		"./Poweroff.png": "poweroff.png",
+		"starting":       "starting.png",
+	} {
+		file, err := ScreenFile(name)
+		if err != nil {
+			t.Error(err)
+		}
+		if file != expected {
+			t.Errorf("expected %s for %s, got %s", expected, name, file)
+		}
+	}
+
+	if _, err := ScreenFile("wallpaper.png"); err == nil {
+		t.Error("expected an error for an unknown screen")
+	}
+}
+
+func TestValidateScreen(t *testing.T) {
+	encode := func(w, h int) []byte {
+		var b bytes.Buffer
+		png.Encode(&b, image.NewGray(image.Rect(0, 0, w, h)))
+		return b.Bytes()
+	}
+
+	if err := ValidateScreen(encode(1404, 1872)); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateScreen(encode(100, 100)); err == nil {
+		t.Error("expected an error for a wrong size")
+	}
+}
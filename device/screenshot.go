@@ -0,0 +1,149 @@
+package device
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// A framebuffer describes the raw screen content of a tablet model.
+type framebuffer struct {
+	// Width and Height are the dimensions of the raw buffer
+	Width, Height int
+	// Stride is the number of pixels of a row including padding
+	Stride        int
+	BytesPerPixel int
+	// Rotated is set when the buffer is stored in landscape
+	Rotated bool
+	// Command prints the buffer on the tablet
+	Command string
+}
+
+func (fb framebuffer) size() int {
+	return fb.Stride * fb.Height * fb.BytesPerPixel
+}
+
+// The reMarkable 1 exposes a RGB565 framebuffer with rows of 1408 pixels.
+var rm1Framebuffer = framebuffer{
+	Width:         1404,
+	Height:        1872,
+	Stride:        1408,
+	BytesPerPixel: 2,
+}
+
+// The reMarkable 2 has no usable framebuffer device, the screen
+// content lives in the memory of xochitl right after the /dev/fb0
+// mapping, as 8 bits grayscale in landscape.
+var rm2Framebuffer = framebuffer{
+	Width:         1872,
+	Height:        1404,
+	Stride:        1872,
+	BytesPerPixel: 1,
+	Rotated:       true,
+}
+
+func init() {
+	rm1Framebuffer.Command = fmt.Sprintf("head -c %d /dev/fb0", rm1Framebuffer.size())
+
+	// dd reads whole pages of memory, the start of the buffer
+	// is then reached by skipping the extra bytes
+	size := rm2Framebuffer.size()
+	rm2Framebuffer.Command = strings.Join([]string{
+		"pid=$(pidof xochitl)",
+		`addr=$((0x$(grep -C1 /dev/fb0 /proc/$pid/maps | tail -n1 | sed 's/-.*$//') + 8))`,
+		"skip=$((addr / 4096))",
+		"offset=$((addr % 4096))",
+		fmt.Sprintf("count=$(((%d + offset) / 4096 + 1))", size),
+		fmt.Sprintf("dd if=/proc/$pid/mem bs=4096 skip=$skip count=$count 2>/dev/null | tail -c +$((offset + 1)) | head -c %d", size),
+	}, "; ")
+}
+
+// model returns the framebuffer layout of the tablet.
+func (d *Device) model() (framebuffer, error) {
+	out, err := d.Run("cat /sys/devices/soc0/machine")
+	if err != nil {
+		return framebuffer{}, err
+	}
+
+	machine := strings.TrimSpace(string(out))
+	switch {
+	case strings.HasPrefix(machine, "reMarkable 1"):
+		return rm1Framebuffer, nil
+	case strings.HasPrefix(machine, "reMarkable 2"):
+		return rm2Framebuffer, nil
+	}
+	return framebuffer{}, fmt.Errorf("unsupported tablet model: %s", machine)
+}
+
+// Screenshot captures the current content of the screen as a
+// portrait grayscale image.
+func (d *Device) Screenshot() (image.Image, error) {
+	fb, err := d.model()
+	if err != nil {
+		return nil, err
+	}
+
+	var raw bytes.Buffer
+	if err := d.Stream(fb.Command, &raw); err != nil {
+		return nil, err
+	}
+
+	return fb.decode(raw.Bytes())
+}
+
+// WriteScreenshot captures the screen and encodes it as PNG.
+func (d *Device) WriteScreenshot(w io.Writer) error {
+	img, err := d.Screenshot()
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// decode converts the raw framebuffer into a portrait image.
+func (fb framebuffer) decode(raw []byte) (image.Image, error) {
+	if len(raw) < fb.size() {
+		return nil, fmt.Errorf("incomplete framebuffer: got %d bytes, expected %d", len(raw), fb.size())
+	}
+
+	width, height := fb.Width, fb.Height
+	if fb.Rotated {
+		width, height = height, width
+	}
+	img := image.NewGray(image.Rect(0, 0, width, height))
+
+	for y := 0; y < fb.Height; y++ {
+		for x := 0; x < fb.Width; x++ {
+			i := (y*fb.Stride + x) * fb.BytesPerPixel
+
+			var c color.Gray
+			if fb.BytesPerPixel == 2 {
+				c = rgb565ToGray(uint16(raw[i]) | uint16(raw[i+1])<<8)
+			} else {
+				c = color.Gray{raw[i]}
+			}
+
+			if fb.Rotated {
+				// the buffer is the portrait screen turned clockwise
+				img.SetGray(y, fb.Width-1-x, c)
+			} else {
+				img.SetGray(x, y, c)
+			}
+		}
+	}
+
+	return img, nil
+}
+
+func rgb565ToGray(v uint16) color.Gray {
+	// expand the channels to 8 bits so that white stays white
+	r5, g6, b5 := uint8(v>>11&0x1f), uint8(v>>5&0x3f), uint8(v&0x1f)
+	r := r5<<3 | r5>>2
+	g := g6<<2 | g6>>4
+	b := b5<<3 | b5>>2
+	return color.GrayModel.Convert(color.RGBA{r, g, b, 0xff}).(color.Gray)
+}
@@ -0,0 +1,54 @@
+package device
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDecodeRGB565(t *testing.T) {
+	fb := framebuffer{Width: 2, Height: 2, Stride: 3, BytesPerPixel: 2}
+	// white, black, padding / black, white, padding
+	raw := []byte{
+		0xff, 0xff, 0, 0, 0x12, 0x34,
+		0, 0, 0xff, 0xff, 0x12, 0x34,
+	}
+
+	img, err := fb.decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray := img.(*image.Gray)
+	if gray.Bounds().Dx() != 2 || gray.Bounds().Dy() != 2 {
+		t.Fatalf("wrong size %v", gray.Bounds())
+	}
+	if gray.GrayAt(0, 0).Y != 0xff || gray.GrayAt(1, 0).Y != 0 || gray.GrayAt(1, 1).Y != 0xff {
+		t.Errorf("wrong pixels %v", gray.Pix)
+	}
+}
+
+func TestDecodeRotated(t *testing.T) {
+	fb := framebuffer{Width: 3, Height: 2, Stride: 3, BytesPerPixel: 1, Rotated: true}
+	raw := []byte{
+		1, 2, 3,
+		4, 5, 6,
+	}
+
+	img, err := fb.decode(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gray := img.(*image.Gray)
+	if gray.Bounds().Dx() != 2 || gray.Bounds().Dy() != 3 {
+		t.Fatalf("wrong size %v", gray.Bounds())
+	}
+	// the last column of the buffer is the top row of the screen
+	if gray.GrayAt(0, 0).Y != 3 || gray.GrayAt(1, 0).Y != 6 || gray.GrayAt(0, 2).Y != 1 {
+		t.Errorf("wrong pixels %v", gray.Pix)
+	}
+}
+
+func TestDecodeIncomplete(t *testing.T) {
+	if _, err := rm1Framebuffer.decode([]byte{0}); err == nil {
+		t.Error("expected an error for a truncated buffer")
+	}
+}
@@ -0,0 +1,127 @@
+package device
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/joagonca/rmapi/log"
+	"github.com/joagonca/rmapi/util"
+)
+
+const (
+	templatesFile = "templates.json"
+	// customCategory groups the installed templates in the template picker
+	customCategory = "Custom"
+	// defaultIconCode is the icon of the blank template
+	defaultIconCode = ""
+)
+
+// Template is an entry of templates.json.
+//
+// Unknown fields are kept untouched when the file is rewritten.
+type Template map[string]interface{}
+
+// TemplatesFile represents the templates.json file of the tablet.
+type TemplatesFile struct {
+	Templates []Template `json:"templates"`
+}
+
+// has tells whether a template with this filename is registered.
+func (f *TemplatesFile) has(filename string) bool {
+	for _, t := range f.Templates {
+		if name, ok := t["filename"].(string); ok && name == filename {
+			return true
+		}
+	}
+	return false
+}
+
+// Add registers a template unless one with the same filename exists.
+// It reports whether the template was added.
+func (f *TemplatesFile) Add(name, filename string) bool {
+	if f.has(filename) {
+		return false
+	}
+	f.Templates = append(f.Templates, Template{
+		"name":       name,
+		"filename":   filename,
+		"iconCode":   defaultIconCode,
+		"categories": []string{customCategory},
+	})
+	return true
+}
+
+// isTemplateFile tells whether the file can be used as a template.
+func isTemplateFile(name string) bool {
+	_, ext := util.DocPathToName(name)
+	return ext == "png" || ext == util.SVG
+}
+
+// InstallTemplates uploads the PNG and SVG files found in localDir to
+// the templates folder, registers them in templates.json and restarts
+// the UI. It returns the names of the installed templates.
+func (d *Device) InstallTemplates(localDir string) ([]string, error) {
+	entries, err := os.ReadDir(localDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files, names []string
+	for _, e := range entries {
+		if e.IsDir() || !isTemplateFile(e.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(localDir, e.Name()))
+		name, _ := util.DocPathToName(e.Name())
+		names = append(names, name)
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no png or svg template found")
+	}
+
+	jsonPath := path.Join(TemplatesDir, templatesFile)
+	content, err := d.ReadFile(jsonPath)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := TemplatesFile{}
+	if err := json.Unmarshal(content, &templates); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", jsonPath, err)
+	}
+
+	// keep a copy of the original file, only the first time
+	backup := Quote(jsonPath + ".bak")
+	if _, err := d.Run(fmt.Sprintf("[ -e %s ] || cp %s %s", backup, Quote(jsonPath), backup)); err != nil {
+		return nil, err
+	}
+
+	log.Info.Println("uploading templates", files)
+	if err := d.Upload(TemplatesDir, files...); err != nil {
+		return nil, err
+	}
+
+	var installed []string
+	for _, name := range names {
+		if templates.Add(name, name) {
+			installed = append(installed, name)
+		} else {
+			log.Info.Println("template already registered, file updated:", name)
+		}
+	}
+
+	out, err := json.MarshalIndent(templates, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	if err := d.WriteFile(jsonPath, out); err != nil {
+		return nil, err
+	}
+
+	return installed, d.RestartUI()
+}
@@ -0,0 +1,48 @@
+package device
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testTemplates = `{"templates": [
+  {"name": "Blank", "filename": "Blank", "iconCode": "", "categories": ["Creative"], "landscape": false}
+]}`
+
+func TestTemplatesFileAdd(t *testing.T) {
+	templates := TemplatesFile{}
+	if err := json.Unmarshal([]byte(testTemplates), &templates); err != nil {
+		t.Fatal(err)
+	}
+
+	if templates.Add("Blank", "Blank") {
+		t.Error("existing template should not be added twice")
+	}
+	if !templates.Add("Dots", "Dots") {
+		t.Error("new template should be added")
+	}
+
+	out, err := json.Marshal(templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reread := TemplatesFile{}
+	if err := json.Unmarshal(out, &reread); err != nil {
+		t.Fatal(err)
+	}
+	if len(reread.Templates) != 2 {
+		t.Fatalf("expected 2 templates, got %d", len(reread.Templates))
+	}
+	if _, ok := reread.Templates[0]["landscape"]; !ok {
+		t.Error("unknown fields should be preserved")
+	}
+}
+
+func TestIsTemplateFile(t *testing.T) {
+	for name, expected := range map[string]bool{"a.png": true, "b.SVG": true, "c.pdf": false, "d": false} {
+		if isTemplateFile(name) != expected {
+			t.Errorf("wrong result for %s", name)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package rm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A Handler receives the content of a .rm file while it is decoded.
+// Every callback is optional.
+//
+// Points are not accumulated in the Line given to StartLine, so
+// a page can be processed without holding all its points in memory.
+type Handler struct {
+	// StartLayer is called before the lines of a layer are read
+	StartLayer func(layer int, nbLines int) error
+	// StartLine is called with the attributes of a line, its Points are nil
+	StartLine func(layer int, line Line, nbPoints int) error
+	// Point is called for every point of the current line
+	Point func(p Point) error
+	// EndLine is called once all the points of a line have been read
+	EndLine func() error
+	// LayerInfo is called after StartLayer with the name and the
+	// visibility of a layer, which only the files of version 6 have
+	LayerInfo func(layer int, name string, hidden bool) error
+	// Text is called with the text typed on the page, which only the
+	// files of version 6 have
+	Text func(text Text) error
+}
+
+// ParseMode defines how a Decoder reacts to malformed content.
+type ParseMode int
+
+const (
+	// Strict fails on the first malformed element
+	Strict ParseMode = iota
+	// Lenient skips malformed elements, keeps what could be read
+	// and reports the problems as warnings
+	Lenient
+)
+
+// A Warning reports a problem found while decoding in lenient mode.
+type Warning struct {
+	// Offset is the position in bytes where the problem was found
+	Offset  int64
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("offset %d: %s", w.Offset, w.Message)
+}
+
+// A Decoder reads a .rm file from a stream.
+type Decoder struct {
+	// Mode is Strict by default
+	Mode     ParseMode
+	r        *reader
+	warnings []Warning
+	// the size of the page of the files of version 6
+	pageWidth, pageHeight int
+}
+
+// NewDecoder returns a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: newReader(r)}
+}
+
+// Warnings returns the problems skipped while decoding in lenient mode.
+func (d *Decoder) Warnings() []Warning {
+	return d.warnings
+}
+
+func (d *Decoder) warn(offset int64, format string, args ...interface{}) {
+	d.warnings = append(d.warnings, Warning{offset, fmt.Sprintf(format, args...)})
+}
+
+// truncated handles an error reading the stream: in lenient mode
+// decoding stops and keeps everything read so far.
+func (d *Decoder) truncated(err error) error {
+	if d.Mode == Lenient {
+		d.warn(d.r.offset, "file is truncated or corrupted, stopped decoding (%v)", err)
+		return nil
+	}
+	return err
+}
+
+// Version returns the version of the file once decoding has started.
+func (d *Decoder) Version() Version {
+	return d.r.version
+}
+
+// PageSize returns the size of the page in pixels once decoding has
+// ended. Only the files of version 6 whose page is larger than the
+// screen have one, it is 0 otherwise.
+func (d *Decoder) PageSize() (width, height int) {
+	return d.pageWidth, d.pageHeight
+}
+
+// Decode reads the whole file, calling the handler callbacks
+// in the order the elements are found.
+func (d *Decoder) Decode(h Handler) error {
+	r := d.r
+	if err := r.checkHeader(); err != nil {
+		return err
+	}
+	if r.version == V6 {
+		return d.decodeScene(h)
+	}
+
+	nbLayers, err := r.readNumber()
+	if err != nil {
+		return d.truncated(err)
+	}
+
+	for i := 0; i < int(nbLayers); i++ {
+		nbLines, err := r.readNumber()
+		if err != nil {
+			return d.truncated(err)
+		}
+
+		if h.StartLayer != nil {
+			if err := h.StartLayer(i, int(nbLines)); err != nil {
+				return err
+			}
+		}
+
+		for j := uint32(0); j < nbLines; j++ {
+			stop, err := d.decodeLine(i, h)
+			if err != nil || stop {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// decodeLine reads a line and its points. It reports whether
+// decoding must stop because the stream could not be read.
+func (d *Decoder) decodeLine(layer int, h Handler) (bool, error) {
+	r := d.r
+	offset := r.offset
+	line, err := r.readLineHeader()
+	if err != nil {
+		return true, d.truncated(err)
+	}
+
+	nbPoints, err := r.readNumber()
+	if err != nil {
+		return true, d.truncated(err)
+	}
+
+	// a line with an invalid size is skipped but its points are
+	// consumed to keep reading the following lines
+	valid := isFinite(float32(line.BrushSize))
+	if !valid {
+		if d.Mode == Strict {
+			return true, fmt.Errorf("Invalid brush size at offset %d", offset)
+		}
+		d.warn(offset, "skipped line with invalid brush size")
+	}
+
+	if valid && h.StartLine != nil {
+		if err := h.StartLine(layer, line, int(nbPoints)); err != nil {
+			return true, err
+		}
+	}
+
+	skipped := 0
+	var skippedOffset int64
+	for k := uint32(0); k < nbPoints; k++ {
+		pointOffset := r.offset
+		p, err := r.readPoint()
+		if err != nil {
+			// still close the partial line
+			if valid && h.EndLine != nil && d.Mode == Lenient {
+				if err := h.EndLine(); err != nil {
+					return true, err
+				}
+			}
+			return true, d.truncated(err)
+		}
+
+		if !isValidPoint(p) {
+			if d.Mode == Strict {
+				return true, fmt.Errorf("Invalid point at offset %d", pointOffset)
+			}
+			if skipped == 0 {
+				skippedOffset = pointOffset
+			}
+			skipped++
+			continue
+		}
+
+		if valid && h.Point != nil {
+			if err := h.Point(p); err != nil {
+				return true, err
+			}
+		}
+	}
+
+	if skipped > 0 {
+		d.warn(skippedOffset, "skipped %d invalid points", skipped)
+	}
+
+	if valid && h.EndLine != nil {
+		return false, h.EndLine()
+	}
+	return false, nil
+}
+
+// maxCoordinate is far beyond the page, larger values come from corrupted data.
+const maxCoordinate = 1e5
+
+func isFinite(f float32) bool {
+	return !math.IsNaN(float64(f)) && !math.IsInf(float64(f), 0)
+}
+
+func isValidPoint(p Point) bool {
+	for _, f := range []float32{p.X, p.Y, p.Speed, p.Direction, p.Width, p.Pressure} {
+		if !isFinite(f) {
+			return false
+		}
+	}
+	return math.Abs(float64(p.X)) < maxCoordinate && math.Abs(float64(p.Y)) < maxCoordinate
+}
+
+type reader struct {
+	r       *bufio.Reader
+	offset  int64
+	version Version
+	buf     [pointSize]byte
+}
+
+// pointSize is the size in bytes of an encoded point (6 float32).
+const pointSize = 24
+
+func newReader(r io.Reader) *reader {
+	// we set V5 as default but the real value is
+	// analysed when checking the header
+	return &reader{r: bufio.NewReader(r), version: V5}
+}
+
+func (r *reader) read(n int) ([]byte, error) {
+	b := r.buf[:n]
+	read, err := io.ReadFull(r.r, b)
+	r.offset += int64(read)
+	return b, err
+}
+
+func (r *reader) checkHeader() error {
+	buf := make([]byte, HeaderLen)
+
+	n, err := io.ReadFull(r.r, buf)
+	r.offset += int64(n)
+	if n != HeaderLen {
+		return fmt.Errorf("Wrong header size")
+	}
+	if err != nil {
+		return err
+	}
+
+	switch string(buf) {
+	case HeaderV5:
+		r.version = V5
+	case HeaderV3:
+		r.version = V3
+	case HeaderV6:
+		r.version = V6
+	default:
+		return fmt.Errorf("Unknown header")
+	}
+
+	return nil
+}
+
+func (r *reader) readNumber() (uint32, error) {
+	offset := r.offset
+	b, err := r.read(4)
+	if err != nil {
+		return 0, fmt.Errorf("Wrong number read at offset %d", offset)
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *reader) readFloat() (float32, error) {
+	nb, err := r.readNumber()
+	return math.Float32frombits(nb), err
+}
+
+func (r *reader) readLineHeader() (Line, error) {
+	var line Line
+	var err error
+	offset := r.offset
+
+	fields := []*uint32{(*uint32)(&line.BrushType), (*uint32)(&line.BrushColor), &line.Padding}
+	for _, f := range fields {
+		if *f, err = r.readNumber(); err != nil {
+			return line, fmt.Errorf("Failed to read line at offset %d", offset)
+		}
+	}
+
+	size, err := r.readFloat()
+	if err != nil {
+		return line, fmt.Errorf("Failed to read line at offset %d", offset)
+	}
+	line.BrushSize = BrushSize(size)
+
+	// this new attribute has been added in v5
+	if r.version == V5 {
+		if line.Unknown, err = r.readFloat(); err != nil {
+			return line, fmt.Errorf("Failed to read line at offset %d", offset)
+		}
+	}
+
+	return line, nil
+}
+
+func (r *reader) readPoint() (Point, error) {
+	offset := r.offset
+	b, err := r.read(pointSize)
+	if err != nil {
+		return Point{}, fmt.Errorf("Failed to read point at offset %d", offset)
+	}
+
+	f := func(i int) float32 {
+		return math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+
+	return Point{
+		X:         f(0),
+		Y:         f(1),
+		Speed:     f(2),
+		Direction: f(3),
+		Width:     f(4),
+		Pressure:  f(5),
+	}, nil
+}
@@ -0,0 +1,59 @@
+package rm
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDecoderStream(t *testing.T) {
+	f, err := os.Open("test_v5.rm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	lines, points := 0, 0
+	d := NewDecoder(f)
+	err = d.Decode(Handler{
+		StartLine: func(layer int, line Line, nbPoints int) error {
+			if line.Points != nil {
+				t.Error("points should not be accumulated")
+			}
+			lines++
+			return nil
+		},
+		Point: func(p Point) error {
+			points++
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Version() != V5 {
+		t.Error("wrong version parsed")
+	}
+
+	b, err := ioutil.ReadFile("test_v5.rm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm := New()
+	if err := rm.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLines, expectedPoints := 0, 0
+	for _, layer := range rm.Layers {
+		expectedLines += len(layer.Lines)
+		for _, line := range layer.Lines {
+			expectedPoints += len(line.Points)
+		}
+	}
+
+	if lines != expectedLines || points != expectedPoints {
+		t.Errorf("streamed %d lines and %d points, expected %d and %d", lines, points, expectedLines, expectedPoints)
+	}
+}
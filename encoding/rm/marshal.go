@@ -1,8 +1,94 @@
 package rm
 
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
 // MarshalBinary implements encoding.MarshalBinary for
-// transforming a Rm page into bytes
-// TODO
+// transforming a Rm page into bytes. The pages of version 6 are
+// written in version 5, which the firmwares 3.x still read.
 func (rm *Rm) MarshalBinary() (data []byte, err error) {
-	return nil, nil
+	version := rm.Version
+	if version == V6 {
+		version = V5
+	}
+	w := newWriter(version)
+	if err := w.writeHeader(); err != nil {
+		return nil, err
+	}
+
+	if err := w.writeNumber(uint32(len(rm.Layers))); err != nil {
+		return nil, err
+	}
+
+	for _, layer := range rm.Layers {
+		if err := w.writeNumber(uint32(len(layer.Lines))); err != nil {
+			return nil, err
+		}
+
+		for _, line := range layer.Lines {
+			if err := w.writeLine(line); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return w.Bytes(), nil
+}
+
+type writer struct {
+	bytes.Buffer
+	version Version
+}
+
+func newWriter(version Version) *writer {
+	return &writer{version: version}
+}
+
+func (w *writer) writeHeader() error {
+	switch w.version {
+	case V5:
+		w.WriteString(HeaderV5)
+	case V3:
+		w.WriteString(HeaderV3)
+	default:
+		return fmt.Errorf("Unknown version")
+	}
+	return nil
+}
+
+func (w *writer) writeNumber(nb uint32) error {
+	if err := binary.Write(w, binary.LittleEndian, nb); err != nil {
+		return fmt.Errorf("Wrong number written")
+	}
+	return nil
+}
+
+func (w *writer) writeLine(line Line) error {
+	fields := []interface{}{line.BrushType, line.BrushColor, line.Padding, line.BrushSize}
+
+	// this attribute only exists since v5
+	if w.version == V5 {
+		fields = append(fields, line.Unknown)
+	}
+
+	for _, f := range fields {
+		if err := binary.Write(w, binary.LittleEndian, f); err != nil {
+			return fmt.Errorf("Failed to write line")
+		}
+	}
+
+	if err := w.writeNumber(uint32(len(line.Points))); err != nil {
+		return err
+	}
+
+	for _, p := range line.Points {
+		if err := binary.Write(w, binary.LittleEndian, p); err != nil {
+			return fmt.Errorf("Failed to write point")
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,36 @@
+package rm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func testMarshalBinary(t *testing.T, fn string) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		t.Fatalf("can't open %s file", fn)
+	}
+
+	rm := New()
+	if err := rm.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := rm.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(b, out) {
+		t.Errorf("marshaled %s differs from the original (%d vs %d bytes)", fn, len(out), len(b))
+	}
+}
+
+func TestMarshalBinaryV5(t *testing.T) {
+	testMarshalBinary(t, "test_v5.rm")
+}
+
+func TestMarshalBinaryV3(t *testing.T) {
+	testMarshalBinary(t, "test_v3.rm")
+}
@@ -26,6 +26,10 @@
 //   - BinaryMarshaler
 //   - BinaryUnmarshaler
 //
+// For very dense pages, a Decoder is also provided. It streams the lines and
+// points of a .rm file to callbacks instead of materializing them, so a page
+// can be processed with a bounded amount of memory.
+//
 // The scope of this package is defined as just the encoding/decoding of the .rm format.
 // It will only deal with bytes and not files (one must take care of unzipping the archive
 // taken from the device, extracting and providing the content of .rm file as bytes).
@@ -44,12 +48,15 @@ type Version int
 const (
 	V3 Version = iota
 	V5
+	// V6 is the scene format of the firmwares 3.x
+	V6
 )
 
 // Header starting a .rm binary file. This can help recognizing a .rm file.
 const (
 	HeaderV3  = "reMarkable .lines file, version=3          "
 	HeaderV5  = "reMarkable .lines file, version=5          "
+	HeaderV6  = "reMarkable .lines file, version=6          "
 	HeaderLen = 43
 )
 
@@ -59,16 +66,73 @@ const (
 	Height int = 1872
 )
 
-// BrushColor defines the 3 colors of the brush.
+// PaperProWidth and PaperProHeight are the size in pixels of the screen
+// of the reMarkable Paper Pro.
+const (
+	PaperProWidth  int = 1620
+	PaperProHeight int = 2160
+)
+
+// BrushColor defines the colors of the brush.
 type BrushColor uint32
 
-// Mapping of the three colors.
+// Mapping of the colors: black, grey and white, and the ones added by
+// the firmwares 3.x for the tablets with a color screen and for the
+// highlighters.
 const (
 	Black BrushColor = 0
 	Grey  BrushColor = 1
 	White BrushColor = 2
+
+	Yellow      BrushColor = 3
+	Green       BrushColor = 4
+	Pink        BrushColor = 5
+	Blue        BrushColor = 6
+	Red         BrushColor = 7
+	GreyOverlap BrushColor = 8
+	// Highlight is the default color of the highlighter
+	Highlight   BrushColor = 9
+	LightGreen  BrushColor = 10
+	Cyan        BrushColor = 11
+	Magenta     BrushColor = 12
+	LightYellow BrushColor = 13
 )
 
+var colorNames = map[BrushColor]string{
+	Black:       "black",
+	Grey:        "grey",
+	White:       "white",
+	Yellow:      "yellow",
+	Green:       "green",
+	Pink:        "pink",
+	Blue:        "blue",
+	Red:         "red",
+	GreyOverlap: "grey_overlap",
+	Highlight:   "highlighter",
+	LightGreen:  "light_green",
+	Cyan:        "cyan",
+	Magenta:     "magenta",
+	LightYellow: "light_yellow",
+}
+
+// String returns the name of the color.
+func (c BrushColor) String() string {
+	if name, ok := colorNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (%d)", uint32(c))
+}
+
+// BrushColorByName returns the color of a name returned by String.
+func BrushColorByName(name string) (BrushColor, bool) {
+	for c, n := range colorNames {
+		if n == name {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
 // BrushType respresents the type of brush.
 //
 // The different types of brush are explained here:
@@ -95,8 +159,52 @@ const (
 	TiltPencilV5  BrushType = 14
 	BrushV5       BrushType = 12
 	HighlighterV5 BrushType = 18
+
+	// added by later versions of the software
+	Calligraphy BrushType = 21
 )
 
+var brushNames = map[BrushType]string{
+	BallPoint:     "ballpoint",
+	Marker:        "marker",
+	Fineliner:     "fineliner",
+	SharpPencil:   "sharp pencil",
+	TiltPencil:    "pencil",
+	Brush:         "brush",
+	Highlighter:   "highlighter",
+	Eraser:        "eraser",
+	EraseArea:     "erase area",
+	BallPointV5:   "ballpoint",
+	MarkerV5:      "marker",
+	FinelinerV5:   "fineliner",
+	SharpPencilV5: "sharp pencil",
+	TiltPencilV5:  "pencil",
+	BrushV5:       "brush",
+	HighlighterV5: "highlighter",
+	Calligraphy:   "calligraphy",
+}
+
+// String returns a human readable name of the brush,
+// v3 and v5 identifiers of a same brush share the same name.
+func (b BrushType) String() string {
+	if name, ok := brushNames[b]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (%d)", uint32(b))
+}
+
+// BrushTypeByName returns a brush of a name returned by String, the
+// v5 identifier when there are two.
+func BrushTypeByName(name string) (BrushType, bool) {
+	found, ok := BrushType(0), false
+	for b, n := range brushNames {
+		if n == name && (!ok || b > found) {
+			found, ok = b, true
+		}
+	}
+	return found, ok
+}
+
 // BrushSize represents the base brush sizes.
 type BrushSize float32
 
@@ -112,11 +220,57 @@ const (
 type Rm struct {
 	Version Version
 	Layers  []Layer
+	// PageWidth and PageHeight are the size of the page in pixels when
+	// it is larger than the screen, which only version 6 records
+	PageWidth, PageHeight int
+	// Text is the text typed on the page, only version 6 has one
+	Text *Text
+}
+
+// ParagraphStyle is the style of a paragraph of typed text.
+type ParagraphStyle int
+
+// The styles of the paragraphs, chosen on the device.
+const (
+	StyleBasic ParagraphStyle = iota
+	StylePlain
+	StyleHeading
+	StyleBold
+	StyleBullet
+	StyleBullet2
+	StyleCheckbox
+	StyleCheckboxChecked
+)
+
+// ScreenSize returns the size in pixels of the screen of the device
+// which wrote the page: the pages of the Paper Pro are wider than the
+// screen of the other tablets.
+func (rm *Rm) ScreenSize() (int, int) {
+	if rm != nil && rm.PageWidth > Width {
+		return PaperProWidth, PaperProHeight
+	}
+	return Width, Height
+}
+
+// A Paragraph is a paragraph of typed text, without its newline.
+type Paragraph struct {
+	Style ParagraphStyle
+	Text  string
+}
+
+// A Text is the text typed on a page, in a box of Width pixels whose
+// top left corner is at X, Y like the points of the lines.
+type Text struct {
+	X, Y, Width float32
+	Paragraphs  []Paragraph
 }
 
 // A Layer contains lines.
 type Layer struct {
-	Lines []Line
+	// Name and Hidden are only set by version 6
+	Name   string
+	Hidden bool
+	Lines  []Line
 }
 
 // A Line is composed of points.
@@ -126,7 +280,10 @@ type Line struct {
 	Padding    uint32
 	Unknown    float32
 	BrushSize  BrushSize
-	Points     []Point
+	// ARGB is the color of the inks of the Paper Pro, 0 when the
+	// stroke has none and BrushColor is used
+	ARGB   uint32
+	Points []Point
 }
 
 // A Point has coordinates.
@@ -143,9 +300,8 @@ type Point struct {
 // By mashaling an empty Rm page and exporting it
 // to the device, we should generate an empty page
 // as if it were created using the device itself.
-// TODO
 func New() *Rm {
-	return &Rm{}
+	return &Rm{Version: V5}
 }
 
 // String implements the fmt.Stringer interface
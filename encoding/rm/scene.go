@@ -0,0 +1,690 @@
+package rm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// The files of version 6, written by the firmwares 3.x, are a scene: a
+// sequence of blocks describing a tree of groups, the layers being the
+// groups at the root, and the items of the groups such as the lines,
+// and the text typed on the page.
+// The items are a CRDT sequence, the deleted ones are kept as
+// tombstones.
+//
+// The format has been described by Rick Lupton, whose rmscene
+// implementation has been followed here.
+// https://github.com/ricklupton/rmscene
+
+// The types of the blocks of a scene.
+const (
+	treeNodeBlock       = 0x02
+	sceneGroupItemBlock = 0x04
+	sceneLineItemBlock  = 0x05
+	sceneInfoBlock      = 0x0d
+)
+
+// The types of the values of the blocks, in the low bits of their tags.
+const (
+	tagID      = 0xf
+	tagLength4 = 0xc
+	tagByte8   = 0x8
+	tagByte4   = 0x4
+	tagByte1   = 0x1
+)
+
+// The types of the items of the groups.
+const (
+	groupItem = 0x02
+	lineItem  = 0x03
+)
+
+// maxBlockSize is far beyond the blocks of a page, larger sizes come
+// from corrupted data.
+const maxBlockSize = 1 << 26
+
+// crdtID identifies the nodes and the items of a scene.
+type crdtID struct {
+	part1 uint8
+	part2 uint64
+}
+
+// rootID is the node of the root of the scene, the parent of the layers.
+var rootID = crdtID{0, 1}
+
+// sceneNode is a group of the scene.
+type sceneNode struct {
+	name   string
+	hidden bool
+	// layer is the index of the layer of the group, -1 until the group
+	// has been found in a layer
+	layer int
+}
+
+// sceneDecoder holds the state of the decoding of a scene.
+type sceneDecoder struct {
+	*Decoder
+	h      Handler
+	nodes  map[crdtID]*sceneNode
+	layers int
+}
+
+// decodeScene reads the blocks of a file of version 6.
+func (d *Decoder) decodeScene(h Handler) error {
+	s := &sceneDecoder{Decoder: d, h: h, nodes: make(map[crdtID]*sceneNode)}
+	for {
+		offset := d.r.offset
+		header, err := d.r.readBlockHeader()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return d.truncated(err)
+		}
+		if header.size > maxBlockSize {
+			return d.truncated(fmt.Errorf("Invalid block size %d at offset %d", header.size, offset))
+		}
+
+		b := make([]byte, header.size)
+		n, err := io.ReadFull(d.r.r, b)
+		d.r.offset += int64(n)
+		if err != nil {
+			return d.truncated(fmt.Errorf("Failed to read block at offset %d", offset))
+		}
+
+		if err := s.decodeBlock(header, &blockReader{b: b, offset: offset + blockHeaderSize}); err != nil {
+			if _, ok := err.(blockError); !ok {
+				return err
+			}
+			if d.Mode == Strict {
+				return err
+			}
+			d.warn(offset, "skipped block of type %d: %v", header.kind, err)
+		}
+	}
+}
+
+func (s *sceneDecoder) decodeBlock(header blockHeader, r *blockReader) error {
+	switch header.kind {
+	case treeNodeBlock:
+		return s.decodeTreeNode(r)
+	case sceneGroupItemBlock:
+		return s.decodeGroupItem(r)
+	case sceneLineItemBlock:
+		return s.decodeLineItem(r, header.version)
+	case sceneInfoBlock:
+		return s.decodeSceneInfo(r)
+	case rootTextBlock:
+		return s.decodeRootText(r)
+	}
+	// the other blocks don't change the drawing
+	return nil
+}
+
+// node returns the group of an id, created when it is unknown.
+func (s *sceneDecoder) node(id crdtID) *sceneNode {
+	node, ok := s.nodes[id]
+	if !ok {
+		node = &sceneNode{layer: -1}
+		s.nodes[id] = node
+	}
+	return node
+}
+
+// decodeTreeNode reads the name and the visibility of a group.
+func (s *sceneDecoder) decodeTreeNode(r *blockReader) error {
+	id, err := r.taggedID(1)
+	if err != nil {
+		return err
+	}
+	node := s.node(id)
+
+	// the values are last-writer-wins registers, with a timestamp
+	if err := r.subblock(2, func() error {
+		if _, err := r.taggedID(1); err != nil {
+			return err
+		}
+		node.name, err = r.taggedString(2)
+		return err
+	}); err != nil {
+		return err
+	}
+	return r.subblock(3, func() error {
+		if _, err := r.taggedID(1); err != nil {
+			return err
+		}
+		visible, err := r.taggedBool(2)
+		node.hidden = !visible
+		return err
+	})
+}
+
+// sceneItem is the header shared by the items of the groups. value
+// is nil for the deleted items.
+type sceneItem struct {
+	parent crdtID
+	value  *blockReader
+}
+
+func (r *blockReader) sceneItem(kind uint8) (sceneItem, error) {
+	var item sceneItem
+	var err error
+	if item.parent, err = r.taggedID(1); err != nil {
+		return item, err
+	}
+	// the item and its neighbours in the sequence
+	for index := 2; index <= 4; index++ {
+		if _, err := r.taggedID(index); err != nil {
+			return item, err
+		}
+	}
+	deleted, err := r.taggedUint32(5)
+	if err != nil || deleted > 0 || r.remaining() == 0 {
+		return item, err
+	}
+
+	size, err := r.expectTag(6, tagLength4)
+	if err != nil {
+		return item, err
+	}
+	value, err := r.sub(size)
+	if err != nil {
+		return item, err
+	}
+	t, err := value.uint8()
+	if err != nil {
+		return item, err
+	}
+	if t != kind {
+		return item, r.errorf("unexpected item of type %d", t)
+	}
+	item.value = value
+	return item, nil
+}
+
+// decodeGroupItem reads a group: the groups at the root are the layers,
+// the other ones are drawn in the layer of their parent.
+func (s *sceneDecoder) decodeGroupItem(r *blockReader) error {
+	item, err := r.sceneItem(groupItem)
+	if err != nil || item.value == nil {
+		return err
+	}
+	id, err := item.value.taggedID(2)
+	if err != nil {
+		return err
+	}
+
+	node := s.node(id)
+	if node.layer >= 0 {
+		return nil
+	}
+	if item.parent != rootID {
+		node.layer, err = s.layer(item.parent)
+		return err
+	}
+	node.layer = s.layers
+	return s.startLayer(node)
+}
+
+// layer returns the layer of the items of a group. The items whose
+// group is unknown are drawn in the first layer.
+func (s *sceneDecoder) layer(parent crdtID) (int, error) {
+	if node, ok := s.nodes[parent]; ok && node.layer >= 0 {
+		return node.layer, nil
+	}
+	if s.layers == 0 {
+		return 0, s.startLayer(&sceneNode{})
+	}
+	return 0, nil
+}
+
+func (s *sceneDecoder) startLayer(node *sceneNode) error {
+	layer := s.layers
+	s.layers++
+	if s.h.StartLayer != nil {
+		if err := s.h.StartLayer(layer, 0); err != nil {
+			return err
+		}
+	}
+	if s.h.LayerInfo != nil {
+		return s.h.LayerInfo(layer, node.name, node.hidden)
+	}
+	return nil
+}
+
+// decodeLineItem reads a line and its points, the points of version 2
+// and later are packed in 14 bytes.
+func (s *sceneDecoder) decodeLineItem(r *blockReader, version uint8) error {
+	item, err := r.sceneItem(lineItem)
+	if err != nil || item.value == nil {
+		return err
+	}
+	v := item.value
+
+	var line Line
+	tool, err := v.taggedUint32(1)
+	if err != nil {
+		return err
+	}
+	color, err := v.taggedUint32(2)
+	if err != nil {
+		return err
+	}
+	size, err := v.taggedFloat64(3)
+	if err != nil {
+		return err
+	}
+	if _, err := v.taggedFloat32(4); err != nil {
+		return err
+	}
+	line.BrushType, line.BrushColor, line.BrushSize = BrushType(tool), BrushColor(color), BrushSize(size)
+	if !isFinite(float32(line.BrushSize)) {
+		return v.errorf("invalid brush size")
+	}
+
+	length, err := v.expectTag(5, tagLength4)
+	if err != nil {
+		return err
+	}
+	points, err := v.sub(length)
+	if err != nil {
+		return err
+	}
+	pointSize := 14
+	if version < 2 {
+		pointSize = 24
+	}
+	nbPoints := int(length) / pointSize
+	if line.ARGB, err = v.sceneLineColor(); err != nil {
+		return err
+	}
+
+	layer, err := s.layer(item.parent)
+	if err != nil {
+		return err
+	}
+	if s.h.StartLine != nil {
+		if err := s.h.StartLine(layer, line, nbPoints); err != nil {
+			return err
+		}
+	}
+	skipped := 0
+	for i := 0; i < nbPoints; i++ {
+		offset := points.offset + int64(points.pos)
+		// the subblock holds all the points
+		p, _ := points.scenePoint(version, s.center())
+		if !isValidPoint(p) {
+			if s.Mode == Strict {
+				return fmt.Errorf("Invalid point at offset %d", offset)
+			}
+			if skipped == 0 {
+				s.warn(offset, "skipped invalid points")
+			}
+			skipped++
+			continue
+		}
+		if s.h.Point != nil {
+			if err := s.h.Point(p); err != nil {
+				return err
+			}
+		}
+	}
+	if s.h.EndLine != nil {
+		return s.h.EndLine()
+	}
+	return nil
+}
+
+// sceneLineColor reads the color of the inks of the Paper Pro, after the
+// points of a line, and skips the other values.
+func (r *blockReader) sceneLineColor() (uint32, error) {
+	var argb uint32
+	for r.remaining() > 0 {
+		index, kind, err := r.tag()
+		if err != nil {
+			return 0, err
+		}
+		switch kind {
+		case tagID:
+			_, err = r.id()
+		case tagLength4:
+			var size uint32
+			if size, err = r.uint32(); err == nil {
+				_, err = r.bytes(int(size))
+			}
+		case tagByte8:
+			_, err = r.bytes(8)
+		case tagByte4:
+			var v uint32
+			v, err = r.uint32()
+			if index == 8 {
+				argb = v
+			}
+		case tagByte1:
+			_, err = r.uint8()
+		default:
+			err = r.errorf("unexpected value of type %d", kind)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return argb, nil
+}
+
+// center returns the x of the middle of the page, where the x axis of
+// the scene starts.
+func (s *sceneDecoder) center() float32 {
+	if s.pageWidth > Width {
+		return float32(PaperProWidth) / 2
+	}
+	return float32(Width) / 2
+}
+
+// decodeSceneInfo reads the size of the page, set when it is larger
+// than the screen.
+func (s *sceneDecoder) decodeSceneInfo(r *blockReader) error {
+	for r.remaining() > 0 {
+		index, kind, err := r.tag()
+		if err != nil {
+			return err
+		}
+		if kind != tagLength4 {
+			return r.errorf("unexpected value of type %d", kind)
+		}
+		size, err := r.uint32()
+		if err != nil {
+			return err
+		}
+		value, err := r.sub(size)
+		if err != nil {
+			return err
+		}
+		if index == 5 {
+			width, err := value.uint32()
+			if err != nil {
+				return err
+			}
+			height, err := value.uint32()
+			if err != nil {
+				return err
+			}
+			s.pageWidth, s.pageHeight = int(width), int(height)
+		}
+	}
+	return nil
+}
+
+// blockHeaderSize is the size in bytes of the header of a block.
+const blockHeaderSize = 8
+
+type blockHeader struct {
+	size    uint32
+	version uint8
+	kind    uint8
+}
+
+func (r *reader) readBlockHeader() (blockHeader, error) {
+	var header blockHeader
+	offset := r.offset
+	b, err := r.read(blockHeaderSize)
+	if err == io.EOF {
+		return header, err
+	}
+	if err != nil {
+		return header, fmt.Errorf("Failed to read block at offset %d", offset)
+	}
+	// an unknown byte and the minimal version come before the version
+	header.size = binary.LittleEndian.Uint32(b)
+	header.version = b[6]
+	header.kind = b[7]
+	return header, nil
+}
+
+// blockError reports malformed content in a block, the next blocks can
+// still be read.
+type blockError string
+
+func (e blockError) Error() string { return string(e) }
+
+// blockReader reads the values of a block.
+type blockReader struct {
+	b      []byte
+	pos    int
+	offset int64
+}
+
+func (r *blockReader) errorf(format string, args ...interface{}) error {
+	return blockError(fmt.Sprintf("%s at offset %d", fmt.Sprintf(format, args...), r.offset+int64(r.pos)))
+}
+
+func (r *blockReader) remaining() int {
+	return len(r.b) - r.pos
+}
+
+func (r *blockReader) bytes(n int) ([]byte, error) {
+	if n < 0 || n > r.remaining() {
+		return nil, r.errorf("unexpected end of block")
+	}
+	b := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// sub returns a reader of the next n bytes.
+func (r *blockReader) sub(n uint32) (*blockReader, error) {
+	offset := r.offset + int64(r.pos)
+	b, err := r.bytes(int(n))
+	if err != nil {
+		return nil, err
+	}
+	return &blockReader{b: b, offset: offset}, nil
+}
+
+func (r *blockReader) uint8() (uint8, error) {
+	b, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *blockReader) uint16() (uint16, error) {
+	b, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(b), nil
+}
+
+func (r *blockReader) uint32() (uint32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *blockReader) float32() (float32, error) {
+	n, err := r.uint32()
+	return math.Float32frombits(n), err
+}
+
+func (r *blockReader) float64() (float64, error) {
+	b, err := r.bytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+}
+
+func (r *blockReader) varuint() (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := r.uint8()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, r.errorf("invalid number")
+}
+
+func (r *blockReader) id() (crdtID, error) {
+	part1, err := r.uint8()
+	if err != nil {
+		return crdtID{}, err
+	}
+	part2, err := r.varuint()
+	return crdtID{part1, part2}, err
+}
+
+// tag reads the index and the type of the next value.
+func (r *blockReader) tag() (int, int, error) {
+	t, err := r.varuint()
+	return int(t >> 4), int(t & 0xf), err
+}
+
+// expectTag reads the tag of a value and, for the subblocks, their size.
+func (r *blockReader) expectTag(index, kind int) (uint32, error) {
+	i, k, err := r.tag()
+	if err != nil {
+		return 0, err
+	}
+	if i != index || k != kind {
+		return 0, r.errorf("expected value %d of type %d, got %d of type %d", index, kind, i, k)
+	}
+	if kind == tagLength4 {
+		return r.uint32()
+	}
+	return 0, nil
+}
+
+func (r *blockReader) taggedID(index int) (crdtID, error) {
+	if _, err := r.expectTag(index, tagID); err != nil {
+		return crdtID{}, err
+	}
+	return r.id()
+}
+
+func (r *blockReader) taggedBool(index int) (bool, error) {
+	if _, err := r.expectTag(index, tagByte1); err != nil {
+		return false, err
+	}
+	b, err := r.uint8()
+	return b != 0, err
+}
+
+func (r *blockReader) taggedUint32(index int) (uint32, error) {
+	if _, err := r.expectTag(index, tagByte4); err != nil {
+		return 0, err
+	}
+	return r.uint32()
+}
+
+func (r *blockReader) taggedFloat32(index int) (float32, error) {
+	if _, err := r.expectTag(index, tagByte4); err != nil {
+		return 0, err
+	}
+	return r.float32()
+}
+
+func (r *blockReader) taggedFloat64(index int) (float64, error) {
+	if _, err := r.expectTag(index, tagByte8); err != nil {
+		return 0, err
+	}
+	return r.float64()
+}
+
+// taggedString reads a string, stored in a subblock after its length.
+func (r *blockReader) taggedString(index int) (string, error) {
+	var s string
+	err := r.subblock(index, func() error {
+		n, err := r.varuint()
+		if err != nil {
+			return err
+		}
+		// whether the string is ascii
+		if _, err := r.uint8(); err != nil {
+			return err
+		}
+		b, err := r.bytes(int(n))
+		s = string(b)
+		return err
+	})
+	return s, err
+}
+
+// subblock reads the values of a subblock with read, the values it
+// doesn't read are skipped.
+func (r *blockReader) subblock(index int, read func() error) error {
+	size, err := r.expectTag(index, tagLength4)
+	if err != nil {
+		return err
+	}
+	if int(size) > r.remaining() {
+		return r.errorf("unexpected end of block")
+	}
+	end := r.pos + int(size)
+	if err := read(); err != nil {
+		return err
+	}
+	if r.pos > end {
+		return r.errorf("subblock overflow")
+	}
+	r.pos = end
+	return nil
+}
+
+// scenePoint reads a point. The x axis of the scene starts at the
+// middle of the page, center, the points are moved to the one of the
+// previous versions, starting at the left of the page.
+func (r *blockReader) scenePoint(version uint8, center float32) (Point, error) {
+	var p Point
+	var err error
+	if p.X, err = r.float32(); err != nil {
+		return p, err
+	}
+	if p.Y, err = r.float32(); err != nil {
+		return p, err
+	}
+	p.X += center
+
+	if version < 2 {
+		for _, f := range []*float32{&p.Speed, &p.Direction, &p.Width, &p.Pressure} {
+			if *f, err = r.float32(); err != nil {
+				return p, err
+			}
+		}
+		return p, nil
+	}
+
+	// the speed and the width are stored in quarters, the direction
+	// and the pressure on a byte
+	speed, err := r.uint16()
+	if err != nil {
+		return p, err
+	}
+	width, err := r.uint16()
+	if err != nil {
+		return p, err
+	}
+	direction, err := r.uint8()
+	if err != nil {
+		return p, err
+	}
+	pressure, err := r.uint8()
+	if err != nil {
+		return p, err
+	}
+	p.Speed = float32(speed) / 4
+	p.Width = float32(width) / 4
+	p.Direction = float32(direction) * 2 * math.Pi / 255
+	p.Pressure = float32(pressure) / 255
+	return p, nil
+}
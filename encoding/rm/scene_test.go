@@ -0,0 +1,337 @@
+package rm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// sceneWriter writes the values of the blocks of a scene.
+type sceneWriter struct {
+	bytes.Buffer
+}
+
+func (w *sceneWriter) varuint(v uint64) {
+	for v >= 0x80 {
+		w.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	w.WriteByte(byte(v))
+}
+
+func (w *sceneWriter) tag(index, kind int) {
+	w.varuint(uint64(index<<4 | kind))
+}
+
+func (w *sceneWriter) id(index int, id crdtID) {
+	w.tag(index, tagID)
+	w.WriteByte(id.part1)
+	w.varuint(id.part2)
+}
+
+func (w *sceneWriter) uint32(index int, v uint32) {
+	w.tag(index, tagByte4)
+	binary.Write(w, binary.LittleEndian, v)
+}
+
+func (w *sceneWriter) subblock(index int, write func(*sceneWriter)) {
+	var sub sceneWriter
+	write(&sub)
+	w.tag(index, tagLength4)
+	binary.Write(w, binary.LittleEndian, uint32(sub.Len()))
+	w.Write(sub.Bytes())
+}
+
+func (w *sceneWriter) block(kind, version uint8, write func(*sceneWriter)) {
+	var b sceneWriter
+	write(&b)
+	binary.Write(w, binary.LittleEndian, uint32(b.Len()))
+	w.Write([]byte{0, 1, version, kind})
+	w.Write(b.Bytes())
+}
+
+func (w *sceneWriter) item(parent, id crdtID, deleted uint32, kind uint8, value func(*sceneWriter)) {
+	w.id(1, parent)
+	w.id(2, id)
+	w.id(3, crdtID{})
+	w.id(4, crdtID{})
+	w.uint32(5, deleted)
+	if value != nil {
+		w.subblock(6, func(v *sceneWriter) {
+			v.WriteByte(kind)
+			value(v)
+		})
+	}
+}
+
+func (w *sceneWriter) layer(id crdtID, name string, visible bool) {
+	w.block(treeNodeBlock, 1, func(b *sceneWriter) {
+		b.id(1, id)
+		b.subblock(2, func(s *sceneWriter) {
+			s.id(1, crdtID{})
+			s.subblock(2, func(s *sceneWriter) {
+				s.varuint(uint64(len(name)))
+				s.WriteByte(1)
+				s.WriteString(name)
+			})
+		})
+		b.subblock(3, func(s *sceneWriter) {
+			s.id(1, crdtID{})
+			s.tag(2, tagByte1)
+			if visible {
+				s.WriteByte(1)
+			} else {
+				s.WriteByte(0)
+			}
+		})
+	})
+	w.block(sceneGroupItemBlock, 1, func(b *sceneWriter) {
+		b.item(rootID, crdtID{1, id.part2}, 0, groupItem, func(v *sceneWriter) {
+			v.id(2, id)
+		})
+	})
+}
+
+func (w *sceneWriter) line(parent crdtID, deleted uint32, version uint8, tool BrushType, points ...Point) {
+	w.block(sceneLineItemBlock, version, func(b *sceneWriter) {
+		var value func(*sceneWriter)
+		if deleted == 0 {
+			value = func(v *sceneWriter) {
+				v.uint32(1, uint32(tool))
+				v.uint32(2, uint32(Blue))
+				v.tag(3, tagByte8)
+				binary.Write(v, binary.LittleEndian, float64(Medium))
+				v.uint32(4, 0)
+				v.subblock(5, func(p *sceneWriter) {
+					for _, point := range points {
+						x := point.X - float32(Width)/2
+						if version < 2 {
+							binary.Write(p, binary.LittleEndian, []float32{x, point.Y, point.Speed, point.Direction, point.Width, point.Pressure})
+							continue
+						}
+						binary.Write(p, binary.LittleEndian, []float32{x, point.Y})
+						binary.Write(p, binary.LittleEndian, []uint16{uint16(point.Speed * 4), uint16(point.Width * 4)})
+						p.WriteByte(byte(math.Round(float64(point.Direction) * 255 / (2 * math.Pi))))
+						p.WriteByte(byte(math.Round(float64(point.Pressure) * 255)))
+					}
+				})
+				v.id(6, crdtID{1, 99})
+			}
+		}
+		b.item(parent, crdtID{1, 50}, deleted, lineItem, value)
+	})
+}
+
+func testScene() []byte {
+	var w sceneWriter
+	w.WriteString(HeaderV6)
+	// an unknown block is skipped
+	w.block(0x09, 1, func(b *sceneWriter) { b.WriteString("author") })
+	w.block(sceneInfoBlock, 1, func(b *sceneWriter) {
+		b.subblock(1, func(s *sceneWriter) {
+			s.id(1, crdtID{})
+			s.id(2, crdtID{0, 11})
+		})
+		b.subblock(5, func(s *sceneWriter) {
+			binary.Write(s, binary.LittleEndian, []uint32{1404, 2600})
+		})
+	})
+	w.layer(crdtID{0, 11}, "Layer 1", true)
+	w.layer(crdtID{0, 12}, "Sketch", false)
+
+	point := Point{X: 700, Y: 300, Speed: 2.5, Direction: float32(64) * 2 * math.Pi / 255, Width: 3.25, Pressure: 128.0 / 255}
+	w.line(crdtID{0, 12}, 0, 2, FinelinerV5, point, Point{X: 800, Y: 400, Width: 3})
+	w.line(crdtID{0, 11}, 1, 2, FinelinerV5)
+	w.line(crdtID{0, 11}, 0, 1, Calligraphy, point)
+	return w.Bytes()
+}
+
+func TestUnmarshalScene(t *testing.T) {
+	page := New()
+	if err := page.UnmarshalBinary(testScene()); err != nil {
+		t.Fatal(err)
+	}
+
+	if page.Version != V6 {
+		t.Errorf("wrong version parsed %d", page.Version)
+	}
+	if page.PageWidth != 1404 || page.PageHeight != 2600 {
+		t.Errorf("wrong page size %dx%d", page.PageWidth, page.PageHeight)
+	}
+	if len(page.Layers) != 2 {
+		t.Fatalf("expected 2 layers, got %d", len(page.Layers))
+	}
+	if l := page.Layers[0]; l.Name != "Layer 1" || l.Hidden || len(l.Lines) != 1 {
+		t.Errorf("unexpected first layer %+v", l)
+	}
+	if l := page.Layers[1]; l.Name != "Sketch" || !l.Hidden || len(l.Lines) != 1 {
+		t.Errorf("unexpected second layer %+v", l)
+	}
+
+	want := Point{X: 700, Y: 300, Speed: 2.5, Direction: float32(64) * 2 * math.Pi / 255, Width: 3.25, Pressure: 128.0 / 255}
+	for _, line := range []Line{page.Layers[1].Lines[0], page.Layers[0].Lines[0]} {
+		if line.BrushColor != Blue || line.BrushSize != Medium {
+			t.Errorf("unexpected line %+v", line)
+		}
+		if len(line.Points) == 0 || line.Points[0] != want {
+			t.Errorf("%s: expected %+v, got %+v", line.BrushType, want, line.Points)
+		}
+	}
+	if n := len(page.Layers[1].Lines[0].Points); n != 2 {
+		t.Errorf("expected 2 points, got %d", n)
+	}
+
+	// the pages are written in version 5
+	b, err := page.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:HeaderLen]) != HeaderV5 {
+		t.Errorf("unexpected header %q", b[:HeaderLen])
+	}
+}
+
+func TestUnmarshalSceneLenient(t *testing.T) {
+	scene := testScene()
+	// corrupt the tag of the last line
+	last := bytes.LastIndex(scene, []byte{0x1f, 0, 11})
+	scene[last] = 0x2f
+
+	if err := New().UnmarshalBinary(scene); err == nil {
+		t.Error("strict mode should fail on a corrupted block")
+	}
+
+	page := New()
+	warnings, err := page.UnmarshalLenient(scene)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", warnings)
+	}
+	if len(page.Layers) != 2 || len(page.Layers[0].Lines) != 0 || len(page.Layers[1].Lines) != 1 {
+		t.Errorf("lenient mode should keep the other lines, got %+v", page.Layers)
+	}
+}
+
+func (w *sceneWriter) textItem(id, left, right crdtID, deleted uint32, text string) {
+	w.subblock(0, func(s *sceneWriter) {
+		s.id(2, id)
+		s.id(3, left)
+		s.id(4, right)
+		s.uint32(5, deleted)
+		if text != "" {
+			s.subblock(6, func(v *sceneWriter) {
+				v.varuint(uint64(len(text)))
+				v.WriteByte(1)
+				v.WriteString(text)
+			})
+		}
+	})
+}
+
+func TestUnmarshalSceneText(t *testing.T) {
+	var w sceneWriter
+	w.WriteString(HeaderV6)
+	w.block(rootTextBlock, 1, func(b *sceneWriter) {
+		b.id(1, crdtID{0, 0})
+		b.subblock(2, func(s *sceneWriter) {
+			s.subblock(1, func(s *sceneWriter) {
+				s.subblock(1, func(s *sceneWriter) {
+					// the items are not in the order of the text
+					s.varuint(3)
+					s.textItem(crdtID{1, 30}, crdtID{1, 20}, crdtID{}, 0, " world")
+					s.textItem(crdtID{1, 40}, crdtID{1, 15}, crdtID{1, 16}, 3, "")
+					s.textItem(crdtID{1, 10}, crdtID{}, crdtID{}, 0, "Title\nhello")
+				})
+			})
+			s.subblock(2, func(s *sceneWriter) {
+				s.subblock(1, func(s *sceneWriter) {
+					s.varuint(2)
+					for _, format := range []struct {
+						id    crdtID
+						style ParagraphStyle
+					}{{crdtID{}, StyleHeading}, {crdtID{1, 15}, StyleBullet}} {
+						s.WriteByte(format.id.part1)
+						s.varuint(format.id.part2)
+						s.id(1, crdtID{1, 1})
+						s.subblock(2, func(v *sceneWriter) {
+							v.Write([]byte{17, byte(format.style)})
+						})
+					}
+				})
+			})
+		})
+		b.subblock(3, func(s *sceneWriter) {
+			binary.Write(s, binary.LittleEndian, []float64{-468, 94})
+		})
+		b.tag(4, tagByte4)
+		binary.Write(b, binary.LittleEndian, float32(936))
+	})
+
+	page := New()
+	if err := page.UnmarshalBinary(w.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if page.Text == nil {
+		t.Fatal("the text has not been read")
+	}
+	if x := page.Text; x.X != 234 || x.Y != 94 || x.Width != 936 {
+		t.Errorf("unexpected text box %v,%v %v", x.X, x.Y, x.Width)
+	}
+	want := []Paragraph{{StyleHeading, "Title"}, {StyleBullet, "hello world"}}
+	if len(page.Text.Paragraphs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, page.Text.Paragraphs)
+	}
+	for i, p := range want {
+		if page.Text.Paragraphs[i] != p {
+			t.Errorf("expected %v, got %v", p, page.Text.Paragraphs[i])
+		}
+	}
+}
+
+func TestUnmarshalScenePaperPro(t *testing.T) {
+	var w sceneWriter
+	w.WriteString(HeaderV6)
+	w.block(sceneInfoBlock, 1, func(b *sceneWriter) {
+		b.subblock(5, func(s *sceneWriter) {
+			binary.Write(s, binary.LittleEndian, []uint32{uint32(PaperProWidth), uint32(PaperProHeight)})
+		})
+	})
+	w.block(sceneLineItemBlock, 2, func(b *sceneWriter) {
+		b.item(crdtID{0, 11}, crdtID{1, 50}, 0, lineItem, func(v *sceneWriter) {
+			v.uint32(1, uint32(FinelinerV5))
+			v.uint32(2, uint32(Highlight))
+			v.tag(3, tagByte8)
+			binary.Write(v, binary.LittleEndian, float64(Medium))
+			v.uint32(4, 0)
+			v.subblock(5, func(p *sceneWriter) {
+				binary.Write(p, binary.LittleEndian, []float32{0, 100})
+				binary.Write(p, binary.LittleEndian, []uint16{0, 8})
+				p.Write([]byte{0, 0})
+			})
+			v.id(6, crdtID{1, 99})
+			v.uint32(8, 0xff2a7fff)
+		})
+	})
+
+	page := New()
+	if err := page.UnmarshalBinary(w.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if width, height := page.ScreenSize(); width != PaperProWidth || height != PaperProHeight {
+		t.Errorf("unexpected screen %dx%d", width, height)
+	}
+	if len(page.Layers) != 1 || len(page.Layers[0].Lines) != 1 {
+		t.Fatalf("unexpected layers %+v", page.Layers)
+	}
+	line := page.Layers[0].Lines[0]
+	if line.ARGB != 0xff2a7fff {
+		t.Errorf("unexpected color %x", line.ARGB)
+	}
+	// the middle of the wider page
+	if p := line.Points[0]; p.X != float32(PaperProWidth)/2 {
+		t.Errorf("unexpected point %+v", p)
+	}
+}
@@ -0,0 +1,248 @@
+package rm
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// rootTextBlock holds the text typed on the page.
+const rootTextBlock = 0x07
+
+// endMarker stands for the ends of a CRDT sequence, the zero id, when
+// it is on the right of an item.
+var endMarker = crdtID{math.MaxUint8, math.MaxUint64}
+
+// textChar is a character of the typed text, deleted characters are
+// kept to order the others.
+type textChar struct {
+	id, left, right crdtID
+	// c is the character, 0 for the deleted ones and the inline formats
+	c rune
+}
+
+// decodeRootText reads the typed text: a CRDT sequence of strings and
+// the styles of the paragraphs, keyed by the newline starting them.
+func (s *sceneDecoder) decodeRootText(r *blockReader) error {
+	if _, err := r.taggedID(1); err != nil {
+		return err
+	}
+
+	var chars []textChar
+	styles := map[crdtID]ParagraphStyle{}
+	if err := r.subblock(2, func() error {
+		if err := r.subblock(1, func() error {
+			return r.subblock(1, func() error {
+				n, err := r.varuint()
+				if err != nil {
+					return err
+				}
+				for i := uint64(0); i < n; i++ {
+					if chars, err = r.textItem(chars); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}); err != nil {
+			return err
+		}
+		return r.subblock(2, func() error {
+			return r.subblock(1, func() error {
+				n, err := r.varuint()
+				if err != nil {
+					return err
+				}
+				for i := uint64(0); i < n; i++ {
+					id, err := r.id()
+					if err != nil {
+						return err
+					}
+					if _, err := r.taggedID(1); err != nil {
+						return err
+					}
+					if err := r.subblock(2, func() error {
+						if _, err := r.uint8(); err != nil {
+							return err
+						}
+						style, err := r.uint8()
+						styles[id] = ParagraphStyle(style)
+						return err
+					}); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	}); err != nil {
+		return err
+	}
+
+	var x, y float64
+	if err := r.subblock(3, func() error {
+		var err error
+		if x, err = r.float64(); err != nil {
+			return err
+		}
+		y, err = r.float64()
+		return err
+	}); err != nil {
+		return err
+	}
+	width, err := r.taggedFloat32(4)
+	if err != nil {
+		return err
+	}
+
+	ordered, err := orderText(chars, r)
+	if err != nil {
+		return err
+	}
+	text := Text{X: float32(x) + s.center(), Y: float32(y), Width: width}
+	style, ok := styles[crdtID{}]
+	if !ok {
+		style = StylePlain
+	}
+	var paragraph strings.Builder
+	for _, c := range ordered {
+		if c.c != '\n' {
+			if c.c != 0 {
+				paragraph.WriteRune(c.c)
+			}
+			continue
+		}
+		text.Paragraphs = append(text.Paragraphs, Paragraph{Style: style, Text: paragraph.String()})
+		paragraph.Reset()
+		if style, ok = styles[c.id]; !ok {
+			style = StylePlain
+		}
+	}
+	text.Paragraphs = append(text.Paragraphs, Paragraph{Style: style, Text: paragraph.String()})
+
+	if s.h.Text != nil {
+		return s.h.Text(text)
+	}
+	return nil
+}
+
+// textItem reads an item of the text and appends its characters, each
+// one having the next id.
+func (r *blockReader) textItem(chars []textChar) ([]textChar, error) {
+	err := r.subblock(0, func() error {
+		var ids [3]crdtID
+		for i := range ids {
+			var err error
+			if ids[i], err = r.taggedID(i + 2); err != nil {
+				return err
+			}
+		}
+		deleted, err := r.taggedUint32(5)
+		if err != nil {
+			return err
+		}
+
+		value := make([]rune, deleted)
+		if deleted == 0 && r.remaining() > 0 {
+			if err := r.subblock(6, func() error {
+				n, err := r.varuint()
+				if err != nil {
+					return err
+				}
+				if _, err := r.uint8(); err != nil {
+					return err
+				}
+				b, err := r.bytes(int(n))
+				value = []rune(string(b))
+				if err == nil && len(value) == 0 {
+					// an inline format takes a character
+					value = []rune{0}
+				}
+				return err
+			}); err != nil {
+				return err
+			}
+		}
+
+		id, left := ids[0], ids[1]
+		for i, c := range value {
+			char := textChar{id: crdtID{id.part1, id.part2 + uint64(i)}, left: left, c: c}
+			if i == len(value)-1 {
+				char.right = ids[2]
+			} else {
+				char.right = crdtID{id.part1, char.id.part2 + 1}
+			}
+			chars = append(chars, char)
+			left = char.id
+		}
+		return nil
+	})
+	return chars, err
+}
+
+// orderText sorts the characters of the text, each one coming after its
+// left neighbour and before its right one. The characters ready at the
+// same time, inserted concurrently, are sorted by id like rmscene does.
+func orderText(chars []textChar, r *blockReader) ([]textChar, error) {
+	byID := make(map[crdtID]textChar, len(chars))
+	for _, c := range chars {
+		byID[c.id] = c
+	}
+
+	// the characters which must come before each one
+	deps := map[crdtID]map[crdtID]bool{}
+	next := map[crdtID][]crdtID{}
+	depend := func(after, before crdtID) {
+		if deps[before] == nil {
+			deps[before] = map[crdtID]bool{}
+		}
+		if deps[after] == nil {
+			deps[after] = map[crdtID]bool{}
+		}
+		if !deps[after][before] {
+			deps[after][before] = true
+			next[before] = append(next[before], after)
+		}
+	}
+	for _, c := range chars {
+		right := c.right
+		if right == (crdtID{}) {
+			right = endMarker
+		}
+		depend(c.id, c.left)
+		depend(right, c.id)
+	}
+
+	var ready []crdtID
+	for id, d := range deps {
+		if len(d) == 0 {
+			ready = append(ready, id)
+		}
+	}
+	ordered := make([]textChar, 0, len(chars))
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool {
+			if ready[i].part1 != ready[j].part1 {
+				return ready[i].part1 < ready[j].part1
+			}
+			return ready[i].part2 < ready[j].part2
+		})
+		var following []crdtID
+		for _, id := range ready {
+			if c, ok := byID[id]; ok {
+				ordered = append(ordered, c)
+			}
+			for _, n := range next[id] {
+				delete(deps[n], id)
+				if len(deps[n]) == 0 {
+					following = append(following, n)
+				}
+			}
+		}
+		ready = following
+	}
+	if len(ordered) != len(chars) {
+		return nil, r.errorf("cycle in the order of the text")
+	}
+	return ordered, nil
+}
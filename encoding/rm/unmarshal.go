@@ -2,159 +2,71 @@ package rm
 
 import (
 	"bytes"
-	"encoding/binary"
-	"fmt"
 )
 
 // UnmarshalBinary implements encoding.UnmarshalBinary for
 // transforming bytes into a Rm page
 func (rm *Rm) UnmarshalBinary(data []byte) error {
-	r := newReader(data)
-	if err := r.checkHeader(); err != nil {
-		return err
-	}
-	rm.Version = r.version
-
-	nbLayers, err := r.readNumber()
-	if err != nil {
-		return err
-	}
-
-	rm.Layers = make([]Layer, nbLayers)
-	for i := uint32(0); i < nbLayers; i++ {
-		nbLines, err := r.readNumber()
-		if err != nil {
-			return err
-		}
-
-		rm.Layers[i].Lines = make([]Line, nbLines)
-		for j := uint32(0); j < nbLines; j++ {
-			line, err := r.readLine()
-			if err != nil {
-				return err
-			}
-			rm.Layers[i].Lines[j] = line
-		}
-	}
-
-	return nil
-}
-
-type reader struct {
-	bytes.Reader
-	version Version
+	_, err := rm.UnmarshalMode(data, Strict)
+	return err
 }
 
-func newReader(data []byte) reader {
-	br := bytes.NewReader(data)
-
-	// we set V5 as default but the real value is
-	// analysed when checking the header
-	return reader{*br, V5}
+// UnmarshalLenient transforms bytes into a Rm page, skipping malformed
+// lines and points instead of failing. Whatever could be read is kept
+// and the skipped problems are returned as warnings.
+func (rm *Rm) UnmarshalLenient(data []byte) ([]Warning, error) {
+	return rm.UnmarshalMode(data, Lenient)
 }
 
-func (r *reader) checkHeader() error {
-	buf := make([]byte, HeaderLen)
-
-	n, err := r.Read(buf)
-	if err != nil {
-		return err
-	}
-
-	if n != HeaderLen {
-		return fmt.Errorf("Wrong header size")
-	}
-
-	switch string(buf) {
-	case HeaderV5:
-		r.version = V5
-	case HeaderV3:
-		r.version = V3
-	default:
-		return fmt.Errorf("Unknown header")
-	}
-
-	return nil
-}
-
-func (r *reader) readNumber() (uint32, error) {
-	var nb uint32
-	if err := binary.Read(r, binary.LittleEndian, &nb); err != nil {
-		return 0, fmt.Errorf("Wrong number read")
-	}
-	return nb, nil
-}
-
-func (r *reader) readLine() (Line, error) {
-	var line Line
-
-	if err := binary.Read(r, binary.LittleEndian, &line.BrushType); err != nil {
-		return line, fmt.Errorf("Failed to read line")
-	}
-
-	if err := binary.Read(r, binary.LittleEndian, &line.BrushColor); err != nil {
-		return line, fmt.Errorf("Failed to read line")
-	}
-
-	if err := binary.Read(r, binary.LittleEndian, &line.Padding); err != nil {
-		return line, fmt.Errorf("Failed to read line")
-	}
-
-	if err := binary.Read(r, binary.LittleEndian, &line.BrushSize); err != nil {
-		return line, fmt.Errorf("Failed to read line")
-	}
-
-	// this new attribute has been added in v5
-	if r.version == V5 {
-		if err := binary.Read(r, binary.LittleEndian, &line.Unknown); err != nil {
-			return line, fmt.Errorf("Failed to read line")
-		}
-	}
-
-	nbPoints, err := r.readNumber()
-	if err != nil {
-		return line, err
-	}
-
-	if nbPoints == 0 {
-		return line, nil
-	}
-
-	line.Points = make([]Point, nbPoints)
-
-	for i := uint32(0); i < nbPoints; i++ {
-		p, err := r.readPoint()
-		if err != nil {
-			return line, err
-		}
-
-		line.Points[i] = p
-	}
-
-	return line, nil
+// UnmarshalMode transforms bytes into a Rm page in the given mode. The
+// warnings are only returned in lenient mode.
+func (rm *Rm) UnmarshalMode(data []byte, mode ParseMode) ([]Warning, error) {
+	d := NewDecoder(bytes.NewReader(data))
+	d.Mode = mode
+	rm.Layers = nil
+	rm.Text = nil
+
+	var line *Line
+	err := d.Decode(Handler{
+		StartLayer: func(layer int, nbLines int) error {
+			rm.Layers = append(rm.Layers, Layer{Lines: make([]Line, 0, capacity(nbLines))})
+			return nil
+		},
+		LayerInfo: func(layer int, name string, hidden bool) error {
+			rm.Layers[layer].Name, rm.Layers[layer].Hidden = name, hidden
+			return nil
+		},
+		StartLine: func(layer int, l Line, nbPoints int) error {
+			lines := &rm.Layers[layer].Lines
+			*lines = append(*lines, l)
+			line = &(*lines)[len(*lines)-1]
+			if nbPoints > 0 {
+				line.Points = make([]Point, 0, capacity(nbPoints))
+			}
+			return nil
+		},
+		Point: func(p Point) error {
+			line.Points = append(line.Points, p)
+			return nil
+		},
+		Text: func(text Text) error {
+			rm.Text = &text
+			return nil
+		},
+	})
+
+	rm.Version = d.Version()
+	rm.PageWidth, rm.PageHeight = d.PageSize()
+	return d.Warnings(), err
 }
 
-func (r *reader) readPoint() (Point, error) {
-	var point Point
+// maxPrealloc limits the memory allocated upfront from the
+// counts read in a file, which may be corrupted.
+const maxPrealloc = 1 << 16
 
-	if err := binary.Read(r, binary.LittleEndian, &point.X); err != nil {
-		return point, fmt.Errorf("Failed to read point")
+func capacity(n int) int {
+	if n > maxPrealloc {
+		return maxPrealloc
 	}
-	if err := binary.Read(r, binary.LittleEndian, &point.Y); err != nil {
-		return point, fmt.Errorf("Failed to read point")
-	}
-	if err := binary.Read(r, binary.LittleEndian, &point.Speed); err != nil {
-		return point, fmt.Errorf("Failed to read point")
-	}
-	if err := binary.Read(r, binary.LittleEndian, &point.Direction); err != nil {
-		return point, fmt.Errorf("Failed to read point")
-	}
-	if err := binary.Read(r, binary.LittleEndian, &point.Width); err != nil {
-		return point, fmt.Errorf("Failed to read point")
-	}
-	if err := binary.Read(r, binary.LittleEndian, &point.Pressure); err != nil {
-		return point, fmt.Errorf("Failed to read point")
-	}
-
-	return point, nil
+	return n
 }
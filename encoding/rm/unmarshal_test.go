@@ -43,3 +43,38 @@ func TestUnmarshalBinaryV5(t *testing.T) {
 func TestUnmarshalBinaryV3(t *testing.T) {
 	testUnmarshalBinary(t, "test_v3.rm", V3)
 }
+
+func TestUnmarshalLenient(t *testing.T) {
+	b, err := ioutil.ReadFile("test_v5.rm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt the first point of the first line and truncate the file
+	corrupted := make([]byte, len(b)-10)
+	copy(corrupted, b)
+	// header, layer count, line count, line attributes and point count
+	firstPoint := HeaderLen + 4 + 4 + 5*4 + 4
+	copy(corrupted[firstPoint:], []byte{0xff, 0xff, 0xff, 0x7f})
+
+	if err := New().UnmarshalBinary(corrupted); err == nil {
+		t.Error("strict mode should fail on a corrupted file")
+	}
+
+	rm := New()
+	warnings, err := rm.UnmarshalLenient(corrupted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if warnings[0].Offset != int64(firstPoint) {
+		t.Errorf("wrong offset for invalid point %d", warnings[0].Offset)
+	}
+
+	if len(rm.Layers) == 0 || len(rm.Layers[0].Lines) == 0 {
+		t.Error("lenient mode should keep the lines read")
+	}
+}
@@ -0,0 +1,185 @@
+// Package export writes the documents in the formats of a registry,
+// such as pdf with the annotations, images or the highlights. The
+// formats register themselves, new ones are added without changing
+// the commands using them.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joagonca/rmapi/annotations"
+	"github.com/joagonca/rmapi/archive"
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+// Archive is the format keeping the archive of the document as it is
+// downloaded.
+const Archive = "zip"
+
+// Options are the options of the exports, each format uses the ones
+// which apply to it.
+type Options struct {
+	annotations.PdfGeneratorOptions
+	// DPI is the resolution of the images, the one of the device when 0
+	DPI int
+	// Document is the path of the document and Modified its date, for
+	// the formats listing them
+	Document string
+	Modified time.Time
+}
+
+// An Exporter writes an archive in a format.
+type Exporter interface {
+	// Export writes the archive zipName to outputFilePath, or to
+	// several numbered files (see NumberedName), and returns the
+	// written files.
+	Export(zipName, outputFilePath string, options Options) ([]string, error)
+}
+
+// A Format is an exporter of the registry.
+type Format struct {
+	Name string
+	// Extension is the one of the written files, without the dot
+	Extension   string
+	Description string
+	Exporter    Exporter
+}
+
+var formats = make(map[string]Format)
+
+var errNoAnnotations = errors.New("the document has no annotations, use the option to export all pages")
+
+// Register adds a format to the registry.
+func Register(format Format) {
+	if _, ok := formats[format.Name]; ok {
+		panic("export: format registered twice: " + format.Name)
+	}
+	formats[format.Name] = format
+}
+
+// Formats returns the formats of the registry, by name.
+func Formats() []Format {
+	list := make([]Format, 0, len(formats))
+	for _, f := range formats {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Names returns the names of the formats.
+func Names() []string {
+	var names []string
+	for _, f := range Formats() {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// Lookup returns the format named name.
+func Lookup(name string) (Format, error) {
+	format, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("unknown format %s, available: %s", name, strings.Join(Names(), ", "))
+	}
+	return format, nil
+}
+
+// NumberedName returns the path of the n-th file of an export written
+// in several files, numbered from 1, e.g. name-1.png.
+func NumberedName(outputFilePath string, n int) string {
+	ext := filepath.Ext(outputFilePath)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(outputFilePath, ext), n, ext)
+}
+
+// readArchive reads the structure of an archive and calls fn with it,
+// the drawings of the pages are read on demand.
+func readArchive(zipName string, fn func(zip *archive.Zip) error) error {
+	file, err := os.Open(zipName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	zip := archive.NewZip()
+	if err := zip.ReadLazy(file, fi.Size()); err != nil {
+		return err
+	}
+	return fn(zip)
+}
+
+// eachPage calls fn with the pages of an archive selected by the
+// options, their number from 1 and their drawing, with the selected
+// layers. The pages without drawing are skipped, unless all is set.
+func eachPage(zipName string, all bool, options Options, fn func(number int, page archive.Page, data *rmencoding.Rm) error) error {
+	return readArchive(zipName, func(zip *archive.Zip) error {
+		total := 0
+		for index := range zip.Pages {
+			if options.Pages.Contains(index + 1) {
+				total++
+			}
+		}
+
+		read := 0
+		for index, page := range zip.Pages {
+			if !options.Pages.Contains(index + 1) {
+				continue
+			}
+			data, err := zip.PageDrawing(index)
+			if err != nil {
+				return err
+			}
+			data = options.Layers.Apply(data)
+			if data != nil || all {
+				if err := fn(index+1, page, data); err != nil {
+					return err
+				}
+			}
+			read++
+			if options.Progress != nil {
+				options.Progress(read, total)
+			}
+		}
+		return nil
+	})
+}
+
+// writePages writes a file for every page of an archive: output when
+// there is a single one, numbered files otherwise.
+func writePages(zipName, output string, all bool, options Options, write func(name string, data *rmencoding.Rm) error) ([]string, error) {
+	var files []string
+	err := eachPage(zipName, all, options, func(_ int, page archive.Page, data *rmencoding.Rm) error {
+		name := NumberedName(output, len(files)+1)
+		if err := write(name, data); err != nil {
+			return err
+		}
+		files = append(files, name)
+		return nil
+	})
+	if err != nil {
+		return files, err
+	}
+	if len(files) == 0 {
+		return nil, errNoAnnotations
+	}
+	if len(files) == 1 {
+		if err := os.Rename(files[0], output); err != nil {
+			return files, err
+		}
+		files[0] = output
+	}
+	return files, nil
+}
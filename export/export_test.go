@@ -0,0 +1,105 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testArchive = "../annotations/testfiles/strange.zip"
+
+func TestRegistry(t *testing.T) {
+	for _, name := range []string{"pdf", "png", "svg", "cbz", "tiff", "markdown-highlights", "json-highlights", "json-strokes", "rmdoc", Archive} {
+		if _, err := Lookup(name); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := Lookup("docx"); err == nil || !strings.Contains(err.Error(), "json-strokes") {
+		t.Errorf("expected an error listing the formats, got %v", err)
+	}
+	if got := NumberedName("out/doc.svg", 2); got != "out/doc-2.svg" {
+		t.Errorf("unexpected name %s", got)
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range Formats() {
+		out := filepath.Join(dir, "doc."+f.Extension)
+		files, err := f.Exporter.Export(testArchive, out, Options{Document: "/doc"})
+		if err != nil {
+			t.Errorf("%s: %v", f.Name, err)
+			continue
+		}
+		if len(files) == 0 {
+			t.Errorf("%s: no file written", f.Name)
+		}
+		for _, name := range files {
+			// the archive has no highlights
+			if info, err := os.Stat(name); err != nil || (info.Size() == 0 && f.Name != "markdown-highlights") {
+				t.Errorf("%s: %s not written (%v)", f.Name, name, err)
+			}
+		}
+	}
+}
+
+func TestSVGPages(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "doc.svg")
+	files, err := svgExporter{}.Export(testArchive, out, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the two annotated pages
+	if len(files) != 2 || files[1] != NumberedName(out, 2) {
+		t.Fatalf("unexpected files %v", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "<svg") || !strings.Contains(string(data), "<path") {
+		t.Errorf("unexpected svg %.100s", data)
+	}
+}
+
+func TestCBZ(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "doc.cbz")
+	if _, err := (cbzExporter{}).Export(testArchive, out, Options{DPI: 50}); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 2 || zr.File[0].Name != "0001.png" || zr.File[1].Name != "0002.png" {
+		t.Errorf("unexpected entries %v", zr.File)
+	}
+}
+
+func TestJSONStrokes(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "doc.json")
+	if _, err := (strokesExporter{}).Export(testArchive, out, Options{Document: "/doc"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc strokesDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Document != "/doc" || len(doc.Pages) != 2 {
+		t.Fatalf("unexpected document %s with %d pages", doc.Document, len(doc.Pages))
+	}
+	if p := doc.Pages[1]; p.Page != 3 || len(p.Layers) == 0 || len(p.Layers[0].Lines) == 0 {
+		t.Errorf("unexpected page %d", p.Page)
+	}
+	if line := doc.Pages[0].Layers[0].Lines[0]; line.Brush == "" || line.Color == "" || len(line.Points) == 0 {
+		t.Errorf("unexpected line %+v", line)
+	}
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"image/png"
+	"os"
+
+	"github.com/joagonca/rmapi/annotations"
+	"github.com/joagonca/rmapi/archive"
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+func init() {
+	Register(Format{Name: "png", Extension: "png", Description: "an image of the strokes of every page", Exporter: pngExporter{}})
+	Register(Format{Name: "svg", Extension: "svg", Description: "a vector image of the strokes of every page", Exporter: svgExporter{}})
+	Register(Format{Name: "tiff", Extension: "tiff", Description: "a multi-page image of the strokes of the pages", Exporter: tiffExporter{}})
+	Register(Format{Name: "cbz", Extension: "cbz", Description: "a comic book archive of the images of the pages", Exporter: cbzExporter{}})
+}
+
+type pngExporter struct{}
+
+func (pngExporter) Export(zipName, outputFilePath string, options Options) ([]string, error) {
+	generator := annotations.CreatePngGenerator(zipName, outputFilePath, annotations.PngGeneratorOptions{
+		AllPages:        options.AllPages,
+		DPI:             options.DPI,
+		Colors:          options.Colors,
+		HighlightColors: options.HighlightColors,
+		Widths:          options.Widths,
+		Pages:           options.Pages,
+		Layers:          options.Layers,
+		Eink:            options.Eink,
+	})
+	return generator.Generate()
+}
+
+type tiffExporter struct{}
+
+func (tiffExporter) Export(zipName, outputFilePath string, options Options) ([]string, error) {
+	generator := annotations.CreateTiffGenerator(zipName, outputFilePath, annotations.TiffGeneratorOptions{
+		AllPages:        options.AllPages,
+		DPI:             options.DPI,
+		Colors:          options.Colors,
+		HighlightColors: options.HighlightColors,
+		Widths:          options.Widths,
+		Pages:           options.Pages,
+		Layers:          options.Layers,
+		Eink:            options.Eink,
+	})
+	if err := generator.Generate(); err != nil {
+		return nil, err
+	}
+	return []string{outputFilePath}, nil
+}
+
+type svgExporter struct{}
+
+func (svgExporter) Export(zipName, outputFilePath string, options Options) ([]string, error) {
+	return writePages(zipName, outputFilePath, options.AllPages, options, func(name string, data *rmencoding.Rm) error {
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		if err := annotations.WriteSVG(f, data, options.Colors, options.HighlightColors, options.Widths); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+type cbzExporter struct{}
+
+// Export writes the images of the pages in a zip, named in the order
+// of the pages as the comic book readers expect.
+func (cbzExporter) Export(zipName, outputFilePath string, options Options) ([]string, error) {
+	out, err := os.Create(outputFilePath)
+	if err != nil {
+		return nil, err
+	}
+	zw := zip.NewWriter(out)
+
+	count := 0
+	err = eachPage(zipName, options.AllPages, options, func(_ int, page archive.Page, data *rmencoding.Rm) error {
+		count++
+		// png is compressed already
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: fmt.Sprintf("%04d.png", count), Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		return png.Encode(w, annotations.RenderPage(data, options.DPI, options.Colors, options.HighlightColors, options.Widths, options.Eink))
+	})
+	if err == nil && count == 0 {
+		err = errNoAnnotations
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(outputFilePath)
+		return nil, err
+	}
+	return []string{outputFilePath}, nil
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"io"
+	"os"
+	"path"
+
+	"github.com/joagonca/rmapi/annotations"
+	"github.com/joagonca/rmapi/archive"
+)
+
+func init() {
+	Register(Format{Name: "pdf", Extension: "pdf", Description: "the pages with their annotations", Exporter: pdfExporter{}})
+	Register(Format{Name: Archive, Extension: "zip", Description: "the archive of the document, as downloaded", Exporter: archiveExporter{}})
+	Register(Format{Name: "rmdoc", Extension: "rmdoc", Description: "the document as exported by the device, to import it on another one", Exporter: rmdocExporter{}})
+}
+
+type pdfExporter struct{}
+
+func (pdfExporter) Export(zipName, outputFilePath string, options Options) ([]string, error) {
+	generator := annotations.CreatePdfGenerator(zipName, outputFilePath, options.PdfGeneratorOptions)
+	if err := generator.Generate(); err != nil {
+		return nil, err
+	}
+	return generator.OutputFiles(), nil
+}
+
+type archiveExporter struct{}
+
+func (archiveExporter) Export(zipName, outputFilePath string, options Options) ([]string, error) {
+	if zipName == outputFilePath {
+		return []string{outputFilePath}, nil
+	}
+	in, err := os.Open(zipName)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	out, err := os.Create(outputFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return nil, err
+	}
+	return []string{outputFilePath}, out.Close()
+}
+
+type rmdocExporter struct{}
+
+func (rmdocExporter) Export(zipName, outputFilePath string, options Options) ([]string, error) {
+	name := ""
+	if options.Document != "" {
+		name = path.Base(options.Document)
+	}
+	if err := archive.WriteRmdoc(zipName, outputFilePath, name, options.Modified); err != nil {
+		return nil, err
+	}
+	return []string{outputFilePath}, nil
+}
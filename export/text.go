@@ -0,0 +1,120 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/joagonca/rmapi/archive"
+	rmencoding "github.com/joagonca/rmapi/encoding/rm"
+)
+
+func init() {
+	Register(Format{Name: "markdown-highlights", Extension: "md", Description: "the highlighted passages, by page", Exporter: highlightsExporter{}})
+	Register(Format{Name: "json-highlights", Extension: "json", Description: "the highlighted passages, with their page, box and color", Exporter: highlightsExporter{json: true}})
+	Register(Format{Name: "json-strokes", Extension: "json", Description: "the points of the strokes of every page", Exporter: strokesExporter{}})
+}
+
+// highlightsExporter writes the highlights as markdown, or as json
+// for the other tools.
+type highlightsExporter struct {
+	json bool
+}
+
+func (e highlightsExporter) Export(zipName, outputFilePath string, options Options) ([]string, error) {
+	var records []archive.HighlightRecord
+	err := readArchive(zipName, func(zip *archive.Zip) error {
+		records = zip.HighlightRecords(options.Document, options.Modified)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out, err := os.Create(outputFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if e.json {
+		err = archive.WriteHighlightsJSON(out, records)
+	} else {
+		err = archive.WriteHighlightsMarkdown(out, records)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []string{outputFilePath}, nil
+}
+
+// strokesDocument is the structure of the json-strokes exports.
+type strokesDocument struct {
+	Document string        `json:"document,omitempty"`
+	Pages    []strokesPage `json:"pages"`
+}
+
+type strokesPage struct {
+	// Page is the number of the page in the document, from 1
+	Page   int            `json:"page"`
+	Layers []strokesLayer `json:"layers"`
+}
+
+type strokesLayer struct {
+	Lines []strokesLine `json:"lines"`
+}
+
+type strokesLine struct {
+	Brush  string             `json:"brush"`
+	Color  string             `json:"color"`
+	Size   float32            `json:"size"`
+	Points []rmencoding.Point `json:"points"`
+}
+
+type strokesExporter struct{}
+
+func (strokesExporter) Export(zipName, outputFilePath string, options Options) ([]string, error) {
+	doc := strokesDocument{Document: options.Document, Pages: []strokesPage{}}
+	err := eachPage(zipName, true, options, func(number int, page archive.Page, data *rmencoding.Rm) error {
+		if data == nil && !options.AllPages {
+			return nil
+		}
+		p := strokesPage{Page: number, Layers: []strokesLayer{}}
+		if page.DocPage >= 0 {
+			p.Page = page.DocPage + 1
+		}
+		if data != nil {
+			for _, layer := range data.Layers {
+				l := strokesLayer{Lines: []strokesLine{}}
+				for _, line := range layer.Lines {
+					l.Lines = append(l.Lines, strokesLine{
+						Brush:  line.BrushType.String(),
+						Color:  line.BrushColor.String(),
+						Size:   float32(line.BrushSize),
+						Points: line.Points,
+					})
+				}
+				p.Layers = append(p.Layers, l)
+			}
+		}
+		doc.Pages = append(doc.Pages, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := os.Create(outputFilePath)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(doc)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []string{outputFilePath}, nil
+}
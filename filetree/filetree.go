@@ -2,12 +2,19 @@ package filetree
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/joagonca/rmapi/model"
 	"github.com/joagonca/rmapi/util"
 )
 
+// FileTreeCtx is the tree of the documents of the account. Its methods
+// are safe for concurrent use. The nodes are shared by all the users of
+// the tree: they are changed through the methods of the tree only, and
+// reading their fields while another goroutine changes the tree needs
+// ReadLock.
 type FileTreeCtx struct {
+	mu            *sync.RWMutex
 	root          *model.Node
 	idToNode      map[string]*model.Node
 	pendingParent map[string]map[string]struct{}
@@ -18,9 +25,18 @@ type FileTreeVistor struct {
 }
 
 func (ctx *FileTreeCtx) Clear() {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
 	ctx.root.Children = nil
 }
 
+// ReadLock prevents the tree from changing until the returned function
+// is called, e.g. while walking it.
+func (ctx *FileTreeCtx) ReadLock() (unlock func()) {
+	ctx.mu.RLock()
+	return ctx.mu.RUnlock
+}
+
 func CreateFileTreeCtx() FileTreeCtx {
 	root := model.CreateNode(model.Document{
 		ID:           "",
@@ -29,6 +45,7 @@ func CreateFileTreeCtx() FileTreeCtx {
 	})
 
 	return FileTreeCtx{
+		&sync.RWMutex{},
 		&root,
 		make(map[string]*model.Node),
 		make(map[string]map[string]struct{}),
@@ -44,6 +61,8 @@ func (ctx *FileTreeCtx) NodeById(id string) *model.Node {
 		return ctx.Root()
 	}
 
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
 	if n, ok := ctx.idToNode[id]; ok {
 		return n
 	} else {
@@ -56,6 +75,9 @@ func (ctx *FileTreeCtx) AddDocument(document *model.Document) {
 	nodeId := document.ID
 	parentId := document.Parent
 
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
 	ctx.idToNode[nodeId] = &node
 
 	if parentId == "" {
@@ -89,6 +111,8 @@ func (ctx *FileTreeCtx) DeleteNode(node *model.Node) {
 		return
 	}
 
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
 	delete(node.Parent.Children, node.Id())
 }
 
@@ -97,6 +121,8 @@ func (ctx *FileTreeCtx) MoveNode(src, dst *model.Node) {
 		return
 	}
 
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
 	src.Document.VissibleName = dst.Document.VissibleName
 	src.Document.Version = dst.Document.Version
 	src.Document.ModifiedClient = dst.Document.ModifiedClient
@@ -115,6 +141,9 @@ func (ctx *FileTreeCtx) NodeByPath(path string, current *model.Node) (*model.Nod
 
 	entries := util.SplitPath(path)
 
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+
 	if len(entries) == 0 {
 		return current, nil
 	}
@@ -159,8 +188,10 @@ func (ctx *FileTreeCtx) NodeToPath(targetNode *model.Node) (string, error) {
 	resultPath := ""
 	found := false
 
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
 	visitor := FileTreeVistor{
-		func(currentNode *model.Node, path []string) bool {
+		Visit: func(currentNode *model.Node, path []string) bool {
 			if targetNode != currentNode {
 				return ContinueVisiting
 			}
@@ -179,3 +210,12 @@ func (ctx *FileTreeCtx) NodeToPath(targetNode *model.Node) (string, error) {
 		return "", errors.New("entry not found")
 	}
 }
+
+// UpdateDocument replaces the document of the node with the same ID.
+func (ctx *FileTreeCtx) UpdateDocument(document *model.Document) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if node, ok := ctx.idToNode[document.ID]; ok {
+		node.Document = document
+	}
+}
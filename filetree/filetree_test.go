@@ -1,6 +1,8 @@
 package filetree
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/joagonca/rmapi/model"
@@ -198,3 +200,33 @@ func TestNodeToPath(t *testing.T) {
 	path, _ = ctx.NodeToPath(ctx.root.Children["9"])
 	assert.Equal(t, "/file5", path)
 }
+
+// TestConcurrentAccess is meant to be run with -race.
+func TestConcurrentAccess(t *testing.T) {
+	ctx := CreateFileTreeCtx()
+	ctx.AddDocument(createDirectory("dir", "", "dir"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprint("file", i)
+			ctx.AddDocument(createFile(id, "dir", id))
+			ctx.MoveNode(ctx.NodeById(id), &model.Node{Document: createFile(id, "dir", id+"b"), Parent: ctx.NodeById("dir")})
+		}(i)
+		go func() {
+			defer wg.Done()
+			node, _ := ctx.NodeByPath("/dir", nil)
+			ctx.NodeToPath(node)
+			unlock := ctx.ReadLock()
+			WalkTree(ctx.Root(), FileTreeVistor{Visit: func(*model.Node, []string) bool { return ContinueVisiting }})
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	unlock := ctx.ReadLock()
+	defer unlock()
+	assert.Equal(t, 10, len(ctx.NodeById("dir").Children))
+}
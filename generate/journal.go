@@ -0,0 +1,140 @@
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/strokes"
+)
+
+// DefaultJournalName is the default time layout of the journal
+// document names and page titles.
+const DefaultJournalName = "2006-01-02 Monday"
+
+// journalDateKey is stored in the .content of a journal notebook to
+// remember the last day a page was added.
+const journalDateKey = "rmapiJournalDate"
+
+// JournalTitle formats the title of the journal entry of a day.
+func JournalTitle(date time.Time, layout string) string {
+	if layout == "" {
+		layout = DefaultJournalName
+	}
+	return date.Format(layout)
+}
+
+// JournalPage creates a page with the title handwritten at the top.
+func JournalPage(title string) *rm.Rm {
+	return strokes.FromText(title, strokes.DefaultTextOptions())
+}
+
+// AppendJournalPage adds a page to the raw files of a notebook, as
+// read by archive.ReadRawFiles. It returns false when the page of this
+// day has already been added. background is the name of the template
+// of the new page, e.g. "P Lines small", Blank when empty.
+func AppendJournalPage(files map[string][]byte, docID string, date time.Time, page *rm.Rm, background string) (bool, error) {
+	contentName := docID + ".content"
+	data, ok := files[contentName]
+	if !ok {
+		return false, fmt.Errorf("%s not found in the archive", contentName)
+	}
+
+	content := make(map[string]interface{})
+	if err := json.Unmarshal(data, &content); err != nil {
+		return false, fmt.Errorf("cannot read the content of the notebook: %v", err)
+	}
+	if fileType, _ := content["fileType"].(string); fileType != "" && fileType != "notebook" {
+		return false, fmt.Errorf("pages can only be added to notebooks, not %s documents", fileType)
+	}
+
+	day := date.Format("2006-01-02")
+	if content[journalDateKey] == day {
+		return false, nil
+	}
+	content[journalDateKey] = day
+
+	if background == "" {
+		background = "Blank"
+	}
+
+	pageID := uuid.New().String()
+	pages := appendPageID(content, pageID, background)
+	content["pageCount"] = pages
+
+	data, err := json.MarshalIndent(content, "", "    ")
+	if err != nil {
+		return false, err
+	}
+	files[contentName] = data
+
+	rmData, err := page.MarshalBinary()
+	if err != nil {
+		return false, err
+	}
+	files[fmt.Sprintf("%s/%s.rm", docID, pageID)] = rmData
+	files[fmt.Sprintf("%s/%s-metadata.json", docID, pageID)] = []byte(`{"layers":[{"name":"Layer 1"}]}`)
+
+	// the pagedata has one template per page
+	pagedata := docID + ".pagedata"
+	if old, ok := files[pagedata]; ok || pages == 1 {
+		lines := strings.TrimRight(string(old), "\n")
+		if lines != "" {
+			lines += "\n"
+		}
+		files[pagedata] = []byte(lines + background + "\n")
+	}
+
+	return true, nil
+}
+
+// appendPageID adds the page to both the old "pages" list and the
+// "cPages" structure of the newer firmwares, and returns the number
+// of pages.
+func appendPageID(content map[string]interface{}, pageID, background string) int {
+	count := 0
+
+	pages, _ := content["pages"].([]interface{})
+	if _, hasCPages := content["cPages"]; !hasCPages || pages != nil {
+		pages = append(pages, pageID)
+		content["pages"] = pages
+		count = len(pages)
+	}
+
+	cPages, ok := content["cPages"].(map[string]interface{})
+	if !ok {
+		return count
+	}
+	list, _ := cPages["pages"].([]interface{})
+
+	// pages are ordered by their index, a string sorting after
+	// every existing index places the page at the end
+	last := ""
+	for _, p := range list {
+		if entry, ok := p.(map[string]interface{}); ok {
+			if idx, ok := entry["idx"].(map[string]interface{}); ok {
+				if value, _ := idx["value"].(string); value > last {
+					last = value
+				}
+			}
+		}
+	}
+	if last == "" {
+		last = "b"
+	}
+
+	list = append(list, map[string]interface{}{
+		"id":       pageID,
+		"idx":      map[string]interface{}{"timestamp": "1:2", "value": last + "a"},
+		"template": map[string]interface{}{"timestamp": "1:1", "value": background},
+	})
+	cPages["pages"] = list
+
+	if len(list) > count {
+		count = len(list)
+	}
+	return count
+}
@@ -0,0 +1,74 @@
+package generate
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendJournalPage(t *testing.T) {
+	files := map[string][]byte{
+		"doc.content":  []byte(`{"fileType":"notebook","pageCount":1,"pages":["p1"]}`),
+		"doc.pagedata": []byte("P Grid small\n"),
+		"doc/p1.rm":    []byte("page"),
+	}
+	date := time.Date(2025, time.March, 3, 7, 0, 0, 0, time.UTC)
+	title := JournalTitle(date, "")
+	if title != "2025-03-03 Monday" {
+		t.Errorf("wrong title %s", title)
+	}
+
+	added, err := AppendJournalPage(files, "doc", date, JournalPage(title), "P Lines small")
+	if err != nil || !added {
+		t.Fatalf("page not added: %v", err)
+	}
+
+	content := make(map[string]interface{})
+	if err := json.Unmarshal(files["doc.content"], &content); err != nil {
+		t.Fatal(err)
+	}
+	pages := content["pages"].([]interface{})
+	if len(pages) != 2 || content["pageCount"].(float64) != 2 {
+		t.Fatalf("wrong pages %v", content)
+	}
+	if _, ok := files["doc/"+pages[1].(string)+".rm"]; !ok {
+		t.Error("missing page file")
+	}
+	if string(files["doc.pagedata"]) != "P Grid small\nP Lines small\n" {
+		t.Errorf("wrong pagedata %q", files["doc.pagedata"])
+	}
+
+	// the same day is only added once
+	added, err = AppendJournalPage(files, "doc", date, JournalPage(title), "")
+	if err != nil || added {
+		t.Errorf("page added twice: %v", err)
+	}
+}
+
+func TestAppendJournalPageCPages(t *testing.T) {
+	files := map[string][]byte{
+		"doc.content": []byte(`{"formatVersion":2,"pageCount":1,"cPages":{"pages":[{"id":"p1","idx":{"timestamp":"1:2","value":"ba"}}]}}`),
+	}
+
+	_, err := AppendJournalPage(files, "doc", time.Now(), JournalPage("today"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := string(files["doc.content"])
+	if !strings.Contains(content, `"value": "baa"`) || !strings.Contains(content, `"pageCount": 2`) {
+		t.Errorf("wrong content %s", content)
+	}
+	// the old list of pages is not added to the new format
+	if strings.Contains(content, `"pages": [`+"\n"+`        "`) {
+		t.Errorf("unexpected pages list %s", content)
+	}
+}
+
+func TestAppendJournalPagePdf(t *testing.T) {
+	files := map[string][]byte{"doc.content": []byte(`{"fileType":"pdf"}`)}
+	if _, err := AppendJournalPage(files, "doc", time.Now(), JournalPage("today"), ""); err == nil {
+		t.Error("expected an error for a pdf")
+	}
+}
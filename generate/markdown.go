@@ -0,0 +1,207 @@
+package generate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// Layout of the documents rendered from markdown, in points.
+const (
+	textSize    = 11.0
+	textLeading = 16.0
+	indentWidth = 16.0
+)
+
+var headingSizes = []float64{22, 17, 14}
+
+var (
+	orderedItem = regexp.MustCompile(`^(\d+)[.)]\s+(.*)$`)
+	taskItem    = regexp.MustCompile(`^[-*] \[([ xX])\](?:\s+(.*))?$`)
+	// inline markers which are dropped since only whole lines can be bold
+	inlineMarkers = strings.NewReplacer("**", "", "__", "", "`", "")
+)
+
+// MarkdownOptions are the layout of the documents rendered from
+// markdown or text, in points. The text is 11 points and the pages
+// have the size of the screen of the device when they are 0.
+type MarkdownOptions struct {
+	FontSize              float64
+	PageWidth, PageHeight float64
+}
+
+// pageSizes are the sizes of the pages by name.
+var pageSizes = map[string][2]float64{
+	"remarkable": {pdf.RemarkableWidth, pdf.RemarkableHeight},
+	"a4":         {pdf.A4Width, pdf.A4Height},
+	"a5":         {420, 595},
+	"letter":     {612, 792},
+}
+
+// ParsePageSize returns the size of the pages named remarkable, a4, a5
+// or letter.
+func ParsePageSize(name string) (width, height float64, err error) {
+	size, ok := pageSizes[strings.ToLower(name)]
+	if !ok {
+		return 0, 0, fmt.Errorf("unknown page size %q, expected remarkable, a4, a5 or letter", name)
+	}
+	return size[0], size[1], nil
+}
+
+// markdownWriter lays out blocks of text on pages.
+type markdownWriter struct {
+	doc           *pdf.Document
+	page          *pdf.Page
+	y             float64
+	width, height float64
+	// size and leading of the text, which the headings follow
+	size, leading float64
+}
+
+func newMarkdownWriter(title string, opts MarkdownOptions) *markdownWriter {
+	w := &markdownWriter{doc: pdf.NewDocument(), width: pageWidth, height: pageHeight, size: textSize}
+	w.doc.Title = title
+	if opts.PageWidth > 0 && opts.PageHeight > 0 {
+		w.width, w.height = opts.PageWidth, opts.PageHeight
+	}
+	if opts.FontSize > 0 {
+		w.size = opts.FontSize
+	}
+	w.leading = w.size * textLeading / textSize
+	return w
+}
+
+func (w *markdownWriter) newPage() {
+	w.page = w.doc.AddPage(w.width, w.height)
+	w.y = margin
+}
+
+// space reserves height on the page, starting a new page if needed.
+func (w *markdownWriter) space(height float64) {
+	if w.page == nil || w.y+height > w.height-margin {
+		w.newPage()
+	}
+	w.y += height
+}
+
+func (w *markdownWriter) text(x float64, font pdf.Font, size, leading float64, s string) {
+	for _, line := range pdf.WrapText(s, font, size, w.width-margin-x) {
+		w.space(leading)
+		w.page.Text(x, w.y, font, size, line)
+	}
+}
+
+// MarkdownPDF renders a subset of markdown: headings, paragraphs,
+// bullet, numbered and task lists, and horizontal rules which are
+// drawn as lines to write on.
+func MarkdownPDF(src, title string, opts MarkdownOptions) *pdf.Document {
+	w := newMarkdownWriter(title, opts)
+
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		text := strings.Join(paragraph, " ")
+		font := pdf.Helvetica
+		if isBold(text) {
+			font = pdf.HelveticaBold
+		}
+		w.text(margin, font, w.size, w.leading, inlineMarkers.Replace(text))
+		w.space(w.leading / 2)
+		paragraph = nil
+	}
+
+	for _, raw := range strings.Split(src, "\n") {
+		line := strings.TrimRight(raw, " \t")
+		trimmed := strings.TrimLeft(line, " \t")
+		indent := float64(len(line)-len(trimmed)) / 2 * indentWidth
+		x := margin + indent
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "#"):
+			flush()
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			if level > len(headingSizes) {
+				level = len(headingSizes)
+			}
+			size := headingSizes[level-1] * w.size / textSize
+			w.space(size / 2)
+			w.text(margin, pdf.HelveticaBold, size, size*1.3, inlineMarkers.Replace(strings.TrimSpace(strings.TrimLeft(trimmed, "#"))))
+			w.space(size / 3)
+
+		case trimmed == "---" || trimmed == "***" || trimmed == "___":
+			// an empty line to write on
+			flush()
+			w.space(w.leading * 1.5)
+			w.page.SetGray(0.6)
+			w.page.Line(margin, w.y, w.width-margin, w.y, 0.5)
+			w.page.SetGray(0)
+
+		case taskItem.MatchString(trimmed):
+			flush()
+			m := taskItem.FindStringSubmatch(trimmed)
+			checked := m[1] != " "
+			w.space(w.leading)
+			w.page.Rect(x, w.y-9, 9, 9, 0.8, false)
+			if checked {
+				w.page.Line(x+2, w.y-4.5, x+4, w.y-2, 1)
+				w.page.Line(x+4, w.y-2, x+8, w.y-8, 1)
+			}
+			w.y -= w.leading
+			w.text(x+16, pdf.Helvetica, w.size, w.leading, inlineMarkers.Replace(m[2]))
+
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") || strings.HasPrefix(trimmed, "+ "):
+			flush()
+			w.space(w.leading)
+			w.page.Text(x+2, w.y, pdf.Helvetica, w.size, "•")
+			w.y -= w.leading
+			w.text(x+14, pdf.Helvetica, w.size, w.leading, inlineMarkers.Replace(trimmed[2:]))
+
+		case orderedItem.MatchString(trimmed):
+			flush()
+			m := orderedItem.FindStringSubmatch(trimmed)
+			w.space(w.leading)
+			w.page.Text(x, w.y, pdf.Helvetica, w.size, m[1]+".")
+			w.y -= w.leading
+			w.text(x+18, pdf.Helvetica, w.size, w.leading, inlineMarkers.Replace(m[2]))
+
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	if w.page == nil {
+		w.newPage()
+	}
+	return w.doc
+}
+
+// TextPDF renders plain text, keeping its lines and wrapping the long
+// ones.
+func TextPDF(src, title string, opts MarkdownOptions) *pdf.Document {
+	w := newMarkdownWriter(title, opts)
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(strings.ReplaceAll(line, "\t", "    "), " ")
+		if line == "" {
+			w.space(w.leading)
+			continue
+		}
+		w.text(margin, pdf.Helvetica, w.size, w.leading, line)
+	}
+	if w.page == nil {
+		w.newPage()
+	}
+	return w.doc
+}
+
+func isBold(s string) bool {
+	return len(s) > 4 && (strings.HasPrefix(s, "**") && strings.HasSuffix(s, "**") ||
+		strings.HasPrefix(s, "__") && strings.HasSuffix(s, "__"))
+}
@@ -0,0 +1,349 @@
+// Package generate builds documents meant to be written on, such as
+// planners, ready to be uploaded to the tablet.
+package generate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// Layout selects the pages of a planner.
+type Layout string
+
+const (
+	// Monthly has a page for the year and one per month
+	Monthly Layout = "monthly"
+	// Weekly adds a page per week
+	Weekly Layout = "weekly"
+	// Daily adds a page per week and per day
+	Daily Layout = "daily"
+)
+
+// Layouts lists the valid planner layouts.
+var Layouts = []Layout{Monthly, Weekly, Daily}
+
+// ParseLayout validates a layout name.
+func ParseLayout(name string) (Layout, error) {
+	for _, l := range Layouts {
+		if string(l) == strings.ToLower(name) {
+			return l, nil
+		}
+	}
+	return "", fmt.Errorf("unknown layout %s, valid layouts: monthly, weekly, daily", name)
+}
+
+// PlannerOptions configures a planner.
+type PlannerOptions struct {
+	Year   int
+	Layout Layout
+	// WeekStart is the first day of the weeks, Monday or Sunday
+	WeekStart time.Weekday
+}
+
+// Dimensions of the planner pages, in points.
+const (
+	pageWidth  = pdf.RemarkableWidth
+	pageHeight = pdf.RemarkableHeight
+	margin     = 30.0
+	headerY    = 55.0
+	contentY   = 80.0
+	lineGap    = 22.0
+	navSize    = 10.0
+)
+
+// planner holds the pages so that links can point to any of them.
+type planner struct {
+	opts   PlannerOptions
+	doc    *pdf.Document
+	year   *pdf.Page
+	months [12]*pdf.Page
+	weeks  []*pdf.Page
+	// weekStarts are the first days of the weeks
+	weekStarts []time.Time
+	days       map[string]*pdf.Page
+}
+
+func dayKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// Planner creates a hyperlinked planner: every page has navigation
+// links to the year, the month and the previous and next pages.
+func Planner(opts PlannerOptions) (*pdf.Document, error) {
+	if opts.Year < 1 || opts.Year > 9999 {
+		return nil, fmt.Errorf("invalid year %d", opts.Year)
+	}
+	if opts.Layout == "" {
+		opts.Layout = Monthly
+	}
+	if _, err := ParseLayout(string(opts.Layout)); err != nil {
+		return nil, err
+	}
+
+	p := &planner{opts: opts, doc: pdf.NewDocument(), days: make(map[string]*pdf.Page)}
+	p.doc.Title = fmt.Sprintf("Planner %d", opts.Year)
+	p.addPages()
+
+	p.drawYear()
+	for m := range p.months {
+		p.drawMonth(time.Month(m + 1))
+	}
+	for i := range p.weeks {
+		p.drawWeek(i)
+	}
+	for _, day := range p.sortedDays() {
+		p.drawDay(day)
+	}
+
+	return p.doc, nil
+}
+
+func (p *planner) newPage() *pdf.Page {
+	return p.doc.AddPage(pageWidth, pageHeight)
+}
+
+// addPages creates all the pages up front, in reading order.
+func (p *planner) addPages() {
+	p.year = p.newPage()
+	for m := range p.months {
+		p.months[m] = p.newPage()
+	}
+	if p.opts.Layout == Monthly {
+		return
+	}
+
+	first := time.Date(p.opts.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	start := p.weekStart(first)
+	for d := start; d.Year() <= p.opts.Year; d = d.AddDate(0, 0, 7) {
+		p.weekStarts = append(p.weekStarts, d)
+		p.weeks = append(p.weeks, p.newPage())
+	}
+
+	if p.opts.Layout != Daily {
+		return
+	}
+	for d := first; d.Year() == p.opts.Year; d = d.AddDate(0, 0, 1) {
+		p.days[dayKey(d)] = p.newPage()
+	}
+}
+
+// weekStart returns the first day of the week containing t.
+func (p *planner) weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) - int(p.opts.WeekStart) + 7) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+// weekIndex returns the week page containing t, -1 if there is none.
+func (p *planner) weekIndex(t time.Time) int {
+	start := p.weekStart(t)
+	for i, s := range p.weekStarts {
+		if s.Equal(start) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *planner) sortedDays() []time.Time {
+	var days []time.Time
+	first := time.Date(p.opts.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for d := first; d.Year() == p.opts.Year; d = d.AddDate(0, 0, 1) {
+		if _, ok := p.days[dayKey(d)]; ok {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// target returns the most detailed page for a day: the day, its week or its month.
+func (p *planner) target(t time.Time) *pdf.Page {
+	if page, ok := p.days[dayKey(t)]; ok {
+		return page
+	}
+	if t.Year() == p.opts.Year {
+		if i := p.weekIndex(t); i >= 0 {
+			return p.weeks[i]
+		}
+		return p.months[t.Month()-1]
+	}
+	return nil
+}
+
+// header draws the title and the navigation links of a page.
+func (p *planner) header(page *pdf.Page, title string, month *pdf.Page, prev, next *pdf.Page) {
+	page.Text(margin, headerY, pdf.HelveticaBold, 20, title)
+
+	type nav struct {
+		label  string
+		target *pdf.Page
+	}
+	items := []nav{{fmt.Sprint(p.opts.Year), p.year}, {"Month", month}, {"<", prev}, {">", next}}
+
+	x := float64(pageWidth - margin)
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if item.target == nil {
+			continue
+		}
+		w := pdf.TextWidth(item.label, pdf.Helvetica, navSize)
+		x -= w
+		page.Text(x, headerY, pdf.Helvetica, navSize, item.label)
+		page.Link(x-4, headerY-navSize-2, w+8, navSize+8, item.target)
+		x -= 14
+	}
+
+	page.Line(margin, headerY+8, pageWidth-margin, headerY+8, 1)
+}
+
+// ruled draws writing lines from y to the bottom of the page.
+func ruled(page *pdf.Page, y float64) {
+	page.SetGray(0.7)
+	for ; y < pageHeight-margin; y += lineGap {
+		page.Line(margin, y, pageWidth-margin, y, 0.3)
+	}
+	page.SetGray(0)
+}
+
+func (p *planner) weekdays() []time.Weekday {
+	days := make([]time.Weekday, 7)
+	for i := range days {
+		days[i] = time.Weekday((int(p.opts.WeekStart) + i) % 7)
+	}
+	return days
+}
+
+func (p *planner) drawYear() {
+	page := p.year
+	p.header(page, fmt.Sprint(p.opts.Year), nil, nil, p.months[0])
+
+	const cols, rows = 3, 4
+	cellW := (pageWidth - 2*margin) / cols
+	cellH := (pageHeight - contentY - margin) / rows
+
+	for m := 0; m < 12; m++ {
+		x := margin + float64(m%cols)*cellW
+		y := contentY + float64(m/cols)*cellH
+		month := time.Month(m + 1)
+
+		page.Text(x+4, y+14, pdf.HelveticaBold, 11, month.String())
+		page.Link(x, y, cellW, cellH, p.months[m])
+
+		// small calendar of the month
+		dayW := (cellW - 8) / 7
+		first := time.Date(p.opts.Year, month, 1, 0, 0, 0, 0, time.UTC)
+		col := (int(first.Weekday()) - int(p.opts.WeekStart) + 7) % 7
+		row := 0
+		for d := first; d.Month() == month; d = d.AddDate(0, 0, 1) {
+			page.Text(x+4+float64(col)*dayW, y+30+float64(row)*12, pdf.Helvetica, 7, fmt.Sprint(d.Day()))
+			col++
+			if col == 7 {
+				col = 0
+				row++
+			}
+		}
+	}
+}
+
+func (p *planner) drawMonth(month time.Month) {
+	page := p.months[month-1]
+	var prev, next *pdf.Page
+	if month > time.January {
+		prev = p.months[month-2]
+	}
+	if month < time.December {
+		next = p.months[month]
+	}
+	p.header(page, fmt.Sprintf("%s %d", month, p.opts.Year), nil, prev, next)
+
+	gridX := margin + 16.0
+	cellW := (pageWidth - margin - gridX) / 7
+	const rows = 6
+	cellH := (pageHeight - contentY - margin - 16) / rows
+
+	for i, wd := range p.weekdays() {
+		page.Text(gridX+float64(i)*cellW+3, contentY+10, pdf.Helvetica, 8, wd.String()[:3])
+	}
+	top := contentY + 16
+
+	first := time.Date(p.opts.Year, month, 1, 0, 0, 0, 0, time.UTC)
+	start := p.weekStart(first)
+	for row := 0; row < rows; row++ {
+		y := top + float64(row)*cellH
+		weekFirst := start.AddDate(0, 0, row*7)
+		if weekFirst.Month() != month && weekFirst.AddDate(0, 0, 6).Month() != month {
+			break
+		}
+
+		// week number linking to the week page
+		if i := p.weekIndex(weekFirst); i >= 0 && len(p.weeks) > 0 {
+			page.Text(margin, y+12, pdf.Helvetica, 7, fmt.Sprintf("W%d", i+1))
+			page.Link(margin, y, 16, cellH, p.weeks[i])
+		}
+
+		for col := 0; col < 7; col++ {
+			d := weekFirst.AddDate(0, 0, col)
+			x := gridX + float64(col)*cellW
+			page.Rect(x, y, cellW, cellH, 0.5, false)
+			if d.Month() != month {
+				continue
+			}
+			page.Text(x+3, y+12, pdf.Helvetica, 9, fmt.Sprint(d.Day()))
+			if target := p.target(d); target != nil && target != page {
+				page.Link(x, y, cellW, cellH, target)
+			}
+		}
+	}
+}
+
+func (p *planner) drawWeek(i int) {
+	page := p.weeks[i]
+	start := p.weekStarts[i]
+	end := start.AddDate(0, 0, 6)
+
+	var prev, next *pdf.Page
+	if i > 0 {
+		prev = p.weeks[i-1]
+	}
+	if i+1 < len(p.weeks) {
+		next = p.weeks[i+1]
+	}
+	monthDay := start
+	if monthDay.Year() != p.opts.Year {
+		monthDay = end
+	}
+	title := fmt.Sprintf("Week %d  %s - %s", i+1, start.Format("Jan 2"), end.Format("Jan 2"))
+	p.header(page, title, p.months[monthDay.Month()-1], prev, next)
+
+	rowH := (pageHeight - contentY - margin) / 7
+	for col := 0; col < 7; col++ {
+		d := start.AddDate(0, 0, col)
+		y := contentY + float64(col)*rowH
+		page.Text(margin, y+14, pdf.HelveticaBold, 10, d.Format("Monday 2 January"))
+		if target, ok := p.days[dayKey(d)]; ok {
+			page.Link(margin, y, pageWidth-2*margin, 20, target)
+		}
+		page.SetGray(0.7)
+		for ly := y + 20 + lineGap; ly < y+rowH-4; ly += lineGap {
+			page.Line(margin, ly, pageWidth-margin, ly, 0.3)
+		}
+		page.SetGray(0)
+		page.Line(margin, y+rowH, pageWidth-margin, y+rowH, 0.5)
+	}
+}
+
+func (p *planner) drawDay(d time.Time) {
+	page := p.days[dayKey(d)]
+	prev := p.days[dayKey(d.AddDate(0, 0, -1))]
+	next := p.days[dayKey(d.AddDate(0, 0, 1))]
+	p.header(page, d.Format("Monday 2 January"), p.months[d.Month()-1], prev, next)
+
+	if i := p.weekIndex(d); i >= 0 {
+		label := fmt.Sprintf("Week %d", i+1)
+		page.Text(margin, contentY, pdf.Helvetica, navSize, label)
+		page.Link(margin, contentY-navSize-2, pdf.TextWidth(label, pdf.Helvetica, navSize), navSize+6, p.weeks[i])
+	}
+	ruled(page, contentY+lineGap)
+}
@@ -0,0 +1,54 @@
+package generate
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestPlannerPages(t *testing.T) {
+	tests := []struct {
+		layout Layout
+		pages  int
+	}{
+		{Monthly, 13},
+		// 2025 starts on a Wednesday, the first week starts on 2024-12-30
+		{Weekly, 13 + 53},
+		{Daily, 13 + 53 + 365},
+	}
+
+	for _, tt := range tests {
+		doc, err := Planner(PlannerOptions{Year: 2025, Layout: tt.layout, WeekStart: time.Monday})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(doc.Pages()) != tt.pages {
+			t.Errorf("%s: expected %d pages, got %d", tt.layout, tt.pages, len(doc.Pages()))
+		}
+
+		var b bytes.Buffer
+		if err := doc.Write(&b); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(b.Bytes(), []byte("/Subtype /Link")) {
+			t.Errorf("%s: no navigation links", tt.layout)
+		}
+	}
+}
+
+func TestPlannerWeekStart(t *testing.T) {
+	p := &planner{opts: PlannerOptions{WeekStart: time.Sunday}}
+	wednesday := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if start := p.weekStart(wednesday); start.Weekday() != time.Sunday || start.Day() != 29 {
+		t.Errorf("wrong week start %v", start)
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	if l, err := ParseLayout("Weekly"); err != nil || l != Weekly {
+		t.Errorf("expected weekly, got %s %v", l, err)
+	}
+	if _, err := ParseLayout("hourly"); err == nil {
+		t.Error("expected an error for an unknown layout")
+	}
+}
@@ -0,0 +1,95 @@
+package generate
+
+import (
+	"bytes"
+	"html"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs are available in the document templates.
+var templateFuncs = map[string]interface{}{
+	// now returns the current time, e.g. {{now.Format "2006-01-02"}}
+	"now": time.Now,
+	// date formats the current time, e.g. {{date "Monday 2 January"}}
+	"date": func(layout string) string { return time.Now().Format(layout) },
+}
+
+// RenderTemplate executes a Go template with the variables and returns
+// its output as markdown. Templates with a .html or .htm extension are
+// HTML: the variables are escaped and the result is converted.
+func RenderTemplate(path string, vars map[string]string) (string, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(path)
+	var out bytes.Buffer
+	if isHTML(path) {
+		t, err := htmltemplate.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(string(src))
+		if err != nil {
+			return "", err
+		}
+		if err := t.Execute(&out, vars); err != nil {
+			return "", err
+		}
+		return htmlToMarkdown(out.String()), nil
+	}
+
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(string(src))
+	if err != nil {
+		return "", err
+	}
+	if err := t.Execute(&out, vars); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func isHTML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".html" || ext == ".htm" || strings.HasSuffix(strings.ToLower(path), ".html.tmpl")
+}
+
+var (
+	htmlBlocks = []struct {
+		re   *regexp.Regexp
+		repl string
+	}{
+		{regexp.MustCompile(`(?is)<(head|script|style)[^>]*>.*?</(head|script|style)>`), ""},
+		{regexp.MustCompile(`(?i)<h1[^>]*>`), "\n\n# "},
+		{regexp.MustCompile(`(?i)<h2[^>]*>`), "\n\n## "},
+		{regexp.MustCompile(`(?i)<h[3-6][^>]*>`), "\n\n### "},
+		{regexp.MustCompile(`(?i)<li[^>]*>\s*<input[^>]*checked[^>]*>\s*`), "\n- [x] "},
+		{regexp.MustCompile(`(?i)<li[^>]*>\s*<input[^>]*checkbox[^>]*>\s*`), "\n- [ ] "},
+		{regexp.MustCompile(`(?i)<li[^>]*>`), "\n- "},
+		{regexp.MustCompile(`(?i)<hr[^>]*>`), "\n\n---\n\n"},
+		{regexp.MustCompile(`(?i)<br[^>]*>`), "\n\n"},
+		{regexp.MustCompile(`(?i)</(h[1-6]|p|div|ul|ol|table|tr)>`), "\n\n"},
+		{regexp.MustCompile(`(?i)<(b|strong)>([^<]*)</(b|strong)>`), "$2"},
+		{regexp.MustCompile(`<[^>]*>`), ""},
+	}
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToMarkdown converts the block structure of an HTML document
+// into the markdown subset rendered by MarkdownPDF.
+func htmlToMarkdown(src string) string {
+	// the layout of the source is not meaningful in HTML
+	src = strings.Join(strings.Fields(src), " ")
+	for _, b := range htmlBlocks {
+		src = b.re.ReplaceAllString(src, b.repl)
+	}
+
+	lines := strings.Split(html.UnescapeString(src), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")) + "\n"
+}
@@ -0,0 +1,99 @@
+package generate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joagonca/rmapi/pdf"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "meeting.tmpl")
+	os.WriteFile(path, []byte("# {{.title}}\n\n- [ ] {{.item}}\n"), 0600)
+
+	out, err := RenderTemplate(path, map[string]string{"title": "Sprint review", "item": "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "# Sprint review\n\n- [ ] demo\n" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	if _, err := RenderTemplate(path, map[string]string{"title": "missing item"}); err == nil {
+		t.Error("expected an error for a missing variable")
+	}
+}
+
+func TestRenderHTMLTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "form.html")
+	os.WriteFile(path, []byte(`<html><head><title>x</title></head><body>
+<h1>{{.title}}</h1>
+<p>Some <b>text</b> &amp; more</p>
+<ul><li>one</li><li><input type="checkbox"> two</li></ul>
+<hr>
+</body></html>`), 0600)
+
+	out, err := RenderTemplate(path, map[string]string{"title": "A < B"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "# A < B\n\nSome text & more\n\n- one\n- [ ] two\n\n---\n"
+	if out != expected {
+		t.Errorf("got %q, expected %q", out, expected)
+	}
+}
+
+func TestMarkdownPDF(t *testing.T) {
+	doc := MarkdownPDF("# Title\n\nSome text\n\n- item\n1. first\n- [x] done\n\n---\n", "Title", MarkdownOptions{})
+	if len(doc.Pages()) != 1 {
+		t.Errorf("expected 1 page, got %d", len(doc.Pages()))
+	}
+
+	var b bytes.Buffer
+	if err := doc.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	// long documents continue on new pages
+	long := ""
+	for i := 0; i < 100; i++ {
+		long += "- item\n"
+	}
+	if pages := len(MarkdownPDF(long, "", MarkdownOptions{}).Pages()); pages < 2 {
+		t.Errorf("expected several pages, got %d", pages)
+	}
+}
+
+func TestMarkdownEmptyTask(t *testing.T) {
+	if !taskItem.MatchString("- [ ]") || taskItem.FindStringSubmatch("- [x] done")[2] != "done" {
+		t.Error("task items not recognized")
+	}
+}
+
+func TestMarkdownOptions(t *testing.T) {
+	width, height, err := ParsePageSize("A4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParsePageSize("b5"); err == nil {
+		t.Error("expected an error")
+	}
+
+	doc := MarkdownPDF("# Title\n\nSome text", "", MarkdownOptions{FontSize: 14, PageWidth: width, PageHeight: height})
+	if page := doc.Pages()[0]; page.Width != pdf.A4Width || page.Height != pdf.A4Height {
+		t.Errorf("expected an a4 page, got %vx%v", page.Width, page.Height)
+	}
+
+	// a larger text takes more pages
+	long := strings.Repeat("A line of text\n", 60)
+	small := len(TextPDF(long, "", MarkdownOptions{}).Pages())
+	large := len(TextPDF(long, "", MarkdownOptions{FontSize: 22}).Pages())
+	if small < 2 || large <= small {
+		t.Errorf("expected more pages with a larger text, got %d and %d", small, large)
+	}
+}
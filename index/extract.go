@@ -0,0 +1,258 @@
+package index
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/joagonca/rmapi/annotations"
+	"github.com/joagonca/rmapi/archive"
+	"github.com/joagonca/rmapi/log"
+	"github.com/joagonca/rmapi/pdf"
+)
+
+// ArchiveText returns the text of the pdf or epub of a document
+// archive. The text of the notebooks is the handwriting found by
+// recognizer, they give nil without it. notebook tells whether the
+// archive is a notebook.
+func ArchiveText(zipPath string, recognizer annotations.Recognizer) (pages []string, notebook bool, err error) {
+	file, err := os.Open(zipPath)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+
+	z := archive.NewZip()
+	if err := z.ReadLazy(file, fi.Size()); err != nil {
+		return nil, false, err
+	}
+	fileType := z.Content.FileType
+	if fileType != "pdf" && fileType != "epub" {
+		if recognizer == nil {
+			return nil, true, nil
+		}
+		pages, err := HandwrittenText(z, recognizer)
+		return pages, true, err
+	}
+	pages, err = payloadText(z, fileType)
+	return pages, false, err
+}
+
+// HandwrittenText returns the words recognized in the strokes of each
+// page of a document.
+func HandwrittenText(z *archive.Zip, recognizer annotations.Recognizer) ([]string, error) {
+	texts := make([]string, len(z.Pages))
+	for i := range z.Pages {
+		data, err := z.PageDrawing(i)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		words, err := annotations.RecognizeWords(recognizer, data)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %v", i+1, err)
+		}
+		var text []string
+		for _, w := range words {
+			text = append(text, w.Text)
+		}
+		texts[i] = strings.Join(text, " ")
+	}
+	return texts, nil
+}
+
+// payloadText returns the text of the pdf or epub of an archive.
+func payloadText(z *archive.Zip, fileType string) ([]string, error) {
+	payload, err := z.OpenPayload()
+	if err != nil || payload == nil {
+		return nil, err
+	}
+	defer payload.Close()
+
+	// the payload is copied to read it at random without loading it in memory
+	tmp, err := os.CreateTemp("", "rmapi-index-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	size, err := io.Copy(tmp, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if fileType == "pdf" {
+		return PDFText(tmp, size)
+	}
+	return EPUBText(tmp, size)
+}
+
+// PDFText returns the text of each page of a pdf. The pages whose text
+// cannot be read are left empty.
+func PDFText(r io.ReaderAt, size int64) ([]string, error) {
+	f, err := pdf.OpenReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(pages))
+	for i, p := range pages {
+		text, err := f.PageText(p)
+		if err != nil {
+			log.Warning.Printf("page %d: %v", i+1, err)
+			continue
+		}
+		texts[i] = text
+	}
+	return texts, nil
+}
+
+// EPUBText returns the text of each section of an epub, in reading order.
+func EPUBText(r io.ReaderAt, size int64) ([]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	container, ok := files["META-INF/container.xml"]
+	if !ok {
+		return nil, errors.New("epub: container.xml not found")
+	}
+	var c struct {
+		Rootfiles []struct {
+			Path string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := decodeXML(container, &c); err != nil {
+		return nil, err
+	}
+	if len(c.Rootfiles) == 0 {
+		return nil, errors.New("epub: package not found")
+	}
+	opfPath := c.Rootfiles[0].Path
+	opf, ok := files[opfPath]
+	if !ok {
+		return nil, errors.New("epub: package not found")
+	}
+	var pkg struct {
+		Items []struct {
+			ID   string `xml:"id,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := decodeXML(opf, &pkg); err != nil {
+		return nil, err
+	}
+
+	hrefs := make(map[string]string)
+	for _, item := range pkg.Items {
+		hrefs[item.ID] = item.Href
+	}
+	var texts []string
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		f, ok := files[path.Join(path.Dir(opfPath), href)]
+		if !ok {
+			continue
+		}
+		text, err := htmlText(f)
+		if err != nil {
+			log.Warning.Printf("%s: %v", f.Name, err)
+			continue
+		}
+		texts = append(texts, text)
+	}
+	return texts, nil
+}
+
+func decodeXML(f *zip.File, v interface{}) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// blockElements are the elements ending a line of text.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "td": true, "th": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"blockquote": true, "pre": true, "section": true, "title": true,
+}
+
+// htmlText returns the text of an xhtml file, without the markup.
+func htmlText(f *zip.File) (string, error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var b strings.Builder
+	skip := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "script" || t.Name.Local == "style" || t.Name.Local == "head" {
+				skip++
+			}
+		case xml.EndElement:
+			if t.Name.Local == "script" || t.Name.Local == "style" || t.Name.Local == "head" {
+				skip--
+			}
+			if blockElements[t.Name.Local] {
+				b.WriteByte('\n')
+			}
+		case xml.CharData:
+			if skip == 0 {
+				b.Write(t)
+			}
+		}
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	kept := lines[:0]
+	for _, l := range lines {
+		if l = strings.Join(strings.Fields(l), " "); l != "" {
+			kept = append(kept, l)
+		}
+	}
+	return strings.Join(kept, "\n"), nil
+}
@@ -0,0 +1,50 @@
+package index
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEPUBText(t *testing.T) {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	files := []struct{ name, content string }{
+		{"mimetype", "application/epub+zip"},
+		{"META-INF/container.xml", `<?xml version="1.0"?>
+<container xmlns="urn:oasis:names:tc:opendocument:xmlns:container" version="1.0">
+<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`},
+		{"OEBPS/content.opf", `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+<manifest>
+<item id="c1" href="text/one.xhtml" media-type="application/xhtml+xml"/>
+<item id="c2" href="text/two.xhtml" media-type="application/xhtml+xml"/>
+</manifest>
+<spine><itemref idref="c2"/><itemref idref="c1"/></spine>
+</package>`},
+		{"OEBPS/text/one.xhtml", `<html><head><title>One</title><style>p {}</style></head>
+<body><h1>Chapter&nbsp;one</h1><p>The <em>quarterly</em> forecast.</p></body></html>`},
+		{"OEBPS/text/two.xhtml", `<html><body><p>Preface<br/>text</p></body></html>`},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	texts, err := EPUBText(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Preface\ntext", "Chapter one\nThe quarterly forecast."}
+	if !reflect.DeepEqual(texts, want) {
+		t.Errorf("expected %q, got %q", want, texts)
+	}
+}
@@ -0,0 +1,313 @@
+// Package index is a local full-text index of the documents, to search
+// them without downloading them again. It is a SQLite database whose
+// pages are in a full-text table.
+package index
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/joagonca/rmapi/log"
+
+	// the pure go driver, rmapi builds without cgo
+	_ "modernc.org/sqlite"
+)
+
+// indexVersion is the version of the schema, an index of another
+// version is rebuilt.
+const indexVersion = 2
+
+const schema = `
+CREATE TABLE documents (
+	id TEXT PRIMARY KEY,
+	path TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	modified TEXT NOT NULL,
+	notebook INTEGER NOT NULL,
+	ocr TEXT NOT NULL
+);
+CREATE VIRTUAL TABLE pages USING fts5(doc UNINDEXED, page UNINDEXED, text);
+`
+
+// A Document is the text of an indexed document.
+type Document struct {
+	ID   string
+	Path string
+	// Version and Modified tell whether the document changed since
+	// it was indexed
+	Version  int
+	Modified string
+	// Notebook is set for the notebooks, whose text is the handwriting
+	// recognized by OCR, empty when it wasn't
+	Notebook bool
+	OCR      string
+	// Pages is the text of each page, or of each section of an epub
+	Pages []string
+}
+
+// Index is the set of indexed documents.
+type Index struct {
+	db *sql.DB
+}
+
+// DefaultPath returns the path of the index in the cache directory.
+func DefaultPath() (string, error) {
+	cachedir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	rmapiFolder := path.Join(cachedir, "rmapi")
+	if err := os.MkdirAll(rmapiFolder, 0700); err != nil {
+		return "", err
+	}
+	return path.Join(rmapiFolder, "index.db"), nil
+}
+
+// Open opens an index file, creating it when it is missing. An index of
+// another version is emptied.
+func Open(file string) (*Index, error) {
+	db, err := sql.Open("sqlite", file)
+	if err != nil {
+		return nil, err
+	}
+	// a single connection, the changes are made one at a time
+	db.SetMaxOpenConns(1)
+	ix := &Index{db: db}
+	if err := ix.init(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot open the index %s: %v", file, err)
+	}
+	return ix, nil
+}
+
+// init creates the tables of a new index or of an outdated one.
+func (ix *Index) init() error {
+	var version int
+	if err := ix.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version == indexVersion {
+		return nil
+	}
+	if version != 0 {
+		log.Info.Println("wrong index version, rebuilding")
+	}
+
+	tx, err := ix.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{
+		"DROP TABLE IF EXISTS documents",
+		"DROP TABLE IF EXISTS pages",
+		schema,
+		fmt.Sprintf("PRAGMA user_version = %d", indexVersion),
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Close closes the index file.
+func (ix *Index) Close() error {
+	return ix.db.Close()
+}
+
+// Add indexes a document, replacing the previous version.
+func (ix *Index) Add(doc *Document) error {
+	tx, err := ix.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := remove(tx, doc.ID); err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO documents (id, path, version, modified, notebook, ocr) VALUES (?, ?, ?, ?, ?, ?)",
+		doc.ID, doc.Path, doc.Version, doc.Modified, doc.Notebook, doc.OCR)
+	if err != nil {
+		return err
+	}
+	for i, text := range doc.Pages {
+		if text == "" {
+			continue
+		}
+		if _, err := tx.Exec("INSERT INTO pages (doc, page, text) VALUES (?, ?, ?)", doc.ID, i, text); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Remove removes a document from the index.
+func (ix *Index) Remove(id string) error {
+	tx, err := ix.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := remove(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func remove(tx *sql.Tx, id string) error {
+	if _, err := tx.Exec("DELETE FROM documents WHERE id = ?", id); err != nil {
+		return err
+	}
+	_, err := tx.Exec("DELETE FROM pages WHERE doc = ?", id)
+	return err
+}
+
+// SetPath changes the path of a document, when it is moved.
+func (ix *Index) SetPath(id, path string) error {
+	_, err := ix.db.Exec("UPDATE documents SET path = ? WHERE id = ?", path, id)
+	return err
+}
+
+// IDs returns the IDs of the indexed documents.
+func (ix *Index) IDs() ([]string, error) {
+	rows, err := ix.db.Query("SELECT id FROM documents ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// UpToDate tells whether a document is indexed in this version. The
+// notebooks must also have been indexed with the recognizer ocr.
+func (ix *Index) UpToDate(id string, version int, modified, ocr string) (bool, error) {
+	var notebook bool
+	var indexedOCR string
+	err := ix.db.QueryRow("SELECT notebook, ocr FROM documents WHERE id = ? AND version = ? AND modified = ?",
+		id, version, modified).Scan(&notebook, &indexedOCR)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !notebook || indexedOCR == ocr, nil
+}
+
+// Tokens splits a text in lower case words.
+func Tokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// A Result is a page matching a query.
+type Result struct {
+	ID   string
+	Path string
+	// Page starts at 1
+	Page    int
+	Snippet string
+	score   int
+}
+
+// snippetLength is the number of characters shown around a match.
+const snippetLength = 80
+
+// Search finds the pages containing every word of the query. The pages
+// containing the words as a phrase come first, then the pages where
+// they appear most often.
+func (ix *Index) Search(query string) ([]Result, error) {
+	words := Tokens(query)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	// every word is quoted, the full-text table finds the pages with
+	// all of them
+	terms := make([]string, len(words))
+	for i, w := range words {
+		terms[i] = `"` + w + `"`
+	}
+	rows, err := ix.db.Query(`SELECT d.id, d.path, p.page, p.text FROM pages p
+		JOIN documents d ON d.id = p.doc WHERE pages MATCH ?`, strings.Join(terms, " "))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	phrase := strings.Join(words, " ")
+	var results []Result
+	for rows.Next() {
+		var r Result
+		var text string
+		if err := rows.Scan(&r.ID, &r.Path, &r.Page, &text); err != nil {
+			return nil, err
+		}
+		tokens := Tokens(text)
+		for _, t := range tokens {
+			for _, w := range words {
+				if t == w {
+					r.score++
+				}
+			}
+		}
+		if strings.Contains(" "+strings.Join(tokens, " ")+" ", " "+phrase+" ") {
+			r.score += 1000
+		}
+		r.Page++
+		r.Snippet = snippet(text, words[0])
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Page < b.Page
+	})
+	return results, nil
+}
+
+// snippet returns the text around the first occurrence of word.
+func snippet(text, word string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	at := 0
+	if len(lower) == len(runes) {
+		if i := strings.Index(string(lower), word); i >= 0 {
+			at = len([]rune(string(lower)[:i]))
+		}
+	}
+	start := max(0, at-snippetLength/2)
+	end := min(len(runes), start+snippetLength)
+	s := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
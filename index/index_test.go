@@ -0,0 +1,117 @@
+package index
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testIndex(t *testing.T, file string) *Index {
+	ix, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ix.Close() })
+	for _, doc := range []*Document{
+		{ID: "a", Path: "/Reports/q3", Version: 2, Modified: "m", Pages: []string{
+			"Revenue grew this quarter.",
+			"The forecast for the next quarterly review is quarterly forecast of sales.",
+		}},
+		{ID: "b", Path: "/Books/novel", Version: 1, Pages: []string{
+			"A forecast, quarterly or not, is only a guess. Forecast forecast forecast.",
+		}},
+		{ID: "c", Path: "/Notes/meeting", Version: 1, Notebook: true, OCR: "tesseract", Pages: []string{
+			"", "call the printer about the guess",
+		}},
+	} {
+		if err := ix.Add(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ix
+}
+
+func search(t *testing.T, ix *Index, query string) []Result {
+	results, err := ix.Search(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestTokens(t *testing.T) {
+	got := Tokens("Quarterly-Forecast, 2024: Café!")
+	want := []string{"quarterly", "forecast", "2024", "café"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ix := testIndex(t, filepath.Join(t.TempDir(), "index.db"))
+
+	results := search(t, ix, `"quarterly forecast"`)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	// the phrase comes first even with fewer occurrences
+	if r := results[0]; r.ID != "a" || r.Page != 2 {
+		t.Errorf("unexpected first result %+v", r)
+	}
+	if results[1].ID != "b" {
+		t.Errorf("unexpected second result %+v", results[1])
+	}
+
+	if results := search(t, ix, "revenue forecast"); len(results) != 0 {
+		t.Errorf("every word must be on the page, got %+v", results)
+	}
+	if results := search(t, ix, "REVENUE"); len(results) != 1 || results[0].Snippet != "Revenue grew this quarter." {
+		t.Errorf("unexpected results %+v", results)
+	}
+	// the handwriting of the notebooks
+	if results := search(t, ix, "printer"); len(results) != 1 || results[0].Path != "/Notes/meeting" || results[0].Page != 2 {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	if err := ix.Remove("a"); err != nil {
+		t.Fatal(err)
+	}
+	if results := search(t, ix, "quarterly"); len(results) != 1 {
+		t.Errorf("the removed document is still found")
+	}
+}
+
+func TestReopen(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "index.db")
+	testIndex(t, file).Close()
+
+	ix, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+	for _, c := range []struct {
+		id       string
+		version  int
+		modified string
+		ocr      string
+		want     bool
+	}{
+		{"a", 2, "m", "", true},
+		{"a", 3, "m", "", false},
+		{"d", 1, "", "", false},
+		{"c", 1, "", "tesseract", true},
+		// the notebooks are indexed again with another recognizer
+		{"c", 1, "", "", false},
+	} {
+		if ok, err := ix.UpToDate(c.id, c.version, c.modified, c.ocr); err != nil || ok != c.want {
+			t.Errorf("UpToDate(%s, %d, %s, %s) = %v %v, expected %v", c.id, c.version, c.modified, c.ocr, ok, err, c.want)
+		}
+	}
+	if ids, err := ix.IDs(); err != nil || !reflect.DeepEqual(ids, []string{"a", "b", "c"}) {
+		t.Errorf("unexpected documents %v %v", ids, err)
+	}
+	if results := search(t, ix, "guess"); len(results) != 2 {
+		t.Errorf("expected 2 results after reopening, got %d", len(results))
+	}
+}
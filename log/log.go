@@ -1,59 +1,283 @@
+// Package log provides the loggers of rmapi.
+//
+// Messages are handled by a structured logger (log/slog) and every
+// subsystem has its own level, so that e.g. the http traffic can be
+// traced without the noise of the other modules. The classic
+// Trace/Info/Warning/Error loggers are kept for the call sites which
+// only print messages.
+//
+// Levels are configured with RMAPI_LOG_LEVEL, a default level
+// optionally followed by per module levels:
+//
+//	RMAPI_LOG_LEVEL=warning,transport=trace,annotations=error
+//
+// RMAPI_LOG_FORMAT=json switches to JSON output, useful when running
+// as a daemon. The older RMAPI_TRACE=1 (trace) and RMAPI_TRACE=2
+// (info) are still honored.
 package log
 
 import (
+	"context"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
+	"sync"
 )
 
-var (
-	Trace          *log.Logger
-	Info           *log.Logger
-	Warning        *log.Logger
-	Error          *log.Logger
-	TracingEnabled bool
-	TraceLevel     int
+// Level is the severity of a message.
+type Level = slog.Level
+
+// The levels of the messages, from the most to the least verbose.
+const (
+	LevelTrace   Level = slog.LevelDebug - 4
+	LevelDebug   Level = slog.LevelDebug
+	LevelInfo    Level = slog.LevelInfo
+	LevelWarning Level = slog.LevelWarn
+	LevelError   Level = slog.LevelError
+)
+
+// The subsystems having their own logger.
+const (
+	Main        = "main"
+	Transport   = "transport"
+	Filetree    = "filetree"
+	Annotations = "annotations"
 )
 
-func Init(
-	traceHandle io.Writer,
-	infoHandle io.Writer,
-	warningHandle io.Writer,
-	errorHandle io.Writer) {
+const (
+	levelEnvVar  = "RMAPI_LOG_LEVEL"
+	formatEnvVar = "RMAPI_LOG_FORMAT"
+	traceEnvVar  = "RMAPI_TRACE"
+)
 
-	Trace = log.New(traceHandle,
-		"Trace: ",
-		log.Ldate|log.Ltime|log.Lshortfile)
+// A Logger writes the messages of a module.
+type Logger struct {
+	module string
 
-	Info = log.New(infoHandle,
-		"INFO: ",
-		log.Ldate|log.Ltime|log.Lshortfile)
+	Trace   *log.Logger
+	Info    *log.Logger
+	Warning *log.Logger
+	Error   *log.Logger
+	// Slog is used for messages with attributes
+	Slog *slog.Logger
+}
 
-	Warning = log.New(warningHandle,
-		"WARNING: ",
-		log.Ldate|log.Ltime|log.Lshortfile)
+// Loggers of the main module, for the code which is not part of a subsystem.
+var (
+	Trace   *log.Logger
+	Info    *log.Logger
+	Warning *log.Logger
+	Error   *log.Logger
+)
 
-	Error = log.New(errorHandle,
-		"ERROR: ",
-		log.Ldate|log.Ltime|log.Lshortfile)
+// Config defines how messages are filtered and written.
+type Config struct {
+	// Level applies to the modules not listed in Modules
+	Level   Level
+	Modules map[string]Level
+	JSON    bool
+	Output  io.Writer
 }
+
+var (
+	mu      sync.RWMutex
+	config  Config
+	handler slog.Handler
+)
+
 func init() {
 	InitLog()
 }
 
+// InitLog configures the loggers from the environment.
 func InitLog() {
-	info := ioutil.Discard
-	trace := ioutil.Discard
-	traceRmapi := os.Getenv("RMAPI_TRACE")
-	switch traceRmapi {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	Configure(cfg)
+
+	main := For(Main)
+	Trace, Info, Warning, Error = main.Trace, main.Info, main.Warning, main.Error
+}
+
+// ConfigFromEnv reads the configuration from the environment variables.
+// The default level is warning.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Level:   LevelWarning,
+		Modules: make(map[string]Level),
+		JSON:    strings.EqualFold(os.Getenv(formatEnvVar), "json"),
+		Output:  os.Stderr,
+	}
+
+	switch os.Getenv(traceEnvVar) {
 	case "1":
-		TracingEnabled = true
-		trace = os.Stdout
-		fallthrough
+		cfg.Level = LevelTrace
 	case "2":
-		info = os.Stdout
+		cfg.Level = LevelInfo
+	}
+
+	spec := os.Getenv(levelEnvVar)
+	if spec == "" {
+		return cfg, nil
+	}
+	if err := parseLevels(spec, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid %s: %v", levelEnvVar, err)
+	}
+	return cfg, nil
+}
+
+// parseLevels reads "level,module=level,..." into cfg.
+func parseLevels(spec string, cfg *Config) error {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		module, name, found := strings.Cut(item, "=")
+		if !found {
+			name = module
+		}
+		level, err := ParseLevel(name)
+		if err != nil {
+			return err
+		}
+
+		if found {
+			cfg.Modules[strings.TrimSpace(module)] = level
+		} else {
+			cfg.Level = level
+		}
+	}
+	return nil
+}
+
+// ParseLevel converts a level name into a Level.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarning, nil
+	case "error":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %s", name)
+}
+
+// Configure replaces the configuration of all the loggers,
+// including the ones already created.
+func Configure(cfg Config) {
+	if cfg.Output == nil {
+		cfg.Output = os.Stderr
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       LevelTrace,
+		ReplaceAttr: replaceLevelName,
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	config = cfg
+	if cfg.JSON {
+		handler = slog.NewJSONHandler(cfg.Output, opts)
+	} else {
+		handler = slog.NewTextHandler(cfg.Output, opts)
+	}
+}
+
+// SetLevel changes the default level, the levels set for
+// specific modules are kept.
+func SetLevel(level Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	config.Level = level
+}
+
+// Enabled tells whether messages of a level are written for a module.
+func Enabled(module string, level Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	min, ok := config.Modules[module]
+	if !ok {
+		min = config.Level
+	}
+	return level >= min
+}
+
+// replaceLevelName names the trace level, which slog prints as DEBUG-4.
+func replaceLevelName(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level == LevelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
+	}
+	return a
+}
+
+// For returns the logger of a module.
+func For(module string) *Logger {
+	h := &moduleHandler{module: module}
+	return &Logger{
+		module:  module,
+		Trace:   slog.NewLogLogger(h, LevelTrace),
+		Info:    slog.NewLogLogger(h, LevelInfo),
+		Warning: slog.NewLogLogger(h, LevelWarning),
+		Error:   slog.NewLogLogger(h, LevelError),
+		Slog:    slog.New(h),
 	}
+}
+
+// TraceEnabled tells whether trace messages are written, to
+// avoid building expensive messages for nothing.
+func (l *Logger) TraceEnabled() bool {
+	return Enabled(l.module, LevelTrace)
+}
+
+// moduleHandler filters the records with the level of its module and
+// passes them to the current handler. The handler is looked up for
+// every record so that loggers created before Configure follow it.
+type moduleHandler struct {
+	module string
+	// ops are the WithAttrs and WithGroup calls to replay
+	ops []func(slog.Handler) slog.Handler
+}
+
+func (h *moduleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return Enabled(h.module, level)
+}
+
+func (h *moduleHandler) Handle(ctx context.Context, r slog.Record) error {
+	mu.RLock()
+	target := handler
+	mu.RUnlock()
+
+	target = target.WithAttrs([]slog.Attr{slog.String("module", h.module)})
+	for _, op := range h.ops {
+		target = op(target)
+	}
+	return target.Handle(ctx, r)
+}
+
+func (h *moduleHandler) with(op func(slog.Handler) slog.Handler) *moduleHandler {
+	ops := append(h.ops[:len(h.ops):len(h.ops)], op)
+	return &moduleHandler{module: h.module, ops: ops}
+}
+
+func (h *moduleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(t slog.Handler) slog.Handler { return t.WithAttrs(attrs) })
+}
 
-	Init(trace, info, os.Stdout, os.Stderr)
+func (h *moduleHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(t slog.Handler) slog.Handler { return t.WithGroup(name) })
 }
@@ -0,0 +1,64 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseLevels(t *testing.T) {
+	cfg := Config{Level: LevelWarning, Modules: make(map[string]Level)}
+	if err := parseLevels("info, transport=trace,annotations=error", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Level != LevelInfo || cfg.Modules[Transport] != LevelTrace || cfg.Modules[Annotations] != LevelError {
+		t.Errorf("wrong levels %+v", cfg)
+	}
+
+	if err := parseLevels("transport=loud", &cfg); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestModuleLevels(t *testing.T) {
+	defer InitLog()
+
+	var out bytes.Buffer
+	Configure(Config{
+		Level:   LevelWarning,
+		Modules: map[string]Level{Transport: LevelTrace},
+		JSON:    true,
+		Output:  &out,
+	})
+
+	transport := For(Transport)
+	filetree := For(Filetree)
+
+	transport.Trace.Println("request")
+	filetree.Info.Println("hidden")
+	filetree.Warning.Println("shown")
+
+	if !transport.TraceEnabled() || filetree.TraceEnabled() {
+		t.Error("trace should only be enabled for the transport")
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 messages, got %q", lines)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["level"] != "TRACE" || record["module"] != Transport || record["msg"] != "request" {
+		t.Errorf("wrong record %v", record)
+	}
+
+	// the default level changes, the module level stays
+	SetLevel(LevelError)
+	if Enabled(Filetree, LevelWarning) || !Enabled(Transport, LevelTrace) {
+		t.Error("SetLevel should only change the default level")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/joagonca/rmapi/api"
 	"github.com/joagonca/rmapi/config"
@@ -36,8 +37,42 @@ func parseOfflineCommands(cmd []string) bool {
 	return false
 }
 
+// verbosity counts the -v flags
+type verbosity int
+
+func (v *verbosity) String() string   { return strconv.Itoa(int(*v)) }
+func (v *verbosity) IsBoolFlag() bool { return true }
+
+func (v *verbosity) Set(s string) error {
+	if s == "true" {
+		*v++
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	*v = verbosity(n)
+	return err
+}
+
+// setLogLevel applies the --verbose/--quiet flags on top of the
+// level configured by the environment. Module levels are kept.
+func setLogLevel(verbose verbosity, quiet bool) {
+	switch {
+	case quiet:
+		log.SetLevel(log.LevelError)
+	case verbose == 1:
+		log.SetLevel(log.LevelInfo)
+	case verbose > 1:
+		log.SetLevel(log.LevelTrace)
+	}
+}
+
 func main() {
 	ni := flag.Bool("ni", false, "not interactive (prevents asking for code)")
+	var verbose verbosity
+	flag.Var(&verbose, "v", "verbose output, repeat for trace output")
+	flag.Var(&verbose, "verbose", "same as -v")
+	quiet := flag.Bool("q", false, "only print errors")
+	flag.BoolVar(quiet, "quiet", false, "same as -q")
 	flag.Usage = func() {
 		fmt.Println(`
   help		detailed commands, but the user needs to be logged in
@@ -49,6 +84,7 @@ Offline Commands:
 		flag.PrintDefaults()
 	}
 	flag.Parse()
+	setLogLevel(verbose, *quiet)
 	otherFlags := flag.Args()
 	if parseOfflineCommands(otherFlags) {
 		return
@@ -61,7 +97,7 @@ Offline Commands:
 	for i := 0; i < AUTH_RETRIES; i++ {
 		authCtx := api.AuthHttpCtx(i > 0, *ni)
 
-		userInfo, err = api.ParseToken(authCtx.Tokens.UserToken)
+		userInfo, err = api.ParseToken(authCtx.Tokens().UserToken)
 		if err != nil {
 			log.Trace.Println(err)
 			continue
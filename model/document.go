@@ -22,8 +22,11 @@ type Document struct {
 	Type              string
 	VissibleName      string
 	CurrentPage       int
-	Bookmarked        bool
-	Parent            string
+	// Bookmarked is the pinned (starred) flag, named as in the 1.0 api
+	Bookmarked bool
+	Parent     string
+	// Tags are the names of the tags of the document
+	Tags []string
 }
 
 type MetadataDocument struct {
@@ -33,6 +36,7 @@ type MetadataDocument struct {
 	Type           string
 	Version        int
 	ModifiedClient string
+	Bookmarked     bool
 }
 
 type DeleteDocument struct {
@@ -132,6 +136,7 @@ func (meta MetadataDocument) ToDocument() Document {
 		Type:           meta.Type,
 		Version:        1,
 		ModifiedClient: meta.ModifiedClient,
+		Bookmarked:     meta.Bookmarked,
 	}
 }
 
@@ -143,6 +148,7 @@ func (doc Document) ToMetaDocument() MetadataDocument {
 		Type:           doc.Type,
 		Version:        doc.Version,
 		ModifiedClient: time.Now().UTC().Format(time.RFC3339Nano),
+		Bookmarked:     doc.Bookmarked,
 	}
 }
 
@@ -152,3 +158,16 @@ func (doc Document) ToDeleteDocument() DeleteDocument {
 		Version: doc.Version,
 	}
 }
+
+// DocumentFiles describes the files stored in the cloud for a
+// document, to analyse an account without downloading it.
+type DocumentFiles struct {
+	ID string
+	// Files maps the file names to their size in bytes
+	Files map[string]int64
+	// FileType is "pdf", "epub" or "notebook"
+	FileType string
+	Pages    int
+	// LastOpened is zero when the document has never been opened
+	LastOpened time.Time
+}
@@ -38,6 +38,16 @@ func (node *Node) IsFile() bool {
 	return !node.IsDirectory()
 }
 
+// IsPinned tells whether the entry is pinned (starred) on the tablet.
+func (node *Node) IsPinned() bool {
+	return node.Document.Bookmarked
+}
+
+// Tags returns the names of the tags of the entry.
+func (node *Node) Tags() []string {
+	return node.Document.Tags
+}
+
 func (node *Node) EntyExists(id string) bool {
 	_, ok := node.Children[id]
 	return ok
@@ -0,0 +1,388 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+type graphicsState struct {
+	ctm          Matrix
+	fill, stroke color.RGBA
+	lineWidth    float64
+}
+
+func newGraphicsState() graphicsState {
+	return graphicsState{ctm: Identity, fill: black, stroke: black, lineWidth: 1}
+}
+
+var black = color.RGBA{0, 0, 0, 0xff}
+
+// contentScanner interprets the content of a page and draws it with
+// painter.
+type contentScanner struct {
+	f       *File
+	painter painter
+	depth   int
+}
+
+// A painter draws the content of a page, in default user space. The
+// paths are lists of points x, y, with the start of each subpath.
+type painter interface {
+	fillPath(path []float64, starts []int, c color.RGBA)
+	strokePath(path []float64, starts []int, c color.RGBA, width float64)
+	fillRect(m Matrix, r Rect, c color.RGBA)
+	drawImage(m Matrix, img image.Image)
+	// text draws a string of the given width and font size, m being
+	// the text matrix
+	text(m Matrix, width, size float64, c color.RGBA)
+}
+
+// pageContent returns the decoded content streams of a page.
+func (f *File) pageContent(p *PageObject) ([]byte, error) {
+	contents, err := f.Resolve(p.Dict["Contents"])
+	if err != nil {
+		return nil, err
+	}
+	streams, ok := contents.(Array)
+	if !ok {
+		streams = Array{contents}
+	}
+
+	var b bytes.Buffer
+	for _, obj := range streams {
+		obj, err := f.Resolve(obj)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := obj.(*Stream)
+		if !ok {
+			continue
+		}
+		data, err := s.Decode()
+		if err != nil {
+			return nil, err
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+type textState struct {
+	tm, tlm     Matrix
+	font        Dict
+	size        float64
+	leading     float64
+	scale       float64
+	charSpacing float64
+	wordSpacing float64
+}
+
+func (s *contentScanner) scan(data []byte, resources Dict, gs graphicsState) error {
+	if s.depth > 10 {
+		return errors.New("pdf: too many nested forms")
+	}
+	s.depth++
+	defer func() { s.depth-- }()
+
+	p := parser{data: data}
+	var operands []Object
+	var stack []graphicsState
+	var path []float64 // transformed points of the current path
+	var starts []int   // indexes in path of its subpaths
+	ts := textState{scale: 1}
+
+	addPoint := func(x, y float64) {
+		tx, ty := gs.ctm.Apply(x, y)
+		path = append(path, tx, ty)
+	}
+	num := func(i int) float64 {
+		if i >= len(operands) {
+			return 0
+		}
+		v, _ := Number(operands[i])
+		return v
+	}
+	colorOf := func() color.RGBA {
+		switch len(operands) {
+		case 1:
+			g := uint8(clamp01(num(0)) * 0xff)
+			return color.RGBA{g, g, g, 0xff}
+		case 3:
+			return color.RGBA{uint8(clamp01(num(0)) * 0xff), uint8(clamp01(num(1)) * 0xff), uint8(clamp01(num(2)) * 0xff), 0xff}
+		case 4:
+			k := 1 - clamp01(num(3))
+			return color.RGBA{uint8((1 - clamp01(num(0))) * k * 0xff), uint8((1 - clamp01(num(1))) * k * 0xff), uint8((1 - clamp01(num(2))) * k * 0xff), 0xff}
+		}
+		// patterns are drawn in grey
+		return color.RGBA{0x80, 0x80, 0x80, 0xff}
+	}
+	paint := func(fill, stroke bool) {
+		if len(path) > 0 {
+			if fill {
+				s.painter.fillPath(path, starts, gs.fill)
+			}
+			if stroke {
+				scale := math.Sqrt(math.Abs(gs.ctm[0]*gs.ctm[3] - gs.ctm[1]*gs.ctm[2]))
+				s.painter.strokePath(path, starts, gs.stroke, gs.lineWidth*scale)
+			}
+		}
+		path, starts = path[:0], starts[:0]
+	}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil
+		}
+		c := p.data[p.pos]
+		if c == '/' || c == '(' || c == '<' || c == '[' || c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+			obj, err := p.object()
+			if err != nil {
+				return err
+			}
+			operands = append(operands, obj)
+			continue
+		}
+
+		op := p.keyword()
+		if op == "" {
+			// unexpected delimiter
+			p.pos++
+			operands = operands[:0]
+			continue
+		}
+
+		switch op {
+		case "true", "false", "null":
+			operands = append(operands, op == "true")
+			continue
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			gs.ctm = Matrix{num(0), num(1), num(2), num(3), num(4), num(5)}.Multiply(gs.ctm)
+
+		case "w":
+			gs.lineWidth = num(0)
+
+		case "g", "rg", "k", "sc", "scn":
+			gs.fill = colorOf()
+		case "G", "RG", "K", "SC", "SCN":
+			gs.stroke = colorOf()
+		case "cs":
+			gs.fill = black
+		case "CS":
+			gs.stroke = black
+
+		case "m":
+			starts = append(starts, len(path))
+			addPoint(num(0), num(1))
+		case "l":
+			addPoint(num(0), num(1))
+		case "c":
+			addPoint(num(0), num(1))
+			addPoint(num(2), num(3))
+			addPoint(num(4), num(5))
+		case "v", "y":
+			addPoint(num(0), num(1))
+			addPoint(num(2), num(3))
+		case "re":
+			x, y, w, h := num(0), num(1), num(2), num(3)
+			starts = append(starts, len(path))
+			addPoint(x, y)
+			addPoint(x+w, y)
+			addPoint(x+w, y+h)
+			addPoint(x, y+h)
+		case "S", "s":
+			paint(false, true)
+		case "f", "F", "f*":
+			paint(true, false)
+		case "B", "B*", "b", "b*":
+			paint(true, true)
+		case "n":
+			path, starts = path[:0], starts[:0]
+
+		case "BI":
+			s.painter.fillRect(gs.ctm, Rect{0, 0, 1, 1}, unknownImage)
+			end := bytes.Index(p.data[p.pos:], []byte("EI"))
+			for end >= 0 {
+				at := p.pos + end
+				if at+2 >= len(p.data) || isSpace(p.data[at+2]) {
+					break
+				}
+				next := bytes.Index(p.data[at+2:], []byte("EI"))
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += 2 + next
+			}
+			if end < 0 {
+				return nil
+			}
+			p.pos += end + 2
+		case "Do":
+			if err := s.xobject(resources, operands, gs); err != nil {
+				return err
+			}
+
+		case "BT":
+			ts.tm, ts.tlm = Identity, Identity
+		case "Tf":
+			if name, ok := firstName(operands); ok {
+				fonts, _ := s.f.Get(resources, "Font").(Dict)
+				ts.font, _ = s.f.Get(fonts, name).(Dict)
+			}
+			ts.size = num(len(operands) - 1)
+		case "TL":
+			ts.leading = num(0)
+		case "Tz":
+			ts.scale = num(0) / 100
+		case "Tc":
+			ts.charSpacing = num(0)
+		case "Tw":
+			ts.wordSpacing = num(0)
+		case "Td":
+			ts.tlm = Matrix{1, 0, 0, 1, num(0), num(1)}.Multiply(ts.tlm)
+			ts.tm = ts.tlm
+		case "TD":
+			ts.leading = -num(1)
+			ts.tlm = Matrix{1, 0, 0, 1, num(0), num(1)}.Multiply(ts.tlm)
+			ts.tm = ts.tlm
+		case "Tm":
+			ts.tlm = Matrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+			ts.tm = ts.tlm
+		case "T*":
+			ts.tlm = Matrix{1, 0, 0, 1, 0, -ts.leading}.Multiply(ts.tlm)
+			ts.tm = ts.tlm
+		case "Tj":
+			if str, ok := lastString(operands); ok {
+				s.showText(&ts, gs, str)
+			}
+		case "'", "\"":
+			ts.tlm = Matrix{1, 0, 0, 1, 0, -ts.leading}.Multiply(ts.tlm)
+			ts.tm = ts.tlm
+			if str, ok := lastString(operands); ok {
+				s.showText(&ts, gs, str)
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				items, _ := operands[len(operands)-1].(Array)
+				for _, item := range items {
+					switch v := item.(type) {
+					case String:
+						s.showText(&ts, gs, v)
+					default:
+						if n, ok := Number(v); ok {
+							ts.tm = Matrix{1, 0, 0, 1, -n / 1000 * ts.size * ts.scale, 0}.Multiply(ts.tm)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func firstName(operands []Object) (Name, bool) {
+	if len(operands) == 0 {
+		return "", false
+	}
+	n, ok := operands[0].(Name)
+	return n, ok
+}
+
+func lastString(operands []Object) (String, bool) {
+	if len(operands) == 0 {
+		return nil, false
+	}
+	s, ok := operands[len(operands)-1].(String)
+	return s, ok
+}
+
+// showText draws a string and moves the text position.
+func (s *contentScanner) showText(ts *textState, gs graphicsState, str String) {
+	if ts.size == 0 || len(str) == 0 {
+		return
+	}
+
+	// composite fonts mostly use two bytes per character
+	step := 1
+	if s.f.Get(ts.font, "Subtype") == Name("Type0") {
+		step = 2
+	}
+	widths, _ := s.f.Get(ts.font, "Widths").(Array)
+	firstChar, _ := Number(s.f.Get(ts.font, "FirstChar"))
+
+	var width float64
+	for i := 0; i+step <= len(str); i += step {
+		w := 500.0
+		if step == 1 {
+			if idx := int(str[i]) - int(firstChar); idx >= 0 && idx < len(widths) {
+				if v, ok := Number(widths[idx]); ok && v > 0 {
+					w = v
+				}
+			}
+			if str[i] == ' ' {
+				width += ts.wordSpacing
+			}
+		}
+		width += w/1000*ts.size + ts.charSpacing
+	}
+	width *= ts.scale
+
+	s.painter.text(ts.tm.Multiply(gs.ctm), width, ts.size, gs.fill)
+	ts.tm = Matrix{1, 0, 0, 1, width, 0}.Multiply(ts.tm)
+}
+
+// xobject draws an image or a form.
+func (s *contentScanner) xobject(resources Dict, operands []Object, gs graphicsState) error {
+	name, ok := firstName(operands)
+	if !ok {
+		return nil
+	}
+	xobjects, _ := s.f.Get(resources, "XObject").(Dict)
+	obj, err := s.f.Resolve(xobjects[name])
+	if err != nil {
+		return err
+	}
+	stream, ok := obj.(*Stream)
+	if !ok {
+		return nil
+	}
+
+	switch s.f.Get(stream.Dict, "Subtype") {
+	case Name("Image"):
+		if img, ok := s.f.decodeImage(stream); ok {
+			s.painter.drawImage(gs.ctm, img)
+		} else {
+			s.painter.fillRect(gs.ctm, Rect{0, 0, 1, 1}, unknownImage)
+		}
+	case Name("Form"):
+		if a, ok := s.f.Get(stream.Dict, "Matrix").(Array); ok && len(a) == 6 {
+			var m Matrix
+			for i := range m {
+				m[i], _ = Number(a[i])
+			}
+			gs.ctm = m.Multiply(gs.ctm)
+		}
+		data, err := stream.Decode()
+		if err != nil {
+			return err
+		}
+		formResources, ok := s.f.Get(stream.Dict, "Resources").(Dict)
+		if !ok {
+			formResources = resources
+		}
+		return s.scan(data, formResources, gs)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+func init() {
+	// pdfcpu is used without the configuration and the fonts it
+	// installs in the config directory of the user
+	model.ConfigPath = "disable"
+}
+
+// ErrEncrypted is returned when opening a file which needs a password.
+var ErrEncrypted = errors.New("pdf: the file needs a password")
+
+// readContext reads a file with pdfcpu, opened with password.
+func readContext(r io.ReadSeeker, password string) (*model.Context, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = password
+	conf.OwnerPW = password
+	ctx, err := api.ReadContext(r, conf)
+	if errors.Is(err, pdfcpu.ErrWrongPassword) {
+		if password == "" {
+			return nil, ErrEncrypted
+		}
+		return nil, errors.New("pdf: wrong password")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pdf: %w", err)
+	}
+	return ctx, nil
+}
+
+// Decrypt writes a decrypted copy of a file to w, opened with password,
+// the empty one being enough for the files which only restrict their
+// printing or their editing. The files which are not encrypted are
+// copied as written by pdfcpu.
+func Decrypt(r io.ReadSeeker, w io.Writer, password string) error {
+	ctx, err := readContext(r, password)
+	if err != nil {
+		return err
+	}
+	if ctx.Encrypt != nil {
+		ctx.Cmd = model.DECRYPT
+	}
+	return api.WriteContext(ctx, w)
+}
+
+// rewrite opens the copy of a file written by pdfcpu, which rebuilds
+// the cross-reference of the damaged files and decrypts the ones which
+// only restrict their printing or their editing. The copy is kept in
+// memory.
+func rewrite(r io.ReadSeeker) (*File, error) {
+	var b bytes.Buffer
+	if err := Decrypt(r, &b, ""); err != nil {
+		return nil, err
+	}
+	f := newFile(b.Bytes(), bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err := f.readXref(); err != nil {
+		return nil, err
+	}
+	return f, f.init()
+}
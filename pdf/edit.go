@@ -0,0 +1,230 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf16"
+)
+
+// resourceCategories are the kinds of resources merged by AppendContent.
+var resourceCategories = []Name{"ExtGState", "ColorSpace", "Pattern", "Shading", "XObject", "Font", "Properties"}
+
+// NewFile creates a file without pages, to be filled with NewPage and SetPages.
+func NewFile() *File {
+	var b bytes.Buffer
+	NewDocument().Write(&b)
+	f, err := Open(b.Bytes())
+	if err != nil {
+		panic("pdf: cannot open a generated document: " + err.Error())
+	}
+	return f
+}
+
+// NewPage creates an empty page of the given size in points. It is
+// added to the file by SetPages.
+func (f *File) NewPage(width, height float64) *PageObject {
+	return &PageObject{
+		Dict: Dict{
+			"Type":     Name("Page"),
+			"MediaBox": Rect{0, 0, width, height}.Array(),
+		},
+		inherited: Dict{},
+	}
+}
+
+// DisplayMatrix returns the matrix converting the coordinates of a
+// page as it is shown, rotation included, with the origin at the
+// bottom left corner, into its user space.
+func (f *File) DisplayMatrix(p *PageObject) Matrix {
+	box := f.PageBox(p)
+	switch f.PageRotation(p) {
+	case 90:
+		return Matrix{0, 1, -1, 0, box.URX, box.LLY}
+	case 180:
+		return Matrix{-1, 0, 0, -1, box.URX, box.URY}
+	case 270:
+		return Matrix{0, -1, 1, 0, box.LLX, box.URY}
+	}
+	return Translate(box.LLX, box.LLY)
+}
+
+// AppendContent draws content over a page. The existing content is
+// isolated so that its graphics state doesn't leak into the new one,
+// and the resources used by the new content are added to the page.
+func (f *File) AppendContent(p *PageObject, content []byte, resources Dict) error {
+	dict := p.Dict.Clone()
+
+	contents, err := f.Resolve(dict["Contents"])
+	if err != nil {
+		return err
+	}
+	var streams Array
+	switch v := contents.(type) {
+	case Array:
+		streams = v
+	case *Stream:
+		streams = Array{dict["Contents"]}
+	}
+	if len(streams) > 0 {
+		streams = append(Array{f.Add(&Stream{Dict: Dict{}, Data: []byte("q\n")})}, streams...)
+		content = append([]byte("Q\n"), content...)
+	}
+	streams = append(streams, f.Add(NewStream(nil, content)))
+	dict["Contents"] = streams
+	p.Dict = dict
+	return f.AddResources(p, resources)
+}
+
+// AddResources adds resources to the ones of a page, replacing the
+// resources of the same name.
+func (f *File) AddResources(p *PageObject, resources Dict) error {
+	dict := p.Dict.Clone()
+	res, err := f.Resolve(p.Attr("Resources"))
+	if err != nil {
+		return err
+	}
+	merged := Dict{}
+	if d, ok := res.(Dict); ok {
+		merged = d.Clone()
+	}
+	for _, category := range resourceCategories {
+		added, ok := resources[category].(Dict)
+		if !ok {
+			continue
+		}
+		existing, err := f.Resolve(merged[category])
+		if err != nil {
+			return err
+		}
+		entries := Dict{}
+		if d, ok := existing.(Dict); ok {
+			entries = d.Clone()
+		}
+		for name, v := range added {
+			entries[name] = v
+		}
+		merged[category] = entries
+	}
+	dict["Resources"] = merged
+
+	p.Dict = dict
+	return nil
+}
+
+// SetPages replaces the pages of the file with the given ones, in
+// order. The attributes inherited from the page tree are copied into
+// the pages. A page used more than once is copied, so that links to
+// its first use keep working.
+func (f *File) SetPages(pages []*PageObject) error {
+	root, ok := f.Get(f.trailer, "Root").(Dict)
+	if !ok {
+		return errors.New("pdf: document catalog not found")
+	}
+	treeRef, ok := root["Pages"].(Ref)
+	if !ok {
+		treeRef = f.Add(nil)
+		root = root.Clone()
+		root["Pages"] = treeRef
+		f.Set(f.trailer["Root"].(Ref), root)
+	}
+
+	used := make(map[int]bool)
+	kids := make(Array, 0, len(pages))
+	for _, p := range pages {
+		dict := p.Dict.Clone()
+		for name, v := range p.inherited {
+			if _, ok := dict[name]; !ok {
+				dict[name] = v
+			}
+		}
+		dict["Type"] = Name("Page")
+		dict["Parent"] = treeRef
+
+		ref := p.Ref
+		if ref.Num <= 0 || used[ref.Num] {
+			ref = f.Add(dict)
+		} else {
+			f.Set(ref, dict)
+		}
+		used[ref.Num] = true
+		kids = append(kids, ref)
+	}
+
+	f.Set(treeRef, Dict{
+		"Type":  Name("Pages"),
+		"Kids":  kids,
+		"Count": int64(len(kids)),
+	})
+	return nil
+}
+
+// AddLayer adds an optional content group, a layer of the document
+// which the viewers can hide, shown by default. The content of the
+// layer is marked with "/OC /name BDC ... EMC", name being a resource
+// of the category Properties set to the returned reference.
+func (f *File) AddLayer(name string) (Ref, error) {
+	root, ok := f.Get(f.trailer, "Root").(Dict)
+	if !ok {
+		return Ref{}, errors.New("pdf: document catalog not found")
+	}
+	layer := f.Add(Dict{"Type": Name("OCG"), "Name": String(name)})
+
+	// the layers of the document are kept
+	properties := Dict{}
+	if d, ok := f.Get(root, "OCProperties").(Dict); ok {
+		properties = d.Clone()
+	}
+	groups, _ := f.Get(properties, "OCGs").(Array)
+	properties["OCGs"] = append(append(Array{}, groups...), layer)
+	config := Dict{}
+	if d, ok := f.Get(properties, "D").(Dict); ok {
+		config = d.Clone()
+	}
+	for _, key := range []Name{"Order", "ON"} {
+		list, _ := f.Get(config, key).(Array)
+		config[key] = append(append(Array{}, list...), layer)
+	}
+	properties["D"] = config
+
+	root = root.Clone()
+	root["OCProperties"] = properties
+	f.Set(f.trailer["Root"].(Ref), root)
+	return layer, nil
+}
+
+// AddAnnotation adds an annotation to a page, after the ones it has.
+func (f *File) AddAnnotation(p *PageObject, annot Dict) error {
+	existing, err := f.Resolve(p.Dict["Annots"])
+	if err != nil {
+		return err
+	}
+	var annots Array
+	if a, ok := existing.(Array); ok {
+		annots = append(annots, a...)
+	}
+
+	dict := p.Dict.Clone()
+	dict["Annots"] = append(annots, f.Add(annot))
+	p.Dict = dict
+	return nil
+}
+
+// TextString returns a text string, in UTF-16 when s isn't ASCII.
+func TextString(s string) String {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return String(s)
+	}
+
+	b := []byte{0xfe, 0xff}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return String(b)
+}
@@ -0,0 +1,265 @@
+package pdf
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestDisplayMatrix(t *testing.T) {
+	f, err := Open(generated(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pages[0]
+	box := f.PageBox(p)
+
+	// the top left corner of the page as shown
+	tests := map[int][2]float64{
+		0:   {box.LLX, box.URY},
+		90:  {box.LLX, box.LLY},
+		180: {box.URX, box.LLY},
+		270: {box.URX, box.URY},
+	}
+	for rotation, want := range tests {
+		setRotation(f, p, rotation)
+		_, h := f.DisplaySize(p)
+		x, y := f.DisplayMatrix(p).Apply(0, h)
+		if math.Abs(x-want[0]) > 1e-9 || math.Abs(y-want[1]) > 1e-9 {
+			t.Errorf("rotation %d: expected (%v, %v), got (%v, %v)", rotation, want[0], want[1], x, y)
+		}
+	}
+}
+
+func TestSetPages(t *testing.T) {
+	f, err := Open(generated(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blank := f.NewPage(100, 200)
+	if err := f.AppendContent(pages[1], []byte("0 0 m 10 10 l S"), Dict{"ExtGState": Dict{"GS": Dict{"CA": 0.5}}}); err != nil {
+		t.Fatal(err)
+	}
+	// the second page twice, and a new page
+	if err := f.SetPages([]*PageObject{pages[1], blank, pages[1]}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := f.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	f, err = Open(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(result))
+	}
+	if result[0].Ref != pages[1].Ref || result[2].Ref == pages[1].Ref {
+		t.Error("the first use of a page should keep its object, the next ones should be copies")
+	}
+	if w, h := f.DisplaySize(result[1]); w != 100 || h != 200 {
+		t.Errorf("wrong size of the new page %vx%v", w, h)
+	}
+
+	content, err := f.pageContent(result[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(content, []byte("q\n")) || !bytes.Contains(content, []byte("Q\n0 0 m 10 10 l S")) {
+		t.Errorf("the original content should be isolated, got %q", content)
+	}
+	res, _ := f.Get(result[0].Dict, "Resources").(Dict)
+	if _, ok := f.Get(res, "Font").(Dict); !ok {
+		t.Error("the fonts of the page should be kept")
+	}
+	if gs, _ := f.Get(res, "ExtGState").(Dict); gs["GS"] == nil {
+		t.Error("the new resources should be added")
+	}
+}
+
+func TestImport(t *testing.T) {
+	src, err := Open(generated(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := src.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inherited, err := Open(xrefStreamFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := inherited.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFile()
+	first, err := f.NewImporter(src).Import(pages[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := f.NewImporter(inherited).Import(rotated[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetPages([]*PageObject{first, second}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := f.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	f, err = Open(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(result))
+	}
+	if w, h := f.DisplaySize(result[0]); w != A4Height || h != A4Width {
+		t.Errorf("wrong size of the imported page %vx%v", w, h)
+	}
+	res, _ := f.Get(result[0].Dict, "Resources").(Dict)
+	if _, ok := f.Get(res, "Font").(Dict); !ok {
+		t.Error("the fonts of the page should be copied")
+	}
+	if f.PageRotation(result[1]) != 90 || f.PageBox(result[1]).URX != 200 {
+		t.Error("the inherited attributes should be copied")
+	}
+	want, err := src.pageContent(pages[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.pageContent(result[0]); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("the content should be copied, got %q (%v)", got, err)
+	}
+}
+
+func TestAddLayer(t *testing.T) {
+	f, err := Open(generated(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := f.AddLayer("Background")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := f.AddLayer("reMarkable annotations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := f.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = Open(b.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	root, _ := f.Get(f.Trailer(), "Root").(Dict)
+	properties, ok := f.Get(root, "OCProperties").(Dict)
+	if !ok {
+		t.Fatal("no optional content in the catalog")
+	}
+	groups, _ := f.Get(properties, "OCGs").(Array)
+	if len(groups) != 2 || groups[0] != first || groups[1] != second {
+		t.Errorf("unexpected layers %v", groups)
+	}
+	config, _ := f.Get(properties, "D").(Dict)
+	if on, _ := f.Get(config, "ON").(Array); len(on) != 2 {
+		t.Errorf("the layers should be shown, got %v", on)
+	}
+	layer, _ := f.Get(Dict{"L": second}, "L").(Dict)
+	if layer["Type"] != Name("OCG") || string(layer["Name"].(String)) != "reMarkable annotations" {
+		t.Errorf("unexpected layer %v", layer)
+	}
+}
+
+func TestTextString(t *testing.T) {
+	if got := TextString("plain"); string(got) != "plain" {
+		t.Errorf("unexpected ascii string %q", got)
+	}
+	if got := TextString("é€"); !bytes.Equal(got, []byte{0xfe, 0xff, 0, 0xe9, 0x20, 0xac}) {
+		t.Errorf("unexpected utf-16 string %x", got)
+	}
+}
+
+func TestImportAnnotations(t *testing.T) {
+	src := NewFile()
+	if err := src.SetPages([]*PageObject{src.NewPage(A4Width, A4Height), src.NewPage(A4Width, A4Height)}); err != nil {
+		t.Fatal(err)
+	}
+	pages, err := src.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := Dict{"Type": Name("Annot"), "Subtype": Name("Link"), "Dest": Array{pages[1].Ref, Name("Fit")}}
+	note := Dict{"Type": Name("Annot"), "Subtype": Name("Text"), "P": pages[0].Ref, "Contents": String("note")}
+	for _, annot := range []Dict{link, note} {
+		if err := src.AddAnnotation(pages[0], annot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := NewFile()
+	im := f.NewImporter(src)
+	var imported []*PageObject
+	for _, p := range pages {
+		page, err := im.Import(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imported = append(imported, page)
+	}
+	if err := f.SetPages(imported); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := f.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	f, err = Open(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	annots, _ := f.Get(result[0].Dict, "Annots").(Array)
+	if len(annots) != 2 {
+		t.Fatalf("expected the 2 annotations, got %v", annots)
+	}
+	copiedLink, _ := f.Resolve(annots[0])
+	dest, _ := f.Get(copiedLink.(Dict), "Dest").(Array)
+	if len(dest) == 0 || dest[0] != result[1].Ref {
+		t.Errorf("the link should lead to the imported page, got %v", dest)
+	}
+	copiedNote, _ := f.Resolve(annots[1])
+	if copiedNote.(Dict)["P"] != result[0].Ref {
+		t.Errorf("the annotation should refer to its imported page, got %v", copiedNote)
+	}
+}
@@ -0,0 +1,663 @@
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// File is an existing PDF file opened to be modified. The changes are
+// written as an incremental update appended to the original bytes, so
+// that everything which is not changed is kept as is.
+type File struct {
+	// data is the content of the files opened in memory, the others
+	// are read from src when needed
+	data    []byte
+	src     io.ReaderAt
+	srcSize int
+	xref    map[int]xrefEntry
+	trailer Dict
+	// startxref is the offset of the last cross-reference section
+	startxref  int
+	xrefStream bool
+	size       int
+
+	objects    map[int]Object
+	objStreams map[int]*objStream
+	changed    map[int]Object
+	gens       map[int]int
+}
+
+type xrefEntry struct {
+	offset int
+	gen    int
+	// compressed objects are the index-th object of the object stream
+	compressed bool
+	stream     int
+	index      int
+}
+
+type objStream struct {
+	data    []byte
+	offsets []int
+}
+
+// windowSize is the size of the first read of an object of a file
+// which is not in memory.
+const windowSize = 16 * 1024
+
+// Open reads the structure of a PDF file. Objects are read on demand.
+// The encrypted and the damaged files are rewritten by pdfcpu first,
+// see Decrypt.
+func Open(data []byte) (*File, error) {
+	return open(data, bytes.NewReader(data), int64(len(data)))
+}
+
+// OpenReader reads the structure of a PDF file without loading it in
+// memory, e.g. from an *os.File. Objects are read on demand, r must
+// stay readable until the file is written. The encrypted and the
+// damaged files are rewritten in memory.
+func OpenReader(r io.ReaderAt, size int64) (*File, error) {
+	return open(nil, r, size)
+}
+
+func newFile(data []byte, src io.ReaderAt, size int64) *File {
+	return &File{
+		data:       data,
+		src:        src,
+		srcSize:    int(size),
+		xref:       make(map[int]xrefEntry),
+		objects:    make(map[int]Object),
+		objStreams: make(map[int]*objStream),
+		changed:    make(map[int]Object),
+		gens:       make(map[int]int),
+	}
+}
+
+func open(data []byte, src io.ReaderAt, size int64) (*File, error) {
+	f := newFile(data, src, size)
+	header, err := f.read(0, 1024)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(header, []byte("%PDF-")) {
+		return nil, errors.New("pdf: not a pdf file")
+	}
+
+	if err := f.readXref(); err != nil || f.trailer["Root"] == nil || f.trailer["Encrypt"] != nil {
+		return rewrite(io.NewSectionReader(src, 0, size))
+	}
+	return f, f.init()
+}
+
+// init sets the number of the next object added.
+func (f *File) init() error {
+	if f.trailer["Root"] == nil {
+		return errors.New("pdf: document catalog not found")
+	}
+	if size, ok := f.trailer["Size"].(int64); ok {
+		f.size = int(size)
+	}
+	for num := range f.xref {
+		f.size = max(f.size, num+1)
+	}
+	return nil
+}
+
+// read returns at most n bytes of the file from offset.
+func (f *File) read(offset, n int) ([]byte, error) {
+	n = max(0, min(n, f.srcSize-offset))
+	if f.data != nil {
+		return f.data[offset : offset+n], nil
+	}
+	b := make([]byte, n)
+	if _, err := f.src.ReadAt(b, int64(offset)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return b, nil
+}
+
+// parseAt runs parse with a parser positioned at offset. The files
+// which are not in memory are read by windows, which grow until
+// parse succeeds or the end of the file is reached.
+func (f *File) parseAt(offset int, parse func(p *parser) error) error {
+	if offset < 0 || offset >= f.srcSize {
+		return fmt.Errorf("pdf: invalid offset %d", offset)
+	}
+	if f.data != nil {
+		return parse(&parser{data: f.data, pos: offset})
+	}
+	for n := windowSize; ; n *= 4 {
+		data, err := f.read(offset, n)
+		if err != nil {
+			return err
+		}
+		p := &parser{data: data, truncated: offset+len(data) < f.srcSize}
+		if err := parse(p); err == nil || !p.truncated {
+			return err
+		}
+	}
+}
+
+// readXref reads the chain of cross-reference sections, starting with the last one.
+func (f *File) readXref() error {
+	tailStart := max(0, f.srcSize-1024)
+	tail, err := f.read(tailStart, 1024)
+	if err != nil {
+		return err
+	}
+	idx := bytes.LastIndex(tail, []byte("startxref"))
+	if idx < 0 {
+		return errors.New("pdf: startxref not found")
+	}
+	p := parser{data: tail, pos: idx + len("startxref")}
+	p.skipSpace()
+	start, err := p.number()
+	if err != nil {
+		return err
+	}
+	offset, ok := start.(int64)
+	if !ok || offset <= 0 || int(offset) >= f.srcSize {
+		return errors.New("pdf: invalid startxref")
+	}
+	f.startxref = int(offset)
+
+	visited := make(map[int]bool)
+	for first := true; !visited[int(offset)]; first = false {
+		visited[int(offset)] = true
+		trailer, isStream, err := f.readSection(int(offset))
+		if err != nil {
+			return err
+		}
+		if first {
+			f.trailer, f.xrefStream = trailer, isStream
+		}
+		// hybrid files list their compressed objects in a separate stream
+		if stm, ok := trailer["XRefStm"].(int64); ok && !visited[int(stm)] {
+			visited[int(stm)] = true
+			if _, _, err := f.readSection(int(stm)); err != nil {
+				return err
+			}
+		}
+		if offset, ok = trailer["Prev"].(int64); !ok {
+			break
+		}
+	}
+	return nil
+}
+
+// readSection reads a cross-reference table or stream, entries
+// already known from a newer section are kept.
+func (f *File) readSection(offset int) (Dict, bool, error) {
+	if offset < 0 || offset >= f.srcSize {
+		return nil, false, errors.New("pdf: invalid xref offset")
+	}
+	var trailer Dict
+	var obj Object
+	table := false
+	err := f.parseAt(offset, func(p *parser) (err error) {
+		// a table cut by the end of the window is read again, its
+		// entries are stored only when they are complete
+		if table = p.acceptKeyword("xref"); table {
+			trailer, err = f.readTable(p)
+		} else {
+			_, obj, err = p.indirect(f.length)
+		}
+		return err
+	})
+	if table || err != nil {
+		return trailer, false, err
+	}
+	stream, ok := obj.(*Stream)
+	if !ok || stream.Dict["Type"] != Name("XRef") {
+		return nil, false, errors.New("pdf: invalid xref section")
+	}
+	return stream.Dict, true, f.readXrefStream(stream)
+}
+
+func (f *File) readTable(p *parser) (Dict, error) {
+	for {
+		if p.acceptKeyword("trailer") {
+			obj, err := p.object()
+			if err != nil {
+				return nil, err
+			}
+			trailer, ok := obj.(Dict)
+			if !ok {
+				return nil, errors.New("pdf: invalid trailer")
+			}
+			return trailer, nil
+		}
+
+		p.skipSpace()
+		first, err1 := p.number()
+		p.skipSpace()
+		count, err2 := p.number()
+		start, ok1 := first.(int64)
+		n, ok2 := count.(int64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return nil, errors.New("pdf: invalid xref table")
+		}
+		for i := 0; i < int(n); i++ {
+			p.skipSpace()
+			off, _ := p.number()
+			p.skipSpace()
+			gen, _ := p.number()
+			kind := p.keyword()
+			num := int(start) + i
+			if _, known := f.xref[num]; known {
+				continue
+			}
+			o, _ := off.(int64)
+			g, _ := gen.(int64)
+			switch kind {
+			case "n":
+				f.xref[num] = xrefEntry{offset: int(o), gen: int(g)}
+			case "f":
+				f.xref[num] = xrefEntry{offset: -1, gen: int(g)}
+			default:
+				return nil, errors.New("pdf: invalid xref entry")
+			}
+		}
+	}
+}
+
+func (f *File) readXrefStream(s *Stream) error {
+	data, err := s.Decode()
+	if err != nil {
+		return err
+	}
+
+	w, _ := s.Dict["W"].(Array)
+	if len(w) != 3 {
+		return errors.New("pdf: invalid xref stream widths")
+	}
+	var widths [3]int
+	for i := range widths {
+		v, _ := w[i].(int64)
+		widths[i] = int(v)
+	}
+	entryLen := widths[0] + widths[1] + widths[2]
+	if entryLen == 0 {
+		return errors.New("pdf: invalid xref stream widths")
+	}
+
+	index, _ := s.Dict["Index"].(Array)
+	if index == nil {
+		size, _ := s.Dict["Size"].(int64)
+		index = Array{int64(0), size}
+	}
+
+	field := func(b []byte, def int) int {
+		if len(b) == 0 {
+			return def
+		}
+		v := 0
+		for _, c := range b {
+			v = v<<8 | int(c)
+		}
+		return v
+	}
+
+	for i := 0; i+1 < len(index); i += 2 {
+		start, _ := index[i].(int64)
+		count, _ := index[i+1].(int64)
+		for j := 0; j < int(count); j++ {
+			if len(data) < entryLen {
+				return nil
+			}
+			entry := data[:entryLen]
+			data = data[entryLen:]
+
+			num := int(start) + j
+			if _, known := f.xref[num]; known {
+				continue
+			}
+			kind := field(entry[:widths[0]], 1)
+			a := field(entry[widths[0]:widths[0]+widths[1]], 0)
+			b := field(entry[widths[0]+widths[1]:], 0)
+			switch kind {
+			case 0:
+				f.xref[num] = xrefEntry{offset: -1, gen: b}
+			case 1:
+				f.xref[num] = xrefEntry{offset: a, gen: b}
+			case 2:
+				f.xref[num] = xrefEntry{compressed: true, stream: a, index: b}
+			}
+		}
+	}
+	return nil
+}
+
+// length returns the length of a stream, resolving it if needed.
+func (f *File) length(obj Object) (int, bool) {
+	if ref, ok := obj.(Ref); ok {
+		if f.xref == nil {
+			return 0, false
+		}
+		var err error
+		if obj, err = f.object(ref.Num); err != nil {
+			return 0, false
+		}
+	}
+	n, ok := obj.(int64)
+	return int(n), ok
+}
+
+func (f *File) objStream(num int) (*objStream, error) {
+	if stm, ok := f.objStreams[num]; ok {
+		return stm, nil
+	}
+	obj, err := f.object(num)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := obj.(*Stream)
+	if !ok {
+		return nil, fmt.Errorf("pdf: object %d is not an object stream", num)
+	}
+	data, err := s.Decode()
+	if err != nil {
+		return nil, err
+	}
+	n, _ := s.Dict["N"].(int64)
+	first, _ := s.Dict["First"].(int64)
+
+	stm := &objStream{data: data}
+	p := parser{data: data}
+	for i := 0; i < int(n); i++ {
+		p.skipSpace()
+		p.number()
+		p.skipSpace()
+		off, _ := p.number()
+		v, _ := off.(int64)
+		stm.offsets = append(stm.offsets, int(first)+int(v))
+	}
+	f.objStreams[num] = stm
+	return stm, nil
+}
+
+// object returns the object with the given number, nil when it doesn't exist.
+func (f *File) object(num int) (Object, error) {
+	if obj, ok := f.changed[num]; ok {
+		return obj, nil
+	}
+	if obj, ok := f.objects[num]; ok {
+		return obj, nil
+	}
+	entry, ok := f.xref[num]
+	if !ok || (!entry.compressed && entry.offset < 0) {
+		return nil, nil
+	}
+	// protect against cycles while resolving
+	f.objects[num] = nil
+	obj, err := f.readObject(num, entry)
+	if err != nil {
+		delete(f.objects, num)
+		return nil, err
+	}
+	f.objects[num] = obj
+	return obj, nil
+}
+
+func (f *File) readObject(num int, entry xrefEntry) (Object, error) {
+	if entry.compressed {
+		stm, err := f.objStream(entry.stream)
+		if err != nil {
+			return nil, err
+		}
+		if entry.index >= len(stm.offsets) || stm.offsets[entry.index] >= len(stm.data) {
+			return nil, fmt.Errorf("pdf: object %d not found in its stream", num)
+		}
+		p := parser{data: stm.data, pos: stm.offsets[entry.index]}
+		return p.object()
+	}
+
+	if entry.offset >= f.srcSize {
+		return nil, fmt.Errorf("pdf: invalid offset of object %d", num)
+	}
+	var ref Ref
+	var obj Object
+	err := f.parseAt(entry.offset, func(p *parser) (err error) {
+		ref, obj, err = p.indirect(f.length)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pdf: object %d: %v", num, err)
+	}
+	if ref.Num != num {
+		return nil, fmt.Errorf("pdf: object %d not found at its offset", num)
+	}
+	if s, ok := obj.(*Stream); ok && f.data == nil {
+		// don't keep the whole window
+		s.Data = bytes.Clone(s.Data)
+	}
+	return obj, nil
+}
+
+// Resolve follows references until a direct object.
+func (f *File) Resolve(obj Object) (Object, error) {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(Ref)
+		if !ok {
+			return obj, nil
+		}
+		var err error
+		if obj, err = f.object(ref.Num); err != nil {
+			return nil, err
+		}
+	}
+	return nil, errors.New("pdf: too many levels of references")
+}
+
+// Get returns an entry of a dictionary, resolved. Errors are reported as missing entries.
+func (f *File) Get(d Dict, key Name) Object {
+	obj, err := f.Resolve(d[key])
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// Trailer returns the trailer dictionary of the file.
+func (f *File) Trailer() Dict {
+	return f.trailer
+}
+
+// Set replaces an object. The change is kept in memory until Write.
+func (f *File) Set(ref Ref, obj Object) {
+	f.changed[ref.Num] = obj
+	f.gens[ref.Num] = ref.Gen
+	f.size = max(f.size, ref.Num+1)
+}
+
+// Add adds a new object and returns its reference.
+func (f *File) Add(obj Object) Ref {
+	ref := Ref{Num: f.size}
+	f.Set(ref, obj)
+	return ref
+}
+
+// Modified tells whether objects were set or added.
+func (f *File) Modified() bool {
+	return len(f.changed) > 0
+}
+
+// Write writes the file with its modifications.
+func (f *File) Write(w io.Writer) error {
+	if !f.Modified() {
+		_, err := io.Copy(w, io.NewSectionReader(f.src, 0, int64(f.srcSize)))
+		return err
+	}
+	return f.NewWriter(w).Close()
+}
+
+// A Writer writes a file with its modifications progressively, so
+// that large changes don't have to be kept in memory.
+type Writer struct {
+	f       *File
+	w       *bufio.Writer
+	n       int
+	offsets map[int]int
+	err     error
+}
+
+// NewWriter creates a writer of the file to w. Nothing is written
+// before Flush or Close.
+func (f *File) NewWriter(w io.Writer) *Writer {
+	return &Writer{f: f, w: bufio.NewWriter(w)}
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(b)
+	w.n += n
+	w.err = err
+	return n, err
+}
+
+// Flush writes the objects set or added since the previous call and
+// releases them from memory: reading them again returns their original
+// version. The objects read from a file opened with OpenReader are
+// released too.
+func (w *Writer) Flush() error {
+	f := w.f
+	if w.offsets == nil {
+		w.offsets = make(map[int]int)
+		if _, err := io.Copy(w, io.NewSectionReader(f.src, 0, int64(f.srcSize))); err != nil {
+			return err
+		}
+		if last, err := f.read(f.srcSize-1, 1); err != nil || len(last) == 0 || last[0] != '\n' {
+			w.Write([]byte("\n"))
+		}
+	}
+
+	nums := make([]int, 0, len(f.changed))
+	for num := range f.changed {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	var b bytes.Buffer
+	for _, num := range nums {
+		b.Reset()
+		fmt.Fprintf(&b, "%d %d obj\n", num, f.gens[num])
+		if err := writeObject(&b, f.changed[num]); err != nil {
+			return err
+		}
+		b.WriteString("\nendobj\n")
+		w.offsets[num] = w.n
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+		delete(f.changed, num)
+	}
+
+	f.Release()
+	return w.err
+}
+
+// Release drops the objects read from a file opened with OpenReader,
+// they are read again when needed.
+func (f *File) Release() {
+	if f.data == nil {
+		f.objects = make(map[int]Object)
+		f.objStreams = make(map[int]*objStream)
+	}
+}
+
+// Close writes the remaining objects and the cross-reference section
+// ending the file.
+func (w *Writer) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	f := w.f
+
+	nums := make([]int, 0, len(w.offsets))
+	for num := range w.offsets {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+
+	trailer := Dict{"Size": int64(f.size)}
+	for _, key := range []Name{"Root", "Info", "ID"} {
+		if v, ok := f.trailer[key]; ok {
+			trailer[key] = v
+		}
+	}
+
+	var b bytes.Buffer
+	var startxref int
+	switch {
+	case f.xrefStream:
+		startxref = w.writeXrefStream(&b, trailer, nums)
+	default:
+		trailer["Prev"] = int64(f.startxref)
+		startxref = w.n
+		b.WriteString("xref\n")
+		for i := 0; i < len(nums); {
+			j := i + 1
+			for j < len(nums) && nums[j] == nums[j-1]+1 {
+				j++
+			}
+			fmt.Fprintf(&b, "%d %d\n", nums[i], j-i)
+			for _, num := range nums[i:j] {
+				fmt.Fprintf(&b, "%010d %05d n\r\n", w.offsets[num], f.gens[num])
+			}
+			i = j
+		}
+		b.WriteString("trailer\n")
+		if err := writeObject(&b, trailer); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(&b, "\nstartxref\n%d\n%%%%EOF\n", startxref)
+
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// writeXrefStream ends an update of a file using cross-reference streams.
+func (w *Writer) writeXrefStream(b *bytes.Buffer, trailer Dict, nums []int) int {
+	f := w.f
+	num := f.size
+	offsets := w.offsets
+	offsets[num] = w.n
+	nums = append(nums, num)
+	f.gens[num] = 0
+
+	var data []byte
+	var index Array
+	for i := 0; i < len(nums); {
+		j := i + 1
+		for j < len(nums) && nums[j] == nums[j-1]+1 {
+			j++
+		}
+		index = append(index, int64(nums[i]), int64(j-i))
+		for _, n := range nums[i:j] {
+			off, gen := offsets[n], f.gens[n]
+			data = append(data, 1, byte(off>>24), byte(off>>16), byte(off>>8), byte(off), byte(gen>>8), byte(gen))
+		}
+		i = j
+	}
+
+	dict := trailer.Clone()
+	dict["Type"] = Name("XRef")
+	dict["Size"] = int64(num + 1)
+	dict["Prev"] = int64(f.startxref)
+	dict["W"] = Array{int64(1), int64(4), int64(2)}
+	dict["Index"] = index
+
+	fmt.Fprintf(b, "%d 0 obj\n", num)
+	writeObject(b, NewStream(dict, data))
+	b.WriteString("\nendobj")
+	return offsets[num]
+}
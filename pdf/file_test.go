@@ -0,0 +1,323 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+func generated(t *testing.T) []byte {
+	doc := NewDocument()
+	doc.AddPage(RemarkableWidth, RemarkableHeight)
+	doc.AddPage(A4Height, A4Width)
+	var b bytes.Buffer
+	if err := doc.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// xrefStreamFile builds a file using an object stream and a
+// cross-reference stream with a png predictor.
+func xrefStreamFile() []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n")
+	offsets := make(map[int]int)
+
+	catalog := "<</Type/Catalog/Pages 2 0 R>> "
+	tree := "<</Type/Pages/Kids[3 0 R]/Count 1/Rotate 90/MediaBox[0 0 200 100]>>"
+	header := fmt.Sprintf("1 0 2 %2d ", len(catalog))
+	objects := header + catalog + tree
+	var packed bytes.Buffer
+	zw := zlib.NewWriter(&packed)
+	zw.Write([]byte(objects))
+	zw.Close()
+	offsets[4] = b.Len()
+	fmt.Fprintf(&b, "4 0 obj\n<</Type/ObjStm/N 2/First %d/Filter/FlateDecode/Length %d>>\nstream\n", len(header), packed.Len())
+	b.Write(packed.Bytes())
+	b.WriteString("\nendstream\nendobj\n")
+
+	offsets[3] = b.Len()
+	b.WriteString("3 0 obj\n<</Type/Page/Parent 2 0 R>>\nendobj\n")
+
+	xrefOffset := b.Len()
+	entries := [][]byte{
+		{0, 0, 0, 0},
+		{2, 0, 4, 0},
+		{2, 0, 4, 1},
+		{1, byte(offsets[3] >> 8), byte(offsets[3]), 0},
+		{1, byte(offsets[4] >> 8), byte(offsets[4]), 0},
+		{1, byte(xrefOffset >> 8), byte(xrefOffset), 0},
+	}
+	// png "up" predictor
+	var rows []byte
+	prev := make([]byte, 4)
+	for _, e := range entries {
+		rows = append(rows, 2)
+		for i := range e {
+			rows = append(rows, e[i]-prev[i])
+		}
+		prev = e
+	}
+	var xref bytes.Buffer
+	zw = zlib.NewWriter(&xref)
+	zw.Write(rows)
+	zw.Close()
+	fmt.Fprintf(&b, "5 0 obj\n<</Type/XRef/Size 6/W[1 2 1]/Root 1 0 R/Filter/FlateDecode/DecodeParms<</Columns 4/Predictor 12>>/Length %d>>\nstream\n", xref.Len())
+	b.Write(xref.Bytes())
+	fmt.Fprintf(&b, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", xrefOffset)
+	return b.Bytes()
+}
+
+func TestOpenGenerated(t *testing.T) {
+	f, err := Open(generated(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(pages))
+	}
+	if w, h := f.DisplaySize(pages[1]); w != A4Height || h != A4Width {
+		t.Errorf("wrong size of the second page %vx%v", w, h)
+	}
+}
+
+// setRotation changes the rotation of a page.
+func setRotation(f *File, p *PageObject, angle int) {
+	dict := p.Dict.Clone()
+	dict["Rotate"] = int64(angle)
+	p.Dict = dict
+	f.Set(p.Ref, dict)
+}
+
+func TestUpdateAndReopen(t *testing.T) {
+	for name, data := range map[string][]byte{"table": generated(t), "stream": xrefStreamFile()} {
+		t.Run(name, func(t *testing.T) {
+			f, err := Open(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages, err := f.Pages()
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := f.PageRotation(pages[0])
+			setRotation(f, pages[0], (before+90)%360)
+
+			var b bytes.Buffer
+			if err := f.Write(&b); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(b.Bytes(), data) {
+				t.Fatal("the update must be appended to the original file")
+			}
+
+			f, err = Open(b.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(f.data, data) {
+				t.Error("the updated file should not need to be rewritten")
+			}
+			pages, err = f.Pages()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := f.PageRotation(pages[0]), (before+90)%360; got != want {
+				t.Errorf("expected rotation %d, got %d", want, got)
+			}
+		})
+	}
+}
+
+func TestInheritedAttributes(t *testing.T) {
+	f, err := Open(xrefStreamFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("expected 1 page, got %d", len(pages))
+	}
+	if r := f.PageRotation(pages[0]); r != 90 {
+		t.Errorf("expected inherited rotation 90, got %d", r)
+	}
+	if w, h := f.DisplaySize(pages[0]); w != 100 || h != 200 {
+		t.Errorf("wrong display size %vx%v", w, h)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	data := generated(t)
+	idx := bytes.LastIndex(data, []byte("startxref"))
+	broken := append(append([]byte{}, data[:idx]...), []byte("startxref\n12\n%%EOF\n")...)
+
+	f, err := Open(broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(pages))
+	}
+
+	setRotation(f, pages[0], 180)
+	var b bytes.Buffer
+	if err := f.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = Open(b.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if pages, err = f.Pages(); err != nil || f.PageRotation(pages[0]) != 180 {
+		t.Error("the rotation of the repaired file was not saved")
+	}
+}
+
+func TestOpenEncrypted(t *testing.T) {
+	encrypt := func(user, owner string) []byte {
+		conf := model.NewAESConfiguration(user, owner, 256)
+		conf.Permissions = model.PermissionsNone
+		var b bytes.Buffer
+		if err := api.Encrypt(bytes.NewReader(generated(t)), &b, conf); err != nil {
+			t.Fatal(err)
+		}
+		return b.Bytes()
+	}
+
+	// only the printing and the editing are restricted
+	data := encrypt("", "owner")
+	f, err := OpenReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Trailer()["Encrypt"] != nil {
+		t.Error("the file should be decrypted")
+	}
+	if pages, err := f.Pages(); err != nil || len(pages) != 2 {
+		t.Errorf("expected 2 pages, got %d (%v)", len(pages), err)
+	}
+
+	if _, err := Open(encrypt("user", "owner")); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("expected ErrEncrypted, got %v", err)
+	}
+	var b bytes.Buffer
+	if err := Decrypt(bytes.NewReader(encrypt("user", "owner")), &b, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(b.Bytes()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseObjects(t *testing.T) {
+	p := parser{data: []byte(`<</A (a\(b\)\101\
+c) /B#20C <414> /D [1 2 R -3.5 true null] /E null>>`)}
+	obj, err := p.object()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := obj.(Dict)
+	if s := string(d["A"].(String)); s != "a(b)Ac" {
+		t.Errorf("wrong literal string %q", s)
+	}
+	if s := string(d["B C"].(String)); s != "A@" {
+		t.Errorf("wrong hex string %q", s)
+	}
+	a := d["D"].(Array)
+	if len(a) != 4 || a[0] != (Ref{1, 2}) || a[1] != -3.5 || a[2] != true || a[3] != nil {
+		t.Errorf("wrong array %v", a)
+	}
+	if _, ok := d["E"]; ok {
+		t.Error("null entries should be dropped")
+	}
+
+	var b bytes.Buffer
+	if err := writeObject(&b, d); err != nil {
+		t.Fatal(err)
+	}
+	p = parser{data: b.Bytes()}
+	again, err := p.object()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(again) != fmt.Sprint(obj) {
+		t.Errorf("round trip changed %v into %v", obj, again)
+	}
+}
+
+func TestWriterFlush(t *testing.T) {
+	for name, data := range map[string][]byte{"table": generated(t), "stream": xrefStreamFile()} {
+		t.Run(name, func(t *testing.T) {
+			f, err := OpenReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages, err := f.Pages()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			w := f.NewWriter(&b)
+			for i := 0; i < 10; i++ {
+				page := f.NewPage(A4Width, A4Height)
+				if err := f.AppendContent(page, []byte("0 0 m 10 10 l S"), nil); err != nil {
+					t.Fatal(err)
+				}
+				pages = append(pages, page)
+				if i%3 == 0 {
+					if err := w.Flush(); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if err := f.SetPages(pages); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(b.Bytes(), data) {
+				t.Fatal("the update must be appended to the original file")
+			}
+
+			f, err = Open(b.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(f.data, data) {
+				t.Error("the updated file should not need to be rewritten")
+			}
+			got, err := f.Pages()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(pages) {
+				t.Fatalf("expected %d pages, got %d", len(pages), len(got))
+			}
+			content, err := f.pageContent(got[len(got)-1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Contains(content, []byte("10 10 l")) {
+				t.Error("the content of the flushed pages is missing")
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package pdf
+
+// importedPageKeys are the attributes of the pages kept by Import. The
+// others, such as the structure of the document, may refer to the rest
+// of the source file.
+var importedPageKeys = []Name{"MediaBox", "CropBox", "BleedBox", "TrimBox", "ArtBox", "Rotate", "UserUnit", "Resources", "Contents", "Group", "Annots"}
+
+// An Importer copies pages of a file into another one, with the
+// objects they use. The objects shared by the imported pages are
+// copied once.
+type Importer struct {
+	src, dst *File
+	refs     map[int]Ref
+}
+
+// NewImporter creates an importer of the pages of src into f.
+func (f *File) NewImporter(src *File) *Importer {
+	return &Importer{src: src, dst: f, refs: make(map[int]Ref)}
+}
+
+// Import copies a page of the source file, with its links and other
+// annotations. The page is added to the destination file by SetPages.
+// The links to the pages which are not imported lead nowhere.
+func (im *Importer) Import(p *PageObject) (*PageObject, error) {
+	dict := Dict{"Type": Name("Page")}
+	for _, key := range importedPageKeys {
+		v := p.Attr(key)
+		if v == nil {
+			continue
+		}
+		copied, err := im.copy(v)
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = copied
+	}
+	page := &PageObject{Dict: dict, inherited: Dict{}}
+	if p.Ref.Num > 0 {
+		// the reference the annotations may already refer to
+		ref, ok := im.refs[p.Ref.Num]
+		if !ok {
+			ref = im.dst.Add(nil)
+			im.refs[p.Ref.Num] = ref
+		}
+		page.Ref = ref
+	}
+	return page, nil
+}
+
+// copy returns obj with its references replaced by the ones of the
+// copied objects.
+func (im *Importer) copy(obj Object) (Object, error) {
+	switch v := obj.(type) {
+	case Ref:
+		if ref, ok := im.refs[v.Num]; ok {
+			return ref, nil
+		}
+		// reserved first, for the objects referring to themselves
+		ref := im.dst.Add(nil)
+		im.refs[v.Num] = ref
+		target, err := im.src.object(v.Num)
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := target.(Dict); ok && d["Type"] == Name("Page") {
+			// the pages are set by SetPages when they are imported,
+			// the annotations only refer to them
+			return ref, nil
+		}
+		copied, err := im.copy(target)
+		if err != nil {
+			return nil, err
+		}
+		im.dst.Set(ref, copied)
+		return ref, nil
+	case Dict:
+		d := make(Dict, len(v))
+		for key, value := range v {
+			copied, err := im.copy(value)
+			if err != nil {
+				return nil, err
+			}
+			d[key] = copied
+		}
+		return d, nil
+	case Array:
+		a := make(Array, len(v))
+		for i, value := range v {
+			copied, err := im.copy(value)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = copied
+		}
+		return a, nil
+	case *Stream:
+		d, err := im.copy(v.Dict)
+		if err != nil {
+			return nil, err
+		}
+		return &Stream{Dict: d.(Dict), Data: v.Data}, nil
+	}
+	return obj, nil
+}
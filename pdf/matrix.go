@@ -0,0 +1,50 @@
+package pdf
+
+import "strings"
+
+// Matrix is a PDF transformation matrix [a b c d e f].
+type Matrix [6]float64
+
+// Identity is the matrix which changes nothing.
+var Identity = Matrix{1, 0, 0, 1, 0, 0}
+
+// Multiply returns m x n, the transformation applying m then n.
+func (m Matrix) Multiply(n Matrix) Matrix {
+	return Matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// Apply transforms a point.
+func (m Matrix) Apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+// Scale returns a matrix scaling by sx, sy.
+func Scale(sx, sy float64) Matrix {
+	return Matrix{sx, 0, 0, sy, 0, 0}
+}
+
+// Translate returns a matrix moving by tx, ty.
+func Translate(tx, ty float64) Matrix {
+	return Matrix{1, 0, 0, 1, tx, ty}
+}
+
+// Array returns the matrix as a PDF array.
+func (m Matrix) Array() Array {
+	return Array{m[0], m[1], m[2], m[3], m[4], m[5]}
+}
+
+// String formats the matrix as the operands of the cm operator.
+func (m Matrix) String() string {
+	parts := make([]string, len(m))
+	for i, v := range m {
+		parts[i] = num(v)
+	}
+	return strings.Join(parts, " ")
+}
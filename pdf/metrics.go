@@ -0,0 +1,59 @@
+package pdf
+
+import "strings"
+
+// helveticaWidths are the widths of the printable ASCII characters
+// of Helvetica in 1/1000 of the font size, from the standard metrics.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+// boldScale approximates the extra width of Helvetica-Bold.
+const boldScale = 1.06
+
+// TextWidth returns the width in points of a string.
+func TextWidth(s string, font Font, size float64) float64 {
+	total := 0
+	for _, r := range s {
+		if r >= 0x20 && r < 0x7f {
+			total += helveticaWidths[r-0x20]
+		} else {
+			total += 556
+		}
+	}
+	width := float64(total) * size / 1000
+	if font == HelveticaBold {
+		width *= boldScale
+	}
+	return width
+}
+
+// WrapText splits a text into lines no wider than maxWidth,
+// breaking between words.
+func WrapText(s string, font Font, size, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		line := words[0]
+		for _, word := range words[1:] {
+			if TextWidth(line+" "+word, font, size) > maxWidth {
+				lines = append(lines, line)
+				line = word
+			} else {
+				line += " " + word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,242 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/filter"
+)
+
+// Object is a value read from an existing PDF file: nil, bool, int64,
+// float64, String, Name, Array, Dict, *Stream or Ref.
+type Object interface{}
+
+// Name is a PDF name, without the leading slash.
+type Name string
+
+// String is a PDF string, which may contain binary data.
+type String []byte
+
+// Array is a PDF array.
+type Array []Object
+
+// Dict is a PDF dictionary.
+type Dict map[Name]Object
+
+// Ref is a reference to an indirect object.
+type Ref struct {
+	Num, Gen int
+}
+
+// Stream is a stream object. Data is kept encoded.
+type Stream struct {
+	Dict Dict
+	Data []byte
+}
+
+// Rect is a rectangle in PDF coordinates (origin at the bottom left).
+type Rect struct {
+	LLX, LLY, URX, URY float64
+}
+
+// Width returns the width of the rectangle.
+func (r Rect) Width() float64 {
+	return r.URX - r.LLX
+}
+
+// Height returns the height of the rectangle.
+func (r Rect) Height() float64 {
+	return r.URY - r.LLY
+}
+
+// Intersect returns the part of r inside o.
+func (r Rect) Intersect(o Rect) Rect {
+	r.LLX = max(r.LLX, o.LLX)
+	r.LLY = max(r.LLY, o.LLY)
+	r.URX = max(min(r.URX, o.URX), r.LLX)
+	r.URY = max(min(r.URY, o.URY), r.LLY)
+	return r
+}
+
+// Array returns the rectangle as a PDF array.
+func (r Rect) Array() Array {
+	return Array{r.LLX, r.LLY, r.URX, r.URY}
+}
+
+// Number converts a numeric object to a float.
+func Number(obj Object) (float64, bool) {
+	switch v := obj.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// toRect converts an array of 4 numbers, in any corner order.
+func toRect(obj Object) (Rect, bool) {
+	a, ok := obj.(Array)
+	if !ok || len(a) != 4 {
+		return Rect{}, false
+	}
+	var v [4]float64
+	for i := range a {
+		if v[i], ok = Number(a[i]); !ok {
+			return Rect{}, false
+		}
+	}
+	return Rect{min(v[0], v[2]), min(v[1], v[3]), max(v[0], v[2]), max(v[1], v[3])}, true
+}
+
+// Clone returns a shallow copy of the dictionary.
+func (d Dict) Clone() Dict {
+	c := make(Dict, len(d))
+	for k, v := range d {
+		c[k] = v
+	}
+	return c
+}
+
+// Decode returns the decoded data of a stream, with the filters of
+// pdfcpu. The filters and their parameters must be direct objects, the
+// image filters such as DCTDecode are not decoded.
+func (s *Stream) Decode() ([]byte, error) {
+	filters, params := s.Dict["Filter"], s.Dict["DecodeParms"]
+	if name, ok := filters.(Name); ok {
+		filters, params = Array{name}, Array{params}
+	}
+	list, _ := filters.(Array)
+	paramList, _ := params.(Array)
+
+	data := s.Data
+	for i, f := range list {
+		name, _ := f.(Name)
+		if !slices.Contains(filter.List(), string(name)) {
+			return nil, fmt.Errorf("unsupported filter %v", f)
+		}
+		parms := make(map[string]int)
+		if i < len(paramList) {
+			p, _ := paramList[i].(Dict)
+			for k, v := range p {
+				if n, ok := v.(int64); ok {
+					parms[string(k)] = int(n)
+				}
+			}
+		}
+		fl, err := filter.NewFilter(string(name), parms)
+		if err != nil {
+			return nil, err
+		}
+		r, err := fl.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// writeObject serializes an object.
+func writeObject(b *bytes.Buffer, obj Object) error {
+	switch v := obj.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case int64:
+		b.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	case Name:
+		writeName(b, v)
+	case String:
+		fmt.Fprintf(b, "<%x>", []byte(v))
+	case Ref:
+		fmt.Fprintf(b, "%d %d R", v.Num, v.Gen)
+	case Array:
+		b.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			if err := writeObject(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case Dict:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, string(k))
+		}
+		sort.Strings(keys)
+		b.WriteString("<<")
+		for _, k := range keys {
+			writeName(b, Name(k))
+			b.WriteByte(' ')
+			if err := writeObject(b, v[Name(k)]); err != nil {
+				return err
+			}
+		}
+		b.WriteString(">>")
+	case *Stream:
+		dict := v.Dict.Clone()
+		dict["Length"] = int64(len(v.Data))
+		if err := writeObject(b, dict); err != nil {
+			return err
+		}
+		b.WriteString("\nstream\n")
+		b.Write(v.Data)
+		b.WriteString("\nendstream")
+	default:
+		return errors.New("cannot write object of type " + fmt.Sprintf("%T", obj))
+	}
+	return nil
+}
+
+func writeName(b *bytes.Buffer, n Name) {
+	b.WriteByte('/')
+	for i := 0; i < len(n); i++ {
+		c := n[i]
+		if c < '!' || c > '~' || c == '#' || isDelimiter(c) {
+			fmt.Fprintf(b, "#%02x", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+}
+
+// NewStream creates a stream compressed with FlateDecode.
+func NewStream(dict Dict, data []byte) *Stream {
+	return NewStreamLevel(dict, data, zlib.DefaultCompression)
+}
+
+// NewStreamLevel creates a stream compressed with FlateDecode at a
+// level of compress/zlib.
+func NewStreamLevel(dict Dict, data []byte, level int) *Stream {
+	var b bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&b, level)
+	if err != nil {
+		zw = zlib.NewWriter(&b)
+	}
+	zw.Write(data)
+	zw.Close()
+
+	if dict == nil {
+		dict = Dict{}
+	}
+	dict["Filter"] = Name("FlateDecode")
+	delete(dict, "DecodeParms")
+	return &Stream{Dict: dict, Data: b.Bytes()}
+}
@@ -0,0 +1,134 @@
+package pdf
+
+import "errors"
+
+// inheritable are the page attributes which can be set on the nodes of the page tree.
+var inheritable = []Name{"Resources", "MediaBox", "CropBox", "Rotate"}
+
+// letter is the default size of pages without MediaBox.
+var letter = Rect{0, 0, 612, 792}
+
+// PageObject is a page of an opened File.
+type PageObject struct {
+	Ref  Ref
+	Dict Dict
+	// inherited are the attributes set on the ancestors of the page
+	inherited Dict
+}
+
+// Attr returns an attribute of the page, looking at its ancestors for
+// the inheritable ones. References are not resolved.
+func (p *PageObject) Attr(name Name) Object {
+	if v, ok := p.Dict[name]; ok {
+		return v
+	}
+	return p.inherited[name]
+}
+
+// Pages returns the pages of the file in order.
+func (f *File) Pages() ([]*PageObject, error) {
+	root, ok := f.Get(f.trailer, "Root").(Dict)
+	if !ok {
+		return nil, errors.New("pdf: document catalog not found")
+	}
+	var pages []*PageObject
+	visited := make(map[int]bool)
+	if err := f.walkPages(root["Pages"], Dict{}, visited, &pages); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+func (f *File) walkPages(node Object, inherited Dict, visited map[int]bool, pages *[]*PageObject) error {
+	ref, isRef := node.(Ref)
+	if isRef {
+		if visited[ref.Num] {
+			return errors.New("pdf: loop in the page tree")
+		}
+		visited[ref.Num] = true
+	}
+	obj, err := f.Resolve(node)
+	if err != nil {
+		return err
+	}
+	dict, ok := obj.(Dict)
+	if !ok {
+		return errors.New("pdf: invalid page tree")
+	}
+
+	kids, isTree := f.Get(dict, "Kids").(Array)
+	if !isTree && dict["Type"] != Name("Pages") {
+		if !isRef {
+			return errors.New("pdf: page is not an indirect object")
+		}
+		*pages = append(*pages, &PageObject{Ref: ref, Dict: dict, inherited: inherited})
+		return nil
+	}
+
+	attrs := inherited.Clone()
+	for _, name := range inheritable {
+		if v, ok := dict[name]; ok {
+			attrs[name] = v
+		}
+	}
+	for _, kid := range kids {
+		if err := f.walkPages(kid, attrs, visited, pages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PageBox returns the visible area of a page: its crop box, limited
+// to its media box.
+func (f *File) PageBox(p *PageObject) Rect {
+	media, ok := f.box(p, "MediaBox")
+	if !ok {
+		media = letter
+	}
+	if crop, ok := f.box(p, "CropBox"); ok {
+		return crop.Intersect(media)
+	}
+	return media
+}
+
+func (f *File) box(p *PageObject, name Name) (Rect, bool) {
+	obj, err := f.Resolve(p.Attr(name))
+	if err != nil {
+		return Rect{}, false
+	}
+	if a, ok := obj.(Array); ok {
+		resolved := make(Array, len(a))
+		for i := range a {
+			resolved[i], _ = f.Resolve(a[i])
+		}
+		obj = resolved
+	}
+	r, ok := toRect(obj)
+	if !ok || r.Width() <= 0 || r.Height() <= 0 {
+		return Rect{}, false
+	}
+	return r, true
+}
+
+// PageRotation returns the rotation of a page in degrees clockwise:
+// 0, 90, 180 or 270.
+func (f *File) PageRotation(p *PageObject) int {
+	obj, _ := f.Resolve(p.Attr("Rotate"))
+	v, _ := Number(obj)
+	return normalizeRotation(int(v))
+}
+
+func normalizeRotation(angle int) int {
+	angle = (angle/90*90)%360 + 360
+	return angle % 360
+}
+
+// DisplaySize returns the size of a page as shown, taking its rotation into account.
+func (f *File) DisplaySize(p *PageObject) (width, height float64) {
+	box := f.PageBox(p)
+	if f.PageRotation(p)%180 == 90 {
+		return box.Height(), box.Width()
+	}
+	return box.Width(), box.Height()
+}
@@ -0,0 +1,373 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// parser reads objects from the bytes of a PDF file.
+type parser struct {
+	data []byte
+	pos  int
+	// truncated is set when data is only a part of the file, which
+	// may end before the object being read
+	truncated bool
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0:
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// skipSpace skips white spaces and comments.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '%' {
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		if !isSpace(c) {
+			return
+		}
+		p.pos++
+	}
+}
+
+// keyword reads a sequence of regular characters.
+func (p *parser) keyword() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.data) && !isSpace(p.data[p.pos]) && !isDelimiter(p.data[p.pos]) {
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// peekKeyword tells whether the next token is the keyword, and consumes it if so.
+func (p *parser) acceptKeyword(kw string) bool {
+	save := p.pos
+	if p.keyword() == kw {
+		return true
+	}
+	p.pos = save
+	return false
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("pdf: offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// object reads a direct object or a reference.
+func (p *parser) object() (Object, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of file")
+	}
+
+	switch c := p.data[p.pos]; {
+	case c == '/':
+		return p.name(), nil
+	case c == '(':
+		return p.literalString()
+	case c == '<':
+		if p.pos+1 < len(p.data) && p.data[p.pos+1] == '<' {
+			return p.dict()
+		}
+		return p.hexString()
+	case c == '[':
+		return p.array()
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return p.numberOrRef()
+	}
+
+	switch kw := p.keyword(); kw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		return nil, p.errorf("unexpected character %q", p.data[p.pos])
+	default:
+		return nil, p.errorf("unexpected keyword %s", kw)
+	}
+}
+
+func (p *parser) name() Name {
+	p.pos++ // slash
+	var b []byte
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if isSpace(c) || isDelimiter(c) {
+			break
+		}
+		if c == '#' && p.pos+2 < len(p.data) {
+			if v, err := strconv.ParseUint(string(p.data[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				p.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		p.pos++
+	}
+	return Name(b)
+}
+
+func (p *parser) literalString() (Object, error) {
+	p.pos++ // parenthesis
+	var b []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return String(b), nil
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				break
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+func (p *parser) hexString() (Object, error) {
+	p.pos++ // <
+	end := bytes.IndexByte(p.data[p.pos:], '>')
+	if end < 0 {
+		return nil, p.errorf("unterminated hex string")
+	}
+	var digits []byte
+	for _, c := range p.data[p.pos : p.pos+end] {
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	p.pos += end + 1
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	for i := range b {
+		v, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return nil, p.errorf("invalid hex string")
+		}
+		b[i] = byte(v)
+	}
+	return String(b), nil
+}
+
+func (p *parser) array() (Object, error) {
+	p.pos++ // [
+	a := Array{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return a, nil
+		}
+		obj, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, obj)
+	}
+}
+
+func (p *parser) dict() (Object, error) {
+	p.pos += 2 // <<
+	d := Dict{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unterminated dictionary")
+		}
+		if bytes.HasPrefix(p.data[p.pos:], []byte(">>")) {
+			p.pos += 2
+			return d, nil
+		}
+		if p.data[p.pos] != '/' {
+			return nil, p.errorf("dictionary key is not a name")
+		}
+		key := p.name()
+		value, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		// a null value is the same as a missing key
+		if value != nil {
+			d[key] = value
+		}
+	}
+}
+
+// number reads an integer or a real.
+func (p *parser) number() (Object, error) {
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if !(c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9')) {
+			break
+		}
+		p.pos++
+	}
+	s := string(p.data[start:p.pos])
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		// some writers produce numbers such as 0.00-5, read them as 0
+		return float64(0), nil
+	}
+	return f, nil
+}
+
+// numberOrRef reads a number, or a reference "num gen R".
+func (p *parser) numberOrRef() (Object, error) {
+	n, err := p.number()
+	if err != nil {
+		return nil, err
+	}
+	num, ok := n.(int64)
+	if !ok || num < 0 {
+		return n, nil
+	}
+
+	save := p.pos
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '9' {
+		gen, _ := p.number()
+		if g, ok := gen.(int64); ok && p.acceptKeyword("R") {
+			return Ref{int(num), int(g)}, nil
+		}
+	}
+	p.pos = save
+	return n, nil
+}
+
+// errNotObject is returned when no indirect object starts at an offset.
+var errNotObject = errors.New("pdf: no object at offset")
+
+// errTruncated is returned when a stream continues after the data.
+var errTruncated = errors.New("pdf: truncated data")
+
+// indirect reads "num gen obj ... endobj" at the current position.
+// length resolves the /Length of streams when it is a reference.
+func (p *parser) indirect(length func(Object) (int, bool)) (Ref, Object, error) {
+	num, err := p.number()
+	if err != nil {
+		return Ref{}, nil, err
+	}
+	p.skipSpace()
+	gen, _ := p.number()
+	n, ok1 := num.(int64)
+	g, ok2 := gen.(int64)
+	if !ok1 || !ok2 || !p.acceptKeyword("obj") {
+		return Ref{}, nil, errNotObject
+	}
+	ref := Ref{int(n), int(g)}
+
+	obj, err := p.object()
+	if err != nil {
+		return ref, nil, err
+	}
+	dict, ok := obj.(Dict)
+	if !ok || !p.acceptKeyword("stream") {
+		return ref, obj, nil
+	}
+
+	// the data starts after an end of line
+	if p.pos < len(p.data) && p.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+	start := p.pos
+
+	size, known := length(dict["Length"])
+	if known && p.truncated && start+size+len("endstream") > len(p.data) {
+		return ref, nil, errTruncated
+	}
+	if known && size >= 0 && start+size <= len(p.data) {
+		end := p.pos + size
+		after := parser{data: p.data, pos: end}
+		if after.acceptKeyword("endstream") {
+			p.pos = after.pos
+			return ref, &Stream{Dict: dict, Data: p.data[start:end]}, nil
+		}
+	}
+
+	// wrong length, look for the end of the stream
+	end := bytes.Index(p.data[start:], []byte("endstream"))
+	if end < 0 {
+		return ref, nil, p.errorf("unterminated stream")
+	}
+	p.pos = start + end + len("endstream")
+	data := p.data[start : start+end]
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\r"))
+	return ref, &Stream{Dict: dict, Data: data}, nil
+}
@@ -0,0 +1,243 @@
+// Package pdf writes simple PDF documents: text with the standard
+// Helvetica fonts, lines, rectangles and links between pages.
+//
+// Coordinates are in points (1/72 inch) with the origin at the top
+// left corner of the page and y going down, unlike PDF itself.
+//
+// Existing files can be opened with Open to read the content of their
+// pages and to add objects, such as the drawings of the annotations.
+// The changes are appended as an incremental update. The encrypted
+// and the damaged files are rewritten by pdfcpu first.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page sizes in points.
+const (
+	// RemarkableWidth and RemarkableHeight match the display of the tablet
+	RemarkableWidth  = 445
+	RemarkableHeight = 594
+	A4Width          = 595
+	A4Height         = 842
+)
+
+// Font is one of the standard fonts available in every PDF reader.
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold"}
+
+// Document is a PDF document being built.
+type Document struct {
+	Title string
+	pages []*Page
+}
+
+// NewDocument creates an empty document.
+func NewDocument() *Document {
+	return &Document{}
+}
+
+// Page is a page of a Document.
+type Page struct {
+	Width, Height float64
+	index         int
+	content       bytes.Buffer
+	links         []link
+}
+
+type link struct {
+	x, y, w, h float64
+	target     int
+}
+
+// AddPage appends a page of the given size in points.
+func (d *Document) AddPage(width, height float64) *Page {
+	p := &Page{Width: width, Height: height, index: len(d.pages)}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Pages returns the pages added so far.
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// Index returns the position of the page in the document, starting at 0.
+func (p *Page) Index() int {
+	return p.index
+}
+
+// y converts a coordinate from the top of the page.
+func (p *Page) y(y float64) float64 {
+	return p.Height - y
+}
+
+// Text writes a line of text with its baseline at y.
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		font, num(size), num(x), num(p.y(y)), escape(s))
+}
+
+// Line draws a line of the given width.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(p.y(y1)), num(x2), num(p.y(y2)))
+}
+
+// Rect draws the outline of a rectangle, or fills it.
+func (p *Page) Rect(x, y, w, h, lineWidth float64, fill bool) {
+	op := "S"
+	if fill {
+		op = "f"
+	}
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re %s\n",
+		num(lineWidth), num(x), num(p.y(y+h)), num(w), num(h), op)
+}
+
+// SetGray sets the color of the following drawings,
+// from 0 (black) to 1 (white).
+func (p *Page) SetGray(gray float64) {
+	fmt.Fprintf(&p.content, "%s G %s g\n", num(gray), num(gray))
+}
+
+// Link makes a rectangle of the page jump to another page when tapped.
+func (p *Page) Link(x, y, w, h float64, target *Page) {
+	p.links = append(p.links, link{x, y, w, h, target.index})
+}
+
+// Write encodes the document.
+func (d *Document) Write(w io.Writer) error {
+	if len(d.pages) == 0 {
+		d.AddPage(RemarkableWidth, RemarkableHeight)
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(content string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), content)
+	}
+
+	// objects: catalog, pages, info, fonts, then the page and
+	// content of every page, and finally the links
+	const fontRef = 4
+	pageRef := func(i int) int {
+		return fontRef + len(fontNames) + i*2
+	}
+	linkRef := pageRef(len(d.pages))
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageRef(i))
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (rmapi) >>", escape(d.Title)))
+
+	fonts := make([]string, len(fontNames))
+	for i := range fontNames {
+		fonts[i] = fmt.Sprintf("/F%d %d 0 R", i, fontRef+i)
+	}
+	for _, name := range fontNames {
+		obj(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+
+	for i, p := range d.pages {
+		var annots []string
+		for range p.links {
+			annots = append(annots, fmt.Sprintf("%d 0 R", linkRef))
+			linkRef++
+		}
+		annotsEntry := ""
+		if len(annots) > 0 {
+			annotsEntry = fmt.Sprintf(" /Annots [%s]", strings.Join(annots, " "))
+		}
+
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s >> >> /Contents %d 0 R%s >>",
+			num(p.Width), num(p.Height), strings.Join(fonts, " "), pageRef(i)+1, annotsEntry))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(p.content.Bytes())
+		zw.Close()
+
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", len(offsets), compressed.Len())
+		out.Write(compressed.Bytes())
+		out.WriteString("\nendstream\nendobj\n")
+	}
+
+	for _, p := range d.pages {
+		for _, l := range p.links {
+			obj(fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect [%s %s %s %s] /Border [0 0 0] /Dest [%d 0 R /Fit] >>",
+				num(l.x), num(p.y(l.y+l.h)), num(l.x+l.w), num(p.y(l.y)), pageRef(l.target)))
+		}
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// num formats a number without useless decimals.
+func num(f float64) string {
+	s := fmt.Sprintf("%.2f", f)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// winAnsi maps the punctuation of WinAnsiEncoding outside of latin-1.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// EncodeText returns the string operand of a text showing operator,
+// encoded in the WinAnsi encoding of the fonts of the package.
+func EncodeText(s string) string {
+	return "(" + escape(s) + ")"
+}
+
+// escape encodes a string in WinAnsi and escapes the special characters.
+func escape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			sb.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			// latin-1 characters have the same code in WinAnsi
+			fmt.Fprintf(&sb, "\\%03o", r)
+		case winAnsi[r] != 0:
+			fmt.Fprintf(&sb, "\\%03o", winAnsi[r])
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
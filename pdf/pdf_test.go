@@ -0,0 +1,67 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	doc := NewDocument()
+	doc.Title = "Test (1)"
+	first := doc.AddPage(RemarkableWidth, RemarkableHeight)
+	second := doc.AddPage(A4Width, A4Height)
+	first.Text(50, 50, HelveticaBold, 20, "Hello (world) é")
+	first.Line(10, 10, 100, 10, 1)
+	first.Rect(10, 20, 30, 40, 0.5, true)
+	first.Link(10, 20, 30, 40, second)
+
+	var b bytes.Buffer
+	if err := doc.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) {
+		t.Fatal("missing pdf header")
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatal("wrong xref offset")
+	}
+
+	// every object must start at its offset
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if len(entries) != 10 {
+		t.Errorf("expected 10 objects, got %d", len(entries))
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		prefix := fmt.Sprintf("%d 0 obj", i+1)
+		if !bytes.HasPrefix(out[offset:], []byte(prefix)) {
+			t.Errorf("object %d not found at offset %d", i+1, offset)
+		}
+	}
+
+	if !bytes.Contains(out, []byte("/Title (Test \\(1\\))")) {
+		t.Error("title not escaped")
+	}
+	if !bytes.Contains(out, []byte("/Dest [8 0 R /Fit]")) {
+		t.Error("link to the second page not found")
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	lines := WrapText("one two three four\n\nfive", Helvetica, 10, TextWidth("one two three", Helvetica, 10))
+	expected := []string{"one two three", "four", "", "five"}
+	if fmt.Sprintf("%q", lines) != fmt.Sprintf("%q", expected) {
+		t.Errorf("got %q, expected %q", lines, expected)
+	}
+}
@@ -0,0 +1,249 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/vector"
+)
+
+// unknownImage is the color of the images which can't be decoded.
+var unknownImage = color.RGBA{0xc0, 0xc0, 0xc0, 0xff}
+
+// RenderPage draws a page as shown into an image of width by height
+// pixels, on a white background. It is a rough rendering meant for the
+// previews such as the thumbnails: the curves follow their control
+// points, the text is drawn as bars of its color, and the images which
+// aren't jpeg or 8 bits gray, rgb or cmyk are drawn in grey.
+func (f *File) RenderPage(p *PageObject, width, height int) (*image.RGBA, error) {
+	data, err := f.pageContent(p)
+	if err != nil {
+		return nil, err
+	}
+	resources, _ := f.Resolve(p.Attr("Resources"))
+	res, _ := resources.(Dict)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	// from user space to the display, then to the pixels from the top
+	w, h := f.DisplaySize(p)
+	display, ok := f.DisplayMatrix(p).invert()
+	if !ok || w <= 0 || h <= 0 {
+		return img, nil
+	}
+	toPixels := display.Multiply(Matrix{float64(width) / w, 0, 0, -float64(height) / h, 0, float64(height)})
+
+	s := &contentScanner{f: f, painter: &rasterPainter{img: img, m: toPixels}}
+	if err := s.scan(data, res, newGraphicsState()); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// invert returns the inverse of a matrix, false when it has none.
+func (m Matrix) invert() (Matrix, bool) {
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 {
+		return Matrix{}, false
+	}
+	return Matrix{
+		m[3] / det,
+		-m[1] / det,
+		-m[2] / det,
+		m[0] / det,
+		(m[2]*m[5] - m[3]*m[4]) / det,
+		(m[1]*m[4] - m[0]*m[5]) / det,
+	}, true
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// rasterPainter draws the content of a page into an image, m converting
+// default user space into its pixels.
+type rasterPainter struct {
+	img *image.RGBA
+	m   Matrix
+}
+
+func (p *rasterPainter) rasterizer() *vector.Rasterizer {
+	b := p.img.Bounds()
+	return vector.NewRasterizer(b.Dx(), b.Dy())
+}
+
+func (p *rasterPainter) point(x, y float64) (float32, float32) {
+	x, y = p.m.Apply(x, y)
+	return float32(x), float32(y)
+}
+
+func (p *rasterPainter) paint(r *vector.Rasterizer, c color.RGBA) {
+	r.DrawOp = draw.Over
+	r.Draw(p.img, p.img.Bounds(), image.NewUniform(c), image.Point{})
+}
+
+// subpaths calls fn with the points of each subpath of a path.
+func subpaths(path []float64, starts []int, fn func(points []float64)) {
+	if len(starts) == 0 || starts[0] != 0 {
+		starts = append([]int{0}, starts...)
+	}
+	for i, start := range starts {
+		end := len(path)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		if end-start >= 2 {
+			fn(path[start:end])
+		}
+	}
+}
+
+func (p *rasterPainter) fillPath(path []float64, starts []int, c color.RGBA) {
+	r := p.rasterizer()
+	subpaths(path, starts, func(points []float64) {
+		r.MoveTo(p.point(points[0], points[1]))
+		for i := 2; i+1 < len(points); i += 2 {
+			r.LineTo(p.point(points[i], points[i+1]))
+		}
+		r.ClosePath()
+	})
+	p.paint(r, c)
+}
+
+// strokePath draws each segment of the path as a rectangle, at least a
+// pixel wide.
+func (p *rasterPainter) strokePath(path []float64, starts []int, c color.RGBA, width float64) {
+	scale := math.Sqrt(math.Abs(p.m[0]*p.m[3] - p.m[1]*p.m[2]))
+	half := math.Max(width*scale, 1) / 2
+	r := p.rasterizer()
+	subpaths(path, starts, func(points []float64) {
+		for i := 0; i+3 < len(points); i += 2 {
+			x0, y0 := p.m.Apply(points[i], points[i+1])
+			x1, y1 := p.m.Apply(points[i+2], points[i+3])
+			length := math.Hypot(x1-x0, y1-y0)
+			if length == 0 {
+				continue
+			}
+			nx, ny := -(y1-y0)/length*half, (x1-x0)/length*half
+			r.MoveTo(float32(x0+nx), float32(y0+ny))
+			r.LineTo(float32(x1+nx), float32(y1+ny))
+			r.LineTo(float32(x1-nx), float32(y1-ny))
+			r.LineTo(float32(x0-nx), float32(y0-ny))
+			r.ClosePath()
+		}
+	})
+	p.paint(r, c)
+}
+
+func (p *rasterPainter) fillRect(m Matrix, rect Rect, c color.RGBA) {
+	m = m.Multiply(p.m)
+	r := p.rasterizer()
+	r.MoveTo(float32Point(m.Apply(rect.LLX, rect.LLY)))
+	r.LineTo(float32Point(m.Apply(rect.URX, rect.LLY)))
+	r.LineTo(float32Point(m.Apply(rect.URX, rect.URY)))
+	r.LineTo(float32Point(m.Apply(rect.LLX, rect.URY)))
+	r.ClosePath()
+	p.paint(r, c)
+}
+
+func float32Point(x, y float64) (float32, float32) {
+	return float32(x), float32(y)
+}
+
+// drawImage draws an image on the unit square transformed by m, its
+// first row at the top.
+func (p *rasterPainter) drawImage(m Matrix, img image.Image) {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 {
+		return
+	}
+	fromImage := Matrix{1 / float64(b.Dx()), 0, 0, -1 / float64(b.Dy()), -float64(b.Min.X) / float64(b.Dx()), 1 + float64(b.Min.Y)/float64(b.Dy())}
+	s := fromImage.Multiply(m).Multiply(p.m)
+	xdraw.ApproxBiLinear.Transform(p.img, f64.Aff3{s[0], s[2], s[4], s[1], s[3], s[5]}, img, b, xdraw.Over, nil)
+}
+
+// decodeImage reads an image XObject: the jpeg ones, and the ones of
+// 8 bits per component in gray, rgb or cmyk.
+func (f *File) decodeImage(s *Stream) (image.Image, bool) {
+	filter := f.Get(s.Dict, "Filter")
+	if filters, ok := filter.(Array); ok && len(filters) == 1 {
+		filter = filters[0]
+	}
+	if filter == Name("DCTDecode") {
+		img, err := jpeg.Decode(bytes.NewReader(s.Data))
+		return img, err == nil
+	}
+
+	if mask, _ := f.Get(s.Dict, "ImageMask").(bool); mask {
+		return nil, false
+	}
+	bits, _ := Number(f.Get(s.Dict, "BitsPerComponent"))
+	w, _ := Number(f.Get(s.Dict, "Width"))
+	h, _ := Number(f.Get(s.Dict, "Height"))
+	width, height := int(w), int(h)
+	if bits != 8 || width <= 0 || height <= 0 {
+		return nil, false
+	}
+	components := f.colorComponents(f.Get(s.Dict, "ColorSpace"))
+	if components == 0 {
+		return nil, false
+	}
+	data, err := s.Decode()
+	if err != nil || len(data) < width*height*components {
+		return nil, false
+	}
+
+	rect := image.Rect(0, 0, width, height)
+	switch components {
+	case 1:
+		return &image.Gray{Pix: data, Stride: width, Rect: rect}, true
+	case 3:
+		img := image.NewRGBA(rect)
+		for i := 0; i < width*height; i++ {
+			copy(img.Pix[i*4:], data[i*3:i*3+3])
+			img.Pix[i*4+3] = 0xff
+		}
+		return img, true
+	default:
+		return &image.CMYK{Pix: data, Stride: width * 4, Rect: rect}, true
+	}
+}
+
+// colorComponents returns the number of components of a color space:
+// 1 for gray, 3 for rgb, 4 for cmyk, and 0 for the other ones.
+func (f *File) colorComponents(space Object) int {
+	switch space {
+	case Name("DeviceGray"), Name("CalGray"):
+		return 1
+	case Name("DeviceRGB"), Name("CalRGB"):
+		return 3
+	case Name("DeviceCMYK"):
+		return 4
+	}
+	if a, ok := space.(Array); ok && len(a) == 2 && a[0] == Name("ICCBased") {
+		if profile, err := f.Resolve(a[1]); err == nil {
+			if s, ok := profile.(*Stream); ok {
+				if n, _ := Number(f.Get(s.Dict, "N")); n == 1 || n == 3 || n == 4 {
+					return int(n)
+				}
+			}
+		}
+	}
+	if a, ok := space.(Array); ok && len(a) > 0 && (a[0] == Name("CalGray") || a[0] == Name("CalRGB")) {
+		return f.colorComponents(a[0])
+	}
+	return 0
+}
+
+// text draws the glyphs as bars of their color, enough for the previews.
+func (p *rasterPainter) text(m Matrix, width, size float64, c color.RGBA) {
+	c.A = 0x80
+	p.fillRect(m, Rect{0, 0, width, 0.5 * size}, c)
+}
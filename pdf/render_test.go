@@ -0,0 +1,55 @@
+package pdf
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestRenderPage(t *testing.T) {
+	f := NewFile()
+	page := f.NewPage(100, 100)
+	red := NewStream(Dict{
+		"Type":             Name("XObject"),
+		"Subtype":          Name("Image"),
+		"Width":            int64(1),
+		"Height":           int64(1),
+		"ColorSpace":       Name("DeviceRGB"),
+		"BitsPerComponent": int64(8),
+	}, []byte{0xff, 0, 0})
+	// a blue square at the bottom left, a red image at the top right
+	// and a line of text at the top left
+	content := "0 0 1 rg 10 10 30 30 re f q 40 0 0 40 50 50 cm /Im0 Do Q BT /F1 10 Tf 10 80 Td (Title) Tj ET"
+	if err := f.AppendContent(page, []byte(content), Dict{"XObject": Dict{"Im0": f.Add(red)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := f.RenderPage(page, 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{25, 75, color.RGBA{0, 0, 0xff, 0xff}},
+		{70, 30, color.RGBA{0xff, 0, 0, 0xff}},
+		{5, 5, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+		{95, 95, color.RGBA{0xff, 0xff, 0xff, 0xff}},
+	} {
+		if got := img.RGBAAt(tc.x, tc.y); got != tc.want {
+			t.Errorf("pixel %d,%d: expected %v, got %v", tc.x, tc.y, tc.want, got)
+		}
+	}
+	if got := img.RGBAAt(15, 18); got.R == 0xff {
+		t.Errorf("expected the text to be drawn, got %v", got)
+	}
+
+	// the blue square shown at the top left once rotated
+	setRotation(f, page, 90)
+	if img, err = f.RenderPage(page, 100, 100); err != nil {
+		t.Fatal(err)
+	}
+	if got := img.RGBAAt(25, 25); got != (color.RGBA{0, 0, 0xff, 0xff}) {
+		t.Errorf("expected the square at the top left of the rotated page, got %v", got)
+	}
+}
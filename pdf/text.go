@@ -0,0 +1,353 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"unicode/utf16"
+)
+
+// textExtractor collects the text shown by the content of a page.
+type textExtractor struct {
+	f     *File
+	b     strings.Builder
+	depth int
+	// decoders caches the decoding of the fonts, by object number
+	decoders map[int]*fontDecoder
+}
+
+// PageText returns the text shown on a page, in the order of the
+// content stream. Lines are separated by newlines. Characters are
+// decoded with the ToUnicode map of the fonts when there is one, the
+// codes of the other simple fonts are read as WinAnsi.
+func (f *File) PageText(p *PageObject) (string, error) {
+	data, err := f.pageContent(p)
+	if err != nil {
+		return "", err
+	}
+	resources, _ := f.Resolve(p.Attr("Resources"))
+	res, _ := resources.(Dict)
+
+	e := &textExtractor{f: f, decoders: make(map[int]*fontDecoder)}
+	if err := e.scan(data, res); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(e.b.String()), nil
+}
+
+// space adds a separator, unless there is already one.
+func (e *textExtractor) space(sep byte) {
+	s := e.b.String()
+	if len(s) == 0 {
+		return
+	}
+	last := s[len(s)-1]
+	if last == '\n' || (last == ' ' && sep == ' ') {
+		return
+	}
+	e.b.WriteByte(sep)
+}
+
+func (e *textExtractor) scan(data []byte, resources Dict) error {
+	if e.depth > 10 {
+		return errors.New("pdf: too many nested forms")
+	}
+	e.depth++
+	defer func() { e.depth-- }()
+
+	p := parser{data: data}
+	var operands []Object
+	var font *fontDecoder
+
+	num := func(i int) float64 {
+		if i >= len(operands) {
+			return 0
+		}
+		v, _ := Number(operands[i])
+		return v
+	}
+	show := func(str String) {
+		if font != nil {
+			e.b.WriteString(font.decode(str))
+		}
+	}
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil
+		}
+		c := p.data[p.pos]
+		if c == '/' || c == '(' || c == '<' || c == '[' || c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+			obj, err := p.object()
+			if err != nil {
+				return err
+			}
+			operands = append(operands, obj)
+			continue
+		}
+
+		op := p.keyword()
+		if op == "" {
+			p.pos++
+			operands = operands[:0]
+			continue
+		}
+
+		switch op {
+		case "true", "false", "null":
+			operands = append(operands, op == "true")
+			continue
+		case "BI":
+			end := bytes.Index(p.data[p.pos:], []byte("EI"))
+			if end < 0 {
+				return nil
+			}
+			p.pos += end + 2
+		case "Do":
+			if err := e.xobject(resources, operands); err != nil {
+				return err
+			}
+		case "ET":
+			e.space('\n')
+		case "Tf":
+			if name, ok := firstName(operands); ok {
+				font = e.font(resources, name)
+			}
+		case "Td", "TD":
+			if num(1) != 0 {
+				e.space('\n')
+			} else if num(0) != 0 {
+				e.space(' ')
+			}
+		case "T*", "Tm":
+			e.space('\n')
+		case "Tj":
+			if str, ok := lastString(operands); ok {
+				show(str)
+			}
+		case "'", "\"":
+			e.space('\n')
+			if str, ok := lastString(operands); ok {
+				show(str)
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				items, _ := operands[len(operands)-1].(Array)
+				for _, item := range items {
+					switch v := item.(type) {
+					case String:
+						show(v)
+					default:
+						// a large move to the right separates words
+						if n, ok := Number(v); ok && n < -200 {
+							e.space(' ')
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// xobject reads the text of a form.
+func (e *textExtractor) xobject(resources Dict, operands []Object) error {
+	name, ok := firstName(operands)
+	if !ok {
+		return nil
+	}
+	xobjects, _ := e.f.Get(resources, "XObject").(Dict)
+	obj, err := e.f.Resolve(xobjects[name])
+	if err != nil {
+		return err
+	}
+	stream, ok := obj.(*Stream)
+	if !ok || e.f.Get(stream.Dict, "Subtype") != Name("Form") {
+		return nil
+	}
+	data, err := stream.Decode()
+	if err != nil {
+		return err
+	}
+	formResources, ok := e.f.Get(stream.Dict, "Resources").(Dict)
+	if !ok {
+		formResources = resources
+	}
+	return e.scan(data, formResources)
+}
+
+// font returns the decoder of a font of the resources.
+func (e *textExtractor) font(resources Dict, name Name) *fontDecoder {
+	fonts, _ := e.f.Get(resources, "Font").(Dict)
+	ref, isRef := fonts[name].(Ref)
+	if isRef {
+		if d, ok := e.decoders[ref.Num]; ok {
+			return d
+		}
+	}
+	dict, ok := e.f.Get(fonts, name).(Dict)
+	if !ok {
+		return nil
+	}
+	d := e.f.newFontDecoder(dict)
+	if isRef {
+		e.decoders[ref.Num] = d
+	}
+	return d
+}
+
+// A fontDecoder converts the codes of a font to text.
+type fontDecoder struct {
+	// composite fonts use two bytes per code
+	composite bool
+	toUnicode map[uint32]string
+	// codeLen is the number of bytes of the codes of toUnicode
+	codeLen int
+}
+
+func (f *File) newFontDecoder(font Dict) *fontDecoder {
+	d := &fontDecoder{composite: f.Get(font, "Subtype") == Name("Type0"), codeLen: 1}
+	if d.composite {
+		d.codeLen = 2
+	}
+	obj, err := f.Resolve(font["ToUnicode"])
+	if s, ok := obj.(*Stream); ok && err == nil {
+		if data, err := s.Decode(); err == nil {
+			d.toUnicode, d.codeLen = parseCMap(data, d.codeLen)
+		}
+	}
+	return d
+}
+
+func (d *fontDecoder) decode(str String) string {
+	var b strings.Builder
+	if d.toUnicode == nil {
+		// without a map, the codes of composite fonts are glyph
+		// identifiers which cannot be converted
+		if !d.composite {
+			for _, c := range str {
+				b.WriteRune(simpleRune(c))
+			}
+		}
+		return b.String()
+	}
+	for i := 0; i+d.codeLen <= len(str); i += d.codeLen {
+		var code uint32
+		for _, c := range str[i : i+d.codeLen] {
+			code = code<<8 | uint32(c)
+		}
+		if s, ok := d.toUnicode[code]; ok {
+			b.WriteString(s)
+		} else if !d.composite {
+			b.WriteRune(simpleRune(byte(code)))
+		}
+	}
+	return b.String()
+}
+
+// simpleRune decodes a code of a simple font without map, read as
+// WinAnsi which is latin-1 with some punctuation.
+func simpleRune(c byte) rune {
+	for r, code := range winAnsi {
+		if code == c {
+			return r
+		}
+	}
+	return rune(c)
+}
+
+// parseCMap reads the bfchar and bfrange sections of a ToUnicode
+// map. It returns the map and the length of its codes.
+func parseCMap(data []byte, codeLen int) (map[uint32]string, int) {
+	m := make(map[uint32]string)
+	p := parser{data: data}
+	var operands []Object
+	section := ""
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			break
+		}
+		c := p.data[p.pos]
+		if c == '/' || c == '(' || c == '<' || c == '[' || c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+			obj, err := p.object()
+			if err != nil {
+				break
+			}
+			if section != "" {
+				operands = append(operands, obj)
+			}
+			continue
+		}
+		op := p.keyword()
+		if op == "" {
+			p.pos++
+			continue
+		}
+		switch op {
+		case "beginbfchar", "beginbfrange":
+			section = op
+			operands = operands[:0]
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(String)
+				dst, ok2 := operands[i+1].(String)
+				if ok1 && ok2 && len(src) > 0 {
+					codeLen = len(src)
+					m[code(src)] = utf16String(dst)
+				}
+			}
+			section = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(String)
+				hi, ok2 := operands[i+1].(String)
+				if !ok1 || !ok2 || len(lo) == 0 {
+					continue
+				}
+				codeLen = len(lo)
+				from, to := code(lo), code(hi)
+				if to < from || to-from > 0xffff {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case String:
+					runes := utf16.Decode(utf16Units(dst))
+					for c := from; c <= to && len(runes) > 0; c++ {
+						m[c] = string(runes)
+						runes[len(runes)-1]++
+					}
+				case Array:
+					for j, item := range dst {
+						if s, ok := item.(String); ok && from+uint32(j) <= to {
+							m[from+uint32(j)] = utf16String(s)
+						}
+					}
+				}
+			}
+			section = ""
+		}
+	}
+	return m, codeLen
+}
+
+func code(s String) uint32 {
+	var c uint32
+	for _, b := range s {
+		c = c<<8 | uint32(b)
+	}
+	return c
+}
+
+func utf16Units(s String) []uint16 {
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return units
+}
+
+func utf16String(s String) string {
+	return string(utf16.Decode(utf16Units(s)))
+}
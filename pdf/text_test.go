@@ -0,0 +1,57 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPageText(t *testing.T) {
+	doc := NewDocument()
+	page := doc.AddPage(A4Width, A4Height)
+	page.Text(50, 50, Helvetica, 12, "Quarterly forecast")
+	page.Text(50, 70, Helvetica, 12, "Café – 2024")
+	var b bytes.Buffer
+	if err := doc.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Open(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := f.PageText(pages[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Quarterly forecast\nCafé – 2024"; text != want {
+		t.Errorf("expected %q, got %q", want, text)
+	}
+}
+
+func TestToUnicode(t *testing.T) {
+	cmap := []byte(`/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+2 beginbfchar
+<0003> <0020>
+<0011> <0048>
+endbfchar
+2 beginbfrange
+<0020> <0022> <0061>
+<0030> <0031> [<0066> <FB01>]
+endbfrange
+endcmap`)
+	m, codeLen := parseCMap(cmap, 2)
+	if codeLen != 2 {
+		t.Errorf("expected codes of 2 bytes, got %d", codeLen)
+	}
+	d := &fontDecoder{composite: true, toUnicode: m, codeLen: codeLen}
+	got := d.decode(String{0x00, 0x11, 0x00, 0x20, 0x00, 0x22, 0x00, 0x03, 0x00, 0x31, 0x00, 0x30})
+	if want := "Hac ﬁf"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
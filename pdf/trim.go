@@ -0,0 +1,156 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// minTrim is the fraction of a page which must be removed for it to be cropped.
+const minTrim = 0.02
+
+// boundsPainter accumulates the area covered by the content of a page.
+// The white drawings are skipped, they are usually page backgrounds.
+type boundsPainter struct {
+	r     Rect
+	empty bool
+}
+
+func (b *boundsPainter) add(x, y float64) {
+	if math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return
+	}
+	if b.empty {
+		b.r = Rect{x, y, x, y}
+		b.empty = false
+		return
+	}
+	b.r.LLX, b.r.LLY = min(b.r.LLX, x), min(b.r.LLY, y)
+	b.r.URX, b.r.URY = max(b.r.URX, x), max(b.r.URY, y)
+}
+
+func isWhite(c color.RGBA) bool {
+	return c.R >= 0xfc && c.G >= 0xfc && c.B >= 0xfc
+}
+
+func (b *boundsPainter) addPath(path []float64, c color.RGBA) {
+	if isWhite(c) {
+		return
+	}
+	for i := 0; i+1 < len(path); i += 2 {
+		b.add(path[i], path[i+1])
+	}
+}
+
+func (b *boundsPainter) fillPath(path []float64, starts []int, c color.RGBA) {
+	b.addPath(path, c)
+}
+
+func (b *boundsPainter) strokePath(path []float64, starts []int, c color.RGBA, width float64) {
+	b.addPath(path, c)
+}
+
+func (b *boundsPainter) fillRect(m Matrix, r Rect, c color.RGBA) {
+	if isWhite(c) {
+		return
+	}
+	for _, p := range [][2]float64{{r.LLX, r.LLY}, {r.URX, r.LLY}, {r.LLX, r.URY}, {r.URX, r.URY}} {
+		b.add(m.Apply(p[0], p[1]))
+	}
+}
+
+func (b *boundsPainter) drawImage(m Matrix, img image.Image) {
+	b.fillRect(m, Rect{0, 0, 1, 1}, black)
+}
+
+func (b *boundsPainter) text(m Matrix, width, size float64, c color.RGBA) {
+	// glyphs go a little below the baseline
+	b.fillRect(m, Rect{0, -0.2 * size, width, size}, c)
+}
+
+// ContentBounds returns the area of a page where something is drawn:
+// paths, text and images, white shapes excepted. The result is in
+// default user space and is false for a blank page. Text extents are
+// estimated from the font widths when available.
+func (f *File) ContentBounds(p *PageObject) (Rect, bool, error) {
+	data, err := f.pageContent(p)
+	if err != nil {
+		return Rect{}, false, err
+	}
+	resources, _ := f.Resolve(p.Attr("Resources"))
+	res, _ := resources.(Dict)
+
+	b := &boundsPainter{empty: true}
+	s := &contentScanner{f: f, painter: b}
+	if err := s.scan(data, res, newGraphicsState()); err != nil {
+		return Rect{}, false, err
+	}
+	if b.empty {
+		return Rect{}, false, nil
+	}
+	r := b.r.Intersect(f.PageBox(p))
+	return r, r.Width() > 0 && r.Height() > 0, nil
+}
+
+// TrimMargins crops the white margins of the pages of a file, keeping
+// margin points around their content. Pages whose content cannot be
+// read are kept as they are. The crop boxes are set by pdfcpu, which
+// keeps the encryption of the files only restricting their printing or
+// their editing. It returns the updated file and the number of cropped
+// pages.
+func TrimMargins(data []byte, margin float64) ([]byte, int, error) {
+	f, err := Open(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	crops := make(map[int]Rect)
+	for i, p := range pages {
+		content, ok, err := f.ContentBounds(p)
+		if err != nil || !ok {
+			continue
+		}
+		box := f.PageBox(p)
+		crop := Rect{
+			content.LLX - margin, content.LLY - margin,
+			content.URX + margin, content.URY + margin,
+		}.Intersect(box)
+
+		if crop.Width()*crop.Height() > (1-minTrim)*box.Width()*box.Height() {
+			continue
+		}
+		crops[i+1] = crop
+	}
+	if len(crops) == 0 {
+		return data, 0, nil
+	}
+
+	conf := model.NewDefaultConfiguration()
+	conf.Cmd = model.CROP
+	ctx, err := api.ReadValidateAndOptimize(bytes.NewReader(data), conf)
+	if err != nil {
+		return nil, 0, fmt.Errorf("pdf: %w", err)
+	}
+	for page, crop := range crops {
+		box := &model.Box{Rect: types.NewRectangle(crop.LLX, crop.LLY, crop.URX, crop.URY)}
+		if err := ctx.Crop(types.IntSet{page: true}, box); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var b bytes.Buffer
+	if err := api.Write(ctx, &b, conf); err != nil {
+		return nil, 0, err
+	}
+	return b.Bytes(), len(crops), nil
+}
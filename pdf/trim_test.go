@@ -0,0 +1,93 @@
+package pdf
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+func trimmedDocument(t *testing.T) []byte {
+	doc := NewDocument()
+	page := doc.AddPage(A4Width, A4Height)
+	// white backgrounds don't count
+	page.SetGray(1)
+	page.Rect(0, 0, A4Width, A4Height, 0, true)
+	page.SetGray(0)
+	page.Line(100, 200, 300, 200, 1)
+	page.Text(100, 400, Helvetica, 10, "Hello")
+	doc.AddPage(A4Width, A4Height)
+
+	var b bytes.Buffer
+	if err := doc.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestTrimMargins(t *testing.T) {
+	out, cropped, err := TrimMargins(trimmedDocument(t), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cropped != 1 {
+		t.Fatalf("expected 1 cropped page, got %d", cropped)
+	}
+
+	f, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Rect{90, A4Height - 400 - 2 - 10, 310, A4Height - 200 + 10}
+	got := f.PageBox(pages[0])
+	for _, d := range []float64{got.LLX - want.LLX, got.LLY - want.LLY, got.URX - want.URX, got.URY - want.URY} {
+		if math.Abs(d) > 0.01 {
+			t.Fatalf("expected crop box %v, got %v", want, got)
+		}
+	}
+	if box := f.PageBox(pages[1]); box != (Rect{0, 0, A4Width, A4Height}) {
+		t.Errorf("the blank page should not be cropped, got %v", box)
+	}
+}
+
+func TestTrimMarginsEncrypted(t *testing.T) {
+	// only the printing and the editing are restricted
+	conf := model.NewAESConfiguration("", "owner", 256)
+	conf.Permissions = model.PermissionsNone
+	var b bytes.Buffer
+	if err := api.Encrypt(bytes.NewReader(trimmedDocument(t)), &b, conf); err != nil {
+		t.Fatal(err)
+	}
+
+	out, cropped, err := TrimMargins(b.Bytes(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cropped != 1 {
+		t.Fatalf("expected 1 cropped page, got %d", cropped)
+	}
+	ctx, err := api.ReadContext(bytes.NewReader(out), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Encrypt == nil {
+		t.Error("the file should stay encrypted")
+	}
+	f, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := f.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if box := f.PageBox(pages[0]); box.Width() >= A4Width {
+		t.Errorf("the first page should be cropped, got %v", box)
+	}
+}
@@ -0,0 +1,181 @@
+// Package queue keeps the operations which failed, to retry them later.
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The kinds of operations.
+const (
+	Upload = "upload"
+	Delete = "delete"
+)
+
+// An Operation is a failed change of the cloud.
+type Operation struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Name is the name of the uploaded document, Path the path of
+	// the deleted entry
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+	// File is the copy of the uploaded file kept in the queue
+	File string `json:"file,omitempty"`
+	// ParentID is the directory of an upload, DocumentID the deleted entry
+	ParentID   string    `json:"parent_id,omitempty"`
+	DocumentID string    `json:"document_id,omitempty"`
+	Added      time.Time `json:"added"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error"`
+}
+
+func (op *Operation) String() string {
+	if op.Kind == Upload {
+		return fmt.Sprintf("upload %s", op.Name)
+	}
+	return fmt.Sprintf("delete %s", op.Path)
+}
+
+// Queue is the list of the operations to retry, in the order they
+// failed. It is stored in a directory with the uploaded files.
+type Queue struct {
+	dir        string
+	Operations []*Operation `json:"operations"`
+}
+
+// DefaultDir returns the directory of the queue in the cache directory.
+func DefaultDir() (string, error) {
+	cachedir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cachedir, "rmapi", "queue"), nil
+}
+
+// Load reads the queue stored in dir, which is empty if there is none.
+func Load(dir string) (*Queue, error) {
+	q := &Queue{dir: dir}
+	b, err := os.ReadFile(q.file())
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, q); err != nil {
+		return nil, fmt.Errorf("cannot read the queue %s: %v", q.file(), err)
+	}
+	return q, nil
+}
+
+func (q *Queue) file() string {
+	return filepath.Join(q.dir, "queue.json")
+}
+
+// Save writes the queue.
+func (q *Queue) Save() error {
+	if err := os.MkdirAll(q.dir, 0700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(q.file(), b, 0600)
+}
+
+// AddUpload queues the upload of a local file under the name of the
+// document, the file is copied because it may be temporary.
+func (q *Queue) AddUpload(name, file, parentID string, cause error) (*Operation, error) {
+	op := newOperation(Upload, cause)
+	op.Name = name
+	op.ParentID = parentID
+
+	dst := filepath.Join(q.dir, op.ID, filepath.Base(file))
+	if err := copyFile(file, dst); err != nil {
+		return nil, err
+	}
+	op.File = dst
+
+	q.Operations = append(q.Operations, op)
+	return op, q.Save()
+}
+
+// AddDelete queues the deletion of an entry.
+func (q *Queue) AddDelete(path, documentID string, cause error) (*Operation, error) {
+	op := newOperation(Delete, cause)
+	op.Path = path
+	op.DocumentID = documentID
+
+	q.Operations = append(q.Operations, op)
+	return op, q.Save()
+}
+
+func newOperation(kind string, cause error) *Operation {
+	return &Operation{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Added:     time.Now(),
+		Attempts:  1,
+		LastError: cause.Error(),
+	}
+}
+
+// Remove removes an operation and its files.
+func (q *Queue) Remove(op *Operation) error {
+	for i, o := range q.Operations {
+		if o.ID == op.ID {
+			q.Operations = append(q.Operations[:i], q.Operations[i+1:]...)
+			break
+		}
+	}
+	if op.File != "" {
+		os.RemoveAll(filepath.Dir(op.File))
+	}
+	return q.Save()
+}
+
+// Clear removes every operation.
+func (q *Queue) Clear() error {
+	for _, op := range q.Operations {
+		if op.File != "" {
+			os.RemoveAll(filepath.Dir(op.File))
+		}
+	}
+	q.Operations = nil
+	return q.Save()
+}
+
+// Failed records a new failure of an operation.
+func (q *Queue) Failed(op *Operation, err error) error {
+	op.Attempts++
+	op.LastError = err.Error()
+	return q.Save()
+}
+
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(t.TempDir(), "notes.pdf")
+	if err := os.WriteFile(src, []byte("%PDF-1.4"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := q.AddUpload("notes", src, "parent", errors.New("offline"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.AddDelete("/old", "doc", errors.New("429 Too Many Requests")); err != nil {
+		t.Fatal(err)
+	}
+	// the upload must not depend on the original file
+	os.Remove(src)
+
+	q, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Operations) != 2 {
+		t.Fatalf("expected 2 operations, got %d", len(q.Operations))
+	}
+	op := q.Operations[0]
+	if op.Kind != Upload || op.Name != "notes" || op.ParentID != "parent" || op.LastError != "offline" {
+		t.Errorf("unexpected operation %+v", op)
+	}
+	if data, err := os.ReadFile(op.File); err != nil || string(data) != "%PDF-1.4" {
+		t.Errorf("the uploaded file is not kept: %v", err)
+	}
+
+	if err := q.Failed(q.Operations[1], errors.New("offline")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Remove(upload); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(upload.File); !os.IsNotExist(err) {
+		t.Error("the file of a removed operation should be deleted")
+	}
+
+	q, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Operations) != 1 || q.Operations[0].Attempts != 2 || q.Operations[0].String() != "delete /old" {
+		t.Errorf("unexpected queue %+v", q.Operations)
+	}
+
+	if err := q.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if q, _ = Load(dir); len(q.Operations) != 0 {
+		t.Error("the queue should be empty")
+	}
+}
@@ -0,0 +1,47 @@
+package report
+
+import (
+	"io"
+	"strings"
+
+	"github.com/joagonca/rmapi/pdf"
+)
+
+const (
+	margin     = 40
+	fontSize   = 10
+	lineHeight = 14
+	titleSize  = 20
+)
+
+// WritePDF renders the report as a PDF sized for the tablet.
+func (r *Report) WritePDF(w io.Writer) error {
+	doc := pdf.NewDocument()
+	doc.Title = "Account report"
+
+	page := doc.AddPage(pdf.RemarkableWidth, pdf.RemarkableHeight)
+	page.Text(margin, margin+titleSize, pdf.HelveticaBold, titleSize, doc.Title)
+	y := float64(margin + titleSize + 2*lineHeight)
+
+	width := pdf.RemarkableWidth - 2*margin
+	for _, line := range r.Lines() {
+		// keep the indentation of the wrapped lines
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		x := float64(margin) + pdf.TextWidth(line[:indent], pdf.Helvetica, fontSize)
+
+		for _, part := range pdf.WrapText(line[indent:], pdf.Helvetica, fontSize, float64(width)-(x-margin)) {
+			if y > pdf.RemarkableHeight-margin {
+				page = doc.AddPage(pdf.RemarkableWidth, pdf.RemarkableHeight)
+				y = margin + lineHeight
+			}
+			font := pdf.Helvetica
+			if indent == 0 && len(part) > 0 && part[len(part)-1] == ':' {
+				font = pdf.HelveticaBold
+			}
+			page.Text(x, y, font, fontSize, part)
+			y += lineHeight
+		}
+	}
+
+	return doc.Write(w)
+}
@@ -0,0 +1,215 @@
+// Package report builds an overview of the documents of an account.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joagonca/rmapi/filetree"
+	"github.com/joagonca/rmapi/model"
+	"github.com/joagonca/rmapi/util"
+)
+
+// DefaultTop is the default length of the most annotated list.
+const DefaultTop = 10
+
+// A FolderCount is the number of documents directly in a folder.
+type FolderCount struct {
+	Path      string `json:"path"`
+	Documents int    `json:"documents"`
+}
+
+// A DocumentCount associates a document with a number, such as its
+// number of annotated pages.
+type DocumentCount struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+// Report is an overview of an account.
+type Report struct {
+	Generated time.Time `json:"generated"`
+	Documents int       `json:"documents"`
+	Folders   int       `json:"folders"`
+	Pages     int       `json:"pages"`
+	// DocumentTypes counts the documents by type (pdf, epub, notebook)
+	DocumentTypes map[string]int `json:"document_types"`
+	// Storage is the size in bytes used by each kind of file
+	Storage   map[string]int64 `json:"storage"`
+	PerFolder []FolderCount    `json:"per_folder"`
+	// NeverOpened lists the notebooks which have never been opened
+	NeverOpened []string `json:"never_opened"`
+	// MostAnnotated lists the documents with the most pages having strokes
+	MostAnnotated []DocumentCount `json:"most_annotated"`
+}
+
+// Build creates the report of the documents found under root, using
+// the description of their files. At most top documents are listed
+// as the most annotated.
+func Build(root *model.Node, files []*model.DocumentFiles, top int) *Report {
+	byID := make(map[string]*model.DocumentFiles, len(files))
+	for _, f := range files {
+		byID[f.ID] = f
+	}
+
+	r := &Report{
+		Generated:     time.Now(),
+		DocumentTypes: make(map[string]int),
+		Storage:       make(map[string]int64),
+		PerFolder:     []FolderCount{},
+		NeverOpened:   []string{},
+		MostAnnotated: []DocumentCount{},
+	}
+	perFolder := make(map[string]int)
+
+	filetree.WalkTree(root, filetree.FileTreeVistor{
+		Visit: func(node *model.Node, nodePath []string) bool {
+			if node.IsRoot() {
+				return filetree.ContinueVisiting
+			}
+			folder := "/" + path.Join(nodePath[1:]...)
+			entryPath := path.Join(folder, node.Name())
+
+			if node.IsDirectory() {
+				r.Folders++
+				return filetree.ContinueVisiting
+			}
+
+			r.Documents++
+			perFolder[folder]++
+
+			f, ok := byID[node.Id()]
+			if !ok {
+				return filetree.ContinueVisiting
+			}
+
+			r.Pages += f.Pages
+			r.DocumentTypes[f.FileType]++
+			annotated := 0
+			for name, size := range f.Files {
+				kind := fileKind(name)
+				r.Storage[kind] += size
+				if kind == "rm" {
+					annotated++
+				}
+			}
+
+			if f.FileType == "notebook" && f.LastOpened.IsZero() {
+				r.NeverOpened = append(r.NeverOpened, entryPath)
+			}
+			if annotated > 0 {
+				r.MostAnnotated = append(r.MostAnnotated, DocumentCount{entryPath, annotated})
+			}
+			return filetree.ContinueVisiting
+		},
+	})
+
+	for folder, count := range perFolder {
+		r.PerFolder = append(r.PerFolder, FolderCount{folder, count})
+	}
+	sort.Slice(r.PerFolder, func(i, j int) bool { return r.PerFolder[i].Path < r.PerFolder[j].Path })
+	sort.Strings(r.NeverOpened)
+
+	sort.SliceStable(r.MostAnnotated, func(i, j int) bool {
+		a, b := r.MostAnnotated[i], r.MostAnnotated[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Path < b.Path
+	})
+	if len(r.MostAnnotated) > top {
+		r.MostAnnotated = r.MostAnnotated[:top]
+	}
+
+	return r
+}
+
+// fileKind groups the files of a document: the original pdf or epub,
+// the strokes (rm), the thumbnails and the other metadata files.
+func fileKind(name string) string {
+	if strings.Contains(name, ".thumbnails/") {
+		return "thumbnails"
+	}
+	switch ext := strings.TrimPrefix(filepath.Ext(name), "."); ext {
+	case "pdf", "epub", "rm":
+		return ext
+	}
+	return "metadata"
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Lines returns the report as lines of text.
+func (r *Report) Lines() []string {
+	lines := []string{
+		fmt.Sprintf("Generated: %s", r.Generated.Format("2006-01-02 15:04")),
+		fmt.Sprintf("Documents: %d (%s)", r.Documents, formatCounts(r.DocumentTypes)),
+		fmt.Sprintf("Folders: %d", r.Folders),
+		fmt.Sprintf("Pages: %d", r.Pages),
+		"",
+		"Storage:",
+	}
+
+	kinds := make([]string, 0, len(r.Storage))
+	var total int64
+	for kind, size := range r.Storage {
+		kinds = append(kinds, kind)
+		total += size
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		lines = append(lines, fmt.Sprintf("  %-12s %s", kind, util.FormatSize(r.Storage[kind])))
+	}
+	lines = append(lines, fmt.Sprintf("  %-12s %s", "total", util.FormatSize(total)), "", "Documents per folder:")
+
+	for _, f := range r.PerFolder {
+		lines = append(lines, fmt.Sprintf("  %5d  %s", f.Documents, f.Path))
+	}
+
+	lines = append(lines, "", "Most annotated documents (pages with strokes):")
+	for _, d := range r.MostAnnotated {
+		lines = append(lines, fmt.Sprintf("  %5d  %s", d.Count, d.Path))
+	}
+
+	lines = append(lines, "", fmt.Sprintf("Notebooks never opened: %d", len(r.NeverOpened)))
+	for _, p := range r.NeverOpened {
+		lines = append(lines, "  "+p)
+	}
+
+	return lines
+}
+
+// WriteText writes the report as plain text.
+func (r *Report) WriteText(w io.Writer) error {
+	for _, line := range r.Lines() {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s: %d", name, counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
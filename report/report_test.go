@@ -0,0 +1,78 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joagonca/rmapi/filetree"
+	"github.com/joagonca/rmapi/model"
+)
+
+func TestBuild(t *testing.T) {
+	tree := filetree.CreateFileTreeCtx()
+	tree.AddDocument(&model.Document{ID: "dir", VissibleName: "Books", Type: model.DirectoryType})
+	tree.AddDocument(&model.Document{ID: "book", Parent: "dir", VissibleName: "Novel", Type: model.DocumentType})
+	tree.AddDocument(&model.Document{ID: "notes", VissibleName: "Notes", Type: model.DocumentType})
+	tree.AddDocument(&model.Document{ID: "empty", VissibleName: "Empty", Type: model.DocumentType})
+
+	files := []*model.DocumentFiles{
+		{
+			ID:         "book",
+			FileType:   "epub",
+			Pages:      200,
+			LastOpened: time.Now(),
+			Files:      map[string]int64{"book.epub": 1000, "book.pdf": 3000, "book/1.rm": 10, "book.content": 5},
+		},
+		{
+			ID:         "notes",
+			FileType:   "notebook",
+			Pages:      3,
+			LastOpened: time.Now(),
+			Files:      map[string]int64{"notes/1.rm": 10, "notes/2.rm": 20, "notes.thumbnails/1.jpg": 7},
+		},
+		{
+			ID:       "empty",
+			FileType: "notebook",
+			Pages:    1,
+			Files:    map[string]int64{"empty.content": 5},
+		},
+	}
+
+	r := Build(tree.Root(), files, 1)
+
+	if r.Documents != 3 || r.Folders != 1 || r.Pages != 204 {
+		t.Errorf("wrong totals %+v", r)
+	}
+	if r.Storage["epub"] != 1000 || r.Storage["pdf"] != 3000 || r.Storage["rm"] != 40 ||
+		r.Storage["thumbnails"] != 7 || r.Storage["metadata"] != 10 {
+		t.Errorf("wrong storage %v", r.Storage)
+	}
+	if r.DocumentTypes["notebook"] != 2 || r.DocumentTypes["epub"] != 1 {
+		t.Errorf("wrong document types %v", r.DocumentTypes)
+	}
+	if len(r.PerFolder) != 2 || r.PerFolder[0] != (FolderCount{"/", 2}) || r.PerFolder[1] != (FolderCount{"/Books", 1}) {
+		t.Errorf("wrong folders %v", r.PerFolder)
+	}
+	if len(r.NeverOpened) != 1 || r.NeverOpened[0] != "/Empty" {
+		t.Errorf("wrong never opened %v", r.NeverOpened)
+	}
+	if len(r.MostAnnotated) != 1 || r.MostAnnotated[0] != (DocumentCount{"/Notes", 2}) {
+		t.Errorf("wrong most annotated %v", r.MostAnnotated)
+	}
+
+	var text bytes.Buffer
+	r.WriteText(&text)
+	if !strings.Contains(text.String(), "Documents: 3 (epub: 1, notebook: 2)") {
+		t.Errorf("unexpected text report:\n%s", text.String())
+	}
+
+	var doc bytes.Buffer
+	if err := r.WritePDF(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(doc.Bytes(), []byte("%PDF-")) {
+		t.Error("not a pdf")
+	}
+}
@@ -0,0 +1,35 @@
+package shell
+
+import (
+	"os"
+
+	"github.com/joagonca/rmapi/archive"
+	"github.com/joagonca/rmapi/model"
+)
+
+// fetchZip downloads a document into a temporary file and reads it
+// lazily, the drawings and the thumbnails of the pages are read on
+// demand. The returned function closes and removes the file.
+func fetchZip(ctx *ShellCtxt, node *model.Node) (*archive.Zip, func(), error) {
+	tmp, err := os.CreateTemp("", "rmapi-*.zip")
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+
+	if err := ctx.api.FetchDocument(node.Document.ID, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, nil, err
+	}
+
+	zip, err := archive.OpenZip(tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, nil, err
+	}
+	return zip, func() {
+		zip.Close()
+		os.Remove(tmpPath)
+	}, nil
+}
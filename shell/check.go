@@ -0,0 +1,84 @@
+package shell
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/archive"
+)
+
+func checkCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "check",
+		Help:      "check that local zip or .rmdoc documents are consistent before uploading them, usage: check [--json] file...",
+		Completer: createFsEntryCompleter(),
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("check", flag.ContinueOnError)
+			asJSON := flagSet.Bool("json", false, "print the problems as json")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			if flagSet.NArg() == 0 {
+				c.Err(errors.New("missing source file"))
+				return
+			}
+
+			type result struct {
+				File     string            `json:"file"`
+				Error    string            `json:"error,omitempty"`
+				Problems []archive.Problem `json:"problems"`
+			}
+			var results []result
+			failed := 0
+			for _, name := range flagSet.Args() {
+				r := result{File: name, Problems: []archive.Problem{}}
+				problems, err := archive.Validate(name)
+				if err != nil {
+					r.Error = err.Error()
+					failed++
+				} else {
+					r.Problems = problems
+				}
+				for _, p := range problems {
+					if !p.Warning {
+						failed++
+						break
+					}
+				}
+				results = append(results, r)
+			}
+
+			if *asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(results); err != nil {
+					c.Err(err)
+				}
+			} else {
+				for _, r := range results {
+					switch {
+					case r.Error != "":
+						c.Printf("%s: cannot be read: %s\n", r.File, r.Error)
+					case len(r.Problems) == 0:
+						c.Printf("%s: OK\n", r.File)
+					default:
+						c.Printf("%s:\n", r.File)
+						for _, p := range r.Problems {
+							c.Printf("  %s\n", p)
+						}
+					}
+				}
+			}
+			if failed > 0 {
+				c.Err(fmt.Errorf("%d document(s) with errors", failed))
+			}
+		},
+	}
+}
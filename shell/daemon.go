@@ -0,0 +1,132 @@
+package shell
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/config"
+	"github.com/joagonca/rmapi/daemon"
+)
+
+func daemonCmd(ctx *ShellCtxt, shell *ishell.Shell) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "daemon",
+		Help: "run the jobs of the daemon config on their schedules until interrupted, usage: daemon [--listen addr] [--list]",
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("daemon", flag.ContinueOnError)
+			listen := flagSet.String("listen", "", "address of the status and metrics endpoints, instead of the one of the config")
+			list := flagSet.Bool("list", false, "print the jobs and their next run, without running them")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+
+			settings, path, err := loadSettings()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			cfg, err := settings.Daemon()
+			if err != nil {
+				c.Err(fmt.Errorf("%s: %v", path, err))
+				return
+			}
+			if cfg == nil {
+				c.Err(fmt.Errorf("no daemon configured in %s", path))
+				return
+			}
+			if err := checkJobs(shell, cfg.Jobs); err != nil {
+				c.Err(err)
+				return
+			}
+
+			scheduler, err := daemon.NewScheduler(cfg.Jobs, func(job *config.Job) error {
+				return runJob(ctx, shell, c, job)
+			})
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			if *list {
+				for _, j := range scheduler.Status() {
+					c.Printf("%-12s %-16s next: %s  %s\n", j.Name, j.Schedule, j.NextRun.Format(time.RFC3339), j.Command)
+				}
+				return
+			}
+
+			runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			addr := cfg.Listen
+			if *listen != "" {
+				addr = *listen
+			}
+			if addr != "" {
+				server := &http.Server{Addr: addr, Handler: scheduler.Handler()}
+				go func() {
+					if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+						c.Printf("status endpoint: %v\n", err)
+					}
+				}()
+				defer server.Close()
+				c.Printf("status on http://%s/status and /metrics\n", addr)
+			}
+
+			c.Printf("daemon started with %d job(s) from %s\n", len(cfg.Jobs), path)
+			scheduler.Run(runCtx)
+			c.Println("daemon stopped")
+		},
+	}
+}
+
+// checkJobs checks that the jobs run commands of the shell.
+func checkJobs(shell *ishell.Shell, jobs []*config.Job) error {
+	commands := make(map[string]bool)
+	for _, cmd := range shell.Cmds() {
+		commands[cmd.Name] = true
+	}
+	for _, job := range jobs {
+		args := parseArguments(job.Command)
+		if len(args) == 0 || !commands[args[0]] {
+			return fmt.Errorf("invalid job %s: unknown command %q", job.Name, job.Command)
+		}
+		if args[0] == "daemon" {
+			return fmt.Errorf("invalid job %s: the daemon cannot be a job", job.Name)
+		}
+	}
+	return nil
+}
+
+// runJob runs the command of a job from the root folder, on a tree
+// refreshed with the remote changes. The queued operations are retried
+// first.
+func runJob(ctx *ShellCtxt, shell *ishell.Shell, c *ishell.Context, job *config.Job) error {
+	c.Printf("%s job %s started: %s\n", time.Now().Format(time.RFC3339), job.Name, job.Command)
+
+	if err := drainQueue(ctx, c); err != nil {
+		c.Printf("cannot retry the queued operations: %v\n", err)
+	}
+	err := ctx.api.Refresh()
+	if err == nil {
+		ctx.node = ctx.api.Filetree().Root()
+		ctx.path = ctx.node.Name()
+		err = shell.Process(parseArguments(job.Command)...)
+	}
+
+	if err != nil {
+		c.Printf("%s job %s failed: %v\n", time.Now().Format(time.RFC3339), job.Name, err)
+		return err
+	}
+	c.Printf("%s job %s done\n", time.Now().Format(time.RFC3339), job.Name)
+	return nil
+}
@@ -0,0 +1,273 @@
+package shell
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/device"
+	"github.com/joagonca/rmapi/transfer"
+	"github.com/joagonca/rmapi/util"
+)
+
+func deviceCmd(ctx *ShellCtxt) *ishell.Cmd {
+	cmd := &ishell.Cmd{
+		Name: "device",
+		Help: "manage the tablet over ssh (RMAPI_DEVICE_HOST, default 10.11.99.1)",
+	}
+
+	templates := &ishell.Cmd{
+		Name: "templates",
+		Help: "manage custom templates",
+	}
+	templates.AddCmd(deviceTemplatesInstallCmd(ctx))
+	cmd.AddCmd(templates)
+
+	screens := &ishell.Cmd{
+		Name: "screens",
+		Help: "manage the splash, suspend and poweroff screens",
+	}
+	screens.AddCmd(deviceScreensSetCmd(ctx))
+	screens.AddCmd(deviceScreensRestoreCmd(ctx))
+	cmd.AddCmd(screens)
+
+	cmd.AddCmd(deviceBackupCmd(ctx))
+	cmd.AddCmd(deviceScreenshotCmd(ctx))
+	cmd.AddCmd(deviceInfoCmd(ctx))
+
+	cmd.Completer = createSubcmdCompleter(cmd)
+
+	return cmd
+}
+
+func deviceTemplatesInstallCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "install",
+		Help:      "upload png/svg templates from a local directory, usage: device templates install dir",
+		Completer: createFsDirCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			if len(c.Args) != 1 {
+				c.Err(errors.New("missing local template directory"))
+				return
+			}
+
+			dev := device.FromEnv()
+			c.Printf("installing templates on %s...\n", dev.Host)
+
+			installed, err := dev.InstallTemplates(c.Args[0])
+			if err != nil {
+				c.Err(fmt.Errorf("failed to install templates: %v", err))
+				return
+			}
+
+			if len(installed) > 0 {
+				c.Printf("new templates: %s\n", strings.Join(installed, ", "))
+			}
+			c.Println("OK")
+		},
+	}
+}
+
+func deviceScreensSetCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "set",
+		Help:      "replace a screen with a 1404x1872 png, usage: device screens set [screen] image.png",
+		Completer: createFsFileCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			var screen, image string
+			switch len(c.Args) {
+			case 1:
+				// the screen is given by the image name, e.g. suspend.png
+				screen, image = c.Args[0], c.Args[0]
+			case 2:
+				screen, image = c.Args[0], c.Args[1]
+			default:
+				c.Err(errors.New("usage: device screens set [screen] image.png"))
+				return
+			}
+
+			dev := device.FromEnv()
+			if err := dev.SetScreen(screen, image); err != nil {
+				c.Err(fmt.Errorf("failed to set screen: %v", err))
+				return
+			}
+			c.Println("OK")
+		},
+	}
+}
+
+func deviceScreensRestoreCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "restore",
+		Help: "restore the original image of a screen, usage: device screens restore screen",
+		Completer: func([]string) []string {
+			return device.ScreenNames()
+		},
+		Func: func(c *ishell.Context) {
+			if len(c.Args) != 1 {
+				c.Err(fmt.Errorf("missing screen, one of: %s", strings.Join(device.ScreenNames(), ", ")))
+				return
+			}
+
+			dev := device.FromEnv()
+			if err := dev.RestoreScreen(c.Args[0]); err != nil {
+				c.Err(err)
+				return
+			}
+			c.Println("OK")
+		},
+	}
+}
+
+func deviceBackupCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "backup",
+		Help:      "copy the documents of the tablet to a local directory, usage: device backup [--delete] [--summary file.json] dir",
+		Completer: createFsDirCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("device backup", flag.ContinueOnError)
+			prune := flagSet.Bool("delete", false, "remove the local files deleted on the tablet")
+			summaryFile := flagSet.String("summary", "", "write the transfer summary as json to this file")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			if flagSet.NArg() != 1 {
+				c.Err(errors.New("missing local backup directory"))
+				return
+			}
+			dir := flagSet.Arg(0)
+
+			dev := device.FromEnv()
+			c.Printf("backing up %s to %s...\n", dev.Host, dir)
+
+			summary := transfer.Start("backup")
+			result, err := dev.Backup(dir, *prune)
+			if err != nil {
+				c.Err(fmt.Errorf("failed to backup: %v", err))
+				summary.Fail()
+				printSummary(c, summary, *summaryFile)
+				return
+			}
+			summary.Transferred = result.Copied
+			summary.Skipped = result.Unchanged
+			summary.Removed = result.Removed
+			summary.Bytes = result.Bytes
+
+			c.Printf("%d files copied (%d bytes), %d unchanged, %d removed\n",
+				result.Copied, result.Bytes, result.Unchanged, result.Removed)
+			printSummary(c, summary, *summaryFile)
+		},
+	}
+}
+
+func deviceScreenshotCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "screenshot",
+		Help:      "save the current screen as a png, usage: device screenshot out.png",
+		Completer: createFsFileCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			if len(c.Args) != 1 {
+				c.Err(errors.New("missing output file"))
+				return
+			}
+
+			out, err := os.Create(c.Args[0])
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			defer out.Close()
+
+			dev := device.FromEnv()
+			if err := dev.WriteScreenshot(out); err != nil {
+				c.Err(fmt.Errorf("failed to capture the screen: %v", err))
+				return
+			}
+			c.Println("OK")
+		},
+	}
+}
+
+func deviceInfoCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "info",
+		Help: "show firmware, serial, battery, storage and document count, usage: device info [--json]",
+		Completer: func([]string) []string {
+			return []string{"--json"}
+		},
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("device info", flag.ContinueOnError)
+			asJSON := flagSet.Bool("json", false, "print the information as json")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+
+			dev := device.FromEnv()
+			info, err := dev.Info()
+			if err != nil {
+				c.Err(fmt.Errorf("failed to get device info: %v", err))
+				return
+			}
+
+			if *asJSON {
+				jsn, err := json.MarshalIndent(info, "", "  ")
+				if err != nil {
+					c.Err(errors.New("can't serialize to json"))
+					return
+				}
+				c.Println(string(jsn))
+				return
+			}
+
+			battery := "unknown"
+			if info.Battery >= 0 {
+				battery = fmt.Sprintf("%d%%", info.Battery)
+			}
+			c.Printf("model:     %s\n", info.Model)
+			c.Printf("firmware:  %s\n", info.Firmware)
+			c.Printf("serial:    %s\n", info.Serial)
+			c.Printf("battery:   %s\n", battery)
+			c.Printf("storage:   %s free of %s\n", util.FormatSize(info.FreeStorage), util.FormatSize(info.TotalStorage))
+			c.Printf("documents: %d\n", info.Documents)
+		},
+	}
+}
+
+// createSubcmdCompleter completes the names of the subcommands and
+// delegates to the completer of the selected subcommand.
+func createSubcmdCompleter(cmd *ishell.Cmd) func([]string) []string {
+	return func(args []string) []string {
+		current := cmd
+		for i := 0; i < len(args)-1; i++ {
+			var next *ishell.Cmd
+			for _, child := range current.Children() {
+				if child.Name == args[i] {
+					next = child
+				}
+			}
+			if next == nil {
+				return []string{}
+			}
+			current = next
+			if current.Completer != nil && len(current.Children()) == 0 {
+				return current.Completer(args[i+1:])
+			}
+		}
+
+		names := make([]string, 0)
+		for _, child := range current.Children() {
+			names = append(names, child.Name)
+		}
+		return names
+	}
+}
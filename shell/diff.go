@@ -0,0 +1,68 @@
+package shell
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/archive"
+)
+
+func diffCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "diff",
+		Help:      "list the pages and the fields which changed between two local zips of a document, usage: diff [--json] old.zip new.zip",
+		Completer: createFsEntryCompleter(),
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("diff", flag.ContinueOnError)
+			asJSON := flagSet.Bool("json", false, "print the changes as json")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			if flagSet.NArg() != 2 {
+				c.Err(errors.New("usage: diff [--json] old.zip new.zip"))
+				return
+			}
+
+			diff, err := archive.Diff(flagSet.Arg(0), flagSet.Arg(1))
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			if *asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(diff); err != nil {
+					c.Err(err)
+				}
+				return
+			}
+			if diff.Empty() {
+				c.Println("no changes")
+				return
+			}
+			for _, p := range diff.Added {
+				c.Printf("+ page %d (%s)\n", p.Page, p.ID)
+			}
+			for _, p := range diff.Removed {
+				c.Printf("- page %d (%s)\n", p.Page, p.ID)
+			}
+			for _, p := range diff.Modified {
+				c.Printf("~ page %d (%s): %s\n", p.Page, p.ID, strings.Join(p.Changes, ", "))
+			}
+			if diff.DocumentChanged {
+				c.Println("~ the pdf or the epub changed")
+			}
+			for _, f := range diff.Fields {
+				c.Printf("~ %s: %s -> %s\n", f.Field, f.Old, f.New)
+			}
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/archive"
+)
+
+func extractCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "extract",
+		Help:      "copy a page into a new document, usage: extract document page destination",
+		Completer: createEntryCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			if len(c.Args) != 3 {
+				c.Err(errors.New("usage: extract document page destination"))
+				return
+			}
+
+			srcName := c.Args[0]
+			node, err := ctx.api.Filetree().NodeByPath(srcName, ctx.node)
+			if err != nil || node.IsDirectory() {
+				c.Err(errors.New("file doesn't exist"))
+				return
+			}
+
+			page, err := strconv.Atoi(c.Args[1])
+			if err != nil {
+				c.Err(fmt.Errorf("invalid page number %s", c.Args[1]))
+				return
+			}
+
+			dst := c.Args[2]
+			if _, err := ctx.api.Filetree().NodeByPath(dst, ctx.node); err == nil {
+				c.Err(errors.New("entry already exists"))
+				return
+			}
+			dstDir, err := ctx.api.Filetree().NodeByPath(path.Dir(dst), ctx.node)
+			if err != nil || dstDir.IsFile() {
+				c.Err(errors.New("directory doesn't exist"))
+				return
+			}
+			name := path.Base(dst)
+
+			tmpDir, err := os.MkdirTemp("", "rmapi-extract")
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			defer os.RemoveAll(tmpDir)
+
+			zipPath := filepath.Join(tmpDir, "source.zip")
+			if err := ctx.api.FetchDocument(node.Id(), zipPath); err != nil {
+				c.Err(fmt.Errorf("Failed to download file %s with %v", srcName, err))
+				return
+			}
+
+			files, err := archive.ReadRawFiles(zipPath)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			extracted, _, err := archive.ExtractPage(files, node.Id(), page)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			// the document is named after the archive
+			dstPath := filepath.Join(tmpDir, name+".zip")
+			if err := archive.WriteRawFiles(dstPath, extracted); err != nil {
+				c.Err(err)
+				return
+			}
+
+			c.Printf("uploading: [%s]...", name)
+			document, err := ctx.api.UploadDocument(dstDir.Id(), dstPath, true)
+			if err != nil {
+				c.Err(fmt.Errorf("Failed to upload file [%s] %v", name, err))
+				return
+			}
+			c.Println("OK")
+
+			ctx.api.Filetree().AddDocument(document)
+		},
+	}
+}
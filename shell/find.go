@@ -2,6 +2,7 @@ package shell
 
 import (
 	"errors"
+	"flag"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -14,15 +15,24 @@ import (
 func findCmd(ctx *ShellCtxt) *ishell.Cmd {
 	return &ishell.Cmd{
 		Name:      "find",
-		Help:      "find files recursively, usage: find dir [regexp]",
+		Help:      "find files recursively, usage: find [--pinned] dir [regexp]",
 		Completer: createDirCompleter(ctx),
 		Func: func(c *ishell.Context) {
-			if len(c.Args) != 1 && len(c.Args) != 2 {
-				c.Err(errors.New("missing arguments; usage find dir [regexp]"))
+			flagSet := flag.NewFlagSet("find", flag.ContinueOnError)
+			pinned := flagSet.Bool("pinned", false, "only pinned entries")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			args := flagSet.Args()
+			if len(args) != 1 && len(args) != 2 {
+				c.Err(errors.New("missing arguments; usage find [--pinned] dir [regexp]"))
 				return
 			}
 
-			start := c.Args[0]
+			start := args[0]
 
 			startNode, err := ctx.api.Filetree().NodeByPath(start, ctx.node)
 
@@ -32,8 +42,8 @@ func findCmd(ctx *ShellCtxt) *ishell.Cmd {
 			}
 
 			var matchRegexp *regexp.Regexp
-			if len(c.Args) == 2 {
-				matchRegexp, err = regexp.Compile(c.Args[1])
+			if len(args) == 2 {
+				matchRegexp, err = regexp.Compile(args[1])
 				if err != nil {
 					c.Err(errors.New("failed to compile regexp"))
 					return
@@ -42,6 +52,10 @@ func findCmd(ctx *ShellCtxt) *ishell.Cmd {
 
 			filetree.WalkTree(startNode, filetree.FileTreeVistor{
 				Visit: func(node *model.Node, path []string) bool {
+					if *pinned && !node.IsPinned() {
+						return false
+					}
+
 					var entryType string
 					if node.IsDirectory() {
 						entryType = "[d] "
@@ -0,0 +1,18 @@
+package shell
+
+import (
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/export"
+)
+
+func formatsCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "formats",
+		Help: "list the export formats of geta and of the export profiles",
+		Func: func(c *ishell.Context) {
+			for _, f := range export.Formats() {
+				c.Printf("%-20s .%-5s %s\n", f.Name, f.Extension, f.Description)
+			}
+		},
+	}
+}
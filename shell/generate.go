@@ -0,0 +1,147 @@
+package shell
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/generate"
+	"github.com/joagonca/rmapi/util"
+)
+
+func generateCmd(ctx *ShellCtxt) *ishell.Cmd {
+	cmd := &ishell.Cmd{
+		Name: "generate",
+		Help: "generate documents and upload them",
+	}
+	cmd.AddCmd(generatePlannerCmd(ctx))
+	cmd.AddCmd(generateFromTemplateCmd(ctx))
+	cmd.Completer = createSubcmdCompleter(cmd)
+	return cmd
+}
+
+func generatePlannerCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "planner",
+		Help:      "upload a hyperlinked planner, usage: generate planner [--year 2025] [--layout monthly|weekly|daily] [--sunday] [dir]",
+		Completer: createDirCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("generate planner", flag.ContinueOnError)
+			year := flagSet.Int("year", time.Now().Year(), "year of the planner")
+			layout := flagSet.String("layout", string(generate.Monthly), "monthly, weekly or daily pages")
+			sunday := flagSet.Bool("sunday", false, "weeks start on sunday instead of monday")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+
+			opts := generate.PlannerOptions{Year: *year, WeekStart: time.Monday}
+			var err error
+			if opts.Layout, err = generate.ParseLayout(*layout); err != nil {
+				c.Err(err)
+				return
+			}
+			if *sunday {
+				opts.WeekStart = time.Sunday
+			}
+
+			dstDir := ctx.node
+			if flagSet.NArg() > 0 {
+				node, err := ctx.api.Filetree().NodeByPath(flagSet.Arg(0), ctx.node)
+				if err != nil || node.IsFile() {
+					c.Err(errors.New("directory doesn't exist"))
+					return
+				}
+				dstDir = node
+			}
+
+			doc, err := generate.Planner(opts)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			name := fmt.Sprintf("Planner %d", opts.Year)
+			if err := uploadPDF(ctx, c, name, dstDir, doc.Write); err != nil {
+				c.Err(err)
+			}
+		},
+	}
+}
+
+// varsFlag collects repeated name=value flags.
+type varsFlag map[string]string
+
+func (v varsFlag) String() string {
+	return fmt.Sprint(map[string]string(v))
+}
+
+func (v varsFlag) Set(s string) error {
+	name, value, found := strings.Cut(s, "=")
+	if !found || name == "" {
+		return fmt.Errorf("invalid variable %s, expected name=value", s)
+	}
+	v[name] = value
+	return nil
+}
+
+func generateFromTemplateCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "from-template",
+		Help:      "render a markdown or html go template to pdf and upload it, usage: generate from-template file [--var name=value]... [--name doc] [dir]",
+		Completer: createFsFileCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			if len(c.Args) == 0 {
+				c.Err(errors.New("missing template file"))
+				return
+			}
+			templatePath := c.Args[0]
+
+			flagSet := flag.NewFlagSet("generate from-template", flag.ContinueOnError)
+			vars := make(varsFlag)
+			flagSet.Var(vars, "var", "template variable as name=value, can be repeated")
+			name := flagSet.String("name", "", "name of the document, the title variable or the template name by default")
+			if err := flagSet.Parse(c.Args[1:]); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+
+			dstDir := ctx.node
+			if flagSet.NArg() > 0 {
+				node, err := ctx.api.Filetree().NodeByPath(flagSet.Arg(0), ctx.node)
+				if err != nil || node.IsFile() {
+					c.Err(errors.New("directory doesn't exist"))
+					return
+				}
+				dstDir = node
+			}
+
+			markdown, err := generate.RenderTemplate(templatePath, vars)
+			if err != nil {
+				c.Err(fmt.Errorf("failed to render template: %v", err))
+				return
+			}
+
+			docName := *name
+			if docName == "" {
+				docName = vars["title"]
+			}
+			if docName == "" {
+				docName, _ = util.DocPathToName(templatePath)
+			}
+			docName = strings.ReplaceAll(docName, "/", "-")
+
+			doc := generate.MarkdownPDF(markdown, docName, generate.MarkdownOptions{})
+			if err := uploadPDF(ctx, c, docName, dstDir, doc.Write); err != nil {
+				c.Err(err)
+			}
+		},
+	}
+}
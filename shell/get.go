@@ -2,23 +2,38 @@ package shell
 
 import (
 	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/archive"
+	"github.com/joagonca/rmapi/model"
+	"github.com/joagonca/rmapi/util"
 )
 
 func getCmd(ctx *ShellCtxt) *ishell.Cmd {
 	return &ishell.Cmd{
 		Name:      "get",
-		Help:      "copy remote file to local",
+		Help:      "copy remote file to local, usage: get [--rmdoc] [--name-template template] file",
 		Completer: createEntryCompleter(ctx),
 		Func: func(c *ishell.Context) {
-			if len(c.Args) == 0 {
+			flagSet := flag.NewFlagSet("get", flag.ContinueOnError)
+			templateText := flagSet.String("name-template", "", "template of the path of the file, instead of RMAPI_NAME_TEMPLATE")
+			rmdoc := flagSet.Bool("rmdoc", false, "download the document as an .rmdoc, the format of the device, instead of a zip")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			if flagSet.NArg() == 0 {
 				c.Err(errors.New("missing source file"))
 				return
 			}
 
-			srcName := c.Args[0]
+			srcName := flagSet.Arg(0)
 
 			node, err := ctx.api.Filetree().NodeByPath(srcName, ctx.node)
 
@@ -27,9 +42,28 @@ func getCmd(ctx *ShellCtxt) *ishell.Cmd {
 				return
 			}
 
+			nameTemplate, err := loadNameTemplate(*templateText)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			ext := util.ZIP
+			if *rmdoc {
+				ext = util.RMDOC
+			}
+			dstName, err := downloadFileName(nameTemplate, node, ext)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
 			c.Println(fmt.Sprintf("downloading: [%s]...", srcName))
 
-			err = ctx.api.FetchDocument(node.Document.ID, fmt.Sprintf("%s.zip", node.Name()))
+			if *rmdoc {
+				err = fetchRmdoc(ctx, node, dstName)
+			} else {
+				err = ctx.api.FetchDocument(node.Document.ID, dstName)
+			}
 
 			if err == nil {
 				c.Println("OK")
@@ -40,3 +74,20 @@ func getCmd(ctx *ShellCtxt) *ishell.Cmd {
 		},
 	}
 }
+
+// fetchRmdoc downloads a document as an .rmdoc, which the device and
+// the desktop apps import as it was.
+func fetchRmdoc(ctx *ShellCtxt, node *model.Node, dst string) error {
+	tmpDir, err := os.MkdirTemp("", "rmapi-get")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	zipName := filepath.Join(tmpDir, node.Document.ID+".zip")
+	if err := ctx.api.FetchDocument(node.Document.ID, zipName); err != nil {
+		return err
+	}
+	modified, _ := node.LastModified()
+	return archive.WriteRmdoc(zipName, util.LongPath(dst), node.Name(), modified)
+}
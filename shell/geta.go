@@ -4,22 +4,60 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"path"
+	"strings"
 
 	"github.com/abiosoft/ishell"
 	"github.com/joagonca/rmapi/annotations"
+	"github.com/joagonca/rmapi/config"
+	"github.com/joagonca/rmapi/export"
+	"github.com/joagonca/rmapi/util"
 )
 
 func getACmd(ctx *ShellCtxt) *ishell.Cmd {
 	return &ishell.Cmd{
 		Name:      "geta",
-		Help:      "copy remote file to local and generate a PDF with its annotations",
+		Help:      "copy remote file to local and generate a PDF with its annotations, or another format, usage: geta [-p] [--number-position pos] [--number-size pt] [--number-format \"Page %d of %d\"] [--number-offset n] [--number-skip-first] [-a] [-n] [--no-template] [--smooth] [--raster dpi] [--raster-compression level] [--flatten] [--jpeg-quality 1-100] [--eink] [--ocr recognizer] [--ocr-language lang] [--watermark text] [--watermark-image file] [--watermark-opacity 0.3] [--colors pen=#rrggbb,...] [--highlight-colors yellow=#rrggbb,...] [--layers 1,name] [--exclude-layers 2,name] [--visible-layers] [--split-layers] [--pdf-password password] [-j jobs] [--pages 3-10,15] [--format name] [--highlights-json file] [--dpi n] [--split-every pages] [--renderer name] [--profile name] file",
 		Completer: createEntryCompleter(ctx),
 		Func: func(c *ishell.Context) {
 
 			flagSet := flag.NewFlagSet("geta", flag.ContinueOnError)
 			addPageNumbers := flagSet.Bool("p", false, "add page numbers")
+			numberPosition := flagSet.String("number-position", "", "position of the page numbers: "+strings.Join(annotations.PageNumberPositions, ", "))
+			numberSize := flagSet.Float64("number-size", 0, "size of the font of the page numbers, 8 by default")
+			numberFormat := flagSet.String("number-format", "", "format of the page numbers, with %d for the number and the number of pages")
+			numberOffset := flagSet.Int("number-offset", 0, "added to the page numbers, -1 numbering the second page 1")
+			numberSkipFirst := flagSet.Bool("number-skip-first", false, "leave the first page without number")
 			allPages := flagSet.Bool("a", false, "all pages")
 			annotationsOnly := flagSet.Bool("n", false, "annotations only")
+			noTemplate := flagSet.Bool("no-template", false, "leave the pages of the notebooks blank, without their template")
+			smooth := flagSet.Bool("smooth", false, "draw the strokes as curves through their points")
+			raster := flagSet.Int("raster", 0, "draw the strokes of the pdf as images of this resolution")
+			rasterCompression := flagSet.Int("raster-compression", 0, "compression of the images of --raster, from 1 (fastest) to 9 (smallest)")
+			flatten := flagSet.Bool("flatten", false, "draw the strokes of the pdf as jpeg images, at --raster or 150 dpi, for smaller files")
+			jpegQuality := flagSet.Int("jpeg-quality", 0, "quality of the images of --flatten, from 1 to 100, 75 by default")
+			eink := flagSet.Bool("eink", false, "draw the strokes like the screen of the device, in greys")
+			ocr := flagSet.String("ocr", "", "recognizer of the handwriting written as searchable text, see renderers")
+			ocrLanguage := flagSet.String("ocr-language", "", "language of the handwriting, such as eng")
+			watermark := flagSet.String("watermark", "", "text stamped on the pages, {date} being the date of the export")
+			watermarkImage := flagSet.String("watermark-image", "", "png or jpeg image stamped on the pages")
+			watermarkOpacity := flagSet.Float64("watermark-opacity", 0, "opacity of the watermark, from 0 to 1, 0.3 by default")
+			colors := flagSet.String("colors", "", "colors of the pens, such as blue=#1f4e79,highlighter=#ff8000")
+			highlightColors := flagSet.String("highlight-colors", "", "colors of the highlighters, such as yellow=#ff8000,green=#00a0a0")
+			layers := flagSet.String("layers", "", "layers exported, by name or number, such as 1,Sketch")
+			excludeLayers := flagSet.String("exclude-layers", "", "layers left out, by name or number")
+			visibleLayers := flagSet.Bool("visible-layers", false, "leave out the layers hidden on the device")
+			splitLayers := flagSet.Bool("split-layers", false, "write a pdf for each layer")
+			password := flagSet.String("pdf-password", "", "password of the original pdf, when it is encrypted")
+			jobs := flagSet.Int("j", 0, "number of pages drawn at the same time by the native renderer")
+			templateText := flagSet.String("name-template", "", "template of the path of the pdf, instead of RMAPI_NAME_TEMPLATE")
+			splitEvery := flagSet.Int("split-every", 0, "write the pdf in files of this number of pages")
+			renderer := flagSet.String("renderer", "", "renderer of the pdf, instead of RMAPI_RENDERER, see renderers")
+			pages := flagSet.String("pages", "", "pages exported, as a list of pages and ranges such as 3-10,15")
+			format := flagSet.String("format", "", "export format, pdf by default, see formats")
+			highlightsJSON := flagSet.String("highlights-json", "", "also write the highlights, with their page, box and color, to this json file")
+			dpi := flagSet.Int("dpi", 0, "resolution of the images of the png, tiff and cbz formats, the one of the device by default")
+			profileName := flagSet.String("profile", "", "export profile, instead of the one of the folder or tags of the document")
 			if err := flagSet.Parse(c.Args); err != nil {
 				if err != flag.ErrHelp {
 					c.Err(err)
@@ -34,6 +72,12 @@ func getACmd(ctx *ShellCtxt) *ishell.Cmd {
 
 			srcName := argRest[0]
 
+			nameTemplate, err := loadNameTemplate(*templateText)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
 			node, err := ctx.api.Filetree().NodeByPath(srcName, ctx.node)
 
 			if err != nil || node.IsDirectory() {
@@ -41,9 +85,173 @@ func getACmd(ctx *ShellCtxt) *ishell.Cmd {
 				return
 			}
 
+			profiles, err := loadProfiles()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			profile, err := documentProfile(profiles, *profileName, node)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			if *renderer != "" {
+				if _, err := annotations.LookupRenderer(*renderer); err != nil {
+					c.Err(err)
+					return
+				}
+			}
+			if *ocr != "" {
+				if _, err := annotations.LookupRecognizer(*ocr); err != nil {
+					c.Err(err)
+					return
+				}
+			}
+			if *splitEvery < 0 {
+				c.Err(errors.New("the number of pages of --split-every must be positive"))
+				return
+			}
+			if *dpi < 0 {
+				c.Err(errors.New("the resolution of --dpi must be positive"))
+				return
+			}
+			if *raster < 0 {
+				c.Err(errors.New("the resolution of --raster must be positive"))
+				return
+			}
+			if *rasterCompression < 0 || *rasterCompression > 9 {
+				c.Err(errors.New("the compression of --raster-compression must be between 1 and 9"))
+				return
+			}
+			if *jpegQuality < 0 || *jpegQuality > 100 {
+				c.Err(errors.New("the quality of --jpeg-quality must be between 1 and 100"))
+				return
+			}
+			if *watermarkOpacity < 0 || *watermarkOpacity > 1 {
+				c.Err(errors.New("the opacity of --watermark-opacity must be between 0 and 1"))
+				return
+			}
+			if *jobs < 0 {
+				c.Err(errors.New("the number of pages of -j must be positive"))
+				return
+			}
+			penColors, err := colorFlag(*colors, config.PenColorMap)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			highlighterColors, err := colorFlag(*highlightColors, config.HighlightColorMap)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			var pageRanges annotations.PageRanges
+			if *pages != "" {
+				if pageRanges, err = annotations.ParsePageRanges(*pages); err != nil {
+					c.Err(err)
+					return
+				}
+			}
+
+			// the flags given override the profile
+			options := exportOptions(ctx, node, profile)
+			outputFormat := config.FormatPDF
+			if profile != nil {
+				outputFormat = profile.Format
+			}
+			flagSet.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "p":
+					options.AddPageNumbers = *addPageNumbers
+				case "number-position":
+					options.PageNumbers.Position = *numberPosition
+				case "number-size":
+					options.PageNumbers.Size = *numberSize
+				case "number-format":
+					options.PageNumbers.Format = *numberFormat
+				case "number-offset":
+					options.PageNumbers.Offset = *numberOffset
+				case "number-skip-first":
+					options.PageNumbers.SkipFirst = *numberSkipFirst
+				case "a":
+					options.AllPages = *allPages
+				case "n":
+					options.AnnotationsOnly = *annotationsOnly
+				case "no-template":
+					options.NoTemplates = *noTemplate
+				case "smooth":
+					options.Smooth = *smooth
+				case "raster":
+					options.RasterDPI = *raster
+				case "raster-compression":
+					options.RasterCompression = *rasterCompression
+				case "flatten":
+					options.Flatten = *flatten
+				case "jpeg-quality":
+					options.JPEGQuality = *jpegQuality
+				case "eink":
+					options.Eink = *eink
+				case "ocr":
+					options.OCR = *ocr
+				case "ocr-language":
+					options.OCRLanguage = *ocrLanguage
+				case "watermark":
+					options.Watermark.Text = *watermark
+				case "watermark-image":
+					options.Watermark.Image = *watermarkImage
+				case "watermark-opacity":
+					options.Watermark.Opacity = *watermarkOpacity
+				case "colors":
+					options.Colors = mergeColors(options.Colors, penColors)
+				case "highlight-colors":
+					options.HighlightColors = mergeColors(options.HighlightColors, highlighterColors)
+				case "layers":
+					options.Layers.Include = annotations.ParseLayers(*layers)
+				case "exclude-layers":
+					options.Layers.Exclude = annotations.ParseLayers(*excludeLayers)
+				case "visible-layers":
+					options.Layers.Visible = *visibleLayers
+				case "split-layers":
+					options.SplitLayers = *splitLayers
+				case "pdf-password":
+					options.Password = *password
+				case "j":
+					options.Jobs = *jobs
+				case "split-every":
+					options.SplitEvery = *splitEvery
+				case "renderer":
+					options.Renderer = *renderer
+				case "format":
+					outputFormat = *format
+				case "dpi":
+					options.DPI = *dpi
+				case "pages":
+					options.Pages = pageRanges
+				}
+			})
+			exportFormat, err := export.Lookup(outputFormat)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			if err := options.PageNumbers.Check(); err != nil {
+				c.Err(err)
+				return
+			}
+
 			c.Println(fmt.Sprintf("downloading: [%s]...", srcName))
 
-			zipName := fmt.Sprintf("%s.zip", node.Name())
+			fileName := util.SafeFileName(node.Name())
+			zipName := fmt.Sprintf("%s.zip", fileName)
+			outputName := fmt.Sprintf("%s-annotations.%s", fileName, exportFormat.Extension)
+			if nameTemplate != nil {
+				if outputName, err = downloadFileName(nameTemplate, node, exportFormat.Extension); err != nil {
+					c.Err(err)
+					return
+				}
+				zipName = strings.TrimSuffix(outputName, path.Ext(outputName)) + ".zip"
+			}
 			err = ctx.api.FetchDocument(node.Document.ID, zipName)
 
 			if err != nil {
@@ -51,17 +259,33 @@ func getACmd(ctx *ShellCtxt) *ishell.Cmd {
 				return
 			}
 
-			pdfName := fmt.Sprintf("%s-annotations.pdf", node.Name())
-			options := annotations.PdfGeneratorOptions{AddPageNumbers: *addPageNumbers, AllPages: *allPages, AnnotationsOnly: *annotationsOnly}
-			generator := annotations.CreatePdfGenerator(zipName, pdfName, options)
-			err = generator.Generate()
+			if *highlightsJSON != "" {
+				highlights, _ := export.Lookup("json-highlights")
+				if _, err := highlights.Exporter.Export(zipName, *highlightsJSON, options); err != nil {
+					c.Err(fmt.Errorf("Failed to write the highlights of %s with %s", srcName, err.Error()))
+					return
+				}
+				c.Printf("Highlights written in: %s\n", *highlightsJSON)
+			}
 
+			if exportFormat.Name == export.Archive {
+				c.Printf("Downloaded in: %s\n", zipName)
+				return
+			}
+
+			options.Progress = func(page, total int) {
+				c.Printf("\rexporting: page %d/%d", page, total)
+				if page == total {
+					c.Println()
+				}
+			}
+			files, err := exportFormat.Exporter.Export(zipName, outputName, options)
 			if err != nil {
 				c.Err(errors.New(fmt.Sprintf("Failed to generate annotations for %s with %s", srcName, err.Error())))
 				return
 			}
 
-			c.Printf("Annotations generated in: %s\n", pdfName)
+			c.Printf("Annotations generated in: %s\n", strings.Join(files, ", "))
 		},
 	}
 }
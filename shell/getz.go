@@ -0,0 +1,243 @@
+package shell
+
+import (
+	"archive/zip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/config"
+	"github.com/joagonca/rmapi/export"
+	"github.com/joagonca/rmapi/filetree"
+	"github.com/joagonca/rmapi/model"
+	"github.com/joagonca/rmapi/util"
+)
+
+func getzCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "getz",
+		Help:      "copy a remote directory into a local zip, usage: getz [--format name] [--profile name] dir [file.zip]",
+		Completer: createDirCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("getz", flag.ContinueOnError)
+			format := flagSet.String("format", "", "export format of the documents, instead of the one of their profile: "+strings.Join(export.Names(), ", "))
+			profileName := flagSet.String("profile", "", "export profile, instead of the one of the folder or tags of the documents")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			if name, ok := getzFormats[*format]; ok {
+				*format = name
+			}
+			if *format != "" {
+				if _, err := export.Lookup(*format); err != nil {
+					c.Err(err)
+					return
+				}
+			}
+			profiles, err := loadProfiles()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			if *profileName != "" {
+				if _, err := profiles.Find(*profileName); err != nil {
+					c.Err(err)
+					return
+				}
+			}
+
+			argRest := flagSet.Args()
+			if len(argRest) == 0 {
+				c.Err(errors.New("missing source dir"))
+				return
+			}
+			srcName := argRest[0]
+
+			node, err := ctx.api.Filetree().NodeByPath(srcName, ctx.node)
+			if err != nil || node.IsFile() {
+				c.Err(errors.New("directory doesn't exist"))
+				return
+			}
+
+			dst := "rmapi.zip"
+			if !node.IsRoot() {
+				dst = zipEntryName(node.Name()) + ".zip"
+			}
+			if len(argRest) > 1 {
+				dst = argRest[1]
+			}
+
+			tmpDir, err := os.MkdirTemp("", "rmapi-getz")
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			defer os.RemoveAll(tmpDir)
+
+			out, err := os.Create(util.LongPath(dst))
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			defer out.Close()
+
+			w := zip.NewWriter(out)
+			used := make(map[string]bool)
+			count, failed := 0, 0
+
+			visitor := filetree.FileTreeVistor{
+				Visit: func(currentNode *model.Node, currentPath []string) bool {
+					// the entries start with the name of the downloaded
+					// directory, except for the root which has none
+					var parts []string
+					for i, p := range currentPath {
+						if i > 0 || !node.IsRoot() {
+							parts = append(parts, zipEntryName(p))
+						}
+					}
+					if currentNode != node || !node.IsRoot() {
+						parts = append(parts, zipEntryName(currentNode.Name()))
+					}
+					entry := path.Join(parts...)
+
+					modified, err := currentNode.LastModified()
+					if err != nil {
+						modified = time.Now()
+					}
+
+					if currentNode.IsDirectory() {
+						if entry != "" {
+							w.CreateHeader(&zip.FileHeader{Name: entry + "/", Modified: modified})
+						}
+						return filetree.ContinueVisiting
+					}
+
+					c.Printf("downloading [%s]...", entry)
+					// the profile is checked above
+					profile, _ := documentProfile(profiles, *profileName, currentNode)
+					files, err := downloadForZip(ctx, currentNode, tmpDir, *format, profile)
+					if err != nil {
+						c.Err(fmt.Errorf("Failed to download file %s with %v", entry, err))
+						failed++
+						return filetree.ContinueVisiting
+					}
+
+					// the exports written in several files keep their numbers
+					for _, file := range files {
+						suffix := strings.TrimPrefix(filepath.Base(file), currentNode.Id())
+						ext := path.Ext(suffix)
+						name := uniqueEntryName(used, entry+strings.TrimSuffix(suffix, ext), strings.TrimPrefix(ext, "."))
+						err = addFileToZip(w, file, name, modified)
+						os.Remove(file)
+						if err != nil {
+							break
+						}
+					}
+					if err != nil {
+						c.Err(err)
+						failed++
+						return filetree.ContinueVisiting
+					}
+					count++
+					c.Println(" OK")
+					return filetree.ContinueVisiting
+				},
+			}
+			filetree.WalkTree(node, visitor)
+
+			if err := w.Close(); err != nil {
+				c.Err(err)
+				return
+			}
+			c.Printf("%d document(s) written to %s", count, dst)
+			if failed > 0 {
+				c.Printf(", %d failed", failed)
+			}
+			c.Println()
+		},
+	}
+}
+
+// getzFormats are the former names of the formats of getz.
+var getzFormats = map[string]string{
+	"raw":       export.Archive,
+	"annotated": config.FormatPDF,
+}
+
+// downloadForZip fetches a document into dir and writes it in format,
+// or else in the one of its profile, the archive as downloaded without
+// profile. It returns the written files.
+func downloadForZip(ctx *ShellCtxt, node *model.Node, dir, format string, profile *config.ExportProfile) ([]string, error) {
+	if format == "" {
+		format = export.Archive
+		if profile != nil {
+			format = profile.Format
+		}
+	}
+	f, err := export.Lookup(format)
+	if err != nil {
+		return nil, err
+	}
+
+	zipName := filepath.Join(dir, node.Id()+".zip")
+	if err := ctx.api.FetchDocument(node.Id(), zipName); err != nil {
+		return nil, err
+	}
+	if f.Name == export.Archive {
+		return []string{zipName}, nil
+	}
+	defer os.Remove(zipName)
+
+	options := exportOptions(ctx, node, profile)
+	if profile == nil {
+		// the documents without annotations are exported too
+		options.AllPages = true
+	}
+	files, err := f.Exporter.Export(zipName, filepath.Join(dir, node.Id()+"."+f.Extension), options)
+	if err != nil {
+		return nil, fmt.Errorf("cannot export the document: %v", err)
+	}
+	return files, nil
+}
+
+func addFileToZip(w *zip.Writer, file, name string, modified time.Time) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified}
+	dst, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, in)
+	return err
+}
+
+// zipEntryName makes a visible name usable as a path element.
+func zipEntryName(name string) string {
+	return util.SafeFileName(name)
+}
+
+// uniqueEntryName adds a number to the names of documents sharing a
+// name in the same directory.
+func uniqueEntryName(used map[string]bool, entry, ext string) string {
+	name := entry + "." + ext
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s (%d).%s", entry, i, ext)
+	}
+	used[name] = true
+	return name
+}
@@ -0,0 +1,15 @@
+package shell
+
+import "testing"
+
+func TestUniqueEntryName(t *testing.T) {
+	used := make(map[string]bool)
+	for _, want := range []string{"a/doc.zip", "a/doc (2).zip", "a/doc (3).zip"} {
+		if got := uniqueEntryName(used, "a/doc", "zip"); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+	if got := uniqueEntryName(used, "a/doc", "pdf"); got != "a/doc.pdf" {
+		t.Errorf("the extension should be part of the name, got %s", got)
+	}
+}
@@ -0,0 +1,104 @@
+package shell
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/archive"
+)
+
+func highlightsCmd(ctx *ShellCtxt) *ishell.Cmd {
+	cmd := &ishell.Cmd{
+		Name: "highlights",
+		Help: "work with the highlights of documents",
+	}
+
+	cmd.AddCmd(highlightsExportCmd(ctx))
+
+	cmd.Completer = createSubcmdCompleter(cmd)
+
+	return cmd
+}
+
+func highlightsExportCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "export",
+		Help:      "print the highlights of a document, usage: highlights export document [--format csv|json|markdown|text] [--output file]",
+		Completer: createEntryCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("highlights export", flag.ContinueOnError)
+			format := flagSet.String("format", "csv", "csv, json, markdown or text")
+			output := flagSet.String("output", "", "write to a local file instead of the standard output")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			if flagSet.NArg() == 0 {
+				c.Err(errors.New("missing source file"))
+				return
+			}
+			srcName := flagSet.Arg(0)
+			// the flags may follow the document
+			if err := flagSet.Parse(flagSet.Args()[1:]); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			switch *format {
+			case "csv", "json", "markdown", "text":
+			default:
+				c.Err(fmt.Errorf("unknown format %s, expected csv, json, markdown or text", *format))
+				return
+			}
+
+			node, err := ctx.api.Filetree().NodeByPath(srcName, ctx.node)
+			if err != nil || node.IsDirectory() {
+				c.Err(errors.New("file doesn't exist"))
+				return
+			}
+
+			zip, done, err := fetchZip(ctx, node)
+			if err != nil {
+				c.Err(fmt.Errorf("Failed to download file %s with %v", srcName, err))
+				return
+			}
+			defer done()
+
+			docPath, err := ctx.api.Filetree().NodeToPath(node)
+			if err != nil {
+				docPath = node.Name()
+			}
+			modified, _ := time.Parse(time.RFC3339Nano, node.Document.ModifiedClient)
+			records := zip.HighlightRecords(docPath, modified)
+
+			if *output == "" {
+				if err := archive.WriteHighlights(os.Stdout, *format, records); err != nil {
+					c.Err(err)
+				}
+				return
+			}
+
+			f, err := os.Create(*output)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			err = archive.WriteHighlights(f, *format, records)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			c.Printf("%d highlight(s) written to %s\n", len(records), *output)
+		},
+	}
+}
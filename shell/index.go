@@ -0,0 +1,210 @@
+package shell
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/annotations"
+	"github.com/joagonca/rmapi/filetree"
+	"github.com/joagonca/rmapi/index"
+	"github.com/joagonca/rmapi/model"
+)
+
+func indexCmd(ctx *ShellCtxt) *ishell.Cmd {
+	cmd := &ishell.Cmd{
+		Name: "index",
+		Help: "manage the local full-text index of the documents",
+	}
+
+	cmd.AddCmd(indexBuildCmd(ctx))
+
+	cmd.Completer = createSubcmdCompleter(cmd)
+
+	return cmd
+}
+
+func indexBuildCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "build",
+		Help: "index the text of the pdfs and epubs, and of the notebooks with --ocr, only new and changed documents are downloaded, usage: index build [--ocr recognizer] [--ocr-language lang]",
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("index build", flag.ContinueOnError)
+			ocr := flagSet.String("ocr", "", "recognizer of the handwriting of the notebooks")
+			ocrLanguage := flagSet.String("ocr-language", "", "language of the handwriting, the default one of the recognizer when empty")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			var recognizer annotations.Recognizer
+			if *ocr != "" {
+				info, err := annotations.LookupRecognizer(*ocr)
+				if err != nil {
+					c.Err(err)
+					return
+				}
+				recognizer = info.New(*ocrLanguage)
+			}
+
+			indexPath, err := index.DefaultPath()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			ix, err := index.Open(indexPath)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			defer ix.Close()
+
+			tmpDir, err := os.MkdirTemp("", "rmapi-index")
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			defer os.RemoveAll(tmpDir)
+
+			// the documents are copied, the tree may change while they
+			// are downloaded
+			var docs []model.Document
+			var paths []string
+			present := make(map[string]bool)
+			unlock := ctx.api.Filetree().ReadLock()
+			filetree.WalkTree(ctx.api.Filetree().Root(), filetree.FileTreeVistor{
+				Visit: func(node *model.Node, path []string) bool {
+					if node.IsFile() {
+						present[node.Id()] = true
+						docs = append(docs, *node.Document)
+						paths = append(paths, filetree.BuildPath(path, node.Name()))
+					}
+					return filetree.ContinueVisiting
+				},
+			})
+			unlock()
+
+			ids, err := ix.IDs()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			for _, id := range ids {
+				if !present[id] {
+					if err := ix.Remove(id); err != nil {
+						c.Err(err)
+						return
+					}
+				}
+			}
+
+			indexed, failed := 0, 0
+			for i, doc := range docs {
+				upToDate, err := ix.UpToDate(doc.ID, doc.Version, doc.ModifiedClient, *ocr)
+				if err != nil {
+					c.Err(err)
+					return
+				}
+				if upToDate {
+					// the path changes when the document is moved
+					if err := ix.SetPath(doc.ID, paths[i]); err != nil {
+						c.Err(err)
+						return
+					}
+					continue
+				}
+
+				c.Printf("indexing [%d/%d] %s\n", i+1, len(docs), paths[i])
+				zipPath := filepath.Join(tmpDir, doc.ID+".zip")
+				pages, notebook, err := indexText(ctx, doc.ID, zipPath, recognizer)
+				os.Remove(zipPath)
+				if err != nil {
+					c.Err(fmt.Errorf("failed to index %s: %v", paths[i], err))
+					failed++
+					continue
+				}
+				err = ix.Add(&index.Document{
+					ID:       doc.ID,
+					Path:     paths[i],
+					Version:  doc.Version,
+					Modified: doc.ModifiedClient,
+					Notebook: notebook,
+					OCR:      *ocr,
+					Pages:    pages,
+				})
+				if err != nil {
+					c.Err(err)
+					return
+				}
+				indexed++
+			}
+
+			ids, err = ix.IDs()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			c.Printf("%d document(s) indexed, %d failed, %d in the index\n", indexed, failed, len(ids))
+		},
+	}
+}
+
+// indexText downloads a document and extracts its text.
+func indexText(ctx *ShellCtxt, docID, zipPath string, recognizer annotations.Recognizer) ([]string, bool, error) {
+	if err := ctx.api.FetchDocument(docID, zipPath); err != nil {
+		return nil, false, err
+	}
+	return index.ArchiveText(zipPath, recognizer)
+}
+
+func searchCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "search",
+		Help: "search the text of the documents in the local index, usage: search words...",
+		Func: func(c *ishell.Context) {
+			query := strings.Trim(strings.Join(c.Args, " "), `"'`)
+			if len(index.Tokens(query)) == 0 {
+				c.Err(errors.New("missing search words"))
+				return
+			}
+
+			indexPath, err := index.DefaultPath()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			ix, err := index.Open(indexPath)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			defer ix.Close()
+			ids, err := ix.IDs()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			if len(ids) == 0 {
+				c.Err(errors.New("the index is empty, run index build first"))
+				return
+			}
+
+			results, err := ix.Search(query)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			for _, r := range results {
+				c.Printf("%s, page %d: %s\n", r.Path, r.Page, r.Snippet)
+			}
+			if len(results) == 0 {
+				c.Println("no match")
+			}
+		},
+	}
+}
@@ -0,0 +1,191 @@
+package shell
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/api"
+	"github.com/joagonca/rmapi/archive"
+	"github.com/joagonca/rmapi/generate"
+	"github.com/joagonca/rmapi/model"
+	"github.com/joagonca/rmapi/util"
+)
+
+func journalCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "journal",
+		Help: "add today's journal entry, usage: journal [--notebook path | --folder path] [--file template] [--background name] [--name layout] [--date yyyy-mm-dd]",
+		Completer: func([]string) []string {
+			return []string{"--notebook", "--folder", "--file", "--background", "--name", "--date"}
+		},
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("journal", flag.ContinueOnError)
+			notebook := flagSet.String("notebook", "", "append a page to this notebook instead of creating a document")
+			folder := flagSet.String("folder", "/Journal", "folder of the journal documents, created if missing")
+			file := flagSet.String("file", "", "local document (pdf, epub, svg...) copied for every day")
+			background := flagSet.String("background", "", "template of the appended pages, e.g. \"P Lines small\"")
+			name := flagSet.String("name", generate.DefaultJournalName, "go time layout of the names and titles")
+			day := flagSet.String("date", "", "day of the entry, today by default")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+
+			date := time.Now()
+			if *day != "" {
+				var err error
+				if date, err = time.ParseInLocation("2006-01-02", *day, time.Local); err != nil {
+					c.Err(fmt.Errorf("invalid date %s", *day))
+					return
+				}
+			}
+			// the title is also used as document name
+			title := strings.ReplaceAll(generate.JournalTitle(date, *name), "/", "-")
+
+			var err error
+			if *notebook != "" {
+				err = appendJournalPage(ctx, c, *notebook, date, title, *background)
+			} else {
+				err = createJournalDocument(ctx, c, *folder, title, *file)
+			}
+			if err != nil {
+				c.Err(err)
+			}
+		},
+	}
+}
+
+// appendJournalPage adds a dated page at the end of a notebook.
+func appendJournalPage(ctx *ShellCtxt, c *ishell.Context, notebook string, date time.Time, title, background string) error {
+	updater, ok := ctx.api.(api.Updater)
+	if !ok {
+		return errors.New("appending pages is only available with the sync 1.5 api")
+	}
+
+	node, err := ctx.api.Filetree().NodeByPath(notebook, ctx.node)
+	if err != nil || node.IsDirectory() {
+		return errors.New("notebook doesn't exist")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "rmapi-journal")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	zipPath := filepath.Join(tmpDir, "notebook.zip")
+	if err := ctx.api.FetchDocument(node.Id(), zipPath); err != nil {
+		return fmt.Errorf("Failed to download file %s with %v", notebook, err)
+	}
+
+	files, err := archive.ReadRawFiles(zipPath)
+	if err != nil {
+		return err
+	}
+
+	added, err := generate.AppendJournalPage(files, node.Id(), date, generate.JournalPage(title), background)
+	if err != nil {
+		return err
+	}
+	if !added {
+		c.Printf("the page of %s has already been added\n", date.Format("2006-01-02"))
+		return nil
+	}
+
+	updatedPath := filepath.Join(tmpDir, "updated.zip")
+	if err := archive.WriteRawFiles(updatedPath, files); err != nil {
+		return err
+	}
+
+	c.Printf("adding page [%s] to %s...", title, notebook)
+	if _, err := updater.UpdateDocument(node.Id(), updatedPath, true); err != nil {
+		return fmt.Errorf("Failed to update %s %v", notebook, err)
+	}
+	c.Println("OK")
+	return nil
+}
+
+// createJournalDocument uploads the document of the day to the journal
+// folder: a copy of the template file or a notebook with the title.
+func createJournalDocument(ctx *ShellCtxt, c *ishell.Context, folder, title, file string) error {
+	dir, err := journalFolder(ctx, folder)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ctx.api.Filetree().NodeByPath(title, dir); err == nil {
+		c.Printf("%s already exists\n", title)
+		return nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "rmapi-journal")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var srcPath string
+	if file != "" {
+		_, ext := util.DocPathToName(file)
+		srcPath = filepath.Join(tmpDir, title+"."+ext)
+		if _, err := util.CopyFile(file, srcPath); err != nil {
+			return err
+		}
+	} else {
+		data, err := generate.JournalPage(title).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		srcPath = filepath.Join(tmpDir, title+"."+util.RM)
+		if err := os.WriteFile(srcPath, data, 0600); err != nil {
+			return err
+		}
+	}
+
+	content, err := contentSettings()
+	if err != nil {
+		return err
+	}
+
+	c.Printf("uploading: [%s]...", title)
+	document, err := uploadDocument(ctx, dir.Id(), srcPath, content, true)
+	if err != nil {
+		return fmt.Errorf("Failed to upload file [%s] %v", title, err)
+	}
+	c.Println("OK")
+
+	ctx.api.Filetree().AddDocument(document)
+	return nil
+}
+
+// journalFolder returns the journal folder, creating it when its parent exists.
+func journalFolder(ctx *ShellCtxt, folder string) (*model.Node, error) {
+	node, err := ctx.api.Filetree().NodeByPath(folder, ctx.node)
+	if err == nil {
+		if node.IsFile() {
+			return nil, fmt.Errorf("%s is not a directory", folder)
+		}
+		return node, nil
+	}
+
+	parent, err := ctx.api.Filetree().NodeByPath(path.Dir(folder), ctx.node)
+	if err != nil || parent.IsFile() {
+		return nil, errors.New("directory doesn't exist")
+	}
+
+	document, err := ctx.api.CreateDir(parent.Id(), path.Base(folder), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory %v", err)
+	}
+	ctx.api.Filetree().AddDocument(document)
+	return ctx.api.Filetree().NodeById(document.ID), nil
+}
@@ -32,6 +32,9 @@ func lsCmd(ctx *ShellCtxt) *ishell.Cmd {
 				if e.IsFile() {
 					eType = "f"
 				}
+				if e.IsPinned() {
+					eType += "*"
+				}
 				c.Printf("[%s]\t%s\n", eType, e.Name())
 			}
 		},
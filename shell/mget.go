@@ -10,20 +10,26 @@ import (
 	"time"
 
 	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/config"
+	"github.com/joagonca/rmapi/export"
 	"github.com/joagonca/rmapi/filetree"
 	"github.com/joagonca/rmapi/model"
+	"github.com/joagonca/rmapi/transfer"
+	"github.com/joagonca/rmapi/util"
 )
 
 func mgetCmd(ctx *ShellCtxt) *ishell.Cmd {
 	return &ishell.Cmd{
 		Name:      "mget",
-		Help:      "recursively copy remote directory to local",
+		Help:      "recursively copy remote directory to local, the export profiles of the documents apply",
 		Completer: createDirCompleter(ctx),
 		Func: func(c *ishell.Context) {
 			flagSet := flag.NewFlagSet("mget", flag.ContinueOnError)
 			incremental := flagSet.Bool("i", false, "incremental")
 			outputDir := flagSet.String("o", ".", "output folder")
 			removeDeleted := flagSet.Bool("d", false, "remove deleted/moved")
+			summaryFile := flagSet.String("summary", "", "write the transfer summary as json to this file")
+			templateText := flagSet.String("name-template", "", "template of the paths of the files, instead of RMAPI_NAME_TEMPLATE")
 
 			if err := flagSet.Parse(c.Args); err != nil {
 				if err != flag.ErrHelp {
@@ -32,6 +38,12 @@ func mgetCmd(ctx *ShellCtxt) *ishell.Cmd {
 				return
 			}
 
+			nameTemplate, err := loadNameTemplate(*templateText)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
 			target := path.Clean(*outputDir)
 			if *removeDeleted && target == "." {
 				c.Err(fmt.Errorf("set a folder explictly with the -o flag when removing deleted (and not .)"))
@@ -52,6 +64,14 @@ func mgetCmd(ctx *ShellCtxt) *ishell.Cmd {
 				return
 			}
 
+			profiles, err := loadProfiles()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			summary := transfer.Start("mget")
+
 			fileMap := make(map[string]struct{})
 			fileMap[target] = struct{}{}
 
@@ -62,15 +82,40 @@ func mgetCmd(ctx *ShellCtxt) *ishell.Cmd {
 						idxDir = 1
 					}
 
-					fileName := currentNode.Name() + ".zip"
+					// with a template, the directories are the ones of the files
+					if currentNode.IsDirectory() && nameTemplate != nil {
+						return filetree.ContinueVisiting
+					}
+
+					var profile *config.ExportProfile
+					ext := "zip"
+					if currentNode.IsFile() {
+						if profile = profiles.Match(remoteFolder(currentNode), currentNode.Tags()); profile != nil {
+							format, err := export.Lookup(profile.Format)
+							if err != nil {
+								c.Err(err)
+								summary.Fail()
+								return filetree.ContinueVisiting
+							}
+							ext = format.Extension
+						}
+					}
 
-					dst := path.Join(target, filetree.BuildPath(currentPath[idxDir:], fileName))
+					fileName, err := outputFileName(nameTemplate, currentNode, currentPath[idxDir:], ext)
+					if err != nil {
+						c.Err(err)
+						summary.Fail()
+						return filetree.ContinueVisiting
+					}
+					dst := path.Join(target, fileName)
 					fileMap[dst] = struct{}{}
 
 					dir := path.Dir(dst)
-					fileMap[dir] = struct{}{}
+					for d := dir; d != target && d != "." && d != "/"; d = path.Dir(d) {
+						fileMap[d] = struct{}{}
+					}
 
-					os.MkdirAll(dir, 0766)
+					os.MkdirAll(util.LongPath(dir), 0766)
 
 					if currentNode.IsDirectory() {
 						return filetree.ContinueVisiting
@@ -83,31 +128,50 @@ func mgetCmd(ctx *ShellCtxt) *ishell.Cmd {
 					}
 
 					if *incremental {
-						stat, err := os.Stat(dst)
-						if err == nil {
-							localMod := stat.ModTime()
-
-							if !lastModified.After(localMod) {
-								return filetree.ContinueVisiting
+						files := existingFiles(dst)
+						if len(files) > 0 {
+							stat, err := os.Stat(util.LongPath(files[0]))
+							if err == nil {
+								localMod := stat.ModTime()
+
+								if !lastModified.After(localMod) {
+									for _, f := range files {
+										fileMap[f] = struct{}{}
+									}
+									summary.Skip()
+									return filetree.ContinueVisiting
+								}
 							}
 						}
 					}
 
 					c.Printf("downloading [%s]...", dst)
 
-					err = ctx.api.FetchDocument(currentNode.Document.ID, dst)
+					files := []string{dst}
+					if profile != nil {
+						files, err = exportDocument(ctx, currentNode, profile, dst)
+					} else {
+						err = ctx.api.FetchDocument(currentNode.Document.ID, dst)
+					}
 
 					if err == nil {
 						c.Println(" OK")
+						for _, f := range files {
+							fileMap[f] = struct{}{}
+							if stat, err := os.Stat(util.LongPath(f)); err == nil {
+								summary.Transfer(stat.Size())
+							}
 
-						err = os.Chtimes(dst, lastModified, lastModified)
-						if err != nil {
-							c.Err(fmt.Errorf("cant set lastModified for %s", dst))
+							err = os.Chtimes(util.LongPath(f), lastModified, lastModified)
+							if err != nil {
+								c.Err(fmt.Errorf("cant set lastModified for %s", f))
+							}
 						}
 						return filetree.ContinueVisiting
 					}
 
 					c.Err(fmt.Errorf("Failed to download file %s", currentNode.Name()))
+					summary.Fail()
 
 					return filetree.ContinueVisiting
 				},
@@ -145,6 +209,25 @@ func mgetCmd(ctx *ShellCtxt) *ishell.Cmd {
 					return nil
 				})
 			}
+
+			printSummary(c, summary, *summaryFile)
 		},
 	}
 }
+
+// existingFiles returns the local files of a document downloaded to
+// dst, or else its numbered files, such as the pages of the png
+// exports.
+func existingFiles(dst string) []string {
+	if _, err := os.Stat(util.LongPath(dst)); err == nil {
+		return []string{dst}
+	}
+	var files []string
+	for page := 1; ; page++ {
+		name := export.NumberedName(dst, page)
+		if _, err := os.Stat(util.LongPath(name)); err != nil {
+			return files
+		}
+		files = append(files, name)
+	}
+}
@@ -2,23 +2,35 @@ package shell
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
 
 	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/archive"
+	"github.com/joagonca/rmapi/transfer"
 	"github.com/joagonca/rmapi/util"
 )
 
 func mputCmd(ctx *ShellCtxt) *ishell.Cmd {
 	return &ishell.Cmd{
 		Name:      "mput",
-		Help:      "recursively copy local files to remote directory",
+		Help:      "recursively copy local files to remote directory, usage: mput [--summary file.json] dir",
 		Completer: createFsEntryCompleter(),
 		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("mput", flag.ContinueOnError)
+			summaryFile := flagSet.String("summary", "", "write the transfer summary as json to this file")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			args := flagSet.Args()
 
-			argsLen := len(c.Args)
+			argsLen := len(args)
 
 			if argsLen == 0 {
 				c.Err(errors.New(("missing destination dir")))
@@ -32,7 +44,14 @@ func mputCmd(ctx *ShellCtxt) *ishell.Cmd {
 
 			// Past this point, the number of arguments is 1.
 
-			node, err := ctx.api.Filetree().NodeByPath(c.Args[0], ctx.node)
+			// The settings are read once, before the uploads.
+			content, err := contentSettings()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			node, err := ctx.api.Filetree().NodeByPath(args[0], ctx.node)
 
 			if err != nil || node.IsFile() {
 				c.Err(errors.New("remote directory does not exist"))
@@ -55,8 +74,10 @@ func mputCmd(ctx *ShellCtxt) *ishell.Cmd {
 			ctx.path = path
 			ctx.node = node
 
+			summary := transfer.Start("mput")
+
 			c.Println()
-			err = putFilesAndDirs(ctx, c, "./", 0, &treeFormatStr)
+			err = putFilesAndDirs(ctx, c, "./", 0, &treeFormatStr, content, summary)
 			if err != nil {
 				c.Err(err)
 			}
@@ -69,6 +90,8 @@ func mputCmd(ctx *ShellCtxt) *ishell.Cmd {
 			// Reset.
 			ctx.path = currCtxPath
 			ctx.node = currCtxNode
+
+			printSummary(c, summary, *summaryFile)
 		},
 	}
 }
@@ -111,7 +134,7 @@ func treeFormat(pC *ishell.Context, num int, lIndex int, lSize int, tFS *string)
 	*tFS = tFStr
 }
 
-func putFilesAndDirs(pCtx *ShellCtxt, pC *ishell.Context, localDir string, depth int, tFS *string) error {
+func putFilesAndDirs(pCtx *ShellCtxt, pC *ishell.Context, localDir string, depth int, tFS *string, content archive.ContentSettings, summary *transfer.Summary) error {
 
 	if depth == 0 {
 		pC.Println(pCtx.path)
@@ -152,7 +175,8 @@ func putFilesAndDirs(pCtx *ShellCtxt, pC *ishell.Context, localDir string, depth
 					continue
 				} else {
 					pC.Println(" complete")
-					pCtx.api.Filetree().AddDocument(doc) // Add dir to file tree.
+					pCtx.api.Filetree().AddDocument(doc)
+					summary.Transfer(d.Size()) // Add dir to file tree.
 				}
 			} else {
 				// Directory already exists.
@@ -173,7 +197,7 @@ func putFilesAndDirs(pCtx *ShellCtxt, pC *ishell.Context, localDir string, depth
 			pCtx.path = path
 			pCtx.node = node
 
-			err = putFilesAndDirs(pCtx, pC, name, depth+1, tFS)
+			err = putFilesAndDirs(pCtx, pC, name, depth+1, tFS, content, summary)
 			if err != nil {
 				return err
 			}
@@ -196,14 +220,17 @@ func putFilesAndDirs(pCtx *ShellCtxt, pC *ishell.Context, localDir string, depth
 				// Document already exists.
 				treeFormat(pC, depth, index, lSize, tFS)
 				pC.Printf("document [%s] already exists\n", name)
+				summary.Skip()
 			} else {
 				// Document does not exist.
 				treeFormat(pC, depth, index, lSize, tFS)
 				pC.Printf("uploading: [%s]...", name)
-				doc, err := pCtx.api.UploadDocument(pCtx.node.Id(), name, false)
+				doc, err := uploadDocument(pCtx, pCtx.node.Id(), name, content, false)
 
 				if err != nil {
 					pC.Err(fmt.Errorf("failed to upload file %s", name))
+					enqueueUpload(pC, docName, name, pCtx.node.Id(), err)
+					summary.Fail()
 				} else {
 					// Document uploaded successfully.
 					pC.Println(" complete")
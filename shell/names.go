@@ -0,0 +1,62 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/joagonca/rmapi/model"
+	"github.com/joagonca/rmapi/util"
+)
+
+// loadNameTemplate parses the template of the names of the downloaded
+// files given as flag, or else the one of the environment. It returns
+// nil when there is none.
+func loadNameTemplate(text string) (*util.NameTemplate, error) {
+	if text != "" {
+		return util.ParseNameTemplate(text)
+	}
+	return util.NameTemplateFromEnv()
+}
+
+// outputFileName returns the path of the file of a document in the
+// given folders, with the extension ext.
+func outputFileName(t *util.NameTemplate, node *model.Node, folder []string, ext string) (string, error) {
+	fields := util.NameFields{
+		Name:    util.SafeFileName(node.Name()),
+		Folder:  util.SafeFolder(folder),
+		ID:      node.Id(),
+		Version: node.Version(),
+		Ext:     ext,
+	}
+	fields.Modified, _ = node.LastModified()
+	return t.FileName(fields)
+}
+
+// downloadFileName returns the path of the file of a single document,
+// in the current directory unless a template sets another one. The
+// directories of the path are created.
+func downloadFileName(t *util.NameTemplate, node *model.Node, ext string) (string, error) {
+	var folder []string
+	if t != nil {
+		folder = parentFolders(node)
+	}
+	name, err := outputFileName(t, node, folder, ext)
+	if err != nil {
+		return "", err
+	}
+	if dir := filepath.Dir(name); dir != "." {
+		if err := os.MkdirAll(util.LongPath(dir), 0766); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// parentFolders returns the names of the folders of a node, from the root.
+func parentFolders(node *model.Node) []string {
+	var names []string
+	for p := node.Parent; p != nil && !p.IsRoot(); p = p.Parent {
+		names = append([]string{p.Name()}, names...)
+	}
+	return names
+}
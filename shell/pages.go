@@ -0,0 +1,271 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/api"
+	"github.com/joagonca/rmapi/archive"
+	"github.com/joagonca/rmapi/model"
+)
+
+func pagesCmd(ctx *ShellCtxt) *ishell.Cmd {
+	cmd := &ishell.Cmd{
+		Name: "pages",
+		Help: "change the pages of a document",
+	}
+
+	cmd.AddCmd(pagesRotateCmd(ctx))
+	cmd.AddCmd(pagesDeleteCmd(ctx))
+	cmd.AddCmd(pagesReorderCmd(ctx))
+	cmd.AddCmd(pagesMergeCmd(ctx))
+
+	cmd.Completer = createSubcmdCompleter(cmd)
+
+	return cmd
+}
+
+func pagesRotateCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "rotate",
+		Help:      "rotate pdf pages clockwise with their annotations, usage: pages rotate document pages angle (e.g. 2,5-7 90)",
+		Completer: createEntryCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			if len(c.Args) != 3 {
+				c.Err(errors.New("usage: pages rotate document pages angle"))
+				return
+			}
+
+			pages, err := parsePageList(c.Args[1])
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			angle, err := strconv.Atoi(c.Args[2])
+			if err != nil {
+				c.Err(fmt.Errorf("invalid angle %s", c.Args[2]))
+				return
+			}
+
+			err = editPages(ctx, c, c.Args[0], fmt.Sprintf("rotating %d page(s)", len(pages)), func(files map[string][]byte, id string) error {
+				return archive.RotatePages(files, id, pages, angle)
+			})
+			if err != nil {
+				c.Err(err)
+			}
+		},
+	}
+}
+
+func pagesDeleteCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "delete",
+		Help:      "remove pages with their annotations, usage: pages delete document pages (e.g. 2,5-7)",
+		Completer: createEntryCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			if len(c.Args) != 2 {
+				c.Err(errors.New("usage: pages delete document pages"))
+				return
+			}
+
+			pages, err := parsePageList(c.Args[1])
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			err = editPages(ctx, c, c.Args[0], fmt.Sprintf("removing %d page(s)", len(pages)), func(files map[string][]byte, id string) error {
+				return archive.DeletePages(files, id, pages)
+			})
+			if err != nil {
+				c.Err(err)
+			}
+		},
+	}
+}
+
+func pagesReorderCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "reorder",
+		Help:      "move the pages listed first, the other ones following in their order, usage: pages reorder document pages (e.g. 3,1-2)",
+		Completer: createEntryCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			if len(c.Args) != 2 {
+				c.Err(errors.New("usage: pages reorder document pages"))
+				return
+			}
+
+			pages, err := parsePageList(c.Args[1])
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			err = editPages(ctx, c, c.Args[0], "reordering the pages", func(files map[string][]byte, id string) error {
+				return archive.ReorderPages(files, id, pages)
+			})
+			if err != nil {
+				c.Err(err)
+			}
+		},
+	}
+}
+
+func pagesMergeCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "merge",
+		Help:      "copy the pages of two notebooks into a new one, usage: pages merge first second destination",
+		Completer: createEntryCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			if len(c.Args) != 3 {
+				c.Err(errors.New("usage: pages merge first second destination"))
+				return
+			}
+
+			var nodes [2]*model.Node
+			for i, name := range c.Args[:2] {
+				node, err := ctx.api.Filetree().NodeByPath(name, ctx.node)
+				if err != nil || node.IsDirectory() {
+					c.Err(fmt.Errorf("file %s doesn't exist", name))
+					return
+				}
+				nodes[i] = node
+			}
+
+			dst := c.Args[2]
+			if _, err := ctx.api.Filetree().NodeByPath(dst, ctx.node); err == nil {
+				c.Err(errors.New("entry already exists"))
+				return
+			}
+			dstDir, err := ctx.api.Filetree().NodeByPath(path.Dir(dst), ctx.node)
+			if err != nil || dstDir.IsFile() {
+				c.Err(errors.New("directory doesn't exist"))
+				return
+			}
+			name := path.Base(dst)
+
+			tmpDir, err := os.MkdirTemp("", "rmapi-pages")
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			defer os.RemoveAll(tmpDir)
+
+			var files [2]map[string][]byte
+			for i, node := range nodes {
+				zipPath := filepath.Join(tmpDir, fmt.Sprintf("source%d.zip", i))
+				if err := ctx.api.FetchDocument(node.Id(), zipPath); err != nil {
+					c.Err(fmt.Errorf("Failed to download file %s with %v", c.Args[i], err))
+					return
+				}
+				if files[i], err = archive.ReadRawFiles(zipPath); err != nil {
+					c.Err(err)
+					return
+				}
+			}
+
+			merged, _, err := archive.MergeNotebooks(files[0], nodes[0].Id(), files[1], nodes[1].Id())
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			// the document is named after the archive
+			dstPath := filepath.Join(tmpDir, name+".zip")
+			if err := archive.WriteRawFiles(dstPath, merged); err != nil {
+				c.Err(err)
+				return
+			}
+
+			c.Printf("uploading: [%s]...", name)
+			document, err := ctx.api.UploadDocument(dstDir.Id(), dstPath, true)
+			if err != nil {
+				c.Err(fmt.Errorf("Failed to upload file [%s] %v", name, err))
+				return
+			}
+			c.Println("OK")
+
+			ctx.api.Filetree().AddDocument(document)
+		},
+	}
+}
+
+// editPages downloads a document, changes the files of its archive
+// with edit and replaces them in the cloud, keeping its ID.
+func editPages(ctx *ShellCtxt, c *ishell.Context, srcName, action string, edit func(files map[string][]byte, id string) error) error {
+	updater, ok := ctx.api.(api.Updater)
+	if !ok {
+		return errors.New("changing the pages is only available with the sync 1.5 api")
+	}
+
+	node, err := ctx.api.Filetree().NodeByPath(srcName, ctx.node)
+	if err != nil || node.IsDirectory() {
+		return errors.New("file doesn't exist")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "rmapi-pages")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	zipPath := filepath.Join(tmpDir, "source.zip")
+	if err := ctx.api.FetchDocument(node.Id(), zipPath); err != nil {
+		return fmt.Errorf("Failed to download file %s with %v", srcName, err)
+	}
+
+	files, err := archive.ReadRawFiles(zipPath)
+	if err != nil {
+		return err
+	}
+
+	if err := edit(files, node.Id()); err != nil {
+		return err
+	}
+
+	updatedPath := filepath.Join(tmpDir, "updated.zip")
+	if err := archive.WriteRawFiles(updatedPath, files); err != nil {
+		return err
+	}
+
+	c.Printf("%s of %s...", action, srcName)
+	if _, err := updater.UpdateDocument(node.Id(), updatedPath, true); err != nil {
+		return fmt.Errorf("Failed to update %s %v", srcName, err)
+	}
+	c.Println("OK")
+	return nil
+}
+
+// parsePageList reads page numbers such as "2,5-7", starting at 1.
+func parsePageList(s string) ([]int, error) {
+	var pages []int
+	seen := make(map[int]bool)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		first, last, isRange := strings.Cut(item, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil || from < 1 {
+			return nil, fmt.Errorf("invalid page %s", item)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil || to < from {
+				return nil, fmt.Errorf("invalid page range %s", item)
+			}
+		}
+		for p := from; p <= to; p++ {
+			if !seen[p] {
+				seen[p] = true
+				pages = append(pages, p)
+			}
+		}
+	}
+	return pages, nil
+}
@@ -0,0 +1,22 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePageList(t *testing.T) {
+	pages, err := parsePageList("2,5-7,6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 5, 6, 7}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("expected %v, got %v", want, pages)
+	}
+
+	for _, invalid := range []string{"", "0", "a", "5-3", "2,"} {
+		if _, err := parsePageList(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
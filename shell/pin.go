@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/abiosoft/ishell"
+)
+
+func pinCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return setPinnedCmd(ctx, "pin", "pin (star) entries", true)
+}
+
+func unpinCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return setPinnedCmd(ctx, "unpin", "unpin (unstar) entries", false)
+}
+
+func setPinnedCmd(ctx *ShellCtxt, name, help string, pinned bool) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      name,
+		Help:      help,
+		Completer: createEntryCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			if len(c.Args) == 0 {
+				c.Err(errors.New("missing entry"))
+				return
+			}
+
+			for _, target := range c.Args {
+				node, err := ctx.api.Filetree().NodeByPath(target, ctx.node)
+
+				if err != nil || node.IsRoot() {
+					c.Err(errors.New("entry doesn't exist"))
+					return
+				}
+
+				if node.IsPinned() == pinned {
+					continue
+				}
+
+				if _, err := ctx.api.SetPinned(node, pinned); err != nil {
+					c.Err(errors.New(fmt.Sprint("failed to ", name, " entry ", err)))
+					return
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,163 @@
+package shell
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joagonca/rmapi/annotations"
+	"github.com/joagonca/rmapi/config"
+	"github.com/joagonca/rmapi/export"
+	"github.com/joagonca/rmapi/model"
+)
+
+// loadProfiles reads the export profiles of the settings.
+func loadProfiles() (config.Profiles, error) {
+	settings, path, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := settings.Profiles()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, p := range profiles {
+		if _, err := export.Lookup(p.Format); err != nil {
+			return nil, fmt.Errorf("%s: invalid profile %s: %v", path, p.Name, err)
+		}
+	}
+	return profiles, nil
+}
+
+// documentProfile returns the profile named name, or else the first one
+// matching the folder or the tags of a document. It returns nil when
+// none applies.
+func documentProfile(profiles config.Profiles, name string, node *model.Node) (*config.ExportProfile, error) {
+	if name != "" {
+		return profiles.Find(name)
+	}
+	return profiles.Match(remoteFolder(node), node.Tags()), nil
+}
+
+// remoteFolder returns the path of the folder of a node, as /a/b.
+func remoteFolder(node *model.Node) string {
+	return "/" + strings.Join(parentFolders(node), "/")
+}
+
+// exportDocument downloads a document and writes it to dst in the
+// format of the profile. It returns the written files.
+func exportDocument(ctx *ShellCtxt, node *model.Node, profile *config.ExportProfile, dst string) ([]string, error) {
+	if profile.Format == export.Archive {
+		return []string{dst}, ctx.api.FetchDocument(node.Document.ID, dst)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "rmapi-export")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	zipName := filepath.Join(tmpDir, node.Document.ID+".zip")
+	if err := ctx.api.FetchDocument(node.Document.ID, zipName); err != nil {
+		return nil, err
+	}
+	return generateExport(zipName, dst, profile.Format, exportOptions(ctx, node, profile))
+}
+
+// exportOptions returns the options of the exports of a document,
+// the ones of the profile when there is one.
+func exportOptions(ctx *ShellCtxt, node *model.Node, profile *config.ExportProfile) export.Options {
+	options := export.Options{Document: node.Name()}
+	if docPath, err := ctx.api.Filetree().NodeToPath(node); err == nil {
+		options.Document = docPath
+	}
+	options.Modified, _ = node.LastModified()
+	if profile == nil {
+		return options
+	}
+
+	// the colors are checked when the profiles are loaded
+	colors, _ := profile.ColorMap()
+	highlightColors, _ := profile.HighlightColorMap()
+	widths := make(annotations.PenWidths, len(profile.Widths))
+	for brush, w := range profile.Widths {
+		widths[brush] = annotations.PenWidth{Scale: w.Scale, Offset: w.Offset}
+	}
+	options.PdfGeneratorOptions = annotations.PdfGeneratorOptions{
+		AddPageNumbers:    profile.PageNumbers,
+		AllPages:          profile.AllPages,
+		AnnotationsOnly:   profile.AnnotationsOnly,
+		NoTemplates:       profile.NoTemplates,
+		Smooth:            profile.Smooth,
+		RasterDPI:         profile.RasterDPI,
+		RasterCompression: profile.RasterCompression,
+		Flatten:           profile.Flatten,
+		JPEGQuality:       profile.JPEGQuality,
+		Eink:              profile.Eink,
+		OCR:               profile.OCR,
+		OCRLanguage:       profile.OCRLanguage,
+		Colors:            colors,
+		HighlightColors:   highlightColors,
+		Widths:            widths,
+		SplitEvery:        profile.SplitEvery,
+		Renderer:          profile.Renderer,
+		SplitLayers:       profile.SplitLayers,
+		Layers: annotations.LayerFilter{
+			Include: profile.Layers,
+			Exclude: profile.ExcludeLayers,
+			Visible: profile.VisibleLayers,
+		},
+		PageNumbers: annotations.PageNumberStyle{
+			Position:  profile.PageNumberPosition,
+			Size:      profile.PageNumberSize,
+			Format:    profile.PageNumberFormat,
+			Offset:    profile.PageNumberOffset,
+			SkipFirst: profile.SkipFirstPageNumber,
+		},
+		Watermark: annotations.Watermark{
+			Text:    profile.Watermark,
+			Image:   profile.WatermarkImage,
+			Opacity: profile.WatermarkOpacity,
+		},
+	}
+	options.DPI = profile.DPI
+	return options
+}
+
+// colorFlag reads the colors of a flag written name=#rrggbb,... with
+// parse, the pens or the highlighters.
+func colorFlag(value string, parse func(map[string]string) (map[string]color.RGBA, error)) (annotations.PenColors, error) {
+	if value == "" {
+		return nil, nil
+	}
+	list, err := config.ParseColorList(value)
+	if err != nil {
+		return nil, err
+	}
+	return parse(list)
+}
+
+// mergeColors returns the colors of the profile replaced by the ones of
+// the flags.
+func mergeColors(profile, flags annotations.PenColors) annotations.PenColors {
+	colors := make(annotations.PenColors, len(profile)+len(flags))
+	for name, c := range profile {
+		colors[name] = c
+	}
+	for name, c := range flags {
+		colors[name] = c
+	}
+	return colors
+}
+
+// generateExport writes the archive zipName to dst in a format of the
+// export registry.
+func generateExport(zipName, dst, format string, options export.Options) ([]string, error) {
+	f, err := export.Lookup(format)
+	if err != nil {
+		return nil, err
+	}
+	return f.Exporter.Export(zipName, dst, options)
+}
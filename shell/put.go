@@ -2,32 +2,109 @@ package shell
 
 import (
 	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/abiosoft/ishell"
+	"github.com/google/uuid"
+	"github.com/joagonca/rmapi/archive"
+	"github.com/joagonca/rmapi/generate"
+	"github.com/joagonca/rmapi/pdf"
 	"github.com/joagonca/rmapi/util"
 )
 
 func putCmd(ctx *ShellCtxt) *ishell.Cmd {
 	return &ishell.Cmd{
 		Name:      "put",
-		Help:      "copy a local document to cloud",
+		Help:      "copy a local document to cloud, usage: put [--trim-margins] [--margin points] [--font-size points] [--page-size a4] [--cover first|last] [--tag name]... [--template name] [--modified time] [--version n] [--pinned] [--thumbnail image] file|dir_of_rm_pages [dir]",
 		Completer: createFsEntryCompleter(),
 		Func: func(c *ishell.Context) {
-			if len(c.Args) == 0 {
+			flagSet := flag.NewFlagSet("put", flag.ContinueOnError)
+			trim := flagSet.Bool("trim-margins", false, "crop the white margins of the pages of a pdf")
+			margin := flagSet.Float64("margin", 10, "space in points kept around the content when trimming")
+			fontSize := flagSet.Float64("font-size", 0, "size in points of the text of the markdown and text files, 11 by default")
+			pageSize := flagSet.String("page-size", "", "size of the pages of the markdown and text files: remarkable (by default), a4, a5 or letter")
+			cover := flagSet.String("cover", "", "page shown as the cover in the library: first or last (the last visited one)")
+			var tags tagsFlag
+			flagSet.Var(&tags, "tag", "tag of the document, can be repeated")
+			template := flagSet.String("template", "", "template of the pages of a notebook, such as \"LS Grid medium\"")
+			modified := flagSet.String("modified", "", "modification time of the document, such as 2024-03-01 or 2024-03-01T12:00:00Z, now by default")
+			version := flagSet.Int("version", 0, "version of the metadata of the document")
+			pinned := flagSet.Bool("pinned", false, "mark the document as a favorite")
+			thumbnail := flagSet.String("thumbnail", "", "jpeg or png image shown as the thumbnail of the document, such as its cover")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			args := flagSet.Args()
+
+			if len(args) == 0 {
 				c.Err(errors.New("missing source file"))
 				return
 			}
 
-			srcName := c.Args[0]
+			srcName := args[0]
+			if *fontSize < 0 {
+				c.Err(errors.New("the size of --font-size must be positive"))
+				return
+			}
+			typeset := *fontSize > 0 || *pageSize != ""
+			var opts archive.DocumentOptions
+			if *cover != "" {
+				var err error
+				if opts.Cover, err = archive.ParseCoverPage(*cover); err != nil {
+					c.Err(err)
+					return
+				}
+			}
+			if *modified != "" {
+				var err error
+				if opts.LastModified, err = parseModified(*modified); err != nil {
+					c.Err(err)
+					return
+				}
+			}
+			if *thumbnail != "" {
+				var err error
+				if opts.Thumbnail, err = os.ReadFile(*thumbnail); err != nil {
+					c.Err(err)
+					return
+				}
+			}
+			opts.Tags = tags
+			opts.Template = *template
+			opts.Version = *version
+			opts.Pinned = *pinned
+			// a directory of .rm files is uploaded as a notebook
+			info, err := os.Stat(srcName)
+			isDir := err == nil && info.IsDir()
+			zipped := *cover != "" || len(tags) > 0 || *template != "" || isDir || *modified != "" || *version != 0 || *pinned || *thumbnail != ""
+			// the settings are read before the upload, a mistake in
+			// them isn't queued as a failed upload
+			content, err := contentSettings()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			// the zip keeps the settings, uploadDocument applies them to
+			// the other new documents
+			opts.Content = content
 
 			docName, _ := util.DocPathToName(srcName)
+			if isDir {
+				docName = filepath.Base(filepath.Clean(srcName))
+			}
 
 			node := ctx.node
-			var err error
 
-			if len(c.Args) == 2 {
-				node, err = ctx.api.Filetree().NodeByPath(c.Args[1], ctx.node)
+			if len(args) == 2 {
+				node, err = ctx.api.Filetree().NodeByPath(args[1], ctx.node)
 
 				if err != nil || node.IsFile() {
 					c.Err(errors.New("directory doesn't exist"))
@@ -42,14 +119,38 @@ func putCmd(ctx *ShellCtxt) *ishell.Cmd {
 				return
 			}
 
+			uploadName := srcName
+			if *trim || typeset || zipped {
+				tmpDir, err := os.MkdirTemp("", "rmapi-put")
+				if err != nil {
+					c.Err(err)
+					return
+				}
+				defer os.RemoveAll(tmpDir)
+
+				if *trim {
+					uploadName, err = trimMargins(c, srcName, tmpDir, *margin)
+				} else if typeset {
+					uploadName, err = typesetText(srcName, tmpDir, *fontSize, *pageSize)
+				}
+				if err == nil && zipped {
+					uploadName, err = zipDocument(uploadName, tmpDir, opts)
+				}
+				if err != nil {
+					c.Err(err)
+					return
+				}
+			}
+
 			c.Printf("uploading: [%s]...", srcName)
 
 			dstDir := node.Id()
 
-			document, err := ctx.api.UploadDocument(dstDir, srcName, true)
+			document, err := uploadDocument(ctx, dstDir, uploadName, content, true)
 
 			if err != nil {
 				c.Err(fmt.Errorf("Failed to upload file [%s] %v", srcName, err))
+				enqueueUpload(c, docName, uploadName, dstDir, err)
 				return
 			}
 
@@ -59,3 +160,109 @@ func putCmd(ctx *ShellCtxt) *ishell.Cmd {
 		},
 	}
 }
+
+// trimMargins writes a copy of a pdf with its margins cropped into
+// dir, under the same name so that the document keeps it.
+func trimMargins(c *ishell.Context, srcName, dir string, margin float64) (string, error) {
+	if _, ext := util.DocPathToName(srcName); ext != util.PDF {
+		return "", errors.New("only the margins of pdf files can be trimmed")
+	}
+
+	data, err := os.ReadFile(srcName)
+	if err != nil {
+		return "", err
+	}
+	trimmed, cropped, err := pdf.TrimMargins(data, margin)
+	if err != nil {
+		return "", fmt.Errorf("cannot trim the margins of %s: %v", srcName, err)
+	}
+	c.Printf("trimmed the margins of %d page(s)\n", cropped)
+
+	dst := filepath.Join(dir, filepath.Base(srcName))
+	if err := os.WriteFile(dst, trimmed, 0600); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// typesetText writes the pdf of a markdown or text file into dir, with
+// the size of its text and of its pages, under the same name so that
+// the document keeps it.
+func typesetText(srcName, dir string, fontSize float64, pageSize string) (string, error) {
+	name, ext := util.DocPathToName(srcName)
+	if ext != util.MD && ext != util.MARKDOWN && ext != util.TXT {
+		return "", errors.New("only markdown and text files are typeset with --font-size and --page-size")
+	}
+	opts := generate.MarkdownOptions{FontSize: fontSize}
+	if pageSize != "" {
+		var err error
+		if opts.PageWidth, opts.PageHeight, err = generate.ParsePageSize(pageSize); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := os.ReadFile(srcName)
+	if err != nil {
+		return "", err
+	}
+	typeset, err := archive.ConvertText(data, ext, name, opts)
+	if err != nil {
+		return "", fmt.Errorf("cannot typeset %s: %v", srcName, err)
+	}
+	dst := filepath.Join(dir, name+"."+util.PDF)
+	if err := os.WriteFile(dst, typeset, 0600); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// tagsFlag collects repeated tag flags.
+type tagsFlag []string
+
+func (t *tagsFlag) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *tagsFlag) Set(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return errors.New("empty tag")
+	}
+	*t = append(*t, s)
+	return nil
+}
+
+// parseModified parses the modification time of --modified, a date in
+// the local time or a RFC 3339 time.
+func parseModified(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --modified %q, expected 2006-01-02 or 2006-01-02T15:04:05Z", s)
+	}
+	return t, nil
+}
+
+// zipDocument writes the zip of a document with its cover page, its
+// tags, its template, its metadata and its thumbnail into dir, named after the document so that it
+// keeps its name.
+func zipDocument(srcName, dir string, opts archive.DocumentOptions) (string, error) {
+	name, ext := util.DocPathToName(srcName)
+	if info, err := os.Stat(srcName); err == nil && info.IsDir() {
+		name, ext = filepath.Base(filepath.Clean(srcName)), ""
+	}
+	if ext == util.ZIP || ext == util.RMDOC {
+		return "", errors.New("the cover, the tags, the template, the metadata and the thumbnails of zip and .rmdoc files are kept as they are")
+	}
+	zipPath, err := archive.CreateZipDocument(uuid.New().String(), srcName, opts)
+	if err != nil {
+		return "", fmt.Errorf("cannot create the document of %s: %v", srcName, err)
+	}
+	dst := filepath.Join(dir, name+"."+util.ZIP)
+	if err := os.Rename(zipPath, dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
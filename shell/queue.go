@@ -0,0 +1,186 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/archive"
+	"github.com/joagonca/rmapi/queue"
+)
+
+func queueCmd(ctx *ShellCtxt) *ishell.Cmd {
+	cmd := &ishell.Cmd{
+		Name: "queue",
+		Help: "manage the failed uploads and deletions kept to be retried",
+	}
+
+	cmd.AddCmd(queueLsCmd(ctx))
+	cmd.AddCmd(queueRetryCmd(ctx))
+	cmd.AddCmd(queueClearCmd(ctx))
+
+	cmd.Completer = createSubcmdCompleter(cmd)
+
+	return cmd
+}
+
+func queueLsCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "ls",
+		Help: "list the queued operations",
+		Func: func(c *ishell.Context) {
+			q, err := loadQueue()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			for _, op := range q.Operations {
+				c.Printf("%s\t%s\t%d attempt(s)\t%s\n", op.Added.Format(time.DateTime), op, op.Attempts, op.LastError)
+			}
+		},
+	}
+}
+
+func queueRetryCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "retry",
+		Help: "retry the queued operations",
+		Func: func(c *ishell.Context) {
+			if err := drainQueue(ctx, c); err != nil {
+				c.Err(err)
+			}
+		},
+	}
+}
+
+func queueClearCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "clear",
+		Help: "drop the queued operations",
+		Func: func(c *ishell.Context) {
+			q, err := loadQueue()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			count := len(q.Operations)
+			if err := q.Clear(); err != nil {
+				c.Err(err)
+				return
+			}
+			c.Printf("%d operation(s) dropped\n", count)
+		},
+	}
+}
+
+func loadQueue() (*queue.Queue, error) {
+	dir, err := queue.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return queue.Load(dir)
+}
+
+// printer is the output of the shell or of a command.
+type printer interface {
+	Printf(format string, val ...interface{})
+}
+
+// enqueueUpload keeps a failed upload to retry it later.
+func enqueueUpload(c printer, name, file, parentID string, cause error) {
+	q, err := loadQueue()
+	if err == nil {
+		_, err = q.AddUpload(name, file, parentID, cause)
+	}
+	if err != nil {
+		c.Printf("cannot queue the upload of %s: %v\n", name, err)
+		return
+	}
+	c.Printf("the upload of %s is queued, use queue retry to try again\n", name)
+}
+
+// enqueueDelete keeps a failed deletion to retry it later.
+func enqueueDelete(c printer, path, documentID string, cause error) {
+	q, err := loadQueue()
+	if err == nil {
+		_, err = q.AddDelete(path, documentID, cause)
+	}
+	if err != nil {
+		c.Printf("cannot queue the deletion of %s: %v\n", path, err)
+		return
+	}
+	c.Printf("the deletion of %s is queued, use queue retry to try again\n", path)
+}
+
+// drainQueue retries the queued operations, the ones which fail again
+// stay in the queue. The operations are kept as they are when the
+// settings of the uploads are wrong.
+func drainQueue(ctx *ShellCtxt, c printer) error {
+	q, err := loadQueue()
+	if err != nil {
+		return err
+	}
+	var content archive.ContentSettings
+	for _, op := range q.Operations {
+		if op.Kind == queue.Upload {
+			if content, err = contentSettings(); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	done, failed := 0, 0
+	for _, op := range append([]*queue.Operation(nil), q.Operations...) {
+		if err := retry(ctx, op, content); err != nil {
+			c.Printf("%s failed again: %v\n", op, err)
+			failed++
+			if err := q.Failed(op, err); err != nil {
+				return err
+			}
+			continue
+		}
+		done++
+		if err := q.Remove(op); err != nil {
+			return err
+		}
+	}
+	if done+failed > 0 {
+		c.Printf("queue: %d operation(s) done, %d still queued\n", done, failed)
+	}
+	return nil
+}
+
+func retry(ctx *ShellCtxt, op *queue.Operation, content archive.ContentSettings) error {
+	tree := ctx.api.Filetree()
+	switch op.Kind {
+	case queue.Upload:
+		parent := tree.NodeById(op.ParentID)
+		if parent == nil {
+			return errors.New("destination directory not found")
+		}
+		if _, err := tree.NodeByPath(op.Name, parent); err == nil {
+			// the failed attempt went through
+			return nil
+		}
+		document, err := uploadDocument(ctx, op.ParentID, op.File, content, true)
+		if err != nil {
+			return err
+		}
+		tree.AddDocument(document)
+	case queue.Delete:
+		node := tree.NodeById(op.DocumentID)
+		if node == nil {
+			// already deleted
+			return nil
+		}
+		if err := ctx.api.DeleteEntry(node); err != nil {
+			return err
+		}
+		tree.DeleteNode(node)
+	default:
+		return fmt.Errorf("unknown operation %s", op.Kind)
+	}
+	return nil
+}
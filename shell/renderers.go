@@ -0,0 +1,28 @@
+package shell
+
+import (
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/annotations"
+)
+
+func renderersCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "renderers",
+		Help: "list the renderers of the annotated exports and their capabilities, and the recognizers of the handwriting",
+		Func: func(c *ishell.Context) {
+			def := annotations.DefaultRenderer()
+			for _, info := range annotations.Renderers() {
+				mark := " "
+				if info.Name == def {
+					mark = "*"
+				}
+				c.Printf("%s %-8s %s\n", mark, info.Name, info.Description)
+				c.Printf("  %-8s capabilities: %s\n", "", info.Capabilities)
+			}
+			c.Println("recognizers of --ocr:")
+			for _, info := range annotations.Recognizers() {
+				c.Printf("  %-8s %s\n", info.Name, info.Description)
+			}
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/api"
+	"github.com/joagonca/rmapi/report"
+)
+
+func reportCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "report",
+		Help: "account overview, usage: report [--json] [--pdf [dir]] [--top n]",
+		Completer: func([]string) []string {
+			return []string{"--json", "--pdf", "--top"}
+		},
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("report", flag.ContinueOnError)
+			asJSON := flagSet.Bool("json", false, "print the report as json")
+			asPDF := flagSet.Bool("pdf", false, "upload the report as a pdf to the given or current directory")
+			top := flagSet.Int("top", report.DefaultTop, "number of most annotated documents")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+
+			inspector, ok := ctx.api.(api.Inspector)
+			if !ok {
+				c.Err(errors.New("report is only available with the sync 1.5 api"))
+				return
+			}
+
+			dstDir := ctx.node
+			if *asPDF && flagSet.NArg() > 0 {
+				node, err := ctx.api.Filetree().NodeByPath(flagSet.Arg(0), ctx.node)
+				if err != nil || node.IsFile() {
+					c.Err(errors.New("directory doesn't exist"))
+					return
+				}
+				dstDir = node
+			}
+
+			c.Println("collecting documents...")
+			files, err := inspector.DocumentFiles()
+			if err != nil {
+				c.Err(fmt.Errorf("failed to list documents: %v", err))
+				return
+			}
+
+			r := report.Build(ctx.api.Filetree().Root(), files, *top)
+
+			switch {
+			case *asJSON:
+				err = r.WriteJSON(os.Stdout)
+			case *asPDF:
+				name := fmt.Sprintf("Account report %s", time.Now().Format("2006-01-02"))
+				err = uploadPDF(ctx, c, name, dstDir, r.WritePDF)
+			default:
+				err = r.WriteText(os.Stdout)
+			}
+			if err != nil {
+				c.Err(err)
+			}
+		},
+	}
+}
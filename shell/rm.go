@@ -21,10 +21,16 @@ func rmCmd(ctx *ShellCtxt) *ishell.Cmd {
 					return
 				}
 
+				if node.IsDirectory() && len(node.Children) > 0 {
+					c.Err(errors.New("directory is not empty"))
+					return
+				}
+
 				err = ctx.api.DeleteEntry(node)
 
 				if err != nil {
 					c.Err(errors.New(fmt.Sprint("failed to delete entry", err)))
+					enqueueDelete(c, target, node.Id(), err)
 					return
 				}
 
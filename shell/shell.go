@@ -60,19 +60,47 @@ func RunShell(apiCtx api.ApiCtx, userInfo *api.UserInfo, args []string) error {
 	shell.AddCmd(mgetCmd(ctx))
 	shell.AddCmd(mkdirCmd(ctx))
 	shell.AddCmd(rmCmd(ctx))
+	shell.AddCmd(pinCmd(ctx))
+	shell.AddCmd(unpinCmd(ctx))
 	shell.AddCmd(mvCmd(ctx))
 	shell.AddCmd(putCmd(ctx))
 	shell.AddCmd(mputCmd(ctx))
 	shell.AddCmd(versionCmd(ctx))
 	shell.AddCmd(statCmd(ctx))
 	shell.AddCmd(getACmd(ctx))
+	shell.AddCmd(getzCmd(ctx))
 	shell.AddCmd(findCmd(ctx))
 	shell.AddCmd(nukeCmd(ctx))
 	shell.AddCmd(accountCmd(ctx))
 	shell.AddCmd(refreshCmd(ctx))
+	shell.AddCmd(statsCmd(ctx))
+	shell.AddCmd(deviceCmd(ctx))
+	shell.AddCmd(reportCmd(ctx))
+	shell.AddCmd(generateCmd(ctx))
+	shell.AddCmd(journalCmd(ctx))
+	shell.AddCmd(extractCmd(ctx))
+	shell.AddCmd(pagesCmd(ctx))
+	shell.AddCmd(highlightsCmd(ctx))
+	shell.AddCmd(indexCmd(ctx))
+	shell.AddCmd(searchCmd(ctx))
+	shell.AddCmd(queueCmd(ctx))
+	shell.AddCmd(renderersCmd(ctx))
+	shell.AddCmd(formatsCmd(ctx))
+	shell.AddCmd(checkCmd(ctx))
+	shell.AddCmd(diffCmd(ctx))
+	shell.AddCmd(xferCmd(ctx))
+	shell.AddCmd(daemonCmd(ctx, shell))
 
 	setCustomCompleter(shell)
 
+	// the operations which failed during a previous run are retried
+	// first, unless the queue itself is managed
+	if len(args) == 0 || args[0] != "queue" {
+		if err := drainQueue(ctx, shell); err != nil {
+			shell.Printf("cannot retry the queued operations: %v\n", err)
+		}
+	}
+
 	if len(args) > 0 {
 		return shell.Process(args...)
 	} else {
@@ -0,0 +1,92 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/encoding/rm"
+	"github.com/joagonca/rmapi/strokes"
+)
+
+func statsCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name:      "stats",
+		Help:      "show stroke statistics of a document",
+		Completer: createEntryCompleter(ctx),
+		Func: func(c *ishell.Context) {
+			if len(c.Args) == 0 {
+				c.Err(errors.New("missing source file"))
+				return
+			}
+
+			srcName := c.Args[0]
+
+			node, err := ctx.api.Filetree().NodeByPath(srcName, ctx.node)
+
+			if err != nil || node.IsDirectory() {
+				c.Err(errors.New("file doesn't exist"))
+				return
+			}
+
+			zip, done, err := fetchZip(ctx, node)
+			if err != nil {
+				c.Err(fmt.Errorf("Failed to download file %s with %v", srcName, err))
+				return
+			}
+			defer done()
+
+			pages := make([]*rm.Rm, len(zip.Pages))
+			for i := range zip.Pages {
+				if pages[i], err = zip.PageData(i); err != nil {
+					c.Err(err)
+					return
+				}
+			}
+			stats := strokes.Statistics(pages)
+
+			c.Printf("%-6s %8s %8s %10s %8s  %s\n", "page", "strokes", "points", "ink (px)", "time", "pens")
+			for i, p := range stats.Pages {
+				if p.Empty() {
+					continue
+				}
+				c.Printf("%-6d %8d %8d %10.0f %8s  %s\n", i+1, p.Strokes, p.Points, p.InkDistance,
+					p.WritingTime.Round(time.Second), formatPens(p.Pens))
+			}
+
+			t := stats.Total
+			c.Printf("%-6s %8d %8d %10.0f %8s  %s\n", "total", t.Strokes, t.Points, t.InkDistance,
+				t.WritingTime.Round(time.Second), formatPens(t.Pens))
+
+			if !t.Bounds.Empty() {
+				c.Printf("bounding box: (%.0f, %.0f) - (%.0f, %.0f)\n", t.Bounds.MinX, t.Bounds.MinY, t.Bounds.MaxX, t.Bounds.MaxY)
+			}
+
+			empty := stats.EmptyPages()
+			if len(empty) > 0 {
+				numbers := make([]string, len(empty))
+				for i, idx := range empty {
+					numbers[i] = fmt.Sprint(idx + 1)
+				}
+				c.Printf("empty pages: %s\n", strings.Join(numbers, ", "))
+			}
+		},
+	}
+}
+
+func formatPens(pens map[string]int) string {
+	names := make([]string, 0, len(pens))
+	for name := range pens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s: %d", name, pens[name])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,19 @@
+package shell
+
+import (
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/transfer"
+)
+
+// printSummary ends a bulk transfer, prints its summary and writes it
+// as json to file when it is set.
+func printSummary(c *ishell.Context, summary *transfer.Summary, file string) {
+	summary.Finish()
+	c.Println(summary)
+	if file == "" {
+		return
+	}
+	if err := summary.WriteJSON(file); err != nil {
+		c.Err(err)
+	}
+}
@@ -0,0 +1,114 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/archive"
+	"github.com/joagonca/rmapi/config"
+	"github.com/joagonca/rmapi/model"
+	"github.com/joagonca/rmapi/util"
+)
+
+// uploadPDF uploads a generated PDF document named name to the
+// directory dstDir. The content is written by the write function.
+func uploadPDF(ctx *ShellCtxt, c *ishell.Context, name string, dstDir *model.Node, write func(io.Writer) error) error {
+	if _, err := ctx.api.Filetree().NodeByPath(name, dstDir); err == nil {
+		return errors.New("entry already exists")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "rmapi-generate")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, name+".pdf")
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	content, err := contentSettings()
+	if err != nil {
+		return err
+	}
+
+	c.Printf("uploading: [%s]...", name)
+	document, err := uploadDocument(ctx, dstDir.Id(), path, content, true)
+	if err != nil {
+		enqueueUpload(c, name, path, dstDir.Id(), err)
+		return fmt.Errorf("Failed to upload file [%s] %v", name, err)
+	}
+	c.Println("OK")
+
+	ctx.api.Filetree().AddDocument(document)
+	return nil
+}
+
+// uploadDocument uploads a local document to the directory parentID,
+// with the settings content when it is a new document. The zip and
+// .rmdoc files keep their own settings.
+func uploadDocument(ctx *ShellCtxt, parentID, srcName string, content archive.ContentSettings, notify bool) (*model.Document, error) {
+	_, ext := util.DocPathToName(srcName)
+	if content == (archive.ContentSettings{}) || ext == util.ZIP || ext == util.RMDOC {
+		return ctx.api.UploadDocument(parentID, srcName, notify)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "rmapi-upload")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	zipName, err := zipDocument(srcName, tmpDir, archive.DocumentOptions{Content: content})
+	if err != nil {
+		return nil, err
+	}
+	return ctx.api.UploadDocument(parentID, zipName, notify)
+}
+
+// contentSettings returns the settings of the new documents, of the
+// documents section of the settings. The other sections aren't checked,
+// they don't matter to the uploads.
+func contentSettings() (archive.ContentSettings, error) {
+	settings, path, err := loadSettings()
+	if err != nil {
+		return archive.ContentSettings{}, err
+	}
+	d, err := settings.Documents()
+	if err != nil {
+		return archive.ContentSettings{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return archive.ContentSettings{
+		Pen:           d.Pen,
+		Margins:       d.Margins,
+		TextScale:     d.TextScale,
+		FontName:      d.FontName,
+		LineHeight:    d.LineHeight,
+		TextAlignment: d.TextAlignment,
+	}, nil
+}
+
+// loadSettings reads the settings file, the sections are checked by
+// the features using them. It returns the path of the file for the
+// error messages.
+func loadSettings() (*config.Settings, string, error) {
+	path, err := config.SettingsPath()
+	if err != nil {
+		return nil, "", err
+	}
+	settings, err := config.LoadSettings(path)
+	if err != nil {
+		return nil, path, fmt.Errorf("%s: %v", path, err)
+	}
+	return settings, path, nil
+}
@@ -0,0 +1,249 @@
+package shell
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/abiosoft/ishell"
+	"github.com/joagonca/rmapi/api"
+	"github.com/joagonca/rmapi/config"
+	"github.com/joagonca/rmapi/filetree"
+	"github.com/joagonca/rmapi/model"
+	"github.com/joagonca/rmapi/transfer"
+	"github.com/joagonca/rmapi/util"
+)
+
+// xferEntry is a document or a folder copied by xfer, with the names
+// of its parent folders.
+type xferEntry struct {
+	node    *model.Node
+	folders []string
+	zipName string
+}
+
+func xferCmd(ctx *ShellCtxt) *ishell.Cmd {
+	return &ishell.Cmd{
+		Name: "xfer",
+		Help: "copy documents or folders between configured accounts, keeping their names, folders and annotations, usage: xfer --from account --to account [--summary file.json] path...",
+		Func: func(c *ishell.Context) {
+			flagSet := flag.NewFlagSet("xfer", flag.ContinueOnError)
+			from := flagSet.String("from", "", "account of the documents")
+			to := flagSet.String("to", "", "account receiving the copies")
+			summaryFile := flagSet.String("summary", "", "write the transfer summary as json to this file")
+			if err := flagSet.Parse(c.Args); err != nil {
+				if err != flag.ErrHelp {
+					c.Err(err)
+				}
+				return
+			}
+			paths := flagSet.Args()
+			if len(paths) == 0 {
+				c.Err(errors.New("missing source path"))
+				return
+			}
+			if *from == "" || *to == "" {
+				c.Err(errors.New("missing --from or --to account"))
+				return
+			}
+			if *from == *to {
+				c.Err(errors.New("the accounts must be different"))
+				return
+			}
+
+			accounts, err := loadAccounts()
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			src, err := accounts.Find(*from)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			dst, err := accounts.Find(*to)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+
+			tmpDir, err := os.MkdirTemp("", "rmapi-xfer")
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			defer os.RemoveAll(tmpDir)
+
+			// the hosts are the ones of a single account at a time, the
+			// documents are downloaded before the uploads
+			defer config.SetHosts(config.CurrentHosts())
+
+			summary := transfer.Start("xfer")
+			entries, err := xferDownload(c, src, paths, tmpDir, summary)
+			if err != nil {
+				c.Err(err)
+				return
+			}
+			if err := xferUpload(c, dst, entries, summary); err != nil {
+				c.Err(err)
+			}
+			printSummary(c, summary, *summaryFile)
+		},
+	}
+}
+
+// loadAccounts reads the accounts of the settings.
+func loadAccounts() (config.Accounts, error) {
+	settings, path, err := loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := settings.Accounts()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no account configured in %s", path)
+	}
+	return accounts, nil
+}
+
+// xferDownload downloads the documents of the paths of an account to
+// dir. The documents which fail aren't returned.
+func xferDownload(c *ishell.Context, account *config.Account, paths []string, dir string, summary *transfer.Summary) ([]xferEntry, error) {
+	config.SetHosts(account.Hosts())
+	apiCtx, _, err := api.OpenAccount(account)
+	if err != nil {
+		return nil, err
+	}
+	tree := apiCtx.Filetree()
+
+	var entries []xferEntry
+	for _, p := range paths {
+		node, err := tree.NodeByPath(p, tree.Root())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s doesn't exist", account.Name, p)
+		}
+		filetree.WalkTree(node, filetree.FileTreeVistor{
+			Visit: func(n *model.Node, _ []string) bool {
+				if !n.IsRoot() {
+					entries = append(entries, xferEntry{node: n, folders: parentFolders(n)})
+				}
+				return filetree.ContinueVisiting
+			},
+		})
+	}
+
+	downloaded := entries[:0]
+	for _, e := range entries {
+		if e.node.IsDirectory() {
+			downloaded = append(downloaded, e)
+			continue
+		}
+		c.Printf("downloading: [%s]...\n", e.node.Name())
+		e.zipName = filepath.Join(dir, e.node.Id(), util.SafeFileName(e.node.Name())+".zip")
+		if err := os.MkdirAll(filepath.Dir(e.zipName), 0700); err != nil {
+			return nil, err
+		}
+		if err := apiCtx.FetchDocument(e.node.Id(), e.zipName); err != nil {
+			c.Err(fmt.Errorf("failed to download %s: %v", e.node.Name(), err))
+			summary.Fail()
+			continue
+		}
+		downloaded = append(downloaded, e)
+	}
+	return downloaded, nil
+}
+
+// xferUpload recreates the folders and uploads the documents to an
+// account. The documents already in the account, with the same ID,
+// are skipped.
+func xferUpload(c *ishell.Context, account *config.Account, entries []xferEntry, summary *transfer.Summary) error {
+	config.SetHosts(account.Hosts())
+	apiCtx, _, err := api.OpenAccount(account)
+	if err != nil {
+		return err
+	}
+	tree := apiCtx.Filetree()
+
+	for _, e := range entries {
+		parent, err := xferFolder(apiCtx, e.folders)
+		if err != nil {
+			return err
+		}
+		if e.node.IsDirectory() {
+			if _, err := xferFolder(apiCtx, append(e.folders, e.node.Name())); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if tree.NodeById(e.node.Id()) != nil {
+			c.Printf("skipping: [%s], already in %s\n", e.node.Name(), account.Name)
+			summary.Skip()
+			continue
+		}
+
+		c.Printf("uploading: [%s]...\n", e.node.Name())
+		parentId := parent.Id()
+		if parent.IsRoot() {
+			parentId = ""
+		}
+		document, err := apiCtx.UploadDocument(parentId, e.zipName, false)
+		if err != nil {
+			c.Err(fmt.Errorf("failed to upload %s: %v", e.node.Name(), err))
+			summary.Fail()
+			continue
+		}
+		tree.AddDocument(document)
+
+		// the name of the file may have been changed to be valid
+		if document.VissibleName != e.node.Name() {
+			if _, err := apiCtx.MoveEntry(tree.NodeById(document.ID), parent, e.node.Name()); err != nil {
+				c.Err(fmt.Errorf("failed to rename %s: %v", document.VissibleName, err))
+			}
+		}
+
+		if fi, err := os.Stat(e.zipName); err == nil {
+			summary.Transfer(fi.Size())
+		}
+	}
+	return apiCtx.SyncComplete()
+}
+
+// xferFolder returns the folder of the path given by the names of the
+// folders, creating the missing ones.
+func xferFolder(apiCtx api.ApiCtx, folders []string) (*model.Node, error) {
+	tree := apiCtx.Filetree()
+	node := tree.Root()
+	for _, name := range folders {
+		child := childFolder(node, name)
+		if child == nil {
+			parentId := node.Id()
+			if node.IsRoot() {
+				parentId = ""
+			}
+			document, err := apiCtx.CreateDir(parentId, name, false)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create the folder %s: %v", name, err)
+			}
+			tree.AddDocument(document)
+			child = tree.NodeById(document.ID)
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// childFolder returns the folder of a node with the given name, nil
+// when there is none.
+func childFolder(node *model.Node, name string) *model.Node {
+	for _, child := range node.Children {
+		if child.IsDirectory() && child.Name() == name {
+			return child
+		}
+	}
+	return nil
+}
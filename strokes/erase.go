@@ -0,0 +1,73 @@
+package strokes
+
+import "github.com/joagonca/rmapi/encoding/rm"
+
+// EraseAreas applies the strokes of the area eraser of a page: the
+// strokes drawn before them in their layer which cross the area they
+// enclose are removed, with the erasers themselves.
+func EraseAreas(page *rm.Rm) {
+	for l := range page.Layers {
+		layer := &page.Layers[l]
+		var kept []rm.Line
+		for _, line := range layer.Lines {
+			if line.BrushType != rm.EraseArea {
+				kept = append(kept, line)
+				continue
+			}
+			if len(line.Points) < 3 {
+				continue
+			}
+			remaining := kept[:0]
+			for _, k := range kept {
+				if !crossesArea(k, line.Points) {
+					remaining = append(remaining, k)
+				}
+			}
+			kept = remaining
+		}
+		layer.Lines = kept
+	}
+}
+
+// crossesArea returns whether a stroke has a point in the polygon area
+// or crosses its border.
+func crossesArea(line rm.Line, area []rm.Point) bool {
+	for i, p := range line.Points {
+		if insideArea(p, area) {
+			return true
+		}
+		if i == 0 {
+			continue
+		}
+		prev := line.Points[i-1]
+		for j := range area {
+			if segmentsCross(prev, p, area[j], area[(j+1)%len(area)]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// insideArea returns whether a point is in a polygon, by the parity of
+// the crossings of its border by a horizontal ray.
+func insideArea(p rm.Point, area []rm.Point) bool {
+	inside := false
+	for i := range area {
+		a, b := area[i], area[(i+1)%len(area)]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// segmentsCross returns whether the segments ab and cd cross.
+func segmentsCross(a, b, c, d rm.Point) bool {
+	side := func(p, q, r rm.Point) float32 {
+		return (q.X-p.X)*(r.Y-p.Y) - (q.Y-p.Y)*(r.X-p.X)
+	}
+	d1, d2 := side(c, d, a), side(c, d, b)
+	d3, d4 := side(a, b, c), side(a, b, d)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
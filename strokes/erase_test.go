@@ -0,0 +1,32 @@
+package strokes
+
+import (
+	"testing"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+func TestEraseAreas(t *testing.T) {
+	line := func(brush rm.BrushType, points ...rm.Point) rm.Line {
+		return rm.Line{BrushType: brush, Points: points}
+	}
+	page := NewPage()
+	page.Layers[0].Lines = []rm.Line{
+		// inside the area
+		line(rm.FinelinerV5, rm.Point{X: 150, Y: 150}, rm.Point{X: 160, Y: 160}),
+		// crossing it without a point inside
+		line(rm.FinelinerV5, rm.Point{X: 50, Y: 150}, rm.Point{X: 300, Y: 150}),
+		// outside
+		line(rm.FinelinerV5, rm.Point{X: 500, Y: 500}, rm.Point{X: 600, Y: 600}),
+		line(rm.EraseArea, rm.Point{X: 100, Y: 100}, rm.Point{X: 200, Y: 100}, rm.Point{X: 200, Y: 200}, rm.Point{X: 100, Y: 200}),
+		// drawn after the eraser
+		line(rm.BallPointV5, rm.Point{X: 150, Y: 150}, rm.Point{X: 160, Y: 160}),
+	}
+
+	EraseAreas(page)
+
+	lines := page.Layers[0].Lines
+	if len(lines) != 2 || lines[0].Points[0].X != 500 || lines[1].BrushType != rm.BallPointV5 {
+		t.Errorf("unexpected lines %+v", lines)
+	}
+}
@@ -0,0 +1,299 @@
+package strokes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Font is a stroke font: every glyph is a set of polylines.
+//
+// Glyph coordinates are in font units, x starting at 0 on the left of
+// the glyph and y relative to the baseline, growing downwards.
+type Font struct {
+	Glyphs map[rune]*Glyph
+	// CapHeight is the height of capital letters in font units
+	CapHeight float64
+	// LineHeight is the distance between two baselines in font units
+	LineHeight float64
+}
+
+// A Glyph is the drawing of a single character.
+type Glyph struct {
+	Advance float64
+	Strokes []polyline
+}
+
+// glyph returns the glyph of r, falling back to '?' for
+// characters the font does not define.
+func (f *Font) glyph(r rune) *Glyph {
+	if g, ok := f.Glyphs[r]; ok {
+		return g
+	}
+	return f.Glyphs['?']
+}
+
+// hersheyBaseline and hersheyCapHeight describe the metrics used
+// by the roman Hershey fonts.
+const (
+	hersheyBaseline   = 9
+	hersheyCapHeight  = 21
+	hersheyLineHeight = 32
+)
+
+// LoadHersheyFont parses a Hershey font in the .jhf format. Glyphs are
+// mapped in order to the printable ASCII characters, starting at space,
+// as in the usual distributions of these fonts (e.g. futural.jhf).
+func LoadHersheyFont(r io.Reader) (*Font, error) {
+	font := &Font{
+		Glyphs:     make(map[rune]*Glyph),
+		CapHeight:  hersheyCapHeight,
+		LineHeight: hersheyLineHeight,
+	}
+
+	br := bufio.NewReader(r)
+	char := rune(' ')
+	for {
+		g, err := readHersheyGlyph(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid glyph for %q: %v", char, err)
+		}
+		font.Glyphs[char] = g
+		char++
+	}
+
+	if len(font.Glyphs) == 0 {
+		return nil, errors.New("font does not contain any glyph")
+	}
+	if _, ok := font.Glyphs['?']; !ok {
+		font.Glyphs['?'] = &Glyph{Advance: hersheyCapHeight / 2}
+	}
+
+	return font, nil
+}
+
+// readHersheyGlyph reads a glyph which may be wrapped on several lines.
+func readHersheyGlyph(br *bufio.Reader) (*Glyph, error) {
+	var line string
+	for strings.TrimSpace(line) == "" {
+		l, err := br.ReadString('\n')
+		line = strings.TrimRight(l, "\r\n")
+		if err == io.EOF && strings.TrimSpace(line) == "" {
+			return nil, io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+
+	if len(line) < 8 {
+		return nil, errors.New("line too short")
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[5:8]))
+	if err != nil || count < 1 {
+		return nil, errors.New("invalid vertex count")
+	}
+
+	data := line[8:]
+	for len(data) < 2*count {
+		l, err := br.ReadString('\n')
+		data += strings.TrimRight(l, "\r\n")
+		if err != nil {
+			if len(data) < 2*count {
+				return nil, errors.New("truncated glyph")
+			}
+			break
+		}
+	}
+
+	coord := func(i int) float64 {
+		return float64(int(data[i]) - int('R'))
+	}
+
+	left, right := coord(0), coord(1)
+	g := &Glyph{Advance: right - left}
+
+	var current polyline
+	for i := 2; i+1 < 2*count; i += 2 {
+		if data[i] == ' ' && data[i+1] == 'R' {
+			if len(current) > 0 {
+				g.Strokes = append(g.Strokes, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, point{coord(i) - left, coord(i+1) - hersheyBaseline})
+	}
+	if len(current) > 0 {
+		g.Strokes = append(g.Strokes, current)
+	}
+
+	return g, nil
+}
+
+// builtinCapHeight is the cap height of the builtin font, which is
+// also where its baseline sits in the glyph definitions below.
+const builtinCapHeight = 6
+
+// builtinGlyphs defines a simple single-stroke font on a small grid.
+// Each definition is "width|stroke;stroke" and every stroke is a list
+// of "x,y" points, y going from 0 (cap height) to 6 (baseline).
+var builtinGlyphs = map[rune]string{
+	' ':  "3|",
+	'0':  "4|1,0 3,0 4,1 4,5 3,6 1,6 0,5 0,1 1,0",
+	'1':  "4|1,1 2,0 2,6;1,6 3,6",
+	'2':  "4|0,1 1,0 3,0 4,1 4,2 0,6 4,6",
+	'3':  "4|0,0 4,0 2,2.5 3,2.5 4,3.5 4,5 3,6 1,6 0,5",
+	'4':  "4|3,6 3,0 0,4 4,4",
+	'5':  "4|4,0 0,0 0,2.5 3,2.5 4,3.5 4,5 3,6 0,6",
+	'6':  "4|3,0 1,0 0,1 0,5 1,6 3,6 4,5 4,3.5 3,2.5 0,2.5",
+	'7':  "4|0,0 4,0 1,6",
+	'8':  "4|1,0 3,0 4,1 4,2 3,3 1,3 0,4 0,5 1,6 3,6 4,5 4,4 3,3;1,3 0,2 0,1 1,0",
+	'9':  "4|4,3.5 1,3.5 0,2.5 0,1 1,0 3,0 4,1 4,5 3,6 1,6",
+	'A':  "4|0,6 2,0 4,6;0.7,4 3.3,4",
+	'B':  "4|0,6 0,0 3,0 4,1 4,2 3,3 0,3;3,3 4,4 4,5 3,6 0,6",
+	'C':  "4|4,1 3,0 1,0 0,1 0,5 1,6 3,6 4,5",
+	'D':  "4|0,0 0,6 2.5,6 4,4.5 4,1.5 2.5,0 0,0",
+	'E':  "4|4,0 0,0 0,6 4,6;0,3 3,3",
+	'F':  "4|4,0 0,0 0,6;0,3 3,3",
+	'G':  "4|4,1 3,0 1,0 0,1 0,5 1,6 3,6 4,5 4,3 2,3",
+	'H':  "4|0,0 0,6;4,0 4,6;0,3 4,3",
+	'I':  "4|1,0 3,0;2,0 2,6;1,6 3,6",
+	'J':  "4|4,0 4,5 3,6 1,6 0,5",
+	'K':  "4|0,0 0,6;4,0 0,4;1.3,3 4,6",
+	'L':  "4|0,0 0,6 4,6",
+	'M':  "5|0,6 0,0 2.5,3 5,0 5,6",
+	'N':  "4|0,6 0,0 4,6 4,0",
+	'O':  "4|1,0 3,0 4,1 4,5 3,6 1,6 0,5 0,1 1,0",
+	'P':  "4|0,6 0,0 3,0 4,1 4,2 3,3 0,3",
+	'Q':  "4|1,0 3,0 4,1 4,5 3,6 1,6 0,5 0,1 1,0;2.5,4.5 4.5,6.5",
+	'R':  "4|0,6 0,0 3,0 4,1 4,2 3,3 0,3;2,3 4,6",
+	'S':  "4|4,1 3,0 1,0 0,1 0,2 1,3 3,3 4,4 4,5 3,6 1,6 0,5",
+	'T':  "4|0,0 4,0;2,0 2,6",
+	'U':  "4|0,0 0,5 1,6 3,6 4,5 4,0",
+	'V':  "4|0,0 2,6 4,0",
+	'W':  "5|0,0 1.25,6 2.5,2 3.75,6 5,0",
+	'X':  "4|0,0 4,6;4,0 0,6",
+	'Y':  "4|0,0 2,3 4,0;2,3 2,6",
+	'Z':  "4|0,0 4,0 0,6 4,6",
+	'a':  "3|3,2 3,6;3,3 2,2 1,2 0,3 0,5 1,6 2,6 3,5",
+	'b':  "3|0,0 0,6;0,3 1,2 2,2 3,3 3,5 2,6 1,6 0,5",
+	'c':  "3|3,2.5 2.5,2 1,2 0,3 0,5 1,6 2.5,6 3,5.5",
+	'd':  "3|3,0 3,6;3,3 2,2 1,2 0,3 0,5 1,6 2,6 3,5",
+	'e':  "3|0,4 3,4 3,3 2,2 1,2 0,3 0,5 1,6 2.5,6 3,5.5",
+	'f':  "3|3,0.5 2.5,0 2,0 1,1 1,6;0,2 2.5,2",
+	'g':  "3|3,2 3,7 2,8 1,8 0,7.5;3,3 2,2 1,2 0,3 0,4.5 1,5.5 2,5.5 3,4.5",
+	'h':  "3|0,0 0,6;0,3 1,2 2,2 3,3 3,6",
+	'i':  "2|1,2 1,6;1,0.6 1,0.9",
+	'j':  "2|1.5,2 1.5,7 0.5,8 0,8;1.5,0.6 1.5,0.9",
+	'k':  "3|0,0 0,6;3,2 0,4.5;1,3.8 3,6",
+	'l':  "2|1,0 1,5.5 1.5,6",
+	'm':  "5|0,2 0,6;0,3 1,2 1.5,2 2.5,3 2.5,6;2.5,3 3.5,2 4,2 5,3 5,6",
+	'n':  "3|0,2 0,6;0,3 1,2 2,2 3,3 3,6",
+	'o':  "3|1,2 2,2 3,3 3,5 2,6 1,6 0,5 0,3 1,2",
+	'p':  "3|0,2 0,8;0,3 1,2 2,2 3,3 3,5 2,6 1,6 0,5",
+	'q':  "3|3,2 3,8;3,3 2,2 1,2 0,3 0,5 1,6 2,6 3,5",
+	'r':  "3|0,2 0,6;0,3.5 1.5,2 3,2",
+	's':  "3|3,2.5 2.5,2 0.5,2 0,2.5 0,3.5 0.5,4 2.5,4 3,4.5 3,5.5 2.5,6 0.5,6 0,5.5",
+	't':  "3|1,0.5 1,5.5 1.5,6 2.5,6;0,2 2.5,2",
+	'u':  "3|0,2 0,5 1,6 2,6 3,5;3,2 3,6",
+	'v':  "3|0,2 1.5,6 3,2",
+	'w':  "5|0,2 1.25,6 2.5,3 3.75,6 5,2",
+	'x':  "3|0,2 3,6;3,2 0,6",
+	'y':  "3|0,2 1.5,6;3,2 1,8 0,8",
+	'z':  "3|0,2 3,2 0,6 3,6",
+	'.':  "1|0.5,5.7 0.5,6",
+	',':  "1|0.5,5.5 0.5,6 0,7",
+	':':  "1|0.5,2.7 0.5,3;0.5,5.7 0.5,6",
+	';':  "1|0.5,2.7 0.5,3;0.5,5.5 0.5,6 0,7",
+	'!':  "1|0.5,0 0.5,4;0.5,5.7 0.5,6",
+	'?':  "4|0,1 1,0 3,0 4,1 4,2 2,3.5 2,4.5;2,5.7 2,6",
+	'\'': "1|0.5,0 0.5,1.5",
+	'"':  "2|0.5,0 0.5,1.5;1.5,0 1.5,1.5",
+	'-':  "3|0,3.5 3,3.5",
+	'+':  "3|0,3.5 3,3.5;1.5,2 1.5,5",
+	'=':  "3|0,2.5 3,2.5;0,4.5 3,4.5",
+	'*':  "3|1.5,1 1.5,4;0,1.75 3,3.25;0,3.25 3,1.75",
+	'/':  "3|0,6 3,0",
+	'\\': "3|0,0 3,6",
+	'|':  "1|0.5,0 0.5,7",
+	'_':  "4|0,6.5 4,6.5",
+	'(':  "2|2,0 0.5,2 0.5,5 2,7",
+	')':  "2|0,0 1.5,2 1.5,5 0,7",
+	'[':  "2|2,0 0,0 0,7 2,7",
+	']':  "2|0,0 2,0 2,7 0,7",
+	'<':  "3|3,2 0,3.5 3,5",
+	'>':  "3|0,2 3,3.5 0,5",
+	'#':  "4|1,1 1,5;3,1 3,5;0,2 4,2;0,4 4,4",
+	'%':  "4|0,6 4,0;0.5,0.5 1,0.5 1,1.5 0.5,1.5 0.5,0.5;3,4.5 3.5,4.5 3.5,5.5 3,5.5 3,4.5",
+	'☐':  "5|0,1 5,1 5,6 0,6 0,1",
+	'☑':  "5|0,1 5,1 5,6 0,6 0,1;1,3.5 2,5 4,2",
+}
+
+// BuiltinFont is a simple single-stroke font covering ASCII letters,
+// digits, common punctuation and checkbox characters.
+var BuiltinFont = mustParseBuiltinFont()
+
+func mustParseBuiltinFont() *Font {
+	font := &Font{
+		Glyphs:     make(map[rune]*Glyph, len(builtinGlyphs)),
+		CapHeight:  builtinCapHeight,
+		LineHeight: 10,
+	}
+
+	for r, def := range builtinGlyphs {
+		g, err := parseBuiltinGlyph(def)
+		if err != nil {
+			panic(fmt.Sprintf("invalid builtin glyph %q: %v", r, err))
+		}
+		font.Glyphs[r] = g
+	}
+
+	return font
+}
+
+func parseBuiltinGlyph(def string) (*Glyph, error) {
+	parts := strings.SplitN(def, "|", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("missing width")
+	}
+
+	width, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, err
+	}
+
+	// one unit of spacing between letters
+	g := &Glyph{Advance: width + 1}
+	if parts[1] == "" {
+		return g, nil
+	}
+
+	for _, stroke := range strings.Split(parts[1], ";") {
+		var pl polyline
+		for _, pair := range strings.Fields(stroke) {
+			xy := strings.Split(pair, ",")
+			if len(xy) != 2 {
+				return nil, fmt.Errorf("invalid point %s", pair)
+			}
+			x, err := strconv.ParseFloat(xy[0], 64)
+			if err != nil {
+				return nil, err
+			}
+			y, err := strconv.ParseFloat(xy[1], 64)
+			if err != nil {
+				return nil, err
+			}
+			pl = append(pl, point{x, y - builtinCapHeight})
+		}
+		g.Strokes = append(g.Strokes, pl)
+	}
+
+	return g, nil
+}
@@ -0,0 +1,366 @@
+package strokes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// pathScanner tokenizes SVG path data and number lists.
+type pathScanner struct {
+	s   string
+	pos int
+}
+
+func newPathScanner(s string) *pathScanner {
+	return &pathScanner{s: s}
+}
+
+func (sc *pathScanner) done() bool {
+	return sc.pos >= len(sc.s)
+}
+
+func (sc *pathScanner) skipSeparators() {
+	for !sc.done() {
+		switch sc.s[sc.pos] {
+		case ' ', '\t', '\r', '\n', ',':
+			sc.pos++
+		default:
+			return
+		}
+	}
+}
+
+func isCommand(c byte) bool {
+	switch c {
+	case 'M', 'm', 'L', 'l', 'H', 'h', 'V', 'v', 'C', 'c', 'S', 's',
+		'Q', 'q', 'T', 't', 'A', 'a', 'Z', 'z':
+		return true
+	}
+	return false
+}
+
+// peekNumber tells whether the next token is a number.
+func (sc *pathScanner) peekNumber() bool {
+	sc.skipSeparators()
+	if sc.done() {
+		return false
+	}
+	c := sc.s[sc.pos]
+	return c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9')
+}
+
+func (sc *pathScanner) number() (float64, error) {
+	sc.skipSeparators()
+	start := sc.pos
+	if !sc.done() && (sc.s[sc.pos] == '-' || sc.s[sc.pos] == '+') {
+		sc.pos++
+	}
+	seenDot, seenExp := false, false
+	for !sc.done() {
+		c := sc.s[sc.pos]
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.' && !seenDot && !seenExp:
+			seenDot = true
+		case (c == 'e' || c == 'E') && !seenExp:
+			seenExp = true
+			if sc.pos+1 < len(sc.s) && (sc.s[sc.pos+1] == '-' || sc.s[sc.pos+1] == '+') {
+				sc.pos++
+			}
+		default:
+			return sc.parse(start)
+		}
+		sc.pos++
+	}
+	return sc.parse(start)
+}
+
+func (sc *pathScanner) parse(start int) (float64, error) {
+	v, err := strconv.ParseFloat(sc.s[start:sc.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number at %d in %q", start, sc.s)
+	}
+	return v, nil
+}
+
+// flag reads an arc flag which may not be separated from the next number.
+func (sc *pathScanner) flag() (bool, error) {
+	sc.skipSeparators()
+	if sc.done() {
+		return false, fmt.Errorf("missing arc flag in %q", sc.s)
+	}
+	c := sc.s[sc.pos]
+	if c != '0' && c != '1' {
+		return false, fmt.Errorf("invalid arc flag at %d in %q", sc.pos, sc.s)
+	}
+	sc.pos++
+	return c == '1', nil
+}
+
+// pathBuilder accumulates flattened subpaths.
+type pathBuilder struct {
+	step    float64
+	lines   []polyline
+	current polyline
+}
+
+func (b *pathBuilder) moveTo(p point) {
+	b.flush()
+	b.current = polyline{p}
+}
+
+func (b *pathBuilder) lineTo(p point) {
+	b.current = append(b.current, p)
+}
+
+func (b *pathBuilder) flush() {
+	if len(b.current) > 1 {
+		b.lines = append(b.lines, b.current)
+	}
+	b.current = nil
+}
+
+func (b *pathBuilder) cubicTo(p0, p1, p2, p3 point) {
+	length := dist(p0, p1) + dist(p1, p2) + dist(p2, p3)
+	n := segmentsFor(length, b.step)
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		mt := 1 - t
+		b.lineTo(point{
+			mt*mt*mt*p0.X + 3*mt*mt*t*p1.X + 3*mt*t*t*p2.X + t*t*t*p3.X,
+			mt*mt*mt*p0.Y + 3*mt*mt*t*p1.Y + 3*mt*t*t*p2.Y + t*t*t*p3.Y,
+		})
+	}
+}
+
+func (b *pathBuilder) quadTo(p0, p1, p2 point) {
+	length := dist(p0, p1) + dist(p1, p2)
+	n := segmentsFor(length, b.step)
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		mt := 1 - t
+		b.lineTo(point{
+			mt*mt*p0.X + 2*mt*t*p1.X + t*t*p2.X,
+			mt*mt*p0.Y + 2*mt*t*p1.Y + t*t*p2.Y,
+		})
+	}
+}
+
+// arcTo flattens an elliptical arc using the endpoint to center
+// parameterization described in the SVG specification (F.6.5).
+func (b *pathBuilder) arcTo(p0 point, rx, ry, rotation float64, large, sweep bool, p1 point) {
+	if rx == 0 || ry == 0 || p0 == p1 {
+		b.lineTo(p1)
+		return
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	phi := rotation * math.Pi / 180
+	cos, sin := math.Cos(phi), math.Sin(phi)
+
+	dx, dy := (p0.X-p1.X)/2, (p0.Y-p1.Y)/2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+
+	// scale up radii that are too small
+	lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry)
+	if lambda > 1 {
+		s := math.Sqrt(lambda)
+		rx *= s
+		ry *= s
+	}
+
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := 0.0
+	if den != 0 && num > 0 {
+		coef = math.Sqrt(num / den)
+	}
+	if large == sweep {
+		coef = -coef
+	}
+	cx1 := coef * rx * y1 / ry
+	cy1 := -coef * ry * x1 / rx
+
+	cx := cos*cx1 - sin*cy1 + (p0.X+p1.X)/2
+	cy := sin*cx1 + cos*cy1 + (p0.Y+p1.Y)/2
+
+	theta := math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	delta := math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx) - theta
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	n := segmentsFor(math.Abs(delta)*math.Max(rx, ry), b.step)
+	for i := 1; i <= n; i++ {
+		a := theta + delta*float64(i)/float64(n)
+		ex, ey := rx*math.Cos(a), ry*math.Sin(a)
+		b.lineTo(point{cos*ex - sin*ey + cx, sin*ex + cos*ey + cy})
+	}
+}
+
+func dist(a, b point) float64 {
+	return math.Hypot(b.X-a.X, b.Y-a.Y)
+}
+
+// flattenPath converts SVG path data into polylines.
+func flattenPath(d string, step float64) ([]polyline, error) {
+	sc := newPathScanner(d)
+	b := &pathBuilder{step: step}
+
+	var cur, start, lastCtrl point
+	var cmd, prevCmd byte
+
+	nums := func(n int) ([]float64, error) {
+		out := make([]float64, n)
+		for i := range out {
+			v, err := sc.number()
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+
+	for {
+		sc.skipSeparators()
+		if sc.done() {
+			break
+		}
+
+		if c := sc.s[sc.pos]; isCommand(c) {
+			cmd = c
+			sc.pos++
+		} else if cmd == 0 || cmd == 'Z' || cmd == 'z' {
+			return nil, fmt.Errorf("expected a command at %d in path data %q", sc.pos, d)
+		} else if !sc.peekNumber() {
+			return nil, fmt.Errorf("unexpected character %q in path data", c)
+		}
+
+		rel := cmd >= 'a' && cmd <= 'z'
+		abs := func(x, y float64) point {
+			if rel {
+				return point{cur.X + x, cur.Y + y}
+			}
+			return point{x, y}
+		}
+
+		switch cmd {
+		case 'M', 'm':
+			v, err := nums(2)
+			if err != nil {
+				return nil, err
+			}
+			cur = abs(v[0], v[1])
+			start = cur
+			b.moveTo(cur)
+			// subsequent pairs are implicit lineto commands
+			if rel {
+				cmd = 'l'
+			} else {
+				cmd = 'L'
+			}
+		case 'L', 'l':
+			v, err := nums(2)
+			if err != nil {
+				return nil, err
+			}
+			cur = abs(v[0], v[1])
+			b.lineTo(cur)
+		case 'H', 'h':
+			v, err := nums(1)
+			if err != nil {
+				return nil, err
+			}
+			if rel {
+				cur.X += v[0]
+			} else {
+				cur.X = v[0]
+			}
+			b.lineTo(cur)
+		case 'V', 'v':
+			v, err := nums(1)
+			if err != nil {
+				return nil, err
+			}
+			if rel {
+				cur.Y += v[0]
+			} else {
+				cur.Y = v[0]
+			}
+			b.lineTo(cur)
+		case 'C', 'c':
+			v, err := nums(6)
+			if err != nil {
+				return nil, err
+			}
+			p1, p2, p3 := abs(v[0], v[1]), abs(v[2], v[3]), abs(v[4], v[5])
+			b.cubicTo(cur, p1, p2, p3)
+			lastCtrl, cur = p2, p3
+		case 'S', 's':
+			v, err := nums(4)
+			if err != nil {
+				return nil, err
+			}
+			p1 := cur
+			if prevCmd == 'C' || prevCmd == 'c' || prevCmd == 'S' || prevCmd == 's' {
+				p1 = point{2*cur.X - lastCtrl.X, 2*cur.Y - lastCtrl.Y}
+			}
+			p2, p3 := abs(v[0], v[1]), abs(v[2], v[3])
+			b.cubicTo(cur, p1, p2, p3)
+			lastCtrl, cur = p2, p3
+		case 'Q', 'q':
+			v, err := nums(4)
+			if err != nil {
+				return nil, err
+			}
+			p1, p2 := abs(v[0], v[1]), abs(v[2], v[3])
+			b.quadTo(cur, p1, p2)
+			lastCtrl, cur = p1, p2
+		case 'T', 't':
+			v, err := nums(2)
+			if err != nil {
+				return nil, err
+			}
+			p1 := cur
+			if prevCmd == 'Q' || prevCmd == 'q' || prevCmd == 'T' || prevCmd == 't' {
+				p1 = point{2*cur.X - lastCtrl.X, 2*cur.Y - lastCtrl.Y}
+			}
+			p2 := abs(v[0], v[1])
+			b.quadTo(cur, p1, p2)
+			lastCtrl, cur = p1, p2
+		case 'A', 'a':
+			radii, err := nums(3)
+			if err != nil {
+				return nil, err
+			}
+			large, err := sc.flag()
+			if err != nil {
+				return nil, err
+			}
+			sweep, err := sc.flag()
+			if err != nil {
+				return nil, err
+			}
+			v, err := nums(2)
+			if err != nil {
+				return nil, err
+			}
+			end := abs(v[0], v[1])
+			b.arcTo(cur, radii[0], radii[1], radii[2], large, sweep, end)
+			cur = end
+		case 'Z', 'z':
+			b.lineTo(start)
+			cur = start
+			b.flush()
+			b.current = polyline{cur}
+		}
+		prevCmd = cmd
+	}
+
+	b.flush()
+	return b.lines, nil
+}
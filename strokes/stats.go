@@ -0,0 +1,144 @@
+package strokes
+
+import (
+	"math"
+	"time"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+// Writing speed and pen lift overhead used to estimate the time spent
+// writing a page. At 226 DPI, 270 pixels per second is about 3 cm/s.
+const (
+	writingSpeed = 270.0
+	penLiftTime  = 150 * time.Millisecond
+)
+
+// Bounds is a bounding box in device pixels.
+type Bounds struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// Empty tells whether the box does not contain any point.
+func (b Bounds) Empty() bool {
+	return b.MaxX < b.MinX || b.MaxY < b.MinY
+}
+
+func emptyBounds() Bounds {
+	return Bounds{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+}
+
+func (b *Bounds) add(x, y float64) {
+	b.MinX = math.Min(b.MinX, x)
+	b.MinY = math.Min(b.MinY, y)
+	b.MaxX = math.Max(b.MaxX, x)
+	b.MaxY = math.Max(b.MaxY, y)
+}
+
+func (b *Bounds) union(o Bounds) {
+	if o.Empty() {
+		return
+	}
+	b.add(o.MinX, o.MinY)
+	b.add(o.MaxX, o.MaxY)
+}
+
+// PageStats holds statistics about the strokes of a page.
+// Eraser lines are only counted in the pen usage.
+type PageStats struct {
+	Strokes int
+	Points  int
+	// InkDistance is the length of all the strokes in device pixels
+	InkDistance float64
+	Bounds      Bounds
+	// Pens counts the strokes made with each brush
+	Pens map[string]int
+	// WritingTime is an estimation of the time spent drawing the page
+	WritingTime time.Duration
+}
+
+// Empty tells whether the page does not contain any ink.
+func (s PageStats) Empty() bool {
+	return s.Strokes == 0
+}
+
+// DocumentStats aggregates the statistics of all the pages of a document.
+type DocumentStats struct {
+	Pages []PageStats
+	Total PageStats
+}
+
+// EmptyPages returns the indexes of pages without any ink.
+func (s DocumentStats) EmptyPages() []int {
+	var empty []int
+	for i, p := range s.Pages {
+		if p.Empty() {
+			empty = append(empty, i)
+		}
+	}
+	return empty
+}
+
+func isEraser(b rm.BrushType) bool {
+	return b == rm.Eraser || b == rm.EraseArea
+}
+
+// PageStatistics computes the statistics of a single page.
+// A nil page is reported as empty.
+func PageStatistics(page *rm.Rm) PageStats {
+	stats := PageStats{
+		Bounds: emptyBounds(),
+		Pens:   make(map[string]int),
+	}
+	if page == nil {
+		return stats
+	}
+
+	for _, layer := range page.Layers {
+		for _, line := range layer.Lines {
+			stats.Pens[line.BrushType.String()]++
+			if isEraser(line.BrushType) || len(line.Points) == 0 {
+				continue
+			}
+
+			stats.Strokes++
+			stats.Points += len(line.Points)
+			for i, p := range line.Points {
+				stats.Bounds.add(float64(p.X), float64(p.Y))
+				if i > 0 {
+					prev := line.Points[i-1]
+					stats.InkDistance += math.Hypot(float64(p.X-prev.X), float64(p.Y-prev.Y))
+				}
+			}
+		}
+	}
+
+	stats.WritingTime = time.Duration(stats.InkDistance/writingSpeed*float64(time.Second)) +
+		time.Duration(stats.Strokes)*penLiftTime
+
+	return stats
+}
+
+// Statistics computes the statistics of every page of a document
+// along with the totals. Pages without drawing can be nil.
+func Statistics(pages []*rm.Rm) DocumentStats {
+	doc := DocumentStats{
+		Total: PageStats{Bounds: emptyBounds(), Pens: make(map[string]int)},
+	}
+
+	for _, page := range pages {
+		s := PageStatistics(page)
+		doc.Pages = append(doc.Pages, s)
+
+		doc.Total.Strokes += s.Strokes
+		doc.Total.Points += s.Points
+		doc.Total.InkDistance += s.InkDistance
+		doc.Total.WritingTime += s.WritingTime
+		doc.Total.Bounds.union(s.Bounds)
+		for pen, count := range s.Pens {
+			doc.Total.Pens[pen] += count
+		}
+	}
+
+	return doc
+}
@@ -0,0 +1,43 @@
+package strokes
+
+import (
+	"testing"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+func TestStatistics(t *testing.T) {
+	page := NewPage()
+	page.Layers[0].Lines = []rm.Line{
+		{BrushType: rm.FinelinerV5, Points: []rm.Point{{X: 0, Y: 0}, {X: 30, Y: 40}}},
+		{BrushType: rm.Eraser, Points: []rm.Point{{X: 500, Y: 500}, {X: 600, Y: 600}}},
+	}
+
+	stats := Statistics([]*rm.Rm{page, nil})
+
+	if len(stats.Pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(stats.Pages))
+	}
+
+	first := stats.Pages[0]
+	if first.Strokes != 1 || first.Points != 2 {
+		t.Errorf("wrong counts %d strokes, %d points", first.Strokes, first.Points)
+	}
+	if first.InkDistance != 50 {
+		t.Errorf("wrong ink distance %f", first.InkDistance)
+	}
+	if first.Bounds.MaxX != 30 || first.Bounds.MaxY != 40 {
+		t.Errorf("eraser should not extend the bounds: %+v", first.Bounds)
+	}
+	if first.Pens["fineliner"] != 1 || first.Pens["eraser"] != 1 {
+		t.Errorf("wrong pen usage %v", first.Pens)
+	}
+	if first.WritingTime <= 0 {
+		t.Error("writing time should be estimated")
+	}
+
+	empty := stats.EmptyPages()
+	if len(empty) != 1 || empty[0] != 1 {
+		t.Errorf("wrong empty pages %v", empty)
+	}
+}
@@ -0,0 +1,106 @@
+// Package strokes generates .rm drawings from other sources
+// so they can be uploaded to the device as editable ink, and
+// analyses the strokes of existing drawings.
+//
+// The generated pages are plain rm.Rm values: they can be
+// marshaled with the encoding/rm package and packed into an
+// archive like any page drawn on the tablet.
+package strokes
+
+import (
+	"math"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+// Pen describes the brush used for the generated lines.
+type Pen struct {
+	Type  rm.BrushType
+	Color rm.BrushColor
+	Size  rm.BrushSize
+	// Width is the width stored in every point of a line
+	Width float32
+	// Pressure is the pressure stored in every point of a line
+	Pressure float32
+}
+
+// DefaultPen is a medium black fineliner.
+var DefaultPen = Pen{
+	Type:     rm.FinelinerV5,
+	Color:    rm.Black,
+	Size:     rm.Medium,
+	Width:    2,
+	Pressure: 0.7,
+}
+
+// DefaultStep is the default maximum distance, in device pixels,
+// between two consecutive points of a generated line.
+const DefaultStep = 4.0
+
+// point is a coordinate in device pixels.
+type point struct {
+	X, Y float64
+}
+
+// polyline is a connected sequence of points.
+type polyline []point
+
+// NewPage creates an empty V5 page with a single layer.
+func NewPage() *rm.Rm {
+	page := rm.New()
+	page.Layers = []rm.Layer{{}}
+	return page
+}
+
+// resample inserts points so that no two consecutive points
+// are further apart than step.
+func resample(pl polyline, step float64) polyline {
+	if len(pl) < 2 || step <= 0 {
+		return pl
+	}
+
+	out := polyline{pl[0]}
+	for i := 1; i < len(pl); i++ {
+		a, b := pl[i-1], pl[i]
+		dist := math.Hypot(b.X-a.X, b.Y-a.Y)
+		n := int(math.Ceil(dist / step))
+		for k := 1; k < n; k++ {
+			t := float64(k) / float64(n)
+			out = append(out, point{a.X + (b.X-a.X)*t, a.Y + (b.Y-a.Y)*t})
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// toLine converts a polyline into a rm.Line drawn with pen.
+func toLine(pl polyline, pen Pen) rm.Line {
+	line := rm.Line{
+		BrushType:  pen.Type,
+		BrushColor: pen.Color,
+		BrushSize:  pen.Size,
+		Points:     make([]rm.Point, len(pl)),
+	}
+
+	for i, p := range pl {
+		var direction float64
+		if i+1 < len(pl) {
+			direction = math.Atan2(pl[i+1].Y-p.Y, pl[i+1].X-p.X)
+		} else if i > 0 {
+			direction = math.Atan2(p.Y-pl[i-1].Y, p.X-pl[i-1].X)
+		}
+		if direction < 0 {
+			direction += 2 * math.Pi
+		}
+
+		line.Points[i] = rm.Point{
+			X:         float32(p.X),
+			Y:         float32(p.Y),
+			Direction: float32(direction),
+			Width:     pen.Width,
+			Pressure:  pen.Pressure,
+		}
+	}
+
+	return line
+}
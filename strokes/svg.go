@@ -0,0 +1,389 @@
+package strokes
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+// SvgOptions configures the SVG to .rm conversion.
+type SvgOptions struct {
+	// Pen used for every generated line
+	Pen Pen
+	// Step is the maximum distance, in device pixels, between two
+	// sampled points. Smaller values give smoother curves.
+	Step float64
+	// Margin, in device pixels, kept around the drawing when it is
+	// fitted to the page.
+	Margin float64
+}
+
+// DefaultSvgOptions returns the options used when uploading an SVG file.
+func DefaultSvgOptions() SvgOptions {
+	return SvgOptions{
+		Pen:    DefaultPen,
+		Step:   DefaultStep,
+		Margin: 50,
+	}
+}
+
+// FromSVG reads an SVG document, flattens all its shapes into strokes
+// and returns them as a single .rm page. The drawing is scaled to fit
+// the device page, keeping its aspect ratio.
+func FromSVG(r io.Reader, opts SvgOptions) (*rm.Rm, error) {
+	lines, bounds, err := parseSVG(r, opts.Step)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 {
+		return nil, errors.New("svg does not contain any drawable shape")
+	}
+
+	if bounds == nil {
+		bounds = boundsOf(lines)
+	}
+
+	fit := fitTransform(*bounds, opts.Margin)
+
+	page := NewPage()
+	for _, pl := range lines {
+		for i := range pl {
+			pl[i] = fit.apply(pl[i])
+		}
+		pl = resample(pl, opts.Step)
+		if len(pl) < 2 {
+			continue
+		}
+		page.Layers[0].Lines = append(page.Layers[0].Lines, toLine(pl, opts.Pen))
+	}
+
+	return page, nil
+}
+
+// rect is an axis aligned box in SVG user units.
+type rect struct {
+	X, Y, W, H float64
+}
+
+func boundsOf(lines []polyline) *rect {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, pl := range lines {
+		for _, p := range pl {
+			minX = math.Min(minX, p.X)
+			minY = math.Min(minY, p.Y)
+			maxX = math.Max(maxX, p.X)
+			maxY = math.Max(maxY, p.Y)
+		}
+	}
+	return &rect{minX, minY, maxX - minX, maxY - minY}
+}
+
+// fitTransform maps the box into the device page, centered and
+// with the given margin.
+func fitTransform(box rect, margin float64) matrix {
+	availW := float64(rm.Width) - 2*margin
+	availH := float64(rm.Height) - 2*margin
+
+	scale := 1.0
+	if box.W > 0 && box.H > 0 {
+		scale = math.Min(availW/box.W, availH/box.H)
+	} else if box.W > 0 {
+		scale = availW / box.W
+	} else if box.H > 0 {
+		scale = availH / box.H
+	}
+
+	offX := margin + (availW-box.W*scale)/2 - box.X*scale
+	offY := margin + (availH-box.H*scale)/2 - box.Y*scale
+
+	return matrix{scale, 0, 0, scale, offX, offY}
+}
+
+// elements whose children are never rendered directly
+var svgSkipped = map[string]bool{
+	"defs":     true,
+	"clipPath": true,
+	"mask":     true,
+	"symbol":   true,
+	"pattern":  true,
+	"marker":   true,
+	"title":    true,
+	"desc":     true,
+	"metadata": true,
+}
+
+// parseSVG walks the SVG elements and returns the flattened lines in
+// user units, along with the viewBox (or size) of the root element.
+func parseSVG(r io.Reader, step float64) ([]polyline, *rect, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+
+	var lines []polyline
+	var bounds *rect
+	stack := []matrix{identity}
+	skipDepth := 0
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse svg: %v", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 || svgSkipped[t.Name.Local] {
+				skipDepth++
+				continue
+			}
+
+			attrs := attrMap(t.Attr)
+			ctm := stack[len(stack)-1]
+			if tr, ok := attrs["transform"]; ok {
+				m, err := parseTransform(tr)
+				if err != nil {
+					return nil, nil, err
+				}
+				ctm = ctm.mul(m)
+			}
+
+			if t.Name.Local == "svg" && bounds == nil {
+				bounds = svgBounds(attrs)
+			}
+
+			shapes, err := shapeLines(t.Name.Local, attrs, step)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, pl := range shapes {
+				for i := range pl {
+					pl[i] = ctm.apply(pl[i])
+				}
+				lines = append(lines, pl)
+			}
+
+			stack = append(stack, ctm)
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	return lines, bounds, nil
+}
+
+func attrMap(attrs []xml.Attr) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, a := range attrs {
+		m[a.Name.Local] = a.Value
+	}
+	return m
+}
+
+// svgBounds returns the viewBox of the root element, falling back
+// to its width and height.
+func svgBounds(attrs map[string]string) *rect {
+	if vb, ok := attrs["viewBox"]; ok {
+		nums, err := parseNumbers(vb)
+		if err == nil && len(nums) == 4 && nums[2] > 0 && nums[3] > 0 {
+			return &rect{nums[0], nums[1], nums[2], nums[3]}
+		}
+	}
+
+	w, errW := parseLength(attrs["width"])
+	h, errH := parseLength(attrs["height"])
+	if errW == nil && errH == nil && w > 0 && h > 0 {
+		return &rect{0, 0, w, h}
+	}
+	return nil
+}
+
+// shapeLines flattens a basic shape or path element.
+func shapeLines(name string, attrs map[string]string, step float64) ([]polyline, error) {
+	num := func(key string) float64 {
+		v, _ := parseLength(attrs[key])
+		return v
+	}
+
+	switch name {
+	case "path":
+		return flattenPath(attrs["d"], step)
+	case "line":
+		return []polyline{{{num("x1"), num("y1")}, {num("x2"), num("y2")}}}, nil
+	case "polyline", "polygon":
+		nums, err := parseNumbers(attrs["points"])
+		if err != nil {
+			return nil, err
+		}
+		var pl polyline
+		for i := 0; i+1 < len(nums); i += 2 {
+			pl = append(pl, point{nums[i], nums[i+1]})
+		}
+		if name == "polygon" && len(pl) > 0 {
+			pl = append(pl, pl[0])
+		}
+		return []polyline{pl}, nil
+	case "rect":
+		x, y, w, h := num("x"), num("y"), num("width"), num("height")
+		if w <= 0 || h <= 0 {
+			return nil, nil
+		}
+		return []polyline{{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}, {x, y}}}, nil
+	case "circle":
+		r := num("r")
+		return []polyline{ellipse(num("cx"), num("cy"), r, r, step)}, nil
+	case "ellipse":
+		return []polyline{ellipse(num("cx"), num("cy"), num("rx"), num("ry"), step)}, nil
+	}
+	return nil, nil
+}
+
+func ellipse(cx, cy, rx, ry, step float64) polyline {
+	if rx <= 0 || ry <= 0 {
+		return nil
+	}
+	n := segmentsFor(2*math.Pi*math.Max(rx, ry), step)
+	if n < 8 {
+		n = 8
+	}
+	pl := make(polyline, n+1)
+	for i := 0; i <= n; i++ {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		pl[i] = point{cx + rx*math.Cos(a), cy + ry*math.Sin(a)}
+	}
+	return pl
+}
+
+func segmentsFor(length, step float64) int {
+	if step <= 0 {
+		step = DefaultStep
+	}
+	n := int(math.Ceil(length / step))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// parseLength parses a length ignoring its unit.
+func parseLength(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimRightFunc(s, func(r rune) bool {
+		return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '%'
+	})
+	if s == "" {
+		return 0, errors.New("empty length")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func parseNumbers(s string) ([]float64, error) {
+	sc := newPathScanner(s)
+	var nums []float64
+	for {
+		sc.skipSeparators()
+		if sc.done() {
+			return nums, nil
+		}
+		n, err := sc.number()
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, n)
+	}
+}
+
+// matrix is an affine transform [a c e; b d f; 0 0 1].
+type matrix struct {
+	A, B, C, D, E, F float64
+}
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m * n, i.e. n is applied first.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		A: m.A*n.A + m.C*n.B,
+		B: m.B*n.A + m.D*n.B,
+		C: m.A*n.C + m.C*n.D,
+		D: m.B*n.C + m.D*n.D,
+		E: m.A*n.E + m.C*n.F + m.E,
+		F: m.B*n.E + m.D*n.F + m.F,
+	}
+}
+
+func (m matrix) apply(p point) point {
+	return point{m.A*p.X + m.C*p.Y + m.E, m.B*p.X + m.D*p.Y + m.F}
+}
+
+// parseTransform parses the value of a transform attribute.
+func parseTransform(s string) (matrix, error) {
+	result := identity
+	rest := strings.TrimSpace(s)
+
+	for rest != "" {
+		open := strings.Index(rest, "(")
+		end := strings.Index(rest, ")")
+		if open < 0 || end < open {
+			return identity, fmt.Errorf("invalid transform: %s", s)
+		}
+
+		name := strings.Trim(strings.TrimSpace(rest[:open]), ",")
+		name = strings.TrimSpace(name)
+		args, err := parseNumbers(rest[open+1 : end])
+		if err != nil {
+			return identity, err
+		}
+		rest = strings.TrimLeft(rest[end+1:], " \t\r\n,")
+
+		arg := func(i int, def float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return def
+		}
+
+		var m matrix
+		switch name {
+		case "matrix":
+			if len(args) != 6 {
+				return identity, fmt.Errorf("invalid matrix: %s", s)
+			}
+			m = matrix{args[0], args[1], args[2], args[3], args[4], args[5]}
+		case "translate":
+			m = matrix{1, 0, 0, 1, arg(0, 0), arg(1, 0)}
+		case "scale":
+			sx := arg(0, 1)
+			m = matrix{sx, 0, 0, arg(1, sx), 0, 0}
+		case "rotate":
+			a := arg(0, 0) * math.Pi / 180
+			cx, cy := arg(1, 0), arg(2, 0)
+			cos, sin := math.Cos(a), math.Sin(a)
+			m = matrix{1, 0, 0, 1, cx, cy}.
+				mul(matrix{cos, sin, -sin, cos, 0, 0}).
+				mul(matrix{1, 0, 0, 1, -cx, -cy})
+		case "skewX":
+			m = matrix{1, 0, math.Tan(arg(0, 0) * math.Pi / 180), 1, 0, 0}
+		case "skewY":
+			m = matrix{1, math.Tan(arg(0, 0) * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return identity, fmt.Errorf("unsupported transform: %s", name)
+		}
+		result = result.mul(m)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package strokes
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+const testSvg = `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">
+  <defs><path d="M 0 0 L 100 100"/></defs>
+  <g transform="translate(10, 10)">
+    <rect x="0" y="0" width="20" height="20"/>
+    <path d="M 40 40 C 50 30 60 50 70 40 Z"/>
+  </g>
+  <circle cx="50" cy="80" r="10"/>
+  <path d="m10 90 h 20 v -5 a 5 5 0 0 1 10 0"/>
+</svg>`
+
+func TestFromSVG(t *testing.T) {
+	page, err := FromSVG(strings.NewReader(testSvg), DefaultSvgOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Layers) != 1 {
+		t.Fatalf("expected one layer, got %d", len(page.Layers))
+	}
+
+	lines := page.Layers[0].Lines
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(lines))
+	}
+
+	for _, line := range lines {
+		if line.BrushType != DefaultPen.Type {
+			t.Error("wrong brush type")
+		}
+		for i, p := range line.Points {
+			if p.X < 0 || p.X > float32(rm.Width) || p.Y < 0 || p.Y > float32(rm.Height) {
+				t.Fatalf("point outside of the page: %v", p)
+			}
+			if i > 0 {
+				prev := line.Points[i-1]
+				if math.Hypot(float64(p.X-prev.X), float64(p.Y-prev.Y)) > DefaultStep+0.01 {
+					t.Fatalf("points too far apart: %v %v", prev, p)
+				}
+			}
+		}
+	}
+
+	if _, err := page.MarshalBinary(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFromSVGEmpty(t *testing.T) {
+	_, err := FromSVG(strings.NewReader(`<svg viewBox="0 0 10 10"></svg>`), DefaultSvgOptions())
+	if err == nil {
+		t.Error("expected an error for an empty drawing")
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	m, err := parseTransform("translate(10 20) scale(2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := m.apply(point{1, 1})
+	if p.X != 12 || p.Y != 22 {
+		t.Errorf("wrong transform result %v", p)
+	}
+}
+
+func TestFlattenPathErrors(t *testing.T) {
+	for _, d := range []string{"10 10", "M 10", "M 0 0 Z 10 10", "M 0 0 A 1 1 0 2 0 5 5"} {
+		if _, err := flattenPath(d, DefaultStep); err == nil {
+			t.Errorf("expected error for %q", d)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package strokes
+
+import (
+	"strings"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+// TextOptions configures how text is turned into strokes.
+type TextOptions struct {
+	// Font used to draw the text, BuiltinFont when nil
+	Font *Font
+	// Pen used for every generated line
+	Pen Pen
+	// Size is the height of capital letters in device pixels
+	Size float64
+	// Step is the maximum distance, in device pixels, between two points
+	Step float64
+	// MaxWidth wraps lines longer than this width in device pixels (0 disables wrapping)
+	MaxWidth float64
+}
+
+// DefaultTextOptions returns options drawing medium sized text with the builtin font.
+func DefaultTextOptions() TextOptions {
+	return TextOptions{
+		Font: BuiltinFont,
+		Pen:  DefaultPen,
+		Size: 40,
+		Step: DefaultStep,
+	}
+}
+
+// textMargin is the margin, in device pixels, used by FromText.
+const textMargin = 100
+
+// FromText creates a page containing the text drawn from the top left
+// corner of the page, wrapped to the page width.
+func FromText(text string, opts TextOptions) *rm.Rm {
+	if opts.MaxWidth == 0 {
+		opts.MaxWidth = float64(rm.Width) - 2*textMargin
+	}
+
+	page := NewPage()
+	page.Layers[0].Lines = TextLines(text, textMargin, textMargin, opts)
+	return page
+}
+
+// TextLines draws the text with its top left corner at (x, y) and
+// returns the resulting lines. Line breaks start a new line of text.
+func TextLines(text string, x, y float64, opts TextOptions) []rm.Line {
+	font := opts.Font
+	if font == nil {
+		font = BuiltinFont
+	}
+	if opts.Size <= 0 {
+		opts.Size = DefaultTextOptions().Size
+	}
+	scale := opts.Size / font.CapHeight
+
+	var lines []rm.Line
+	baseline := y + opts.Size
+	for _, row := range wrapText(text, font, scale, opts.MaxWidth) {
+		cursor := x
+		for _, r := range row {
+			g := font.glyph(r)
+			if g == nil {
+				continue
+			}
+			for _, stroke := range g.Strokes {
+				pl := make(polyline, len(stroke))
+				for i, p := range stroke {
+					pl[i] = point{cursor + p.X*scale, baseline + p.Y*scale}
+				}
+				pl = resample(pl, opts.Step)
+				// single points (dots) are drawn as a tiny line
+				if len(pl) == 1 {
+					pl = append(pl, point{pl[0].X + 0.5, pl[0].Y})
+				}
+				lines = append(lines, toLine(pl, opts.Pen))
+			}
+			cursor += g.Advance * scale
+		}
+		baseline += font.LineHeight * scale
+	}
+
+	return lines
+}
+
+// TextWidth returns the width, in device pixels, of a single line of text.
+func TextWidth(text string, opts TextOptions) float64 {
+	font := opts.Font
+	if font == nil {
+		font = BuiltinFont
+	}
+	return measure(text, font, opts.Size/font.CapHeight)
+}
+
+func measure(text string, font *Font, scale float64) float64 {
+	width := 0.0
+	for _, r := range text {
+		if g := font.glyph(r); g != nil {
+			width += g.Advance * scale
+		}
+	}
+	return width
+}
+
+// wrapText splits the text on line breaks and, when maxWidth is set,
+// on spaces so that every row fits.
+func wrapText(text string, font *Font, scale, maxWidth float64) []string {
+	var rows []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		if maxWidth <= 0 {
+			rows = append(rows, paragraph)
+			continue
+		}
+
+		row := ""
+		for _, word := range strings.Split(paragraph, " ") {
+			candidate := word
+			if row != "" {
+				candidate = row + " " + word
+			}
+			if row != "" && measure(candidate, font, scale) > maxWidth {
+				rows = append(rows, row)
+				candidate = word
+			}
+			row = candidate
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
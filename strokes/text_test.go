@@ -0,0 +1,68 @@
+package strokes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextLines(t *testing.T) {
+	opts := DefaultTextOptions()
+	lines := TextLines("Hi", 0, 0, opts)
+
+	// H has 3 strokes, i has 2
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines, got %d", len(lines))
+	}
+
+	for _, line := range lines {
+		for _, p := range line.Points {
+			if p.Y < 0 || float64(p.Y) > opts.Size+0.01 {
+				t.Fatalf("point outside of the text box: %v", p)
+			}
+		}
+	}
+}
+
+func TestTextWrap(t *testing.T) {
+	opts := DefaultTextOptions()
+	width := TextWidth("aaaa", opts)
+	rows := wrapText("aaaa aaaa aaaa", BuiltinFont, opts.Size/BuiltinFont.CapHeight, width*2.5)
+	if len(rows) != 2 {
+		t.Errorf("expected 2 rows, got %q", rows)
+	}
+}
+
+func TestFromTextUnknownRune(t *testing.T) {
+	page := FromText("€", DefaultTextOptions())
+	if len(page.Layers[0].Lines) == 0 {
+		t.Error("unknown characters should fall back to '?'")
+	}
+}
+
+const testHershey = `12345  1JZ
+12345  9I[RFJ[ RRFZ[ RMTWT
+`
+
+func TestLoadHersheyFont(t *testing.T) {
+	font, err := LoadHersheyFont(strings.NewReader(testHershey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, ok := font.Glyphs['!']
+	if !ok {
+		t.Fatal("second glyph should be mapped to '!'")
+	}
+	if a.Advance != 18 {
+		t.Errorf("wrong advance %f", a.Advance)
+	}
+	if len(a.Strokes) != 3 {
+		t.Errorf("expected 3 strokes, got %d", len(a.Strokes))
+	}
+
+	opts := DefaultTextOptions()
+	opts.Font = font
+	if lines := TextLines("! !", 0, 0, opts); len(lines) != 6 {
+		t.Errorf("expected 6 lines, got %d", len(lines))
+	}
+}
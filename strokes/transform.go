@@ -0,0 +1,81 @@
+package strokes
+
+import (
+	"math"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+// PageScale returns the size in points of a device pixel when a page
+// of the given size in points is shown on the tablet: pages wider than
+// the screen ratio fit its width, the others its height.
+func PageScale(width, height float64) float64 {
+	if height/width < 1.33 {
+		return width / float64(rm.Width)
+	}
+	return height / float64(rm.Height)
+}
+
+// RotatePage turns the strokes drawn over a page of width x height
+// points by a multiple of 90 degrees clockwise, so that they stay on
+// the same spot when the page itself is rotated.
+func RotatePage(page *rm.Rm, angle int, width, height float64) {
+	angle = ((angle % 360) + 360) % 360
+	if angle == 0 {
+		return
+	}
+
+	newWidth, newHeight := width, height
+	if angle != 180 {
+		newWidth, newHeight = height, width
+	}
+	before := PageScale(width, height)
+	after := PageScale(newWidth, newHeight)
+	// the strokes keep their size on the page
+	ratio := float32(before / after)
+
+	for l := range page.Layers {
+		for i := range page.Layers[l].Lines {
+			line := &page.Layers[l].Lines[i]
+			for j := range line.Points {
+				p := &line.Points[j]
+				x, y := float64(p.X)*before, float64(p.Y)*before
+				switch angle {
+				case 90:
+					x, y = height-y, x
+				case 180:
+					x, y = width-x, height-y
+				case 270:
+					x, y = y, width-x
+				}
+				p.X, p.Y = float32(x/after), float32(y/after)
+				p.Width *= ratio
+			}
+		}
+	}
+}
+
+// TransformPage moves the strokes and the typed text of a page by a
+// matrix a b c d e f of device pixels, like the matrices of PDF. The
+// widths of the strokes are scaled with them.
+func TransformPage(page *rm.Rm, m [6]float64) {
+	apply := func(x, y float32) (float32, float32) {
+		return float32(m[0]*float64(x) + m[2]*float64(y) + m[4]), float32(m[1]*float64(x) + m[3]*float64(y) + m[5])
+	}
+	ratio := float32(math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2])))
+
+	for l := range page.Layers {
+		for i := range page.Layers[l].Lines {
+			line := &page.Layers[l].Lines[i]
+			for j := range line.Points {
+				p := &line.Points[j]
+				p.X, p.Y = apply(p.X, p.Y)
+				p.Width *= ratio
+			}
+		}
+	}
+	if page.Text != nil {
+		page.Text.X, page.Text.Y = apply(page.Text.X, page.Text.Y)
+		page.Text.Width *= ratio
+	}
+}
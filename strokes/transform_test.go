@@ -0,0 +1,47 @@
+package strokes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/joagonca/rmapi/encoding/rm"
+)
+
+func TestRotatePageRoundTrip(t *testing.T) {
+	page := NewPage()
+	page.Layers[0].Lines = []rm.Line{{Points: []rm.Point{{X: 300, Y: 400, Width: 2}, {X: 1000, Y: 1500, Width: 3}}}}
+
+	RotatePage(page, 90, 445, 594)
+	RotatePage(page, 270, 594, 445)
+
+	want := []rm.Point{{X: 300, Y: 400, Width: 2}, {X: 1000, Y: 1500, Width: 3}}
+	for i, p := range page.Layers[0].Lines[0].Points {
+		if math.Abs(float64(p.X-want[i].X)) > 0.01 || math.Abs(float64(p.Y-want[i].Y)) > 0.01 ||
+			math.Abs(float64(p.Width-want[i].Width)) > 0.01 {
+			t.Errorf("point %d: expected %v, got %v", i, want[i], p)
+		}
+	}
+
+	RotatePage(page, 180, 445, 594)
+	p := page.Layers[0].Lines[0].Points[0]
+	scale := PageScale(445, 594)
+	if math.Abs(float64(p.X)-(445/scale-300)) > 0.01 || math.Abs(float64(p.Y)-(594/scale-400)) > 0.01 {
+		t.Errorf("wrong point after a half turn %v", p)
+	}
+}
+
+func TestTransformPage(t *testing.T) {
+	page := NewPage()
+	page.Layers[0].Lines = []rm.Line{{Points: []rm.Point{{X: 100, Y: 200, Width: 2}}}}
+	page.Text = &rm.Text{X: 10, Y: 20, Width: 500}
+
+	// zoomed twice and moved
+	TransformPage(page, [6]float64{2, 0, 0, 2, -50, 30})
+
+	if p := page.Layers[0].Lines[0].Points[0]; p.X != 150 || p.Y != 430 || p.Width != 4 {
+		t.Errorf("unexpected point %+v", p)
+	}
+	if text := page.Text; text.X != -30 || text.Y != 70 || text.Width != 1000 {
+		t.Errorf("unexpected text box %+v", text)
+	}
+}
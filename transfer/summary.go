@@ -0,0 +1,99 @@
+// Package transfer summarizes the bulk transfers of files.
+package transfer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Summary counts the files of a bulk operation such as mget or mput.
+type Summary struct {
+	Operation   string    `json:"operation"`
+	Started     time.Time `json:"started"`
+	Transferred int       `json:"transferred"`
+	Skipped     int       `json:"skipped"`
+	Failed      int       `json:"failed"`
+	Removed     int       `json:"removed,omitempty"`
+	Bytes       int64     `json:"bytes"`
+	// Duration is set by Finish
+	Duration time.Duration `json:"-"`
+}
+
+// Start begins the summary of an operation.
+func Start(operation string) *Summary {
+	return &Summary{Operation: operation, Started: time.Now()}
+}
+
+// Transfer counts a file of size bytes which was transferred.
+func (s *Summary) Transfer(size int64) {
+	s.Transferred++
+	s.Bytes += size
+}
+
+// Skip counts a file which was already up to date.
+func (s *Summary) Skip() {
+	s.Skipped++
+}
+
+// Fail counts a file which could not be transferred.
+func (s *Summary) Fail() {
+	s.Failed++
+}
+
+// Finish ends the operation.
+func (s *Summary) Finish() {
+	s.Duration = time.Since(s.Started)
+}
+
+// Speed returns the average speed in bytes per second.
+func (s *Summary) Speed() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Duration.Seconds()
+}
+
+func (s *Summary) String() string {
+	removed := ""
+	if s.Removed > 0 {
+		removed = fmt.Sprintf(", %d removed", s.Removed)
+	}
+	return fmt.Sprintf("%s: %d transferred, %d skipped, %d failed%s, %s in %s (%s/s)",
+		s.Operation, s.Transferred, s.Skipped, s.Failed, removed, FormatBytes(s.Bytes),
+		s.Duration.Round(time.Millisecond), FormatBytes(int64(s.Speed())))
+}
+
+// MarshalJSON adds the duration in seconds and the average speed.
+func (s *Summary) MarshalJSON() ([]byte, error) {
+	type summary Summary
+	return json.Marshal(struct {
+		*summary
+		Duration float64 `json:"duration_seconds"`
+		Speed    float64 `json:"bytes_per_second"`
+	}{(*summary)(s), s.Duration.Seconds(), s.Speed()})
+}
+
+// WriteJSON writes the summary as json to a file.
+func (s *Summary) WriteJSON(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// FormatBytes formats a size with a binary unit, e.g. 1.5 MiB.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
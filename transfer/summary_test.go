@@ -0,0 +1,57 @@
+package transfer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSummary(t *testing.T) {
+	s := Start("mget")
+	s.Transfer(1024)
+	s.Transfer(2048)
+	s.Skip()
+	s.Fail()
+	s.Finish()
+	s.Duration = 2 * time.Second
+
+	if s.Transferred != 2 || s.Skipped != 1 || s.Failed != 1 || s.Bytes != 3072 {
+		t.Errorf("unexpected counts %+v", s)
+	}
+	if s.Speed() != 1536 {
+		t.Errorf("expected 1536 B/s, got %v", s.Speed())
+	}
+	if got, want := s.String(), "mget: 2 transferred, 1 skipped, 1 failed, 3.0 KiB in 2s (1.5 KiB/s)"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	s.Removed = 3
+	if got, want := s.String(), "mget: 2 transferred, 1 skipped, 1 failed, 3 removed, 3.0 KiB in 2s (1.5 KiB/s)"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	file := filepath.Join(t.TempDir(), "summary.json")
+	if err := s.WriteJSON(file); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["operation"] != "mget" || decoded["transferred"] != 2.0 || decoded["duration_seconds"] != 2.0 || decoded["bytes_per_second"] != 1536.0 {
+		t.Errorf("unexpected json %s", data)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 30: "5.0 GiB"} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("%d: expected %s, got %s", n, want, got)
+		}
+	}
+}
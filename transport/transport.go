@@ -10,6 +10,7 @@ import (
 	"net/http/httputil"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/joagonca/rmapi/log"
@@ -28,6 +29,8 @@ var ErrConflict = errors.New("409 Conflict")
 
 var RmapiUserAGent = "rmapi"
 
+var logger = log.For(log.Transport)
+
 const (
 	EmptyBearer AuthType = iota
 	DeviceBearer
@@ -38,15 +41,44 @@ const (
 	EmptyBody string = ""
 )
 
+// HttpClientCtx sends the requests to the cloud. It is safe for
+// concurrent use, the copies of a context share its tokens.
 type HttpClientCtx struct {
 	Client *http.Client
-	Tokens model.AuthTokens
+	tokens *tokenStore
+}
+
+// tokenStore holds the tokens, which can be renewed while requests are sent.
+type tokenStore struct {
+	mu     sync.RWMutex
+	tokens model.AuthTokens
 }
 
 func CreateHttpClientCtx(tokens model.AuthTokens) HttpClientCtx {
 	var httpClient = &http.Client{Timeout: 5 * 60 * time.Second}
 
-	return HttpClientCtx{httpClient, tokens}
+	return HttpClientCtx{httpClient, &tokenStore{tokens: tokens}}
+}
+
+// Tokens returns the current tokens.
+func (ctx HttpClientCtx) Tokens() model.AuthTokens {
+	ctx.tokens.mu.RLock()
+	defer ctx.tokens.mu.RUnlock()
+	return ctx.tokens.tokens
+}
+
+// SetDeviceToken replaces the device token used by the next requests.
+func (ctx HttpClientCtx) SetDeviceToken(token string) {
+	ctx.tokens.mu.Lock()
+	defer ctx.tokens.mu.Unlock()
+	ctx.tokens.tokens.DeviceToken = token
+}
+
+// SetUserToken replaces the user token used by the next requests.
+func (ctx HttpClientCtx) SetUserToken(token string) {
+	ctx.tokens.mu.Lock()
+	defer ctx.tokens.mu.Unlock()
+	ctx.tokens.tokens.UserToken = token
 }
 
 func (ctx HttpClientCtx) addAuthorization(req *http.Request, authType AuthType) {
@@ -56,9 +88,9 @@ func (ctx HttpClientCtx) addAuthorization(req *http.Request, authType AuthType)
 	case EmptyBearer:
 		header = "Bearer"
 	case DeviceBearer:
-		header = fmt.Sprintf("Bearer %s", ctx.Tokens.DeviceToken)
+		header = fmt.Sprintf("Bearer %s", ctx.Tokens().DeviceToken)
 	case UserBearer:
-		header = fmt.Sprintf("Bearer %s", ctx.Tokens.UserToken)
+		header = fmt.Sprintf("Bearer %s", ctx.Tokens().UserToken)
 	}
 
 	req.Header.Add("Authorization", header)
@@ -68,7 +100,7 @@ func (ctx HttpClientCtx) Get(authType AuthType, url string, body interface{}, ta
 	bodyReader, err := util.ToIOReader(body)
 
 	if err != nil {
-		log.Error.Println("failed to serialize body", err)
+		logger.Error.Println("failed to serialize body", err)
 		return err
 	}
 
@@ -122,7 +154,7 @@ func (ctx HttpClientCtx) httpRawReq(authType AuthType, verb, url string, reqBody
 		c, err := util.ToIOReader(reqBody)
 
 		if err != nil {
-			log.Error.Println("failed to serialize body", err)
+			logger.Error.Println("failed to serialize body", err)
 			return nil
 		}
 
@@ -157,7 +189,7 @@ func (ctx HttpClientCtx) httpRawReq(authType AuthType, verb, url string, reqBody
 		err := json.NewDecoder(response.Body).Decode(resp)
 
 		if err != nil {
-			log.Error.Println("failed to deserialize body", err, response.Body)
+			logger.Error.Println("failed to deserialize body", err, response.Body)
 			return err
 		}
 	}
@@ -173,26 +205,26 @@ func (ctx HttpClientCtx) Request(authType AuthType, verb, url string, body io.Re
 	ctx.addAuthorization(request, authType)
 	request.Header.Add("User-Agent", RmapiUserAGent)
 
-	if log.TracingEnabled {
+	if logger.TraceEnabled() {
 		drequest, err := httputil.DumpRequest(request, true)
-		log.Trace.Printf("request: %s %v", string(drequest), err)
+		logger.Trace.Printf("request: %s %v", string(drequest), err)
 	}
 
 	response, err := ctx.Client.Do(request)
 
 	if err != nil {
-		log.Error.Println("http request failed with", err)
+		logger.Error.Println("http request failed with", err)
 		return nil, err
 	}
 
-	if log.TracingEnabled {
+	if logger.TraceEnabled() {
 		defer response.Body.Close()
 		dresponse, err := httputil.DumpResponse(response, true)
-		log.Trace.Printf("%s %v", string(dresponse), err)
+		logger.Trace.Printf("%s %v", string(dresponse), err)
 	}
 
 	if response.StatusCode != 200 {
-		log.Trace.Printf("request failed with status %d\n", response.StatusCode)
+		logger.Trace.Printf("request failed with status %d\n", response.StatusCode)
 	}
 
 	switch response.StatusCode {
@@ -228,7 +260,7 @@ func (ctx HttpClientCtx) GetBlobStream(url string) (io.ReadCloser, int64, error)
 	if response.Header != nil {
 		genh := response.Header.Get(HeaderGeneration)
 		if genh != "" {
-			log.Trace.Println("got generation header: ", genh)
+			logger.Trace.Println("got generation header: ", genh)
 			gen, err = strconv.ParseInt(genh, 10, 64)
 		}
 	}
@@ -267,9 +299,9 @@ func (ctx HttpClientCtx) PutRootBlobStream(url string, gen, maxRequestSize int64
 	addGenerationMatchHeader(req, gen)
 	addSizeHeader(req, maxRequestSize)
 
-	if log.TracingEnabled {
+	if logger.TraceEnabled() {
 		drequest, err := httputil.DumpRequest(req, true)
-		log.Trace.Printf("PutRootBlobStream: %s %v", string(drequest), err)
+		logger.Trace.Printf("PutRootBlobStream: %s %v", string(drequest), err)
 	}
 	client := &http.Client{}
 	response, err := client.Do(req)
@@ -277,10 +309,10 @@ func (ctx HttpClientCtx) PutRootBlobStream(url string, gen, maxRequestSize int64
 		return
 	}
 
-	if log.TracingEnabled {
+	if logger.TraceEnabled() {
 		defer response.Body.Close()
 		dresponse, err := httputil.DumpResponse(response, true)
-		log.Trace.Printf("PutRootBlobStream:Response: %s %v", string(dresponse), err)
+		logger.Trace.Printf("PutRootBlobStream:Response: %s %v", string(dresponse), err)
 	}
 
 	if response.StatusCode == http.StatusPreconditionFailed {
@@ -295,14 +327,14 @@ func (ctx HttpClientCtx) PutRootBlobStream(url string, gen, maxRequestSize int64
 	}
 	generationHeader := response.Header.Get(HeaderGeneration)
 	if generationHeader == "" {
-		log.Warning.Println("no new generation header")
+		logger.Warning.Println("no new generation header")
 		return
 	}
 
-	log.Trace.Println("new generation header: ", generationHeader)
+	logger.Trace.Println("new generation header: ", generationHeader)
 	newGeneration, err = strconv.ParseInt(generationHeader, 10, 64)
 	if err != nil {
-		log.Error.Print(err)
+		logger.Error.Print(err)
 	}
 
 	return
@@ -315,9 +347,9 @@ func (ctx HttpClientCtx) PutBlobStream(url string, reader io.Reader, maxRequestS
 	req.Header.Add("User-Agent", RmapiUserAGent)
 	addSizeHeader(req, maxRequestSize)
 
-	if log.TracingEnabled {
+	if logger.TraceEnabled() {
 		drequest, err := httputil.DumpRequest(req, true)
-		log.Trace.Printf("PutBlobStream: %s %v", string(drequest), err)
+		logger.Trace.Printf("PutBlobStream: %s %v", string(drequest), err)
 	}
 	client := &http.Client{}
 	response, err := client.Do(req)
@@ -325,10 +357,10 @@ func (ctx HttpClientCtx) PutBlobStream(url string, reader io.Reader, maxRequestS
 		return
 	}
 
-	if log.TracingEnabled {
+	if logger.TraceEnabled() {
 		defer response.Body.Close()
 		dresponse, err := httputil.DumpResponse(response, true)
-		log.Trace.Printf("PutBlobSteam: Response: %s %v", string(dresponse), err)
+		logger.Trace.Printf("PutBlobSteam: Response: %s %v", string(dresponse), err)
 	}
 
 	if response.StatusCode != http.StatusOK {
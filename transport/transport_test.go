@@ -0,0 +1,49 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/joagonca/rmapi/model"
+)
+
+// TestTokensConcurrentUse is meant to be run with -race.
+func TestTokensConcurrentUse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer user") {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	ctx := CreateHttpClientCtx(model.AuthTokens{UserToken: "user"})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			ctx.SetUserToken(fmt.Sprint("user", i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			errs <- ctx.Post(UserBearer, server.URL, nil, nil)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if !strings.HasPrefix(ctx.Tokens().UserToken, "user") {
+		t.Errorf("unexpected token %s", ctx.Tokens().UserToken)
+	}
+}
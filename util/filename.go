@@ -0,0 +1,144 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const replacementEnvVar = "RMAPI_FILENAME_REPLACEMENT"
+
+// windowsIllegal are the characters which cannot be part of a file name on Windows.
+const windowsIllegal = `<>:"/\|?*`
+
+// windowsReserved are the device names which cannot be used as file
+// names on Windows, with or without extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// NameSanitizer turns the visible names of documents into valid file names.
+type NameSanitizer struct {
+	// Default replaces the illegal characters without a specific replacement
+	Default string
+	// Replacements are the replacements of specific characters
+	Replacements map[rune]string
+	// Windows applies the rules of Windows, otherwise only the
+	// path separator is replaced
+	Windows bool
+}
+
+// ParseReplacements reads a replacement scheme: a comma separated list
+// of "c=replacement" pairs for specific characters, an entry without
+// "=" being the default replacement, e.g. "_,:=-,?=".
+func ParseReplacements(spec string) (def string, replacements map[rune]string) {
+	def = "_"
+	replacements = make(map[rune]string)
+	if spec == "" {
+		return
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		r, size := utf8.DecodeRuneInString(entry)
+		if size > 0 && len(entry) > size && entry[size] == '=' {
+			replacements[r] = entry[size+1:]
+		} else {
+			def = entry
+		}
+	}
+	return
+}
+
+// SanitizerFromEnv creates a sanitizer for the current system, with
+// the replacements set in RMAPI_FILENAME_REPLACEMENT.
+func SanitizerFromEnv() NameSanitizer {
+	def, replacements := ParseReplacements(os.Getenv(replacementEnvVar))
+	return NameSanitizer{Default: def, Replacements: replacements, Windows: runtime.GOOS == "windows"}
+}
+
+func (s NameSanitizer) illegal(r rune) bool {
+	if r == '/' || r == 0 {
+		return true
+	}
+	return s.Windows && (r < 32 || strings.ContainsRune(windowsIllegal, r))
+}
+
+// Sanitize returns a valid file name for a visible name.
+func (s NameSanitizer) Sanitize(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if !s.illegal(r) {
+			b.WriteRune(r)
+			continue
+		}
+		if replacement, ok := s.Replacements[r]; ok {
+			b.WriteString(replacement)
+		} else {
+			b.WriteString(s.Default)
+		}
+	}
+	safe := b.String()
+
+	if s.Windows {
+		// Windows drops the trailing dots and spaces
+		safe = strings.TrimRight(safe, ". ")
+		base := strings.ToUpper(strings.TrimSpace(strings.SplitN(safe, ".", 2)[0]))
+		if windowsReserved[base] {
+			safe = "_" + safe
+		}
+	}
+	if safe == "" || safe == "." || safe == ".." {
+		safe = "_" + safe
+	}
+	return safe
+}
+
+var (
+	sanitizerOnce    sync.Once
+	defaultSanitizer NameSanitizer
+)
+
+// SafeFileName returns a valid file name for a visible name, with the
+// sanitizer configured from the environment.
+func SafeFileName(name string) string {
+	sanitizerOnce.Do(func() {
+		defaultSanitizer = SanitizerFromEnv()
+	})
+	return defaultSanitizer.Sanitize(name)
+}
+
+// windowsMaxPath is the length from which paths need the \\?\ prefix,
+// leaving room for the 8.3 names of directories.
+const windowsMaxPath = 248
+
+// LongPath returns a path usable for the file operations even when it
+// is too long for the default limits of Windows, by turning it into
+// an extended length path. It returns the path unchanged elsewhere.
+func LongPath(p string) string {
+	if runtime.GOOS != "windows" {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil || len(abs) < windowsMaxPath {
+		return p
+	}
+	return extendedPath(abs)
+}
+
+// extendedPath prefixes an absolute Windows path with \\?\.
+func extendedPath(abs string) string {
+	if strings.HasPrefix(abs, `\\?\`) {
+		return abs
+	}
+	abs = strings.ReplaceAll(abs, "/", `\`)
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
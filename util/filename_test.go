@@ -0,0 +1,59 @@
+package util
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	windows := NameSanitizer{Default: "_", Replacements: map[rune]string{':': " -"}, Windows: true}
+	unix := NameSanitizer{Default: "_"}
+
+	tests := []struct {
+		name, windows, unix string
+	}{
+		{"Meeting: notes?", "Meeting - notes_", "Meeting: notes?"},
+		{"a/b", "a_b", "a_b"},
+		{`x<>"\|*`, "x______", `x<>"\|*`},
+		{"trailing. ", "trailing", "trailing. "},
+		{"con", "_con", "con"},
+		{"LPT1.pdf", "_LPT1.pdf", "LPT1.pdf"},
+		{"console", "console", "console"},
+		{"..", "_", "_.."},
+		{"", "_", "_"},
+	}
+	for _, tt := range tests {
+		if got := windows.Sanitize(tt.name); got != tt.windows {
+			t.Errorf("windows: %q: expected %q, got %q", tt.name, tt.windows, got)
+		}
+		if got := unix.Sanitize(tt.name); got != tt.unix {
+			t.Errorf("unix: %q: expected %q, got %q", tt.name, tt.unix, got)
+		}
+	}
+}
+
+func TestParseReplacements(t *testing.T) {
+	def, replacements := ParseReplacements("-,:=,?=¿")
+	if def != "-" {
+		t.Errorf("expected default -, got %q", def)
+	}
+	if r, ok := replacements[':']; !ok || r != "" {
+		t.Errorf("expected : to be removed, got %q", r)
+	}
+	if replacements['?'] != "¿" {
+		t.Errorf("wrong replacement of ?: %q", replacements['?'])
+	}
+
+	if def, _ := ParseReplacements(""); def != "_" {
+		t.Errorf("expected default _, got %q", def)
+	}
+}
+
+func TestExtendedPath(t *testing.T) {
+	if got := extendedPath(`C:\backup\doc.zip`); got != `\\?\C:\backup\doc.zip` {
+		t.Errorf("wrong local path %s", got)
+	}
+	if got := extendedPath(`\\server\share\doc.zip`); got != `\\?\UNC\server\share\doc.zip` {
+		t.Errorf("wrong network path %s", got)
+	}
+	if got := extendedPath(`\\?\C:\doc.zip`); got != `\\?\C:\doc.zip` {
+		t.Errorf("the prefix should not be added twice: %s", got)
+	}
+}
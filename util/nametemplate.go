@@ -0,0 +1,89 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const nameTemplateEnvVar = "RMAPI_NAME_TEMPLATE"
+
+// NameFields are the values available to the templates of the names
+// of downloaded files. The names are valid file names.
+type NameFields struct {
+	// Name is the name of the document
+	Name string
+	// Folder is the path of the folder of the document, relative to
+	// the downloaded directory, with "/" separators
+	Folder   string
+	ID       string
+	Version  int
+	Modified time.Time
+	// Ext is the extension of the written file, such as "zip" or "pdf"
+	Ext string
+}
+
+// A NameTemplate computes the paths of the downloaded files from the
+// documents, e.g. `{{.Folder}}/{{.Name}}_{{.Modified.Format "2006-01-02"}}.pdf`.
+type NameTemplate struct {
+	t *template.Template
+}
+
+// ParseNameTemplate reads a text/template using the NameFields.
+func ParseNameTemplate(text string) (*NameTemplate, error) {
+	t, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %v", err)
+	}
+	return &NameTemplate{t}, nil
+}
+
+// NameTemplateFromEnv returns the template set in RMAPI_NAME_TEMPLATE,
+// or nil when there is none.
+func NameTemplateFromEnv() (*NameTemplate, error) {
+	text := os.Getenv(nameTemplateEnvVar)
+	if text == "" {
+		return nil, nil
+	}
+	return ParseNameTemplate(text)
+}
+
+// Execute returns the relative path of the file of a document. The
+// extension is added when the result doesn't already end with it.
+func (t *NameTemplate) Execute(fields NameFields) (string, error) {
+	var b bytes.Buffer
+	if err := t.t.Execute(&b, fields); err != nil {
+		return "", fmt.Errorf("invalid name template: %v", err)
+	}
+	name := path.Clean(strings.ReplaceAll(b.String(), "\\", "/"))
+	name = strings.TrimPrefix(name, "./")
+	if name == "." || strings.HasPrefix(name, "/") || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("the name template gives an invalid path %q", b.String())
+	}
+	if fields.Ext != "" && !strings.HasSuffix(strings.ToLower(name), "."+fields.Ext) {
+		name += "." + fields.Ext
+	}
+	return name, nil
+}
+
+// FileName returns the path of the file of a document with the template,
+// or with the name of the document in its folder when t is nil.
+func (t *NameTemplate) FileName(fields NameFields) (string, error) {
+	if t == nil {
+		return path.Join(fields.Folder, fields.Name+"."+fields.Ext), nil
+	}
+	return t.Execute(fields)
+}
+
+// SafeFolder turns the names of the folders of a path into valid file names.
+func SafeFolder(names []string) string {
+	safe := make([]string, len(names))
+	for i, name := range names {
+		safe[i] = SafeFileName(name)
+	}
+	return path.Join(safe...)
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNameTemplate(t *testing.T) {
+	fields := NameFields{
+		Name:     "Notes",
+		Folder:   "Work/2024",
+		ID:       "1234",
+		Version:  3,
+		Modified: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		Ext:      "pdf",
+	}
+
+	for text, want := range map[string]string{
+		`{{.Folder}}/{{.Name}}_{{.Modified.Format "2006-01-02"}}.pdf`: "Work/2024/Notes_2024-05-06.pdf",
+		`{{.Name}}-v{{.Version}}`:                                     "Notes-v3.pdf",
+		`./{{.ID}}.PDF`:                                               "1234.PDF",
+	} {
+		tmpl, err := ParseNameTemplate(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := tmpl.Execute(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: expected %s, got %s", text, want, got)
+		}
+	}
+
+	for _, text := range []string{`../{{.Name}}`, `/tmp/{{.Name}}`, `{{.Missing}}`} {
+		tmpl, err := ParseNameTemplate(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name, err := tmpl.Execute(fields); err == nil {
+			t.Errorf("%s: expected an error, got %s", text, name)
+		}
+	}
+
+	if _, err := ParseNameTemplate("{{.Name"); err == nil {
+		t.Error("expected a parse error")
+	}
+
+	var none *NameTemplate
+	if got, _ := none.FileName(fields); got != "Work/2024/Notes.pdf" {
+		t.Errorf("unexpected default name %s", got)
+	}
+}
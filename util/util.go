@@ -3,6 +3,7 @@ package util
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path"
@@ -14,13 +15,33 @@ const (
 	ZIP  = "zip"
 	RM   = "rm"
 	EPUB = "epub"
+	SVG  = "svg"
+	// RMDOC is the zip of a document exported by the device, with the
+	// layout of the archives of the cloud
+	RMDOC = "rmdoc"
+	// the images are uploaded as pdfs
+	PNG  = "png"
+	JPG  = "jpg"
+	JPEG = "jpeg"
+	// the markdown and the text are uploaded as pdfs
+	MD       = "md"
+	MARKDOWN = "markdown"
+	TXT      = "txt"
 )
 
 var supportedExt = map[string]bool{
-	EPUB: true,
-	PDF:  true,
-	ZIP:  true,
-	RM:   true,
+	EPUB:     true,
+	PDF:      true,
+	ZIP:      true,
+	RM:       true,
+	SVG:      true,
+	RMDOC:    true,
+	PNG:      true,
+	JPG:      true,
+	JPEG:     true,
+	MD:       true,
+	MARKDOWN: true,
+	TXT:      true,
 }
 
 func IsFileTypeSupported(ext string) bool {
@@ -55,7 +76,7 @@ func CopyFile(src, dst string) (int64, error) {
 	}
 	defer r.Close()
 
-	w, err := os.Create(dst)
+	w, err := os.Create(LongPath(dst))
 	if err != nil {
 		return 0, err
 	}
@@ -75,3 +96,17 @@ func InSlice(req interface{}) []interface{} {
 	slice = append(slice, req)
 	return slice
 }
+
+// FormatSize prints a size in bytes with a binary unit.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}